@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -305,4 +308,312 @@ func TestAPIIntegrationWithStorage(t *testing.T) {
 		t.Logf("Documents in memory: %v", docsInMemory)
 		t.Logf("Full stats: %+v", result)
 	})
-}
\ No newline at end of file
+}
+
+// TestDocumentEndpoints tests reading stored documents back through the API
+func TestDocumentEndpoints(t *testing.T) {
+	gitRepoPath := filepath.Join(t.TempDir(), "test-repo")
+	require.NoError(t, os.MkdirAll(gitRepoPath, 0755))
+	_, err := git.PlainInit(gitRepoPath, false)
+	require.NoError(t, err)
+
+	metrics := storage.NewSimpleMetricsCollector()
+	config := storage.DefaultHybridConfig()
+	config.PrimaryBackend = "govc"
+	config.EnableSync = false
+
+	hybridStorage, err := storage.NewHybridStorage(gitRepoPath, "api-documents-test", config, metrics)
+	require.NoError(t, err)
+	defer hybridStorage.Close()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	docs := []*document.Document{
+		{
+			ID:     "doc-api-1",
+			Source: document.Source{Type: "text", URL: "https://example.com/1"},
+			Content: document.Content{
+				Text:     "First document",
+				Raw:      []byte("first raw bytes"),
+				Metadata: map[string]string{"category": "cs", "content_type": "text/plain"},
+			},
+			CreatedAt: base,
+			UpdatedAt: base,
+		},
+		{
+			ID:     "doc-api-2",
+			Source: document.Source{Type: "html", URL: "https://example.com/2"},
+			Content: document.Content{
+				Text:     "Second document",
+				Metadata: map[string]string{"category": "bio"},
+			},
+			CreatedAt: base.Add(24 * time.Hour),
+			UpdatedAt: base.Add(24 * time.Hour),
+		},
+		{
+			ID:     "doc-api-3",
+			Source: document.Source{Type: "text", URL: "https://example.com/3"},
+			Content: document.Content{
+				Text:     "Third document",
+				Metadata: map[string]string{"category": "cs"},
+			},
+			CreatedAt: base.Add(48 * time.Hour),
+			UpdatedAt: base.Add(48 * time.Hour),
+		},
+	}
+	for _, doc := range docs {
+		_, err := hybridStorage.StoreDocument(context.Background(), doc)
+		require.NoError(t, err)
+	}
+
+	counted := &countingStorage{HybridStorage: hybridStorage}
+	h := api.NewHandlers(nil, gitRepoPath, counted)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	documents := app.Group("/api/v1/documents")
+	documents.Get("/:id", h.GetDocument)
+	documents.Get("/", h.ListDocuments)
+
+	type listResponse struct {
+		Documents  []api.DocumentResponse `json:"documents"`
+		Pagination struct {
+			Total      *int   `json:"total"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"pagination"`
+	}
+	list := func(query string) listResponse {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/?"+query, nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, query)
+		var result listResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+	ids := func(result listResponse) []string {
+		var ids []string
+		for _, doc := range result.Documents {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		counted.reset()
+		page := list("limit=2")
+		assert.Equal(t, []string{"doc-api-3", "doc-api-2"}, ids(page))
+		require.NotNil(t, page.Pagination.Total)
+		assert.Equal(t, 3, *page.Pagination.Total)
+		assert.Zero(t, counted.lists, "Pages come from the document index")
+		assert.Equal(t, 3, counted.gets, "Only the page and the next document are loaded")
+		assert.True(t, page.Pagination.HasMore)
+		assert.Nil(t, page.Documents[0].Text, "Lists default to metadata only")
+
+		page = list("limit=2&cursor=" + page.Pagination.NextCursor)
+		assert.Equal(t, []string{"doc-api-1"}, ids(page))
+		assert.False(t, page.Pagination.HasMore)
+		assert.Empty(t, page.Pagination.NextCursor)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		assert.Equal(t, []string{"doc-api-3", "doc-api-1"}, ids(list("type=text")))
+		page := list("metadata.category=bio")
+		assert.Equal(t, []string{"doc-api-2"}, ids(page))
+		assert.Nil(t, page.Pagination.Total, "Counting metadata matches would load every document")
+		assert.Equal(t, []string{"doc-api-3", "doc-api-2"}, ids(list("created_after=2024-03-01T13:00:00Z")))
+		assert.Equal(t, []string{"doc-api-1"}, ids(list("type=text&created_before=2024-03-02")))
+
+		page = list("metadata.category=cs&content=text")
+		require.Len(t, page.Documents, 2)
+		require.NotNil(t, page.Documents[0].Text)
+		assert.Equal(t, "Third document", *page.Documents[0].Text)
+
+		for _, query := range []string{"limit=500", "cursor=bogus", "content=all", "created_after=yesterday"} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/?"+query, nil), -1)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("get document", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/doc-api-1", nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		etag := resp.Header.Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		var doc api.DocumentResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		assert.Equal(t, "text", doc.Type)
+		require.NotNil(t, doc.Text)
+		assert.Equal(t, "First document", *doc.Text)
+		assert.Equal(t, "cs", doc.Metadata["category"])
+
+		// Conditional requests
+		req := httptest.NewRequest("GET", "/api/v1/documents/doc-api-1", nil)
+		req.Header.Set("If-None-Match", etag)
+		resp, err = app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", "/api/v1/documents/doc-api-1?content=metadata", nil)
+		req.Header.Set("If-None-Match", etag)
+		resp, err = app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Projections have their own ETags")
+
+		// A new timestamp or source type is a new version
+		updated := *docs[0]
+		updated.UpdatedAt = base.Add(72 * time.Hour)
+		_, err = hybridStorage.UpdateDocument(context.Background(), &updated)
+		require.NoError(t, err)
+		req = httptest.NewRequest("GET", "/api/v1/documents/doc-api-1", nil)
+		req.Header.Set("If-None-Match", etag)
+		resp, err = app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Updating the timestamp changes the ETag")
+
+		// Raw bytes
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/documents/doc-api-1?content=raw", nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "first raw bytes", string(body))
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/documents/missing-doc", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// TestDocumentEndpointsGitPrimary tests that documents stored only in git
+// are listed when git is the primary backend
+func TestDocumentEndpointsGitPrimary(t *testing.T) {
+	gitRepoPath := filepath.Join(t.TempDir(), "test-repo")
+	require.NoError(t, os.MkdirAll(gitRepoPath, 0755))
+	_, err := git.PlainInit(gitRepoPath, false)
+	require.NoError(t, err)
+
+	config := storage.DefaultHybridConfig()
+	config.PrimaryBackend = "git"
+	config.EnableSync = false
+
+	hybridStorage, err := storage.NewHybridStorage(gitRepoPath, "api-documents-git-test", config, storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer hybridStorage.Close()
+
+	now := time.Now().UTC()
+	_, err = hybridStorage.StoreDocument(context.Background(), &document.Document{
+		ID:        "doc-git-only",
+		Source:    document.Source{Type: "text", URL: "https://example.com/git"},
+		Content:   document.Content{Text: "Stored in git alone", Metadata: map[string]string{}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	require.NoError(t, err)
+	_, inGovc := hybridStorage.GetDocumentIndex().Get("doc-git-only")
+	require.False(t, inGovc, "The document should not reach govc")
+
+	h := api.NewHandlers(nil, gitRepoPath, hybridStorage)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/api/v1/documents/", h.ListDocuments)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/?type=text", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Documents []api.DocumentResponse `json:"documents"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Documents, 1)
+	assert.Equal(t, "doc-git-only", result.Documents[0].ID)
+}
+
+// TestQueryEndpointWithHybridStorage tests GQL document and aggregate
+// queries against hybrid storage with either backend primary
+func TestQueryEndpointWithHybridStorage(t *testing.T) {
+	for _, primary := range []string{"govc", "git"} {
+		t.Run(primary, func(t *testing.T) {
+			gitRepoPath := filepath.Join(t.TempDir(), "test-repo")
+			require.NoError(t, os.MkdirAll(gitRepoPath, 0755))
+			_, err := git.PlainInit(gitRepoPath, false)
+			require.NoError(t, err)
+
+			config := storage.DefaultHybridConfig()
+			config.PrimaryBackend = primary
+			config.EnableSync = false
+
+			hybridStorage, err := storage.NewHybridStorage(gitRepoPath, "api-query-"+primary, config, storage.NewSimpleMetricsCollector())
+			require.NoError(t, err)
+			defer hybridStorage.Close()
+
+			now := time.Now().UTC()
+			for id, source := range map[string]string{"query-1": "arxiv", "query-2": "arxiv", "query-3": "pubmed"} {
+				_, err := hybridStorage.StoreDocument(context.Background(), &document.Document{
+					ID:        id,
+					Source:    document.Source{Type: source, URL: "https://example.com/" + id},
+					Content:   document.Content{Text: "Query test document", Metadata: map[string]string{"title": id}},
+					CreatedAt: now,
+					UpdatedAt: now,
+				})
+				require.NoError(t, err)
+			}
+
+			h := api.NewHandlers(nil, gitRepoPath, hybridStorage)
+			app := fiber.New(fiber.Config{DisableStartupMessage: true})
+			app.Post("/api/v1/query/", h.ExecuteQuery)
+
+			query := func(gql string) api.QueryResponse {
+				body, err := json.Marshal(api.QueryRequest{Query: gql})
+				require.NoError(t, err)
+				req := httptest.NewRequest("POST", "/api/v1/query/", bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				resp, err := app.Test(req, -1)
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, resp.StatusCode, gql)
+				var result api.QueryResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+				return result
+			}
+
+			result := query(`SELECT FROM documents WHERE source = "arxiv"`)
+			assert.Equal(t, 2, result.Count)
+
+			result = query(`SELECT COUNT(*) FROM documents GROUP BY source`)
+			assert.ElementsMatch(t, []interface{}{
+				map[string]interface{}{"source": "arxiv", "count": float64(2)},
+				map[string]interface{}{"source": "pubmed", "count": float64(1)},
+			}, result.Items)
+		})
+	}
+}
+
+// countingStorage counts the document reads the API makes
+type countingStorage struct {
+	*storage.HybridStorage
+
+	mu    sync.Mutex
+	gets  int
+	lists int
+}
+
+func (s *countingStorage) GetDocument(ctx context.Context, id string) (*document.Document, error) {
+	s.mu.Lock()
+	s.gets++
+	s.mu.Unlock()
+	return s.HybridStorage.GetDocument(ctx, id)
+}
+
+func (s *countingStorage) ListDocuments(ctx context.Context, filters map[string]string) ([]*document.Document, error) {
+	s.mu.Lock()
+	s.lists++
+	s.mu.Unlock()
+	return s.HybridStorage.ListDocuments(ctx, filters)
+}
+
+func (s *countingStorage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets, s.lists = 0, 0
+}
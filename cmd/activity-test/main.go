@@ -77,11 +77,12 @@ func main() {
 	fmt.Println("\n🔢 Test 3: GenerateEmbeddingsActivity...")
 	
 	testText := "This is a test document about machine learning and artificial intelligence."
-	embeddings, err := activities.GenerateEmbeddingsActivity(ctx, []byte(testText))
+	embedResult, err := activities.GenerateEmbeddingsActivity(ctx, []byte(testText))
+	embeddings := embedResult.Vector
 	if err != nil {
 		fmt.Printf("❌ GenerateEmbeddingsActivity failed: %v\n", err)
 	} else {
-		fmt.Printf("✅ Generated %d-dimensional embeddings\n", len(embeddings))
+		fmt.Printf("✅ Generated %d-dimensional embeddings with %s\n", len(embeddings), embedResult.Model)
 		if len(embeddings) > 0 {
 			fmt.Printf("   First 5 values: %v\n", embeddings[:min(5, len(embeddings))])
 		}
@@ -128,7 +129,7 @@ func main() {
 	// Test 5: IndexDocumentActivity
 	fmt.Println("\n🗂️  Test 5: IndexDocumentActivity...")
 	
-	err = activities.IndexDocumentActivity(ctx, commitHash)
+	_, err = activities.IndexDocumentActivity(ctx, commitHash)
 	if err != nil {
 		fmt.Printf("❌ IndexDocumentActivity failed: %v\n", err)
 	} else {
@@ -168,7 +169,7 @@ func main() {
 	
 	academicCollector := activities.NewAcademicCollectorActivities()
 	
-	academicSources, err := academicCollector.CollectAcademicSourcesActivity(ctx, workflows.ScheduledIngestionInput{
+	academicResult, err := academicCollector.CollectAcademicSourcesActivity(ctx, workflows.ScheduledIngestionInput{
 		Name: "arxiv",
 		Type: "api",
 		URL:  "https://export.arxiv.org/api/query",
@@ -180,8 +181,8 @@ func main() {
 	if err != nil {
 		fmt.Printf("❌ CollectAcademicSourcesActivity failed: %v\n", err)
 	} else {
-		fmt.Printf("✅ Found %d academic sources\n", len(academicSources))
-		for i, source := range academicSources {
+		fmt.Printf("✅ Found %d academic sources\n", len(academicResult.Documents))
+		for i, source := range academicResult.Documents {
 			fmt.Printf("   [%d] ID: %s, URL: %s\n", i+1, source.ID, source.URL)
 		}
 	}
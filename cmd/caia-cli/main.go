@@ -50,6 +50,9 @@ func main() {
 		urls := parseURLList(os.Args[2])
 		batchIngest(urls)
 
+	case "replay":
+		replayCommand(os.Args[2:])
+
 	default:
 		showHelp()
 	}
@@ -228,14 +231,20 @@ func showHelp() {
 	fmt.Println("  batch <url1,url2,url3>  - Batch ingest multiple documents")
 	fmt.Println("  list                    - List recent workflows")
 	fmt.Println("  show <workflow-id>      - Show workflow details")
+	fmt.Println("  replay start [options]  - Republish stored documents as document.added events")
+	fmt.Println("  replay status [id]      - Show replay progress")
+	fmt.Println("  replay resume <id>      - Resume a stopped replay")
+	fmt.Println("  replay cancel <id>      - Cancel a running replay")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  caia-cli ingest https://go.dev html")
 	fmt.Println("  caia-cli batch https://go.dev,https://golang.org")
 	fmt.Println("  caia-cli show cli-ingest-1234567890")
+	fmt.Println("  caia-cli replay start -since 2026-01-01 -type html -rate 10")
 	fmt.Println("")
 	fmt.Println("Requirements:")
 	fmt.Println("  - Temporal server running on localhost:7233")
 	fmt.Println("  - CAIA Library worker running")
+	fmt.Println("  - replay: CAIA Library server at CAIA_API_URL (default http://localhost:8080)")
 	fmt.Println("")
 }
\ No newline at end of file
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline/replay"
+)
+
+// replayCommand runs `caia-cli replay <start|status|resume|cancel>` against
+// the server's admin API
+func replayCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Println("❌ Usage: caia-cli replay <start|status|resume|cancel> [options]")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "start":
+		startReplay(args[1:])
+	case "status":
+		if len(args) < 2 {
+			var list struct {
+				Replays []replay.Job `json:"replays"`
+			}
+			callReplayAPI(http.MethodGet, "", nil, &list)
+			if len(list.Replays) == 0 {
+				fmt.Println("📋 No replays")
+			}
+			for _, job := range list.Replays {
+				printReplay(job)
+			}
+			return
+		}
+		var job replay.Job
+		callReplayAPI(http.MethodGet, "/"+args[1], nil, &job)
+		printReplay(job)
+	case "resume", "cancel":
+		if len(args) < 2 {
+			fmt.Printf("❌ Usage: caia-cli replay %s <replay-id>\n", args[0])
+			os.Exit(1)
+		}
+		var job replay.Job
+		callReplayAPI(http.MethodPost, "/"+args[1]+"/"+args[0], nil, &job)
+		printReplay(job)
+	default:
+		fmt.Printf("❌ Unknown replay command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func startReplay(args []string) {
+	flags := flag.NewFlagSet("replay start", flag.ExitOnError)
+	since := flags.String("since", "", "replay documents created at or after this time (RFC 3339 or YYYY-MM-DD)")
+	until := flags.String("until", "", "replay documents created before this time (RFC 3339 or YYYY-MM-DD)")
+	docType := flags.String("type", "", "replay only documents of this source type")
+	source := flags.String("source", "", "replay only documents from this source URL")
+	rate := flags.Float64("rate", replay.DefaultRate, "events published per second")
+	limit := flags.Int("limit", 0, "replay at most this many documents")
+	wait := flags.Bool("wait", false, "wait for the replay to finish")
+	flags.Parse(args)
+
+	req := replay.Request{Rate: *rate, Limit: *limit}
+	var err error
+	if req.Since, err = parseReplayTime(*since); err != nil {
+		fmt.Printf("❌ Invalid -since: %v\n", err)
+		os.Exit(1)
+	}
+	if req.Until, err = parseReplayTime(*until); err != nil {
+		fmt.Printf("❌ Invalid -until: %v\n", err)
+		os.Exit(1)
+	}
+	if *docType != "" || *source != "" {
+		req.Filters = map[string]string{}
+		if *docType != "" {
+			req.Filters["type"] = *docType
+		}
+		if *source != "" {
+			req.Filters["source"] = *source
+		}
+	}
+
+	var job replay.Job
+	callReplayAPI(http.MethodPost, "", req, &job)
+	fmt.Printf("✅ Replay started: %s\n", job.ID)
+	if !*wait {
+		fmt.Printf("   Follow it with: caia-cli replay status %s\n", job.ID)
+		return
+	}
+
+	for job.State == replay.StateRunning {
+		time.Sleep(2 * time.Second)
+		callReplayAPI(http.MethodGet, "/"+job.ID, nil, &job)
+		fmt.Printf("   %d/%d documents published\n", job.Published, job.Total)
+	}
+	printReplay(job)
+	if job.State != replay.StateCompleted {
+		os.Exit(1)
+	}
+}
+
+// parseReplayTime reads a time given as RFC 3339 or as a date; empty means
+// no bound
+func parseReplayTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD, got %q", value)
+}
+
+// callReplayAPI sends a request to the replay endpoints and decodes the
+// response into out, exiting on failure
+func callReplayAPI(method, path string, body, out interface{}) {
+	baseURL := strings.TrimSuffix(os.Getenv("CAIA_API_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			fmt.Printf("❌ Failed to encode request: %v\n", err)
+			os.Exit(1)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, baseURL+"/api/v1/admin/replays"+path, reader)
+	if err != nil {
+		fmt.Printf("❌ Failed to create request: %v\n", err)
+		os.Exit(1)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Printf("❌ Failed to reach the CAIA Library server: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Printf("❌ Failed to read response: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(data, &apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		fmt.Printf("❌ %s\n", apiErr.Error)
+		os.Exit(1)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		fmt.Printf("❌ Failed to decode response: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printReplay(job replay.Job) {
+	fmt.Printf("🔁 %s: %s, %d/%d documents published\n", job.ID, job.State, job.Published, job.Total)
+	if job.Cursor != nil {
+		fmt.Printf("   Last document: %s (created %s)\n", job.Cursor.DocumentID, job.Cursor.CreatedAt.Format(time.RFC3339))
+	}
+	if job.Error != "" {
+		fmt.Printf("   Error: %s\n", job.Error)
+	}
+}
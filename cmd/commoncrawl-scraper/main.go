@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -15,6 +14,7 @@ import (
 	"github.com/Caia-Tech/caia-library/pkg/extractor"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
 	"github.com/Caia-Tech/caia-library/pkg/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
 )
 
 // CommonCrawlRecord represents a WARC record from Common Crawl
@@ -99,7 +99,7 @@ func main() {
 	
 	// Try multiple query patterns - use domain-based queries that are more likely to work
 	queries := []string{"go.dev/*", "golang.org/*", "github.com/golang/*"}
-	var allUrls []map[string]string
+	var allUrls []warc.CDXEntry
 	
 	for _, query := range queries {
 		fmt.Printf("   • Trying query: %s\n", query)
@@ -187,114 +187,59 @@ func getCommonCrawlIndex() (string, error) {
 	return crawlID, nil
 }
 
-func queryCommonCrawlIndex(crawlID, query string) ([]map[string]string, error) {
+func queryCommonCrawlIndex(crawlID, query string) ([]warc.CDXEntry, error) {
 	// Use the CDX Server API for querying Common Crawl
-	queryURL := fmt.Sprintf("https://index.commoncrawl.org/%s-index?url=%s&output=json&limit=5", crawlID, query)
+	endpoint := fmt.Sprintf("https://index.commoncrawl.org/%s-index", crawlID)
+	fmt.Printf("     Querying: %s?url=%s\n", endpoint, query)
 
-	fmt.Printf("     Querying: %s\n", queryURL)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(queryURL)
+	client := warc.NewCDXClient(endpoint, &http.Client{Timeout: 30 * time.Second})
+	entries, err := client.Lookup(context.Background(), query, 50)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query index: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("index query failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var results []map[string]string
-	scanner := bufio.NewScanner(resp.Body)
-	count := 0
-
-	for scanner.Scan() && count < 5 { // Limit results per query
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		var record map[string]string
-		if err := json.Unmarshal([]byte(line), &record); err != nil {
-			fmt.Printf("     ⚠️  Failed to parse record: %v\n", err)
-			continue
-		}
 
+	var results []warc.CDXEntry
+	for _, entry := range entries {
 		// Basic filtering for content that might be useful
-		if record["status"] == "200" && 
-		   (record["mime"] == "text/html" || strings.Contains(record["mime"], "text/")) {
-			results = append(results, record)
-			count++
-			fmt.Printf("     • Found: %s (mime: %s)\n", record["url"], record["mime"])
+		if entry.Status == "200" && strings.HasPrefix(entry.MIME, "text/") {
+			results = append(results, entry)
+			fmt.Printf("     • Found: %s (mime: %s)\n", entry.URL, entry.MIME)
+		}
+		if len(results) >= 5 { // Limit results per query
+			break
 		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading index results: %w", err)
 	}
 
 	return results, nil
 }
 
-func fetchWARCRecords(indexResults []map[string]string) ([]CommonCrawlRecord, error) {
+func fetchWARCRecords(indexResults []warc.CDXEntry) ([]CommonCrawlRecord, error) {
 	var records []CommonCrawlRecord
 	client := &http.Client{Timeout: 30 * time.Second}
 
 	for i, result := range indexResults {
 		fmt.Printf("   [%d/%d] Fetching WARC record...\n", i+1, len(indexResults))
 
-		filename := result["filename"]
-		offset := result["offset"]
-		length := result["length"]
-
-		if filename == "" || offset == "" || length == "" {
+		if result.Filename == "" || result.Length <= 0 {
 			continue
 		}
 
-		// Construct WARC URL
-		warcURL := fmt.Sprintf("https://commoncrawl.s3.amazonaws.com/%s", filename)
-
-		// Create range request
-		req, err := http.NewRequest("GET", warcURL, nil)
-		if err != nil {
-			fmt.Printf("   ❌ Failed to create request: %v\n", err)
-			continue
-		}
-
-		// Calculate end position for range header
-		offsetInt := 0
-		lengthInt := 0
-		fmt.Sscanf(offset, "%d", &offsetInt)
-		fmt.Sscanf(length, "%d", &lengthInt)
-		endPos := offsetInt + lengthInt - 1
-		
-		// Set range header to get specific record
-		rangeHeader := fmt.Sprintf("bytes=%d-%d", offsetInt, endPos)
-		req.Header.Set("Range", rangeHeader)
-
-		resp, err := client.Do(req)
+		// Fetch just this record with a range request
+		warcURL := result.Location(warc.CommonCrawlDataURL)
+		warcRecord, err := warc.FetchRecord(context.Background(), client, warcURL, result.Offset, result.Length)
 		if err != nil {
 			fmt.Printf("   ❌ Failed to fetch WARC: %v\n", err)
 			continue
 		}
 
-		content, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		if err != nil {
-			fmt.Printf("   ❌ Failed to read WARC content: %v\n", err)
-			continue
-		}
-
 		// Parse WARC record
-		record, err := parseWARCRecord(content, result)
+		record, err := parseWARCRecord(warcRecord, result)
 		if err != nil {
 			fmt.Printf("   ⚠️  Failed to parse WARC: %v\n", err)
 			continue
 		}
 
-		if record != nil && len(record.Content) > 500 {
+		if len(record.Content) > 500 {
 			records = append(records, *record)
 			fmt.Printf("   ✅ Parsed %d bytes of content\n", len(record.Content))
 		}
@@ -306,86 +251,36 @@ func fetchWARCRecords(indexResults []map[string]string) ([]CommonCrawlRecord, er
 	return records, nil
 }
 
-func parseWARCRecord(data []byte, indexResult map[string]string) (*CommonCrawlRecord, error) {
-	contentStr := string(data)
-	
-	// Debug: show what we got
-	fmt.Printf("   📋 Content type: %s (length: %d)\n", detectContentType(data), len(data))
-	
-	// Handle different response types
-	if strings.HasPrefix(contentStr, "<?xml") {
-		// This is likely an error response or API response
-		return nil, fmt.Errorf("received XML response instead of WARC data")
-	}
-
-	// Try to find WARC-Type header to confirm this is a WARC record
-	if !strings.Contains(contentStr, "WARC/") {
-		// Maybe it's direct HTML content - let's try to use it anyway
-		if strings.Contains(contentStr, "<html") || strings.Contains(contentStr, "<HTML") || 
-		   strings.Contains(contentStr, "<body") || strings.Contains(contentStr, "<div") {
-			fmt.Printf("   ✅ Found HTML content directly\n")
-			record := &CommonCrawlRecord{
-				URL:         indexResult["url"],
-				Timestamp:   indexResult["timestamp"],
-				Content:     contentStr,
-				Filename:    indexResult["filename"],
-				ContentType: "text/html",
-			}
-			return record, nil
-		}
-		return nil, fmt.Errorf("not a WARC record and no HTML found")
-	}
-
-	// Parse proper WARC record
-	sections := strings.Split(contentStr, "\r\n\r\n")
-	if len(sections) < 3 {
-		sections = strings.Split(contentStr, "\n\n")
+// parseWARCRecord reads the archived HTTP response of a record
+func parseWARCRecord(warcRecord *warc.Record, indexResult warc.CDXEntry) (*CommonCrawlRecord, error) {
+	payload, err := warc.ReadPayload(warcRecord, 10*1024*1024)
+	if err != nil {
+		return nil, err
 	}
-	
-	if len(sections) < 3 {
-		return nil, fmt.Errorf("malformed WARC record")
+	if payload.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("archived response has status %d", payload.StatusCode)
 	}
 
-	// sections[0] = WARC headers
-	// sections[1] = HTTP headers  
-	// sections[2+] = HTML content
-	
-	htmlContent := strings.Join(sections[2:], "\n\n")
-	
 	// Validation
-	if len(htmlContent) < 100 {
+	if len(payload.Body) < 100 {
 		return nil, fmt.Errorf("content too small")
 	}
 
 	record := &CommonCrawlRecord{
-		URL:         indexResult["url"],
-		Timestamp:   indexResult["timestamp"],
-		Content:     htmlContent,
-		Filename:    indexResult["filename"],
-		ContentType: "text/html",
+		URL:           indexResult.URL,
+		Timestamp:     indexResult.Timestamp,
+		ContentType:   payload.ContentType,
+		ContentLength: len(payload.Body),
+		Content:       string(payload.Body),
+		StatusCode:    payload.StatusCode,
+		Filename:      indexResult.Filename,
+		Offset:        int(indexResult.Offset),
+		Length:        int(indexResult.Length),
 	}
 
 	return record, nil
 }
 
-func detectContentType(data []byte) string {
-	if len(data) == 0 {
-		return "empty"
-	}
-	
-	s := string(data[:min(100, len(data))])
-	if strings.HasPrefix(s, "<?xml") {
-		return "xml"
-	}
-	if strings.Contains(s, "WARC/") {
-		return "warc"
-	}
-	if strings.Contains(s, "<html") || strings.Contains(s, "<HTML") {
-		return "html"
-	}
-	return "unknown"
-}
-
 func extractTextFromRecords(records []CommonCrawlRecord) []CommonCrawlRecord {
 	extractorEngine := extractor.NewEngine()
 	var processed []CommonCrawlRecord
@@ -13,6 +13,7 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/procurement/scraping"
 	"github.com/Caia-Tech/caia-library/internal/procurement/quality"
+	"github.com/Caia-Tech/caia-library/internal/procurement/quality/sandbox"
 	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/Caia-Tech/caia-library/pkg/extractor"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
@@ -67,6 +68,9 @@ type GolangContent struct {
 }
 
 func main() {
+	// Code samples are validated in a sandbox that re-executes this binary
+	sandbox.Init()
+
 	fmt.Println("🔄 GOLANG.ORG CURATE & CONVERT TO CONVERSATIONAL JSON")
 	fmt.Println("===================================================")
 	fmt.Println("Complete pipeline: Curate golang.org docs → Convert to LLM conversational JSON")
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/procurement/scraping"
 	"github.com/Caia-Tech/caia-library/internal/procurement/quality"
+	"github.com/Caia-Tech/caia-library/internal/procurement/quality/sandbox"
 	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/Caia-Tech/caia-library/pkg/extractor"
@@ -27,6 +28,9 @@ type GolangContent struct {
 }
 
 func main() {
+	// Code samples are validated in a sandbox that re-executes this binary
+	sandbox.Init()
+
 	fmt.Println("🐹 GOLANG.ORG DATA CURATION")
 	fmt.Println("===========================")
 	fmt.Println("Curating comprehensive Go documentation and resources")
@@ -14,6 +14,7 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/procurement/scraping"
 	"github.com/Caia-Tech/caia-library/internal/procurement/quality"
+	"github.com/Caia-Tech/caia-library/internal/procurement/quality/sandbox"
 	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/Caia-Tech/caia-library/pkg/extractor"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
@@ -73,6 +74,9 @@ type DatasetMetadata struct {
 }
 
 func main() {
+	// Code samples are validated in a sandbox that re-executes this binary
+	sandbox.Init()
+
 	fmt.Println("🌐 DIVERSE HIGH-VALUE DATA SCRAPER")
 	fmt.Println("==================================")
 	fmt.Println("Ethically collecting high-quality content across multiple domains for general LLM training")
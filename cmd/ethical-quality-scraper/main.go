@@ -110,9 +110,11 @@ func main() {
 	w.RegisterWorkflow(workflows.DocumentIngestionWorkflow)
 	w.RegisterWorkflow(workflows.BatchIngestionWorkflow)
 	w.RegisterActivity(activities.FetchDocumentActivity)
+	w.RegisterActivity(activities.FetchWARCRecordActivity)
 	w.RegisterActivity(activities.ExtractTextActivity)
 	w.RegisterActivity(activities.GenerateEmbeddingsActivity)
 	w.RegisterActivity(activities.StoreDocumentActivity)
+	w.RegisterActivity(activities.CheckContentDuplicateActivity)
 	w.RegisterActivity(activities.IndexDocumentActivity)
 	w.RegisterActivity(activities.MergeBranchActivity)
 
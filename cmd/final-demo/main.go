@@ -12,6 +12,7 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/procurement"
 	"github.com/Caia-Tech/caia-library/internal/procurement/quality"
+	"github.com/Caia-Tech/caia-library/internal/procurement/quality/sandbox"
 	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/Caia-Tech/caia-library/pkg/extractor"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
@@ -20,6 +21,9 @@ import (
 
 // ComprehensiveDemo showcases the complete CAIA Library functionality
 func main() {
+	// Code samples are validated in a sandbox that re-executes this binary
+	sandbox.Init()
+
 	fmt.Println("🌟 CAIA LIBRARY COMPREHENSIVE DEMONSTRATION")
 	fmt.Println("===========================================")
 	fmt.Println("Complete end-to-end data processing pipeline")
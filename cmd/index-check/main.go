@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		showHelp()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	repoName := os.Args[2]
+
+	config := storage.GetGovcConfig()
+	if len(os.Args) > 3 {
+		config.IndexPath = os.Args[3]
+	}
+	if config.IndexPath == "" {
+		fmt.Println("❌ No index path given and GOVC_INDEX_PATH is not set")
+		os.Exit(1)
+	}
+
+	backend, err := storage.NewGovcBackendWithConfig(repoName, config, nil)
+	if err != nil {
+		fmt.Printf("❌ Failed to open repository: %v\n", err)
+		os.Exit(1)
+	}
+	defer backend.Close()
+
+	switch command {
+	case "verify":
+		if !verifyIndex(backend) {
+			os.Exit(2)
+		}
+
+	case "repair":
+		if verifyIndex(backend) {
+			return
+		}
+		fmt.Println("\n🔧 Rebuilding document index from repository...")
+		if err := backend.RebuildIndex(); err != nil {
+			fmt.Printf("❌ Rebuild failed: %v\n", err)
+			os.Exit(1)
+		}
+		if !verifyIndex(backend) {
+			os.Exit(2)
+		}
+
+	default:
+		showHelp()
+		os.Exit(1)
+	}
+}
+
+func verifyIndex(backend *storage.GovcBackend) bool {
+	report, err := backend.VerifyIndex()
+	if err != nil {
+		fmt.Printf("❌ Consistency check failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("🔍 Document Index Consistency Check")
+	fmt.Println("===================================")
+	fmt.Printf("Indexed documents:    %d\n", report.IndexedCount)
+	fmt.Printf("Repository documents: %d\n", report.RepositoryCount)
+
+	printIDs("Missing from index", report.MissingFromIndex)
+	printIDs("Stale in index", report.StaleInIndex)
+	printIDs("Path mismatches", report.PathMismatches)
+
+	if report.Consistent() {
+		fmt.Println("✅ Index is consistent with the repository")
+		return true
+	}
+	fmt.Println("⚠️  Index is inconsistent with the repository")
+	return false
+}
+
+func printIDs(label string, ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	fmt.Printf("\n%s (%d):\n", label, len(ids))
+	for _, id := range ids {
+		fmt.Printf("  - %s\n", id)
+	}
+}
+
+func showHelp() {
+	fmt.Println(`🔍 CAIA Library Index Check
+
+Usage:
+  index-check verify <repo-name> [index-path]   Compare the persisted index with the repository
+  index-check repair <repo-name> [index-path]   Rebuild the index if it is inconsistent
+
+The index path defaults to GOVC_INDEX_PATH. Exit status is 2 when the index
+is inconsistent after the command completes.`)
+}
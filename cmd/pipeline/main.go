@@ -38,6 +38,11 @@ func main() {
 		logger.Fatal().Err(err).Msg("Configuration validation failed")
 	}
 	
+	// The durable event log is opt-in; events stay in memory otherwise
+	if eventLogPath := os.Getenv("GOVC_EVENT_LOG_PATH"); eventLogPath != "" {
+		config.DataPaths.EventLogPath = eventLogPath
+	}
+	
 	// Setup directories
 	logger.Info().Msg("Setting up directories")
 	if err := pipeline.SetupDirectories(config); err != nil {
@@ -58,6 +63,8 @@ func main() {
 	// Initialize storage system
 	logger.Info().Msg("Initializing storage system")
 	metricsCollector := storage.NewSimpleMetricsCollector()
+	config.Storage.IndexPath = config.DataPaths.IndexPath
+	config.Storage.EventLogPath = config.DataPaths.EventLogPath
 	
 	hybridStorage, err := storage.NewHybridStorage(
 		config.DataPaths.GitRepo,
@@ -2,15 +2,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/Caia-Tech/caia-library/internal/api"
+	"github.com/Caia-Tech/caia-library/internal/pipeline/replay"
+	"github.com/Caia-Tech/caia-library/internal/pipeline/sinks"
+	"github.com/Caia-Tech/caia-library/internal/presentation"
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
 	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/internal/temporal/activities"
 	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
+	"github.com/Caia-Tech/caia-library/pkg/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -49,6 +59,156 @@ func main() {
 	
 	// Set global storage for activities
 	activities.SetGlobalStorage(hybridStorage, metricsCollector)
+	
+	// Build the content dedup index and keep it current from storage events,
+	// so deleted documents stop counting as duplicates
+	dedupIndex := storage.NewDedupIndex(hybridStorage, dedup.DefaultConfig())
+	if _, err := dedupIndex.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to build dedup index: %v", err)
+	}
+	if eventBus := hybridStorage.GetEventBus(); eventBus != nil {
+		if err := dedupIndex.Subscribe(eventBus); err != nil {
+			log.Printf("Dedup index will not follow document changes: %v", err)
+		}
+	}
+	defer dedupIndex.Close()
+	activities.SetGlobalDedupIndex(dedupIndex.Index())
+	
+	// Remember validators of fetched URLs so re-fetches are conditional
+	fetchValidators, err := conditional.OpenStore(getEnv("FETCH_VALIDATORS_PATH", "./data/fetch-validators.jsonl"))
+	if err != nil {
+		log.Fatalf("Failed to open fetch validators: %v", err)
+	}
+	defer fetchValidators.Close()
+	activities.SetGlobalFetchValidators(fetchValidators)
+	
+	// Capture every fetch to WARC files when a directory is configured
+	if warcDir := getEnv("WARC_CAPTURE_DIR", ""); warcDir != "" {
+		warcWriter, err := warc.OpenFileWriter(warcDir, "caia", 0, warc.Header{
+			{Name: "software", Value: "CAIA-Library/1.0"},
+		})
+		if err != nil {
+			log.Fatalf("Failed to open WARC capture: %v", err)
+		}
+		defer warcWriter.Close()
+		activities.SetGlobalWARCWriter(warcWriter)
+		log.Printf("Capturing fetches to WARC files in %s", warcDir)
+	}
+	
+	// Record the domain policy each fetched document is collected under
+	policies, err := policy.Load(getEnv("DOMAIN_POLICIES_PATH", "./configs/domain_policies.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to load domain policies: %v", err)
+	}
+	activities.SetGlobalPolicies(policies)
+	
+	// Initialize the embedding provider selected by the environment
+	pipelineConfig := pipeline.DefaultPipelineConfig()
+	pipelineConfig.Embedding.Provider = getEnv("EMBEDDING_PROVIDER", pipelineConfig.Embedding.Provider)
+	pipelineConfig.Embedding.ModelPath = getEnv("EMBEDDING_MODEL_PATH", pipelineConfig.Embedding.ModelPath)
+	pipelineConfig.Embedding.Endpoint = getEnv("EMBEDDING_ENDPOINT", "")
+	pipelineConfig.Embedding.Model = getEnv("EMBEDDING_MODEL", "")
+	pipelineConfig.Embedding.API = getEnv("EMBEDDING_API", pipelineConfig.Embedding.API)
+	pipelineConfig.Embedding.APIKey = getEnv("EMBEDDING_API_KEY", "")
+	
+	embeddingEngine, err := pipeline.InitializeEmbedder(pipelineConfig, func() ([]string, error) {
+		docs, err := hybridStorage.ListDocuments(context.Background(), nil)
+		if err != nil {
+			return nil, err
+		}
+		texts := make([]string, 0, len(docs))
+		for _, doc := range docs {
+			texts = append(texts, doc.Content.Text)
+		}
+		return texts, nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create embedding engine: %v", err)
+	}
+	activities.SetGlobalEmbedder(embeddingEngine)
+	
+	// Build the vector index and keep it current from storage events
+	vectorConfig := storage.DefaultVectorIndexConfig()
+	vectorConfig.Type = getEnv("VECTOR_INDEX_TYPE", storage.VectorIndexFlat)
+	vectorSearcher, err := storage.NewVectorSearcher(hybridStorage, vectorConfig)
+	if err != nil {
+		log.Fatalf("Failed to create vector index: %v", err)
+	}
+	vectorSearcher.ExpectModel(embeddingEngine.Model())
+	if _, err := vectorSearcher.Load(context.Background()); err != nil {
+		log.Printf("Failed to load vector index: %v", err)
+	}
+	if eventBus := hybridStorage.GetEventBus(); eventBus != nil {
+		if err := vectorSearcher.Subscribe(eventBus); err != nil {
+			log.Printf("Vector index will not follow document changes: %v", err)
+		}
+	}
+	defer vectorSearcher.Close()
+	
+	// Build the full-text index the same way
+	textSearcher := storage.NewTextSearcher(hybridStorage, nil)
+	if _, err := textSearcher.Load(context.Background()); err != nil {
+		log.Printf("Failed to load text index: %v", err)
+	}
+	if eventBus := hybridStorage.GetEventBus(); eventBus != nil {
+		if err := textSearcher.Subscribe(eventBus); err != nil {
+			log.Printf("Text index will not follow document changes: %v", err)
+		}
+	}
+	defer textSearcher.Close()
+	
+	// Serve the presentation API from storage when a port is configured
+	if port := os.Getenv("PRESENTATION_PORT"); port != "" {
+		presentationPort, err := strconv.Atoi(port)
+		if err != nil {
+			log.Fatalf("Invalid PRESENTATION_PORT %q: %v", port, err)
+		}
+		presentationAPI := presentation.NewAPI(presentation.NewRenderer(nil), presentation.NewBackendStorage(hybridStorage), &presentation.APIConfig{
+			Port:            presentationPort,
+			Host:            getEnv("PRESENTATION_HOST", "0.0.0.0"),
+			BasePath:        "/api/v1",
+			EnableCORS:      true,
+			RateLimitPerMin: 100,
+		})
+		presentationAPI.SetTextIndex(textSearcher.Index())
+		presentationAPI.SetSemanticSearch(presentation.NewEmbeddingSearch(vectorSearcher, embeddingEngine))
+		go func() {
+			if err := presentationAPI.Start(); err != nil {
+				log.Printf("Presentation API stopped: %v", err)
+			}
+		}()
+	}
+	
+	// Replay stored documents onto the event bus on request
+	var replays *replay.Manager
+	if eventBus := hybridStorage.GetEventBus(); eventBus != nil {
+		replays, err = replay.NewManager(hybridStorage, eventBus, getEnv("REPLAY_STATE_PATH", "./data/replays.json"))
+		if err != nil {
+			log.Fatalf("Failed to load replay progress: %v", err)
+		}
+		defer replays.Close()
+	}
+	
+	// Forward document events to webhooks and streaming clients
+	var eventStream *sinks.Stream
+	var webhooks *sinks.Dispatcher
+	if eventBus := hybridStorage.GetEventBus(); eventBus != nil {
+		webhooks, err = sinks.NewDispatcher(getEnv("WEBHOOKS_PATH", "./data/webhooks.json"), sinks.DefaultWebhookConfig())
+		if err != nil {
+			log.Fatalf("Failed to load webhooks: %v", err)
+		}
+		if err := webhooks.Subscribe(eventBus); err != nil {
+			log.Fatalf("Failed to start webhooks: %v", err)
+		}
+		defer webhooks.Close()
+		
+		eventStream = sinks.NewStream(sinks.DefaultStreamHistory)
+		if err := eventStream.Subscribe(eventBus); err != nil {
+			log.Fatalf("Failed to start event stream: %v", err)
+		}
+		defer eventStream.Close()
+	}
+	
 
 	// Create worker for Temporal workflows
 	w := worker.New(temporalClient, "caia-library", worker.Options{
@@ -60,14 +220,18 @@ func main() {
 	w.RegisterWorkflow(workflows.DocumentIngestionWorkflow)
 	w.RegisterWorkflow(workflows.ScheduledIngestionWorkflow)
 	w.RegisterWorkflow(workflows.BatchIngestionWorkflow)
+	w.RegisterWorkflow(workflows.WARCIngestionWorkflow)
 	
 	// Register all activities
 	w.RegisterActivity(activities.FetchDocumentActivity)
 	w.RegisterActivity(activities.ExtractTextActivity)
 	w.RegisterActivity(activities.GenerateEmbeddingsActivity)
 	w.RegisterActivity(activities.StoreDocumentActivity)
+	w.RegisterActivity(activities.CheckContentDuplicateActivity)
 	w.RegisterActivity(activities.IndexDocumentActivity)
 	w.RegisterActivity(activities.MergeBranchActivity)
+	w.RegisterActivity(activities.FetchWARCRecordActivity)
+	w.RegisterActivity(activities.ListWARCRecordsActivity)
 	
 	// Register collector activities
 	collector := activities.NewCollectorActivities()
@@ -76,7 +240,13 @@ func main() {
 	
 	// Register academic collector activities
 	academicCollector := activities.NewAcademicCollectorActivities()
+	academicCursors, err := activities.LoadCollectionCursors(getEnv("ACADEMIC_CURSORS_PATH", "./data/academic-cursors.json"))
+	if err != nil {
+		log.Fatalf("Failed to load academic collection cursors: %v", err)
+	}
+	academicCollector.UseCursors(academicCursors)
 	w.RegisterActivity(academicCollector.CollectAcademicSourcesActivity)
+	w.RegisterActivity(academicCollector.AdvanceCollectionCursorActivity)
 
 	// Start worker in background
 	go func() {
@@ -119,13 +289,26 @@ func main() {
 	}))
 
 	// Initialize handlers
-	h := api.NewHandlers(temporalClient, repoPath)
+	h := api.NewHandlers(temporalClient, repoPath, hybridStorage)
+	h.SetTextSearcher(textSearcher)
 	
 	// Initialize storage handler for monitoring
 	storageHandler := api.NewStorageHandler(hybridStorage, metricsCollector)
+	
+	// Initialize search handler
+	searchHandler := api.NewSearchHandler(vectorSearcher, embeddingEngine)
+	
+	// Initialize policy handler
+	policyHandler := api.NewPolicyHandler(policies)
+	
+	// Initialize replay handler
+	replayHandler := api.NewReplayHandler(replays)
+	
+	// Initialize events handler
+	eventsHandler := api.NewEventsHandler(eventStream, webhooks)
 
 	// API Routes
-	setupRoutes(app, h, storageHandler)
+	setupRoutes(app, h, storageHandler, searchHandler, policyHandler, replayHandler, eventsHandler)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -134,6 +317,10 @@ func main() {
 	go func() {
 		<-quit
 		log.Println("Shutting down server...")
+		// Streaming responses only end when their stream does
+		if eventStream != nil {
+			eventStream.Close()
+		}
 		if err := app.Shutdown(); err != nil {
 			log.Printf("Server shutdown error: %v", err)
 		}
@@ -148,7 +335,7 @@ func main() {
 }
 
 // setupRoutes configures all API routes
-func setupRoutes(app *fiber.App, h *api.Handlers, storageHandler *api.StorageHandler) {
+func setupRoutes(app *fiber.App, h *api.Handlers, storageHandler *api.StorageHandler, searchHandler *api.SearchHandler, policyHandler *api.PolicyHandler, replayHandler *api.ReplayHandler, eventsHandler *api.EventsHandler) {
 	// Health check
 	app.Get("/health", h.Health)
 	
@@ -165,6 +352,7 @@ func setupRoutes(app *fiber.App, h *api.Handlers, storageHandler *api.StorageHan
 	ingestion := v1.Group("/ingestion")
 	ingestion.Post("/scheduled", h.CreateScheduledIngestion)
 	ingestion.Post("/batch", h.CreateBatchIngestion)
+	ingestion.Post("/warc", h.CreateWARCIngestion)
 	
 	// Workflow routes
 	workflows := v1.Group("/workflows")
@@ -175,17 +363,46 @@ func setupRoutes(app *fiber.App, h *api.Handlers, storageHandler *api.StorageHan
 	query.Post("/", h.ExecuteQuery)
 	query.Get("/examples", h.GetQueryExamples)
 	
+	// Search routes
+	search := v1.Group("/search")
+	search.Post("/similar", searchHandler.SearchSimilar)
+	
 	// Stats routes
 	stats := v1.Group("/stats")
 	stats.Get("/attribution", h.GetAttributionStats)
 	
+	// Domain policy routes
+	policies := v1.Group("/policies")
+	policies.Get("/", policyHandler.ListPolicies)
+	policies.Get("/:domain", policyHandler.GetPolicy)
+	
 	// Storage monitoring routes
 	storage := v1.Group("/storage")
 	storage.Get("/stats", storageHandler.GetStorageStats)
 	storage.Get("/metrics", storageHandler.GetStorageMetrics)
 	storage.Get("/health", storageHandler.GetStorageHealth)
+	storage.Post("/sync", storageHandler.SyncStorage)
 	storage.Delete("/metrics", storageHandler.ClearMetrics)
 	
+	// Event routes
+	events := v1.Group("/events")
+	events.Get("/stream", eventsHandler.StreamEvents)
+	
+	// Webhook routes
+	webhooks := v1.Group("/webhooks")
+	webhooks.Post("/", eventsHandler.RegisterWebhook)
+	webhooks.Get("/", eventsHandler.ListWebhooks)
+	webhooks.Get("/:id", eventsHandler.GetWebhook)
+	webhooks.Delete("/:id", eventsHandler.DeleteWebhook)
+	
+	// Admin routes
+	replays := v1.Group("/admin/replays")
+	replays.Post("/", replayHandler.StartReplay)
+	replays.Get("/", replayHandler.ListReplays)
+	replays.Get("/:id", replayHandler.GetReplay)
+	replays.Post("/:id/resume", replayHandler.ResumeReplay)
+	replays.Post("/:id/cancel", replayHandler.CancelReplay)
+	
 	// Root redirect
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -81,9 +81,11 @@ func main() {
 	
 	w.RegisterWorkflow(workflows.DocumentIngestionWorkflow)
 	w.RegisterActivity(activities.FetchDocumentActivity)
+	w.RegisterActivity(activities.FetchWARCRecordActivity)
 	w.RegisterActivity(activities.ExtractTextActivity)
 	w.RegisterActivity(activities.GenerateEmbeddingsActivity)
 	w.RegisterActivity(activities.StoreDocumentActivity)
+	w.RegisterActivity(activities.CheckContentDuplicateActivity)
 	w.RegisterActivity(activities.IndexDocumentActivity)
 	w.RegisterActivity(activities.MergeBranchActivity)
 
@@ -67,9 +67,11 @@ func main() {
 
 	// Register activities  
 	w.RegisterActivity(activities.FetchDocumentActivity)
+	w.RegisterActivity(activities.FetchWARCRecordActivity)
 	w.RegisterActivity(activities.ExtractTextActivity)
 	w.RegisterActivity(activities.GenerateEmbeddingsActivity)
 	w.RegisterActivity(activities.StoreDocumentActivity)
+	w.RegisterActivity(activities.CheckContentDuplicateActivity)
 	w.RegisterActivity(activities.IndexDocumentActivity)
 	w.RegisterActivity(activities.MergeBranchActivity)
 
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Content projections for document reads
+const (
+	// ContentMetadata returns a document's source, timestamps and metadata
+	ContentMetadata = "metadata"
+
+	// ContentText adds the extracted text
+	ContentText = "text"
+
+	// ContentRaw adds the raw bytes as fetched. A single document is then
+	// returned as the bytes themselves; in lists they are base64 encoded.
+	ContentRaw = "raw"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// DocumentResponse is a stored document as returned by the documents API
+type DocumentResponse struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	URL       string            `json:"url"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Text      *string           `json:"text,omitempty"`
+	Raw       []byte            `json:"raw,omitempty"`
+}
+
+// newDocumentResponse projects doc to the requested content
+func newDocumentResponse(doc *document.Document, content string) DocumentResponse {
+	resp := DocumentResponse{
+		ID:        doc.ID,
+		Type:      doc.Source.Type,
+		URL:       doc.Source.URL,
+		Metadata:  doc.Content.Metadata,
+		CreatedAt: doc.CreatedAt,
+		UpdatedAt: doc.UpdatedAt,
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+
+	switch content {
+	case ContentText:
+		resp.Text = &doc.Content.Text
+	case ContentRaw:
+		resp.Text = &doc.Content.Text
+		resp.Raw = doc.Content.Raw
+	}
+	return resp
+}
+
+// parseContent reads the content projection, defaulting to fallback
+func parseContent(value, fallback string) (string, error) {
+	switch value {
+	case "":
+		return fallback, nil
+	case ContentMetadata, ContentText, ContentRaw:
+		return value, nil
+	default:
+		return "", fmt.Errorf("content must be one of %s, %s or %s", ContentMetadata, ContentText, ContentRaw)
+	}
+}
+
+// documentETag identifies a document's representation under a projection
+func documentETag(doc *document.Document, content string) string {
+	h := sha256.New()
+	writeDocumentVersion(h, doc)
+	return fmt.Sprintf(`"%s-%s"`, hex.EncodeToString(h.Sum(nil))[:32], content)
+}
+
+// writeDocumentVersion writes what identifies a version of doc as served:
+// its content, its source type and when it was last updated
+func writeDocumentVersion(w io.Writer, doc *document.Document) {
+	fmt.Fprintf(w, "%s:%s:%s:%d\n", doc.ID, storage.ContentHash(doc), doc.Source.Type, doc.UpdatedAt.UnixNano())
+}
+
+// etagMatches reports whether an If-None-Match header matches etag,
+// comparing weakly as RFC 9110 requires for GET
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header and, when the client already holds that
+// representation, answers 304 and returns true
+func notModified(c *fiber.Ctx, etag string) bool {
+	c.Set(fiber.HeaderETag, etag)
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		c.Status(fiber.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// documentCursor marks the last document of a page. Documents are listed
+// newest first, ties broken by ID.
+type documentCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+func (cur documentCursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*documentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cur documentCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cur, nil
+}
+
+// after reports whether the document at entry comes after the cursor in
+// list order
+func (cur *documentCursor) after(entry documentCursor) bool {
+	if !entry.CreatedAt.Equal(cur.CreatedAt) {
+		return entry.CreatedAt.Before(cur.CreatedAt)
+	}
+	return entry.ID > cur.ID
+}
+
+// sortEntries orders documents for listing, newest first
+func sortEntries(entries []documentCursor) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+}
+
+// documentFilter holds the list filters besides type, which the storage
+// backend applies itself
+type documentFilter struct {
+	Source        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Metadata      map[string]string
+}
+
+// parseDocumentFilter reads source, created_after, created_before and
+// metadata.<key> query parameters. Dates may be RFC 3339 or YYYY-MM-DD.
+func parseDocumentFilter(c *fiber.Ctx) (*documentFilter, error) {
+	filter := &documentFilter{
+		Source:   c.Query("source"),
+		Metadata: make(map[string]string),
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseFilterTime(c.Query("created_after")); err != nil {
+		return nil, fmt.Errorf("invalid created_after: %w", err)
+	}
+	if filter.CreatedBefore, err = parseFilterTime(c.Query("created_before")); err != nil {
+		return nil, fmt.Errorf("invalid created_before: %w", err)
+	}
+
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if name, ok := strings.CutPrefix(string(key), "metadata."); ok && name != "" {
+			filter.Metadata[name] = string(value)
+		}
+	})
+	return filter, nil
+}
+
+func parseFilterTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// matchesCreated reports whether a document created at createdAt is in
+// the filter's date range
+func (f *documentFilter) matchesCreated(createdAt time.Time) bool {
+	if !f.CreatedAfter.IsZero() && !createdAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !createdAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// needsDocument reports whether the filter reads fields that only a loaded
+// document has
+func (f *documentFilter) needsDocument() bool {
+	return f.Source != "" || len(f.Metadata) > 0
+}
+
+func (f *documentFilter) matches(doc *document.Document) bool {
+	if f.Source != "" && doc.Source.URL != f.Source {
+		return false
+	}
+	if !f.matchesCreated(doc.CreatedAt) {
+		return false
+	}
+	for key, value := range f.Metadata {
+		if doc.Content.Metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// listETag identifies a page of documents: the documents on it, their
+// content, and where the next page starts
+func listETag(docs []*document.Document, content, nextCursor string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "content:%s\nnext:%s\n", content, nextCursor)
+	for _, doc := range docs {
+		writeDocumentVersion(h, doc)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/internal/pipeline/sinks"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// streamKeepAlive is how often an idle event stream writes something so
+// proxies and clients keep the connection open
+const streamKeepAlive = 15 * time.Second
+
+// EventsHandler exposes document events to consumers outside the process,
+// as a live stream and as webhooks
+type EventsHandler struct {
+	stream   *sinks.Stream
+	webhooks *sinks.Dispatcher
+}
+
+// NewEventsHandler creates a new events handler; nil components answer that
+// they are unavailable
+func NewEventsHandler(stream *sinks.Stream, webhooks *sinks.Dispatcher) *EventsHandler {
+	return &EventsHandler{
+		stream:   stream,
+		webhooks: webhooks,
+	}
+}
+
+func (h *EventsHandler) unavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Events need a storage backend with an event bus",
+	})
+}
+
+// StreamEvents serves document events as Server-Sent Events, or as
+// newline-delimited JSON with ?format=ndjson. A client resumes after the
+// last event it saw with the Last-Event-ID header or ?last_event_id=.
+func (h *EventsHandler) StreamEvents(c *fiber.Ctx) error {
+	if h.stream == nil {
+		return h.unavailable(c)
+	}
+
+	var types []pipeline.EventType
+	if param := c.Query("types"); param != "" {
+		for _, name := range strings.Split(param, ",") {
+			eventType := pipeline.EventType(strings.TrimSpace(name))
+			if !pipeline.IsEventType(eventType) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("Unknown event type: %s", eventType),
+				})
+			}
+			types = append(types, eventType)
+		}
+	}
+
+	ndjson := c.Query("format") == "ndjson" || strings.Contains(c.Get(fiber.HeaderAccept), "application/x-ndjson")
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	follower, err := h.stream.Follow(lastEventID, types)
+	if err != nil {
+		return h.unavailable(c)
+	}
+
+	if ndjson {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	} else {
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+	}
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	if follower.Missed {
+		c.Set("X-Caia-Events-Missed", "true")
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer follower.Close()
+
+		write := func(event *pipeline.DocumentEvent) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if ndjson {
+				_, err = fmt.Fprintf(w, "%s\n", data)
+			} else {
+				_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			}
+			return err
+		}
+
+		// An SSE comment tells the client the stream is open before the
+		// first event arrives
+		if !ndjson {
+			fmt.Fprint(w, ": connected\n\n")
+		}
+		for _, event := range follower.Backlog {
+			if err := write(event); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event, ok := <-follower.Events:
+				if !ok {
+					return
+				}
+				if err := write(event); err != nil {
+					log.Debug().Err(err).Msg("Event stream client went away")
+					return
+				}
+			case <-keepAlive.C:
+				if ndjson {
+					fmt.Fprint(w, "\n")
+				} else {
+					fmt.Fprint(w, ": keepalive\n\n")
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// webhookRequest is the body of a webhook registration
+type webhookRequest struct {
+	URL         string               `json:"url"`
+	Secret      string               `json:"secret,omitempty"`
+	EventTypes  []pipeline.EventType `json:"event_types,omitempty"`
+	SourceTypes []string             `json:"source_types,omitempty"`
+}
+
+// RegisterWebhook adds a webhook. The response is the only one that shows
+// its signing secret.
+func (h *EventsHandler) RegisterWebhook(c *fiber.Ctx) error {
+	if h.webhooks == nil {
+		return h.unavailable(c)
+	}
+
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	hook, err := h.webhooks.Register(sinks.Webhook{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		EventTypes:  req.EventTypes,
+		SourceTypes: req.SourceTypes,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(hook)
+}
+
+// ListWebhooks returns every webhook with its delivery statistics
+func (h *EventsHandler) ListWebhooks(c *fiber.Ctx) error {
+	if h.webhooks == nil {
+		return h.unavailable(c)
+	}
+	return c.JSON(fiber.Map{
+		"webhooks": h.webhooks.List(),
+	})
+}
+
+// GetWebhook returns a webhook with its delivery statistics
+func (h *EventsHandler) GetWebhook(c *fiber.Ctx) error {
+	if h.webhooks == nil {
+		return h.unavailable(c)
+	}
+	status, ok := h.webhooks.Get(c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	return c.JSON(status)
+}
+
+// DeleteWebhook unregisters a webhook
+func (h *EventsHandler) DeleteWebhook(c *fiber.Ctx) error {
+	if h.webhooks == nil {
+		return h.unavailable(c)
+	}
+	if err := h.webhooks.Remove(c.Params("id")); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, sinks.ErrWebhookNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
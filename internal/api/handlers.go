@@ -1,16 +1,20 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/Caia-Tech/caia-library/pkg/gql"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -21,14 +25,45 @@ import (
 type Handlers struct {
 	temporal client.Client
 	repoPath string
+	storage  storage.StorageBackend
+
+	// textSearcher answers GQL ~ filters from the full-text index
+	textSearcher *storage.TextSearcher
+}
+
+// queryExecutor runs GQL queries
+type queryExecutor interface {
+	Execute(ctx context.Context, query string) (*gql.Result, error)
 }
 
-// NewHandlers creates a new handlers instance
-func NewHandlers(temporal client.Client, repoPath string) *Handlers {
+// NewHandlers creates a new handlers instance. Documents are read back
+// from backend.
+func NewHandlers(temporal client.Client, repoPath string, backend storage.StorageBackend) *Handlers {
 	return &Handlers{
 		temporal: temporal,
 		repoPath: repoPath,
+		storage:  backend,
+	}
+}
+
+// SetTextSearcher makes GQL ~ filters on title, text and author use the
+// searcher's full-text index
+func (h *Handlers) SetTextSearcher(searcher *storage.TextSearcher) {
+	h.textSearcher = searcher
+}
+
+// queryExecutor returns the executor for GQL queries. Queries run against
+// storage when the handlers have it and against the git repository
+// otherwise.
+func (h *Handlers) queryExecutor() queryExecutor {
+	if h.storage == nil {
+		return gql.NewExecutor(h.repoPath)
+	}
+	executor := gql.NewGovcExecutor(h.storage)
+	if h.textSearcher != nil {
+		executor.SetTextSearcher(h.textSearcher)
 	}
+	return executor
 }
 
 // Health returns the service health status
@@ -246,7 +281,9 @@ func getSupportedTypes() []string {
 	return []string{"txt", "html", "pdf", "docx", "doc", "png", "jpg", "jpeg", "tiff", "bmp", "gif"}
 }
 
-// GetDocument retrieves a document by ID
+// GetDocument retrieves a document by ID. The content query parameter
+// selects metadata, text (the default) or raw; raw returns the stored bytes
+// as the response body. Responses carry an ETag and honour If-None-Match.
 func (h *Handlers) GetDocument(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
@@ -255,27 +292,55 @@ func (h *Handlers) GetDocument(c *fiber.Ctx) error {
 		})
 	}
 
-	// TODO: Implement document retrieval from Git repository
-	// This would involve:
-	// 1. Opening the Git repository
-	// 2. Finding the document by ID
-	// 3. Reading the metadata, text, and embeddings
-	// 4. Returning the document
+	content, err := parseContent(c.Query("content"), ContentText)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
-		"error": "Document retrieval not yet implemented",
-		"id":    id,
-	})
+	doc, err := h.storage.GetDocument(c.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+				"id":    id,
+			})
+		}
+		log.Printf("Failed to get document %s: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve document",
+			"details": err.Error(),
+		})
+	}
+
+	if notModified(c, documentETag(doc, content)) {
+		return nil
+	}
+
+	if content == ContentRaw {
+		contentType := doc.Content.Metadata["content_type"]
+		if contentType == "" {
+			contentType = fiber.MIMEOctetStream
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.Send(doc.Content.Raw)
+	}
+
+	return c.JSON(newDocumentResponse(doc, content))
 }
 
-// ListDocumentsRequest represents query parameters for listing documents
+// ListDocumentsRequest represents query parameters for listing documents.
+// Metadata filters are given as metadata.<key>=<value> parameters.
 type ListDocumentsRequest struct {
-	Page  int    `query:"page" validate:"min=1"`
-	Limit int    `query:"limit" validate:"min=1,max=100"`
-	Type  string `query:"type"`
+	Limit   int    `query:"limit" validate:"min=1,max=100"`
+	Cursor  string `query:"cursor"`
+	Type    string `query:"type"`
+	Content string `query:"content"`
 }
 
-// ListDocuments returns a paginated list of documents
+// ListDocuments returns a page of documents, newest first. Pass the
+// returned next_cursor as cursor to fetch the following page.
 func (h *Handlers) ListDocuments(c *fiber.Ctx) error {
 	var req ListDocumentsRequest
 	
@@ -288,31 +353,147 @@ func (h *Handlers) ListDocuments(c *fiber.Ctx) error {
 	}
 
 	// Set defaults
-	if req.Page == 0 {
-		req.Page = 1
-	}
 	if req.Limit == 0 {
-		req.Limit = 20
+		req.Limit = defaultListLimit
+	}
+	if req.Limit < 1 || req.Limit > maxListLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("limit must be between 1 and %d", maxListLimit),
+		})
+	}
+
+	content, err := parseContent(req.Content, ContentMetadata)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var cursor *documentCursor
+	if req.Cursor != "" {
+		if cursor, err = decodeCursor(req.Cursor); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	filter, err := parseDocumentFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page, last, total, hasMore, err := h.listPage(c.Context(), req.Type, filter, cursor, req.Limit)
+	if err != nil {
+		log.Printf("Failed to list documents: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list documents",
+			"details": err.Error(),
+		})
 	}
 
-	// TODO: Implement document listing from Git repository
-	// This would involve:
-	// 1. Opening the Git repository
-	// 2. Walking through the documents directory
-	// 3. Filtering by type if specified
-	// 4. Implementing pagination
-	// 5. Reading metadata for each document
+	nextCursor := ""
+	if hasMore {
+		nextCursor = last.encode()
+	}
+
+	if notModified(c, listETag(page, content, nextCursor)) {
+		return nil
+	}
+
+	documents := make([]DocumentResponse, len(page))
+	for i, doc := range page {
+		documents[i] = newDocumentResponse(doc, content)
+	}
 
+	pagination := fiber.Map{
+		"limit":       req.Limit,
+		"has_more":    hasMore,
+		"next_cursor": nextCursor,
+	}
+	if total >= 0 {
+		pagination["total"] = total
+	}
 	return c.JSON(fiber.Map{
-		"documents": []interface{}{},
-		"pagination": fiber.Map{
-			"page":  req.Page,
-			"limit": req.Limit,
-			"total": 0,
-		},
+		"documents":  documents,
+		"pagination": pagination,
 	})
 }
 
+// listPage returns up to limit documents of docType matching filter that
+// follow cursor in list order, the list position of the last of them, and
+// whether more follow. Storage that lists document metadata is ordered and
+// filtered by type and date from the metadata, so only the documents up to
+// the end of the page are loaded. total counts the matching documents, or
+// is -1 when that would mean loading every document.
+func (h *Handlers) listPage(ctx context.Context, docType string, filter *documentFilter, cursor *documentCursor, limit int) ([]*document.Document, documentCursor, int, bool, error) {
+	var entries []documentCursor
+	loaded := make(map[string]*document.Document)
+
+	lister, listsMetadata := h.storage.(storage.MetadataLister)
+	if listsMetadata {
+		metadata, err := lister.ListMetadata(ctx)
+		if err != nil {
+			return nil, documentCursor{}, 0, false, err
+		}
+		for _, meta := range metadata {
+			if (docType != "" && meta.Type != docType) || !filter.matchesCreated(meta.CreatedAt) {
+				continue
+			}
+			entries = append(entries, documentCursor{CreatedAt: meta.CreatedAt, ID: meta.ID})
+		}
+	} else {
+		var backendFilters map[string]string
+		if docType != "" {
+			backendFilters = map[string]string{"type": docType}
+		}
+		docs, err := h.storage.ListDocuments(ctx, backendFilters)
+		if err != nil {
+			return nil, documentCursor{}, 0, false, err
+		}
+		for _, doc := range docs {
+			if filter.matches(doc) {
+				loaded[doc.ID] = doc
+				entries = append(entries, documentCursor{CreatedAt: doc.CreatedAt, ID: doc.ID})
+			}
+		}
+	}
+	sortEntries(entries)
+
+	total := len(entries)
+	if listsMetadata && filter.needsDocument() {
+		total = -1
+	}
+
+	// Skip to the cursor, then load until one past the page to learn
+	// whether more follow
+	if cursor != nil {
+		entries = entries[sort.Search(len(entries), func(i int) bool { return cursor.after(entries[i]) }):]
+	}
+	var page []*document.Document
+	var last documentCursor
+	for _, entry := range entries {
+		doc, ok := loaded[entry.ID]
+		if !ok {
+			var err error
+			if doc, err = h.storage.GetDocument(ctx, entry.ID); err != nil {
+				continue // Deleted since it was listed
+			}
+		}
+		if !filter.matches(doc) {
+			continue
+		}
+		if len(page) == limit {
+			return page, last, total, true, nil
+		}
+		page = append(page, doc)
+		last = entry
+	}
+	return page, last, total, false, nil
+}
+
 // WorkflowStatusResponse represents the workflow status
 type WorkflowStatusResponse struct {
 	WorkflowID string                 `json:"workflow_id"`
@@ -497,6 +678,73 @@ func (h *Handlers) CreateBatchIngestion(c *fiber.Ctx) error {
 	})
 }
 
+// WARCIngestionRequest selects archived captures to ingest, from a whole
+// WARC file or by looking URLs up in a CDX server. Archives and indexes
+// must be http(s) URLs.
+type WARCIngestionRequest struct {
+	WARC      string            `json:"warc"`
+	CDX       string            `json:"cdx"`
+	URLs      []string          `json:"urls"`
+	BaseURL   string            `json:"base_url"`
+	MIMETypes []string          `json:"mime_types"`
+	Limit     int               `json:"limit"`
+	Type      string            `json:"type"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// CreateWARCIngestion starts a workflow ingesting archived captures, such as
+// Common Crawl or Internet Archive records, through the document pipeline
+func (h *Handlers) CreateWARCIngestion(c *fiber.Ctx) error {
+	var req WARCIngestionRequest
+
+	// Parse request body
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("Failed to parse request body: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := h.validateWARCIngestionRequest(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Validation failed",
+			"details": err.Error(),
+		})
+	}
+
+	// Generate workflow ID
+	workflowID := fmt.Sprintf("warc-%s", uuid.New().String())
+
+	we, err := h.temporal.ExecuteWorkflow(c.Context(), client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: "caia-library",
+	}, workflows.WARCIngestionWorkflow, workflows.WARCIngestionInput{
+		WARC:      req.WARC,
+		CDX:       req.CDX,
+		URLs:      req.URLs,
+		BaseURL:   req.BaseURL,
+		MIMETypes: req.MIMETypes,
+		Limit:     req.Limit,
+		Type:      req.Type,
+		Metadata:  req.Metadata,
+	})
+	if err != nil {
+		log.Printf("Failed to start WARC ingestion workflow: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start WARC ingestion",
+			"details": err.Error(),
+		})
+	}
+
+	log.Printf("Started WARC ingestion workflow: %s", workflowID)
+
+	return c.Status(fiber.StatusAccepted).JSON(IngestDocumentResponse{
+		WorkflowID: we.GetID(),
+		RunID:      we.GetRunID(),
+	})
+}
+
 // QueryRequest represents a GQL query request
 type QueryRequest struct {
 	Query string `json:"query" validate:"required"`
@@ -533,11 +781,8 @@ func (h *Handlers) ExecuteQuery(c *fiber.Ctx) error {
 
 	log.Printf("Executing GQL query: %s", req.Query)
 
-	// Create executor
-	executor := gql.NewExecutor(h.repoPath)
-
 	// Execute query
-	result, err := executor.Execute(c.Context(), req.Query)
+	result, err := h.queryExecutor().Execute(c.Context(), req.Query)
 	if err != nil {
 		log.Printf("Query execution failed: %v", err)
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -572,6 +817,7 @@ func (h *Handlers) GetQueryExamples(c *fiber.Ctx) error {
 		"examples": examples,
 		"syntax": fiber.Map{
 			"select":    "SELECT FROM <type> WHERE <conditions> ORDER BY <field> [DESC] LIMIT <n>",
+			"aggregate": "SELECT COUNT(*) FROM documents WHERE <conditions> GROUP BY <field|DAY|WEEK|MONTH|YEAR(<field>)>, ...",
 			"types":     []string{"documents", "attribution", "sources", "authors"},
 			"operators": []string{"=", "!=", "~", ">", "<", "exists", "not exists"},
 		},
@@ -581,8 +827,7 @@ func (h *Handlers) GetQueryExamples(c *fiber.Ctx) error {
 // GetAttributionStats returns attribution compliance statistics
 func (h *Handlers) GetAttributionStats(c *fiber.Ctx) error {
 	// Execute attribution query
-	executor := gql.NewExecutor(h.repoPath)
-	result, err := executor.Execute(c.Context(), gql.ExampleAttributionCompliance)
+	result, err := h.queryExecutor().Execute(c.Context(), gql.ExampleAttributionCompliance)
 	if err != nil {
 		log.Printf("Failed to get attribution stats: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -616,6 +861,13 @@ func (h *Handlers) GetAttributionStats(c *fiber.Ctx) error {
 
 // Input validation functions
 
+// validDocumentTypes are the document types ingestion accepts
+var validDocumentTypes = map[string]bool{
+	"text": true, "html": true, "pdf": true, "docx": true,
+	"doc": true, "png": true, "jpg": true, "jpeg": true,
+	"tiff": true, "bmp": true, "gif": true,
+}
+
 // validateIngestRequest validates and sanitizes document ingestion requests
 func (h *Handlers) validateIngestRequest(req *IngestDocumentRequest) error {
 	// Validate required fields
@@ -642,12 +894,7 @@ func (h *Handlers) validateIngestRequest(req *IngestDocumentRequest) error {
 	}
 	
 	// Validate document type
-	validTypes := map[string]bool{
-		"text": true, "html": true, "pdf": true, "docx": true, 
-		"doc": true, "png": true, "jpg": true, "jpeg": true, 
-		"tiff": true, "bmp": true, "gif": true,
-	}
-	if !validTypes[req.Type] {
+	if !validDocumentTypes[req.Type] {
 		return fmt.Errorf("unsupported document type: %s", req.Type)
 	}
 	
@@ -666,6 +913,59 @@ func (h *Handlers) validateIngestRequest(req *IngestDocumentRequest) error {
 	return nil
 }
 
+// validateWARCIngestionRequest validates and sanitizes WARC ingestion
+// requests. Only remote archives are accepted, so requests cannot read
+// files on the server.
+func (h *Handlers) validateWARCIngestionRequest(req *WARCIngestionRequest) error {
+	const maxURLs = 1000
+
+	req.WARC = strings.TrimSpace(req.WARC)
+	req.CDX = strings.TrimSpace(req.CDX)
+	req.BaseURL = strings.TrimSpace(req.BaseURL)
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+
+	if (req.WARC == "") == (req.CDX == "") {
+		return fmt.Errorf("either warc or cdx is required")
+	}
+	if req.CDX != "" && len(req.URLs) == 0 {
+		return fmt.Errorf("urls to look up are required with cdx")
+	}
+	if len(req.URLs) > maxURLs {
+		return fmt.Errorf("too many urls: %d (max %d)", len(req.URLs), maxURLs)
+	}
+	if req.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	for _, location := range []string{req.WARC, req.CDX, req.BaseURL} {
+		if location == "" {
+			continue
+		}
+		parsedURL, err := url.Parse(location)
+		if err != nil {
+			return fmt.Errorf("invalid URL format: %w", err)
+		}
+		if err := h.validateURLSafety(parsedURL); err != nil {
+			return fmt.Errorf("URL not allowed: %w", err)
+		}
+	}
+
+	if req.Type != "" && !validDocumentTypes[req.Type] {
+		return fmt.Errorf("unsupported document type: %s", req.Type)
+	}
+	if req.Metadata != nil {
+		metadataInterface := make(map[string]interface{})
+		for k, v := range req.Metadata {
+			metadataInterface[k] = v
+		}
+		if err := h.validateMetadata(metadataInterface); err != nil {
+			return fmt.Errorf("invalid metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // validateURLSafety prevents SSRF and other URL-based attacks
 func (h *Handlers) validateURLSafety(parsedURL *url.URL) error {
 	// Only allow HTTP and HTTPS
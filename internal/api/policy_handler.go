@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PolicyHandler serves the domain policies documents are collected under
+type PolicyHandler struct {
+	policies *policy.Registry
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(policies *policy.Registry) *PolicyHandler {
+	return &PolicyHandler{
+		policies: policies,
+	}
+}
+
+// ListPolicies returns the policies in force, their version and when they
+// were loaded
+func (h *PolicyHandler) ListPolicies(c *fiber.Ctx) error {
+	return c.JSON(h.policies.Snapshot())
+}
+
+// GetPolicy returns the policy that applies to a domain, which is its
+// parent domain's or the default policy when it has none of its own
+func (h *PolicyHandler) GetPolicy(c *fiber.Ctx) error {
+	domain := strings.TrimSpace(c.Params("domain"))
+	if domain == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Domain is required",
+		})
+	}
+
+	p := h.policies.Lookup(domain)
+	return c.JSON(fiber.Map{
+		"domain":  strings.ToLower(domain),
+		"listed":  p.Domain != "",
+		"policy":  p,
+		"version": p.Version,
+	})
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline/replay"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReplayHandler lets operators republish stored documents onto the event
+// bus
+type ReplayHandler struct {
+	replays *replay.Manager
+}
+
+// NewReplayHandler creates a new replay handler; a nil manager answers that
+// replays are unavailable
+func NewReplayHandler(replays *replay.Manager) *ReplayHandler {
+	return &ReplayHandler{
+		replays: replays,
+	}
+}
+
+func (h *ReplayHandler) unavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Replays need a storage backend with an event bus",
+	})
+}
+
+// StartReplay starts republishing the documents a request selects
+func (h *ReplayHandler) StartReplay(c *fiber.Ctx) error {
+	if h.replays == nil {
+		return h.unavailable(c)
+	}
+
+	var req replay.Request
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	job, err := h.replays.Start(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// ListReplays returns all replays, most recent first
+func (h *ReplayHandler) ListReplays(c *fiber.Ctx) error {
+	if h.replays == nil {
+		return h.unavailable(c)
+	}
+	return c.JSON(fiber.Map{
+		"replays": h.replays.List(),
+	})
+}
+
+// GetReplay returns a replay and its progress
+func (h *ReplayHandler) GetReplay(c *fiber.Ctx) error {
+	if h.replays == nil {
+		return h.unavailable(c)
+	}
+	job, ok := h.replays.Get(c.Params("id"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Replay not found",
+		})
+	}
+	return c.JSON(job)
+}
+
+// ResumeReplay continues a stopped replay after the last document it
+// published
+func (h *ReplayHandler) ResumeReplay(c *fiber.Ctx) error {
+	if h.replays == nil {
+		return h.unavailable(c)
+	}
+	job, err := h.replays.Resume(c.Params("id"))
+	if err != nil {
+		return h.jobError(c, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// CancelReplay stops a running replay
+func (h *ReplayHandler) CancelReplay(c *fiber.Ctx) error {
+	if h.replays == nil {
+		return h.unavailable(c)
+	}
+	job, err := h.replays.Cancel(c.Params("id"))
+	if err != nil {
+		return h.jobError(c, err)
+	}
+	return c.JSON(job)
+}
+
+func (h *ReplayHandler) jobError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusConflict
+	if errors.Is(err, replay.ErrNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+)
+
+// SearchHandler provides similarity search over stored documents
+type SearchHandler struct {
+	searcher *storage.VectorSearcher
+	embedder *embedder.Engine
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(searcher *storage.VectorSearcher, engine *embedder.Engine) *SearchHandler {
+	return &SearchHandler{
+		searcher: searcher,
+		embedder: engine,
+	}
+}
+
+// SimilarSearchRequest asks for the documents nearest to a text or to an
+// existing document
+type SimilarSearchRequest struct {
+	Text       string            `json:"text"`
+	DocumentID string            `json:"document_id"`
+	K          int               `json:"k"`
+	Filters    map[string]string `json:"filters"`
+}
+
+// SimilarSearchResponse lists the nearest documents, best first
+type SimilarSearchResponse struct {
+	Results []storage.VectorMatch `json:"results"`
+	Count   int                   `json:"count"`
+	Query   string                `json:"query_type"`
+}
+
+// SearchSimilar returns the top-k documents most similar to the request's
+// text or document
+func (h *SearchHandler) SearchSimilar(c *fiber.Ctx) error {
+	var req SimilarSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	req.Text = strings.TrimSpace(req.Text)
+	req.DocumentID = strings.TrimSpace(req.DocumentID)
+	if (req.Text == "") == (req.DocumentID == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Exactly one of text or document_id is required",
+		})
+	}
+
+	if req.K <= 0 {
+		req.K = defaultSearchLimit
+	}
+	if req.K > maxSearchLimit {
+		req.K = maxSearchLimit
+	}
+
+	var (
+		results   []storage.VectorMatch
+		err       error
+		queryType string
+	)
+	if req.Text != "" {
+		queryType = "text"
+		var vector []float32
+		vector, err = h.embedder.Generate(c.Context(), req.Text)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to embed query text",
+				"details": err.Error(),
+			})
+		}
+		results, err = h.searcher.SearchByVector(vector, req.K, req.Filters)
+	} else {
+		queryType = "document"
+		results, err = h.searcher.SearchByDocument(c.Context(), req.DocumentID, req.K, req.Filters)
+		if err != nil && strings.Contains(err.Error(), "not found") {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil && strings.Contains(err.Error(), "no embeddings") {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Similarity search failed",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(SimilarSearchResponse{
+		Results: results,
+		Count:   len(results),
+		Query:   queryType,
+	})
+}
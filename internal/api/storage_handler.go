@@ -54,6 +54,20 @@ func (h *StorageHandler) GetStorageHealth(c *fiber.Ctx) error {
 	})
 }
 
+// SyncStorage reconciles the govc and git backends immediately
+func (h *StorageHandler) SyncStorage(c *fiber.Ctx) error {
+	report, err := h.hybridStorage.Reconcile(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sync_report": report,
+	})
+}
+
 // ClearMetrics clears all collected metrics (useful for testing)
 func (h *StorageHandler) ClearMetrics(c *fiber.Ctx) error {
 	h.metrics.ClearMetrics()
@@ -283,7 +283,7 @@ progress toward practical quantum advantage in specific domains like cryptograph
 			},
 		}
 
-		renderedSearch, err := renderer.RenderSearch(searchResults, &presentation.SearchOptions{
+		renderedSearch, err := renderer.RenderSearch(ctx, searchResults, &presentation.SearchOptions{
 			CollectionOptions: presentation.CollectionOptions{
 				RenderOptions: presentation.RenderOptions{
 					Format:         presentation.FormatHTML,
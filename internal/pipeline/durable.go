@@ -0,0 +1,496 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DurableConfig configures an event bus that persists events before
+// delivering them
+type DurableConfig struct {
+	// Dir holds the event log segments, the subscriptions' offsets and
+	// the dead letter queue
+	Dir string
+	// SegmentBytes is the size past which the log starts a new segment
+	SegmentBytes int64
+	// NoSync skips syncing each published event to disk, trading the
+	// events of the last moments before a crash for publishing speed
+	NoSync bool
+	// MaxAttempts is how many times a handler is given an event before it
+	// goes to the dead letter queue
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// retry after it up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// HandlerTimeout bounds a single handler call
+	HandlerTimeout time.Duration
+}
+
+// DefaultDurableConfig returns the default configuration of a durable event
+// bus kept in dir
+func DefaultDurableConfig(dir string) *DurableConfig {
+	return &DurableConfig{
+		Dir:             dir,
+		SegmentBytes:    64 * 1024 * 1024,
+		MaxAttempts:     5,
+		RetryBackoff:    500 * time.Millisecond,
+		MaxRetryBackoff: time.Minute,
+		HandlerTimeout:  time.Minute,
+	}
+}
+
+// compactInterval is how often subscriptions that caught up remove the log
+// segments every subscription is done with
+const compactInterval = time.Minute
+
+// subscriptionNamePattern restricts durable subscription names to ones
+// safe to use as file names
+var subscriptionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// durableState is what a durable event bus keeps on disk
+type durableState struct {
+	config      *DurableConfig
+	log         *eventLog
+	offsets     *offsetStore
+	deadLetters *deadLetterQueue
+
+	compactMu   sync.Mutex
+	lastCompact time.Time
+}
+
+// NewDurableEventBus creates an event bus that appends every event to an
+// on-disk log before delivering it. Each subscription reads the log in
+// order, one event at a time: an event counts as handled once the handler
+// returns nil, a failing handler gets the event again with backoff, and an
+// event that fails MaxAttempts times goes to the dead letter queue.
+// Subscriptions made with SubscribeDurable commit their offset as they go
+// and resume from it after a restart, so they see every event at least
+// once. Events are read back from JSON, so numbers in their metadata
+// arrive as float64.
+func NewDurableEventBus(config *DurableConfig) (*EventBus, error) {
+	if config == nil || config.Dir == "" {
+		return nil, fmt.Errorf("durable event bus needs a directory")
+	}
+	defaults := DefaultDurableConfig(config.Dir)
+	if config.SegmentBytes <= 0 {
+		config.SegmentBytes = defaults.SegmentBytes
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaults.RetryBackoff
+	}
+	if config.MaxRetryBackoff < config.RetryBackoff {
+		config.MaxRetryBackoff = config.RetryBackoff
+	}
+	if config.HandlerTimeout <= 0 {
+		config.HandlerTimeout = defaults.HandlerTimeout
+	}
+
+	eventLog, err := openEventLog(config.Dir, config.SegmentBytes, !config.NoSync)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := openOffsetStore(config.Dir)
+	if err != nil {
+		eventLog.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	eb := &EventBus{
+		subscriptions: make(map[string]*Subscription),
+		ctx:           ctx,
+		cancel:        cancel,
+		durable: &durableState{
+			config:      config,
+			log:         eventLog,
+			offsets:     offsets,
+			deadLetters: &deadLetterQueue{path: filepath.Join(config.Dir, deadLettersFile)},
+			lastCompact: time.Now(),
+		},
+	}
+
+	end, _ := eventLog.End()
+	log.Info().
+		Str("dir", config.Dir).
+		Int64("log_start", eventLog.Start()).
+		Int64("log_end", end).
+		Msg("Durable event bus started")
+
+	return eb, nil
+}
+
+// Durable reports whether the bus persists events
+func (eb *EventBus) Durable() bool {
+	return eb.durable != nil
+}
+
+// SubscribeDurable creates a subscription that keeps its place in the event
+// log under name. It resumes after the last event it handled when the bus
+// is reopened; a name never seen before starts with the next event
+// published. On an in-memory bus it is a plain subscription with the name
+// as its ID.
+func (eb *EventBus) SubscribeDurable(name string, eventTypes []EventType, handler EventHandler) (*Subscription, error) {
+	if !subscriptionNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid subscription name: %q", name)
+	}
+	if eb.durable == nil {
+		return eb.subscribe(name, eventTypes, handler, 100)
+	}
+	return eb.subscribeLog(name, true, eventTypes, handler)
+}
+
+// DeleteDurable forgets the place an inactive durable subscription kept
+// under name, so the log is no longer kept for it. It does nothing on an
+// in-memory bus.
+func (eb *EventBus) DeleteDurable(name string) error {
+	if !subscriptionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid subscription name: %q", name)
+	}
+	if eb.durable == nil {
+		return nil
+	}
+	eb.mu.RLock()
+	_, active := eb.subscriptions[name]
+	eb.mu.RUnlock()
+	if active {
+		return fmt.Errorf("subscription is active: %s", name)
+	}
+	return eb.durable.offsets.Delete(name)
+}
+
+// Backlog returns how many events of the log a subscription has yet to
+// handle, whether or not they match it; an in-memory bus keeps no log and
+// returns 0
+func (eb *EventBus) Backlog(subscriptionID string) int64 {
+	if eb.durable == nil {
+		return 0
+	}
+	eb.mu.RLock()
+	sub, ok := eb.subscriptions[subscriptionID]
+	eb.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	end, _ := eb.durable.log.End()
+	return end - sub.position.Load()
+}
+
+// DeadLetters returns the events subscriptions gave up on, oldest first; an
+// in-memory bus has none
+func (eb *EventBus) DeadLetters() ([]DeadLetter, error) {
+	if eb.durable == nil {
+		return nil, nil
+	}
+	return eb.durable.deadLetters.List()
+}
+
+// permanentError is a handler error that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as one retrying will not fix, so a
+// durable bus moves the event to the dead letter queue without retrying.
+// Handlers that retry on their own return it once they give up.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// publishDurable appends an event to the log, which wakes the subscriptions
+// waiting for it
+func (eb *EventBus) publishDurable(event *DocumentEvent) error {
+	if eb.ctx.Err() != nil {
+		return fmt.Errorf("event bus is shutting down")
+	}
+	if _, err := eb.durable.log.Append(event); err != nil {
+		return err
+	}
+	eb.statsMu.Lock()
+	eb.stats.EventsPublished++
+	eb.statsMu.Unlock()
+	return nil
+}
+
+// subscribeLog creates a subscription that reads the event log. A durable
+// one starts at its committed offset; any other starts at the end of the log.
+func (eb *EventBus) subscribeLog(id string, durable bool, eventTypes []EventType, handler EventHandler) (*Subscription, error) {
+	d := eb.durable
+	start, _ := d.log.End()
+	if durable {
+		offset, ok, err := d.offsets.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			start = offset
+		}
+	}
+
+	eb.mu.Lock()
+	if _, exists := eb.subscriptions[id]; exists {
+		eb.mu.Unlock()
+		return nil, fmt.Errorf("subscription already exists: %s", id)
+	}
+	ctx, cancel := context.WithCancel(eb.ctx)
+	sub := &Subscription{
+		ID:         id,
+		EventTypes: eventTypes,
+		Handler:    handler,
+		ctx:        ctx,
+		cancel:     cancel,
+		active:     true,
+		durable:    durable,
+		done:       make(chan struct{}),
+	}
+	cursor := newLogCursor(d.log, start)
+	sub.position.Store(cursor.Offset())
+	eb.subscriptions[id] = sub
+	eb.wg.Add(1)
+	eb.mu.Unlock()
+
+	if durable {
+		// Record the starting point so events published before the first
+		// one is handled are not skipped after a restart
+		if err := d.offsets.Commit(id, cursor.Offset()); err != nil {
+			log.Warn().Err(err).Str("subscription_id", id).Msg("Failed to commit subscription offset")
+		}
+	}
+
+	eb.statsMu.Lock()
+	eb.stats.ActiveSubscribers++
+	eb.statsMu.Unlock()
+
+	go eb.consume(sub, cursor)
+
+	log.Info().
+		Str("subscription_id", id).
+		Bool("durable", durable).
+		Int64("offset", cursor.Offset()).
+		Interface("event_types", eventTypes).
+		Msg("New log subscription created")
+
+	return sub, nil
+}
+
+// consume delivers the log's events to a subscription in order until the
+// subscription is cancelled
+func (eb *EventBus) consume(sub *Subscription, cursor *logCursor) {
+	defer eb.wg.Done()
+	defer close(sub.done)
+	defer cursor.Close()
+
+	d := eb.durable
+	// next is the offset of the first event not yet handled or skipped
+	next := cursor.Offset()
+	committed := next
+	commit := func() {
+		sub.position.Store(next)
+		if !sub.durable || next == committed {
+			return
+		}
+		if err := d.offsets.Commit(sub.ID, next); err != nil {
+			log.Error().Err(err).Str("subscription_id", sub.ID).Msg("Failed to commit subscription offset")
+			return
+		}
+		committed = next
+	}
+	defer commit()
+
+	for {
+		end, appended := d.log.End()
+		if cursor.Offset() >= end {
+			// Caught up: save our place and wait for the next event
+			commit()
+			eb.compact()
+			select {
+			case <-appended:
+				continue
+			case <-sub.ctx.Done():
+				return
+			}
+		}
+
+		event, offset, err := cursor.Next()
+		if err != nil {
+			log.Error().Err(err).Str("subscription_id", sub.ID).Msg("Failed to read event log")
+			if cursor.Offset() > offset {
+				// The event could not be decoded and was skipped
+				next = cursor.Offset()
+				continue
+			}
+			select {
+			case <-time.After(d.config.RetryBackoff):
+				continue
+			case <-sub.ctx.Done():
+				return
+			}
+		}
+		if !eb.eventMatchesSubscription(event, sub) {
+			next = offset + 1
+			continue
+		}
+		if !eb.handle(sub, event, offset) {
+			return
+		}
+		next = offset + 1
+		commit()
+	}
+}
+
+// handle gives an event to a subscription's handler until it succeeds or
+// runs out of attempts or fails permanently, in which case the event goes
+// to the dead letter queue. It returns false if the subscription was cancelled first, leaving
+// the event unhandled.
+func (eb *EventBus) handle(sub *Subscription, event *DocumentEvent, offset int64) bool {
+	config := eb.durable.config
+	backoff := config.RetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := eb.callHandler(sub, event)
+		if err == nil {
+			eb.statsMu.Lock()
+			eb.stats.EventsDelivered++
+			eb.statsMu.Unlock()
+			return true
+		}
+		if sub.ctx.Err() != nil {
+			return false
+		}
+
+		eb.statsMu.Lock()
+		eb.stats.EventsFailed++
+		eb.statsMu.Unlock()
+
+		var permanent *permanentError
+		if attempt >= config.MaxAttempts || errors.As(err, &permanent) {
+			letter := DeadLetter{
+				Subscription: sub.ID,
+				Offset:       offset,
+				Attempts:     attempt,
+				Error:        err.Error(),
+				FailedAt:     time.Now(),
+				Event:        event,
+			}
+			if dlqErr := eb.durable.deadLetters.Add(letter); dlqErr != nil {
+				// Keep retrying rather than lose the event
+				log.Error().Err(dlqErr).Str("event_id", event.ID).Msg("Failed to dead-letter event")
+			} else {
+				eb.statsMu.Lock()
+				eb.stats.EventsDeadLettered++
+				eb.statsMu.Unlock()
+				log.Error().
+					Err(err).
+					Str("subscription_id", sub.ID).
+					Str("event_id", event.ID).
+					Int64("offset", offset).
+					Int("attempts", attempt).
+					Msg("Event moved to dead letter queue")
+				return true
+			}
+		} else {
+			eb.statsMu.Lock()
+			eb.stats.EventsRetried++
+			eb.statsMu.Unlock()
+			log.Warn().
+				Err(err).
+				Str("subscription_id", sub.ID).
+				Str("event_id", event.ID).
+				Int("attempt", attempt).
+				Dur("backoff", backoff).
+				Msg("Event handler failed, retrying")
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-sub.ctx.Done():
+			return false
+		}
+		backoff *= 2
+		if backoff > config.MaxRetryBackoff {
+			backoff = config.MaxRetryBackoff
+		}
+	}
+}
+
+// callHandler runs a subscription's handler on an event, turning a panic
+// into an error
+func (eb *EventBus) callHandler(sub *Subscription, event *DocumentEvent) (err error) {
+	ctx, cancel := context.WithTimeout(sub.ctx, eb.durable.config.HandlerTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("event handler panic: %v", r)
+		}
+	}()
+	return sub.Handler(ctx, event)
+}
+
+// compact removes the log segments that every subscription, active or
+// not, has read past. It runs at most once per compactInterval.
+func (eb *EventBus) compact() {
+	d := eb.durable
+	d.compactMu.Lock()
+	defer d.compactMu.Unlock()
+	if time.Since(d.lastCompact) < compactInterval {
+		return
+	}
+	d.lastCompact = time.Now()
+
+	low, _ := d.log.End()
+	offsets, err := d.offsets.All()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read subscription offsets for compaction")
+		return
+	}
+	for _, offset := range offsets {
+		if offset < low {
+			low = offset
+		}
+	}
+	eb.mu.RLock()
+	for _, sub := range eb.subscriptions {
+		if position := sub.position.Load(); position < low {
+			low = position
+		}
+	}
+	eb.mu.RUnlock()
+
+	removed, err := d.log.Compact(low)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to compact event log")
+		return
+	}
+	if removed > 0 {
+		log.Info().Int("segments", removed).Int64("offset", low).Msg("Compacted event log")
+	}
+}
+
+// lag returns how many events the slowest subscription has yet to read
+func (eb *EventBus) lag() int64 {
+	end, _ := eb.durable.log.End()
+	var lag int64
+	for _, sub := range eb.subscriptions {
+		if behind := end - sub.position.Load(); behind > lag {
+			lag = behind
+		}
+	}
+	return lag
+}
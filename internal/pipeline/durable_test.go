@@ -0,0 +1,283 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDurableConfig(dir string) *DurableConfig {
+	config := DefaultDurableConfig(dir)
+	config.RetryBackoff = time.Millisecond
+	config.MaxRetryBackoff = 5 * time.Millisecond
+	config.MaxAttempts = 3
+	return config
+}
+
+func openDurableBus(t *testing.T, config *DurableConfig) *EventBus {
+	bus, err := NewDurableEventBus(config)
+	require.NoError(t, err)
+	return bus
+}
+
+func addedEvent(id string) *DocumentEvent {
+	return NewDocumentEvent(EventDocumentAdded, &document.Document{ID: id})
+}
+
+// recorder collects the document IDs a handler was given
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) handle(ctx context.Context, event *DocumentEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, event.Document.ID)
+	return nil
+}
+
+func (r *recorder) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func (r *recorder) waitFor(t *testing.T, n int) {
+	require.Eventually(t, func() bool { return len(r.seen()) >= n }, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestDurableEventBusResumesFromOffset(t *testing.T) {
+	dir := t.TempDir()
+
+	bus := openDurableBus(t, testDurableConfig(dir))
+	first := &recorder{}
+	_, err := bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, first.handle)
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(addedEvent("doc-1")))
+	require.NoError(t, bus.Publish(NewDocumentEvent(EventDocumentDeleted, &document.Document{ID: "other"})))
+	require.NoError(t, bus.Publish(addedEvent("doc-2")))
+	first.waitFor(t, 2)
+	bus.Close()
+	assert.Equal(t, []string{"doc-1", "doc-2"}, first.seen())
+
+	// Events published while the subscriber is away wait for it
+	bus = openDurableBus(t, testDurableConfig(dir))
+	require.NoError(t, bus.Publish(addedEvent("doc-3")))
+	require.NoError(t, bus.Publish(addedEvent("doc-4")))
+	bus.Close()
+
+	bus = openDurableBus(t, testDurableConfig(dir))
+	defer bus.Close()
+	second := &recorder{}
+	_, err = bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, second.handle)
+	require.NoError(t, err)
+	second.waitFor(t, 2)
+	assert.Equal(t, []string{"doc-3", "doc-4"}, second.seen())
+
+	// A new subscription starts with the next event published
+	fresh := &recorder{}
+	_, err = bus.SubscribeDurable("indexer", []EventType{EventDocumentAdded}, fresh.handle)
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(addedEvent("doc-5")))
+	fresh.waitFor(t, 1)
+	assert.Equal(t, []string{"doc-5"}, fresh.seen())
+
+	_, err = bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, second.handle)
+	assert.Error(t, err, "names are unique")
+	_, err = bus.SubscribeDurable("../cleaner", []EventType{EventDocumentAdded}, second.handle)
+	assert.Error(t, err)
+}
+
+func TestDurableEventBusRedeliversUnackedEvent(t *testing.T) {
+	dir := t.TempDir()
+
+	bus := openDurableBus(t, testDurableConfig(dir))
+	started := make(chan struct{})
+	_, err := bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, func(ctx context.Context, event *DocumentEvent) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(addedEvent("doc-1")))
+	<-started
+	bus.Close()
+
+	bus = openDurableBus(t, testDurableConfig(dir))
+	defer bus.Close()
+	again := &recorder{}
+	_, err = bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, again.handle)
+	require.NoError(t, err)
+	again.waitFor(t, 1)
+	assert.Equal(t, []string{"doc-1"}, again.seen())
+
+	letters, err := bus.DeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, letters, "an interrupted handler is not a failure")
+}
+
+func TestDurableEventBusRetriesAndDeadLetters(t *testing.T) {
+	bus := openDurableBus(t, testDurableConfig(t.TempDir()))
+	defer bus.Close()
+
+	var calls sync.Map
+	handled := &recorder{}
+	_, err := bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, func(ctx context.Context, event *DocumentEvent) error {
+		n, _ := calls.LoadOrStore(event.Document.ID, new(int32))
+		attempt := atomic.AddInt32(n.(*int32), 1)
+		switch event.Document.ID {
+		case "flaky":
+			if attempt < 3 {
+				return errors.New("temporary failure")
+			}
+		case "broken":
+			panic("cannot handle this document")
+		}
+		return handled.handle(ctx, event)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(addedEvent("flaky")))
+	require.NoError(t, bus.Publish(addedEvent("broken")))
+	require.NoError(t, bus.Publish(addedEvent("fine")))
+	handled.waitFor(t, 2)
+	assert.Equal(t, []string{"flaky", "fine"}, handled.seen(), "a failing event does not block the ones after it")
+
+	letters, err := bus.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "cleaner", letters[0].Subscription)
+	assert.Equal(t, int64(1), letters[0].Offset)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Contains(t, letters[0].Error, "cannot handle this document")
+	assert.Equal(t, "broken", letters[0].Event.Document.ID)
+
+	stats := bus.GetStats()
+	assert.Equal(t, int64(3), stats.EventsPublished)
+	assert.Equal(t, int64(2), stats.EventsDelivered)
+	assert.Equal(t, int64(5), stats.EventsFailed)
+	assert.Equal(t, int64(4), stats.EventsRetried)
+	assert.Equal(t, int64(1), stats.EventsDeadLettered)
+	assert.Equal(t, int64(0), stats.EventsInBuffer)
+}
+
+func TestDurableEventBusPermanentFailureAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	bus := openDurableBus(t, testDurableConfig(dir))
+
+	handled := &recorder{}
+	release := make(chan struct{})
+	sub, err := bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, func(ctx context.Context, event *DocumentEvent) error {
+		if event.Document.ID == "rejected" {
+			return Permanent(errors.New("document rejected"))
+		}
+		<-release
+		return handled.handle(ctx, event)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, bus.Publish(addedEvent("rejected")))
+	require.NoError(t, bus.Publish(addedEvent("doc-1")))
+	require.NoError(t, bus.Publish(addedEvent("doc-2")))
+	require.Eventually(t, func() bool { return bus.Backlog(sub.ID) == 2 }, 5*time.Second, 5*time.Millisecond)
+	close(release)
+	handled.waitFor(t, 2)
+
+	letters, err := bus.DeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, 1, letters[0].Attempts, "a permanent failure is not retried")
+	assert.Equal(t, "rejected", letters[0].Event.Document.ID)
+
+	assert.Error(t, bus.DeleteDurable("cleaner"), "an active subscription keeps its place")
+	require.NoError(t, bus.Unsubscribe(sub.ID))
+	require.NoError(t, bus.DeleteDurable("cleaner"))
+	bus.Close()
+
+	// A deleted subscription starts over with the next event published
+	bus = openDurableBus(t, testDurableConfig(dir))
+	defer bus.Close()
+	require.NoError(t, bus.Publish(addedEvent("doc-3")))
+	again := &recorder{}
+	_, err = bus.SubscribeDurable("cleaner", []EventType{EventDocumentAdded}, again.handle)
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(addedEvent("doc-4")))
+	again.waitFor(t, 1)
+	assert.Equal(t, []string{"doc-4"}, again.seen())
+}
+
+func TestEventLogSegmentsAndTornRecords(t *testing.T) {
+	dir := t.TempDir()
+	config := testDurableConfig(dir)
+	config.SegmentBytes = 512
+
+	bus := openDurableBus(t, config)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, bus.Publish(addedEvent("doc")))
+	}
+	bus.Close()
+
+	segments, err := filepath.Glob(filepath.Join(dir, segmentsDir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 2)
+
+	// A crash mid-write leaves part of a record at the end of the log
+	last := segments[len(segments)-1]
+	file, err := os.OpenFile(last, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	eventLog, err := openEventLog(dir, config.SegmentBytes, true)
+	require.NoError(t, err)
+	end, _ := eventLog.End()
+	assert.Equal(t, int64(20), end)
+	offset, err := eventLog.Append(addedEvent("after-crash"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), offset)
+
+	// A cursor reads across segments, including ones added after it opened
+	cursor := newLogCursor(eventLog, 3)
+	for want := int64(3); want < 21; want++ {
+		event, offset, err := cursor.Next()
+		require.NoError(t, err)
+		assert.Equal(t, want, offset)
+		if want == 20 {
+			assert.Equal(t, "after-crash", event.Document.ID)
+			for i := 0; i < 10; i++ {
+				_, err := eventLog.Append(addedEvent("later"))
+				require.NoError(t, err)
+			}
+		}
+	}
+	for want := int64(21); want < 31; want++ {
+		event, offset, err := cursor.Next()
+		require.NoError(t, err)
+		assert.Equal(t, want, offset)
+		assert.Equal(t, "later", event.Document.ID)
+	}
+	cursor.Close()
+
+	// Compaction keeps the segment holding the offset and the ones after it
+	removed, err := eventLog.Compact(25)
+	require.NoError(t, err)
+	assert.Greater(t, removed, 0)
+	assert.LessOrEqual(t, eventLog.Start(), int64(25))
+	cursor = newLogCursor(eventLog, 0)
+	_, offset, err = cursor.Next()
+	require.NoError(t, err)
+	assert.Equal(t, eventLog.Start(), offset)
+	cursor.Close()
+	require.NoError(t, eventLog.Close())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -23,6 +24,11 @@ type Subscription struct {
 	cancel      context.CancelFunc
 	mu          sync.Mutex
 	active      bool
+	
+	// Set for subscriptions that read a durable bus's event log
+	durable     bool          // keeps a committed offset under its ID
+	done        chan struct{} // closed when delivery has stopped
+	position    atomic.Int64  // offset of the next event to read
 }
 
 // EventBus manages pub/sub for document events
@@ -36,15 +42,18 @@ type EventBus struct {
 	wg            sync.WaitGroup
 	stats         EventBusStats
 	statsMu       sync.RWMutex // Protects stats fields
+	durable       *durableState // nil for an in-memory bus
 }
 
 // EventBusStats tracks event bus statistics
 type EventBusStats struct {
-	EventsPublished   int64 `json:"events_published"`
-	EventsDelivered   int64 `json:"events_delivered"`
-	EventsFailed      int64 `json:"events_failed"`
-	ActiveSubscribers int64 `json:"active_subscribers"`
-	EventsInBuffer    int64 `json:"events_in_buffer"`
+	EventsPublished    int64 `json:"events_published"`
+	EventsDelivered    int64 `json:"events_delivered"`
+	EventsFailed       int64 `json:"events_failed"`
+	ActiveSubscribers  int64 `json:"active_subscribers"`
+	EventsInBuffer     int64 `json:"events_in_buffer"`
+	EventsRetried      int64 `json:"events_retried"`
+	EventsDeadLettered int64 `json:"events_dead_lettered"`
 }
 
 // NewEventBus creates a new event bus
@@ -75,6 +84,10 @@ func NewEventBus(bufferSize, workers int) *EventBus {
 
 // Publish publishes an event to all matching subscribers
 func (eb *EventBus) Publish(event *DocumentEvent) error {
+	if eb.durable != nil {
+		return eb.publishDurable(event)
+	}
+	
 	select {
 	case eb.eventBuffer <- event:
 		eb.statsMu.Lock()
@@ -94,15 +107,28 @@ func (eb *EventBus) Publish(event *DocumentEvent) error {
 	}
 }
 
-// Subscribe creates a new subscription for specific event types
+// Subscribe creates a new subscription for specific event types. On a
+// durable bus it reads the event log from the next event published, with
+// retries, but does not keep its place across restarts.
 func (eb *EventBus) Subscribe(eventTypes []EventType, handler EventHandler, bufferSize int) (*Subscription, error) {
+	if eb.durable != nil {
+		return eb.subscribeLog(generateSubscriptionID(), false, eventTypes, handler)
+	}
+	return eb.subscribe(generateSubscriptionID(), eventTypes, handler, bufferSize)
+}
+
+// subscribe creates an in-memory subscription
+func (eb *EventBus) subscribe(id string, eventTypes []EventType, handler EventHandler, bufferSize int) (*Subscription, error) {
 	eb.mu.Lock()
-	defer eb.mu.Unlock()
+	if _, exists := eb.subscriptions[id]; exists {
+		eb.mu.Unlock()
+		return nil, fmt.Errorf("subscription already exists: %s", id)
+	}
 	
 	ctx, cancel := context.WithCancel(eb.ctx)
 	
 	sub := &Subscription{
-		ID:         generateSubscriptionID(),
+		ID:         id,
 		EventTypes: eventTypes,
 		Handler:    handler,
 		BufferSize: bufferSize,
@@ -141,12 +167,19 @@ func (eb *EventBus) Unsubscribe(subscriptionID string) error {
 	sub.mu.Lock()
 	sub.active = false
 	sub.cancel()
-	close(sub.channel)
+	if sub.channel != nil {
+		close(sub.channel)
+	}
 	sub.mu.Unlock()
 	
 	delete(eb.subscriptions, subscriptionID)
 	eb.mu.Unlock()
 	
+	if sub.done != nil {
+		// Let the event in hand finish or be abandoned and the offset commit
+		<-sub.done
+	}
+	
 	eb.statsMu.Lock()
 	eb.stats.ActiveSubscribers--
 	eb.statsMu.Unlock()
@@ -166,6 +199,12 @@ func (eb *EventBus) Close() {
 	}
 	eb.mu.Unlock()
 	
+	if eb.durable != nil {
+		if err := eb.durable.log.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close event log")
+		}
+	}
+	
 	log.Info().Msg("Event bus shut down")
 }
 
@@ -174,8 +213,14 @@ func (eb *EventBus) GetStats() EventBusStats {
 	eb.mu.RLock()
 	defer eb.mu.RUnlock()
 	
+	eb.statsMu.RLock()
 	stats := eb.stats
-	stats.EventsInBuffer = int64(len(eb.eventBuffer))
+	eb.statsMu.RUnlock()
+	if eb.durable != nil {
+		stats.EventsInBuffer = eb.lag()
+	} else {
+		stats.EventsInBuffer = int64(len(eb.eventBuffer))
+	}
 	return stats
 }
 
@@ -227,12 +272,12 @@ func (eb *EventBus) deliverToSubscription(event *DocumentEvent, sub *Subscriptio
 	
 	// Try to deliver with timeout
 	ctx, cancel := context.WithTimeout(sub.ctx, 5*time.Second)
-	defer cancel()
 	
 	select {
 	case sub.channel <- event:
-		// Event queued, now call handler
+		// Event queued, now call handler; the handler owns the context from here
 		go func() {
+			defer cancel()
 			if err := sub.Handler(ctx, event); err != nil {
 				eb.statsMu.Lock()
 				eb.stats.EventsFailed++
@@ -249,6 +294,7 @@ func (eb *EventBus) deliverToSubscription(event *DocumentEvent, sub *Subscriptio
 			}
 		}()
 	case <-ctx.Done():
+		cancel()
 		eb.statsMu.Lock()
 		eb.stats.EventsFailed++
 		eb.statsMu.Unlock()
@@ -0,0 +1,541 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout of a durable event bus directory
+const (
+	segmentsDir     = "segments"
+	offsetsDir      = "offsets"
+	deadLettersFile = "dead-letters.jsonl"
+	segmentSuffix   = ".log"
+)
+
+// recordHeaderSize is the length and CRC-32 that precede each record
+const recordHeaderSize = 8
+
+// maxRecordSize bounds a record's length so a corrupt header is not read
+// as a huge allocation
+const maxRecordSize = 256 * 1024 * 1024
+
+// errTornRecord marks a record cut short or corrupted by a crash mid-write
+var errTornRecord = errors.New("torn event log record")
+
+// eventLog is an append-only log of events split into segment files. Each
+// segment is named after the offset of its first event and holds records
+// of a big-endian length, the payload's CRC-32 and the JSON-encoded event.
+// Offsets are dense: the n-th event ever appended has offset n.
+type eventLog struct {
+	dir          string
+	segmentBytes int64
+	sync         bool
+
+	mu       sync.Mutex
+	segments []int64 // base offsets, ascending
+	file     *os.File
+	size     int64
+	next     int64         // offset of the next event appended
+	appended chan struct{} // closed and replaced by each append
+	closed   bool
+}
+
+// openEventLog opens the log in dir, creating it if needed. A record torn
+// by a crash at the end of the last segment is cut off.
+func openEventLog(dir string, segmentBytes int64, sync bool) (*eventLog, error) {
+	segDir := filepath.Join(dir, segmentsDir)
+	if err := os.MkdirAll(segDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(segDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log directory: %w", err)
+	}
+	var segments []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		base, err := strconv.ParseInt(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, base)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	if len(segments) == 0 {
+		segments = []int64{0}
+	}
+
+	l := &eventLog{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		sync:         sync,
+		segments:     segments,
+		appended:     make(chan struct{}),
+	}
+
+	base := segments[len(segments)-1]
+	path := l.segmentPath(base)
+	count, size, err := scanSegment(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log segment: %w", err)
+	}
+	if info, err := file.Stat(); err == nil && info.Size() > size {
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to truncate torn event log record: %w", err)
+		}
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek event log segment: %w", err)
+	}
+
+	l.file = file
+	l.size = size
+	l.next = base + count
+	return l, nil
+}
+
+// scanSegment counts the intact records of a segment and returns the size
+// they take up
+func scanSegment(path string) (int64, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open event log segment: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var count, size int64
+	for {
+		payload, err := readRecord(reader)
+		if err != nil {
+			if err == io.EOF || errors.Is(err, errTornRecord) {
+				return count, size, nil
+			}
+			return 0, 0, fmt.Errorf("failed to scan event log segment: %w", err)
+		}
+		count++
+		size += recordHeaderSize + int64(len(payload))
+	}
+}
+
+// readRecord reads one record, returning io.EOF at a clean end and
+// errTornRecord for an incomplete or corrupt record
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errTornRecord
+		}
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > maxRecordSize {
+		return nil, errTornRecord
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errTornRecord
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errTornRecord
+	}
+	return payload, nil
+}
+
+func (l *eventLog) segmentPath(base int64) string {
+	return filepath.Join(l.dir, segmentsDir, fmt.Sprintf("%020d%s", base, segmentSuffix))
+}
+
+// Append writes an event to the log, syncing it to disk unless the log was
+// opened without syncing, and returns its offset
+func (l *eventLog) Append(event *DocumentEvent) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+	if len(payload) > maxRecordSize {
+		return 0, fmt.Errorf("event is too large for the event log: %d bytes", len(payload))
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, fmt.Errorf("event log is closed")
+	}
+
+	if l.size > 0 && l.size+int64(len(record)) > l.segmentBytes {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = l.file.Write(record)
+	if err == nil && l.sync {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		// Cut off whatever part of the record made it to the file
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, io.SeekStart)
+		return 0, fmt.Errorf("failed to write event log: %w", err)
+	}
+
+	offset := l.next
+	l.size += int64(len(record))
+	l.next++
+	close(l.appended)
+	l.appended = make(chan struct{})
+	return offset, nil
+}
+
+// rotate starts a new segment at the next offset; the caller holds the lock
+func (l *eventLog) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event log segment: %w", err)
+	}
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close event log segment: %w", err)
+	}
+	file, err := os.OpenFile(l.segmentPath(l.next), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create event log segment: %w", err)
+	}
+	l.file = file
+	l.size = 0
+	l.segments = append(l.segments, l.next)
+	return nil
+}
+
+// End returns the offset the next event will be appended at, and a channel
+// closed once it is
+func (l *eventLog) End() (int64, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next, l.appended
+}
+
+// Start returns the offset of the oldest event still in the log
+func (l *eventLog) Start() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.segments[0]
+}
+
+// segmentFor returns the base offset of the segment holding offset and of
+// the segment after it, or -1 if it is the last
+func (l *eventLog) segmentFor(offset int64) (int64, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := sort.Search(len(l.segments), func(i int) bool { return l.segments[i] > offset }) - 1
+	if i < 0 {
+		i = 0
+	}
+	next := int64(-1)
+	if i+1 < len(l.segments) {
+		next = l.segments[i+1]
+	}
+	return l.segments[i], next
+}
+
+// Compact removes the segments that hold only events before offset. The
+// last segment is always kept.
+func (l *eventLog) Compact(offset int64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := 0
+	for len(l.segments) > 1 && l.segments[1] <= offset {
+		if err := os.Remove(l.segmentPath(l.segments[0])); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove event log segment: %w", err)
+		}
+		l.segments = l.segments[1:]
+		removed++
+	}
+	return removed, nil
+}
+
+// Close syncs and closes the log
+func (l *eventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to sync event log: %w", err)
+	}
+	return l.file.Close()
+}
+
+// logCursor reads a log's events in order from an offset
+type logCursor struct {
+	log    *eventLog
+	offset int64 // offset of the next event read
+
+	file   *os.File
+	reader *bufio.Reader
+	base   int64 // base offset of the open segment
+	next   int64 // base offset of the segment after it, or -1
+}
+
+// newLogCursor creates a cursor positioned at offset; offsets before the
+// oldest retained event start at the oldest one
+func newLogCursor(l *eventLog, offset int64) *logCursor {
+	if start := l.Start(); offset < start {
+		offset = start
+	}
+	return &logCursor{log: l, offset: offset}
+}
+
+// Offset returns the offset of the next event the cursor reads
+func (c *logCursor) Offset() int64 {
+	return c.offset
+}
+
+// Next reads the event at the cursor's offset and returns it with its
+// offset. The caller makes sure the event was appended. An event that
+// cannot be decoded is skipped, leaving the cursor past it.
+func (c *logCursor) Next() (*DocumentEvent, int64, error) {
+	if c.file != nil && c.next < 0 {
+		// The log may have rotated past the open segment since it was opened
+		_, c.next = c.log.segmentFor(c.base)
+	}
+	if c.file == nil {
+		if err := c.open(); err != nil {
+			return nil, c.offset, err
+		}
+	} else if c.next >= 0 && c.offset >= c.next {
+		// The open segment is exhausted; move on to the one that follows
+		c.Close()
+		if err := c.open(); err != nil {
+			return nil, c.offset, err
+		}
+	}
+
+	offset := c.offset
+	payload, err := readRecord(c.reader)
+	if err != nil {
+		c.Close()
+		return nil, offset, fmt.Errorf("failed to read event %d: %w", offset, err)
+	}
+	c.offset++
+	var event DocumentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, offset, fmt.Errorf("failed to decode event %d: %w", offset, err)
+	}
+	return &event, offset, nil
+}
+
+// open opens the segment holding the cursor's offset and skips to it
+func (c *logCursor) open() error {
+	base, next := c.log.segmentFor(c.offset)
+	if c.offset < base {
+		// The segment was compacted away; continue at the oldest event left
+		c.offset = base
+	}
+	file, err := os.Open(c.log.segmentPath(base))
+	if err != nil {
+		return fmt.Errorf("failed to open event log segment: %w", err)
+	}
+	reader := bufio.NewReader(file)
+	for skip := base; skip < c.offset; skip++ {
+		if _, err := readRecord(reader); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to seek to event %d: %w", c.offset, err)
+		}
+	}
+	c.file = file
+	c.reader = reader
+	c.base = base
+	c.next = next
+	return nil
+}
+
+// Close releases the cursor's open segment
+func (c *logCursor) Close() {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+		c.reader = nil
+	}
+}
+
+// offsetStore persists the committed offset of each named subscription as
+// a file of its own, replaced atomically
+type offsetStore struct {
+	dir string
+}
+
+func openOffsetStore(dir string) (*offsetStore, error) {
+	path := filepath.Join(dir, offsetsDir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create offsets directory: %w", err)
+	}
+	return &offsetStore{dir: path}, nil
+}
+
+// Load returns a subscription's committed offset, reporting whether it has
+// one
+func (s *offsetStore) Load(name string) (int64, bool, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read offset of %s: %w", name, err)
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse offset of %s: %w", name, err)
+	}
+	return offset, true, nil
+}
+
+// Commit records that a subscription has handled every event before offset
+func (s *offsetStore) Commit(name string, offset int64) error {
+	path := filepath.Join(s.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write offset of %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to commit offset of %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes a subscription's committed offset
+func (s *offsetStore) Delete(name string) error {
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete offset of %s: %w", name, err)
+	}
+	return nil
+}
+
+// All returns the committed offset of every subscription
+func (s *offsetStore) All() (map[string]int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offsets directory: %w", err)
+	}
+	offsets := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		offset, ok, err := s.Load(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			offsets[entry.Name()] = offset
+		}
+	}
+	return offsets, nil
+}
+
+// DeadLetter is an event a subscription gave up on after its handler kept
+// failing
+type DeadLetter struct {
+	Subscription string         `json:"subscription"`
+	Offset       int64          `json:"offset"`
+	Attempts     int            `json:"attempts"`
+	Error        string         `json:"error"`
+	FailedAt     time.Time      `json:"failed_at"`
+	Event        *DocumentEvent `json:"event"`
+}
+
+// deadLetterQueue appends dead letters to a JSON lines file
+type deadLetterQueue struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (q *deadLetterQueue) Add(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter queue: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return file.Sync()
+}
+
+// List returns the dead letters in the order they were added
+func (q *deadLetterQueue) List() ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter queue: %w", err)
+	}
+	defer file.Close()
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var letter DeadLetter
+		if err := json.Unmarshal(line, &letter); err != nil {
+			// A line torn by a crash is the last one; skip it
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letter queue: %w", err)
+	}
+	return letters, nil
+}
@@ -21,6 +21,29 @@ const (
 	EventProcessingFailed  EventType = "processing.failed"
 )
 
+// AllEventTypes returns every event type the pipeline publishes
+func AllEventTypes() []EventType {
+	return []EventType{
+		EventDocumentAdded,
+		EventDocumentUpdated,
+		EventDocumentDeleted,
+		EventDocumentProcessed,
+		EventDocumentCleaned,
+		EventDocumentIndexed,
+		EventProcessingFailed,
+	}
+}
+
+// IsEventType reports whether t is an event type the pipeline publishes
+func IsEventType(t EventType) bool {
+	for _, eventType := range AllEventTypes() {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
 // DocumentEvent represents an event in the document processing pipeline
 type DocumentEvent struct {
 	ID        string                 `json:"id"`
@@ -0,0 +1,456 @@
+// Package replay republishes stored documents as document.added events, so
+// that cleaning rules and subscribers added later can be run over the
+// documents stored before them. A replay walks the documents in a time and
+// filter range in a fixed order at a controlled rate, records how far it
+// got, and can be resumed from there after it is cancelled or the process
+// stops.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/rs/zerolog/log"
+)
+
+// States of a replay
+const (
+	StateRunning     = "running"
+	StateCompleted   = "completed"
+	StateFailed      = "failed"
+	StateCancelled   = "cancelled"
+	StateInterrupted = "interrupted" // the process stopped while it ran
+)
+
+// DefaultRate is how many events per second a replay publishes unless asked
+// otherwise
+const DefaultRate = 20.0
+
+// MetadataReplayID is the event metadata key holding the ID of the replay
+// that published the event, which tells subscribers it is not a new
+// document
+const MetadataReplayID = "replay_id"
+
+// ErrNotFound is returned for a replay ID the manager does not know
+var ErrNotFound = errors.New("replay not found")
+
+// saveEvery is how many events a replay publishes between saves of its
+// progress
+const saveEvery = 50
+
+// maxPublishAttempts bounds the attempts to publish one event onto a bus
+// that keeps rejecting it
+const maxPublishAttempts = 10
+
+// Request selects the documents a replay republishes and how fast
+type Request struct {
+	// Since and Until bound when the documents were created; Since is
+	// inclusive and Until exclusive, and either may be left out
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	// Filters are passed to the storage backend's ListDocuments
+	Filters map[string]string `json:"filters,omitempty"`
+	// Rate is the number of events published per second
+	Rate float64 `json:"rate,omitempty"`
+	// Limit caps the number of documents replayed; 0 replays them all
+	Limit int `json:"limit,omitempty"`
+}
+
+// Validate checks the request and fills in the default rate
+func (r *Request) Validate() error {
+	if r.Since != nil && r.Until != nil && !r.Until.After(*r.Since) {
+		return fmt.Errorf("until must be after since")
+	}
+	if r.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	if r.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	if r.Rate == 0 {
+		r.Rate = DefaultRate
+	}
+	return nil
+}
+
+// includes reports whether a document falls in the request's time range
+func (r *Request) includes(doc *document.Document) bool {
+	if r.Since != nil && doc.CreatedAt.Before(*r.Since) {
+		return false
+	}
+	if r.Until != nil && !doc.CreatedAt.Before(*r.Until) {
+		return false
+	}
+	return true
+}
+
+// Cursor is the position of the last document a replay published.
+// Documents are replayed ordered by creation time, then ID.
+type Cursor struct {
+	CreatedAt  time.Time `json:"created_at"`
+	DocumentID string    `json:"document_id"`
+}
+
+// after reports whether a document comes after the cursor
+func (c *Cursor) after(doc *document.Document) bool {
+	if c == nil {
+		return true
+	}
+	if !doc.CreatedAt.Equal(c.CreatedAt) {
+		return doc.CreatedAt.After(c.CreatedAt)
+	}
+	return doc.ID > c.DocumentID
+}
+
+// Job is a replay and its progress
+type Job struct {
+	ID      string  `json:"id"`
+	Request Request `json:"request"`
+	State   string  `json:"state"`
+	// Total is the number of documents the replay selected; Published
+	// counts those published so far, across resumes
+	Total      int        `json:"total"`
+	Published  int        `json:"published"`
+	Cursor     *Cursor    `json:"cursor,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Resumable reports whether the job stopped before publishing everything
+func (j *Job) Resumable() bool {
+	return j.State == StateCancelled || j.State == StateFailed || j.State == StateInterrupted
+}
+
+// Manager runs replays onto an event bus and keeps their progress, in a
+// JSON file when it has a path
+type Manager struct {
+	storage storage.StorageBackend
+	bus     *pipeline.EventBus
+	path    string
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running map[string]*run
+	wg      sync.WaitGroup
+}
+
+// run is a replay in progress
+type run struct {
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the replay recorded where it stopped
+}
+
+// NewManager creates a replay manager that saves progress to path, or only
+// in memory if path is empty. Replays that were running when the file was
+// last saved are marked interrupted and can be resumed.
+func NewManager(store storage.StorageBackend, bus *pipeline.EventBus, path string) (*Manager, error) {
+	m := &Manager{
+		storage: store,
+		bus:     bus,
+		path:    path,
+		jobs:    make(map[string]*Job),
+		running: make(map[string]*run),
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay progress: %w", err)
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse replay progress: %w", err)
+	}
+	for _, job := range jobs {
+		if job.State == StateRunning {
+			job.State = StateInterrupted
+		}
+		m.jobs[job.ID] = job
+	}
+	return m, nil
+}
+
+// Start begins a replay in the background
+func (m *Manager) Start(req Request) (Job, error) {
+	if err := req.Validate(); err != nil {
+		return Job{}, err
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        fmt.Sprintf("replay_%d", now.UnixNano()),
+		Request:   req,
+		State:     StateRunning,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = job
+	m.launch(job)
+	return *job, nil
+}
+
+// Resume continues a cancelled, failed or interrupted replay after the last
+// document it published
+func (m *Manager) Resume(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if !job.Resumable() {
+		return Job{}, fmt.Errorf("replay %s is %s and cannot be resumed", id, job.State)
+	}
+	job.State = StateRunning
+	job.Error = ""
+	job.FinishedAt = nil
+	job.UpdatedAt = time.Now().UTC()
+	m.launch(job)
+	return *job, nil
+}
+
+// Cancel stops a running replay, keeping its progress so it can be resumed
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	r := m.running[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if r == nil {
+		return Job{}, fmt.Errorf("replay %s is not running", id)
+	}
+	r.cancel()
+	<-r.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *job, nil
+}
+
+// Get returns a replay
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns all replays, most recently started first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.After(jobs[j].StartedAt) })
+	return jobs
+}
+
+// Close cancels running replays, which can be resumed later, and waits for
+// them to stop
+func (m *Manager) Close() {
+	m.mu.Lock()
+	for _, r := range m.running {
+		r.cancel()
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// launch runs a job in the background; the caller holds the lock
+func (m *Manager) launch(job *Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &run{cancel: cancel, done: make(chan struct{})}
+	m.running[job.ID] = r
+	if err := m.saveLocked(); err != nil {
+		log.Warn().Err(err).Str("replay_id", job.ID).Msg("Failed to save replay progress")
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer close(r.done)
+		err := m.replay(ctx, job)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.running, job.ID)
+		cancel()
+
+		now := time.Now().UTC()
+		job.UpdatedAt = now
+		switch {
+		case err == nil:
+			job.State = StateCompleted
+			job.FinishedAt = &now
+		case ctx.Err() != nil:
+			job.State = StateCancelled
+		default:
+			job.State = StateFailed
+			job.Error = err.Error()
+		}
+		if err := m.saveLocked(); err != nil {
+			log.Warn().Err(err).Str("replay_id", job.ID).Msg("Failed to save replay progress")
+		}
+
+		log.Info().
+			Str("replay_id", job.ID).
+			Str("state", job.State).
+			Int("published", job.Published).
+			Int("total", job.Total).
+			Msg("Replay stopped")
+	}()
+}
+
+// replay publishes the job's documents after its cursor
+func (m *Manager) replay(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	req := job.Request
+	cursor := job.Cursor
+	m.mu.Unlock()
+
+	docs, err := m.storage.ListDocuments(ctx, req.Filters)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	selected := make([]*document.Document, 0, len(docs))
+	for _, doc := range docs {
+		if req.includes(doc) {
+			selected = append(selected, doc)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	if req.Limit > 0 && len(selected) > req.Limit {
+		selected = selected[:req.Limit]
+	}
+
+	m.mu.Lock()
+	job.Total = len(selected)
+	m.mu.Unlock()
+
+	log.Info().
+		Str("replay_id", job.ID).
+		Int("documents", len(selected)).
+		Float64("rate", req.Rate).
+		Msg("Replaying documents")
+
+	interval := time.Duration(float64(time.Second) / req.Rate)
+	pending := 0
+	var next time.Time
+	for _, doc := range selected {
+		if !cursor.after(doc) {
+			continue
+		}
+		// Space events out to the requested rate
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		next = time.Now().Add(interval)
+		if err := m.publish(ctx, job.ID, doc, interval); err != nil {
+			return err
+		}
+
+		cursor = &Cursor{CreatedAt: doc.CreatedAt, DocumentID: doc.ID}
+		m.mu.Lock()
+		job.Cursor = cursor
+		job.Published++
+		job.UpdatedAt = time.Now().UTC()
+		pending++
+		if pending >= saveEvery {
+			if err := m.saveLocked(); err != nil {
+				log.Warn().Err(err).Str("replay_id", job.ID).Msg("Failed to save replay progress")
+			}
+			pending = 0
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// publish puts a document's synthetic document.added event on the bus,
+// waiting for room on a full one
+func (m *Manager) publish(ctx context.Context, replayID string, doc *document.Document, interval time.Duration) error {
+	event := pipeline.NewDocumentEvent(pipeline.EventDocumentAdded, doc)
+	event.Metadata[MetadataReplayID] = replayID
+
+	backoff := interval
+	for attempt := 1; ; attempt++ {
+		err := m.bus.Publish(event)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxPublishAttempts {
+			return fmt.Errorf("failed to publish event for document %s: %w", doc.ID, err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// saveLocked writes the progress of all replays; the caller holds the lock
+func (m *Manager) saveLocked() error {
+	if m.path == "" {
+		return nil
+	}
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode replay progress: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create replay progress directory: %w", err)
+	}
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write replay progress: %w", err)
+	}
+	return os.Rename(tmpPath, m.path)
+}
@@ -0,0 +1,190 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newStore returns a backend holding documents doc-0 to doc-(n-1), created a
+// day apart
+func newStore(t *testing.T, n int) *storage.GovcBackend {
+	backend, err := storage.NewGovcBackend(fmt.Sprintf("replay-test-%d", time.Now().UnixNano()), nil)
+	require.NoError(t, err)
+	t.Cleanup(backend.Close)
+
+	for i := 0; i < n; i++ {
+		doc := &document.Document{
+			ID: fmt.Sprintf("doc-%d", i),
+			Source: document.Source{
+				Type: []string{"html", "text"}[i%2],
+				URL:  fmt.Sprintf("https://example.com/%d", i),
+			},
+			Content: document.Content{
+				Text:     fmt.Sprintf("Document number %d", i),
+				Metadata: map[string]string{},
+			},
+			CreatedAt: base.AddDate(0, 0, i),
+			UpdatedAt: base.AddDate(0, 0, i),
+		}
+		_, err := backend.StoreDocument(context.Background(), doc)
+		require.NoError(t, err)
+	}
+	return backend
+}
+
+// replayed collects the IDs of documents replayed onto a bus
+type replayed struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func subscribe(t *testing.T, bus *pipeline.EventBus) *replayed {
+	r := &replayed{}
+	_, err := bus.Subscribe([]pipeline.EventType{pipeline.EventDocumentAdded}, func(ctx context.Context, event *pipeline.DocumentEvent) error {
+		if _, ok := event.Metadata[MetadataReplayID]; !ok {
+			return nil
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ids = append(r.ids, event.Document.ID)
+		return nil
+	}, 100)
+	require.NoError(t, err)
+	return r
+}
+
+func (r *replayed) seen() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func waitForState(t *testing.T, m *Manager, id, state string) Job {
+	var job Job
+	require.Eventually(t, func() bool {
+		job, _ = m.Get(id)
+		return job.State == state
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestReplayRange(t *testing.T) {
+	backend := newStore(t, 6)
+	bus := backend.GetEventBus()
+	events := subscribe(t, bus)
+
+	m, err := NewManager(backend, bus, "")
+	require.NoError(t, err)
+	defer m.Close()
+
+	since := base.AddDate(0, 0, 1)
+	until := base.AddDate(0, 0, 5)
+	job, err := m.Start(Request{
+		Since:   &since,
+		Until:   &until,
+		Filters: map[string]string{"type": "text"},
+		Rate:    1000,
+	})
+	require.NoError(t, err)
+
+	job = waitForState(t, m, job.ID, StateCompleted)
+	assert.Equal(t, 2, job.Total)
+	assert.Equal(t, 2, job.Published)
+	assert.Equal(t, "doc-3", job.Cursor.DocumentID)
+	assert.NotNil(t, job.FinishedAt)
+	require.Eventually(t, func() bool { return len(events.seen()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"doc-1", "doc-3"}, events.seen())
+
+	_, err = m.Resume(job.ID)
+	assert.Error(t, err, "a completed replay cannot be resumed")
+	_, err = m.Start(Request{Since: &until, Until: &since})
+	assert.Error(t, err)
+}
+
+func TestReplayResume(t *testing.T) {
+	backend := newStore(t, 5)
+	bus := backend.GetEventBus()
+	events := subscribe(t, bus)
+	path := filepath.Join(t.TempDir(), "replays.json")
+
+	// A replay slow enough to cancel part way
+	m, err := NewManager(backend, bus, path)
+	require.NoError(t, err)
+	job, err := m.Start(Request{Rate: 20})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		job, _ := m.Get(job.ID)
+		return job.Published >= 2
+	}, 5*time.Second, 5*time.Millisecond)
+	job, err = m.Cancel(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StateCancelled, job.State)
+	assert.True(t, job.Resumable())
+	published := job.Published
+	assert.Less(t, published, 5)
+	m.Close()
+
+	// Progress survives a restart, and resuming picks up after the cursor
+	m, err = NewManager(backend, bus, path)
+	require.NoError(t, err)
+	defer m.Close()
+	saved, ok := m.Get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, published, saved.Published)
+
+	_, err = m.Resume(job.ID)
+	require.NoError(t, err)
+	job = waitForState(t, m, job.ID, StateCompleted)
+	assert.Equal(t, 5, job.Published)
+	require.Eventually(t, func() bool { return len(events.seen()) == 5 }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"doc-0", "doc-1", "doc-2", "doc-3", "doc-4"}, events.seen(), "each document is replayed once")
+}
+
+func TestReplayInterrupted(t *testing.T) {
+	backend := newStore(t, 3)
+	bus := backend.GetEventBus()
+	events := subscribe(t, bus)
+
+	// The process stopped while this replay ran
+	path := filepath.Join(t.TempDir(), "replays.json")
+	data, err := json.Marshal([]*Job{{
+		ID:        "replay_1",
+		Request:   Request{Rate: 1000},
+		State:     StateRunning,
+		Total:     3,
+		Published: 1,
+		Cursor:    &Cursor{CreatedAt: base, DocumentID: "doc-0"},
+		StartedAt: base,
+	}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	m, err := NewManager(backend, bus, path)
+	require.NoError(t, err)
+	defer m.Close()
+	job, ok := m.Get("replay_1")
+	require.True(t, ok)
+	assert.Equal(t, StateInterrupted, job.State)
+
+	_, err = m.Resume("replay_1")
+	require.NoError(t, err)
+	job = waitForState(t, m, "replay_1", StateCompleted)
+	assert.Equal(t, 3, job.Published)
+	require.Eventually(t, func() bool { return len(events.seen()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"doc-1", "doc-2"}, events.seen())
+	assert.Len(t, m.List(), 1)
+}
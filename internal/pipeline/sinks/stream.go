@@ -0,0 +1,187 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultStreamHistory is how many recent events a stream keeps for
+// followers resuming after a disconnect
+const DefaultStreamHistory = 1000
+
+// followerBuffer is how many events may wait for a follower before it is
+// dropped as too slow
+const followerBuffer = 256
+
+// Stream keeps the most recent events of an event bus and fans new ones out
+// to followers. A follower that reconnects with the ID of the last event it
+// saw is sent the events it missed, as long as the stream still holds them.
+type Stream struct {
+	mu        sync.Mutex
+	history   []*pipeline.DocumentEvent // ring of recent events
+	start     int                       // index of the oldest event in history
+	size      int
+	followers map[*Follower]struct{}
+	closed    bool
+
+	bus          *pipeline.EventBus
+	subscription *pipeline.Subscription
+}
+
+// Follower receives a stream's events
+type Follower struct {
+	// Backlog holds the events missed since the event the follower
+	// resumed after, oldest first
+	Backlog []*pipeline.DocumentEvent
+	// Missed is set when the event to resume after is no longer held, so
+	// Backlog starts with the oldest event the stream has and earlier
+	// ones were lost
+	Missed bool
+	// Events delivers new events. It is closed when the follower is
+	// dropped for falling behind or the stream is closed.
+	Events <-chan *pipeline.DocumentEvent
+
+	stream *Stream
+	events chan *pipeline.DocumentEvent
+	types  map[pipeline.EventType]bool
+}
+
+// NewStream creates a stream that keeps the last historySize events
+func NewStream(historySize int) *Stream {
+	if historySize <= 0 {
+		historySize = DefaultStreamHistory
+	}
+	return &Stream{
+		history:   make([]*pipeline.DocumentEvent, historySize),
+		followers: make(map[*Follower]struct{}),
+	}
+}
+
+// Subscribe starts streaming the events published on bus
+func (s *Stream) Subscribe(bus *pipeline.EventBus) error {
+	subscription, err := bus.Subscribe(pipeline.AllEventTypes(), s.handleEvent, followerBuffer)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe event stream: %w", err)
+	}
+	s.bus = bus
+	s.subscription = subscription
+	return nil
+}
+
+// Follow registers a follower of the given event types, or of all types if
+// none are given. With a lastEventID it resumes after that event.
+func (s *Stream) Follow(lastEventID string, types []pipeline.EventType) (*Follower, error) {
+	f := &Follower{
+		stream: s,
+		events: make(chan *pipeline.DocumentEvent, followerBuffer),
+	}
+	f.Events = f.events
+	if len(types) > 0 {
+		f.types = make(map[pipeline.EventType]bool, len(types))
+		for _, eventType := range types {
+			f.types[eventType] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("event stream is closed")
+	}
+
+	if lastEventID != "" {
+		found := -1
+		for i := s.size - 1; i >= 0; i-- {
+			if s.at(i).ID == lastEventID {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			f.Missed = true
+		}
+		for i := found + 1; i < s.size; i++ {
+			if event := s.at(i); f.wants(event) {
+				f.Backlog = append(f.Backlog, event)
+			}
+		}
+	}
+
+	s.followers[f] = struct{}{}
+	return f, nil
+}
+
+// Close stops following the stream
+func (f *Follower) Close() {
+	s := f.stream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.followers[f]; ok {
+		delete(s.followers, f)
+		close(f.events)
+	}
+}
+
+func (f *Follower) wants(event *pipeline.DocumentEvent) bool {
+	return f.types == nil || f.types[event.Type]
+}
+
+// Close ends every follower and stops streaming events
+func (s *Stream) Close() {
+	if s.subscription != nil {
+		if err := s.bus.Unsubscribe(s.subscription.ID); err != nil {
+			log.Warn().Err(err).Msg("Failed to unsubscribe event stream")
+		}
+		s.subscription = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for f := range s.followers {
+		delete(s.followers, f)
+		close(f.events)
+	}
+}
+
+// handleEvent records an event and sends it to the followers that want it,
+// dropping those too far behind to take it
+func (s *Stream) handleEvent(ctx context.Context, event *pipeline.DocumentEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+
+	if s.size < len(s.history) {
+		s.history[(s.start+s.size)%len(s.history)] = event
+		s.size++
+	} else {
+		s.history[s.start] = event
+		s.start = (s.start + 1) % len(s.history)
+	}
+
+	for f := range s.followers {
+		if !f.wants(event) {
+			continue
+		}
+		select {
+		case f.events <- event:
+		default:
+			// The follower reconnects with its last event ID to catch up
+			delete(s.followers, f)
+			close(f.events)
+			log.Warn().Str("event_id", event.ID).Msg("Dropped event stream follower that fell behind")
+		}
+	}
+	return nil
+}
+
+// at returns the i-th oldest event held; the caller holds the lock
+func (s *Stream) at(i int) *pipeline.DocumentEvent {
+	return s.history[(s.start+i)%len(s.history)]
+}
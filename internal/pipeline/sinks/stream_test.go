@@ -0,0 +1,87 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishTo(t *testing.T, s *Stream, events ...*pipeline.DocumentEvent) {
+	for _, event := range events {
+		require.NoError(t, s.handleEvent(context.Background(), event))
+	}
+}
+
+func ids(events []*pipeline.DocumentEvent) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = event.ID
+	}
+	return result
+}
+
+func numbered(eventType pipeline.EventType, n int) *pipeline.DocumentEvent {
+	event := newEvent(eventType, "html")
+	event.ID = fmt.Sprintf("evt-%d", n)
+	return event
+}
+
+func TestStreamResume(t *testing.T) {
+	s := NewStream(3)
+	defer s.Close()
+	publishTo(t, s,
+		numbered(pipeline.EventDocumentAdded, 1),
+		numbered(pipeline.EventDocumentCleaned, 2),
+		numbered(pipeline.EventDocumentAdded, 3),
+		numbered(pipeline.EventProcessingFailed, 4),
+	)
+
+	// A new follower gets only new events
+	f, err := s.Follow("", nil)
+	require.NoError(t, err)
+	assert.Empty(t, f.Backlog)
+	assert.False(t, f.Missed)
+	f.Close()
+
+	// Resuming sends the events after the last one seen, by type
+	f, err = s.Follow("evt-2", []pipeline.EventType{pipeline.EventDocumentAdded, pipeline.EventProcessingFailed})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"evt-3", "evt-4"}, ids(f.Backlog))
+	assert.False(t, f.Missed)
+
+	publishTo(t, s, numbered(pipeline.EventDocumentCleaned, 5), numbered(pipeline.EventDocumentAdded, 6))
+	assert.Equal(t, "evt-6", (<-f.Events).ID, "unwanted types are left out")
+	f.Close()
+	_, open := <-f.Events
+	assert.False(t, open)
+
+	// Events older than the history are reported missed
+	f, err = s.Follow("evt-1", nil)
+	require.NoError(t, err)
+	assert.True(t, f.Missed)
+	assert.Equal(t, []string{"evt-4", "evt-5", "evt-6"}, ids(f.Backlog))
+	f.Close()
+}
+
+func TestStreamDropsSlowFollower(t *testing.T) {
+	s := NewStream(10)
+	f, err := s.Follow("", nil)
+	require.NoError(t, err)
+
+	for i := 0; i <= followerBuffer; i++ {
+		publishTo(t, s, numbered(pipeline.EventDocumentAdded, i))
+	}
+	received := 0
+	for range f.Events {
+		received++
+	}
+	assert.Equal(t, followerBuffer, received, "the follower is closed once its buffer overflows")
+
+	s.Close()
+	_, err = s.Follow("", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,544 @@
+// Package sinks forwards pipeline events to consumers outside the process:
+// registered HTTP webhooks, and a stream of recent events that HTTP clients
+// can follow.
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers sent with each webhook delivery
+const (
+	HeaderEvent     = "X-Caia-Event"
+	HeaderEventID   = "X-Caia-Event-Id"
+	HeaderWebhookID = "X-Caia-Webhook-Id"
+	HeaderAttempt   = "X-Caia-Delivery-Attempt"
+	HeaderTimestamp = "X-Caia-Timestamp"
+	HeaderSignature = "X-Caia-Signature"
+)
+
+// WebhookSubscription prefixes the names webhooks keep their place in the
+// event log under on a durable event bus: a webhook's subscription is the
+// prefix, a dot and its ID
+const WebhookSubscription = "webhooks"
+
+// ErrWebhookNotFound is returned for a webhook ID that is not registered
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256, keyed by
+// the webhook's secret, of the timestamp, a dot and the body, prefixed with
+// "sha256=". Receivers recompute it to check a delivery came from us and
+// reject old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhook is an HTTP endpoint events are posted to
+type Webhook struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// EventTypes and SourceTypes filter the events sent: only events of
+	// the listed types about documents of the listed source types. An
+	// empty list lets everything through.
+	EventTypes  []pipeline.EventType `json:"event_types,omitempty"`
+	SourceTypes []string             `json:"source_types,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// Matches reports whether an event passes the webhook's filters
+func (w *Webhook) Matches(event *pipeline.DocumentEvent) bool {
+	if len(w.EventTypes) > 0 {
+		found := false
+		for _, eventType := range w.EventTypes {
+			if event.Type == eventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(w.SourceTypes) > 0 {
+		if event.Document == nil {
+			return false
+		}
+		for _, sourceType := range w.SourceTypes {
+			if event.Document.Source.Type == sourceType {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// DeliveryStats tracks a webhook's deliveries
+type DeliveryStats struct {
+	Delivered       int64      `json:"delivered"`
+	Failed          int64      `json:"failed"`
+	Retries         int64      `json:"retries"`
+	Pending         int        `json:"pending"`
+	LastStatus      int        `json:"last_status,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastAttemptAt   *time.Time `json:"last_attempt_at,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+}
+
+// WebhookStatus is a registered webhook, without its secret, and its
+// delivery statistics
+type WebhookStatus struct {
+	Webhook
+	Stats DeliveryStats `json:"stats"`
+}
+
+// WebhookConfig configures webhook delivery
+type WebhookConfig struct {
+	// MaxAttempts is how many times a delivery is tried before the event
+	// is given up on
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// retry after it up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// Timeout bounds a single delivery request
+	Timeout time.Duration
+	// QueueSize is how many events may wait for delivery to one webhook on
+	// an in-memory event bus
+	QueueSize int
+}
+
+// DefaultWebhookConfig returns the default webhook delivery configuration
+func DefaultWebhookConfig() *WebhookConfig {
+	return &WebhookConfig{
+		MaxAttempts:     6,
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: 5 * time.Minute,
+		Timeout:         10 * time.Second,
+		QueueSize:       1000,
+	}
+}
+
+// Dispatcher posts the events of an event bus to the registered webhooks.
+// Each webhook has its own subscription, so a slow or failing endpoint does
+// not hold up the others. On a durable bus an event counts as handled only
+// once it was delivered or, after the last attempt, dead-lettered, and
+// each webhook resumes from its own place in the log after a restart.
+// Registrations are kept in a JSON file when the dispatcher has a path.
+type Dispatcher struct {
+	config *WebhookConfig
+	client *http.Client
+	path   string
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint
+	bus       *pipeline.EventBus
+}
+
+// endpoint is a registered webhook and its subscription
+type endpoint struct {
+	hook   Webhook
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	subscription *pipeline.Subscription
+	inFlight     atomic.Int64
+
+	mu    sync.Mutex
+	stats DeliveryStats
+}
+
+// NewDispatcher creates a dispatcher with the webhooks registered in the
+// file at path, which is created on the first registration
+func NewDispatcher(path string, config *WebhookConfig) (*Dispatcher, error) {
+	if config == nil {
+		config = DefaultWebhookConfig()
+	}
+	d := &Dispatcher{
+		config:    config,
+		client:    &http.Client{Timeout: config.Timeout},
+		path:      path,
+		endpoints: make(map[string]*endpoint),
+	}
+	if path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+	var hooks []Webhook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks: %w", err)
+	}
+	for _, hook := range hooks {
+		d.add(hook)
+	}
+	return d, nil
+}
+
+// Subscribe starts forwarding the events published on bus. On a durable bus
+// each webhook resumes after the last event it handled.
+func (d *Dispatcher) Subscribe(bus *pipeline.EventBus) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bus = bus
+	for _, ep := range d.endpoints {
+		if err := d.subscribeLocked(ep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register adds a webhook, generating its ID and, when it has none, its
+// secret. The returned webhook is the only place the secret is shown.
+func (d *Dispatcher) Register(hook Webhook) (Webhook, error) {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Webhook{}, fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+	for _, eventType := range hook.EventTypes {
+		if !pipeline.IsEventType(eventType) {
+			return Webhook{}, fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	if hook.Secret == "" {
+		hook.Secret, err = randomHex(32)
+		if err != nil {
+			return Webhook{}, err
+		}
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook.ID = "wh_" + id
+	hook.CreatedAt = time.Now().UTC()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ep := d.add(hook)
+	if err := d.subscribeLocked(ep); err != nil {
+		d.removeLocked(hook.ID)
+		return Webhook{}, err
+	}
+	if err := d.saveLocked(); err != nil {
+		d.removeLocked(hook.ID)
+		return Webhook{}, err
+	}
+
+	log.Info().Str("webhook_id", hook.ID).Str("url", hook.URL).Msg("Webhook registered")
+	return hook, nil
+}
+
+// Remove unregisters a webhook, dropping the events not yet delivered to it
+func (d *Dispatcher) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.endpoints[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	d.removeLocked(id)
+	if err := d.saveLocked(); err != nil {
+		return err
+	}
+	log.Info().Str("webhook_id", id).Msg("Webhook removed")
+	return nil
+}
+
+// Get returns a webhook's status
+func (d *Dispatcher) Get(id string) (WebhookStatus, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ep, ok := d.endpoints[id]
+	if !ok {
+		return WebhookStatus{}, false
+	}
+	return d.status(ep), true
+}
+
+// List returns the status of every webhook, oldest first
+func (d *Dispatcher) List() []WebhookStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	statuses := make([]WebhookStatus, 0, len(d.endpoints))
+	for _, ep := range d.endpoints {
+		statuses = append(statuses, d.status(ep))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].CreatedAt.Before(statuses[j].CreatedAt) })
+	return statuses
+}
+
+// Close stops forwarding events, leaving deliveries in progress to be
+// retried when a durable bus is reopened
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ep := range d.endpoints {
+		d.stopLocked(ep)
+	}
+}
+
+// add creates a webhook's endpoint; the caller holds the lock or owns the
+// dispatcher
+func (d *Dispatcher) add(hook Webhook) *endpoint {
+	ctx, cancel := context.WithCancel(context.Background())
+	ep := &endpoint{
+		hook:   hook,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	d.endpoints[hook.ID] = ep
+	return ep
+}
+
+// subscribeLocked subscribes a webhook to the dispatcher's bus, if it has
+// one, for the event types it accepts; the caller holds the lock
+func (d *Dispatcher) subscribeLocked(ep *endpoint) error {
+	if d.bus == nil || ep.subscription != nil {
+		return nil
+	}
+	eventTypes := ep.hook.EventTypes
+	if len(eventTypes) == 0 {
+		eventTypes = pipeline.AllEventTypes()
+	}
+	var subscription *pipeline.Subscription
+	var err error
+	if d.bus.Durable() {
+		subscription, err = d.bus.SubscribeDurable(WebhookSubscription+"."+ep.hook.ID, eventTypes, d.handler(ep))
+	} else {
+		subscription, err = d.bus.Subscribe(eventTypes, d.handler(ep), d.config.QueueSize)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to subscribe webhook %s to events: %w", ep.hook.ID, err)
+	}
+	ep.subscription = subscription
+	return nil
+}
+
+// stopLocked cancels a webhook's deliveries and ends its subscription; the
+// caller holds the lock
+func (d *Dispatcher) stopLocked(ep *endpoint) {
+	ep.cancel()
+	if ep.subscription == nil {
+		return
+	}
+	if err := d.bus.Unsubscribe(ep.subscription.ID); err != nil {
+		log.Warn().Err(err).Str("webhook_id", ep.hook.ID).Msg("Failed to unsubscribe webhook")
+	}
+	ep.subscription = nil
+}
+
+// removeLocked stops a webhook and forgets it, along with its place in a
+// durable bus's log; the caller holds the lock
+func (d *Dispatcher) removeLocked(id string) {
+	ep, ok := d.endpoints[id]
+	if !ok {
+		return
+	}
+	d.stopLocked(ep)
+	delete(d.endpoints, id)
+	if d.bus != nil {
+		if err := d.bus.DeleteDurable(WebhookSubscription + "." + id); err != nil {
+			log.Warn().Err(err).Str("webhook_id", id).Msg("Failed to delete webhook subscription")
+		}
+	}
+}
+
+// handler returns the event handler of a webhook's subscription. It
+// returns once the event is delivered or given up on, which a durable bus
+// then dead-letters.
+func (d *Dispatcher) handler(ep *endpoint) pipeline.EventHandler {
+	return func(ctx context.Context, event *pipeline.DocumentEvent) error {
+		if !ep.hook.Matches(event) {
+			return nil
+		}
+		ep.inFlight.Add(1)
+		defer ep.inFlight.Add(-1)
+
+		err := d.deliver(ep, event)
+		if err != nil && ep.ctx.Err() != nil {
+			// Stopped mid-delivery: wait for the subscription to end so
+			// the event is left unhandled rather than failed
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return err
+	}
+}
+
+// deliver posts an event to a webhook, retrying with backoff on network
+// errors, 5xx, 408 and 429 responses. Its requests and backoff run under
+// the webhook's own timeout and attempts rather than the bus's. It returns
+// a permanent error once it gives up.
+func (d *Dispatcher) deliver(ep *endpoint, event *pipeline.DocumentEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to encode event for webhook: %w", err))
+	}
+
+	backoff := d.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		status, err := d.post(ep, event, body, attempt)
+		if ep.ctx.Err() != nil {
+			return ep.ctx.Err()
+		}
+
+		now := time.Now().UTC()
+		ep.mu.Lock()
+		ep.stats.LastAttemptAt = &now
+		ep.stats.LastStatus = status
+		if err == nil {
+			ep.stats.Delivered++
+			ep.stats.LastDeliveredAt = &now
+			ep.stats.LastError = ""
+			ep.mu.Unlock()
+			return nil
+		}
+		ep.stats.LastError = err.Error()
+		retry := retryable(status) && attempt < d.config.MaxAttempts
+		if retry {
+			ep.stats.Retries++
+		} else {
+			ep.stats.Failed++
+		}
+		ep.mu.Unlock()
+
+		if !retry {
+			log.Error().
+				Err(err).
+				Str("webhook_id", ep.hook.ID).
+				Str("event_id", event.ID).
+				Int("attempts", attempt).
+				Msg("Webhook delivery failed")
+			return pipeline.Permanent(err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ep.ctx.Done():
+			return ep.ctx.Err()
+		}
+		backoff *= 2
+		if backoff > d.config.MaxRetryBackoff {
+			backoff = d.config.MaxRetryBackoff
+		}
+	}
+}
+
+// post sends one delivery attempt, returning the response status, or 0 if
+// there was no response
+func (d *Dispatcher) post(ep *endpoint, event *pipeline.DocumentEvent, body []byte, attempt int) (int, error) {
+	req, err := http.NewRequestWithContext(ep.ctx, http.MethodPost, ep.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CAIA-Library-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(event.Type))
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderWebhookID, ep.hook.ID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(ep.hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed delivery may succeed if tried again
+func retryable(status int) bool {
+	return status == 0 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// status returns a webhook's status with its secret left out. Pending is
+// the events of a durable bus's log it has yet to handle, or on an
+// in-memory bus the deliveries in progress.
+func (d *Dispatcher) status(ep *endpoint) WebhookStatus {
+	hook := ep.hook
+	hook.Secret = ""
+	ep.mu.Lock()
+	stats := ep.stats
+	ep.mu.Unlock()
+	if d.bus != nil && d.bus.Durable() && ep.subscription != nil {
+		stats.Pending = int(d.bus.Backlog(ep.subscription.ID))
+	} else {
+		stats.Pending = int(ep.inFlight.Load())
+	}
+	return WebhookStatus{Webhook: hook, Stats: stats}
+}
+
+// saveLocked writes the registered webhooks; the caller holds the lock
+func (d *Dispatcher) saveLocked() error {
+	if d.path == "" {
+		return nil
+	}
+	hooks := make([]Webhook, 0, len(d.endpoints))
+	for _, ep := range d.endpoints {
+		hooks = append(hooks, ep.hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(hooks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode webhooks: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create webhooks directory: %w", err)
+	}
+	// The file holds the secrets, so only its owner may read it
+	tmpPath := d.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write webhooks: %w", err)
+	}
+	return os.Rename(tmpPath, d.path)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,264 @@
+package sinks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWebhookConfig() *WebhookConfig {
+	config := DefaultWebhookConfig()
+	config.RetryBackoff = time.Millisecond
+	config.MaxRetryBackoff = 5 * time.Millisecond
+	config.MaxAttempts = 3
+	return config
+}
+
+func newEvent(eventType pipeline.EventType, sourceType string) *pipeline.DocumentEvent {
+	return pipeline.NewDocumentEvent(eventType, &document.Document{
+		ID:     "doc-1",
+		Source: document.Source{Type: sourceType, URL: "https://example.com/doc"},
+	})
+}
+
+// receiver is a webhook endpoint that records the deliveries it accepts
+type receiver struct {
+	mu       sync.Mutex
+	received []*pipeline.DocumentEvent
+	headers  []http.Header
+	failures int32 // responses to fail with before accepting
+	status   int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if atomic.AddInt32(&r.failures, -1) >= 0 {
+		w.WriteHeader(r.status)
+		return
+	}
+
+	var event pipeline.DocumentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if req.Header.Get(HeaderSignature) != Sign("s3cret", timestamp, body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received = append(r.received, &event)
+	r.headers = append(r.headers, req.Header.Clone())
+}
+
+func (r *receiver) events() []*pipeline.DocumentEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*pipeline.DocumentEvent(nil), r.received...)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	rcv := &receiver{failures: 2, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	bus := pipeline.NewEventBus(100, 2)
+	defer bus.Close()
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	dispatcher, err := NewDispatcher(path, testWebhookConfig())
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Subscribe(bus))
+
+	hook, err := dispatcher.Register(Webhook{
+		URL:         server.URL,
+		Secret:      "s3cret",
+		EventTypes:  []pipeline.EventType{pipeline.EventDocumentCleaned, pipeline.EventProcessingFailed},
+		SourceTypes: []string{"html"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, hook.ID)
+
+	// Only the cleaned html document passes the filters
+	require.NoError(t, bus.Publish(newEvent(pipeline.EventDocumentAdded, "html")))
+	require.NoError(t, bus.Publish(newEvent(pipeline.EventDocumentCleaned, "pdf")))
+	cleaned := newEvent(pipeline.EventDocumentCleaned, "html")
+	require.NoError(t, bus.Publish(cleaned))
+
+	require.Eventually(t, func() bool { return len(rcv.events()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	events := rcv.events()
+	require.Len(t, events, 1)
+	assert.Equal(t, cleaned.ID, events[0].ID)
+	assert.Equal(t, "doc-1", events[0].Document.ID)
+
+	headers := rcv.headers[0]
+	assert.Equal(t, string(pipeline.EventDocumentCleaned), headers.Get(HeaderEvent))
+	assert.Equal(t, cleaned.ID, headers.Get(HeaderEventID))
+	assert.Equal(t, hook.ID, headers.Get(HeaderWebhookID))
+	assert.Equal(t, "3", headers.Get(HeaderAttempt), "two 503s were retried")
+
+	status, ok := dispatcher.Get(hook.ID)
+	require.True(t, ok)
+	assert.Empty(t, status.Secret)
+	assert.Equal(t, int64(1), status.Stats.Delivered)
+	assert.Equal(t, int64(2), status.Stats.Retries)
+	assert.Equal(t, http.StatusOK, status.Stats.LastStatus)
+
+	// Registrations survive a restart, secret included
+	dispatcher.Close()
+	reloaded, err := NewDispatcher(path, testWebhookConfig())
+	require.NoError(t, err)
+	defer reloaded.Close()
+	require.Len(t, reloaded.List(), 1)
+	assert.Equal(t, "s3cret", reloaded.endpoints[hook.ID].hook.Secret)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, reloaded.Remove(hook.ID))
+	assert.ErrorIs(t, reloaded.Remove(hook.ID), ErrWebhookNotFound)
+	assert.Empty(t, reloaded.List())
+}
+
+func TestWebhookPermanentFailure(t *testing.T) {
+	rcv := &receiver{failures: 100, status: http.StatusGone}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	dispatcher, err := NewDispatcher("", testWebhookConfig())
+	require.NoError(t, err)
+	defer dispatcher.Close()
+	hook, err := dispatcher.Register(Webhook{URL: server.URL})
+	require.NoError(t, err)
+	assert.Len(t, hook.Secret, 64, "a secret is generated")
+
+	ep := dispatcher.endpoints[hook.ID]
+	assert.Error(t, dispatcher.deliver(ep, newEvent(pipeline.EventDocumentAdded, "html")))
+	status, _ := dispatcher.Get(hook.ID)
+	assert.Equal(t, int64(1), status.Stats.Failed)
+	assert.Equal(t, int64(0), status.Stats.Retries, "a 410 is not retried")
+	assert.Equal(t, http.StatusGone, status.Stats.LastStatus)
+
+	_, err = dispatcher.Register(Webhook{URL: "ftp://example.com/hook"})
+	assert.Error(t, err)
+	_, err = dispatcher.Register(Webhook{URL: server.URL, EventTypes: []pipeline.EventType{"document.renamed"}})
+	assert.Error(t, err)
+}
+
+func TestWebhookDurableDelivery(t *testing.T) {
+	busDir := t.TempDir()
+	openBus := func() *pipeline.EventBus {
+		config := pipeline.DefaultDurableConfig(busDir)
+		config.RetryBackoff = time.Millisecond
+		config.MaxRetryBackoff = 5 * time.Millisecond
+		bus, err := pipeline.NewDurableEventBus(config)
+		require.NoError(t, err)
+		return bus
+	}
+
+	healthy := &receiver{}
+	healthyServer := httptest.NewServer(healthy)
+	defer healthyServer.Close()
+	gone := &receiver{failures: 100, status: http.StatusGone}
+	goneServer := httptest.NewServer(gone)
+	defer goneServer.Close()
+	// The stalled endpoint holds every request until released
+	stalled := &receiver{}
+	release := make(chan struct{})
+	var stalledRequests atomic.Int32
+	stalledServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := stalledRequests.Add(1)
+		select {
+		case <-release:
+		case <-req.Context().Done():
+			return
+		}
+		if n == 1 {
+			// Held across the restart, so its sender is gone
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		stalled.ServeHTTP(w, req)
+	}))
+	defer stalledServer.Close()
+
+	bus := openBus()
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	dispatcher, err := NewDispatcher(path, testWebhookConfig())
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Subscribe(bus))
+	hooks := make(map[string]Webhook)
+	for name, url := range map[string]string{"healthy": healthyServer.URL, "gone": goneServer.URL, "stalled": stalledServer.URL} {
+		hooks[name], err = dispatcher.Register(Webhook{URL: url, Secret: "s3cret"})
+		require.NoError(t, err)
+	}
+
+	var published []string
+	for i := 0; i < 5; i++ {
+		event := newEvent(pipeline.EventDocumentAdded, "html")
+		require.NoError(t, bus.Publish(event))
+		published = append(published, event.ID)
+	}
+	eventIDs := func(r *receiver) []string {
+		var ids []string
+		for _, event := range r.events() {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	// A stalled endpoint holds up neither the others nor their retries
+	require.Eventually(t, func() bool { return len(healthy.events()) == 5 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, published, eventIDs(healthy))
+	assert.Equal(t, int32(1), stalledRequests.Load())
+
+	// Rejected events are dead-lettered under the webhook's subscription
+	var letters []pipeline.DeadLetter
+	require.Eventually(t, func() bool {
+		letters, err = bus.DeadLetters()
+		return err == nil && len(letters) == 5
+	}, 5*time.Second, 5*time.Millisecond)
+	for _, letter := range letters {
+		assert.Equal(t, WebhookSubscription+"."+hooks["gone"].ID, letter.Subscription)
+		assert.Equal(t, 1, letter.Attempts, "a 410 is not retried by the bus either")
+	}
+	status, _ := dispatcher.Get(hooks["stalled"].ID)
+	assert.Equal(t, 5, status.Stats.Pending)
+
+	// Restart with every event still to be delivered to the stalled endpoint
+	dispatcher.Close()
+	bus.Close()
+	close(release)
+
+	bus = openBus()
+	defer bus.Close()
+	dispatcher, err = NewDispatcher(path, testWebhookConfig())
+	require.NoError(t, err)
+	defer dispatcher.Close()
+	require.NoError(t, dispatcher.Subscribe(bus))
+
+	require.Eventually(t, func() bool { return len(stalled.events()) == 5 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, published, eventIDs(stalled), "the interrupted delivery is retried")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, published, eventIDs(healthy), "delivered events are not sent again")
+	letters, err = bus.DeadLetters()
+	require.NoError(t, err)
+	assert.Len(t, letters, 5)
+	assert.Equal(t, int32(95), atomic.LoadInt32(&gone.failures))
+}
@@ -4,19 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
 )
 
 // API provides HTTP endpoints for document presentation
 type API struct {
-	renderer *Renderer
-	storage  Storage
-	config   *APIConfig
+	renderer  *Renderer
+	storage   Storage
+	config    *APIConfig
+	textIndex *fulltext.Index
+	semantic  SemanticSearcher
 }
 
 // APIConfig configures the presentation API
@@ -49,12 +53,31 @@ func NewAPI(renderer *Renderer, storage Storage, config *APIConfig) *API {
 	}
 }
 
+// SetTextIndex makes search rank documents by BM25 using the given full-text
+// index instead of scanning storage. The index must use the storage's
+// document IDs.
+func (api *API) SetTextIndex(index *fulltext.Index) {
+	api.textIndex = index
+}
+
+// SetSemanticSearch enables semantic and hybrid search modes. When searcher
+// can also score passages, search results use it to pick snippets and
+// highlights for documents that matched on meaning alone.
+func (api *API) SetSemanticSearch(searcher SemanticSearcher) {
+	api.semantic = searcher
+	if scorer, ok := searcher.(PassageScorer); ok {
+		api.renderer.SetPassageScorer(scorer)
+	}
+}
+
+// Handler returns the API's routes wrapped in its middleware
+func (api *API) Handler() http.Handler {
+	return api.addMiddleware(api.setupRoutes())
+}
+
 // Start starts the API server
 func (api *API) Start() error {
-	router := api.setupRoutes()
-
-	// Add middleware
-	handler := api.addMiddleware(router)
+	handler := api.Handler()
 
 	addr := fmt.Sprintf("%s:%d", api.config.Host, api.config.Port)
 	log.Info().Str("address", addr).Msg("Starting presentation API")
@@ -263,57 +286,136 @@ func (api *API) exportDocument(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (api *API) searchDocuments(w http.ResponseWriter, r *http.Request) {
-	var query string
+// searchRequest holds the parameters of a search, from the query string
+// of a GET or the JSON body of a POST
+type searchRequest struct {
+	Query string `json:"query"`
+	Mode  string `json:"mode"`
+	FusionOptions
+}
+
+func (api *API) parseSearchRequest(r *http.Request) (*searchRequest, error) {
+	req := &searchRequest{FusionOptions: *DefaultFusionOptions()}
 
 	if r.Method == "GET" {
-		query = r.URL.Query().Get("q")
+		params := r.URL.Query()
+		req.Query = params.Get("q")
+		req.Mode = params.Get("mode")
+		if fusion := params.Get("fusion"); fusion != "" {
+			req.Method = fusion
+		}
+		for name, target := range map[string]*float64{
+			"lexical_weight":  &req.LexicalWeight,
+			"semantic_weight": &req.SemanticWeight,
+		} {
+			if value := params.Get(name); value != "" {
+				weight, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %s", name, value)
+				}
+				*target = weight
+			}
+		}
+		if value := params.Get("rrf_k"); value != "" {
+			k, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rrf_k: %s", value)
+			}
+			req.RRFK = k
+		}
 	} else {
 		// POST request with JSON body
-		var searchReq struct {
-			Query string `json:"query"`
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
 		}
-		if err := json.NewDecoder(r.Body).Decode(&searchReq); err != nil {
-			api.sendError(w, http.StatusBadRequest, "Invalid request body", err)
-			return
+	}
+
+	if req.Mode == "" {
+		req.Mode = SearchModeLexical
+		if api.semantic != nil {
+			req.Mode = SearchModeHybrid
 		}
-		query = searchReq.Query
+	}
+	switch req.Mode {
+	case SearchModeLexical:
+	case SearchModeSemantic, SearchModeHybrid:
+		if api.semantic == nil {
+			return nil, fmt.Errorf("%s search is not configured", req.Mode)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported search mode: %s", req.Mode)
 	}
 
-	if query == "" {
-		api.sendError(w, http.StatusBadRequest, "Query parameter is required", nil)
-		return
+	if err := req.FusionOptions.Validate(); err != nil {
+		return nil, err
 	}
+	return req, nil
+}
 
-	// Simple search implementation - in production would use proper search engine
-	allDocs, err := api.storage.List("", 0, 1000)
+func (api *API) searchDocuments(w http.ResponseWriter, r *http.Request) {
+	req, err := api.parseSearchRequest(r)
 	if err != nil {
-		api.sendError(w, http.StatusInternalServerError, "Failed to search documents", err)
+		api.sendError(w, http.StatusBadRequest, "Invalid search request", err)
 		return
 	}
+	query := req.Query
 
-	// Filter documents containing query
-	var matchedDocs []*Document
-	scores := make(map[string]float64)
-	queryLower := strings.ToLower(query)
+	if query == "" {
+		api.sendError(w, http.StatusBadRequest, "Query parameter is required", nil)
+		return
+	}
 
-	for _, doc := range allDocs {
-		contentLower := strings.ToLower(doc.Content)
-		if strings.Contains(contentLower, queryLower) {
-			matchedDocs = append(matchedDocs, doc)
-			// Simple scoring based on frequency
-			count := strings.Count(contentLower, queryLower)
-			scores[doc.ID] = float64(count) / float64(len(strings.Fields(doc.Content)))
+	start := time.Now()
+	var lexical, semantic []RankedID
+	if req.Mode != SearchModeSemantic {
+		if api.textIndex != nil {
+			lexical = api.searchIndex(query)
+		} else {
+			lexical, err = api.searchScan(query)
+		}
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Failed to search documents", err)
+			return
+		}
+	}
+	if req.Mode != SearchModeLexical {
+		semantic, err = api.semantic.SearchSimilar(r.Context(), query, maxSemanticResults)
+		if err != nil {
+			api.sendError(w, http.StatusInternalServerError, "Failed to search documents", err)
+			return
 		}
 	}
 
+	// A single ranking keeps its own scores; fusion only applies to hybrid
+	var hits []FusedHit
+	switch req.Mode {
+	case SearchModeLexical:
+		hits = unfusedHits(lexical, SearchModeLexical)
+	case SearchModeSemantic:
+		hits = unfusedHits(semantic, SearchModeSemantic)
+	default:
+		hits, err = FuseRankings(lexical, semantic, &req.FusionOptions)
+		if err != nil {
+			api.sendError(w, http.StatusBadRequest, "Invalid search request", err)
+			return
+		}
+	}
+
+	matchedDocs, scores, matchedBy, err := api.loadHits(hits)
+	if err != nil {
+		api.sendError(w, http.StatusInternalServerError, "Failed to search documents", err)
+		return
+	}
+
 	// Create search results
 	results := &SearchResults{
 		Query:      query,
 		Documents:  matchedDocs,
 		Scores:     scores,
+		MatchedBy:  matchedBy,
+		Mode:       req.Mode,
 		TotalHits:  len(matchedDocs),
-		SearchTime: 100 * time.Millisecond, // Simulated
+		SearchTime: time.Since(start),
 	}
 
 	// Parse rendering options
@@ -332,7 +434,7 @@ func (api *API) searchDocuments(w http.ResponseWriter, r *http.Request) {
 		CollectionOptions: CollectionOptions{
 			RenderOptions: RenderOptions{
 				Format:         OutputFormat(params.Get("format")),
-				HighlightTerms: []string{query},
+				HighlightTerms: highlightTerms(query),
 				MaxLength:      200,
 			},
 			PageSize:   pageSize,
@@ -345,7 +447,7 @@ func (api *API) searchDocuments(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Render search results
-	rendered, err := api.renderer.RenderSearch(results, options)
+	rendered, err := api.renderer.RenderSearch(r.Context(), results, options)
 	if err != nil {
 		api.sendError(w, http.StatusInternalServerError, "Failed to render search results", err)
 		return
@@ -354,6 +456,101 @@ func (api *API) searchDocuments(w http.ResponseWriter, r *http.Request) {
 	api.sendJSON(w, rendered)
 }
 
+// maxSearchResults caps the documents considered by a single search
+const maxSearchResults = 1000
+
+// maxSemanticResults caps the documents taken from the semantic ranking;
+// similarity scores far down the list carry little signal
+const maxSemanticResults = 100
+
+// searchIndex ranks documents with the full-text index
+func (api *API) searchIndex(query string) []RankedID {
+	hits := api.textIndex.Search(&fulltext.Query{Text: query, Limit: maxSearchResults})
+
+	ranked := make([]RankedID, len(hits))
+	for i, hit := range hits {
+		ranked[i] = RankedID{ID: hit.ID, Score: hit.Score}
+	}
+	return ranked
+}
+
+// searchScan matches documents containing the query as a substring, for
+// deployments without a full-text index
+func (api *API) searchScan(query string) ([]RankedID, error) {
+	allDocs, err := api.storage.List("", 0, maxSearchResults)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []RankedID
+	queryLower := strings.ToLower(query)
+
+	for _, doc := range allDocs {
+		contentLower := strings.ToLower(doc.Content)
+		if strings.Contains(contentLower, queryLower) {
+			// Simple scoring based on frequency
+			count := strings.Count(contentLower, queryLower)
+			ranked = append(ranked, RankedID{
+				ID:    doc.ID,
+				Score: float64(count) / float64(len(strings.Fields(doc.Content))),
+			})
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked, nil
+}
+
+// unfusedHits wraps a single ranking as search hits
+func unfusedHits(ranking []RankedID, mode string) []FusedHit {
+	hits := make([]FusedHit, len(ranking))
+	for i, ranked := range ranking {
+		hits[i] = FusedHit{ID: ranked.ID, Score: ranked.Score}
+		if mode == SearchModeLexical {
+			hits[i].LexicalRank = i + 1
+		} else {
+			hits[i].SemanticRank = i + 1
+		}
+	}
+	return hits
+}
+
+// loadHits fetches the documents of the hits in order, skipping any the
+// indexes still list after they were deleted
+func (api *API) loadHits(hits []FusedHit) ([]*Document, map[string]float64, map[string][]string, error) {
+	docs := make([]*Document, 0, len(hits))
+	scores := make(map[string]float64, len(hits))
+	matchedBy := make(map[string][]string, len(hits))
+	for _, hit := range hits {
+		doc, err := api.storage.Get(hit.ID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				continue
+			}
+			return nil, nil, nil, err
+		}
+		docs = append(docs, doc)
+		scores[doc.ID] = hit.Score
+		matchedBy[doc.ID] = hit.MatchedBy()
+	}
+	return docs, scores, matchedBy, nil
+}
+
+// highlightTerms splits a query into its quoted phrases and single words
+func highlightTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return terms
+}
+
 func (api *API) listCollections(w http.ResponseWriter, r *http.Request) {
 	// In a real implementation, this would list document collections/categories
 	collections := []map[string]interface{}{
@@ -0,0 +1,175 @@
+package presentation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+)
+
+// Metadata keys BackendStorage adds to the stored metadata of a document
+const (
+	MetaSourceType = "source_type"
+	MetaSourceURL  = "source_url"
+	MetaCreatedAt  = "created_at"
+	MetaUpdatedAt  = "updated_at"
+)
+
+// BackendStorage serves presentation documents from a storage backend. A
+// document's content is its extracted text and its metadata is the stored
+// metadata plus its source and timestamps.
+type BackendStorage struct {
+	backend storage.StorageBackend
+}
+
+// NewBackendStorage creates presentation storage over backend
+func NewBackendStorage(backend storage.StorageBackend) *BackendStorage {
+	return &BackendStorage{backend: backend}
+}
+
+// Store stores doc as a text document. Its source is read from the
+// source_type and source_url metadata.
+func (s *BackendStorage) Store(doc *Document) error {
+	metadata := make(map[string]string, len(doc.Metadata))
+	for k, v := range doc.Metadata {
+		metadata[k] = fmt.Sprint(v)
+	}
+
+	sourceType := metadata[MetaSourceType]
+	if sourceType == "" {
+		sourceType = "text"
+	}
+	sourceURL := metadata[MetaSourceURL]
+	if sourceURL == "" {
+		sourceURL = "presentation://" + doc.ID
+	}
+
+	now := time.Now()
+	_, err := s.backend.StoreDocument(context.Background(), &document.Document{
+		ID: doc.ID,
+		Source: document.Source{
+			Type: sourceType,
+			URL:  sourceURL,
+		},
+		Content: document.Content{
+			Text:     doc.Content,
+			Metadata: metadata,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	return err
+}
+
+// Get returns the document with the given ID
+func (s *BackendStorage) Get(id string) (*Document, error) {
+	doc, err := s.backend.GetDocument(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return presentDocument(doc), nil
+}
+
+// List returns up to limit documents whose IDs start with prefix, in ID
+// order, skipping the first offset. A limit of 0 returns them all.
+func (s *BackendStorage) List(prefix string, offset, limit int) ([]*Document, error) {
+	docs, err := s.backend.ListDocuments(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+
+	result := []*Document{}
+	for _, doc := range docs {
+		if !strings.HasPrefix(doc.ID, prefix) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		result = append(result, presentDocument(doc))
+	}
+	return result, nil
+}
+
+// Delete deletes the document with the given ID
+func (s *BackendStorage) Delete(id string) error {
+	_, err := s.backend.DeleteDocument(context.Background(), id, "deleted through the presentation API")
+	return err
+}
+
+// Search returns documents whose text contains query, ignoring case. It
+// scans every document; the API only uses it without a full-text index.
+func (s *BackendStorage) Search(ctx context.Context, query string, options *SearchOptionsStorage) ([]*Document, error) {
+	var filters map[string]string
+	maxResults := 0
+	if options != nil {
+		filters = options.Filters
+		maxResults = options.MaxResults
+	}
+
+	docs, err := s.backend.ListDocuments(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	results := []*Document{}
+	for _, doc := range docs {
+		if maxResults > 0 && len(results) >= maxResults {
+			break
+		}
+		if strings.Contains(strings.ToLower(doc.Content.Text), query) {
+			results = append(results, presentDocument(doc))
+		}
+	}
+	return results, nil
+}
+
+// GetStats returns the number and total size of stored documents
+func (s *BackendStorage) GetStats() (*Stats, error) {
+	docs, err := s.backend.ListDocuments(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{TotalDocuments: int64(len(docs))}
+	for _, doc := range docs {
+		stats.TotalSize += int64(len(doc.Content.Text) + len(doc.Content.Raw))
+		if doc.UpdatedAt.After(stats.LastUpdated) {
+			stats.LastUpdated = doc.UpdatedAt
+		}
+	}
+	return stats, nil
+}
+
+// Close does nothing; the backend is closed by its owner
+func (s *BackendStorage) Close() error {
+	return nil
+}
+
+func presentDocument(doc *document.Document) *Document {
+	metadata := make(map[string]interface{}, len(doc.Content.Metadata)+4)
+	for k, v := range doc.Content.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetaSourceType] = doc.Source.Type
+	metadata[MetaSourceURL] = doc.Source.URL
+	metadata[MetaCreatedAt] = doc.CreatedAt.Format(time.RFC3339)
+	metadata[MetaUpdatedAt] = doc.UpdatedAt.Format(time.RFC3339)
+
+	return &Document{
+		ID:       doc.ID,
+		Content:  doc.Content.Text,
+		Metadata: metadata,
+	}
+}
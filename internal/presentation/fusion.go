@@ -0,0 +1,179 @@
+package presentation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Search modes
+const (
+	SearchModeLexical  = "lexical"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+)
+
+// Methods for fusing lexical and semantic rankings
+const (
+	// FusionRRF combines rankings by reciprocal rank, ignoring raw scores
+	FusionRRF = "rrf"
+
+	// FusionWeighted combines min-max normalized scores
+	FusionWeighted = "weighted"
+)
+
+// DefaultRRFK is the rank offset from the original reciprocal rank fusion
+// paper; larger values flatten the advantage of top ranks
+const DefaultRRFK = 60
+
+// FusionOptions controls how hybrid search merges its two rankings
+type FusionOptions struct {
+	Method         string  `json:"fusion"`
+	LexicalWeight  float64 `json:"lexical_weight"`
+	SemanticWeight float64 `json:"semantic_weight"`
+	RRFK           int     `json:"rrf_k"`
+}
+
+// DefaultFusionOptions returns reciprocal rank fusion with equal weights
+func DefaultFusionOptions() *FusionOptions {
+	return &FusionOptions{
+		Method:         FusionRRF,
+		LexicalWeight:  1,
+		SemanticWeight: 1,
+		RRFK:           DefaultRRFK,
+	}
+}
+
+// Validate checks the options can produce a ranking
+func (o *FusionOptions) Validate() error {
+	if o.Method != FusionRRF && o.Method != FusionWeighted {
+		return fmt.Errorf("unsupported fusion method: %s", o.Method)
+	}
+	if o.LexicalWeight < 0 || o.SemanticWeight < 0 {
+		return fmt.Errorf("fusion weights must not be negative")
+	}
+	if o.LexicalWeight == 0 && o.SemanticWeight == 0 {
+		return fmt.Errorf("at least one fusion weight must be positive")
+	}
+	if o.RRFK <= 0 {
+		return fmt.Errorf("rrf_k must be positive")
+	}
+	return nil
+}
+
+// RankedID is a document's position in one ranking, best first
+type RankedID struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// FusedHit is a document in the merged ranking. The ranks are 1-based
+// positions in each input ranking, zero when absent from it.
+type FusedHit struct {
+	ID            string  `json:"id"`
+	Score         float64 `json:"score"`
+	LexicalRank   int     `json:"lexical_rank,omitempty"`
+	SemanticRank  int     `json:"semantic_rank,omitempty"`
+	LexicalScore  float64 `json:"lexical_score,omitempty"`
+	SemanticScore float64 `json:"semantic_score,omitempty"`
+}
+
+// MatchedBy names the rankings the hit appeared in
+func (h *FusedHit) MatchedBy() []string {
+	var matched []string
+	if h.LexicalRank > 0 {
+		matched = append(matched, SearchModeLexical)
+	}
+	if h.SemanticRank > 0 {
+		matched = append(matched, SearchModeSemantic)
+	}
+	return matched
+}
+
+// FuseRankings merges a lexical and a semantic ranking, each ordered best
+// first, into one ranking ordered by fused score
+func FuseRankings(lexical, semantic []RankedID, options *FusionOptions) ([]FusedHit, error) {
+	if options == nil {
+		options = DefaultFusionOptions()
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	hits := make(map[string]*FusedHit)
+	get := func(id string) *FusedHit {
+		hit, ok := hits[id]
+		if !ok {
+			hit = &FusedHit{ID: id}
+			hits[id] = hit
+		}
+		return hit
+	}
+
+	for i, ranked := range lexical {
+		hit := get(ranked.ID)
+		hit.LexicalRank = i + 1
+		hit.LexicalScore = ranked.Score
+	}
+	for i, ranked := range semantic {
+		hit := get(ranked.ID)
+		hit.SemanticRank = i + 1
+		hit.SemanticScore = ranked.Score
+	}
+
+	lexicalNorm := minMaxNormalizer(lexical)
+	semanticNorm := minMaxNormalizer(semantic)
+
+	fused := make([]FusedHit, 0, len(hits))
+	for _, hit := range hits {
+		switch options.Method {
+		case FusionRRF:
+			if hit.LexicalRank > 0 {
+				hit.Score += options.LexicalWeight / float64(options.RRFK+hit.LexicalRank)
+			}
+			if hit.SemanticRank > 0 {
+				hit.Score += options.SemanticWeight / float64(options.RRFK+hit.SemanticRank)
+			}
+		case FusionWeighted:
+			if hit.LexicalRank > 0 {
+				hit.Score += options.LexicalWeight * lexicalNorm(hit.LexicalScore)
+			}
+			if hit.SemanticRank > 0 {
+				hit.Score += options.SemanticWeight * semanticNorm(hit.SemanticScore)
+			}
+		}
+		fused = append(fused, *hit)
+	}
+
+	sort.Slice(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		return fused[i].ID < fused[j].ID
+	})
+	return fused, nil
+}
+
+// minMaxNormalizer maps the ranking's scores onto [0, 1]. When every score
+// is equal they all map to 1.
+func minMaxNormalizer(ranking []RankedID) func(float64) float64 {
+	if len(ranking) == 0 {
+		return func(float64) float64 { return 0 }
+	}
+
+	lo, hi := ranking[0].Score, ranking[0].Score
+	for _, ranked := range ranking {
+		if ranked.Score < lo {
+			lo = ranked.Score
+		}
+		if ranked.Score > hi {
+			hi = ranked.Score
+		}
+	}
+
+	return func(score float64) float64 {
+		if hi == lo {
+			return 1
+		}
+		return (score - lo) / (hi - lo)
+	}
+}
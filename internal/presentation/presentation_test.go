@@ -12,6 +12,9 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/presentation"
 	"github.com/Caia-Tech/caia-library/internal/procurement"
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -206,7 +209,7 @@ func TestRenderer(t *testing.T) {
 			HighlightMatches: true,
 		}
 
-		rendered, err := renderer.RenderSearch(searchResults, options)
+		rendered, err := renderer.RenderSearch(context.Background(), searchResults, options)
 		require.NoError(t, err)
 		assert.Equal(t, "test query", rendered.Query)
 		assert.Equal(t, 2, len(rendered.Results))
@@ -426,6 +429,176 @@ func TestPresentationAPI(t *testing.T) {
 	})
 }
 
+// fakeSemantic returns a fixed semantic ranking and scores passages
+// mentioning dogs as relevant
+type fakeSemantic struct {
+	ranking []presentation.RankedID
+}
+
+func (f *fakeSemantic) SearchSimilar(ctx context.Context, query string, k int) ([]presentation.RankedID, error) {
+	return f.ranking, nil
+}
+
+func (f *fakeSemantic) ScorePassages(ctx context.Context, query string, passages []string) ([]float64, error) {
+	scores := make([]float64, len(passages))
+	for i, passage := range passages {
+		scores[i] = 0.1
+		if strings.Contains(passage, "dog") {
+			scores[i] = 0.9
+		}
+	}
+	return scores, nil
+}
+
+func TestHybridSearch(t *testing.T) {
+	storage := NewMockStorage()
+	index := fulltext.NewIndex(nil)
+	for id, content := range map[string]string{
+		"lex":  "Canine training tips. Reward good behaviour.",
+		"both": "Canine nutrition matters. Feed your dog twice a day.",
+		"sem":  "Puppies need patience. A dog learns through repetition and praise.",
+	} {
+		require.NoError(t, storage.Store(&presentation.Document{ID: id, Content: content}))
+		index.Add(id, map[string]string{"text": content})
+	}
+
+	api := presentation.NewAPI(presentation.NewRenderer(nil), storage, nil)
+	api.SetTextIndex(index)
+
+	search := func(t *testing.T, query string) (*presentation.RenderedSearch, int) {
+		w := httptest.NewRecorder()
+		api.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/search?format=plain&"+query, nil))
+		if w.Code != http.StatusOK {
+			return nil, w.Code
+		}
+		var rendered presentation.RenderedSearch
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rendered))
+		return &rendered, w.Code
+	}
+	ids := func(rendered *presentation.RenderedSearch) []string {
+		var ids []string
+		for _, result := range rendered.Results {
+			ids = append(ids, result.Document.ID)
+		}
+		return ids
+	}
+
+	// Without a semantic searcher only lexical search is available
+	rendered, _ := search(t, "q=canine")
+	assert.Equal(t, presentation.SearchModeLexical, rendered.Mode)
+	assert.Equal(t, []string{"lex", "both"}, ids(rendered))
+	_, code := search(t, "q=canine&mode=hybrid")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	api.SetSemanticSearch(&fakeSemantic{ranking: []presentation.RankedID{
+		{ID: "sem", Score: 0.9},
+		{ID: "both", Score: 0.8},
+	}})
+
+	// Hybrid is the default once semantic search is configured
+	rendered, _ = search(t, "q=canine")
+	assert.Equal(t, presentation.SearchModeHybrid, rendered.Mode)
+	assert.Equal(t, []string{"both", "lex", "sem"}, ids(rendered))
+	assert.Equal(t, []string{"lexical", "semantic"}, rendered.Results[0].MatchedBy)
+
+	// Semantic-only hits still get a relevant snippet and highlights
+	semantic := rendered.Results[2]
+	assert.Equal(t, []string{"semantic"}, semantic.MatchedBy)
+	assert.Contains(t, semantic.Snippet, "A dog learns")
+	assert.Equal(t, []string{"A dog learns through repetition and praise"}, semantic.Highlights)
+
+	// Per-request weights change the fused order
+	rendered, _ = search(t, "q=canine&fusion=weighted&lexical_weight=0&semantic_weight=1")
+	assert.Equal(t, []string{"sem", "both", "lex"}, ids(rendered))
+
+	rendered, _ = search(t, "q=canine&mode=semantic")
+	assert.Equal(t, []string{"sem", "both"}, ids(rendered))
+
+	for _, bad := range []string{"q=canine&fusion=bogus", "q=canine&mode=fuzzy", "q=canine&rrf_k=0", "q=canine&lexical_weight=x"} {
+		_, code = search(t, bad)
+		assert.Equal(t, http.StatusBadRequest, code, bad)
+	}
+}
+
+func TestBackendStorageSearch(t *testing.T) {
+	backend, err := storage.NewGovcBackend("presentation-backend-test", storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	store := func(id, text string) {
+		_, err := backend.StoreDocument(ctx, &document.Document{
+			ID:        id,
+			Source:    document.Source{Type: "text", URL: "https://example.com/" + id},
+			Content:   document.Content{Text: text, Metadata: map[string]string{"title": id}},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		require.NoError(t, err)
+	}
+	store("lex", "Canine training tips. Reward good behaviour.")
+	store("other", "Feline grooming advice.")
+
+	searcher := storage.NewTextSearcher(backend, nil)
+	_, err = searcher.Load(ctx)
+	require.NoError(t, err)
+	require.NoError(t, searcher.Subscribe(backend.GetEventBus()))
+	defer searcher.Close()
+
+	api := presentation.NewAPI(presentation.NewRenderer(nil), presentation.NewBackendStorage(backend), nil)
+	api.SetTextIndex(searcher.Index())
+
+	search := func(query string) []string {
+		w := httptest.NewRecorder()
+		api.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/search?format=plain&q="+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var rendered presentation.RenderedSearch
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rendered))
+		var ids []string
+		for _, result := range rendered.Results {
+			ids = append(ids, result.Document.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"lex"}, search("canine"))
+
+	// Documents stored later are found once their event is indexed
+	store("later", "Canine nutrition matters.")
+	require.Eventually(t, func() bool { return len(search("canine")) == 2 }, 2*time.Second, 20*time.Millisecond)
+
+	doc, err := presentation.NewBackendStorage(backend).Get("later")
+	require.NoError(t, err)
+	assert.Equal(t, "Canine nutrition matters.", doc.Content)
+	assert.Equal(t, "https://example.com/later", doc.Metadata[presentation.MetaSourceURL])
+}
+
+func TestFuseRankings(t *testing.T) {
+	lexical := []presentation.RankedID{{ID: "a", Score: 12}, {ID: "b", Score: 6}, {ID: "c", Score: 3}}
+	semantic := []presentation.RankedID{{ID: "c", Score: 0.9}, {ID: "d", Score: 0.5}}
+
+	fused, err := presentation.FuseRankings(lexical, semantic, nil)
+	require.NoError(t, err)
+	require.Len(t, fused, 4)
+	assert.Equal(t, "c", fused[0].ID, "Appearing in both rankings should win under RRF")
+	assert.Equal(t, 3, fused[0].LexicalRank)
+	assert.Equal(t, 1, fused[0].SemanticRank)
+	assert.InDelta(t, 1.0/63+1.0/61, fused[0].Score, 1e-9)
+
+	fused, err = presentation.FuseRankings(lexical, semantic, &presentation.FusionOptions{
+		Method:         presentation.FusionWeighted,
+		LexicalWeight:  2,
+		SemanticWeight: 1,
+		RRFK:           presentation.DefaultRRFK,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a", fused[0].ID)
+	assert.InDelta(t, 2.0, fused[0].Score, 1e-9)
+
+	_, err = presentation.FuseRankings(lexical, semantic, &presentation.FusionOptions{Method: presentation.FusionRRF, RRFK: 60})
+	assert.Error(t, err, "All-zero weights should be rejected")
+}
+
 func TestRenderingPerformance(t *testing.T) {
 	renderer := presentation.NewRenderer(nil)
 
@@ -2,20 +2,24 @@ package presentation
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Caia-Tech/caia-library/internal/procurement"
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
 	"github.com/rs/zerolog/log"
 )
 
 // Renderer implements the DocumentPresenter interface
 type Renderer struct {
-	templates map[string]*ViewTemplate
-	config    *RendererConfig
+	templates     map[string]*ViewTemplate
+	config        *RendererConfig
+	passageScorer PassageScorer
 }
 
 // RendererConfig configures the renderer
@@ -48,6 +52,13 @@ func NewRenderer(config *RendererConfig) *Renderer {
 	return r
 }
 
+// SetPassageScorer lets search snippets and highlights fall back to the
+// passages the scorer rates closest to the query when no passage shares a
+// word with it
+func (r *Renderer) SetPassageScorer(scorer PassageScorer) {
+	r.passageScorer = scorer
+}
+
 // RenderDocument renders a single document
 func (r *Renderer) RenderDocument(doc *Document, options *RenderOptions) (*RenderedDocument, error) {
 	if doc == nil {
@@ -168,8 +179,9 @@ func (r *Renderer) RenderCollection(docs []*Document, options *CollectionOptions
 	return collection, nil
 }
 
-// RenderSearch renders search results
-func (r *Renderer) RenderSearch(results *SearchResults, options *SearchOptions) (*RenderedSearch, error) {
+// RenderSearch renders search results. ctx bounds the passage scoring done
+// for snippets and highlights.
+func (r *Renderer) RenderSearch(ctx context.Context, results *SearchResults, options *SearchOptions) (*RenderedSearch, error) {
 	if results == nil {
 		return nil, fmt.Errorf("search results are nil")
 	}
@@ -217,18 +229,25 @@ func (r *Renderer) RenderSearch(results *SearchResults, options *SearchOptions)
 			}
 
 			searchResult := &SearchResult{
-				Document: rendered,
-				Score:    results.Scores[doc.ID],
+				Document:  rendered,
+				Score:     results.Scores[doc.ID],
+				MatchedBy: results.MatchedBy[doc.ID],
+			}
+
+			// Rank passages once for both snippet and highlights
+			var passages []scoredPassage
+			if options.ShowSnippets || options.HighlightMatches {
+				passages = r.rankPassages(ctx, doc.Content, results.Query)
 			}
 
 			// Generate snippet if requested
 			if options.ShowSnippets {
-				searchResult.Snippet = r.generateSnippet(doc.Content, results.Query, options.SnippetLength)
+				searchResult.Snippet = r.generateSnippet(doc.Content, results.Query, options.SnippetLength, passages)
 			}
 
 			// Add highlights if requested
 			if options.HighlightMatches {
-				searchResult.Highlights = r.findHighlights(doc.Content, results.Query)
+				searchResult.Highlights = r.findHighlights(passages)
 			}
 
 			searchResults = append(searchResults, searchResult)
@@ -241,6 +260,7 @@ func (r *Renderer) RenderSearch(results *SearchResults, options *SearchOptions)
 		TotalHits:  results.TotalHits,
 		PageSize:   options.PageSize,
 		PageNumber: options.PageNumber,
+		Mode:       results.Mode,
 		SearchTime: results.SearchTime,
 		RenderTime: time.Now(),
 	}
@@ -458,14 +478,17 @@ func (r *Renderer) formatMetadata(metadata map[string]interface{}) map[string]in
 	return formatted
 }
 
-func (r *Renderer) generateSnippet(content, query string, length int) string {
+func (r *Renderer) generateSnippet(content, query string, length int, passages []scoredPassage) string {
 	// Find the position of the query in the content
 	lowerContent := strings.ToLower(content)
 	lowerQuery := strings.ToLower(query)
 	
 	pos := strings.Index(lowerContent, lowerQuery)
 	if pos == -1 {
-		// Query not found, return beginning of content
+		// Query not found, use the most relevant passage
+		if len(passages) > 0 && passages[0].score > 0 {
+			return clipSnippet(content, passages[0].start, passages[0].start+length)
+		}
 		if len(content) > length {
 			return content[:length] + "..."
 		}
@@ -478,7 +501,12 @@ func (r *Renderer) generateSnippet(content, query string, length int) string {
 		start = 0
 	}
 	
-	end := pos + len(query) + length/2
+	return clipSnippet(content, start, pos+len(query)+length/2)
+}
+
+// clipSnippet returns content[start:end], clamped to the content, with
+// ellipses marking removed text
+func clipSnippet(content string, start, end int) string {
 	if end > len(content) {
 		end = len(content)
 	}
@@ -496,29 +524,116 @@ func (r *Renderer) generateSnippet(content, query string, length int) string {
 	return snippet
 }
 
-func (r *Renderer) findHighlights(content, query string) []string {
-	var highlights []string
+// maxHighlights is the number of passages returned as highlights
+const maxHighlights = 3
+
+// findHighlights returns the most relevant passages in document order,
+// leaving out any scoring under half the best passage
+func (r *Renderer) findHighlights(passages []scoredPassage) []string {
+	var best []scoredPassage
+	for _, p := range passages {
+		if p.score <= 0 || p.score < passages[0].score/2 || len(best) == maxHighlights {
+			break
+		}
+		best = append(best, p)
+	}
+	sort.Slice(best, func(i, j int) bool { return best[i].start < best[j].start })
 	
-	// Split query into terms
-	terms := strings.Fields(query)
+	highlights := make([]string, 0, len(best))
+	for _, p := range best {
+		highlights = append(highlights, p.text)
+	}
+	return highlights
+}
+
+// maxScoredPassages bounds the passages of one document sent to the
+// passage scorer
+const maxScoredPassages = 64
+
+// scoredPassage is a sentence of a document and its relevance to a query
+type scoredPassage struct {
+	text  string
+	start int
+	score float64
+}
+
+// rankPassages splits content into sentences ordered by relevance to query,
+// best first. Relevance is the share of query terms a sentence contains
+// after stemming; when no sentence contains any, the passage scorer, if
+// set, rates them instead so semantic matches still get useful passages.
+func (r *Renderer) rankPassages(ctx context.Context, content, query string) []scoredPassage {
+	passages := splitPassages(content)
+	terms := fulltext.Terms(query)
 	
-	// Find sentences containing query terms
-	sentences := strings.Split(content, ".")
-	for _, sentence := range sentences {
-		sentenceLower := strings.ToLower(sentence)
-		for _, term := range terms {
-			if strings.Contains(sentenceLower, strings.ToLower(term)) {
-				highlights = append(highlights, strings.TrimSpace(sentence))
-				break
+	lexical := false
+	for i := range passages {
+		passages[i].score = termOverlap(passages[i].text, terms)
+		lexical = lexical || passages[i].score > 0
+	}
+	
+	if !lexical && r.passageScorer != nil && len(passages) > 0 {
+		scored := passages
+		if len(scored) > maxScoredPassages {
+			scored = scored[:maxScoredPassages]
+		}
+		texts := make([]string, len(scored))
+		for i, p := range scored {
+			texts[i] = p.text
+		}
+		
+		scores, err := r.passageScorer.ScorePassages(ctx, query, texts)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to score passages")
+		} else {
+			for i := range scored {
+				scored[i].score = scores[i]
 			}
 		}
+	}
+	
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].score > passages[j].score })
+	return passages
+}
+
+// splitPassages splits content into trimmed sentences, keeping each
+// sentence's byte offset in content
+func splitPassages(content string) []scoredPassage {
+	var passages []scoredPassage
+	start := 0
+	for i := 0; i <= len(content); i++ {
+		if i < len(content) && !strings.ContainsRune(".!?\n", rune(content[i])) {
+			continue
+		}
 		
-		if len(highlights) >= 3 {
-			break
+		sentence := content[start:i]
+		trimmed := strings.TrimSpace(sentence)
+		if trimmed != "" {
+			offset := start + strings.Index(sentence, trimmed)
+			passages = append(passages, scoredPassage{text: trimmed, start: offset})
 		}
+		start = i + 1
+	}
+	return passages
+}
+
+// termOverlap returns the share of terms that occur in text after analysis
+func termOverlap(text string, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
 	}
 	
-	return highlights
+	present := make(map[string]bool)
+	for _, term := range fulltext.Terms(text) {
+		present[term] = true
+	}
+	
+	found := 0
+	for _, term := range terms {
+		if present[term] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(terms))
 }
 
 func (r *Renderer) calculateStatistics(docs []*Document) *CollectionStatistics {
@@ -0,0 +1,70 @@
+package presentation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
+)
+
+// SemanticSearcher ranks documents by how close their meaning is to a query
+type SemanticSearcher interface {
+	// SearchSimilar returns up to k document IDs best first
+	SearchSimilar(ctx context.Context, query string, k int) ([]RankedID, error)
+}
+
+// PassageScorer scores how well each passage answers a query, so search
+// results can show the relevant part of a document even when it shares no
+// words with the query
+type PassageScorer interface {
+	// ScorePassages returns one score per passage, higher is better
+	ScorePassages(ctx context.Context, query string, passages []string) ([]float64, error)
+}
+
+// EmbeddingSearch answers semantic queries from the vector index of stored
+// document embeddings. Queries and passages must be embedded by the engine
+// that embedded the documents.
+type EmbeddingSearch struct {
+	searcher *storage.VectorSearcher
+	engine   *embedder.Engine
+}
+
+// NewEmbeddingSearch creates a semantic searcher over searcher's index
+func NewEmbeddingSearch(searcher *storage.VectorSearcher, engine *embedder.Engine) *EmbeddingSearch {
+	return &EmbeddingSearch{
+		searcher: searcher,
+		engine:   engine,
+	}
+}
+
+func (e *EmbeddingSearch) SearchSimilar(ctx context.Context, query string, k int) ([]RankedID, error) {
+	vector, err := e.engine.Generate(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	matches, err := e.searcher.SearchByVector(vector, k, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]RankedID, len(matches))
+	for i, match := range matches {
+		ranked[i] = RankedID{ID: match.ID, Score: float64(match.Score)}
+	}
+	return ranked, nil
+}
+
+func (e *EmbeddingSearch) ScorePassages(ctx context.Context, query string, passages []string) ([]float64, error) {
+	vectors, err := e.engine.GenerateBatch(ctx, append([]string{query}, passages...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed passages: %w", err)
+	}
+
+	scores := make([]float64, len(passages))
+	for i := range passages {
+		scores[i] = float64(embedder.CosineSimilarity(vectors[0], vectors[i+1]))
+	}
+	return scores, nil
+}
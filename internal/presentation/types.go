@@ -106,6 +106,7 @@ type RenderedSearch struct {
 	PageSize       int                       `json:"page_size"`
 	PageNumber     int                       `json:"page_number"`
 	Facets         map[string]*Facet         `json:"facets,omitempty"`
+	Mode           string                    `json:"mode,omitempty"`
 	SearchTime     time.Duration             `json:"search_time"`
 	RenderTime     time.Time                 `json:"render_time"`
 }
@@ -115,6 +116,8 @@ type SearchResults struct {
 	Query      string                    `json:"query"`
 	Documents  []*Document       `json:"documents"`
 	Scores     map[string]float64        `json:"scores"`
+	MatchedBy  map[string][]string       `json:"matched_by,omitempty"`
+	Mode       string                    `json:"mode,omitempty"`
 	TotalHits  int                       `json:"total_hits"`
 	SearchTime time.Duration             `json:"search_time"`
 }
@@ -125,6 +128,7 @@ type SearchResult struct {
 	Score          float64                   `json:"score"`
 	Snippet        string                    `json:"snippet,omitempty"`
 	Highlights     []string                  `json:"highlights,omitempty"`
+	MatchedBy      []string                  `json:"matched_by,omitempty"`
 }
 
 // CollectionStatistics provides statistics about a collection
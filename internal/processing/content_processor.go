@@ -12,6 +12,10 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// ContentProcessorSubscription is the name the content processor keeps its
+// place under on a durable event bus
+const ContentProcessorSubscription = "content-processor"
+
 // ContentProcessorConfig configures the content processor
 type ContentProcessorConfig struct {
 	Enabled             bool          `json:"enabled"`
@@ -155,6 +159,12 @@ func (cp *ContentProcessor) subscribeToEvents() error {
 			return nil
 		}
 		
+		// A durable bus only counts the event as handled once the document
+		// is cleaned, so process it here and let failures be retried
+		if cp.eventBus.Durable() {
+			return cp.processDocumentSafely(event.Document, -1)
+		}
+		
 		// Queue document for processing with timeout
 		queueTimeout := 5 * time.Second
 		queueCtx, cancel := context.WithTimeout(ctx, queueTimeout)
@@ -183,11 +193,22 @@ func (cp *ContentProcessor) subscribeToEvents() error {
 		}
 	}
 	
-	subscription, err := cp.eventBus.Subscribe(
-		[]pipeline.EventType{pipeline.EventDocumentAdded},
-		handler,
-		cp.config.BatchSize,
-	)
+	var subscription *pipeline.Subscription
+	var err error
+	if cp.eventBus.Durable() {
+		// Resume after the last cleaned document when the bus is reopened
+		subscription, err = cp.eventBus.SubscribeDurable(
+			ContentProcessorSubscription,
+			[]pipeline.EventType{pipeline.EventDocumentAdded},
+			handler,
+		)
+	} else {
+		subscription, err = cp.eventBus.Subscribe(
+			[]pipeline.EventType{pipeline.EventDocumentAdded},
+			handler,
+			cp.config.BatchSize,
+		)
+	}
 	if err != nil {
 		return err
 	}
@@ -234,10 +255,13 @@ func (cp *ContentProcessor) worker(workerID int) {
 	}
 }
 
-// processDocumentSafely processes a document with error recovery
-func (cp *ContentProcessor) processDocumentSafely(doc *document.Document, workerID int) {
+// processDocumentSafely processes a document with error recovery. A
+// workerID of -1 marks a document processed by its event handler.
+func (cp *ContentProcessor) processDocumentSafely(doc *document.Document, workerID int) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
+			err = fmt.Errorf("processing panic: %v", r)
+			
 			log.Error().
 				Str("document_id", doc.ID).
 				Int("worker_id", workerID).
@@ -258,11 +282,12 @@ func (cp *ContentProcessor) processDocumentSafely(doc *document.Document, worker
 		}
 	}()
 	
-	cp.processDocument(doc)
+	return cp.processDocument(doc)
 }
 
-// processDocument processes a single document
-func (cp *ContentProcessor) processDocument(doc *document.Document) {
+// processDocument processes a single document, returning why it could not
+// be cleaned or stored
+func (cp *ContentProcessor) processDocument(doc *document.Document) error {
 	start := time.Now()
 	
 	// Create processing context with timeout
@@ -301,7 +326,7 @@ func (cp *ContentProcessor) processDocument(doc *document.Document) {
 		if publishErr := cp.eventBus.Publish(errorEvent); publishErr != nil {
 			log.Warn().Err(publishErr).Str("document_id", doc.ID).Msg("Failed to publish error event")
 		}
-		return
+		return fmt.Errorf("failed to clean document %s: %w", doc.ID, err)
 	}
 	
 	// Update document in storage if content changed
@@ -322,7 +347,7 @@ func (cp *ContentProcessor) processDocument(doc *document.Document) {
 			if publishErr := cp.eventBus.Publish(errorEvent); publishErr != nil {
 				log.Warn().Err(publishErr).Str("document_id", doc.ID).Msg("Failed to publish error event")
 			}
-			return
+			return fmt.Errorf("failed to store cleaned document %s: %w", doc.ID, err)
 		}
 	}
 	
@@ -360,6 +385,7 @@ func (cp *ContentProcessor) processDocument(doc *document.Document) {
 		Dur("processing_time", result.ProcessingTime).
 		Interface("rules_applied", result.RulesApplied).
 		Msg("Document content cleaned successfully")
+	return nil
 }
 
 // cleanDocumentWithTimeout cleans document with proper timeout handling
@@ -403,9 +429,9 @@ func (cp *ContentProcessor) updateDocumentInStorageWithRetry(ctx context.Context
 		// Create context with timeout for each attempt
 		attemptCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		
-		// For now, we'll store it as a new version
-		// In a real system, you might want to update the existing document
-		_, err := cp.storage.StoreDocument(attemptCtx, doc)
+		// Update in place so the cleaned text replaces the original without
+		// re-announcing the document as newly added
+		_, err := cp.storage.UpdateDocument(attemptCtx, doc)
 		cancel()
 		
 		if err == nil {
@@ -187,4 +187,52 @@ Different line`,
 	t.Logf("  Total bytes processed: %d", stats.TotalBytesProcessed)
 	t.Logf("  Total bytes removed: %d", stats.TotalBytesRemoved)
 	t.Logf("  Average processing time: %v", stats.AverageProcessTime)
-}
\ No newline at end of file
+}
+
+func TestContentProcessorDurableResume(t *testing.T) {
+	config := &storage.GovcConfig{
+		MemoryMode:   true,
+		Path:         ":memory:",
+		Timeout:      30 * time.Second,
+		EventLogPath: t.TempDir(),
+	}
+	backend, err := storage.NewGovcBackendWithConfig("durable-test", config, storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+	require.True(t, backend.GetEventBus().Durable())
+
+	processorConfig := DefaultContentProcessorConfig()
+	processor, err := NewContentProcessor(backend, backend.GetEventBus(), processorConfig)
+	require.NoError(t, err)
+	processor.Close()
+
+	// A document stored while the processor is down waits in the event log
+	doc := &document.Document{
+		ID: "durable-test-001",
+		Source: document.Source{
+			Type: "html",
+			URL:  "https://example.com/durable.html",
+		},
+		Content: document.Content{
+			Text:     "<p>Stored   while the processor was   down.</p>",
+			Metadata: make(map[string]string),
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	_, err = backend.StoreDocument(context.Background(), doc)
+	require.NoError(t, err)
+
+	processor, err = NewContentProcessor(backend, backend.GetEventBus(), processorConfig)
+	require.NoError(t, err)
+	defer processor.Close()
+
+	require.Eventually(t, func() bool {
+		return processor.GetStats().DocumentsProcessed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stored, err := backend.GetDocument(context.Background(), doc.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, stored.Content.Text, "<p>")
+	assert.Equal(t, "true", stored.Content.Metadata["cleaned"])
+}
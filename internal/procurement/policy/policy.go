@@ -0,0 +1,326 @@
+// Package policy holds the collection policies reviewed for each domain:
+// whether its terms allow automated collection, the attribution and
+// license its content carries, and how fast it may be fetched. Policies
+// are read from a versioned file, and every document records the version
+// it was collected under.
+package policy
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Unversioned is the version of a registry without a policy file
+const Unversioned = "unversioned"
+
+// Metadata keys recording the policy a document was collected under
+const (
+	MetadataVersion     = "policy_version"
+	MetadataDomain      = "policy_domain"
+	MetadataLicense     = "policy_license"
+	MetadataAttribution = "policy_attribution"
+)
+
+// DefaultCheckInterval is how often a registry looks for changes to its
+// file
+const DefaultCheckInterval = 10 * time.Second
+
+// Policy is the collection policy of a domain and its subdomains
+type Policy struct {
+	Domain            string `yaml:"domain" json:"domain"`
+	AutomationAllowed bool   `yaml:"automation_allowed" json:"automation_allowed"`
+	// AttributionText is the credit the domain's content must carry;
+	// empty when none is required
+	AttributionText string `yaml:"attribution_text" json:"attribution_text,omitempty"`
+	License         string `yaml:"license" json:"license,omitempty"`
+	CommercialUse   bool   `yaml:"commercial_use" json:"commercial_use"`
+	// MaxRate caps requests to the domain, per second; 0 means no cap
+	// beyond the crawler's own
+	MaxRate  float64 `yaml:"max_rate" json:"max_rate,omitempty"`
+	TermsURL string  `yaml:"terms_url" json:"terms_url,omitempty"`
+	Notes    string  `yaml:"notes" json:"notes,omitempty"`
+
+	// Version is the version of the policy file the policy was read from
+	Version string `yaml:"-" json:"version"`
+}
+
+// RequiresAttribution reports whether the domain's content must be
+// credited
+func (p Policy) RequiresAttribution() bool {
+	return p.AttributionText != ""
+}
+
+// MinInterval returns the shortest interval between requests MaxRate
+// allows, or 0 without a cap
+func (p Policy) MinInterval() time.Duration {
+	if p.MaxRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / p.MaxRate)
+}
+
+// Stamp records in document metadata that the document was collected
+// under the policy
+func (p Policy) Stamp(metadata map[string]string) {
+	metadata[MetadataVersion] = p.Version
+	if p.Domain != "" {
+		metadata[MetadataDomain] = p.Domain
+	}
+	if p.License != "" {
+		metadata[MetadataLicense] = p.License
+	}
+	if p.AttributionText != "" {
+		metadata[MetadataAttribution] = p.AttributionText
+	}
+}
+
+// File is the policy file: a version, the policy of domains not listed,
+// and the listed domains' policies
+type File struct {
+	Version string   `yaml:"version" json:"version"`
+	Default Policy   `yaml:"default" json:"default"`
+	Domains []Policy `yaml:"domains" json:"domains"`
+}
+
+// Parse reads a policy file, in YAML or in JSON, which YAML reads too
+func Parse(data []byte) (*File, error) {
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (f *File) validate() error {
+	f.Version = strings.TrimSpace(f.Version)
+	if f.Version == "" {
+		return fmt.Errorf("policy file has no version")
+	}
+	if f.Default.MaxRate < 0 {
+		return fmt.Errorf("default policy has a negative max_rate")
+	}
+	f.Default.Domain = ""
+
+	seen := make(map[string]bool, len(f.Domains))
+	for i := range f.Domains {
+		p := &f.Domains[i]
+		p.Domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(p.Domain)), ".")
+		if p.Domain == "" {
+			return fmt.Errorf("policy %d has no domain", i)
+		}
+		if seen[p.Domain] {
+			return fmt.Errorf("domain %s has more than one policy", p.Domain)
+		}
+		if p.MaxRate < 0 {
+			return fmt.Errorf("domain %s has a negative max_rate", p.Domain)
+		}
+		seen[p.Domain] = true
+	}
+	return nil
+}
+
+// Registry answers which policy applies to a domain. A registry loaded
+// from a file picks up changes to it as they are made: lookups check the
+// file at most once per check interval and reload it when it changed. A
+// file that no longer parses leaves the policies last read in place.
+type Registry struct {
+	path     string
+	interval time.Duration
+
+	mu       sync.RWMutex
+	file     *File
+	policies map[string]Policy
+	loadedAt time.Time
+	modTime  time.Time
+	size     int64
+	checked  time.Time
+	failed   time.Time // modification time of a file that failed to load
+}
+
+// NewRegistry creates a registry of fixed policies. A nil file makes an
+// unversioned registry that applies a policy denying automation to every
+// domain.
+func NewRegistry(file *File) (*Registry, error) {
+	if file == nil {
+		file = &File{Version: Unversioned}
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	r := &Registry{}
+	r.set(file)
+	return r, nil
+}
+
+// Load creates a registry from a policy file that it reloads when the file
+// changes
+func Load(path string) (*Registry, error) {
+	r := &Registry{path: path, interval: DefaultCheckInterval}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetCheckInterval sets how often lookups check the policy file for
+// changes
+func (r *Registry) SetCheckInterval(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interval = interval
+}
+
+// Reload reads the policy file again if it changed since it was last read,
+// reporting whether it did
+func (r *Registry) Reload() (bool, error) {
+	if r.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(r.path)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	if err != nil {
+		r.failed = time.Unix(0, 0)
+		return false, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	if r.file != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	file, err := Parse(data)
+	if err != nil {
+		r.failed = info.ModTime()
+		return false, err
+	}
+
+	previous := ""
+	if r.file != nil {
+		previous = r.file.Version
+	}
+	r.set(file)
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	r.failed = time.Time{}
+
+	log.Info().
+		Str("path", r.path).
+		Str("version", file.Version).
+		Str("previous_version", previous).
+		Int("domains", len(file.Domains)).
+		Msg("Loaded domain policies")
+	return true, nil
+}
+
+// set replaces the registry's policies; the caller holds the lock or owns
+// the registry
+func (r *Registry) set(file *File) {
+	policies := make(map[string]Policy, len(file.Domains))
+	for _, p := range file.Domains {
+		p.Version = file.Version
+		policies[p.Domain] = p
+	}
+	r.file = file
+	r.policies = policies
+	r.loadedAt = time.Now()
+}
+
+// refresh reloads a changed policy file once the check interval has passed
+func (r *Registry) refresh() {
+	if r.path == "" {
+		return
+	}
+	r.mu.RLock()
+	due := time.Since(r.checked) >= r.interval
+	failed := r.failed
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	if _, err := r.Reload(); err != nil {
+		// Report a broken file once, not at every check
+		r.mu.RLock()
+		repeated := !failed.IsZero() && failed.Equal(r.failed)
+		r.mu.RUnlock()
+		if !repeated {
+			log.Warn().Err(err).Str("path", r.path).Msg("Failed to reload domain policies, keeping current ones")
+		}
+	}
+}
+
+// Lookup returns the policy of a domain, given as a host with or without a
+// port: the policy listed for the host or its closest parent domain, or
+// the default policy if none is
+func (r *Registry) Lookup(domain string) Policy {
+	r.refresh()
+
+	host := strings.ToLower(strings.TrimSpace(domain))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name := host; name != ""; {
+		if p, ok := r.policies[name]; ok {
+			return p
+		}
+		i := strings.IndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[i+1:]
+	}
+
+	p := r.file.Default
+	p.Version = r.file.Version
+	return p
+}
+
+// Version returns the version of the policies in force
+func (r *Registry) Version() string {
+	r.refresh()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.file.Version
+}
+
+// Snapshot is the policies in force and when they were loaded
+type Snapshot struct {
+	File
+	Path     string    `json:"path,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// Snapshot returns a copy of the policies in force
+func (r *Registry) Snapshot() Snapshot {
+	r.refresh()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	file := *r.file
+	file.Default.Version = file.Version
+	file.Domains = make([]Policy, len(r.file.Domains))
+	for i, p := range r.file.Domains {
+		p.Version = file.Version
+		file.Domains[i] = p
+	}
+	return Snapshot{File: file, Path: r.path, LoadedAt: r.loadedAt}
+}
@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicies = `version: "1"
+default:
+  automation_allowed: false
+  notes: "Not reviewed"
+domains:
+  - domain: Example.org
+    automation_allowed: true
+    attribution_text: "Content from example.org"
+    license: "CC BY 4.0"
+    commercial_use: true
+    max_rate: 2
+  - domain: docs.example.org
+    automation_allowed: false
+`
+
+func writePolicies(t *testing.T, path, content string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestLookup(t *testing.T) {
+	file, err := Parse([]byte(testPolicies))
+	require.NoError(t, err)
+	registry, err := NewRegistry(file)
+	require.NoError(t, err)
+
+	p := registry.Lookup("example.org")
+	assert.Equal(t, "example.org", p.Domain)
+	assert.True(t, p.AutomationAllowed)
+	assert.True(t, p.RequiresAttribution())
+	assert.Equal(t, 500*time.Millisecond, p.MinInterval())
+	assert.Equal(t, "1", p.Version)
+
+	// Subdomains inherit the closest listed parent's policy
+	assert.Equal(t, "example.org", registry.Lookup("www.Example.org:8080").Domain)
+	assert.False(t, registry.Lookup("api.docs.example.org").AutomationAllowed)
+
+	// Other domains get the default policy
+	p = registry.Lookup("example.com")
+	assert.Empty(t, p.Domain)
+	assert.False(t, p.AutomationAllowed)
+	assert.Equal(t, "Not reviewed", p.Notes)
+	assert.Equal(t, "1", p.Version)
+
+	metadata := map[string]string{}
+	registry.Lookup("www.example.org").Stamp(metadata)
+	assert.Equal(t, map[string]string{
+		MetadataVersion:     "1",
+		MetadataDomain:      "example.org",
+		MetadataLicense:     "CC BY 4.0",
+		MetadataAttribution: "Content from example.org",
+	}, metadata)
+
+	// Without a file nothing allows automation
+	registry, err = NewRegistry(nil)
+	require.NoError(t, err)
+	assert.False(t, registry.Lookup("example.org").AutomationAllowed)
+	assert.Equal(t, Unversioned, registry.Version())
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"no version":       "domains:\n  - domain: example.org\n",
+		"no domain":        "version: 1\ndomains:\n  - automation_allowed: true\n",
+		"duplicate domain": "version: 1\ndomains:\n  - domain: example.org\n  - domain: EXAMPLE.org\n",
+		"negative rate":    "version: 1\ndomains:\n  - domain: example.org\n    max_rate: -1\n",
+		"not yaml":         "version: [1\n",
+	} {
+		_, err := Parse([]byte(content))
+		assert.Error(t, err, name)
+	}
+
+	// JSON is read as well
+	file, err := Parse([]byte(`{"version": "2", "domains": [{"domain": "example.org", "automation_allowed": true}]}`))
+	require.NoError(t, err)
+	assert.True(t, file.Domains[0].AutomationAllowed)
+}
+
+func TestHotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	modTime := time.Now().Add(-time.Hour)
+	writePolicies(t, path, testPolicies, modTime)
+
+	registry, err := Load(path)
+	require.NoError(t, err)
+	registry.SetCheckInterval(0)
+	assert.True(t, registry.Lookup("example.org").AutomationAllowed)
+
+	// A changed file is picked up on the next lookup
+	writePolicies(t, path, `version: "2"
+domains:
+  - domain: example.org
+    automation_allowed: false
+`, modTime.Add(time.Minute))
+	p := registry.Lookup("example.org")
+	assert.False(t, p.AutomationAllowed)
+	assert.Equal(t, "2", p.Version)
+	assert.Equal(t, "2", registry.Snapshot().Version)
+
+	// A broken file leaves the last policies in force
+	writePolicies(t, path, "version: \n", modTime.Add(2*time.Minute))
+	assert.Equal(t, "2", registry.Lookup("example.org").Version)
+	reloaded, err := registry.Reload()
+	assert.False(t, reloaded)
+	assert.Error(t, err)
+	assert.Equal(t, "2", registry.Version())
+
+	// Checks wait for the interval
+	registry.SetCheckInterval(time.Hour)
+	writePolicies(t, path, testPolicies, modTime.Add(3*time.Minute))
+	assert.Equal(t, "2", registry.Version())
+	reloaded, err = registry.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "1", registry.Version())
+
+	snapshot := registry.Snapshot()
+	assert.Equal(t, path, snapshot.Path)
+	require.Len(t, snapshot.Domains, 2)
+	assert.Equal(t, "1", snapshot.Domains[0].Version)
+}
+
+func TestRepositoryPolicies(t *testing.T) {
+	// The policy file shipped with the repository is valid
+	registry, err := Load(filepath.Join("..", "..", "..", "configs", "domain_policies.yaml"))
+	require.NoError(t, err)
+	assert.NotEqual(t, Unversioned, registry.Version())
+	assert.True(t, registry.Lookup("export.arxiv.org").AutomationAllowed)
+	assert.False(t, registry.Lookup("example.com").AutomationAllowed)
+}
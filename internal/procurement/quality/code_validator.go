@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Caia-Tech/caia-library/internal/procurement"
+	"github.com/Caia-Tech/caia-library/internal/procurement/quality/sandbox"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,6 +20,13 @@ type CodeValidator struct {
 	config        *CodeValidationConfig
 	tempDir       string
 	supportedLangs map[string]*LanguageConfig
+	
+	// sandbox runs compilers and code; sandboxErr says why it is nil
+	sandbox    *sandbox.Sandbox
+	sandboxErr error
+	
+	// goChecker parses and type-checks Go snippets
+	goChecker *goChecker
 }
 
 // CodeValidationConfig configures code validation behavior
@@ -28,6 +36,9 @@ type CodeValidationConfig struct {
 	ExecutionTimeout  time.Duration `json:"execution_timeout"`
 	TempDirectory     string        `json:"temp_directory"`
 	MaxFileSize       int64         `json:"max_file_size"`
+	// Sandbox isolates compilation and execution. Execution is refused
+	// without it; compilation falls back to running on the host.
+	Sandbox           *sandbox.Config `json:"sandbox"`
 }
 
 // LanguageConfig defines configuration for specific programming languages
@@ -35,25 +46,48 @@ type LanguageConfig struct {
 	FileExtension    string   `json:"file_extension"`
 	CompileCommand   []string `json:"compile_command"`
 	ExecuteCommand   []string `json:"execute_command"`
-	SyntaxPatterns   []string `json:"syntax_patterns"`
 	SecurityPatterns []string `json:"security_patterns"`
 }
 
-// NewCodeValidator creates a new code validator
-func NewCodeValidator() *CodeValidator {
+// DefaultCodeValidationConfig returns default code validation configuration
+func DefaultCodeValidationConfig() *CodeValidationConfig {
 	tempDir := filepath.Join(os.TempDir(), "caia-code-validation")
-	os.MkdirAll(tempDir, 0755)
+	
+	sandboxConfig := sandbox.DefaultConfig()
+	// Keep compiler caches between samples; a cold Go build takes seconds
+	sandboxConfig.CacheDir = filepath.Join(tempDir, "cache")
+	
+	return &CodeValidationConfig{
+		EnableCompilation: true,
+		EnableExecution:   false, // Disabled by default for security
+		ExecutionTimeout:  30 * time.Second,
+		TempDirectory:     tempDir,
+		MaxFileSize:       1024 * 1024, // 1MB
+		Sandbox:           sandboxConfig,
+	}
+}
+
+// NewCodeValidator creates a new code validator. Compilation and execution
+// run in a sandbox when the host supports one, which needs sandbox.Init to
+// be called at the start of main.
+func NewCodeValidator(config *CodeValidationConfig) *CodeValidator {
+	if config == nil {
+		config = DefaultCodeValidationConfig()
+	}
+	os.MkdirAll(config.TempDirectory, 0755)
 	
 	cv := &CodeValidator{
-		config: &CodeValidationConfig{
-			EnableCompilation: true,
-			EnableExecution:   false, // Disabled by default for security
-			ExecutionTimeout:  5 * time.Second,
-			TempDirectory:     tempDir,
-			MaxFileSize:       1024 * 1024, // 1MB
-		},
-		tempDir: tempDir,
+		config: config,
+		tempDir: config.TempDirectory,
 		supportedLangs: make(map[string]*LanguageConfig),
+		goChecker: newGoChecker(),
+	}
+	
+	if config.EnableCompilation || config.EnableExecution {
+		cv.sandbox, cv.sandboxErr = sandbox.New(config.Sandbox)
+		if cv.sandboxErr != nil {
+			log.Warn().Err(cv.sandboxErr).Msg("Code sandbox unavailable; code will not be executed and compilers run on the host")
+		}
 	}
 	
 	cv.setupLanguageConfigs()
@@ -94,10 +128,22 @@ func (cv *CodeValidator) ValidateCode(ctx context.Context, code string, language
 		return result, nil
 	}
 	
-	// Basic syntax validation
-	result.SyntaxValid = cv.validateSyntax(code, langConfig)
-	if !result.SyntaxValid {
-		result.Errors = append(result.Errors, "Syntax validation failed")
+	// Syntax validation; Go fragments come back wrapped into a program
+	source := code
+	if strings.TrimSpace(code) == "" {
+		result.Errors = append(result.Errors, "Syntax validation failed: code is empty")
+	} else {
+		syntax := cv.checkSyntax(code, language)
+		source = syntax.Source
+		result.Diagnostics = syntax.Diagnostics
+		result.SyntaxValid = true
+		if diagnostic, found := syntax.Diagnostics.firstError(diagnosticSyntax); found {
+			result.SyntaxValid = false
+			result.Errors = append(result.Errors, "Syntax validation failed: "+describeDiagnostic(diagnostic))
+		}
+		if diagnostic, found := syntax.Diagnostics.firstError(diagnosticTypes); found {
+			result.Errors = append(result.Errors, "Type check failed: "+describeDiagnostic(diagnostic))
+		}
 	}
 	
 	// Security check
@@ -111,7 +157,7 @@ func (cv *CodeValidator) ValidateCode(ctx context.Context, code string, language
 	
 	// Compilation check (if enabled and syntax is valid)
 	if cv.config.EnableCompilation && result.SyntaxValid {
-		compileResult := cv.tryCompile(ctx, code, langConfig)
+		compileResult := cv.tryCompile(ctx, source, langConfig)
 		result.Compilable = compileResult.Success
 		if !compileResult.Success {
 			result.Errors = append(result.Errors, compileResult.Error)
@@ -120,7 +166,7 @@ func (cv *CodeValidator) ValidateCode(ctx context.Context, code string, language
 	
 	// Execution check (if enabled and compilable)
 	if cv.config.EnableExecution && result.Compilable && result.SecuritySafe {
-		execResult := cv.tryExecute(ctx, code, langConfig)
+		execResult := cv.tryExecute(ctx, source, langConfig)
 		result.Executable = execResult.Success
 		if !execResult.Success {
 			result.Errors = append(result.Errors, execResult.Error)
@@ -145,10 +191,6 @@ func (cv *CodeValidator) setupLanguageConfigs() {
 		FileExtension:  ".go",
 		CompileCommand: []string{"go", "build"},
 		ExecuteCommand: []string{"go", "run"},
-		SyntaxPatterns: []string{
-			`^package\s+\w+`, // Must start with package declaration
-			`func\s+\w+\s*\(`, // Function declarations
-		},
 		SecurityPatterns: []string{
 			`os\.Exec`, `exec\.Command`, `syscall\.`, `unsafe\.`,
 			`os\.Remove`, `os\.RemoveAll`, `ioutil\.WriteFile`,
@@ -158,12 +200,8 @@ func (cv *CodeValidator) setupLanguageConfigs() {
 	// Python configuration
 	cv.supportedLangs["python"] = &LanguageConfig{
 		FileExtension:  ".py",
-		CompileCommand: []string{"python", "-m", "py_compile"},
-		ExecuteCommand: []string{"python"},
-		SyntaxPatterns: []string{
-			`def\s+\w+\s*\(`, // Function definitions
-			`class\s+\w+`,    // Class definitions
-		},
+		CompileCommand: []string{"python3", "-m", "py_compile"},
+		ExecuteCommand: []string{"python3"},
 		SecurityPatterns: []string{
 			`import\s+os`, `import\s+subprocess`, `import\s+sys`,
 			`exec\s*\(`, `eval\s*\(`, `__import__`,
@@ -175,11 +213,6 @@ func (cv *CodeValidator) setupLanguageConfigs() {
 		FileExtension:  ".js",
 		CompileCommand: []string{"node", "--check"},
 		ExecuteCommand: []string{"node"},
-		SyntaxPatterns: []string{
-			`function\s+\w+\s*\(`, // Function declarations
-			`=>\s*{`,             // Arrow functions
-			`var\s+\w+|let\s+\w+|const\s+\w+`, // Variable declarations
-		},
 		SecurityPatterns: []string{
 			`require\s*\(\s*['"]fs['"]`, `require\s*\(\s*['"]child_process['"]`,
 			`eval\s*\(`, `Function\s*\(`, `new\s+Function`,
@@ -191,10 +224,6 @@ func (cv *CodeValidator) setupLanguageConfigs() {
 		FileExtension:  ".java",
 		CompileCommand: []string{"javac"},
 		ExecuteCommand: []string{"java"},
-		SyntaxPatterns: []string{
-			`public\s+class\s+\w+`, // Public class declaration
-			`public\s+static\s+void\s+main`, // Main method
-		},
 		SecurityPatterns: []string{
 			`Runtime\.getRuntime`, `ProcessBuilder`, `System\.exit`,
 			`File`, `FileInputStream`, `FileOutputStream`,
@@ -202,36 +231,6 @@ func (cv *CodeValidator) setupLanguageConfigs() {
 	}
 }
 
-// validateSyntax performs basic syntax validation
-func (cv *CodeValidator) validateSyntax(code string, langConfig *LanguageConfig) bool {
-	// Basic checks
-	if strings.TrimSpace(code) == "" {
-		return false
-	}
-	
-	// Language-specific syntax patterns
-	for _, pattern := range langConfig.SyntaxPatterns {
-		matched, err := regexp.MatchString(pattern, code)
-		if err == nil && matched {
-			return true // At least one pattern matched
-		}
-	}
-	
-	// Additional basic syntax checks
-	switch {
-	case strings.Contains(langConfig.FileExtension, "go"):
-		return cv.validateGoSyntax(code)
-	case strings.Contains(langConfig.FileExtension, "py"):
-		return cv.validatePythonSyntax(code)
-	case strings.Contains(langConfig.FileExtension, "js"):
-		return cv.validateJavaScriptSyntax(code)
-	case strings.Contains(langConfig.FileExtension, "java"):
-		return cv.validateJavaSyntax(code)
-	}
-	
-	return true // Default to valid for unsupported detailed validation
-}
-
 // checkSecurity checks for potentially dangerous code patterns
 func (cv *CodeValidator) checkSecurity(code string, langConfig *LanguageConfig) bool {
 	// Check against security patterns
@@ -314,92 +313,8 @@ func (cv *CodeValidator) checkBestPractices(code string, language string) bool {
 	return score >= 0.6 // 60% threshold for best practices
 }
 
-// Language-specific syntax validators
-
-func (cv *CodeValidator) validateGoSyntax(code string) bool {
-	// Basic Go syntax checks
-	hasPackage := strings.Contains(code, "package ")
-	hasProperBraces := cv.checkBraces(code)
-	
-	// Check for basic syntax issues
-	if strings.Contains(code, "fmt.Println") && !strings.Contains(code, "import") {
-		return false // Missing imports
-	}
-	
-	// Check for balanced parentheses
-	if !cv.checkBalancedParentheses(code) {
-		return false
-	}
-	
-	return hasPackage && hasProperBraces
-}
-
-func (cv *CodeValidator) checkBalancedParentheses(code string) bool {
-	parenCount := 0
-	for _, char := range code {
-		switch char {
-		case '(':
-			parenCount++
-		case ')':
-			parenCount--
-			if parenCount < 0 {
-				return false
-			}
-		}
-	}
-	return parenCount == 0
-}
-
-func (cv *CodeValidator) validatePythonSyntax(code string) bool {
-	// Basic Python syntax checks
-	lines := strings.Split(code, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		// Check for proper indentation in Python
-		if strings.HasPrefix(line, " ") && len(line) > 0 {
-			continue // Properly indented
-		}
-		if !strings.HasPrefix(line, " ") {
-			continue // Top-level statement
-		}
-	}
-	return true
-}
-
-func (cv *CodeValidator) validateJavaScriptSyntax(code string) bool {
-	// Basic JavaScript syntax checks
-	hasProperBraces := cv.checkBraces(code)
-	return hasProperBraces
-}
-
-func (cv *CodeValidator) validateJavaSyntax(code string) bool {
-	// Basic Java syntax checks
-	hasClass := strings.Contains(code, "class ")
-	hasProperBraces := cv.checkBraces(code)
-	return hasClass && hasProperBraces
-}
-
 // Helper methods
 
-func (cv *CodeValidator) checkBraces(code string) bool {
-	braceCount := 0
-	for _, char := range code {
-		switch char {
-		case '{':
-			braceCount++
-		case '}':
-			braceCount--
-		}
-		if braceCount < 0 {
-			return false
-		}
-	}
-	return braceCount == 0
-}
-
 func (cv *CodeValidator) checkIndentation(code string) bool {
 	lines := strings.Split(code, "\n")
 	consistentIndentation := true
@@ -456,6 +371,17 @@ func (cv *CodeValidator) tryCompile(ctx context.Context, code string, langConfig
 		return CompilationResult{Success: false, Error: "Compilation disabled: unsafe command detected"}
 	}
 	
+	if cv.sandbox != nil {
+		result, err := cv.runSandboxed(ctx, code, langConfig.CompileCommand, langConfig)
+		if err != nil {
+			return CompilationResult{Success: false, Error: err.Error()}
+		}
+		if !result.Success() {
+			return CompilationResult{Success: false, Error: describeFailure(result)}
+		}
+		return CompilationResult{Success: true}
+	}
+	
 	// Create temporary file
 	tempFile := filepath.Join(cv.tempDir, fmt.Sprintf("temp_%d%s", time.Now().UnixNano(), langConfig.FileExtension))
 	defer os.Remove(tempFile)
@@ -502,39 +428,77 @@ func (cv *CodeValidator) tryExecute(ctx context.Context, code string, langConfig
 		return ExecutionResult{Success: false, Error: "Execution disabled: unsafe command detected"}
 	}
 	
+	// Security: Never run code outside the sandbox
+	if cv.sandbox == nil {
+		return ExecutionResult{Success: false, Error: fmt.Sprintf("Code execution requires a sandbox: %v", cv.sandboxErr)}
+	}
+	
 	// Create timeout context
 	execCtx, cancel := context.WithTimeout(ctx, cv.config.ExecutionTimeout)
 	defer cancel()
 	
-	// Create temporary file
-	tempFile := filepath.Join(cv.tempDir, fmt.Sprintf("temp_%d%s", time.Now().UnixNano(), langConfig.FileExtension))
-	defer os.Remove(tempFile)
-	
-	if err := os.WriteFile(tempFile, []byte(code), 0644); err != nil {
-		return ExecutionResult{Success: false, Error: fmt.Sprintf("Failed to write temp file: %v", err)}
-	}
-	
-	// Prepare execute command with security restrictions
-	cmd := exec.CommandContext(execCtx, langConfig.ExecuteCommand[0])
-	if len(langConfig.ExecuteCommand) > 1 {
-		cmd.Args = append(cmd.Args, langConfig.ExecuteCommand[1:]...)
+	result, err := cv.runSandboxed(execCtx, code, langConfig.ExecuteCommand, langConfig)
+	if err != nil {
+		return ExecutionResult{Success: false, Error: err.Error()}
 	}
-	cmd.Args = append(cmd.Args, tempFile)
-	
-	// Security: Set highly restrictive environment
-	cmd.Env = []string{
-		"PATH=/usr/bin:/bin",
-		"HOME=/tmp",
-		"TMPDIR=" + cv.tempDir,
+	if !result.Success() {
+		return ExecutionResult{Success: false, Error: describeFailure(result), Output: result.Stdout}
 	}
 	
-	// Run execution with timeout
-	output, err := cmd.CombinedOutput()
+	return ExecutionResult{Success: true, Output: result.Stdout}
+}
+
+// runSandboxed runs a compile or execute command on the code in the sandbox
+func (cv *CodeValidator) runSandboxed(ctx context.Context, code string, command []string, langConfig *LanguageConfig) (*sandbox.Result, error) {
+	fileName := sourceFileName(code, langConfig)
+	args := append(append([]string{}, command...), fileName)
+	
+	result, err := cv.sandbox.Run(ctx, sandbox.Command{
+		Args:  args,
+		Files: map[string][]byte{fileName: []byte(code)},
+	})
 	if err != nil {
-		return ExecutionResult{Success: false, Error: err.Error(), Output: string(output)}
+		return nil, fmt.Errorf("sandbox failed: %w", err)
+	}
+	return result, nil
+}
+
+// javaPublicClass finds the public class a Java source file must be named after
+var javaPublicClass = regexp.MustCompile(`public\s+(?:final\s+|abstract\s+)*class\s+(\w+)`)
+
+// sourceFileName names the file a snippet is written to
+func sourceFileName(code string, langConfig *LanguageConfig) string {
+	if langConfig.FileExtension == ".java" {
+		if match := javaPublicClass.FindStringSubmatch(code); match != nil {
+			return match[1] + langConfig.FileExtension
+		}
+	}
+	return "main" + langConfig.FileExtension
+}
+
+// describeFailure explains why a sandboxed command failed
+func describeFailure(result *sandbox.Result) string {
+	var reason string
+	switch {
+	case result.TimedOut:
+		reason = fmt.Sprintf("timed out after %s", result.Duration.Round(time.Millisecond))
+	case result.Signal != "":
+		reason = fmt.Sprintf("killed by signal: %s", result.Signal)
+	default:
+		reason = fmt.Sprintf("exit code %d", result.ExitCode)
 	}
 	
-	return ExecutionResult{Success: true, Output: string(output)}
+	output, truncated := strings.TrimSpace(result.Stderr), result.StderrTruncated
+	if output == "" {
+		output, truncated = strings.TrimSpace(result.Stdout), result.StdoutTruncated
+	}
+	if output == "" {
+		return reason
+	}
+	if truncated {
+		output += "\n[output truncated]"
+	}
+	return reason + ": " + output
 }
 
 // validateCodeContent validates that code content doesn't contain dangerous patterns
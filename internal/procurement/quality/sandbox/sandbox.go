@@ -0,0 +1,198 @@
+// Package sandbox runs untrusted programs, such as code samples found in
+// procured content, isolated from the host.
+//
+// On Linux a program runs in its own user, mount, network, PID, IPC and UTS
+// namespaces. Its root file system is read-only and holds only the system
+// directories the program needs, plus a size-limited scratch tmpfs at /tmp
+// that is also its working directory. It has no network, no capabilities,
+// and rlimits on CPU time, memory, file size, processes and open files.
+//
+// The sandbox starts programs by re-executing the current binary, so a
+// binary that uses it must call Init first thing in main, and tests must
+// call it from TestMain.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned by New on platforms without a sandbox
+var ErrUnsupported = errors.New("sandbox is not supported on this platform")
+
+// ScratchDir is the writable directory programs run in
+const ScratchDir = "/tmp"
+
+// CacheDir is where Config.CacheDir is mounted inside the sandbox
+const CacheDir = "/cache"
+
+// Limits caps the resources a program may use. A zero value leaves that
+// resource unlimited.
+type Limits struct {
+	// CPUTime is the processor time the program may use
+	CPUTime time.Duration `json:"cpu_time"`
+	// MemoryBytes caps each process's address space
+	MemoryBytes int64 `json:"memory_bytes"`
+	// FileSizeBytes caps the size of any file the program writes
+	FileSizeBytes int64 `json:"file_size_bytes"`
+	// Processes caps the processes and threads the program runs at once
+	Processes int `json:"processes"`
+	// OpenFiles caps the file descriptors each process may hold
+	OpenFiles int `json:"open_files"`
+}
+
+// Config configures a sandbox
+type Config struct {
+	// Timeout is the wall clock time a program may run
+	Timeout time.Duration `json:"timeout"`
+	Limits  Limits        `json:"limits"`
+	// ScratchBytes is the size of the tmpfs at /tmp
+	ScratchBytes int64 `json:"scratch_bytes"`
+	// MaxOutputBytes is how much of each of stdout and stderr is kept
+	MaxOutputBytes int `json:"max_output_bytes"`
+	// ReadOnlyPaths are the host paths visible to programs; paths that do
+	// not exist are skipped. The directory holding the program's
+	// toolchain is added when it is not already covered.
+	ReadOnlyPaths []string `json:"read_only_paths"`
+	// CacheDir is a host directory mounted read-write at /cache so
+	// compilers can keep their caches between runs. Runs can affect each
+	// other through it; leave it empty for full isolation.
+	CacheDir string `json:"cache_dir,omitempty"`
+	// CgroupParent is a delegated cgroup v2 directory. When set, each run
+	// gets a child cgroup whose memory.max and pids.max are the memory and
+	// process limits, enforced across all of the run's processes.
+	CgroupParent string `json:"cgroup_parent,omitempty"`
+}
+
+// DefaultConfig returns limits suited to compiling and running short code
+// samples
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout: 30 * time.Second,
+		Limits: Limits{
+			CPUTime:       30 * time.Second,
+			MemoryBytes:   2 << 30,
+			FileSizeBytes: 64 << 20,
+			Processes:     128,
+			OpenFiles:     256,
+		},
+		ScratchBytes:   256 << 20,
+		MaxOutputBytes: 64 << 10,
+		ReadOnlyPaths: []string{
+			"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32",
+			"/etc/alternatives", "/etc/ld.so.cache", "/etc/passwd", "/etc/group",
+		},
+	}
+}
+
+// Command is a program to run in the sandbox
+type Command struct {
+	// Args is the program and its arguments. The program is looked up in
+	// the host's PATH.
+	Args []string
+	// Env is added to the sandbox's minimal environment
+	Env []string
+	// Files are written to the working directory before the program
+	// starts, keyed by relative path
+	Files map[string][]byte
+}
+
+// Result is the outcome of a run
+type Result struct {
+	// ExitCode is the program's exit status, or -1 when a signal ended it
+	ExitCode int `json:"exit_code"`
+	// Signal names the signal that ended the program, if any
+	Signal          string        `json:"signal,omitempty"`
+	TimedOut        bool          `json:"timed_out"`
+	Stdout          string        `json:"stdout"`
+	Stderr          string        `json:"stderr"`
+	StdoutTruncated bool          `json:"stdout_truncated"`
+	StderrTruncated bool          `json:"stderr_truncated"`
+	Duration        time.Duration `json:"duration"`
+}
+
+// Success reports whether the program exited with status 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0 && r.Signal == "" && !r.TimedOut
+}
+
+// Sandbox runs programs in isolation
+type Sandbox struct {
+	config *Config
+}
+
+// New creates a sandbox, checking that this host can run one; a nil config
+// uses DefaultConfig
+func New(config *Config) (*Sandbox, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	s := &Sandbox{config: config}
+	if err := s.probe(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run runs a command in the sandbox and waits for it. An error means the
+// command could not be run; a program that fails is reported in the
+// result.
+func (s *Sandbox) Run(ctx context.Context, command Command) (*Result, error) {
+	if len(command.Args) == 0 {
+		return nil, fmt.Errorf("no command to run")
+	}
+	for name := range command.Files {
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("file name must be a local path: %s", name)
+		}
+	}
+	return s.run(ctx, command)
+}
+
+// initialized is set by Init so New can refuse to start programs in a
+// binary that would not set them up
+var (
+	initMu      sync.Mutex
+	initialized bool
+)
+
+func markInitialized() {
+	initMu.Lock()
+	defer initMu.Unlock()
+	initialized = true
+}
+
+func isInitialized() bool {
+	initMu.Lock()
+	defer initMu.Unlock()
+	return initialized
+}
+
+// outputBuffer keeps the first max bytes written to it
+type outputBuffer struct {
+	max       int
+	data      []byte
+	truncated bool
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	room := b.max - len(b.data)
+	if room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.data = append(b.data, p[:room]...)
+		}
+		return len(p), nil
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *outputBuffer) String() string {
+	return strings.ToValidUTF8(string(b.data), "�")
+}
@@ -0,0 +1,569 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// initEnv marks a process started by the sandbox to set it up
+const initEnv = "_CAIA_SANDBOX_INIT"
+
+// File descriptors a sandbox process receives its spec on and reports
+// setup failures to
+const (
+	specFD  = 3
+	errorFD = 4
+)
+
+// Linux constants the syscall package does not define
+const (
+	rlimitNproc = 6
+
+	prCapbsetDrop        = 24
+	prSetSecurebits      = 28
+	prSetNoNewPrivs      = 38
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+
+	// SECBIT_NOROOT, SECBIT_NO_SETUID_FIXUP and their locks, and
+	// SECBIT_KEEP_CAPS_LOCKED: executing a program as root grants nothing
+	lockedSecurebits = 0x2f
+
+	linuxCapabilityVersion3 = 0x20080522
+
+	stNodev      = 0x4
+	stNoexec     = 0x8
+	stNoatime    = 0x400
+	stNodiratime = 0x800
+	stRelatime   = 0x1000
+)
+
+// devices are the device nodes programs get in /dev
+var devices = []string{"null", "zero", "full", "random", "urandom"}
+
+// spec tells a sandbox process what to set up and run
+type spec struct {
+	// Root is an empty host directory the root file system is built on
+	Root   string  `json:"root"`
+	Mounts []mount `json:"mounts"`
+	// ScratchBytes is the size of the tmpfs at /tmp
+	ScratchBytes int64  `json:"scratch_bytes"`
+	Limits       Limits `json:"limits"`
+
+	// Path is the program to execute; empty only checks that the sandbox
+	// can be set up
+	Path  string            `json:"path,omitempty"`
+	Args  []string          `json:"args,omitempty"`
+	Env   []string          `json:"env,omitempty"`
+	Files map[string][]byte `json:"files,omitempty"`
+}
+
+// mount makes a host path visible in the sandbox, or recreates a symlink
+type mount struct {
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	Link     string `json:"link,omitempty"`
+	Writable bool   `json:"writable,omitempty"`
+}
+
+// Init sets up and runs the program when the current process was started by
+// the sandbox, and returns otherwise. It must be called before anything
+// else in main.
+func Init() {
+	markInitialized()
+	if os.Getenv(initEnv) == "" {
+		return
+	}
+
+	// Credentials are per thread, so drop them on the thread that execs
+	runtime.LockOSThread()
+	errs := os.NewFile(errorFD, "sandbox-errors")
+	err := startProgram()
+	fmt.Fprint(errs, err.Error())
+	os.Exit(125)
+}
+
+// startProgram sets up the sandbox and replaces this process with the
+// program; it only returns on failure
+func startProgram() error {
+	syscall.CloseOnExec(specFD)
+	syscall.CloseOnExec(errorFD)
+
+	var sp spec
+	if err := json.NewDecoder(os.NewFile(specFD, "sandbox-spec")).Decode(&sp); err != nil {
+		return fmt.Errorf("failed to read sandbox spec: %w", err)
+	}
+	if err := buildRoot(&sp); err != nil {
+		return err
+	}
+	for name, data := range sp.Files {
+		path := filepath.Join(ScratchDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	if err := os.Chdir(ScratchDir); err != nil {
+		return fmt.Errorf("failed to enter scratch directory: %w", err)
+	}
+	if err := setLimits(sp.Limits); err != nil {
+		return err
+	}
+	if err := dropPrivileges(); err != nil {
+		return err
+	}
+	if sp.Path == "" {
+		os.Exit(0)
+	}
+	if err := syscall.Exec(sp.Path, sp.Args, sp.Env); err != nil {
+		return fmt.Errorf("failed to execute %s: %w", sp.Path, err)
+	}
+	return nil
+}
+
+// buildRoot assembles the read-only root file system on sp.Root and makes
+// it the process's root
+func buildRoot(sp *spec) error {
+	root := sp.Root
+	// Keep the mounts below out of the host's mount namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "size=1m,mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount root: %w", err)
+	}
+
+	for _, m := range sp.Mounts {
+		if err := bindMount(root, m); err != nil {
+			return err
+		}
+	}
+
+	dev := filepath.Join(root, "dev")
+	if err := os.MkdirAll(dev, 0755); err != nil {
+		return fmt.Errorf("failed to create /dev: %w", err)
+	}
+	for _, name := range devices {
+		if err := bindMount(root, mount{Source: "/dev/" + name, Target: "/dev/" + name, Writable: true}); err != nil {
+			return err
+		}
+	}
+	for name, link := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(link, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("failed to create /dev/%s: %w", name, err)
+		}
+	}
+
+	proc := filepath.Join(root, "proc")
+	if err := os.Mkdir(proc, 0755); err != nil {
+		return fmt.Errorf("failed to create /proc: %w", err)
+	}
+	if err := syscall.Mount("proc", proc, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	scratch := filepath.Join(root, ScratchDir)
+	if err := os.MkdirAll(scratch, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", ScratchDir, err)
+	}
+	options := "mode=0755"
+	if sp.ScratchBytes > 0 {
+		options += ",size=" + strconv.FormatInt(sp.ScratchBytes, 10)
+	}
+	if err := syscall.Mount("tmpfs", scratch, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, options); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", ScratchDir, err)
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return fmt.Errorf("failed to create old root: %w", err)
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("failed to switch root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return fmt.Errorf("failed to enter new root: %w", err)
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach old root: %w", err)
+	}
+	if err := os.Remove("/.oldroot"); err != nil {
+		return fmt.Errorf("failed to remove old root: %w", err)
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to make root read-only: %w", err)
+	}
+	if err := syscall.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("failed to set hostname: %w", err)
+	}
+	return nil
+}
+
+// bindMount mounts a host path at its target below root, read-only unless
+// it is writable
+func bindMount(root string, m mount) error {
+	target := filepath.Join(root, m.Target)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent of %s: %w", m.Target, err)
+	}
+	if m.Link != "" {
+		if err := os.Symlink(m.Link, target); err != nil {
+			return fmt.Errorf("failed to link %s: %w", m.Target, err)
+		}
+		return nil
+	}
+
+	info, err := os.Stat(m.Source)
+	if err != nil {
+		return fmt.Errorf("failed to mount %s: %w", m.Source, err)
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		err = os.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create mount point for %s: %w", m.Target, err)
+	}
+	if err := syscall.Mount(m.Source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", m.Source, err)
+	}
+
+	// A remount in a user namespace must keep the flags the source mount
+	// was locked with
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(m.Source, &stat); err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", m.Source, err)
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_NOSUID)
+	for st, ms := range map[int64]uintptr{
+		stNodev:      syscall.MS_NODEV,
+		stNoexec:     syscall.MS_NOEXEC,
+		stNoatime:    syscall.MS_NOATIME,
+		stNodiratime: syscall.MS_NODIRATIME,
+		stRelatime:   syscall.MS_RELATIME,
+	} {
+		if int64(stat.Flags)&st != 0 {
+			flags |= ms
+		}
+	}
+	if !m.Writable {
+		flags |= syscall.MS_RDONLY
+	}
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to restrict %s: %w", m.Target, err)
+	}
+	return nil
+}
+
+// setLimits applies the rlimits; the program and everything it starts
+// inherit them
+func setLimits(limits Limits) error {
+	type rlimit struct {
+		resource int
+		name     string
+		value    int64
+	}
+	rlimits := []rlimit{
+		{syscall.RLIMIT_CPU, "CPU time", int64((limits.CPUTime + time.Second - 1) / time.Second)},
+		{syscall.RLIMIT_AS, "memory", limits.MemoryBytes},
+		{syscall.RLIMIT_FSIZE, "file size", limits.FileSizeBytes},
+		{rlimitNproc, "processes", int64(limits.Processes)},
+		{syscall.RLIMIT_NOFILE, "open files", int64(limits.OpenFiles)},
+	}
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{}); err != nil {
+		return fmt.Errorf("failed to disable core dumps: %w", err)
+	}
+	for _, limit := range rlimits {
+		if limit.value <= 0 {
+			continue
+		}
+		value := uint64(limit.value)
+		if err := syscall.Setrlimit(limit.resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("failed to limit %s: %w", limit.name, err)
+		}
+	}
+	return nil
+}
+
+// dropPrivileges leaves the program no capabilities, even though it runs
+// as root in its user namespace, and no way to gain any
+func dropPrivileges() error {
+	for capability := uintptr(0); ; capability++ {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapbsetDrop, capability, 0)
+		if errno == syscall.EINVAL {
+			break
+		}
+		if errno != 0 {
+			return fmt.Errorf("failed to drop capability %d: %w", capability, errno)
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSecurebits, lockedSecurebits, 0); errno != 0 {
+		return fmt.Errorf("failed to lock securebits: %w", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0); errno != 0 && errno != syscall.EINVAL {
+		return fmt.Errorf("failed to clear ambient capabilities: %w", errno)
+	}
+
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityVersion3}
+	var data [2]struct {
+		effective   uint32
+		permitted   uint32
+		inheritable uint32
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to clear capabilities: %w", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("failed to set no_new_privs: %w", errno)
+	}
+	return nil
+}
+
+// probe checks that a sandbox can be set up by setting one up without a
+// program
+func (s *Sandbox) probe() error {
+	if !isInitialized() {
+		return fmt.Errorf("sandbox.Init must be called at the start of main")
+	}
+	result, err := s.execute(context.Background(), s.newSpec())
+	if err != nil {
+		return err
+	}
+	if !result.Success() {
+		return fmt.Errorf("sandbox check failed with exit code %d: %s", result.ExitCode, result.Stderr)
+	}
+	return nil
+}
+
+func (s *Sandbox) run(ctx context.Context, command Command) (*Result, error) {
+	path, err := exec.LookPath(command.Args[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", command.Args[0], err)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", command.Args[0], err)
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", command.Args[0], err)
+	}
+
+	sp := s.newSpec()
+	if !sp.covers(path) {
+		// Mount the toolchain the program belongs to, such as /opt/go for
+		// /opt/go/bin/go
+		prefix := filepath.Dir(filepath.Dir(path))
+		if prefix == "/" {
+			prefix = filepath.Dir(path)
+		}
+		sp.Mounts = append(sp.Mounts, mount{Source: prefix, Target: prefix})
+	}
+	sp.Path = path
+	sp.Args = command.Args
+	sp.Files = command.Files
+	sp.Env = []string{
+		"PATH=" + filepath.Dir(path) + ":/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + ScratchDir,
+		"TMPDIR=" + ScratchDir,
+		"LANG=C.UTF-8",
+	}
+	if s.config.CacheDir != "" {
+		sp.Env = append(sp.Env, "XDG_CACHE_HOME="+CacheDir)
+	}
+	sp.Env = append(sp.Env, command.Env...)
+
+	return s.execute(ctx, sp)
+}
+
+// newSpec describes the file system every program gets
+func (s *Sandbox) newSpec() *spec {
+	sp := &spec{
+		ScratchBytes: s.config.ScratchBytes,
+		Limits:       s.config.Limits,
+	}
+	for _, path := range s.config.ReadOnlyPaths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				continue
+			}
+			sp.Mounts = append(sp.Mounts, mount{Target: path, Link: link})
+			continue
+		}
+		sp.Mounts = append(sp.Mounts, mount{Source: path, Target: path})
+	}
+	if s.config.CacheDir != "" {
+		sp.Mounts = append(sp.Mounts, mount{Source: s.config.CacheDir, Target: CacheDir, Writable: true})
+	}
+	return sp
+}
+
+// covers reports whether a host path is visible through a mount
+func (sp *spec) covers(path string) bool {
+	for _, m := range sp.Mounts {
+		if m.Link == "" && !m.Writable && (path == m.Source || strings.HasPrefix(path, m.Source+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// execute starts a sandbox process for sp and waits for it
+func (s *Sandbox) execute(ctx context.Context, sp *spec) (*Result, error) {
+	if s.config.CacheDir != "" {
+		if err := os.MkdirAll(s.config.CacheDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create sandbox cache: %w", err)
+		}
+	}
+	root, err := os.MkdirTemp("", "caia-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+	defer os.RemoveAll(root)
+	sp.Root = root
+
+	specReader, specWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spec pipe: %w", err)
+	}
+	defer specWriter.Close()
+	errReader, errWriter, err := os.Pipe()
+	if err != nil {
+		specReader.Close()
+		return nil, fmt.Errorf("failed to create error pipe: %w", err)
+	}
+	defer errReader.Close()
+
+	runCtx := ctx
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	stdout := &outputBuffer{max: s.config.MaxOutputBytes}
+	stderr := &outputBuffer{max: s.config.MaxOutputBytes}
+	cmd := exec.CommandContext(runCtx, "/proc/self/exe")
+	cmd.Args = []string{"caia-sandbox"}
+	cmd.Env = []string{initEnv + "=1"}
+	cmd.ExtraFiles = []*os.File{specReader, errWriter}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	if s.config.CgroupParent != "" {
+		cgroup, err := s.createCgroup()
+		if err != nil {
+			specReader.Close()
+			errWriter.Close()
+			return nil, err
+		}
+		defer os.Remove(cgroup.Name())
+		defer cgroup.Close()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	specReader.Close()
+	errWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	// A process that fails before reading its spec reports why on the
+	// error pipe
+	json.NewEncoder(specWriter).Encode(sp)
+	specWriter.Close()
+
+	waitErr := cmd.Wait()
+	setupErr, _ := io.ReadAll(errReader)
+	if len(setupErr) > 0 {
+		return nil, fmt.Errorf("failed to set up sandbox: %s", setupErr)
+	}
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) && !errors.Is(waitErr, exec.ErrWaitDelay) {
+		return nil, fmt.Errorf("failed to run sandbox: %w", waitErr)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result := &Result{
+		TimedOut:        errors.Is(runCtx.Err(), context.DeadlineExceeded),
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+		Duration:        time.Since(start),
+	}
+	status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if status.Signaled() {
+		result.ExitCode = -1
+		result.Signal = status.Signal().String()
+	} else {
+		result.ExitCode = status.ExitStatus()
+	}
+	return result, nil
+}
+
+// createCgroup creates a cgroup for one run, limited to the configured
+// memory and processes
+func (s *Sandbox) createCgroup() (*os.File, error) {
+	dir, err := os.MkdirTemp(s.config.CgroupParent, "sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox cgroup: %w", err)
+	}
+	settings := map[string]string{}
+	if s.config.Limits.MemoryBytes > 0 {
+		settings["memory.max"] = strconv.FormatInt(s.config.Limits.MemoryBytes, 10)
+		settings["memory.swap.max"] = "0"
+	}
+	if s.config.Limits.Processes > 0 {
+		settings["pids.max"] = strconv.Itoa(s.config.Limits.Processes)
+	}
+	for name, value := range settings {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+		// Swap accounting may be disabled
+		if err != nil && !(name == "memory.swap.max" && errors.Is(err, os.ErrNotExist)) {
+			os.Remove(dir)
+			return nil, fmt.Errorf("failed to set %s on sandbox cgroup: %w", name, err)
+		}
+	}
+	cgroup, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return nil, fmt.Errorf("failed to open sandbox cgroup: %w", err)
+	}
+	return cgroup, nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sandbox
+
+import "context"
+
+// Init does nothing on platforms without a sandbox
+func Init() {
+	markInitialized()
+}
+
+func (s *Sandbox) probe() error {
+	return ErrUnsupported
+}
+
+func (s *Sandbox) run(ctx context.Context, command Command) (*Result, error) {
+	return nil, ErrUnsupported
+}
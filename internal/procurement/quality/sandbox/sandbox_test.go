@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+// newTestSandbox creates a sandbox or skips the test where the host does not
+// allow one, such as without unprivileged user namespaces
+func newTestSandbox(t *testing.T, configure func(*Config)) *Sandbox {
+	t.Helper()
+	config := DefaultConfig()
+	config.Timeout = 10 * time.Second
+	if configure != nil {
+		configure(config)
+	}
+	s, err := New(config)
+	if err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	return s
+}
+
+func run(t *testing.T, s *Sandbox, script string) *Result {
+	t.Helper()
+	result, err := s.Run(context.Background(), Command{Args: []string{"sh", "-c", script}})
+	require.NoError(t, err)
+	return result
+}
+
+func TestRunCapturesOutput(t *testing.T) {
+	s := newTestSandbox(t, func(c *Config) { c.MaxOutputBytes = 16 })
+
+	result := run(t, s, "echo out; echo err >&2; exit 3")
+	assert.Equal(t, 3, result.ExitCode)
+	assert.False(t, result.Success())
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.False(t, result.StdoutTruncated)
+
+	result = run(t, s, "head -c 100 /dev/zero | tr '\\0' a")
+	assert.True(t, result.Success())
+	assert.Equal(t, strings.Repeat("a", 16), result.Stdout)
+	assert.True(t, result.StdoutTruncated)
+
+	result, err := s.Run(context.Background(), Command{
+		Args:  []string{"sh", "sample/main.sh"},
+		Files: map[string][]byte{"sample/main.sh": []byte("echo \"$GREETING from $(pwd)\"")},
+		Env:   []string{"GREETING=hello"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello from /tmp\n", result.Stdout)
+
+	_, err = s.Run(context.Background(), Command{Args: []string{"sh"}, Files: map[string][]byte{"../escape": nil}})
+	assert.Error(t, err)
+	_, err = s.Run(context.Background(), Command{Args: []string{"no-such-program"}})
+	assert.Error(t, err)
+}
+
+func TestRunIsolatesProgram(t *testing.T) {
+	cache := t.TempDir()
+	s := newTestSandbox(t, func(c *Config) { c.CacheDir = cache })
+	secret := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0644))
+
+	result := run(t, s, "cat "+secret)
+	assert.NotEqual(t, 0, result.ExitCode, "host files outside the read-only paths are hidden")
+
+	result = run(t, s, "touch /usr/caia-sandbox-test || echo denied")
+	assert.Equal(t, "denied\n", result.Stdout, "the root is read-only")
+
+	result = run(t, s, "echo scratch > /tmp/file && cat /tmp/file && echo cached > /cache/file")
+	assert.Equal(t, "scratch\n", result.Stdout)
+	data, err := os.ReadFile(filepath.Join(cache, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "cached\n", string(data))
+
+	result = run(t, s, "hostname; echo $$; grep CapEff /proc/self/status")
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "sandbox", lines[0])
+	assert.Equal(t, "1", lines[1], "the program runs in its own PID namespace")
+	assert.Equal(t, "CapEff:\t0000000000000000", lines[2])
+
+	result = run(t, s, "cat /proc/net/dev | grep -c :")
+	assert.Equal(t, "1\n", result.Stdout, "only the loopback interface exists")
+
+	if _, err := exec.LookPath("python3"); err == nil {
+		result, err = s.Run(context.Background(), Command{
+			Args: []string{"python3", "-c", "import socket; socket.create_connection(('1.1.1.1', 53), timeout=2)"},
+		})
+		require.NoError(t, err)
+		assert.NotEqual(t, 0, result.ExitCode)
+		assert.Contains(t, result.Stderr, "unreachable")
+	}
+}
+
+func TestRunEnforcesLimits(t *testing.T) {
+	s := newTestSandbox(t, func(c *Config) {
+		c.Timeout = 500 * time.Millisecond
+		c.Limits.FileSizeBytes = 1 << 20
+	})
+	result := run(t, s, "sleep 5")
+	assert.True(t, result.TimedOut)
+	assert.Less(t, result.Duration, 3*time.Second)
+
+	result = run(t, s, "head -c 2000000 /dev/zero > /tmp/big")
+	assert.False(t, result.Success(), "file size is limited")
+
+	s = newTestSandbox(t, func(c *Config) {
+		c.Limits.CPUTime = time.Second
+	})
+	result = run(t, s, "while :; do :; done")
+	assert.False(t, result.TimedOut)
+	assert.NotEmpty(t, result.Signal, "CPU time is limited")
+
+	s = newTestSandbox(t, func(c *Config) {
+		c.ScratchBytes = 1 << 20
+	})
+	result = run(t, s, "head -c 2000000 /dev/zero > /tmp/big")
+	assert.False(t, result.Success(), "scratch space is limited")
+	assert.Contains(t, result.Stderr, "No space left")
+}
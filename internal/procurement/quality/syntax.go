@@ -0,0 +1,70 @@
+package quality
+
+import (
+	"fmt"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+)
+
+// Diagnostic sources
+const (
+	diagnosticSyntax = "syntax"
+	diagnosticTypes  = "types"
+)
+
+// maxDiagnostics caps the diagnostics reported for one snippet
+const maxDiagnostics = 20
+
+// diagnostics collects the problems found in a snippet
+type diagnostics []procurement.CodeDiagnostic
+
+func (d *diagnostics) add(line, column int, severity, source, format string, args ...interface{}) {
+	if len(*d) >= maxDiagnostics {
+		return
+	}
+	*d = append(*d, procurement.CodeDiagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Source:   source,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// firstError returns the first error a check found
+func (d diagnostics) firstError(source string) (procurement.CodeDiagnostic, bool) {
+	for _, diagnostic := range d {
+		if diagnostic.Source == source && diagnostic.Severity == procurement.DiagnosticError {
+			return diagnostic, true
+		}
+	}
+	return procurement.CodeDiagnostic{}, false
+}
+
+// syntaxResult is what checking a snippet found
+type syntaxResult struct {
+	// Source is the program to compile and run. Go fragments are wrapped
+	// into a complete file; other snippets are used as they are.
+	Source      string
+	Diagnostics diagnostics
+}
+
+// checkSyntax parses a snippet with a parser or tokenizer for its language
+func (cv *CodeValidator) checkSyntax(code, language string) syntaxResult {
+	switch language {
+	case "go":
+		return cv.goChecker.check(code)
+	case "python":
+		return syntaxResult{Source: code, Diagnostics: checkPythonSyntax(code)}
+	case "javascript":
+		return syntaxResult{Source: code, Diagnostics: checkCLikeSyntax(code, javaScriptDialect)}
+	case "java":
+		return syntaxResult{Source: code, Diagnostics: checkCLikeSyntax(code, javaDialect)}
+	}
+	return syntaxResult{Source: code}
+}
+
+// describeDiagnostic formats a diagnostic for CodeValidation.Errors
+func describeDiagnostic(diagnostic procurement.CodeDiagnostic) string {
+	return fmt.Sprintf("line %d:%d: %s", diagnostic.Line, diagnostic.Column, diagnostic.Message)
+}
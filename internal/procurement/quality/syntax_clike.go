@@ -0,0 +1,359 @@
+package quality
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+)
+
+// cLikeDialect describes the lexical rules of a language with C-like
+// brackets, strings and comments
+type cLikeDialect struct {
+	// templates are backtick strings with ${expression} fields
+	templates bool
+	// regexps are /pattern/flags literals where an expression may start
+	regexps bool
+	// charLiterals are single-quoted characters rather than strings
+	charLiterals bool
+	// textBlocks are triple-quoted strings
+	textBlocks bool
+	// privateNames are #names, and a #! line may start the file
+	privateNames bool
+	// regexpKeywords are the keywords an expression, and so a regexp, may
+	// follow
+	regexpKeywords map[string]bool
+}
+
+var javaScriptDialect = &cLikeDialect{
+	templates:    true,
+	regexps:      true,
+	privateNames: true,
+	regexpKeywords: map[string]bool{
+		"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+		"new": true, "delete": true, "void": true, "throw": true, "case": true,
+		"do": true, "else": true, "yield": true, "await": true,
+	},
+}
+
+var javaDialect = &cLikeDialect{
+	charLiterals: true,
+	textBlocks:   true,
+}
+
+// templateBracket marks an open ${ in a template literal
+const templateBracket = '$'
+
+// cLikeTokenizer reads JavaScript or Java source, checking its strings,
+// comments and brackets
+type cLikeTokenizer struct {
+	dialect   *cLikeDialect
+	src       string
+	pos       int
+	line      int
+	lineStart int
+	diags     diagnostics
+	brackets  []bracket
+	// templates are the starts of template literals with an open ${ field
+	templates []bracket
+	// regexpAllowed is set where an expression may start, so / begins a
+	// regexp rather than dividing
+	regexpAllowed bool
+}
+
+func checkCLikeSyntax(code string, dialect *cLikeDialect) diagnostics {
+	t := &cLikeTokenizer{
+		dialect:       dialect,
+		src:           code,
+		line:          1,
+		regexpAllowed: true,
+	}
+	t.run()
+	return t.diags
+}
+
+func (t *cLikeTokenizer) column() int {
+	return t.pos - t.lineStart + 1
+}
+
+func (t *cLikeTokenizer) errorAt(line, column int, format string, args ...interface{}) {
+	t.diags.add(line, column, procurement.DiagnosticError, diagnosticSyntax, format, args...)
+}
+
+func (t *cLikeTokenizer) newline() {
+	t.pos++
+	t.line++
+	t.lineStart = t.pos
+}
+
+func (t *cLikeTokenizer) run() {
+	if t.dialect.privateNames && strings.HasPrefix(t.src, "#!") {
+		t.skipLine()
+	}
+
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		rest := t.src[t.pos:]
+		switch {
+		case c == '\n':
+			t.newline()
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			t.pos++
+		case strings.HasPrefix(rest, "//"):
+			t.skipLine()
+		case strings.HasPrefix(rest, "/*"):
+			t.skipComment()
+		case c == '"' && t.dialect.textBlocks && strings.HasPrefix(rest, `"""`):
+			t.scanTextBlock()
+			t.regexpAllowed = false
+		case c == '"' || c == '\'' && !t.dialect.charLiterals:
+			t.scanString(c, "string literal")
+			t.regexpAllowed = false
+		case c == '\'':
+			start, line, column := t.pos, t.line, t.column()
+			t.scanString(c, "character literal")
+			if t.pos == start+2 && t.src[start+1] == '\'' {
+				t.errorAt(line, column, "empty character literal")
+			}
+			t.regexpAllowed = false
+		case c == '`' && t.dialect.templates:
+			t.pos++
+			t.scanTemplate(t.line, t.column()-1)
+			t.regexpAllowed = false
+		case c >= '0' && c <= '9' || c == '.' && t.pos+1 < len(t.src) && t.src[t.pos+1] >= '0' && t.src[t.pos+1] <= '9':
+			t.scanNumber()
+			t.regexpAllowed = false
+		case c == '(' || c == '[' || c == '{':
+			t.brackets = append(t.brackets, bracket{c, t.line, t.column()})
+			t.pos++
+			t.regexpAllowed = true
+		case c == ')' || c == ']' || c == '}':
+			if t.closeBracket(c) {
+				// The template literal continues after its ${} field
+				start := t.templates[len(t.templates)-1]
+				t.templates = t.templates[:len(t.templates)-1]
+				t.pos++
+				t.scanTemplate(start.line, start.column)
+				t.regexpAllowed = false
+				continue
+			}
+			t.pos++
+			t.regexpAllowed = c == '}'
+		case c == '/' && t.dialect.regexps && t.regexpAllowed:
+			t.scanRegexp()
+			t.regexpAllowed = false
+		case c == '#' && t.dialect.privateNames:
+			t.pos++
+			t.scanName()
+		case strings.IndexByte("+-*/%=&|^!~<>?:;,.@", c) >= 0:
+			t.pos++
+			t.regexpAllowed = true
+		default:
+			r, size := utf8.DecodeRuneInString(rest)
+			if r == '_' || r == '$' || unicode.IsLetter(r) {
+				t.scanName()
+				continue
+			}
+			t.errorAt(t.line, t.column(), "invalid character '%c' (U+%04X)", r, r)
+			t.pos += size
+		}
+	}
+
+	for _, open := range t.brackets {
+		if open.char == templateBracket {
+			t.errorAt(open.line, open.column, "'${' in template literal was never closed")
+		} else {
+			t.errorAt(open.line, open.column, "'%c' was never closed", open.char)
+		}
+	}
+}
+
+// closeBracket matches a closing bracket with the open one, and reports
+// whether it closed a template literal's ${ field
+func (t *cLikeTokenizer) closeBracket(c byte) bool {
+	if len(t.brackets) == 0 {
+		t.errorAt(t.line, t.column(), "unexpected '%c'", c)
+		return false
+	}
+	open := t.brackets[len(t.brackets)-1]
+	if open.char == templateBracket {
+		if c == '}' {
+			t.brackets = t.brackets[:len(t.brackets)-1]
+			return true
+		}
+		t.errorAt(t.line, t.column(), "'%c' does not match '${' opened on line %d", c, open.line)
+		return false
+	}
+	t.brackets = t.brackets[:len(t.brackets)-1]
+	if open.char != closingBrackets[c] {
+		t.errorAt(t.line, t.column(), "'%c' does not match '%c' opened on line %d", c, open.char, open.line)
+	}
+	return false
+}
+
+func (t *cLikeTokenizer) skipLine() {
+	for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+		t.pos++
+	}
+}
+
+func (t *cLikeTokenizer) skipComment() {
+	line, column := t.line, t.column()
+	t.pos += 2
+	for t.pos < len(t.src) {
+		switch {
+		case strings.HasPrefix(t.src[t.pos:], "*/"):
+			t.pos += 2
+			return
+		case t.src[t.pos] == '\n':
+			t.newline()
+		default:
+			t.pos++
+		}
+	}
+	t.errorAt(line, column, "unterminated comment")
+}
+
+func (t *cLikeTokenizer) scanName() {
+	start := t.pos
+	for t.pos < len(t.src) {
+		r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		t.pos += size
+	}
+	t.regexpAllowed = t.dialect.regexpKeywords[t.src[start:t.pos]]
+}
+
+func (t *cLikeTokenizer) scanNumber() {
+	start := t.pos
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '.':
+			t.pos++
+		case (c == '+' || c == '-') && (t.src[t.pos-1] == 'e' || t.src[t.pos-1] == 'E') && !strings.HasPrefix(strings.ToLower(t.src[start:t.pos]), "0x"):
+			t.pos++
+		default:
+			return
+		}
+	}
+}
+
+// scanString reads a quoted literal that must end on its line
+func (t *cLikeTokenizer) scanString(quote byte, kind string) {
+	line, column := t.line, t.column()
+	t.pos++
+	for t.pos < len(t.src) {
+		switch t.src[t.pos] {
+		case '\\':
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] == '\n' && t.dialect.templates {
+				// JavaScript strings may continue on the next line
+				t.newline()
+				continue
+			}
+			t.pos++
+		case '\n':
+			t.errorAt(line, column, "unterminated %s", kind)
+			return
+		case quote:
+			t.pos++
+			return
+		default:
+			t.pos++
+		}
+	}
+	t.errorAt(line, column, "unterminated %s", kind)
+}
+
+// scanTextBlock reads a Java """ text block
+func (t *cLikeTokenizer) scanTextBlock() {
+	line, column := t.line, t.column()
+	t.pos += 3
+	for t.pos < len(t.src) {
+		switch {
+		case t.src[t.pos] == '\\':
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] == '\n' {
+				t.newline()
+				continue
+			}
+			t.pos++
+		case strings.HasPrefix(t.src[t.pos:], `"""`):
+			t.pos += 3
+			return
+		case t.src[t.pos] == '\n':
+			t.newline()
+		default:
+			t.pos++
+		}
+	}
+	t.errorAt(line, column, "unterminated text block")
+}
+
+// scanTemplate reads a template literal after its opening backtick, or
+// after a ${} field, up to its end or its next ${
+func (t *cLikeTokenizer) scanTemplate(line, column int) {
+	for t.pos < len(t.src) {
+		switch {
+		case t.src[t.pos] == '\\':
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] == '\n' {
+				t.newline()
+				continue
+			}
+			t.pos++
+		case t.src[t.pos] == '`':
+			t.pos++
+			return
+		case strings.HasPrefix(t.src[t.pos:], "${"):
+			t.brackets = append(t.brackets, bracket{templateBracket, t.line, t.column()})
+			t.templates = append(t.templates, bracket{'`', line, column})
+			t.pos += 2
+			t.regexpAllowed = true
+			return
+		case t.src[t.pos] == '\n':
+			t.newline()
+		default:
+			t.pos++
+		}
+	}
+	t.errorAt(line, column, "unterminated template literal")
+}
+
+// scanRegexp reads a /pattern/flags literal
+func (t *cLikeTokenizer) scanRegexp() {
+	line, column := t.line, t.column()
+	t.pos++
+	inClass := false
+	for t.pos < len(t.src) {
+		switch t.src[t.pos] {
+		case '\\':
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+		case '\n':
+			t.errorAt(line, column, "unterminated regular expression literal")
+			return
+		case '[':
+			inClass = true
+			t.pos++
+		case ']':
+			inClass = false
+			t.pos++
+		case '/':
+			t.pos++
+			if !inClass {
+				t.scanName()
+				return
+			}
+		default:
+			t.pos++
+		}
+	}
+	t.errorAt(line, column, "unterminated regular expression literal")
+}
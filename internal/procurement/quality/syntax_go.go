@@ -0,0 +1,318 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+)
+
+// goPackageClause finds the package clause of a complete Go file
+var goPackageClause = regexp.MustCompile(`(?m)^\s*package\s+[\p{L}_][\p{L}\p{N}_]*`)
+
+// goSnippetImports are the standard packages imported for Go fragments that
+// use them without an import, keyed by package name
+var goSnippetImports = map[string]string{
+	"base64":   "encoding/base64",
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"cmp":      "cmp",
+	"context":  "context",
+	"errors":   "errors",
+	"filepath": "path/filepath",
+	"fmt":      "fmt",
+	"hex":      "encoding/hex",
+	"http":     "net/http",
+	"io":       "io",
+	"json":     "encoding/json",
+	"log":      "log",
+	"maps":     "maps",
+	"math":     "math",
+	"os":       "os",
+	"rand":     "math/rand",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"slices":   "slices",
+	"slog":     "log/slog",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"time":     "time",
+	"unicode":  "unicode",
+	"url":      "net/url",
+	"utf8":     "unicode/utf8",
+}
+
+// goWrapping turns a Go fragment into a file
+type goWrapping struct {
+	// header goes before the fragment and ends in a newline; imports are
+	// added to its first line so positions only shift by its line count
+	header  string
+	footer  string
+	imports []string
+}
+
+// goWrappings are the ways a fragment without a package clause is read:
+// as declarations, then as the body of main
+var goWrappings = []goWrapping{
+	{header: "package snippet\n"},
+	{header: "package main\n\nfunc main() {\n", footer: "\n}\n"},
+}
+
+func (w goWrapping) wrap(code string) string {
+	header := w.header
+	if len(w.imports) > 0 {
+		quoted := make([]string, len(w.imports))
+		for i, path := range w.imports {
+			quoted[i] = fmt.Sprintf("%q", path)
+		}
+		newline := strings.Index(header, "\n")
+		header = header[:newline] + "; import (" + strings.Join(quoted, "; ") + ")" + header[newline:]
+	}
+	return header + code + w.footer
+}
+
+// lines is how many lines the wrapping adds before the fragment
+func (w goWrapping) lines() int {
+	return strings.Count(w.header, "\n")
+}
+
+// goChecker parses and type-checks Go snippets. Type-checked standard
+// library packages are kept between snippets, in a file set of their own;
+// each snippet is parsed into a fresh one, so checking does not accumulate
+// the files of every snippet seen.
+type goChecker struct {
+	mu       sync.Mutex
+	importer *goSourceImporter
+}
+
+func newGoChecker() *goChecker {
+	return &goChecker{
+		importer: newGoSourceImporter(token.NewFileSet()),
+	}
+}
+
+// check parses a Go file or fragment and type-checks it
+func (g *goChecker) check(code string) syntaxResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fset := token.NewFileSet()
+	lines := strings.Split(code, "\n")
+	file, wrapping, diags := g.parse(fset, code, lines)
+	if file == nil {
+		return syntaxResult{Source: code, Diagnostics: diags}
+	}
+	if wrapping == nil {
+		return syntaxResult{Source: code, Diagnostics: g.typeCheck(fset, file, 0, lines)}
+	}
+
+	// Fragments often use standard packages without importing them
+	errs := g.typeErrors(fset, file)
+	imports := make(map[string]bool)
+	for _, err := range errs {
+		if name, ok := strings.CutPrefix(err.Msg, "undefined: "); ok {
+			if path, ok := goSnippetImports[name]; ok {
+				imports[path] = true
+			}
+		}
+	}
+	for path := range imports {
+		wrapping.imports = append(wrapping.imports, path)
+	}
+	sort.Strings(wrapping.imports)
+
+	source := wrapping.wrap(code)
+	if len(wrapping.imports) > 0 {
+		var err error
+		if file, err = parser.ParseFile(fset, "main.go", source, parser.AllErrors); err != nil {
+			return syntaxResult{Source: code, Diagnostics: g.parseDiagnostics(err, wrapping.lines(), lines)}
+		}
+	}
+	return syntaxResult{Source: source, Diagnostics: g.typeCheck(fset, file, wrapping.lines(), lines)}
+}
+
+// parse reads code as a file or, without a package clause, as a fragment.
+// It returns the wrapping a fragment needed, or the syntax errors of the
+// reading that got furthest.
+func (g *goChecker) parse(fset *token.FileSet, code string, lines []string) (*ast.File, *goWrapping, diagnostics) {
+	file, err := parser.ParseFile(fset, "main.go", code, parser.AllErrors)
+	if err == nil {
+		return file, nil, nil
+	}
+	best := g.parseDiagnostics(err, 0, lines)
+	if goPackageClause.MatchString(code) {
+		return nil, nil, best
+	}
+
+	for _, wrapping := range goWrappings {
+		file, err := parser.ParseFile(fset, "main.go", wrapping.wrap(code), parser.AllErrors)
+		if err == nil {
+			return file, &wrapping, nil
+		}
+		if diags := g.parseDiagnostics(err, wrapping.lines(), lines); furthest(diags, best) {
+			best = diags
+		}
+	}
+	return nil, nil, best
+}
+
+// furthest reports whether the first error in a comes after the first in b
+func furthest(a, b diagnostics) bool {
+	if len(b) == 0 {
+		return false
+	}
+	if len(a) == 0 {
+		return true
+	}
+	return a[0].Line > b[0].Line || (a[0].Line == b[0].Line && a[0].Column > b[0].Column)
+}
+
+func (g *goChecker) parseDiagnostics(err error, offset int, lines []string) diagnostics {
+	var diags diagnostics
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		diags.add(1, 1, procurement.DiagnosticError, diagnosticSyntax, "%s", err.Error())
+		return diags
+	}
+	for _, e := range list {
+		line, column := snippetPosition(e.Pos, offset, lines)
+		diags.add(line, column, procurement.DiagnosticError, diagnosticSyntax, "%s", e.Msg)
+	}
+	return diags
+}
+
+// typeErrors type-checks a parsed file
+func (g *goChecker) typeErrors(fset *token.FileSet, file *ast.File) []types.Error {
+	var errs []types.Error
+	config := types.Config{
+		Importer: g.importer,
+		Sizes:    types.SizesFor("gc", g.importer.context.GOARCH),
+		Error: func(err error) {
+			var typeErr types.Error
+			if errors.As(err, &typeErr) {
+				errs = append(errs, typeErr)
+			}
+		},
+	}
+	config.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	return errs
+}
+
+func (g *goChecker) typeCheck(fset *token.FileSet, file *ast.File, offset int, lines []string) diagnostics {
+	var diags diagnostics
+	for _, err := range g.typeErrors(fset, file) {
+		// Packages outside the standard library cannot be checked here,
+		// which says nothing about the snippet
+		severity := procurement.DiagnosticError
+		if strings.HasPrefix(err.Msg, "could not import") {
+			severity = procurement.DiagnosticWarning
+		}
+		line, column := snippetPosition(err.Fset.Position(err.Pos), offset, lines)
+		diags.add(line, column, severity, diagnosticTypes, "%s", err.Msg)
+	}
+	return diags
+}
+
+// snippetPosition maps a position in a wrapped file back to the snippet,
+// pinning positions in the wrapping to the snippet's first or last line
+func snippetPosition(pos token.Position, offset int, lines []string) (int, int) {
+	line := pos.Line - offset
+	switch {
+	case line < 1:
+		return 1, 1
+	case line > len(lines):
+		last := len(lines)
+		return last, len(lines[last-1]) + 1
+	}
+	return line, max(pos.Column, 1)
+}
+
+// goSourceImporter type-checks the standard library packages a snippet
+// imports from their source in GOROOT. Function bodies are skipped and cgo
+// is off, so importing never runs a tool; packages outside the standard
+// library are not found.
+type goSourceImporter struct {
+	fset     *token.FileSet
+	context  build.Context
+	packages map[string]*types.Package
+}
+
+func newGoSourceImporter(fset *token.FileSet) *goSourceImporter {
+	context := build.Default
+	context.CgoEnabled = false
+	context.GOPATH = ""
+	return &goSourceImporter{
+		fset:     fset,
+		context:  context,
+		packages: make(map[string]*types.Package),
+	}
+}
+
+// Import implements types.Importer
+func (imp *goSourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+// ImportFrom implements types.ImporterFrom
+func (imp *goSourceImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	// Looking up a package from a directory outside GOROOT can run the go
+	// command to resolve modules
+	if imp.context.GOROOT == "" || !strings.HasPrefix(dir, imp.context.GOROOT) {
+		dir = ""
+	}
+	bp, err := imp.context.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !bp.Goroot {
+		return nil, fmt.Errorf("%s is not in the standard library", path)
+	}
+	if pkg, ok := imp.packages[bp.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", bp.ImportPath)
+		}
+		return pkg, nil
+	}
+
+	imp.packages[bp.ImportPath] = nil
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(imp.fset, filepath.Join(bp.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			delete(imp.packages, bp.ImportPath)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	config := types.Config{
+		Importer:         imp,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Sizes:            types.SizesFor("gc", imp.context.GOARCH),
+		// The standard library is known to compile
+		Error: func(error) {},
+	}
+	pkg, err := config.Check(bp.ImportPath, imp.fset, files, nil)
+	if pkg == nil {
+		delete(imp.packages, bp.ImportPath)
+		return nil, err
+	}
+	imp.packages[bp.ImportPath] = pkg
+	return pkg, nil
+}
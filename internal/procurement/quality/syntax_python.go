@@ -0,0 +1,462 @@
+package quality
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+)
+
+// pythonBlockKeywords start compound statements, which need a colon
+var pythonBlockKeywords = map[string]bool{
+	"if": true, "elif": true, "else": true, "for": true, "while": true,
+	"def": true, "class": true, "try": true, "except": true, "finally": true,
+	"with": true, "async": true,
+}
+
+// bracket is an open bracket waiting to be closed
+type bracket struct {
+	char         byte
+	line, column int
+}
+
+// closingBrackets maps each closing bracket to its opening one
+var closingBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// pythonTokenizer follows Python's tokenizer: strings, brackets, comments,
+// line continuations and indentation. On top of the tokens it checks that
+// compound statements end their header with a colon and have an indented
+// block.
+type pythonTokenizer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+	diags     diagnostics
+
+	brackets []bracket
+	// indents holds the indentation columns of the open blocks, with tabs
+	// to multiples of 8; altIndents counts tabs as 1 to catch indentation
+	// that only lines up for one tab size
+	indents    []int
+	altIndents []int
+
+	// The logical line being read
+	first      string // its first token, or the kind of a literal
+	second     string
+	firstLine  int
+	firstCol   int
+	lastColon  bool // its last token is a colon outside brackets
+	anyColon   bool // it has a colon outside brackets
+	lastLine   int
+	lastColumn int  // just after its last token
+	inToken    bool // a token was read since lastColumn was updated
+
+	// block is the header of a compound statement waiting for its block
+	block     string
+	blockLine int
+}
+
+func checkPythonSyntax(code string) diagnostics {
+	t := &pythonTokenizer{
+		src:        code,
+		line:       1,
+		indents:    []int{0},
+		altIndents: []int{0},
+	}
+	t.run()
+	return t.diags
+}
+
+func (t *pythonTokenizer) column() int {
+	return t.pos - t.lineStart + 1
+}
+
+func (t *pythonTokenizer) errorAt(line, column int, format string, args ...interface{}) {
+	t.diags.add(line, column, procurement.DiagnosticError, diagnosticSyntax, format, args...)
+}
+
+func (t *pythonTokenizer) newline() {
+	t.pos++
+	t.line++
+	t.lineStart = t.pos
+}
+
+func (t *pythonTokenizer) run() {
+	atLineStart := true
+	for t.pos < len(t.src) {
+		t.endToken()
+		if atLineStart {
+			if !t.indent() {
+				continue
+			}
+			atLineStart = false
+		}
+
+		c := t.src[t.pos]
+		switch {
+		case c == '\n':
+			if len(t.brackets) == 0 {
+				t.endLogicalLine()
+				atLineStart = true
+			}
+			t.newline()
+		case c == '#':
+			for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+		case c == '\\':
+			if strings.HasPrefix(t.src[t.pos+1:], "\n") || strings.HasPrefix(t.src[t.pos+1:], "\r\n") {
+				t.pos = strings.IndexByte(t.src[t.pos:], '\n') + t.pos
+				t.newline()
+				continue
+			}
+			t.errorAt(t.line, t.column(), "unexpected character after line continuation character")
+			t.pos++
+		case c == ' ' || c == '\t' || c == '\f' || c == '\r':
+			t.pos++
+		case c == '"' || c == '\'':
+			t.token("string")
+			t.scanString("")
+		case c >= '0' && c <= '9' || c == '.' && t.pos+1 < len(t.src) && t.src[t.pos+1] >= '0' && t.src[t.pos+1] <= '9':
+			t.token("number")
+			t.scanNumber()
+		case c == '(' || c == '[' || c == '{':
+			t.token(string(c))
+			t.brackets = append(t.brackets, bracket{c, t.line, t.column()})
+			t.pos++
+		case c == ')' || c == ']' || c == '}':
+			t.token(string(c))
+			t.closeBracket(c)
+			t.pos++
+		case c == ':':
+			t.token(":")
+			t.pos++
+			if len(t.brackets) == 0 {
+				t.anyColon = true
+				t.lastColon = true
+			}
+		case strings.IndexByte("+-*/%@&|^~<>=!.,;", c) >= 0:
+			t.token(string(c))
+			t.pos++
+		default:
+			r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+			if r != '_' && !unicode.IsLetter(r) {
+				t.errorAt(t.line, t.column(), "invalid character '%c' (U+%04X)", r, r)
+				t.pos += size
+				continue
+			}
+			t.scanName()
+		}
+	}
+
+	t.endToken()
+	// A bracket left open leaves the last logical line unfinished
+	if len(t.brackets) == 0 {
+		t.endLogicalLine()
+	}
+	for _, open := range t.brackets {
+		t.errorAt(open.line, open.column, "'%c' was never closed", open.char)
+	}
+	if t.block != "" && len(t.brackets) == 0 {
+		t.errorAt(t.line, t.column(), "expected an indented block after %s on line %d", t.block, t.blockLine)
+	}
+}
+
+// indent reads the indentation of a line and opens or closes blocks. It
+// returns false for lines without tokens, which leave blocks as they are.
+func (t *pythonTokenizer) indent() bool {
+	column, altColumn := 0, 0
+measure:
+	for ; t.pos < len(t.src); t.pos++ {
+		switch t.src[t.pos] {
+		case ' ':
+			column++
+			altColumn++
+		case '\t':
+			column = (column/8 + 1) * 8
+			altColumn++
+		case '\f':
+			column, altColumn = 0, 0
+		default:
+			break measure
+		}
+	}
+	if t.pos >= len(t.src) || strings.IndexByte("#\r\n", t.src[t.pos]) >= 0 {
+		if t.pos < len(t.src) && t.src[t.pos] == '#' {
+			for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+		}
+		if t.pos < len(t.src) {
+			if t.src[t.pos] == '\r' {
+				t.pos++
+			}
+			if t.pos < len(t.src) {
+				t.newline()
+			}
+		}
+		return false
+	}
+
+	top := len(t.indents) - 1
+	if t.block != "" {
+		block := t.block
+		t.block = ""
+		if column > t.indents[top] {
+			if altColumn <= t.altIndents[top] {
+				t.errorAt(t.line, 1, "inconsistent use of tabs and spaces in indentation")
+			}
+			t.indents = append(t.indents, column)
+			t.altIndents = append(t.altIndents, altColumn)
+			return true
+		}
+		t.errorAt(t.line, column+1, "expected an indented block after %s on line %d", block, t.blockLine)
+	}
+
+	switch {
+	case column > t.indents[top]:
+		t.errorAt(t.line, column+1, "unexpected indent")
+		t.indents = append(t.indents, column)
+		t.altIndents = append(t.altIndents, altColumn)
+	case column < t.indents[top]:
+		for top > 0 && column < t.indents[top] {
+			top--
+		}
+		t.indents = t.indents[:top+1]
+		t.altIndents = t.altIndents[:top+1]
+		if column != t.indents[top] {
+			t.errorAt(t.line, column+1, "unindent does not match any outer indentation level")
+		} else if altColumn != t.altIndents[top] {
+			t.errorAt(t.line, 1, "inconsistent use of tabs and spaces in indentation")
+		}
+	default:
+		if altColumn != t.altIndents[top] {
+			t.errorAt(t.line, 1, "inconsistent use of tabs and spaces in indentation")
+		}
+	}
+	return true
+}
+
+// token records a token of the logical line; call it before consuming it
+func (t *pythonTokenizer) token(text string) {
+	if t.first == "" {
+		t.first = text
+		t.firstLine, t.firstCol = t.line, t.column()
+	} else if t.second == "" {
+		t.second = text
+	}
+	t.lastColon = false
+	t.inToken = true
+}
+
+// endToken notes where the last token read ended
+func (t *pythonTokenizer) endToken() {
+	if t.inToken {
+		t.lastLine, t.lastColumn = t.line, t.column()
+		t.inToken = false
+	}
+}
+
+// endLogicalLine checks the header of a compound statement
+func (t *pythonTokenizer) endLogicalLine() {
+	if t.first == "" {
+		return
+	}
+	if pythonBlockKeywords[t.first] && !t.anyColon {
+		t.errorAt(t.lastLine, t.lastColumn, "expected ':'")
+	}
+	// Python 2 print statements are common in older samples
+	if t.first == "print" && (t.second == "string" || t.second == "number" || isPythonName(t.second)) {
+		t.errorAt(t.firstLine, t.firstCol, "Missing parentheses in call to 'print'. Did you mean print(...)?")
+	}
+	if t.lastColon {
+		switch t.first {
+		case "def":
+			t.block = "function definition"
+		case "class":
+			t.block = "class definition"
+		default:
+			t.block = "'" + t.first + "' statement"
+		}
+		t.blockLine = t.firstLine
+	}
+	t.first, t.second = "", ""
+	t.anyColon = false
+	t.lastColon = false
+}
+
+func (t *pythonTokenizer) closeBracket(c byte) {
+	if len(t.brackets) == 0 {
+		t.errorAt(t.line, t.column(), "unmatched '%c'", c)
+		return
+	}
+	open := t.brackets[len(t.brackets)-1]
+	t.brackets = t.brackets[:len(t.brackets)-1]
+	if open.char != closingBrackets[c] {
+		t.errorAt(t.line, t.column(), "closing parenthesis '%c' does not match opening parenthesis '%c' on line %d", c, open.char, open.line)
+	}
+}
+
+// scanName reads an identifier or keyword, or the prefix of a string
+func (t *pythonTokenizer) scanName() {
+	start := t.pos
+	for t.pos < len(t.src) {
+		r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		t.pos += size
+	}
+	name := t.src[start:t.pos]
+	if t.pos < len(t.src) && (t.src[t.pos] == '"' || t.src[t.pos] == '\'') && isPythonStringPrefix(name) {
+		t.pos = start
+		t.token("string")
+		t.pos += len(name)
+		t.scanString(strings.ToLower(name))
+		return
+	}
+	t.pos = start
+	t.token(name)
+	t.pos += len(name)
+}
+
+func isPythonName(token string) bool {
+	r, _ := utf8.DecodeRuneInString(token)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isPythonStringPrefix(name string) bool {
+	switch strings.ToLower(name) {
+	case "r", "u", "b", "f", "t", "br", "rb", "fr", "rf", "tr", "rt":
+		return true
+	}
+	return false
+}
+
+// scanNumber reads a number literal
+func (t *pythonTokenizer) scanNumber() {
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '.':
+			t.pos++
+		case (c == '+' || c == '-') && (t.src[t.pos-1] == 'e' || t.src[t.pos-1] == 'E') && !strings.HasPrefix(strings.ToLower(t.src[:t.pos]), "0x"):
+			t.pos++
+		default:
+			return
+		}
+	}
+}
+
+// scanString reads a string literal starting at its quote
+func (t *pythonTokenizer) scanString(prefix string) {
+	line, column := t.line, t.column()-len(prefix)
+	quote := t.src[t.pos]
+	triple := strings.HasPrefix(t.src[t.pos:], strings.Repeat(string(quote), 3))
+	if triple {
+		t.pos += 3
+	} else {
+		t.pos++
+	}
+	formatted := strings.ContainsAny(prefix, "ft")
+
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c == '\\':
+			t.pos++
+			if t.pos < len(t.src) && t.src[t.pos] == '\n' {
+				t.newline()
+			} else {
+				t.pos++
+			}
+		case c == '\n':
+			if !triple {
+				t.errorAt(line, column, "unterminated string literal (detected at line %d)", t.line)
+				return
+			}
+			t.newline()
+		case c == quote:
+			if !triple {
+				t.pos++
+				return
+			}
+			if strings.HasPrefix(t.src[t.pos:], strings.Repeat(string(quote), 3)) {
+				t.pos += 3
+				return
+			}
+			t.pos++
+		case formatted && c == '{':
+			if strings.HasPrefix(t.src[t.pos:], "{{") {
+				t.pos += 2
+				continue
+			}
+			t.scanReplacementField()
+		case formatted && c == '}':
+			if strings.HasPrefix(t.src[t.pos:], "}}") {
+				t.pos += 2
+				continue
+			}
+			t.errorAt(t.line, t.column(), "f-string: single '}' is not allowed")
+			t.pos++
+		default:
+			t.pos++
+		}
+	}
+	if triple {
+		t.errorAt(line, column, "unterminated triple-quoted string literal (detected at line %d)", t.line)
+	} else {
+		t.errorAt(line, column, "unterminated string literal (detected at line %d)", t.line)
+	}
+}
+
+// scanReplacementField reads an f-string's {expression}, which may hold
+// brackets and strings of its own
+func (t *pythonTokenizer) scanReplacementField() {
+	line, column := t.line, t.column()
+	depth := 0
+	t.pos++
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c == '"' || c == '\'':
+			t.scanString("")
+			continue
+		case c == '\n':
+			t.newline()
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '}':
+			if depth == 0 {
+				t.pos++
+				return
+			}
+			depth--
+		default:
+			r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+			if unicode.IsLetter(r) || r == '_' {
+				start := t.pos
+				t.pos += size
+				for t.pos < len(t.src) {
+					r, size := utf8.DecodeRuneInString(t.src[t.pos:])
+					if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+						break
+					}
+					t.pos += size
+				}
+				name := t.src[start:t.pos]
+				if t.pos < len(t.src) && (t.src[t.pos] == '"' || t.src[t.pos] == '\'') && isPythonStringPrefix(name) {
+					t.scanString(strings.ToLower(name))
+				}
+				continue
+			}
+		}
+		t.pos++
+	}
+	t.errorAt(line, column, "f-string: expecting '}'")
+}
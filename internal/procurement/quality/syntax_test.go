@@ -0,0 +1,161 @@
+package quality
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+)
+
+// firstSyntaxError returns the first syntax error found, failing the test
+// if there is none
+func firstSyntaxError(t *testing.T, diags diagnostics) procurement.CodeDiagnostic {
+	t.Helper()
+	diagnostic, found := diags.firstError(diagnosticSyntax)
+	require.True(t, found, "expected a syntax error, got %v", diags)
+	return diagnostic
+}
+
+func TestCheckGoSyntax(t *testing.T) {
+	checker := newGoChecker()
+
+	result := checker.check("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n")
+	assert.Empty(t, result.Diagnostics)
+
+	result = checker.check("type point struct{ x, y int }\n\nfunc (p point) sum() int { return p.x + p.y }\n")
+	assert.Empty(t, result.Diagnostics, "declarations are checked as a package")
+
+	result = checker.check("name := \"world\"\nfmt.Println(strings.ToUpper(name))")
+	assert.Empty(t, result.Diagnostics, "statements are checked as main's body with standard imports")
+	assert.Contains(t, result.Source, `import ("fmt"; "strings")`)
+	assert.Contains(t, result.Source, "func main() {\nname := \"world\"")
+
+	result = checker.check("package main\n\nfunc main() {\n\tprintln(\"hi\"\n}\n")
+	diagnostic := firstSyntaxError(t, result.Diagnostics)
+	assert.Equal(t, 4, diagnostic.Line)
+	assert.Equal(t, 14, diagnostic.Column)
+
+	result = checker.check("x := 1\ny := x + )\n")
+	diagnostic = firstSyntaxError(t, result.Diagnostics)
+	assert.Equal(t, 2, diagnostic.Line, "positions are mapped back from the wrapping")
+	assert.Equal(t, 10, diagnostic.Column)
+
+	result = checker.check("var n int = \"text\"\n")
+	_, found := result.Diagnostics.firstError(diagnosticSyntax)
+	assert.False(t, found)
+	diagnostic, found = result.Diagnostics.firstError(diagnosticTypes)
+	require.True(t, found)
+	assert.Equal(t, 1, diagnostic.Line)
+	assert.Equal(t, 13, diagnostic.Column)
+
+	result = checker.check("package main\n\nimport \"github.com/example/missing\"\n\nfunc main() { missing.Run() }\n")
+	require.NotEmpty(t, result.Diagnostics)
+	assert.Equal(t, procurement.DiagnosticWarning, result.Diagnostics[0].Severity, "packages outside the standard library are not checked")
+
+	// Only the cached standard library packages stay in the shared file set
+	base := checker.importer.fset.Base()
+	for i := 0; i < 10; i++ {
+		result = checker.check("fmt.Println(strings.Repeat(\"x\", 3))")
+		assert.Empty(t, result.Diagnostics)
+	}
+	assert.Equal(t, base, checker.importer.fset.Base())
+}
+
+func TestCheckPythonSyntax(t *testing.T) {
+	valid := `import re
+
+class Greeter:
+    """Greets people."""
+
+    def greet(self, name: str) -> str:
+        if not name:
+            raise ValueError('empty')
+        words = [w.title() for w in re.split(r"\s+", name)
+                 if w]
+        return f"Hello, {' '.join(words)}!"  # comment
+`
+	assert.Empty(t, checkPythonSyntax(valid))
+
+	diagnostic := firstSyntaxError(t, checkPythonSyntax("def greet(name)\n    return name\n"))
+	assert.Equal(t, 1, diagnostic.Line)
+	assert.Contains(t, diagnostic.Message, "expected ':'")
+
+	diagnostic = firstSyntaxError(t, checkPythonSyntax("if True:\nprint('x')\n"))
+	assert.Equal(t, 2, diagnostic.Line)
+	assert.Contains(t, diagnostic.Message, "expected an indented block")
+
+	diagnostic = firstSyntaxError(t, checkPythonSyntax("def f():\n    x = 1\n      y = 2\n"))
+	assert.Equal(t, 3, diagnostic.Line)
+	assert.Contains(t, diagnostic.Message, "unexpected indent")
+
+	diagnostic = firstSyntaxError(t, checkPythonSyntax("x = 1\ns = 'open\n"))
+	assert.Equal(t, 2, diagnostic.Line)
+	assert.Equal(t, 5, diagnostic.Column)
+
+	diagnostic = firstSyntaxError(t, checkPythonSyntax("values = [1, 2\n"))
+	assert.Equal(t, 1, diagnostic.Line)
+	assert.Equal(t, 10, diagnostic.Column)
+	assert.Contains(t, diagnostic.Message, "was never closed")
+
+	diagnostic = firstSyntaxError(t, checkPythonSyntax("print \"hello\"\n"))
+	assert.Contains(t, diagnostic.Message, "print")
+}
+
+func TestCheckCLikeSyntax(t *testing.T) {
+	validJS := "#!/usr/bin/env node\n" +
+		"const re = /[}/]+/g; // a regexp\n" +
+		"const half = total / 2 / count;\n" +
+		"class Counter { #count = 0; inc() { return `n=${this.#count + {a: 1}.a}`; } }\n" +
+		"/* block\n   comment */\n"
+	assert.Empty(t, checkCLikeSyntax(validJS, javaScriptDialect))
+
+	diagnostic := firstSyntaxError(t, checkCLikeSyntax("function f() {\n  return [1, 2);\n}\n", javaScriptDialect))
+	assert.Equal(t, 2, diagnostic.Line)
+	assert.Equal(t, 15, diagnostic.Column)
+
+	diagnostic = firstSyntaxError(t, checkCLikeSyntax("const s = `open ${name}\n", javaScriptDialect))
+	assert.Equal(t, 1, diagnostic.Line)
+	assert.Equal(t, 11, diagnostic.Column)
+	assert.Contains(t, diagnostic.Message, "template literal")
+
+	validJava := "public class Main {\n" +
+		"    @Override\n" +
+		"    public String toString() {\n" +
+		"        char c = '}';\n" +
+		"        return \"\"\"\n            text { block\n            \"\"\" + c;\n" +
+		"    }\n" +
+		"}\n"
+	assert.Empty(t, checkCLikeSyntax(validJava, javaDialect))
+
+	diagnostic = firstSyntaxError(t, checkCLikeSyntax("class A {\n    void f() {\n}\n", javaDialect))
+	assert.Equal(t, 1, diagnostic.Line)
+	assert.Equal(t, 9, diagnostic.Column)
+
+	diagnostic = firstSyntaxError(t, checkCLikeSyntax("class A { String s = \"open; }\n", javaDialect))
+	assert.Contains(t, diagnostic.Message, "unterminated string literal")
+}
+
+func TestValidateCodeReportsDiagnostics(t *testing.T) {
+	config := DefaultCodeValidationConfig()
+	config.EnableCompilation = false
+	config.EnableExecution = false
+	config.TempDirectory = t.TempDir()
+	cv := NewCodeValidator(config)
+
+	result, err := cv.ValidateCode(t.Context(), "x := []int{1, 2}\nfmt.Println(len(x))\n", "go")
+	require.NoError(t, err)
+	assert.True(t, result.SyntaxValid)
+	assert.Empty(t, result.Diagnostics)
+
+	result, err = cv.ValidateCode(t.Context(), "def f(:\n    pass\n", "python")
+	require.NoError(t, err)
+	assert.False(t, result.SyntaxValid)
+	require.NotEmpty(t, result.Diagnostics)
+	assert.Contains(t, result.Errors[0], "Syntax validation failed: line 1:6:")
+
+	result, err = cv.ValidateCode(t.Context(), "  \n", "javascript")
+	require.NoError(t, err)
+	assert.False(t, result.SyntaxValid)
+}
@@ -63,7 +63,7 @@ func NewQualityValidator(config *ValidationConfig) *QualityValidator {
 	
 	return &QualityValidator{
 		factChecker:         NewFactChecker(),
-		codeValidator:       NewCodeValidator(),
+		codeValidator:       NewCodeValidator(nil),
 		mathValidator:       NewMathValidator(),
 		readabilityAnalyzer: NewReadabilityAnalyzer(),
 		config:             config,
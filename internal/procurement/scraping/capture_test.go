@@ -0,0 +1,52 @@
+package scraping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/pkg/warc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractorWARCCapture(t *testing.T) {
+	page := "<html><head><title>Archived</title></head><body><p>A page worth keeping.</p></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	capture, err := warc.OpenFileWriter(dir, "crawl", 0, nil)
+	require.NoError(t, err)
+
+	extractor := NewContentExtractor(nil)
+	extractor.SetCapture(capture)
+	result, err := extractor.ExtractContent(context.Background(), server.URL+"/page")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.NoError(t, capture.Close())
+
+	indexes, err := filepath.Glob(filepath.Join(dir, "crawl-*.cdxj"))
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	index, err := warc.LoadCDXIndex(indexes[0])
+	require.NoError(t, err)
+	entries := index.Lookup(server.URL + "/page")
+	require.Len(t, entries, 1)
+
+	file, err := os.Open(entries[0].Location(dir))
+	require.NoError(t, err)
+	defer file.Close()
+	record, err := warc.ReadRecordAt(file, entries[0].Offset, entries[0].Length)
+	require.NoError(t, err)
+	payload, err := warc.ReadPayload(record, 0)
+	require.NoError(t, err)
+	assert.Equal(t, page, string(payload.Body))
+	assert.Equal(t, http.StatusOK, payload.StatusCode)
+}
@@ -3,12 +3,14 @@ package scraping
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,8 +18,7 @@ import (
 type ComplianceEngine struct {
 	robotsCache   map[string]*RobotsData
 	robotsMu      sync.RWMutex
-	tosCache      map[string]*ToSData
-	tosMu         sync.RWMutex
+	policies      *policy.Registry
 	client        *http.Client
 	config        *ComplianceConfig
 }
@@ -30,6 +31,7 @@ type ComplianceConfig struct {
 	UserAgent            string        `json:"user_agent"`
 	MaxConcurrentChecks  int           `json:"max_concurrent_checks"`
 	RequestDelay         time.Duration `json:"request_delay"`
+	PolicyFile           string        `json:"policy_file"` // Domain policies, see package policy
 	EnableWhitelist      bool          `json:"enable_whitelist"`
 	WhitelistedDomains   []string      `json:"whitelisted_domains"`
 	BlacklistedDomains   []string      `json:"blacklisted_domains"`
@@ -43,6 +45,17 @@ type RobotsData struct {
 	CrawlDelay  time.Duration     `json:"crawl_delay"`
 	LastFetched time.Time         `json:"last_fetched"`
 	Valid       bool              `json:"valid"`
+	
+	// Access is how the file could be read, one of the RobotsAccess
+	// constants, and StatusCode the status of the last fetch (0 when the
+	// site could not be reached)
+	Access      string            `json:"access"`
+	StatusCode  int               `json:"status_code"`
+	// LastChecked is when the file was last fetched, successfully or not;
+	// Stale marks rules kept from an earlier fetch while the site is
+	// unreachable
+	LastChecked time.Time         `json:"last_checked"`
+	Stale       bool              `json:"stale"`
 }
 
 // Agent represents robots.txt rules for a specific user agent
@@ -54,17 +67,6 @@ type Agent struct {
 	RequestRate  string        `json:"request_rate"`
 }
 
-// ToSData represents Terms of Service analysis
-type ToSData struct {
-	URL               string            `json:"url"`
-	AllowsAutomation  bool              `json:"allows_automation"`
-	RequiresAttribution bool            `json:"requires_attribution"`
-	CommercialUse     bool              `json:"commercial_use"`
-	RateLimits        map[string]string `json:"rate_limits"`
-	LastAnalyzed      time.Time         `json:"last_analyzed"`
-	Confidence        float64           `json:"confidence"`
-}
-
 // ComplianceResult represents the result of a compliance check
 type ComplianceResult struct {
 	URL                string        `json:"url"`
@@ -73,6 +75,8 @@ type ComplianceResult struct {
 	RobotsCompliant    bool          `json:"robots_compliant"`
 	ToSCompliant       bool          `json:"tos_compliant"`
 	RequiredDelay      time.Duration `json:"required_delay"`
+	CrawlDelay         time.Duration `json:"crawl_delay"` // Delay robots.txt asks for, 0 if none
+	Policy             *policy.Policy `json:"policy,omitempty"` // Domain policy the check was made under
 	AttributionNeeded  bool          `json:"attribution_needed"`
 	Restrictions       []string      `json:"restrictions"`
 	Recommendations    []string      `json:"recommendations"`
@@ -85,16 +89,42 @@ func NewComplianceEngine(config *ComplianceConfig) *ComplianceEngine {
 		config = DefaultComplianceConfig()
 	}
 	
+	// Without a readable policy file no domain's terms are known to allow
+	// automation
+	var policies *policy.Registry
+	if config.PolicyFile != "" {
+		var err error
+		policies, err = policy.Load(config.PolicyFile)
+		if err != nil {
+			log.Warn().Err(err).Str("policy_file", config.PolicyFile).Msg("Failed to load domain policies, no domain allows automation")
+		}
+	}
+	if policies == nil {
+		policies, _ = policy.NewRegistry(nil)
+	}
+	
 	return &ComplianceEngine{
 		robotsCache: make(map[string]*RobotsData),
-		tosCache:    make(map[string]*ToSData),
+		policies:    policies,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:       30 * time.Second,
+			CheckRedirect: stopRobotsRedirects,
 		},
 		config: config,
 	}
 }
 
+// SetPolicies replaces the engine's domain policies, to share one registry
+// between components
+func (ce *ComplianceEngine) SetPolicies(policies *policy.Registry) {
+	ce.policies = policies
+}
+
+// Policies returns the domain policies the engine checks against
+func (ce *ComplianceEngine) Policies() *policy.Registry {
+	return ce.policies
+}
+
 // DefaultComplianceConfig returns default compliance configuration
 func DefaultComplianceConfig() *ComplianceConfig {
 	return &ComplianceConfig{
@@ -104,6 +134,7 @@ func DefaultComplianceConfig() *ComplianceConfig {
 		UserAgent:           "CAIA-Library/1.0 (+https://caia.tech/bot)",
 		MaxConcurrentChecks: 5,
 		RequestDelay:        1 * time.Second,
+		PolicyFile:          "configs/domain_policies.yaml",
 		EnableWhitelist:     false,
 		WhitelistedDomains: []string{
 			"arxiv.org",
@@ -168,31 +199,36 @@ func (ce *ComplianceEngine) CheckCompliance(ctx context.Context, targetURL strin
 	}
 	
 	result.RobotsCompliant = robotsCompliant
+	result.CrawlDelay = robotsDelay
 	result.RequiredDelay = robotsDelay
+	if ce.config.RespectRobotsTxt && result.RequiredDelay < ce.config.RequestDelay {
+		result.RequiredDelay = ce.config.RequestDelay
+	}
 	
 	if !robotsCompliant {
 		result.Restrictions = append(result.Restrictions, "Blocked by robots.txt")
 	}
 	
-	// Check Terms of Service compliance (if enabled)
+	// Look up the domain's policy; its terms are enforced if enabled, and
+	// its rate cap always
+	domainPolicy := ce.policies.Lookup(domain)
+	result.Policy = &domainPolicy
+	result.AttributionNeeded = domainPolicy.RequiresAttribution()
+	if interval := domainPolicy.MinInterval(); interval > result.RequiredDelay {
+		result.RequiredDelay = interval
+	}
+	
 	tosCompliant := true
 	if ce.config.CheckTermsOfService {
-		tosData, err := ce.checkToSCompliance(ctx, domain)
-		if err != nil {
-			log.Warn().Err(err).Str("domain", domain).Msg("Failed to check ToS compliance")
-		} else {
-			tosCompliant = tosData.AllowsAutomation
-			result.AttributionNeeded = tosData.RequiresAttribution
-			
-			if !tosCompliant {
-				result.Restrictions = append(result.Restrictions, "Terms of Service prohibit automation")
-			}
-			
-			if tosData.RequiresAttribution {
-				result.Recommendations = append(result.Recommendations, "Attribution required")
-			}
+		tosCompliant = domainPolicy.AutomationAllowed
+		if !tosCompliant {
+			result.Restrictions = append(result.Restrictions, "Terms of Service prohibit automation")
 		}
 	}
+	if domainPolicy.RequiresAttribution() {
+		result.Recommendations = append(result.Recommendations,
+			fmt.Sprintf("Attribution required: %s", domainPolicy.AttributionText))
+	}
 	
 	result.ToSCompliant = tosCompliant
 	result.Allowed = robotsCompliant && tosCompliant
@@ -218,7 +254,8 @@ func (ce *ComplianceEngine) CheckCompliance(ctx context.Context, targetURL strin
 	return result, nil
 }
 
-// checkRobotsCompliance checks if scraping a URL is allowed by robots.txt
+// checkRobotsCompliance checks if scraping a URL is allowed by robots.txt,
+// returning the delay between requests the site asks of this crawler, or 0
 func (ce *ComplianceEngine) checkRobotsCompliance(ctx context.Context, targetURL string) (bool, time.Duration, error) {
 	if !ce.config.RespectRobotsTxt {
 		return true, 0, nil
@@ -229,268 +266,154 @@ func (ce *ComplianceEngine) checkRobotsCompliance(ctx context.Context, targetURL
 		return false, 0, err
 	}
 	
-	baseURL := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-	robotsURL := fmt.Sprintf("%s/robots.txt", baseURL)
-	
-	// Check cache first
-	ce.robotsMu.RLock()
-	robotsData, exists := ce.robotsCache[baseURL]
-	ce.robotsMu.RUnlock()
-	
-	if exists && time.Since(robotsData.LastFetched) < ce.config.CacheTimeout {
-		return ce.isPathAllowed(robotsData, parsedURL.Path), robotsData.CrawlDelay, nil
-	}
-	
-	// Fetch robots.txt
-	robotsData, err = ce.fetchRobotsTxt(ctx, robotsURL)
+	// Fetch robots.txt, or use the cached copy
+	robotsData, err := ce.robots(ctx, fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host))
 	if err != nil {
-		// If robots.txt doesn't exist or is inaccessible, assume allowed
-		log.Debug().Err(err).Str("robots_url", robotsURL).Msg("Could not fetch robots.txt, assuming allowed")
-		return true, ce.config.RequestDelay, nil
+		return false, 0, err
 	}
 	
-	// Cache the result
-	ce.robotsMu.Lock()
-	ce.robotsCache[baseURL] = robotsData
-	ce.robotsMu.Unlock()
+	// Rules match the path as requested, query included
+	path := parsedURL.EscapedPath()
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+	token := productToken(ce.config.UserAgent)
+	allowed := robotsData.Allowed(token, path)
 	
-	allowed := ce.isPathAllowed(robotsData, parsedURL.Path)
-	delay := robotsData.CrawlDelay
-	if delay == 0 {
-		delay = ce.config.RequestDelay
+	var delay time.Duration
+	if agent := robotsData.Group(token); agent != nil && robotsData.Access == RobotsAccessParsed {
+		delay = agent.Delay()
 	}
 	
 	return allowed, delay, nil
 }
 
-// fetchRobotsTxt fetches and parses robots.txt
-func (ce *ComplianceEngine) fetchRobotsTxt(ctx context.Context, robotsURL string) (*RobotsData, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
-	if err != nil {
-		return nil, err
-	}
+// robots returns the robots.txt of a site, given as scheme://host, from
+// the cache or by fetching it. A site that has become unreachable keeps
+// the rules last read from it for up to maxRobotsStaleness.
+func (ce *ComplianceEngine) robots(ctx context.Context, baseURL string) (*RobotsData, error) {
+	ce.robotsMu.RLock()
+	cached, exists := ce.robotsCache[baseURL]
+	ce.robotsMu.RUnlock()
 	
-	req.Header.Set("User-Agent", ce.config.UserAgent)
+	if exists && !ce.robotsExpired(cached) {
+		return cached, nil
+	}
 	
-	resp, err := ce.client.Do(req)
+	robotsData, err := ce.fetchRobotsTxt(ctx, baseURL+"/robots.txt")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("robots.txt returned status %d", resp.StatusCode)
+	if robotsData.Access == RobotsAccessUnreachable && exists && cached.Access == RobotsAccessParsed &&
+		time.Since(cached.LastFetched) < maxRobotsStaleness {
+		stale := *cached
+		stale.StatusCode = robotsData.StatusCode
+		stale.LastChecked = robotsData.LastChecked
+		stale.Stale = true
+		robotsData = &stale
 	}
 	
-	// Parse robots.txt (simplified implementation)
-	robotsData := &RobotsData{
-		URL:         robotsURL,
-		UserAgents:  make(map[string]*Agent),
-		Sitemaps:    make([]string, 0),
-		LastFetched: time.Now(),
-		Valid:       true,
-	}
-	
-	// Read and parse content (simplified parser)
-	buf := make([]byte, 64*1024) // 64KB limit for robots.txt
-	n, _ := resp.Body.Read(buf)
-	content := string(buf[:n])
-	
-	ce.parseRobotsTxt(robotsData, content)
+	// Cache the result
+	ce.robotsMu.Lock()
+	ce.robotsCache[baseURL] = robotsData
+	ce.robotsMu.Unlock()
 	
 	return robotsData, nil
 }
 
-// parseRobotsTxt parses robots.txt content (simplified implementation)
-func (ce *ComplianceEngine) parseRobotsTxt(robotsData *RobotsData, content string) {
-	lines := strings.Split(content, "\n")
-	var currentAgent *Agent
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		
-		field := strings.ToLower(strings.TrimSpace(parts[0]))
-		value := strings.TrimSpace(parts[1])
-		
-		switch field {
-		case "user-agent":
-			currentAgent = &Agent{
-				Name:     value,
-				Allow:    make([]string, 0),
-				Disallow: make([]string, 0),
-			}
-			robotsData.UserAgents[value] = currentAgent
-			
-		case "disallow":
-			if currentAgent != nil {
-				currentAgent.Disallow = append(currentAgent.Disallow, value)
-			}
-			
-		case "allow":
-			if currentAgent != nil {
-				currentAgent.Allow = append(currentAgent.Allow, value)
-			}
-			
-		case "crawl-delay":
-			if currentAgent != nil {
-				if delay, err := time.ParseDuration(value + "s"); err == nil {
-					currentAgent.CrawlDelay = delay
-					if robotsData.CrawlDelay == 0 || delay > robotsData.CrawlDelay {
-						robotsData.CrawlDelay = delay
-					}
-				}
-			}
-			
-		case "sitemap":
-			robotsData.Sitemaps = append(robotsData.Sitemaps, value)
-			
-		case "request-rate":
-			if currentAgent != nil {
-				currentAgent.RequestRate = value
-			}
-		}
-	}
-}
-
-// isPathAllowed checks if a path is allowed by robots.txt
-func (ce *ComplianceEngine) isPathAllowed(robotsData *RobotsData, path string) bool {
-	userAgent := ce.config.UserAgent
-	
-	// Check specific user agent first
-	if agent, exists := robotsData.UserAgents[userAgent]; exists {
-		return ce.checkAgentRules(agent, path)
-	}
-	
-	// Check wildcard user agent
-	if agent, exists := robotsData.UserAgents["*"]; exists {
-		return ce.checkAgentRules(agent, path)
-	}
-	
-	// If no rules found, assume allowed
-	return true
+// robotsExpired reports whether a cached robots.txt is due to be fetched
+// again. Unreachable sites are retried sooner than the cache timeout.
+func (ce *ComplianceEngine) robotsExpired(robotsData *RobotsData) bool {
+	timeout := ce.config.CacheTimeout
+	if (robotsData.Access == RobotsAccessUnreachable || robotsData.Stale) && timeout > robotsRetryInterval {
+		timeout = robotsRetryInterval
+	}
+	return time.Since(robotsData.LastChecked) >= timeout
 }
 
-// checkAgentRules checks agent-specific allow/disallow rules
-func (ce *ComplianceEngine) checkAgentRules(agent *Agent, path string) bool {
-	// Check explicit allow rules first
-	for _, allowRule := range agent.Allow {
-		if ce.matchesPattern(allowRule, path) {
-			return true
-		}
+// Sitemaps returns the sitemap URLs a site lists in its robots.txt. A site
+// without a readable robots.txt has none.
+func (ce *ComplianceEngine) Sitemaps(ctx context.Context, siteURL string) ([]string, error) {
+	parsedURL, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Check disallow rules
-	for _, disallowRule := range agent.Disallow {
-		if ce.matchesPattern(disallowRule, path) {
-			return false
-		}
+	robotsData, err := ce.robots(ctx, fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host))
+	if err != nil {
+		return nil, err
 	}
-	
-	// Default to allowed
-	return true
+	return append([]string(nil), robotsData.Sitemaps...), nil
 }
 
-// matchesPattern checks if a path matches a robots.txt pattern
-func (ce *ComplianceEngine) matchesPattern(pattern, path string) bool {
-	if pattern == "" {
-		return false
-	}
-	
-	// Exact match
-	if pattern == path {
-		return true
+// fetchRobotsTxt fetches and parses robots.txt. Responses the file cannot
+// be read from are not errors: they are recorded as an unavailable or
+// unreachable robots.txt, which allow everything or nothing.
+func (ce *ComplianceEngine) fetchRobotsTxt(ctx context.Context, robotsURL string) (*RobotsData, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Prefix match
-	if strings.HasSuffix(pattern, "*") {
-		prefix := strings.TrimSuffix(pattern, "*")
-		return strings.HasPrefix(path, prefix)
-	}
+	req.Header.Set("User-Agent", ce.config.UserAgent)
 	
-	// Simple prefix match for paths ending with /
-	if strings.HasSuffix(pattern, "/") {
-		return strings.HasPrefix(path, pattern)
+	resp, err := ce.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Debug().Err(err).Str("robots_url", robotsURL).Msg("robots.txt unreachable, disallowing crawling")
+		return &RobotsData{
+			URL:         robotsURL,
+			UserAgents:  make(map[string]*Agent),
+			Access:      RobotsAccessUnreachable,
+			LastChecked: time.Now(),
+		}, nil
 	}
+	defer resp.Body.Close()
 	
-	return strings.HasPrefix(path, pattern)
-}
-
-// checkToSCompliance checks Terms of Service compliance (simplified implementation)
-func (ce *ComplianceEngine) checkToSCompliance(ctx context.Context, domain string) (*ToSData, error) {
-	// Check cache first
-	ce.tosMu.RLock()
-	tosData, exists := ce.tosCache[domain]
-	ce.tosMu.RUnlock()
-	
-	if exists && time.Since(tosData.LastAnalyzed) < ce.config.CacheTimeout {
-		return tosData, nil
+	access := robotsStatusAccess(resp.StatusCode)
+	if access != RobotsAccessParsed {
+		log.Debug().Int("status", resp.StatusCode).Str("robots_url", robotsURL).Str("access", access).Msg("robots.txt not readable")
+		return &RobotsData{
+			URL:         robotsURL,
+			UserAgents:  make(map[string]*Agent),
+			Access:      access,
+			StatusCode:  resp.StatusCode,
+			LastChecked: time.Now(),
+		}, nil
 	}
 	
-	// For now, implement a simple heuristic-based approach
-	// In production, this would integrate with ToS analysis services
-	tosData = &ToSData{
-		URL:              fmt.Sprintf("https://%s/terms", domain),
-		AllowsAutomation: ce.getDomainPolicy(domain),
-		RequiresAttribution: ce.requiresAttribution(domain),
-		CommercialUse:    false, // Conservative default
-		LastAnalyzed:     time.Now(),
-		Confidence:       0.7, // Medium confidence for heuristic
+	// Only the first maxRobotsBytes are parsed
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt: %w", err)
 	}
 	
-	// Cache the result
-	ce.tosMu.Lock()
-	ce.tosCache[domain] = tosData
-	ce.tosMu.Unlock()
+	// Relative sitemaps resolve against where the file was found
+	robotsData := ParseRobotsTxt(resp.Request.URL.String(), content)
+	robotsData.StatusCode = resp.StatusCode
 	
-	return tosData, nil
+	return robotsData, nil
 }
 
-// getDomainPolicy returns automation policy for known domains
-func (ce *ComplianceEngine) getDomainPolicy(domain string) bool {
-	// Known automation-friendly domains
-	friendlyDomains := map[string]bool{
-		"arxiv.org":        true,
-		"github.com":       true,
-		"stackoverflow.com": true,
-		"docs.python.org":  true,
-		"golang.org":       true,
-		"wikipedia.org":    true,
-		"archive.org":      true,
-	}
-	
-	// Check exact match
-	if policy, exists := friendlyDomains[domain]; exists {
-		return policy
-	}
-	
-	// Check subdomain patterns
-	for friendlyDomain := range friendlyDomains {
-		if strings.HasSuffix(domain, "."+friendlyDomain) {
-			return friendlyDomains[friendlyDomain]
+// addSitemap records a sitemap URL from robots.txt, resolving it against
+// the robots.txt URL and skipping duplicates
+func addSitemap(robotsData *RobotsData, value string) {
+	sitemapURL := value
+	if base, err := url.Parse(robotsData.URL); err == nil && robotsData.URL != "" {
+		if resolved, err := base.Parse(value); err == nil {
+			sitemapURL = resolved.String()
 		}
 	}
 	
-	// Conservative default for unknown domains
-	return false
-}
-
-// requiresAttribution checks if domain requires attribution
-func (ce *ComplianceEngine) requiresAttribution(domain string) bool {
-	attributionRequired := map[string]bool{
-		"arxiv.org":     true,
-		"wikipedia.org": true,
-		"archive.org":   true,
+	for _, existing := range robotsData.Sitemaps {
+		if existing == sitemapURL {
+			return
+		}
 	}
-	
-	return attributionRequired[domain]
+	robotsData.Sitemaps = append(robotsData.Sitemaps, sitemapURL)
 }
 
 // isDomainWhitelisted checks if domain is in whitelist
@@ -520,35 +443,24 @@ func (ce *ComplianceEngine) GetCachedRobotsData(domain string) *RobotsData {
 	return ce.robotsCache[domain]
 }
 
-// ClearExpiredCache removes expired entries from all caches
-func (ce *ComplianceEngine) ClearExpiredCache() (int, int) {
+// ClearExpiredCache removes expired robots.txt entries from the cache
+func (ce *ComplianceEngine) ClearExpiredCache() int {
 	robotsCleared := 0
-	tosCleared := 0
 	
 	ce.robotsMu.Lock()
 	for domain, data := range ce.robotsCache {
-		if time.Since(data.LastFetched) > ce.config.CacheTimeout {
+		if ce.robotsExpired(data) {
 			delete(ce.robotsCache, domain)
 			robotsCleared++
 		}
 	}
 	ce.robotsMu.Unlock()
 	
-	ce.tosMu.Lock()
-	for domain, data := range ce.tosCache {
-		if time.Since(data.LastAnalyzed) > ce.config.CacheTimeout {
-			delete(ce.tosCache, domain)
-			tosCleared++
-		}
-	}
-	ce.tosMu.Unlock()
-	
-	if robotsCleared > 0 || tosCleared > 0 {
+	if robotsCleared > 0 {
 		log.Info().
 			Int("robots_cleared", robotsCleared).
-			Int("tos_cleared", tosCleared).
 			Msg("Expired compliance cache entries cleared")
 	}
 	
-	return robotsCleared, tosCleared
+	return robotsCleared
 }
\ No newline at end of file
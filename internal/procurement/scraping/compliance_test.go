@@ -0,0 +1,57 @@
+package scraping
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplianceDomainPolicies(t *testing.T) {
+	siteURL := newRobotsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	ctx := context.Background()
+
+	config := DefaultComplianceConfig()
+	config.PolicyFile = ""
+	ce := NewComplianceEngine(config)
+
+	// Without a policy file no domain allows automation
+	result, err := ce.CheckCompliance(ctx, siteURL+"/page")
+	require.NoError(t, err)
+	assert.True(t, result.RobotsCompliant)
+	assert.False(t, result.ToSCompliant)
+	assert.False(t, result.Allowed)
+	require.NotNil(t, result.Policy)
+	assert.Equal(t, policy.Unversioned, result.Policy.Version)
+
+	registry, err := policy.NewRegistry(&policy.File{
+		Version: "7",
+		Domains: []policy.Policy{{
+			Domain:            "127.0.0.1",
+			AutomationAllowed: true,
+			AttributionText:   "Content from the test server",
+			License:           "CC0",
+			MaxRate:           0.25,
+		}},
+	})
+	require.NoError(t, err)
+	ce.SetPolicies(registry)
+
+	result, err = ce.CheckCompliance(ctx, siteURL+"/page")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.AttributionNeeded)
+	assert.Equal(t, 4*time.Second, result.RequiredDelay, "the policy's max rate spaces requests")
+	assert.Contains(t, result.Recommendations, "Attribution required: Content from the test server")
+
+	metadata := map[string]string{}
+	result.Policy.Stamp(metadata)
+	assert.Equal(t, "7", metadata[policy.MetadataVersion])
+	assert.Equal(t, "CC0", metadata[policy.MetadataLicense])
+}
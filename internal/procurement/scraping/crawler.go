@@ -2,14 +2,19 @@ package scraping
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Caia-Tech/caia-library/internal/procurement"
 	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
@@ -23,11 +28,22 @@ type DistributedCrawler struct {
 	config           *CrawlerConfig
 	
 	// State management
-	activeJobs    map[string]*CrawlJob
-	jobsMu        sync.RWMutex
+	frontier      *Frontier
 	workers       []*CrawlWorker
 	jobQueue      chan *CrawlJob
 	resultQueue   chan *CrawlResult
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	
+	// Link following, per source
+	scopes        map[string]*CrawlScope
+	scopesMu      sync.RWMutex
+	
+	// Validators of fetched pages, for conditional re-crawls
+	validators    *conditional.Store
+	
+	// WARC capture of fetched pages, when configured
+	capture       *warc.FileWriter
 	
 	// Metrics
 	metrics   *CrawlMetrics
@@ -48,6 +64,21 @@ type CrawlerConfig struct {
 	MaxPagesPerDomain    int           `json:"max_pages_per_domain"`
 	CrawlDepth           int           `json:"crawl_depth"`
 	RespectSitemaps      bool          `json:"respect_sitemaps"`
+
+	// FrontierDir persists the crawl frontier so a crawl survives restarts.
+	// Empty keeps the frontier in memory.
+	FrontierDir        string        `json:"frontier_dir"`
+	ExpectedURLs       int           `json:"expected_urls"`
+	CheckpointInterval time.Duration `json:"checkpoint_interval"`
+
+	// ValidatorsPath persists the ETag, Last-Modified and content hash of
+	// each page fetched, so re-crawls after a restart stay conditional.
+	// Empty keeps them in memory.
+	ValidatorsPath string `json:"validators_path"`
+
+	// WARCDir archives every page fetched as WARC request and response
+	// records, with a CDXJ index beside each file. Empty disables capture.
+	WARCDir string `json:"warc_dir"`
 }
 
 // CrawlJob represents a crawling job
@@ -65,6 +96,7 @@ type CrawlJob struct {
 	Status      procurement.ProcessingStatus `json:"status"`
 	AttemptCount int                   `json:"attempt_count"`
 	LastError   string                 `json:"last_error,omitempty"`
+	Recrawl     bool                   `json:"recrawl,omitempty"` // fetch even if the URL was seen before
 }
 
 // CrawlResult represents the result of a crawling job
@@ -78,6 +110,8 @@ type CrawlResult struct {
 	StatusCode      int                  `json:"status_code"`
 	ProcessingTime  time.Duration        `json:"processing_time"`
 	ExtractedLinks  []string             `json:"extracted_links"`
+	NotModified     bool                 `json:"not_modified,omitempty"`
+	Validators      conditional.Validators `json:"validators"`
 	ComplianceResult *ComplianceResult   `json:"compliance_result"`
 	CreatedAt       time.Time            `json:"created_at"`
 }
@@ -96,7 +130,11 @@ type CrawlMetrics struct {
 	JobsQueued       int64             `json:"jobs_queued"`
 	JobsCompleted    int64             `json:"jobs_completed"`
 	JobsFailed       int64             `json:"jobs_failed"`
+	LinksDiscovered  int64             `json:"links_discovered"`
+	LinksQueued      int64             `json:"links_queued"`
+	PagesUnchanged   int64             `json:"pages_unchanged"`
 	DocumentsStored  int64             `json:"documents_stored"`
+	DocumentsUpdated int64             `json:"documents_updated"`
 	BytesProcessed   int64             `json:"bytes_processed"`
 	AverageQuality   float64           `json:"average_quality"`
 	DomainStats      map[string]*DomainStats `json:"domain_stats"`
@@ -141,10 +179,13 @@ func NewDistributedCrawler(
 		qualityValidator: qualityValidator,
 		storage:         storage,
 		config:          config,
-		activeJobs:      make(map[string]*CrawlJob),
+		frontier:        NewFrontier(config.ExpectedURLs),
 		workers:         make([]*CrawlWorker, 0, config.MaxWorkers),
-		jobQueue:        make(chan *CrawlJob, config.JobQueueSize),
+		jobQueue:        make(chan *CrawlJob),
 		resultQueue:     make(chan *CrawlResult, config.ResultQueueSize),
+		stopCh:          make(chan struct{}),
+		scopes:          make(map[string]*CrawlScope),
+		validators:      conditional.NewStore(),
 		metrics: &CrawlMetrics{
 			DomainStats: make(map[string]*DomainStats),
 			WorkerStats: make(map[int]*WorkerStats),
@@ -170,16 +211,57 @@ func DefaultCrawlerConfig() *CrawlerConfig {
 		MaxPagesPerDomain:    1000,
 		CrawlDepth:           3,
 		RespectSitemaps:      true,
+		ExpectedURLs:         1000000,
+		CheckpointInterval:   30 * time.Second,
 	}
 }
 
-// Start starts the crawler with workers and result processor
+// Start starts the crawler with workers and result processor. With a
+// FrontierDir configured, the persisted frontier is loaded first and the
+// crawl resumes from it under the persisted crawl scopes; jobs submitted and
+// scopes set before Start are carried over.
 func (dc *DistributedCrawler) Start(ctx context.Context) error {
 	log.Info().
 		Int("max_workers", dc.config.MaxWorkers).
 		Int("job_queue_size", dc.config.JobQueueSize).
+		Str("frontier_dir", dc.config.FrontierDir).
 		Msg("Starting distributed crawler")
 	
+	if dc.config.FrontierDir != "" {
+		frontier, err := OpenFrontier(dc.config.FrontierDir, dc.config.ExpectedURLs)
+		if err != nil {
+			return fmt.Errorf("failed to open crawl frontier: %w", err)
+		}
+		for _, job := range dc.frontier.Jobs() {
+			if err := frontier.Push(job); err != nil && !errors.Is(err, ErrURLSeen) {
+				frontier.Close()
+				return fmt.Errorf("failed to carry over job %s: %w", job.ID, err)
+			}
+		}
+		dc.restoreScopes(frontier)
+		dc.frontier = frontier
+	}
+	
+	if dc.config.ValidatorsPath != "" {
+		validators, err := conditional.OpenStore(dc.config.ValidatorsPath)
+		if err != nil {
+			return fmt.Errorf("failed to open page validators: %w", err)
+		}
+		dc.validators = validators
+	}
+	
+	if dc.config.WARCDir != "" {
+		capture, err := warc.OpenFileWriter(dc.config.WARCDir, "crawl", 0, warc.Header{
+			{Name: "software", Value: dc.extractor.config.UserAgent},
+			{Name: "description", Value: "CAIA Library web crawl"},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open WARC capture: %w", err)
+		}
+		dc.capture = capture
+		dc.extractor.SetCapture(capture)
+	}
+	
 	// Start workers
 	for i := 0; i < dc.config.MaxWorkers; i++ {
 		worker := &CrawlWorker{
@@ -201,6 +283,11 @@ func (dc *DistributedCrawler) Start(ctx context.Context) error {
 	// Start result processor
 	go dc.processResults(ctx)
 	
+	// Feed workers from the frontier and checkpoint it periodically
+	dc.wg.Add(2)
+	go dc.dispatch(ctx)
+	go dc.checkpointLoop(ctx)
+	
 	log.Info().
 		Int("workers_started", len(dc.workers)).
 		Msg("Distributed crawler started")
@@ -212,7 +299,9 @@ func (dc *DistributedCrawler) Start(ctx context.Context) error {
 func (dc *DistributedCrawler) Stop() error {
 	log.Info().Msg("Stopping distributed crawler")
 	
-	// Stop all workers
+	// Stop feeding workers, then stop them
+	close(dc.stopCh)
+	dc.wg.Wait()
 	for _, worker := range dc.workers {
 		worker.Stop()
 	}
@@ -221,62 +310,196 @@ func (dc *DistributedCrawler) Stop() error {
 	close(dc.jobQueue)
 	close(dc.resultQueue)
 	
+	// Unfinished jobs stay in the frontier for the next run
+	if err := dc.frontier.Close(); err != nil {
+		return fmt.Errorf("failed to close crawl frontier: %w", err)
+	}
+	if err := dc.validators.Close(); err != nil {
+		return fmt.Errorf("failed to close page validators: %w", err)
+	}
+	if dc.capture != nil {
+		if err := dc.capture.Close(); err != nil {
+			return fmt.Errorf("failed to close WARC capture: %w", err)
+		}
+	}
+	
 	log.Info().Msg("Distributed crawler stopped")
 	return nil
 }
 
-// SubmitJob submits a new crawl job
+// dispatch hands jobs from the frontier to idle workers
+func (dc *DistributedCrawler) dispatch(ctx context.Context) {
+	defer dc.wg.Done()
+	
+	for {
+		job := dc.frontier.Pop()
+		if job == nil {
+			select {
+			case <-dc.frontier.Ready():
+				continue
+			case <-dc.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		
+		// A job popped but never handed out is still in flight in the
+		// frontier, and is queued again when the frontier is reopened
+		select {
+		case dc.jobQueue <- job:
+		case <-dc.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkpointLoop flushes the frontier to disk every CheckpointInterval
+func (dc *DistributedCrawler) checkpointLoop(ctx context.Context) {
+	defer dc.wg.Done()
+	
+	interval := dc.config.CheckpointInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ticker.C:
+			if err := dc.frontier.Checkpoint(); err != nil {
+				log.Error().Err(err).Msg("Failed to checkpoint crawl frontier")
+			}
+		case <-dc.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SubmitJob adds a crawl job to the frontier. With deduplication enabled,
+// a URL that has been submitted before is rejected with ErrURLSeen unless
+// the job is a recrawl.
 func (dc *DistributedCrawler) SubmitJob(ctx context.Context, job *CrawlJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dc.config.JobQueueSize > 0 && dc.frontier.Stats().Queued >= dc.config.JobQueueSize {
+		return fmt.Errorf("job queue full")
+	}
+	
+	scope := dc.scope(job.Source)
+	if scope != nil && !scope.reserve() {
+		return ErrPageBudgetExhausted
+	}
+	
 	job.Status = procurement.StatusPending
 	job.CreatedAt = time.Now()
 	
-	// Track job
-	dc.jobsMu.Lock()
-	dc.activeJobs[job.ID] = job
-	dc.jobsMu.Unlock()
+	push := dc.frontier.Push
+	if !dc.config.EnableDeduplication || job.Recrawl {
+		push = dc.frontier.PushAgain
+	}
+	if err := push(job); err != nil {
+		if scope != nil {
+			scope.release()
+		}
+		return err
+	}
 	
 	// Update metrics
 	dc.metricsMu.Lock()
 	dc.metrics.JobsQueued++
 	dc.metricsMu.Unlock()
 	
-	// Submit to queue
-	select {
-	case dc.jobQueue <- job:
-		log.Debug().
-			Str("job_id", job.ID).
-			Str("url", job.URL).
-			Msg("Job submitted to queue")
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		return fmt.Errorf("job queue full")
-	}
+	log.Debug().
+		Str("job_id", job.ID).
+		Str("url", job.URL).
+		Msg("Job submitted to frontier")
+	return nil
 }
 
-// SubmitBatch submits multiple crawl jobs
+// SubmitBatch submits multiple crawl jobs, skipping URLs already seen and
+// jobs beyond their source's page budget
 func (dc *DistributedCrawler) SubmitBatch(ctx context.Context, jobs []*CrawlJob) error {
 	for _, job := range jobs {
 		if err := dc.SubmitJob(ctx, job); err != nil {
+			if errors.Is(err, ErrURLSeen) || errors.Is(err, ErrPageBudgetExhausted) {
+				continue
+			}
 			return fmt.Errorf("failed to submit job %s: %w", job.ID, err)
 		}
 	}
 	return nil
 }
 
-// GetJobStatus returns the status of a crawl job
-func (dc *DistributedCrawler) GetJobStatus(jobID string) *CrawlJob {
-	dc.jobsMu.RLock()
-	defer dc.jobsMu.RUnlock()
+// SetScope enables link following for a source's jobs. Links found on its
+// pages are queued as new jobs while they stay within the scope and its page
+// budget. Setting a scope replaces the previous one; the budget starts fresh
+// only once the source's earlier jobs have all finished, and is otherwise
+// carried over. A scope without a MaxDepth uses the crawler's CrawlDepth.
+// Scopes are persisted with the frontier.
+func (dc *DistributedCrawler) SetScope(source string, scope *CrawlScope) {
+	if scope.MaxDepth <= 0 {
+		scope.MaxDepth = dc.config.CrawlDepth
+	}
 	
-	if job, exists := dc.activeJobs[jobID]; exists {
-		// Return a copy to prevent race conditions
-		jobCopy := *job
-		return &jobCopy
+	dc.scopesMu.Lock()
+	defer dc.scopesMu.Unlock()
+	
+	if previous := dc.scopes[source]; previous != nil && dc.frontier.Pending(source) > 0 {
+		scope.inherit(previous)
 	}
+	dc.frontier.SetScope(source, scope.state())
+	dc.scopes[source] = scope
+}
+
+// RemoveScope stops link following for a source
+func (dc *DistributedCrawler) RemoveScope(source string) {
+	dc.scopesMu.Lock()
+	defer dc.scopesMu.Unlock()
 	
-	return nil
+	delete(dc.scopes, source)
+	dc.frontier.RemoveScope(source)
+}
+
+// restoreScopes moves the crawl scopes onto a newly opened frontier. Scopes
+// set before Start are recorded in it; those it persisted for other sources
+// are restored, so resumed jobs keep following links within their budget.
+func (dc *DistributedCrawler) restoreScopes(frontier *Frontier) {
+	dc.scopesMu.Lock()
+	defer dc.scopesMu.Unlock()
+	
+	for source, state := range frontier.Scopes() {
+		if _, ok := dc.scopes[source]; ok {
+			continue
+		}
+		scope, err := restoreScope(state)
+		if err != nil {
+			log.Warn().Err(err).Str("source", source).Msg("Dropping persisted crawl scope")
+			frontier.RemoveScope(source)
+			continue
+		}
+		dc.scopes[source] = scope
+	}
+	for source, scope := range dc.scopes {
+		frontier.SetScope(source, scope.state())
+	}
+}
+
+func (dc *DistributedCrawler) scope(source string) *CrawlScope {
+	dc.scopesMu.RLock()
+	defer dc.scopesMu.RUnlock()
+	return dc.scopes[source]
+}
+
+// GetJobStatus returns the status of a crawl job
+func (dc *DistributedCrawler) GetJobStatus(jobID string) *CrawlJob {
+	return dc.frontier.Get(jobID)
 }
 
 // Start starts a crawl worker
@@ -326,6 +549,7 @@ func (cw *CrawlWorker) processJob(ctx context.Context, job *CrawlJob) {
 	job.Status = procurement.StatusProcessing
 	job.StartedAt = time.Now()
 	job.AttemptCount++
+	cw.crawler.frontier.Update(job)
 	
 	// Create job context with timeout
 	jobCtx, cancel := context.WithTimeout(ctx, cw.crawler.config.JobTimeout)
@@ -393,14 +617,19 @@ func (cw *CrawlWorker) crawlURL(ctx context.Context, job *CrawlJob) *CrawlResult
 		return result
 	}
 	
-	// Wait for rate limiting
+	// Wait for rate limiting, no less than robots.txt asks for
+	cw.crawler.rateLimiter.SetRobotsDelay(job.Domain, compliance.CrawlDelay)
 	if err := cw.crawler.rateLimiter.Wait(ctx, job.Domain, compliance.RequiredDelay); err != nil {
 		result.Error = fmt.Sprintf("Rate limiting failed: %v", err)
 		return result
 	}
 	
-	// Extract content
-	extractionResult, err := cw.crawler.extractor.ExtractContent(ctx, job.URL)
+	// Extract content, conditionally if the page was fetched before
+	var prev *conditional.Validators
+	if v, ok := cw.crawler.validators.Get(normalizeCrawlURL(job.URL)); ok {
+		prev = &v
+	}
+	extractionResult, err := cw.crawler.extractor.ExtractContentIfModified(ctx, job.URL, prev)
 	if err != nil {
 		result.Error = fmt.Sprintf("Content extraction failed: %v", err)
 		// Record rate limiting result
@@ -415,6 +644,7 @@ func (cw *CrawlWorker) crawlURL(ctx context.Context, job *CrawlJob) *CrawlResult
 	
 	result.StatusCode = extractionResult.StatusCode
 	result.ProcessingTime = extractionResult.ProcessingTime
+	result.ExtractedLinks = extractionResult.Links
 	
 	if !extractionResult.Success {
 		result.Error = extractionResult.Error
@@ -429,6 +659,32 @@ func (cw *CrawlWorker) crawlURL(ctx context.Context, job *CrawlJob) *CrawlResult
 		return result
 	}
 	
+	result.Validators = extractionResult.Validators
+	result.Validators.URL = normalizeCrawlURL(job.URL)
+	if extractionResult.NotModified {
+		result.NotModified = true
+		result.Success = true
+		cw.crawler.rateLimiter.RecordRequest(job.Domain, RequestResult{
+			Timestamp:   time.Now(),
+			StatusCode:  extractionResult.StatusCode,
+			Duration:    extractionResult.ProcessingTime,
+			Success:     true,
+			RateLimited: false,
+		})
+		return result
+	}
+	if prev != nil {
+		result.Validators.DocumentID = prev.DocumentID
+	}
+	
+	// Record the domain policy the page was collected under
+	if compliance.Policy != nil && extractionResult.Document != nil {
+		if extractionResult.Document.Content.Metadata == nil {
+			extractionResult.Document.Content.Metadata = make(map[string]string)
+		}
+		compliance.Policy.Stamp(extractionResult.Document.Content.Metadata)
+	}
+	
 	// Validate quality
 	if cw.crawler.qualityValidator != nil {
 		validation, err := cw.crawler.qualityValidator.ValidateContent(
@@ -501,6 +757,9 @@ func (dc *DistributedCrawler) handleResult(ctx context.Context, result *CrawlRes
 	dc.metricsMu.Lock()
 	if result.Success {
 		dc.metrics.JobsCompleted++
+		if result.NotModified {
+			dc.metrics.PagesUnchanged++
+		}
 		if result.Document != nil {
 			dc.metrics.DocumentsStored++
 			dc.metrics.BytesProcessed += int64(len(result.Document.Content.Text))
@@ -538,9 +797,10 @@ func (dc *DistributedCrawler) handleResult(ctx context.Context, result *CrawlRes
 	dc.metrics.LastUpdated = time.Now()
 	dc.metricsMu.Unlock()
 	
-	// Store document if successful
+	// Store document if successful, as a new version of the page's
+	// document if it was stored before
 	if result.Success && result.Document != nil {
-		if _, err := dc.storage.StoreDocument(ctx, result.Document); err != nil {
+		if err := dc.storeDocument(ctx, result); err != nil {
 			log.Error().
 				Err(err).
 				Str("job_id", result.JobID).
@@ -554,10 +814,110 @@ func (dc *DistributedCrawler) handleResult(ctx context.Context, result *CrawlRes
 		}
 	}
 	
-	// Remove job from active jobs
-	dc.jobsMu.Lock()
-	delete(dc.activeJobs, result.JobID)
-	dc.jobsMu.Unlock()
+	// Keep the page's validators for the next crawl
+	if result.Success && result.Validators.URL != "" {
+		if err := dc.validators.Put(result.Validators); err != nil {
+			log.Warn().Err(err).Str("url", result.URL).Msg("Failed to save page validators")
+		}
+	}
+	
+	// Queue the links found on the page, then remove the job
+	if job := dc.frontier.Get(result.JobID); job != nil && len(result.ExtractedLinks) > 0 {
+		dc.followLinks(ctx, job, result.ExtractedLinks)
+	}
+	dc.frontier.Done(result.JobID)
+}
+
+// storeDocument stores a crawled page. A page stored by an earlier crawl
+// is updated in place, so storage records a new version of its document;
+// if that document is gone the page is stored afresh. On success the
+// result's validators point at the stored document.
+func (dc *DistributedCrawler) storeDocument(ctx context.Context, result *CrawlResult) error {
+	if previousID := result.Validators.DocumentID; previousID != "" {
+		result.Document.ID = previousID
+		_, err := dc.storage.UpdateDocument(ctx, result.Document)
+		if err == nil {
+			dc.metricsMu.Lock()
+			dc.metrics.DocumentsUpdated++
+			dc.metricsMu.Unlock()
+			return nil
+		}
+		log.Warn().
+			Err(err).
+			Str("document_id", previousID).
+			Str("url", result.URL).
+			Msg("Failed to update previous version, storing as new document")
+	}
+	
+	if _, err := dc.storage.StoreDocument(ctx, result.Document); err != nil {
+		result.Validators.DocumentID = ""
+		return err
+	}
+	result.Validators.DocumentID = result.Document.ID
+	return nil
+}
+
+// followLinks queues the in-scope links found on a job's page as jobs one
+// level deeper. Links already seen are skipped, and following stops once the
+// source's page budget is spent.
+func (dc *DistributedCrawler) followLinks(ctx context.Context, parent *CrawlJob, links []string) {
+	scope := dc.scope(parent.Source)
+	if scope == nil {
+		return
+	}
+	
+	depth := parent.Depth + 1
+	discovered, queued := 0, 0
+	for _, link := range links {
+		if !scope.Allows(link, depth) || dc.frontier.Seen(link) {
+			continue
+		}
+		discovered++
+		
+		host := ""
+		if u, err := url.Parse(link); err == nil {
+			host = u.Hostname()
+		}
+		job := &CrawlJob{
+			ID:       fmt.Sprintf("%s-%s", parent.Source, uuid.NewString()),
+			URL:      link,
+			Domain:   host,
+			Depth:    depth,
+			Priority: parent.Priority,
+			Source:   parent.Source,
+			Metadata: make(map[string]string, len(parent.Metadata)+1),
+		}
+		for k, v := range parent.Metadata {
+			job.Metadata[k] = v
+		}
+		job.Metadata["discovered_from"] = parent.URL
+		
+		err := dc.SubmitJob(ctx, job)
+		if errors.Is(err, ErrURLSeen) {
+			continue
+		}
+		if err != nil {
+			log.Debug().
+				Err(err).
+				Str("source", parent.Source).
+				Str("url", link).
+				Msg("Stopped following links")
+			break
+		}
+		queued++
+	}
+	
+	dc.metricsMu.Lock()
+	dc.metrics.LinksDiscovered += int64(discovered)
+	dc.metrics.LinksQueued += int64(queued)
+	dc.metricsMu.Unlock()
+	
+	log.Debug().
+		Str("job_id", parent.ID).
+		Int("links", len(links)).
+		Int("queued", queued).
+		Int("depth", depth).
+		Msg("Followed links")
 }
 
 // GetMetrics returns current crawling metrics
@@ -585,26 +945,24 @@ func (dc *DistributedCrawler) GetMetrics() *CrawlMetrics {
 	return &metrics
 }
 
-// GetActiveJobs returns currently active jobs
+// GetActiveJobs returns the jobs that have not finished, queued or in flight
 func (dc *DistributedCrawler) GetActiveJobs() []*CrawlJob {
-	dc.jobsMu.RLock()
-	defer dc.jobsMu.RUnlock()
-	
-	jobs := make([]*CrawlJob, 0, len(dc.activeJobs))
-	for _, job := range dc.activeJobs {
-		jobCopy := *job
-		jobs = append(jobs, &jobCopy)
-	}
-	
-	return jobs
+	return dc.frontier.Jobs()
 }
 
-// GetQueueStatus returns the current queue status
+// GetQueueStatus returns the current queue status. job_queue_length and
+// frontier_depth count the jobs held by the frontier, which persist across
+// restarts when a FrontierDir is configured.
 func (dc *DistributedCrawler) GetQueueStatus() map[string]int {
+	stats := dc.frontier.Stats()
 	return map[string]int{
-		"job_queue_length":    len(dc.jobQueue),
+		"job_queue_length":    stats.Queued,
+		"frontier_depth":      stats.Queued + stats.InFlight,
+		"frontier_in_flight":  stats.InFlight,
+		"frontier_domains":    stats.Domains,
+		"seen_urls":           stats.SeenURLs,
 		"result_queue_length": len(dc.resultQueue),
-		"active_jobs":         len(dc.activeJobs),
+		"active_jobs":         stats.Queued + stats.InFlight,
 		"active_workers":      len(dc.workers),
 	}
 }
\ No newline at end of file
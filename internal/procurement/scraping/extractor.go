@@ -10,7 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
 	"github.com/rs/zerolog/log"
 )
 
@@ -19,6 +21,7 @@ type ContentExtractor struct {
 	client    *http.Client
 	config    *ExtractorConfig
 	selectors map[string]*SelectorSet
+	capture   *warc.FileWriter
 }
 
 // ExtractorConfig configures content extraction behavior
@@ -59,6 +62,9 @@ type ExtractionResult struct {
 	ContentType   string             `json:"content_type"`
 	ContentLength int64              `json:"content_length"`
 	RedirectChain []string           `json:"redirect_chain"`
+	Links         []string           `json:"links,omitempty"`
+	NotModified   bool               `json:"not_modified,omitempty"`
+	Validators    conditional.Validators `json:"validators"`
 	ExtractedAt   time.Time          `json:"extracted_at"`
 	ProcessingTime time.Duration     `json:"processing_time"`
 }
@@ -100,7 +106,7 @@ func DefaultExtractorConfig() *ExtractorConfig {
 		ExtractText:        true,
 		ExtractMetadata:    true,
 		ExtractImages:      false,
-		ExtractLinks:       false,
+		ExtractLinks:       true,
 		CleanHTML:          true,
 		PreserveFormatting: true,
 		Languages:          []string{"en"},
@@ -108,8 +114,24 @@ func DefaultExtractorConfig() *ExtractorConfig {
 	}
 }
 
+// SetCapture archives every page fetched from now on to w as WARC request
+// and response records. A nil w stops capturing.
+func (ce *ContentExtractor) SetCapture(w *warc.FileWriter) {
+	ce.capture = w
+}
+
 // ExtractContent extracts content from a URL
 func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string) (*ExtractionResult, error) {
+	return ce.ExtractContentIfModified(ctx, targetURL, nil)
+}
+
+// ExtractContentIfModified extracts content from a URL unless it is
+// unchanged since prev was recorded. The request is made conditional on
+// prev's ETag and Last-Modified; a 304, or a body with the same hash from a
+// server that ignores those headers, succeeds with NotModified set and no
+// document. Either way the result carries the validators to keep for the
+// next fetch.
+func (ce *ContentExtractor) ExtractContentIfModified(ctx context.Context, targetURL string, prev *conditional.Validators) (*ExtractionResult, error) {
 	start := time.Now()
 	
 	log.Debug().
@@ -122,7 +144,7 @@ func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string
 	}
 	
 	// Fetch the content
-	resp, err := ce.fetchContent(ctx, targetURL)
+	resp, err := ce.fetchContent(ctx, targetURL, prev)
 	if err != nil {
 		result.Success = false
 		result.Error = err.Error()
@@ -134,6 +156,20 @@ func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string
 	result.ContentType = resp.Header.Get("Content-Type")
 	result.ContentLength = resp.ContentLength
 	
+	if resp.StatusCode == http.StatusNotModified {
+		result.Validators = *prev
+		result.Validators.URL = targetURL
+		result.Validators.FetchedAt = time.Now().UTC()
+		// A 304 may carry updated validators
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			result.Validators.ETag = etag
+		}
+		if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+			result.Validators.LastModified = lastModified
+		}
+		return ce.notModified(result, start), nil
+	}
+	
 	// Handle redirects
 	if resp.Request.URL.String() != targetURL {
 		result.RedirectChain = append(result.RedirectChain, targetURL)
@@ -166,6 +202,18 @@ func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string
 		return result, fmt.Errorf("content exceeds maximum size limit")
 	}
 	
+	if ce.capture != nil {
+		if _, err := ce.capture.WriteExchange(resp.Request, resp, content); err != nil {
+			log.Warn().Err(err).Str("url", targetURL).Msg("Failed to capture page to WARC")
+		}
+	}
+	
+	result.Validators = conditional.FromResponse(targetURL, resp, content)
+	if prev != nil && prev.Unchanged(content) {
+		result.Validators.DocumentID = prev.DocumentID
+		return ce.notModified(result, start), nil
+	}
+	
 	// Extract document
 	doc, err := ce.extractDocument(string(content), resp.Request.URL.String(), result.ContentType)
 	if err != nil {
@@ -174,6 +222,11 @@ func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string
 		return result, err
 	}
 	
+	// Collect links for the crawler to follow
+	if ce.config.ExtractLinks && strings.Contains(strings.ToLower(result.ContentType), "html") {
+		result.Links = ExtractLinks(string(content), resp.Request.URL.String())
+	}
+	
 	result.Document = doc
 	result.Success = true
 	result.ProcessingTime = time.Since(start)
@@ -182,14 +235,30 @@ func (ce *ContentExtractor) ExtractContent(ctx context.Context, targetURL string
 		Str("url", targetURL).
 		Int("status_code", result.StatusCode).
 		Int64("content_length", int64(len(content))).
+		Int("links", len(result.Links)).
 		Dur("processing_time", result.ProcessingTime).
 		Msg("Content extraction completed")
 	
 	return result, nil
 }
 
-// fetchContent fetches content from a URL
-func (ce *ContentExtractor) fetchContent(ctx context.Context, targetURL string) (*http.Response, error) {
+// notModified completes the result of a fetch that found the page unchanged
+func (ce *ContentExtractor) notModified(result *ExtractionResult, start time.Time) *ExtractionResult {
+	result.NotModified = true
+	result.Success = true
+	result.ProcessingTime = time.Since(start)
+	
+	log.Debug().
+		Str("url", result.Validators.URL).
+		Int("status_code", result.StatusCode).
+		Msg("Content not modified since last fetch")
+	
+	return result
+}
+
+// fetchContent fetches content from a URL, conditionally when prev is set.
+// A 304 response is returned like a 200.
+func (ce *ContentExtractor) fetchContent(ctx context.Context, targetURL string, prev *conditional.Validators) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
 	if err != nil {
 		return nil, err
@@ -202,6 +271,9 @@ func (ce *ContentExtractor) fetchContent(ctx context.Context, targetURL string)
 	req.Header.Set("Accept-Encoding", "gzip, deflate")
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Connection", "keep-alive")
+	if prev != nil {
+		prev.Apply(req)
+	}
 	
 	resp, err := ce.client.Do(req)
 	if err != nil {
@@ -209,6 +281,9 @@ func (ce *ContentExtractor) fetchContent(ctx context.Context, targetURL string)
 	}
 	
 	// Check status code
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		return resp, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
 		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
@@ -0,0 +1,632 @@
+package scraping
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	frontierJournalFile = "frontier.jsonl"
+	frontierSeenFile    = "seen.bin"
+	frontierSeenIndex   = "seen.idx"
+
+	frontierPush    = "push"
+	frontierStart   = "start"
+	frontierDone    = "done"
+	frontierScope   = "scope"
+	frontierUnscope = "unscope"
+)
+
+// ErrURLSeen is returned when a URL has already been submitted to the frontier
+var ErrURLSeen = errors.New("url already seen")
+
+// frontierEntry is one line of the on-disk frontier journal. Scope entries
+// are keyed by source rather than job ID.
+type frontierEntry struct {
+	Op    string      `json:"op"`
+	ID    string      `json:"id"`
+	Job   *CrawlJob   `json:"job,omitempty"`
+	Scope *scopeState `json:"scope,omitempty"`
+}
+
+// FrontierStats describes the jobs held by a frontier
+type FrontierStats struct {
+	Queued     int  `json:"queued"`
+	InFlight   int  `json:"in_flight"`
+	Domains    int  `json:"domains"`
+	SeenURLs   int  `json:"seen_urls"`
+	Persistent bool `json:"persistent"`
+}
+
+// Frontier holds the jobs of a crawl that have not finished: those queued,
+// in a priority queue per domain, and those handed to a worker. Domains are
+// served round robin so one large site cannot starve the others.
+//
+// A persistent frontier journals every change and records each URL it has
+// accepted. Reopening it after a restart re-queues in-flight jobs, so the
+// crawl resumes where it stopped.
+//
+// The frontier also keeps each source's crawl scope, counting the jobs pushed
+// for the source against its page budget, so a resumed crawl follows links
+// under the same scope and budget it started with.
+type Frontier struct {
+	mu       sync.Mutex
+	jobs     map[string]*CrawlJob
+	inFlight map[string]bool
+	queues   map[string]*domainQueue
+	domains  []string
+	next     int
+	seq      int64
+	seen     *seenSet
+	scopes   map[string]*scopeState
+	ready    chan struct{}
+
+	// Persistence, unset for an in-memory frontier
+	dir     string
+	journal *os.File
+	entries int
+}
+
+// NewFrontier creates an in-memory frontier sized for about expectedURLs
+// distinct URLs
+func NewFrontier(expectedURLs int) *Frontier {
+	return &Frontier{
+		jobs:     make(map[string]*CrawlJob),
+		inFlight: make(map[string]bool),
+		queues:   make(map[string]*domainQueue),
+		seen:     newSeenSet(expectedURLs),
+		scopes:   make(map[string]*scopeState),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// OpenFrontier loads the frontier persisted in dir, creating the directory
+// if needed. Jobs that were in flight when it was last closed are queued
+// again.
+func OpenFrontier(dir string, expectedURLs int) (*Frontier, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frontier directory %s: %w", dir, err)
+	}
+
+	seen, err := openSeenSet(filepath.Join(dir, frontierSeenFile), filepath.Join(dir, frontierSeenIndex), expectedURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFrontier(expectedURLs)
+	f.seen = seen
+	f.dir = dir
+
+	jobs, scopes, entries, err := replayFrontier(filepath.Join(dir, frontierJournalFile))
+	if err != nil {
+		seen.close()
+		return nil, err
+	}
+	f.scopes = scopes
+	f.entries = entries
+
+	file, err := os.OpenFile(filepath.Join(dir, frontierJournalFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		seen.close()
+		return nil, fmt.Errorf("failed to open frontier journal: %w", err)
+	}
+	f.journal = file
+
+	// Terminate a torn final line so the next entry starts on its own line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if r, err := os.Open(file.Name()); err == nil {
+			if _, err := r.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				file.Write([]byte{'\n'})
+			}
+			r.Close()
+		}
+	}
+
+	resumed := 0
+	for _, job := range jobs {
+		if job.Status == procurement.StatusProcessing {
+			job.Status = procurement.StatusPending
+			resumed++
+		}
+		f.jobs[job.ID] = job
+		f.enqueueLocked(job)
+	}
+	f.signal()
+
+	log.Info().
+		Str("dir", dir).
+		Int("queued", len(jobs)).
+		Int("resumed_in_flight", resumed).
+		Int("scopes", len(scopes)).
+		Int("seen_urls", seen.size()).
+		Msg("Loaded persisted crawl frontier")
+
+	return f, nil
+}
+
+// replayFrontier reads the journal at path and returns the unfinished jobs
+// in the order they were first pushed, and the scope of each source. A torn
+// final line is skipped.
+func replayFrontier(path string) ([]*CrawlJob, map[string]*scopeState, int, error) {
+	scopes := make(map[string]*scopeState)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, scopes, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to open frontier journal: %w", err)
+	}
+	defer file.Close()
+
+	jobs := make(map[string]*CrawlJob)
+	var order []string
+	entries := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry frontierEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Warn().Err(err).Int("line", entries+1).Msg("Skipping corrupt frontier journal entry")
+			continue
+		}
+		entries++
+
+		switch entry.Op {
+		case frontierPush:
+			if entry.Job == nil {
+				continue
+			}
+			if _, exists := jobs[entry.ID]; !exists {
+				order = append(order, entry.ID)
+			}
+			jobs[entry.ID] = entry.Job
+			if scope, ok := scopes[entry.Job.Source]; ok {
+				scope.Queued++
+			}
+		case frontierStart:
+			if job, ok := jobs[entry.ID]; ok {
+				if entry.Job != nil {
+					*job = *entry.Job
+				}
+				job.Status = procurement.StatusProcessing
+			}
+		case frontierDone:
+			delete(jobs, entry.ID)
+		case frontierScope:
+			if entry.Scope != nil {
+				scopes[entry.ID] = entry.Scope
+			}
+		case frontierUnscope:
+			delete(scopes, entry.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, entries, fmt.Errorf("failed to read frontier journal: %w", err)
+	}
+
+	unfinished := make([]*CrawlJob, 0, len(jobs))
+	for _, id := range order {
+		if job, ok := jobs[id]; ok {
+			unfinished = append(unfinished, job)
+			delete(jobs, id)
+		}
+	}
+	return unfinished, scopes, entries, nil
+}
+
+// Push queues a job whose URL has not been seen before, returning
+// ErrURLSeen otherwise
+func (f *Frontier) Push(job *CrawlJob) error {
+	return f.push(job, true)
+}
+
+// PushAgain queues a job even if its URL has been seen, e.g. to recrawl it
+func (f *Frontier) PushAgain(job *CrawlJob) error {
+	return f.push(job, false)
+}
+
+func (f *Frontier) push(job *CrawlJob, unique bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.jobs[job.ID]; exists {
+		return fmt.Errorf("job %s already queued", job.ID)
+	}
+
+	key := newURLKey(job.URL)
+	if unique {
+		seen, err := f.seen.contains(key)
+		if err != nil {
+			return err
+		}
+		if seen {
+			return ErrURLSeen
+		}
+	}
+	if err := f.seen.add(key); err != nil {
+		return err
+	}
+	if err := f.appendLocked(frontierEntry{Op: frontierPush, ID: job.ID, Job: job}); err != nil {
+		return err
+	}
+
+	f.jobs[job.ID] = job
+	if scope, ok := f.scopes[job.Source]; ok {
+		scope.Queued++
+	}
+	f.enqueueLocked(job)
+	f.signal()
+	return nil
+}
+
+// Pop hands out the next queued job, taking domains in turn and the
+// highest priority job within a domain. It returns nil when nothing is
+// queued. The job stays in the frontier until Done.
+func (f *Frontier) Pop() *CrawlJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.domains) > 0 {
+		if f.next >= len(f.domains) {
+			f.next = 0
+		}
+		domain := f.domains[f.next]
+		q := f.queues[domain]
+		if q.Len() == 0 {
+			delete(f.queues, domain)
+			f.domains = append(f.domains[:f.next], f.domains[f.next+1:]...)
+			continue
+		}
+
+		job := heap.Pop(q).(*frontierItem).job
+		f.next++
+
+		f.inFlight[job.ID] = true
+		if err := f.appendLocked(frontierEntry{Op: frontierStart, ID: job.ID}); err != nil {
+			log.Error().Err(err).Str("job_id", job.ID).Msg("Failed to journal crawl job start")
+		}
+		return job
+	}
+	return nil
+}
+
+// Update checkpoints the state of an in-flight job, such as its attempt count
+func (f *Frontier) Update(job *CrawlJob) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.jobs[job.ID]; !ok {
+		return
+	}
+	snapshot := *job
+	if err := f.appendLocked(frontierEntry{Op: frontierStart, ID: job.ID, Job: &snapshot}); err != nil {
+		log.Error().Err(err).Str("job_id", job.ID).Msg("Failed to journal crawl job state")
+	}
+}
+
+// Done removes a finished job from the frontier. Its URL stays seen.
+func (f *Frontier) Done(jobID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.jobs[jobID]; !ok {
+		return
+	}
+	delete(f.jobs, jobID)
+	delete(f.inFlight, jobID)
+	if err := f.appendLocked(frontierEntry{Op: frontierDone, ID: jobID}); err != nil {
+		log.Error().Err(err).Str("job_id", jobID).Msg("Failed to journal crawl job completion")
+	}
+}
+
+// Get returns a copy of an unfinished job, or nil
+func (f *Frontier) Get(jobID string) *CrawlJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if job, ok := f.jobs[jobID]; ok {
+		jobCopy := *job
+		return &jobCopy
+	}
+	return nil
+}
+
+// Jobs returns copies of all unfinished jobs
+func (f *Frontier) Jobs() []*CrawlJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	jobs := make([]*CrawlJob, 0, len(f.jobs))
+	for _, job := range f.jobs {
+		jobCopy := *job
+		jobs = append(jobs, &jobCopy)
+	}
+	return jobs
+}
+
+// Pending returns the number of unfinished jobs of a source
+func (f *Frontier) Pending(source string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending := 0
+	for _, job := range f.jobs {
+		if job.Source == source {
+			pending++
+		}
+	}
+	return pending
+}
+
+// SetScope records a source's crawl scope. Jobs pushed for the source from
+// then on count against the recorded budget.
+func (f *Frontier) SetScope(source string, scope *scopeState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stateCopy := *scope
+	f.scopes[source] = &stateCopy
+	if err := f.appendLocked(frontierEntry{Op: frontierScope, ID: source, Scope: &stateCopy}); err != nil {
+		log.Error().Err(err).Str("source", source).Msg("Failed to journal crawl scope")
+	}
+}
+
+// RemoveScope forgets a source's crawl scope
+func (f *Frontier) RemoveScope(source string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.scopes[source]; !ok {
+		return
+	}
+	delete(f.scopes, source)
+	if err := f.appendLocked(frontierEntry{Op: frontierUnscope, ID: source}); err != nil {
+		log.Error().Err(err).Str("source", source).Msg("Failed to journal crawl scope removal")
+	}
+}
+
+// Scopes returns copies of the recorded crawl scopes, by source
+func (f *Frontier) Scopes() map[string]*scopeState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	scopes := make(map[string]*scopeState, len(f.scopes))
+	for source, scope := range f.scopes {
+		stateCopy := *scope
+		scopes[source] = &stateCopy
+	}
+	return scopes
+}
+
+// Seen reports whether a URL has been submitted to the frontier. A URL
+// whose lookup fails is reported unseen; pushing it reports the error.
+func (f *Frontier) Seen(rawURL string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	seen, err := f.seen.contains(newURLKey(rawURL))
+	if err != nil {
+		log.Warn().Err(err).Str("url", rawURL).Msg("Failed to look up seen URL")
+	}
+	return seen
+}
+
+// Stats returns the frontier's current depth
+func (f *Frontier) Stats() FrontierStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return FrontierStats{
+		Queued:     len(f.jobs) - len(f.inFlight),
+		InFlight:   len(f.inFlight),
+		Domains:    len(f.queues),
+		SeenURLs:   f.seen.size(),
+		Persistent: f.journal != nil,
+	}
+}
+
+// Ready is signalled when jobs are pushed
+func (f *Frontier) Ready() <-chan struct{} {
+	return f.ready
+}
+
+// Checkpoint flushes the journal and seen set to disk, compacting the
+// journal first when it has grown well beyond the unfinished jobs
+func (f *Frontier) Checkpoint() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.journal == nil {
+		return nil
+	}
+	if f.entries > 2*len(f.jobs)+1000 {
+		f.compactLocked()
+	}
+	if err := f.journal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync frontier journal: %w", err)
+	}
+	if err := f.seen.sync(); err != nil {
+		return fmt.Errorf("failed to sync seen URL store: %w", err)
+	}
+	return nil
+}
+
+// Close checkpoints and closes a persistent frontier
+func (f *Frontier) Close() error {
+	if err := f.Checkpoint(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.journal == nil {
+		return nil
+	}
+	err := f.journal.Close()
+	f.journal = nil
+	if seenErr := f.seen.close(); err == nil {
+		err = seenErr
+	}
+	return err
+}
+
+func (f *Frontier) signal() {
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+}
+
+// appendLocked writes one entry to the journal of a persistent frontier.
+// Callers must hold f.mu.
+func (f *Frontier) appendLocked(entry frontierEntry) error {
+	if f.journal == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal frontier journal entry: %w", err)
+	}
+	if _, err := f.journal.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append to frontier journal: %w", err)
+	}
+	f.entries++
+	return nil
+}
+
+// compactLocked rewrites the journal with one push per unfinished job, in
+// queue order, followed by the crawl scopes. A scope entry resets its
+// source's budget on replay, so it comes after the pushes it already counts.
+// Callers must hold f.mu.
+func (f *Frontier) compactLocked() {
+	path := filepath.Join(f.dir, frontierJournalFile)
+	tmpPath := path + ".tmp"
+
+	jobs := make([]*frontierItem, 0, len(f.jobs))
+	for _, q := range f.queues {
+		jobs = append(jobs, q.items...)
+	}
+	for id := range f.inFlight {
+		if job, ok := f.jobs[id]; ok {
+			jobs = append(jobs, &frontierItem{job: job})
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].seq < jobs[j].seq })
+
+	err := func() error {
+		tmp, err := os.Create(tmpPath)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(tmp)
+		enc := json.NewEncoder(w)
+		for _, item := range jobs {
+			if err := enc.Encode(frontierEntry{Op: frontierPush, ID: item.job.ID, Job: item.job}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+		for source, scope := range f.scopes {
+			if err := enc.Encode(frontierEntry{Op: frontierScope, ID: source, Scope: scope}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+
+		if err := os.Rename(tmpPath, path); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		f.journal.Close()
+		f.journal = file
+		f.entries = len(jobs) + len(f.scopes)
+		return nil
+	}()
+	if err != nil {
+		log.Error().Err(err).Str("dir", f.dir).Msg("Failed to compact frontier journal")
+	}
+}
+
+// enqueueLocked adds a job to its domain's queue. Callers must hold f.mu.
+func (f *Frontier) enqueueLocked(job *CrawlJob) {
+	domain := job.Domain
+	if domain == "" {
+		if u, err := url.Parse(job.URL); err == nil {
+			domain = strings.ToLower(u.Hostname())
+		}
+	}
+	q, ok := f.queues[domain]
+	if !ok {
+		q = &domainQueue{}
+		f.queues[domain] = q
+		f.domains = append(f.domains, domain)
+	}
+	f.seq++
+	heap.Push(q, &frontierItem{job: job, seq: f.seq})
+}
+
+// frontierItem is a queued job and the order it was queued in
+type frontierItem struct {
+	job *CrawlJob
+	seq int64
+}
+
+// domainQueue orders a domain's jobs by priority, highest first, then by
+// depth, shallowest first, then in the order they were queued
+type domainQueue struct {
+	items []*frontierItem
+}
+
+func (q *domainQueue) Len() int { return len(q.items) }
+
+func (q *domainQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.job.Priority != b.job.Priority {
+		return a.job.Priority > b.job.Priority
+	}
+	if a.job.Depth != b.job.Depth {
+		return a.job.Depth < b.job.Depth
+	}
+	return a.seq < b.seq
+}
+
+func (q *domainQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *domainQueue) Push(x interface{}) { q.items = append(q.items, x.(*frontierItem)) }
+
+func (q *domainQueue) Pop() interface{} {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items = q.items[:n-1]
+	return item
+}
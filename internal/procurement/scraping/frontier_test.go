@@ -0,0 +1,227 @@
+package scraping
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frontierJob(id, rawURL string, priority int) *CrawlJob {
+	return &CrawlJob{ID: id, URL: rawURL, Priority: priority, Status: procurement.StatusPending}
+}
+
+func popIDs(f *Frontier) []string {
+	var ids []string
+	for job := f.Pop(); job != nil; job = f.Pop() {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+func TestFrontierOrder(t *testing.T) {
+	f := NewFrontier(1000)
+
+	require.NoError(t, f.Push(frontierJob("a-low", "https://a.example/1", 1)))
+	require.NoError(t, f.Push(frontierJob("a-high", "https://a.example/2", 5)))
+	require.NoError(t, f.Push(frontierJob("a-mid", "https://a.example/3", 3)))
+	require.NoError(t, f.Push(frontierJob("b-1", "https://b.example/1", 0)))
+	require.NoError(t, f.Push(frontierJob("b-2", "https://b.example/2", 0)))
+
+	// Domains alternate; within a domain the highest priority goes first
+	assert.Equal(t, []string{"a-high", "b-1", "a-mid", "b-2", "a-low"}, popIDs(f))
+
+	stats := f.Stats()
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, 5, stats.InFlight)
+	assert.Equal(t, 5, stats.SeenURLs)
+	assert.False(t, stats.Persistent)
+
+	f.Done("a-high")
+	assert.Nil(t, f.Get("a-high"))
+	assert.Equal(t, 4, f.Stats().InFlight)
+}
+
+func TestFrontierSeen(t *testing.T) {
+	f := NewFrontier(1000)
+
+	require.NoError(t, f.Push(frontierJob("1", "https://Example.com:443/page#top", 0)))
+
+	// Normalized forms of a seen URL are rejected
+	assert.ErrorIs(t, f.Push(frontierJob("2", "https://example.com/page", 0)), ErrURLSeen)
+	assert.True(t, f.Seen("HTTPS://EXAMPLE.COM/page"))
+	assert.False(t, f.Seen("https://example.com/other"))
+
+	// A URL stays seen after its job finishes, unless pushed again
+	f.Pop()
+	f.Done("1")
+	assert.ErrorIs(t, f.Push(frontierJob("3", "https://example.com/page", 0)), ErrURLSeen)
+	require.NoError(t, f.PushAgain(frontierJob("3", "https://example.com/page", 0)))
+
+	// Job IDs must be unique among unfinished jobs
+	assert.Error(t, f.PushAgain(frontierJob("3", "https://example.com/page", 0)))
+}
+
+func TestBloomFilter(t *testing.T) {
+	b := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		b.add(newURLKey(fmt.Sprintf("https://example.com/%d", i)))
+	}
+	for i := 0; i < 1000; i++ {
+		assert.True(t, b.mayContain(newURLKey(fmt.Sprintf("https://example.com/%d", i))))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if b.mayContain(newURLKey(fmt.Sprintf("https://other.example/%d", i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 500)
+}
+
+func TestFrontierResume(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	require.NoError(t, f.Push(frontierJob("done", "https://a.example/1", 0)))
+	require.NoError(t, f.Push(frontierJob("running", "https://a.example/2", 0)))
+	require.NoError(t, f.Push(frontierJob("queued", "https://a.example/3", 0)))
+
+	assert.Equal(t, "done", f.Pop().ID)
+	f.Done("done")
+	running := f.Pop()
+	require.Equal(t, "running", running.ID)
+	running.Status = procurement.StatusProcessing
+	running.AttemptCount = 1
+	f.Update(running)
+
+	// Simulate a crash: checkpoint, then drop the frontier without closing
+	require.NoError(t, f.Checkpoint())
+
+	resumed, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	defer resumed.Close()
+
+	stats := resumed.Stats()
+	assert.Equal(t, 2, stats.Queued)
+	assert.Equal(t, 0, stats.InFlight)
+	assert.Equal(t, 3, stats.SeenURLs)
+	assert.True(t, stats.Persistent)
+
+	// The in-flight job is queued again with its attempt count kept
+	job := resumed.Get("running")
+	require.NotNil(t, job)
+	assert.Equal(t, procurement.StatusPending, job.Status)
+	assert.Equal(t, 1, job.AttemptCount)
+	assert.Nil(t, resumed.Get("done"))
+	assert.ErrorIs(t, resumed.Push(frontierJob("again", "https://a.example/1", 0)), ErrURLSeen)
+
+	assert.Equal(t, []string{"running", "queued"}, popIDs(resumed))
+}
+
+func TestFrontierTornJournal(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	require.NoError(t, f.Push(frontierJob("1", "https://a.example/1", 0)))
+	require.NoError(t, f.Close())
+
+	// A crash mid-write leaves a partial journal line and seen key
+	journal, err := os.OpenFile(filepath.Join(dir, frontierJournalFile), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = journal.WriteString(`{"op":"push","id":"2","job":{"id":`)
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	seen, err := os.OpenFile(filepath.Join(dir, frontierSeenFile), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = seen.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, seen.Close())
+
+	resumed, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, resumed.Stats().Queued)
+	assert.Equal(t, 1, resumed.Stats().SeenURLs)
+	require.NoError(t, resumed.Push(frontierJob("3", "https://a.example/3", 0)))
+	assert.Equal(t, []string{"1", "3"}, popIDs(resumed))
+	require.NoError(t, resumed.Close())
+
+	// Entries journaled after the torn line are replayed
+	reopened, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.NotNil(t, reopened.Get("3"))
+}
+
+func TestFrontierSeenStore(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	f.seen.bufferLimit = 4
+	for i := 0; i < 10; i++ {
+		require.NoError(t, f.Push(frontierJob(fmt.Sprintf("job-%d", i), fmt.Sprintf("https://a.example/%d", i), 0)))
+	}
+
+	// Only the keys added since the last merge stay in memory
+	assert.Less(t, len(f.seen.recent), 4)
+	assert.Equal(t, int64(8), f.seen.indexed)
+	assert.Equal(t, 10, f.Stats().SeenURLs)
+	for i := 0; i < 10; i++ {
+		assert.True(t, f.Seen(fmt.Sprintf("https://a.example/%d", i)), i)
+	}
+	assert.False(t, f.Seen("https://a.example/10"))
+
+	// The log only holds keys not yet merged
+	info, err := os.Stat(filepath.Join(dir, frontierSeenFile))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*urlKeySize), info.Size())
+	require.NoError(t, f.Close())
+
+	resumed, err := OpenFrontier(dir, 1000)
+	require.NoError(t, err)
+	defer resumed.Close()
+	assert.Equal(t, 10, resumed.Stats().SeenURLs)
+	assert.ErrorIs(t, resumed.Push(frontierJob("again", "https://a.example/3", 0)), ErrURLSeen)
+	assert.ErrorIs(t, resumed.Push(frontierJob("again", "https://a.example/9", 0)), ErrURLSeen)
+	require.NoError(t, resumed.Push(frontierJob("new", "https://a.example/10", 0)))
+}
+
+func TestFrontierCompaction(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := OpenFrontier(dir, 5000)
+	require.NoError(t, err)
+	f.SetScope("src", &scopeState{Domain: "a.example", MaxPages: 2000})
+	for i := 0; i < 1200; i++ {
+		job := frontierJob(fmt.Sprintf("job-%d", i), fmt.Sprintf("https://a.example/%d", i), 0)
+		job.Source = "src"
+		require.NoError(t, f.Push(job))
+		if i < 1190 {
+			f.Pop()
+			f.Done(job.ID)
+		}
+	}
+	require.NoError(t, f.Checkpoint())
+	assert.Equal(t, 11, f.entries)
+	require.NoError(t, f.Close())
+
+	// The scope's budget still counts the jobs compacted away
+	resumed, err := OpenFrontier(dir, 5000)
+	require.NoError(t, err)
+	defer resumed.Close()
+	assert.Equal(t, 10, resumed.Stats().Queued)
+	assert.Equal(t, 1200, resumed.Stats().SeenURLs)
+	require.Contains(t, resumed.Scopes(), "src")
+	assert.Equal(t, 1200, resumed.Scopes()["src"].Queued)
+	assert.Equal(t, 2000, resumed.Scopes()["src"].MaxPages)
+}
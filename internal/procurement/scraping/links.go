@@ -0,0 +1,140 @@
+package scraping
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CanonicalizeURL resolves rawURL to the form used to queue and deduplicate
+// it: an absolute http(s) URL with a lowercase scheme and host, no default
+// port, no fragment, dot segments removed, "/" for an empty path, and query
+// parameters sorted with utm_* tracking parameters dropped.
+func CanonicalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	return canonicalize(u)
+}
+
+func canonicalize(u *url.URL) (string, error) {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("URL %q has no host", u.String())
+	}
+
+	c := *u
+	c.Scheme = scheme
+	c.User = nil
+	c.Host = strings.ToLower(u.Host)
+	if (scheme == "http" && c.Port() == "80") || (scheme == "https" && c.Port() == "443") {
+		c.Host = c.Hostname()
+	}
+	c.Fragment = ""
+	c.RawFragment = ""
+
+	// ResolveReference removes dot segments
+	c.Path = (&url.URL{Path: "/"}).ResolveReference(&url.URL{Path: c.Path}).Path
+	if strings.HasSuffix(u.Path, "/") && !strings.HasSuffix(c.Path, "/") {
+		c.Path += "/"
+	}
+	c.RawPath = ""
+
+	if c.RawQuery != "" {
+		query := c.Query()
+		for key := range query {
+			if strings.HasPrefix(strings.ToLower(key), "utm_") {
+				query.Del(key)
+			}
+		}
+		for _, values := range query {
+			sort.Strings(values)
+		}
+		c.RawQuery = query.Encode() // Encode sorts by key
+	}
+	c.ForceQuery = false
+
+	return c.String(), nil
+}
+
+// ExtractLinks returns the canonical URLs of the links in an HTML page, in
+// document order without duplicates. Relative links are resolved against
+// pageURL or the page's <base href>. Links marked rel="nofollow", and all
+// links on a page whose robots meta tag says nofollow, are left out.
+func ExtractLinks(content, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return links
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		token := tokenizer.Token()
+		attrs := make(map[string]string, len(token.Attr))
+		for _, attr := range token.Attr {
+			attrs[strings.ToLower(attr.Key)] = attr.Val
+		}
+
+		switch token.DataAtom.String() {
+		case "base":
+			if href, ok := attrs["href"]; ok {
+				if resolved, err := base.Parse(strings.TrimSpace(href)); err == nil {
+					base = resolved
+				}
+			}
+		case "meta":
+			name := strings.ToLower(attrs["name"])
+			if name == "robots" && hasToken(attrs["content"], "nofollow", "none") {
+				return nil
+			}
+		case "a", "area":
+			href, ok := attrs["href"]
+			if !ok || hasToken(attrs["rel"], "nofollow") {
+				continue
+			}
+			target, err := base.Parse(strings.TrimSpace(href))
+			if err != nil {
+				continue
+			}
+			link, err := canonicalize(target)
+			if err != nil || seen[link] {
+				continue
+			}
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+}
+
+// hasToken reports whether a space or comma separated attribute value
+// contains any of the given tokens, ignoring case
+func hasToken(value string, tokens ...string) bool {
+	for _, field := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t' || r == '\n'
+	}) {
+		for _, token := range tokens {
+			if field == token {
+				return true
+			}
+		}
+	}
+	return false
+}
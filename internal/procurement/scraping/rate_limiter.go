@@ -34,6 +34,7 @@ type RateLimiterConfig struct {
 type DomainLimiter struct {
 	Domain           string        `json:"domain"`
 	CurrentDelay     time.Duration `json:"current_delay"`
+	RobotsDelay      time.Duration `json:"robots_delay"` // Floor set by the site's robots.txt
 	LastRequest      time.Time     `json:"last_request"`
 	RequestCount     int64         `json:"request_count"`
 	ErrorCount       int64         `json:"error_count"`
@@ -122,6 +123,25 @@ func (arl *AdaptiveRateLimiter) Wait(ctx context.Context, domain string, require
 	return limiter.Wait(ctx, requiredDelay, arl.config)
 }
 
+// SetRobotsDelay sets the delay a domain's robots.txt asks for, from its
+// Crawl-delay or Request-rate. Requests to the domain are never spaced
+// closer than it, however well they go and whatever MaxDelay is.
+func (arl *AdaptiveRateLimiter) SetRobotsDelay(domain string, delay time.Duration) {
+	limiter := arl.getDomainLimiter(domain)
+	
+	limiter.mu.Lock()
+	changed := limiter.RobotsDelay != delay
+	limiter.RobotsDelay = delay
+	limiter.mu.Unlock()
+	
+	if changed {
+		log.Debug().
+			Str("domain", domain).
+			Dur("robots_delay", delay).
+			Msg("Updated robots.txt delay")
+	}
+}
+
 // RecordRequest records the result of a request for adaptive learning
 func (arl *AdaptiveRateLimiter) RecordRequest(domain string, result RequestResult) {
 	limiter := arl.getDomainLimiter(domain)
@@ -141,6 +161,7 @@ func (arl *AdaptiveRateLimiter) GetDomainStats(domain string) *DomainLimiter {
 		return &DomainLimiter{
 			Domain:          limiter.Domain,
 			CurrentDelay:    limiter.CurrentDelay,
+			RobotsDelay:     limiter.RobotsDelay,
 			LastRequest:     limiter.LastRequest,
 			RequestCount:    limiter.RequestCount,
 			ErrorCount:      limiter.ErrorCount,
@@ -199,6 +220,9 @@ func (dl *DomainLimiter) Wait(ctx context.Context, requiredDelay time.Duration,
 	if requiredDelay > actualDelay {
 		actualDelay = requiredDelay
 	}
+	if dl.RobotsDelay > actualDelay {
+		actualDelay = dl.RobotsDelay
+	}
 	
 	// Apply minimum delay constraint
 	if actualDelay < config.MinDelay {
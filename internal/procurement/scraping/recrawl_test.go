@@ -0,0 +1,158 @@
+package scraping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionStorage records the documents stored and updated by the crawler
+type versionStorage struct {
+	mu      sync.Mutex
+	docs    map[string]*document.Document
+	stored  int
+	updated int
+}
+
+func newVersionStorage() *versionStorage {
+	return &versionStorage{docs: make(map[string]*document.Document)}
+}
+
+func (s *versionStorage) StoreDocument(ctx context.Context, doc *document.Document) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored++
+	s.docs[doc.ID] = doc
+	return "commit", nil
+}
+
+func (s *versionStorage) GetDocument(ctx context.Context, id string) (*document.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.docs[id]; ok {
+		return doc, nil
+	}
+	return nil, assert.AnError
+}
+
+func (s *versionStorage) UpdateDocument(ctx context.Context, doc *document.Document) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[doc.ID]; !ok {
+		return "", assert.AnError
+	}
+	s.updated++
+	s.docs[doc.ID] = doc
+	return "commit", nil
+}
+
+func (s *versionStorage) DeleteDocument(ctx context.Context, id, reason string) (string, error) {
+	return "", nil
+}
+func (s *versionStorage) MergeBranch(ctx context.Context, branchName string) error { return nil }
+func (s *versionStorage) ListDocuments(ctx context.Context, filters map[string]string) ([]*document.Document, error) {
+	return nil, nil
+}
+func (s *versionStorage) Health(ctx context.Context) error { return nil }
+
+func TestConditionalRecrawl(t *testing.T) {
+	var mu sync.Mutex
+	body, etag, honorConditional := "<html><head><title>Page</title></head><body><p>First version of the page.</p></body></html>", `"v1"`, true
+	conditionalHits := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			conditionalHits++
+			if honorConditional {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	store := newVersionStorage()
+	dc := NewDistributedCrawler(nil, nil, NewContentExtractor(nil), nil, store, nil)
+	pageURL := server.URL + "/page"
+
+	// crawl fetches the page as crawlURL does and hands the result over
+	crawl := func() *CrawlResult {
+		v, ok := dc.validators.Get(normalizeCrawlURL(pageURL))
+		prev := &v
+		if !ok {
+			prev = nil
+		}
+		extraction, err := dc.extractor.ExtractContentIfModified(ctx, pageURL, prev)
+		require.NoError(t, err)
+		require.True(t, extraction.Success)
+
+		result := &CrawlResult{
+			JobID:       "job",
+			URL:         pageURL,
+			Success:     true,
+			Document:    extraction.Document,
+			NotModified: extraction.NotModified,
+			Validators:  extraction.Validators,
+		}
+		result.Validators.URL = normalizeCrawlURL(pageURL)
+		if ok && !extraction.NotModified {
+			result.Validators.DocumentID = v.DocumentID
+		}
+		dc.handleResult(ctx, result)
+		return result
+	}
+
+	first := crawl()
+	require.NotNil(t, first.Document)
+	assert.Equal(t, 1, store.stored)
+	v, ok := dc.validators.Get(normalizeCrawlURL(pageURL))
+	require.True(t, ok)
+	assert.Equal(t, `"v1"`, v.ETag)
+	assert.Equal(t, first.Document.ID, v.DocumentID)
+
+	// Unchanged: the server answers the conditional request with a 304
+	second := crawl()
+	assert.True(t, second.NotModified)
+	assert.Nil(t, second.Document)
+	assert.Equal(t, 1, conditionalHits)
+	assert.Equal(t, 1, store.stored)
+
+	// A server that ignores validators is caught by the content hash
+	mu.Lock()
+	honorConditional = false
+	mu.Unlock()
+	third := crawl()
+	assert.True(t, third.NotModified)
+	assert.Equal(t, 2, conditionalHits)
+	assert.Equal(t, 1, store.stored)
+
+	// Changed content is stored as a new version of the same document
+	mu.Lock()
+	body, etag = "<html><head><title>Page</title></head><body><p>Second version of the page.</p></body></html>", `"v2"`
+	mu.Unlock()
+	fourth := crawl()
+	assert.False(t, fourth.NotModified)
+	require.NotNil(t, fourth.Document)
+	assert.Equal(t, first.Document.ID, fourth.Document.ID)
+	assert.Equal(t, 1, store.stored)
+	assert.Equal(t, 1, store.updated)
+	v, _ = dc.validators.Get(normalizeCrawlURL(pageURL))
+	assert.Equal(t, `"v2"`, v.ETag)
+	assert.Equal(t, first.Document.ID, v.DocumentID)
+
+	metrics := dc.GetMetrics()
+	assert.Equal(t, int64(2), metrics.PagesUnchanged)
+	assert.Equal(t, int64(1), metrics.DocumentsUpdated)
+}
@@ -0,0 +1,354 @@
+package scraping
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How a site's robots.txt could be read. RFC 9309 section 2.3.1 decides
+// what may be crawled from it: the parsed rules, everything when the file
+// is unavailable (4xx), nothing when it is unreachable (5xx or network
+// errors).
+const (
+	RobotsAccessParsed      = "parsed"
+	RobotsAccessUnavailable = "unavailable"
+	RobotsAccessUnreachable = "unreachable"
+)
+
+const (
+	// maxRobotsBytes is how much of a robots.txt is parsed; RFC 9309 asks
+	// for at least 500 KiB and lets the rest be ignored
+	maxRobotsBytes = 500 * 1024
+	// maxRobotsRedirects is how many redirects are followed to reach a
+	// robots.txt before it counts as unavailable
+	maxRobotsRedirects = 5
+	// robotsRetryInterval is how soon an unreachable robots.txt is tried
+	// again
+	robotsRetryInterval = 10 * time.Minute
+	// maxRobotsStaleness is how long a parsed robots.txt stands in for one
+	// that has become unreachable
+	maxRobotsStaleness = 30 * 24 * time.Hour
+)
+
+// ParseRobotsTxt parses a robots.txt as RFC 9309 describes. User-agent
+// lines in a row open a group that the rules after them belong to; groups
+// naming the same product token are merged, and rules outside any group
+// are ignored. Sitemap lines apply to the whole file. Only the first
+// maxRobotsBytes are read.
+func ParseRobotsTxt(robotsURL string, content []byte) *RobotsData {
+	robotsData := &RobotsData{
+		URL:         robotsURL,
+		UserAgents:  make(map[string]*Agent),
+		Sitemaps:    make([]string, 0),
+		Access:      RobotsAccessParsed,
+		LastFetched: time.Now(),
+		LastChecked: time.Now(),
+		Valid:       true,
+	}
+	if len(content) > maxRobotsBytes {
+		content = content[:maxRobotsBytes]
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var group []*Agent
+	inAgentLines := false
+	for _, line := range strings.Split(text, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgentLines {
+				group = group[:0:0]
+				inAgentLines = true
+			}
+			token := robotsAgentToken(value)
+			if token == "" {
+				continue
+			}
+			agent, exists := robotsData.UserAgents[token]
+			if !exists {
+				agent = &Agent{Name: token, Allow: make([]string, 0), Disallow: make([]string, 0)}
+				robotsData.UserAgents[token] = agent
+			}
+			group = append(group, agent)
+
+		case "allow", "disallow":
+			inAgentLines = false
+			// An empty path is no rule at all
+			if value == "" {
+				continue
+			}
+			pattern := normalizeRobotsPath(value, false)
+			if pattern[0] != '/' && pattern[0] != '*' {
+				pattern = "/" + pattern
+			}
+			for _, agent := range group {
+				if key == "allow" {
+					agent.Allow = append(agent.Allow, pattern)
+				} else {
+					agent.Disallow = append(agent.Disallow, pattern)
+				}
+			}
+
+		case "crawl-delay":
+			inAgentLines = false
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			delay := time.Duration(seconds * float64(time.Second))
+			for _, agent := range group {
+				agent.CrawlDelay = delay
+			}
+			if delay > robotsData.CrawlDelay {
+				robotsData.CrawlDelay = delay
+			}
+
+		case "request-rate":
+			inAgentLines = false
+			for _, agent := range group {
+				agent.RequestRate = value
+			}
+
+		case "sitemap":
+			addSitemap(robotsData, value)
+		}
+	}
+	return robotsData
+}
+
+// Group returns the rules that apply to a crawler: the group naming its
+// product token, else the "*" group, else nil when no group applies
+func (rd *RobotsData) Group(productToken string) *Agent {
+	if agent, ok := rd.UserAgents[strings.ToLower(productToken)]; ok {
+		return agent
+	}
+	return rd.UserAgents["*"]
+}
+
+// Allowed reports whether a crawler may fetch a URL path, query included.
+// The longest matching rule decides, Allow winning a tie with Disallow; a
+// path no rule matches is allowed, as is /robots.txt itself.
+func (rd *RobotsData) Allowed(productToken, path string) bool {
+	switch rd.Access {
+	case RobotsAccessUnavailable:
+		return true
+	case RobotsAccessUnreachable:
+		return false
+	}
+
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	agent := rd.Group(productToken)
+	if agent == nil {
+		return true
+	}
+
+	path = normalizeRobotsPath(path, true)
+	allowLen, disallowLen := -1, -1
+	for _, pattern := range agent.Allow {
+		if len(pattern) > allowLen && robotsPatternMatch(pattern, path) {
+			allowLen = len(pattern)
+		}
+	}
+	for _, pattern := range agent.Disallow {
+		if len(pattern) > disallowLen && robotsPatternMatch(pattern, path) {
+			disallowLen = len(pattern)
+		}
+	}
+	return allowLen >= disallowLen
+}
+
+// Delay returns the delay between requests the group asks for: its
+// Crawl-delay, or the interval its Request-rate allows if that is longer
+func (a *Agent) Delay() time.Duration {
+	delay := a.CrawlDelay
+	if interval := parseRequestRate(a.RequestRate); interval > delay {
+		delay = interval
+	}
+	return delay
+}
+
+// robotsPatternMatch matches a path against a robots.txt pattern, where
+// "*" matches any run of characters and a final "$" anchors the pattern
+// at the end of the path. Without "$" the pattern matches as a prefix.
+func robotsPatternMatch(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		pattern = pattern[:len(pattern)-1]
+	} else {
+		pattern += "*"
+	}
+
+	p, s := 0, 0
+	star, mark := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case star >= 0:
+			mark++
+			p, s = star+1, mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// normalizeRobotsPath brings a path or pattern to the one form RFC 9309
+// compares them in: percent-encoded printable ASCII is decoded, other
+// percent-encodings are upper-cased, and non-ASCII octets, controls and
+// spaces are percent-encoded. "%25", "%2A" and "%24" stay encoded, as
+// decoding them would change what a pattern means; in a URL path "*" and
+// "$" are encoded instead, so only a pattern's own "%2A" and "%24" match
+// them.
+func normalizeRobotsPath(p string, isURL bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	b.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '%' && i+2 < len(p) && isHexDigit(p[i+1]) && isHexDigit(p[i+2]):
+			v := unhexDigit(p[i+1])<<4 | unhexDigit(p[i+2])
+			if v > 0x20 && v < 0x7f && v != '%' && v != '*' && v != '$' {
+				b.WriteByte(v)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(hex[v>>4])
+				b.WriteByte(hex[v&0x0f])
+			}
+			i += 2
+		case c >= 0x80 || c <= 0x20 || c == 0x7f || (isURL && (c == '*' || c == '$')):
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhexDigit(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// robotsAgentToken returns the product token a User-agent line names, in
+// lower case for case-insensitive matching: "*", or the leading letters,
+// "_" and "-" of the value, so "ExampleBot/1.1" names "examplebot"
+func robotsAgentToken(value string) string {
+	if strings.HasPrefix(value, "*") {
+		return "*"
+	}
+	end := 0
+	for end < len(value) {
+		c := value[end]
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == '-') {
+			break
+		}
+		end++
+	}
+	return strings.ToLower(value[:end])
+}
+
+// productToken returns the product token of a User-Agent header, the name
+// robots.txt groups are matched against, e.g. "CAIA-Library" for
+// "CAIA-Library/1.0 (+https://caia.tech/bot)"
+func productToken(userAgent string) string {
+	return robotsAgentToken(strings.TrimSpace(userAgent))
+}
+
+// parseRequestRate converts a Request-rate value, requests per period as
+// in "1/5" (seconds), "1/10s", "3/1m" or "100/1h" with an optional time
+// window after it, to the interval between requests. Unparsable values
+// give 0.
+func parseRequestRate(value string) time.Duration {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	requests, period, ok := strings.Cut(fields[0], "/")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return 0
+	}
+
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(period, "s"):
+		period = strings.TrimSuffix(period, "s")
+	case strings.HasSuffix(period, "m"):
+		period, unit = strings.TrimSuffix(period, "m"), time.Minute
+	case strings.HasSuffix(period, "h"):
+		period, unit = strings.TrimSuffix(period, "h"), time.Hour
+	case strings.HasSuffix(period, "d"):
+		period, unit = strings.TrimSuffix(period, "d"), 24*time.Hour
+	}
+	length, err := strconv.ParseFloat(period, 64)
+	if err != nil || length <= 0 {
+		return 0
+	}
+	return time.Duration(length * float64(unit) / float64(n))
+}
+
+// robotsStatusAccess maps the status of a robots.txt response to how it
+// could be read. 429 is treated as a server error: a site shedding load
+// has not said everything is allowed. A redirect still left once the
+// client stops following them makes the file unavailable.
+func robotsStatusAccess(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return RobotsAccessParsed
+	case status == http.StatusTooManyRequests || status >= 500:
+		return RobotsAccessUnreachable
+	default:
+		return RobotsAccessUnavailable
+	}
+}
+
+// stopRobotsRedirects is the CheckRedirect of the robots.txt client: after
+// maxRobotsRedirects the last redirect response is returned as it is
+func stopRobotsRedirects(req *http.Request, via []*http.Request) error {
+	if len(via) > maxRobotsRedirects {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
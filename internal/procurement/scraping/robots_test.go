@@ -0,0 +1,434 @@
+package scraping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The conformance cases below follow the examples of RFC 9309
+
+func TestRobotsRFC9309Example(t *testing.T) {
+	// Section 5.1
+	robots := ParseRobotsTxt("https://www.example.com/robots.txt", []byte(`User-Agent: *
+Disallow: *.gif$
+Disallow: /example/
+Allow: /publications/
+
+User-Agent: foobot
+Disallow:/
+Allow:/example/page.html
+Allow:/example/allowed.gif
+
+User-Agent: barbot
+User-Agent: bazbot
+Disallow: /example/page.html
+
+User-Agent: quxbot
+
+EOF
+`))
+
+	tests := []struct {
+		agent, path string
+		allowed     bool
+	}{
+		// The wildcard group applies to crawlers without their own group
+		{"otherbot", "/", true},
+		{"otherbot", "/image.gif", false},
+		{"otherbot", "/image.gif?size=large", true},
+		{"otherbot", "/example/page.html", false},
+		{"otherbot", "/publications/", true},
+		// The longer Allow outweighs the Disallow
+		{"otherbot", "/publications/cover.gif", true},
+
+		// foobot may only fetch what its Allow lines name
+		{"foobot", "/", false},
+		{"foobot", "/example/page.html", true},
+		{"foobot", "/example/allowed.gif", true},
+		{"foobot", "/example/disallowed.gif", false},
+		{"foobot", "/publications/", false},
+
+		// barbot and bazbot share a group and ignore the wildcard group
+		{"barbot", "/example/page.html", false},
+		{"barbot", "/example/other.html", true},
+		{"barbot", "/image.gif", true},
+		{"bazbot", "/example/page.html", false},
+
+		// quxbot's group has no rules, so everything is allowed
+		{"quxbot", "/example/page.html", true},
+		{"quxbot", "/image.gif", true},
+
+		// Product tokens match case-insensitively
+		{"FooBot", "/publications/", false},
+		{"BARBOT", "/example/page.html", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, robots.Allowed(tt.agent, tt.path), "%s %s", tt.agent, tt.path)
+	}
+}
+
+func TestRobotsLongestMatch(t *testing.T) {
+	// Section 5.2: the most specific rule wins, whatever the order
+	robots := ParseRobotsTxt("", []byte(`User-Agent: foobot
+Allow: /example/page/
+Disallow: /example/page/disallowed.gif
+`))
+	assert.True(t, robots.Allowed("foobot", "/example/page/"))
+	assert.True(t, robots.Allowed("foobot", "/example/page/allowed.gif"))
+	assert.False(t, robots.Allowed("foobot", "/example/page/disallowed.gif"))
+
+	// Section 2.2.2: the longer rule wins, and Allow wins a tie
+	robots = ParseRobotsTxt("", []byte(`User-Agent: *
+Disallow: /folder
+Allow: /folder
+Disallow: /page.html
+Allow: /*.html
+`))
+	assert.True(t, robots.Allowed("foobot", "/folder/page"))
+	assert.False(t, robots.Allowed("foobot", "/page.html"))
+	assert.True(t, robots.Allowed("foobot", "/other.html"))
+
+	// Wildcards count towards a rule's length
+	robots = ParseRobotsTxt("", []byte(`User-Agent: *
+Allow: /docs/
+Disallow: /docs/*/drafts
+`))
+	assert.True(t, robots.Allowed("foobot", "/docs/guide"))
+	assert.False(t, robots.Allowed("foobot", "/docs/guide/drafts/one"))
+}
+
+func TestRobotsGroups(t *testing.T) {
+	// Section 2.2.1: consecutive User-agent lines share the rules after them
+	robots := ParseRobotsTxt("", []byte(`user-agent: a
+disallow: /c
+
+user-agent: b
+disallow: /d
+
+user-agent: e
+user-agent: f
+disallow: /g
+
+user-agent: h
+`))
+	assert.False(t, robots.Allowed("a", "/c"))
+	assert.True(t, robots.Allowed("a", "/d"))
+	assert.False(t, robots.Allowed("b", "/d"))
+	assert.True(t, robots.Allowed("b", "/c"))
+	assert.False(t, robots.Allowed("e", "/g"))
+	assert.False(t, robots.Allowed("f", "/g"))
+	assert.True(t, robots.Allowed("h", "/g"))
+	assert.True(t, robots.Allowed("unknown", "/c"), "no group applies")
+
+	// Groups naming the same crawler are merged
+	robots = ParseRobotsTxt("", []byte(`user-agent: ExampleBot
+disallow: /foo
+disallow: /bar
+
+user-agent: ExampleBot
+disallow: /baz
+`))
+	for _, path := range []string{"/foo", "/bar", "/baz"} {
+		assert.False(t, robots.Allowed("examplebot", path), path)
+	}
+
+	// Only the product token of a User-agent line counts
+	robots = ParseRobotsTxt("", []byte(`User-agent: ExampleBot/1.1 (+https://example.com/bot)
+Disallow: /private
+`))
+	assert.False(t, robots.Allowed("examplebot", "/private"))
+
+	// Rules before any User-agent line belong to no group
+	robots = ParseRobotsTxt("", []byte(`Disallow: /orphan
+User-agent: *
+Disallow: /private
+`))
+	assert.True(t, robots.Allowed("foobot", "/orphan"))
+	assert.False(t, robots.Allowed("foobot", "/private"))
+}
+
+func TestRobotsSyntax(t *testing.T) {
+	// A BOM, CR and CRLF line ends, comments, odd spacing and key case
+	content := "\ufeffUSER-AGENT : foobot # the crawler\r\n" +
+		"DisAllow:/private   # keep out\r" +
+		"Disallow:\n" +
+		"# Allow: /private/open\n" +
+		"allow: public\n" +
+		"not a rule\n"
+	robots := ParseRobotsTxt("", []byte(content))
+	agent := robots.Group("foobot")
+	require.NotNil(t, agent)
+	assert.Equal(t, []string{"/private"}, agent.Disallow, "an empty Disallow is no rule")
+	assert.Equal(t, []string{"/public"}, agent.Allow, "paths are rooted")
+	assert.False(t, robots.Allowed("foobot", "/private/open"))
+	assert.True(t, robots.Allowed("foobot", "/other"))
+}
+
+func TestRobotsPercentEncoding(t *testing.T) {
+	// Section 2.2.2: rules and URLs match in one normalized form
+	tests := []struct {
+		rule, path string
+	}{
+		{"/foo/bar?baz=quz", "/foo/bar?baz=quz"},
+		{"/foo/bar?baz=https://foo.bar", "/foo/bar?baz=https%3A%2F%2Ffoo.bar"},
+		{"/foo/bar/ツ", "/foo/bar/%E3%83%84"},
+		{"/foo/bar/%E3%83%84", "/foo/bar/%E3%83%84"},
+		{"/foo/bar/%62%61%7A", "/foo/bar/%62%61%7A"},
+		{"/foo/bar/%62%61%7A", "/foo/bar/baz"},
+		{"/foo/bar/%e3%83%84", "/foo/bar/%E3%83%84"},
+	}
+	for _, tt := range tests {
+		robots := ParseRobotsTxt("", []byte("User-agent: *\nDisallow: "+tt.rule+"\n"))
+		assert.False(t, robots.Allowed("foobot", tt.path), "%s should match %s", tt.rule, tt.path)
+	}
+}
+
+func TestRobotsSpecialCharacters(t *testing.T) {
+	// Section 2.2.3
+	robots := ParseRobotsTxt("", []byte(`User-agent: *
+Disallow: /path/*.php$
+Disallow: /search*q=
+Disallow: /path/file-with-a-%2A.html
+Disallow: /path/foo-%24
+`))
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		// "$" anchors the end of the path
+		{"/path/index.php", false},
+		{"/path/sub/index.php", false},
+		{"/path/index.php?x=1", true},
+		{"/path/index.php5", true},
+		// "*" matches any run of characters, including none
+		{"/search?q=robots", false},
+		{"/search/results?page=2&q=robots", false},
+		{"/search", true},
+		// Encoded "*" and "$" match those characters literally
+		{"/path/file-with-a-*.html", false},
+		{"/path/file-with-a-%2A.html", false},
+		{"/path/file-with-a-b.html", true},
+		{"/path/foo-$", false},
+		{"/path/foo-bar", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, robots.Allowed("foobot", tt.path), tt.path)
+	}
+
+	// The robots.txt itself may always be fetched
+	robots = ParseRobotsTxt("", []byte("User-agent: *\nDisallow: /\n"))
+	assert.True(t, robots.Allowed("foobot", "/robots.txt"))
+	assert.False(t, robots.Allowed("foobot", "/"))
+	assert.False(t, robots.Allowed("foobot", ""))
+}
+
+func TestRobotsSizeLimit(t *testing.T) {
+	// Rules past the first maxRobotsBytes are ignored
+	content := "User-agent: *\nDisallow: /early\n# " + strings.Repeat("x", maxRobotsBytes) + "\nDisallow: /late\n"
+	robots := ParseRobotsTxt("", []byte(content))
+	assert.False(t, robots.Allowed("foobot", "/early"))
+	assert.True(t, robots.Allowed("foobot", "/late"))
+}
+
+func TestRobotsDelays(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1/5":             5 * time.Second,
+		"1/10s":           10 * time.Second,
+		"2/1s":            500 * time.Millisecond,
+		"3/1m":            20 * time.Second,
+		"60/1h 0600-0845": time.Minute,
+		"1/1d":            24 * time.Hour,
+		"":                0,
+		"fast":            0,
+		"0/5s":            0,
+	}
+	for value, want := range tests {
+		assert.Equal(t, want, parseRequestRate(value), value)
+	}
+
+	robots := ParseRobotsTxt("", []byte(`User-agent: slowbot
+Crawl-delay: 2.5
+Request-rate: 1/10s
+
+User-agent: *
+Crawl-delay: 4
+Request-rate: 1/1s
+`))
+	assert.Equal(t, 10*time.Second, robots.Group("slowbot").Delay(), "the longer of Crawl-delay and Request-rate")
+	assert.Equal(t, 2500*time.Millisecond, robots.Group("slowbot").CrawlDelay)
+	assert.Equal(t, 4*time.Second, robots.Group("otherbot").Delay())
+}
+
+// newRobotsServer serves each path's robots.txt response
+func newRobotsServer(t *testing.T, handler http.HandlerFunc) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestRobotsFetchStatus(t *testing.T) {
+	ctx := context.Background()
+	check := func(t *testing.T, handler http.HandlerFunc) (*ComplianceResult, *RobotsData) {
+		siteURL := newRobotsServer(t, handler)
+		ce := NewComplianceEngine(&ComplianceConfig{
+			RespectRobotsTxt: true,
+			CacheTimeout:     time.Hour,
+			UserAgent:        "CAIA-Library/1.0 (+https://caia.tech/bot)",
+			RequestDelay:     time.Second,
+		})
+		result, err := ce.CheckCompliance(ctx, siteURL+"/page")
+		require.NoError(t, err)
+		return result, ce.GetCachedRobotsData(siteURL)
+	}
+
+	// Section 2.3.1.3: a 4xx means there are no rules
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusUnauthorized, http.StatusGone} {
+		result, robots := check(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		assert.True(t, result.RobotsCompliant, "status %d", status)
+		assert.Equal(t, RobotsAccessUnavailable, robots.Access)
+		assert.Equal(t, status, robots.StatusCode)
+	}
+
+	// Section 2.3.1.4: a 5xx, and here 429, means nothing may be crawled
+	for _, status := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		result, robots := check(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		assert.False(t, result.RobotsCompliant, "status %d", status)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, RobotsAccessUnreachable, robots.Access)
+	}
+
+	// Section 2.3.1.2: up to five redirects are followed
+	result, robots := check(t, func(w http.ResponseWriter, r *http.Request) {
+		if n := strings.Count(r.URL.Path, "/hop"); n < maxRobotsRedirects {
+			http.Redirect(w, r, r.URL.Path+"/hop", http.StatusFound)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /page\nSitemap: sitemap.xml\n"))
+	})
+	assert.False(t, result.RobotsCompliant)
+	assert.Equal(t, RobotsAccessParsed, robots.Access)
+	assert.True(t, strings.HasSuffix(robots.Sitemaps[0], "/robots.txt/hop/hop/hop/hop/sitemap.xml"), "sitemaps resolve against the final URL")
+
+	// ...and one more makes the file unavailable
+	result, robots = check(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"/hop", http.StatusFound)
+	})
+	assert.True(t, result.RobotsCompliant)
+	assert.Equal(t, RobotsAccessUnavailable, robots.Access)
+
+	// An unreachable server allows nothing
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	ce := NewComplianceEngine(nil)
+	allowed, _, err := ce.checkRobotsCompliance(ctx, server.URL+"/page")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRobotsStaleRules(t *testing.T) {
+	ctx := context.Background()
+	failing := false
+	siteURL := newRobotsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+
+	ce := NewComplianceEngine(&ComplianceConfig{RespectRobotsTxt: true, CacheTimeout: time.Nanosecond, UserAgent: "foobot"})
+	allowed, _, err := ce.checkRobotsCompliance(ctx, siteURL+"/public")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// While the site fails, the rules last read from it still apply
+	failing = true
+	allowed, _, err = ce.checkRobotsCompliance(ctx, siteURL+"/public")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, err = ce.checkRobotsCompliance(ctx, siteURL+"/private")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	robots := ce.GetCachedRobotsData(siteURL)
+	assert.True(t, robots.Stale)
+	assert.Equal(t, http.StatusServiceUnavailable, robots.StatusCode)
+
+	// Once they are too old, the site counts as unreachable
+	ce.robotsMu.Lock()
+	robots.LastFetched = time.Now().Add(-maxRobotsStaleness)
+	robots.LastChecked = time.Time{}
+	ce.robotsMu.Unlock()
+	allowed, _, err = ce.checkRobotsCompliance(ctx, siteURL+"/public")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRobotsComplianceDelay(t *testing.T) {
+	ctx := context.Background()
+	siteURL := newRobotsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `User-agent: CAIA-Library
+Disallow: /members/
+Request-rate: 1/10s
+
+User-agent: *
+Disallow: /
+Crawl-delay: 3
+`)
+	})
+
+	// The crawler's own group applies, named by its product token
+	ce := NewComplianceEngine(nil)
+	result, err := ce.CheckCompliance(ctx, siteURL+"/articles/1")
+	require.NoError(t, err)
+	assert.True(t, result.RobotsCompliant)
+	assert.Equal(t, 10*time.Second, result.CrawlDelay)
+	assert.Equal(t, 10*time.Second, result.RequiredDelay)
+
+	result, err = ce.CheckCompliance(ctx, siteURL+"/members/list")
+	require.NoError(t, err)
+	assert.False(t, result.RobotsCompliant)
+
+	// Without a delay in robots.txt the configured one is used
+	ce = NewComplianceEngine(&ComplianceConfig{RespectRobotsTxt: true, CacheTimeout: time.Hour, UserAgent: "otherbot", RequestDelay: 5 * time.Second})
+	result, err = ce.CheckCompliance(ctx, siteURL+"/robots.txt")
+	require.NoError(t, err)
+	assert.True(t, result.RobotsCompliant)
+	assert.Equal(t, 3*time.Second, result.CrawlDelay)
+	assert.Equal(t, 5*time.Second, result.RequiredDelay)
+}
+
+func TestRateLimiterRobotsDelay(t *testing.T) {
+	config := DefaultRateLimiterConfig()
+	config.DefaultDelay = time.Millisecond
+	config.MinDelay = time.Millisecond
+	config.MaxDelay = 10 * time.Millisecond
+	limiter := NewAdaptiveRateLimiter(config)
+	ctx := context.Background()
+
+	// The robots.txt delay holds even above MaxDelay
+	limiter.SetRobotsDelay("example.com", 150*time.Millisecond)
+	require.NoError(t, limiter.Wait(ctx, "example.com", 0))
+	start := time.Now()
+	require.NoError(t, limiter.Wait(ctx, "example.com", 0))
+	assert.GreaterOrEqual(t, time.Since(start), 140*time.Millisecond)
+	assert.Equal(t, 150*time.Millisecond, limiter.GetDomainStats("example.com").RobotsDelay)
+
+	// Other domains are not held back
+	start = time.Now()
+	require.NoError(t, limiter.Wait(ctx, "other.example", 0))
+	require.NoError(t, limiter.Wait(ctx, "other.example", 0))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,186 @@
+package scraping
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrPageBudgetExhausted is returned when a source has queued as many pages
+// as its crawl scope allows
+var ErrPageBudgetExhausted = errors.New("page budget exhausted")
+
+// CrawlScope limits which discovered links a source's crawl follows. A link
+// is in scope when it is on the scope's domain or a subdomain of it, matches
+// one of the include patterns (if any) and none of the exclude patterns, and
+// is no deeper than MaxDepth. MaxPages caps the pages queued for the source,
+// start URLs included; zero means no cap.
+type CrawlScope struct {
+	Domain   string
+	Include  []*regexp.Regexp
+	Exclude  []*regexp.Regexp
+	MaxDepth int
+	MaxPages int
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewCrawlScope compiles a crawl scope from URL patterns
+func NewCrawlScope(domain string, include, exclude []string, maxDepth, maxPages int) (*CrawlScope, error) {
+	scope := &CrawlScope{
+		Domain:   strings.TrimPrefix(strings.ToLower((&url.URL{Host: domain}).Hostname()), "www."),
+		MaxDepth: maxDepth,
+		MaxPages: maxPages,
+	}
+	for _, pattern := range include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL pattern %q: %w", pattern, err)
+		}
+		scope.Include = append(scope.Include, re)
+	}
+	for _, pattern := range exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		scope.Exclude = append(scope.Exclude, re)
+	}
+	return scope, nil
+}
+
+// NewSourceScope builds the crawl scope of a scraping source from its URL
+// patterns, its url exclude filter rules, SourceConfig.MaxDepth and MaxPages
+func NewSourceScope(source *ScrapingSource) (*CrawlScope, error) {
+	exclude := append([]string(nil), source.ExcludePatterns...)
+	maxDepth := 0
+	if source.Config != nil {
+		maxDepth = source.Config.MaxDepth
+		for _, rule := range source.Config.FilterRules {
+			if rule.Type == "exclude" && rule.Field == "url" {
+				exclude = append(exclude, rule.Pattern)
+			}
+		}
+	}
+
+	domain := source.Domain
+	if domain == "" {
+		if u, err := url.Parse(source.BaseURL); err == nil {
+			domain = u.Hostname()
+		}
+	}
+	return NewCrawlScope(domain, source.URLPatterns, exclude, maxDepth, source.MaxPages)
+}
+
+// Allows reports whether a link found at the given depth is in scope
+func (s *CrawlScope) Allows(link string, depth int) bool {
+	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		return false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if s.Domain != "" {
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		if host != s.Domain && !strings.HasSuffix(host, "."+s.Domain) {
+			return false
+		}
+	}
+
+	for _, re := range s.Exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, re := range s.Include {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve takes one page from the budget, reporting false when none is left
+func (s *CrawlScope) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.MaxPages > 0 && s.queued >= s.MaxPages {
+		return false
+	}
+	s.queued++
+	return true
+}
+
+// release returns a page reserved for a job that was not queued
+func (s *CrawlScope) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queued > 0 {
+		s.queued--
+	}
+}
+
+// inherit carries over the pages queued against a previous scope
+func (s *CrawlScope) inherit(previous *CrawlScope) {
+	queued := previous.Queued()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queued = queued
+}
+
+// Queued returns the number of pages queued against the budget
+func (s *CrawlScope) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued
+}
+
+// scopeState is the form in which a crawl scope and its spent page budget
+// are persisted with the frontier
+type scopeState struct {
+	Domain   string   `json:"domain,omitempty"`
+	Include  []string `json:"include,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	MaxDepth int      `json:"max_depth,omitempty"`
+	MaxPages int      `json:"max_pages,omitempty"`
+	Queued   int      `json:"queued"`
+}
+
+// state returns the persisted form of the scope
+func (s *CrawlScope) state() *scopeState {
+	state := &scopeState{
+		Domain:   s.Domain,
+		MaxDepth: s.MaxDepth,
+		MaxPages: s.MaxPages,
+		Queued:   s.Queued(),
+	}
+	for _, re := range s.Include {
+		state.Include = append(state.Include, re.String())
+	}
+	for _, re := range s.Exclude {
+		state.Exclude = append(state.Exclude, re.String())
+	}
+	return state
+}
+
+// restoreScope rebuilds a crawl scope from its persisted form
+func restoreScope(state *scopeState) (*CrawlScope, error) {
+	scope, err := NewCrawlScope(state.Domain, state.Include, state.Exclude, state.MaxDepth, state.MaxPages)
+	if err != nil {
+		return nil, err
+	}
+	scope.queued = state.Queued
+	return scope, nil
+}
@@ -0,0 +1,391 @@
+package scraping
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// urlKey identifies a normalized URL in the seen set
+type urlKey [16]byte
+
+// newURLKey hashes the normalized form of rawURL
+func newURLKey(rawURL string) urlKey {
+	sum := sha256.Sum256([]byte(normalizeCrawlURL(rawURL)))
+	var key urlKey
+	copy(key[:], sum[:16])
+	return key
+}
+
+// normalizeCrawlURL reduces URLs that address the same resource to their
+// canonical form. URLs that cannot be canonicalized are used as given.
+func normalizeCrawlURL(rawURL string) string {
+	if canonical, err := CanonicalizeURL(rawURL); err == nil {
+		return canonical
+	}
+	return rawURL
+}
+
+// bloomFilter is a fixed-size Bloom filter over URL keys. Keys are already
+// uniformly distributed hashes, so the probe positions are derived from them
+// directly by double hashing.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newBloomFilter sizes a filter for n keys at false positive rate p
+func newBloomFilter(n int, p float64) *bloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (b *bloomFilter) add(key urlKey) {
+	h1, h2 := binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:])
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(key urlKey) bool {
+	h1, h2 := binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:])
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// seenBufferLimit is the number of new keys a persistent seen set holds in
+// memory before merging them into its sorted key file
+const seenBufferLimit = 1 << 16
+
+// urlKeySize is the size of a key on disk
+const urlKeySize = len(urlKey{})
+
+// seenSet records every URL the frontier has accepted. The Bloom filter
+// answers most lookups for new URLs; the keys it may contain are confirmed
+// against the exact set, which rules out its false positives.
+//
+// A persistent set keeps only the filter and the keys added since the last
+// merge in memory. Keys are appended to a log as they are added and, once
+// bufferLimit have built up, merged into a sorted key file that lookups
+// binary search. The log then starts over. Both files are read on load to
+// rebuild the filter. An in-memory set keeps every key in its buffer.
+type seenSet struct {
+	filter *bloomFilter
+	recent map[urlKey]struct{}
+
+	// Persistence, unset for an in-memory set
+	file        *os.File
+	indexPath   string
+	index       *os.File
+	indexed     int64
+	bufferLimit int
+}
+
+func newSeenSet(expected int) *seenSet {
+	return &seenSet{
+		filter: newBloomFilter(expected, 0.01),
+		recent: make(map[urlKey]struct{}),
+	}
+}
+
+// openSeenSet loads the seen set persisted in the log at path and the sorted
+// key file at indexPath, and keeps appending to the log. A torn final key
+// from a crash mid-write is dropped.
+func openSeenSet(path, indexPath string, expected int) (*seenSet, error) {
+	s := newSeenSet(expected)
+	s.indexPath = indexPath
+	s.bufferLimit = seenBufferLimit
+
+	if err := s.openIndex(); err != nil {
+		return nil, err
+	}
+	if err := s.scanIndex(); err != nil {
+		s.index.Close()
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		s.index.Close()
+		return nil, fmt.Errorf("failed to open seen URL store: %w", err)
+	}
+	s.file = file
+
+	size, merged, err := s.replayLog()
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+
+	if merged {
+		// The log's keys are in the sorted file, bar those still buffered
+		err = s.restartLog()
+	} else {
+		// Drop a torn key and append after the last whole one
+		err = s.truncateLog(size)
+	}
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openIndex opens the sorted key file, creating it empty if needed
+func (s *seenSet) openIndex() error {
+	index, err := os.OpenFile(s.indexPath, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open seen URL index: %w", err)
+	}
+	info, err := index.Stat()
+	if err != nil {
+		index.Close()
+		return fmt.Errorf("failed to stat seen URL index: %w", err)
+	}
+	s.index = index
+	s.indexed = info.Size() / int64(urlKeySize)
+	return nil
+}
+
+// scanIndex adds the keys in the sorted key file to the filter
+func (s *seenSet) scanIndex() error {
+	r := bufio.NewReader(io.NewSectionReader(s.index, 0, s.indexed*int64(urlKeySize)))
+	var key urlKey
+	for i := int64(0); i < s.indexed; i++ {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return fmt.Errorf("failed to read seen URL index: %w", err)
+		}
+		s.filter.add(key)
+	}
+	return nil
+}
+
+// replayLog buffers the keys in the log that are not in the sorted file,
+// merging as the buffer fills. It returns the size of the whole keys in
+// the log and whether any were merged.
+func (s *seenSet) replayLog() (int64, bool, error) {
+	r := bufio.NewReader(s.file)
+	var key urlKey
+	var size int64
+	merged := false
+	for {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return size, merged, nil
+			}
+			return 0, false, fmt.Errorf("failed to read seen URL store: %w", err)
+		}
+		size += int64(urlKeySize)
+
+		seen, err := s.contains(key)
+		if err != nil {
+			return 0, false, err
+		}
+		if seen {
+			continue
+		}
+		s.insert(key)
+		if len(s.recent) >= s.bufferLimit {
+			if err := s.merge(); err != nil {
+				return 0, false, err
+			}
+			merged = true
+		}
+	}
+}
+
+// contains reports whether the URL key has been seen
+func (s *seenSet) contains(key urlKey) (bool, error) {
+	if !s.filter.mayContain(key) {
+		return false, nil
+	}
+	if _, ok := s.recent[key]; ok {
+		return true, nil
+	}
+	return s.indexContains(key)
+}
+
+// indexContains binary searches the sorted key file for key
+func (s *seenSet) indexContains(key urlKey) (bool, error) {
+	var probe urlKey
+	lo, hi := int64(0), s.indexed
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := s.index.ReadAt(probe[:], mid*int64(urlKeySize)); err != nil {
+			return false, fmt.Errorf("failed to read seen URL index: %w", err)
+		}
+		switch c := bytes.Compare(probe[:], key[:]); {
+		case c == 0:
+			return true, nil
+		case c < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// add records a key, persisting it if the set is backed by a file
+func (s *seenSet) add(key urlKey) error {
+	seen, err := s.contains(key)
+	if err != nil || seen {
+		return err
+	}
+	if s.file != nil {
+		if _, err := s.file.Write(key[:]); err != nil {
+			return fmt.Errorf("failed to record seen URL: %w", err)
+		}
+	}
+	s.insert(key)
+
+	if s.file != nil && len(s.recent) >= s.bufferLimit {
+		if err := s.merge(); err != nil {
+			return err
+		}
+		return s.restartLog()
+	}
+	return nil
+}
+
+func (s *seenSet) insert(key urlKey) {
+	s.filter.add(key)
+	s.recent[key] = struct{}{}
+}
+
+// merge writes the sorted key file anew with the buffered keys merged in
+// and empties the buffer
+func (s *seenSet) merge() error {
+	keys := make([]urlKey, 0, len(s.recent))
+	for key := range s.recent {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+
+	tmpPath := s.indexPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create seen URL index: %w", err)
+	}
+	w := bufio.NewWriter(out)
+	r := bufio.NewReader(io.NewSectionReader(s.index, 0, s.indexed*int64(urlKeySize)))
+
+	var existing urlKey
+	remaining := s.indexed
+	next := func() bool {
+		if remaining == 0 {
+			return false
+		}
+		remaining--
+		_, err = io.ReadFull(r, existing[:])
+		return err == nil
+	}
+	haveExisting := next()
+	for _, key := range keys {
+		for haveExisting && bytes.Compare(existing[:], key[:]) < 0 {
+			w.Write(existing[:])
+			haveExisting = next()
+		}
+		if haveExisting && existing == key {
+			continue
+		}
+		w.Write(key[:])
+	}
+	for haveExisting {
+		w.Write(existing[:])
+		haveExisting = next()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write seen URL index: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.indexPath); err != nil {
+		return fmt.Errorf("failed to replace seen URL index: %w", err)
+	}
+	s.index.Close()
+	if err := s.openIndex(); err != nil {
+		return err
+	}
+	s.recent = make(map[urlKey]struct{})
+	return nil
+}
+
+// restartLog rewrites the log with just the buffered keys, the rest being
+// in the sorted key file
+func (s *seenSet) restartLog() error {
+	if err := s.truncateLog(0); err != nil {
+		return err
+	}
+	for key := range s.recent {
+		if _, err := s.file.Write(key[:]); err != nil {
+			return fmt.Errorf("failed to record seen URL: %w", err)
+		}
+	}
+	return nil
+}
+
+// truncateLog cuts the log to size and appends after it
+func (s *seenSet) truncateLog(size int64) error {
+	if err := s.file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate seen URL store: %w", err)
+	}
+	if _, err := s.file.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek seen URL store: %w", err)
+	}
+	return nil
+}
+
+func (s *seenSet) size() int {
+	return int(s.indexed) + len(s.recent)
+}
+
+func (s *seenSet) sync() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+func (s *seenSet) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if indexErr := s.index.Close(); err == nil {
+		err = indexErr
+	}
+	s.file = nil
+	s.index = nil
+	return err
+}
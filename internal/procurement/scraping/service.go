@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,7 @@ type ScrapingService struct {
 	complianceEngine *ComplianceEngine
 	rateLimiter     *AdaptiveRateLimiter
 	extractor       *ContentExtractor
+	sitemaps        *SitemapFetcher
 	qualityValidator procurement.QualityValidator
 	storage         storage.StorageBackend
 	config          *ServiceConfig
@@ -47,6 +49,7 @@ type ServiceConfig struct {
 	QualityThreshold      float64       `json:"quality_threshold"`
 	MaxDocumentsPerSource int           `json:"max_documents_per_source"`
 	ArchiveResults        bool          `json:"archive_results"`
+	FrontierDir           string        `json:"frontier_dir"` // persists the crawl frontier; empty keeps it in memory
 }
 
 // ScrapingSource represents a configured web scraping source
@@ -59,6 +62,8 @@ type ScrapingSource struct {
 	CrawlInterval   time.Duration     `json:"crawl_interval"`
 	MaxPages        int               `json:"max_pages"`
 	StartURLs       []string          `json:"start_urls"`
+	UseSitemaps     bool              `json:"use_sitemaps"`       // seed crawls from the site's sitemaps
+	Sitemaps        []string          `json:"sitemaps,omitempty"` // sitemap URLs; robots.txt is consulted when empty
 	URLPatterns     []string          `json:"url_patterns"`
 	ExcludePatterns []string          `json:"exclude_patterns"`
 	Metadata        map[string]string `json:"metadata"`
@@ -142,6 +147,7 @@ func NewScrapingService(
 	// Create distributed crawler
 	crawlerConfig := DefaultCrawlerConfig()
 	crawlerConfig.QualityThreshold = config.QualityThreshold
+	crawlerConfig.FrontierDir = config.FrontierDir
 	crawler := NewDistributedCrawler(
 		complianceEngine,
 		rateLimiter,
@@ -151,11 +157,24 @@ func NewScrapingService(
 		crawlerConfig,
 	)
 	
+	// Sitemaps are fetched like pages: as the same crawler, under the same
+	// compliance checks and rate limits
+	var sitemaps *SitemapFetcher
+	if extractor != nil {
+		sitemaps = NewSitemapFetcher(extractor.client)
+		sitemaps.UserAgent = extractor.config.UserAgent
+	} else {
+		sitemaps = NewSitemapFetcher(nil)
+	}
+	sitemaps.Compliance = complianceEngine
+	sitemaps.RateLimiter = rateLimiter
+	
 	return &ScrapingService{
 		crawler:          crawler,
 		complianceEngine: complianceEngine,
 		rateLimiter:     rateLimiter,
 		extractor:       extractor,
+		sitemaps:        sitemaps,
 		qualityValidator: qualityValidator,
 		storage:         storage,
 		config:          config,
@@ -328,6 +347,7 @@ func (ss *ScrapingService) RemoveSource(sourceID string) error {
 	}
 	
 	delete(ss.sources, sourceID)
+	ss.crawler.RemoveScope(sourceID)
 	
 	// Update metrics
 	ss.metricsMu.Lock()
@@ -453,8 +473,16 @@ func (ss *ScrapingService) crawlSource(ctx context.Context, source *ScrapingSour
 		Int("start_urls", len(source.StartURLs)).
 		Msg("Starting source crawl")
 	
+	// Follow links within the source's scope. The page budget carries over
+	// while jobs of an earlier crawl are unfinished.
+	scope, err := NewSourceScope(source)
+	if err != nil {
+		return fmt.Errorf("invalid crawl scope: %w", err)
+	}
+	ss.crawler.SetScope(source.ID, scope)
+	
 	// Generate crawl jobs
-	jobs := ss.generateCrawlJobs(source)
+	jobs := ss.generateCrawlJobs(ctx, source)
 	
 	log.Debug().
 		Str("source_id", source.ID).
@@ -469,36 +497,107 @@ func (ss *ScrapingService) crawlSource(ctx context.Context, source *ScrapingSour
 	return nil
 }
 
-// generateCrawlJobs generates crawl jobs for a source
-func (ss *ScrapingService) generateCrawlJobs(source *ScrapingSource) []*CrawlJob {
+// generateCrawlJobs generates crawl jobs for a source: its start URLs and,
+// in sitemap mode, the in-scope pages its sitemaps list. After the first
+// crawl, only sitemap pages modified since the last one are fetched again.
+func (ss *ScrapingService) generateCrawlJobs(ctx context.Context, source *ScrapingSource) []*CrawlJob {
 	jobs := make([]*CrawlJob, 0)
 	
 	for i, startURL := range source.StartURLs {
-		job := &CrawlJob{
-			ID:       fmt.Sprintf("%s-%d-%d", source.ID, time.Now().Unix(), i),
-			URL:      startURL,
-			Domain:   source.Domain,
-			Depth:    0,
-			Priority: 1,
-			Source:   source.ID,
-			Metadata: map[string]string{
-				"source_id":   source.ID,
-				"source_name": source.Name,
-				"source_type": source.SourceType,
-			},
+		job := ss.newCrawlJob(source, fmt.Sprintf("%s-%d-%d", source.ID, time.Now().Unix(), i), startURL)
+		job.Priority = 1
+		job.Recrawl = true
+		jobs = append(jobs, job)
+	}
+	
+	if source.UseSitemaps {
+		jobs = append(jobs, ss.generateSitemapJobs(ctx, source)...)
+	}
+	
+	return jobs
+}
+
+// generateSitemapJobs creates a job per in-scope sitemap page, newest
+// first so the freshest pages fit in the source's page budget
+func (ss *ScrapingService) generateSitemapJobs(ctx context.Context, source *ScrapingSource) []*CrawlJob {
+	sitemapURLs := source.Sitemaps
+	if len(sitemapURLs) == 0 {
+		discovered, err := ss.complianceEngine.Sitemaps(ctx, source.BaseURL)
+		if err != nil {
+			log.Debug().Err(err).Str("source_id", source.ID).Msg("Could not read sitemaps from robots.txt")
 		}
-		
-		// Add source-specific metadata
-		for k, v := range source.Metadata {
-			job.Metadata[k] = v
+		sitemapURLs = discovered
+	}
+	if len(sitemapURLs) == 0 {
+		sitemapURLs = []string{strings.TrimSuffix(source.BaseURL, "/") + "/sitemap.xml"}
+	}
+	
+	// Compare against the day of the last crawl, as lastmod is often a date
+	var since time.Time
+	if !source.LastCrawl.IsZero() {
+		since = source.LastCrawl.UTC().Truncate(24 * time.Hour)
+	}
+	
+	entries, err := ss.sitemaps.Fetch(ctx, sitemapURLs, since)
+	if err != nil {
+		log.Warn().Err(err).Str("source_id", source.ID).Msg("Failed to read source sitemaps")
+		return nil
+	}
+	
+	scope, err := NewSourceScope(source)
+	if err != nil {
+		return nil
+	}
+	
+	jobs := make([]*CrawlJob, 0, len(entries))
+	for i, entry := range entries {
+		if !scope.Allows(entry.Loc, 0) {
+			continue
+		}
+		job := ss.newCrawlJob(source, fmt.Sprintf("%s-sitemap-%d-%d", source.ID, time.Now().Unix(), i), entry.Loc)
+		job.Metadata["discovered_from"] = "sitemap"
+		if !entry.LastMod.IsZero() {
+			job.Metadata["sitemap_lastmod"] = entry.LastMod.Format(time.RFC3339)
+			// A page modified since the last crawl is fetched again
+			job.Recrawl = !since.IsZero()
 		}
-		
 		jobs = append(jobs, job)
 	}
 	
+	log.Debug().
+		Str("source_id", source.ID).
+		Int("sitemaps", len(sitemapURLs)).
+		Int("entries", len(entries)).
+		Int("jobs", len(jobs)).
+		Time("since", since).
+		Msg("Generated sitemap crawl jobs")
+	
 	return jobs
 }
 
+// newCrawlJob creates a depth 0 crawl job for one of a source's URLs
+func (ss *ScrapingService) newCrawlJob(source *ScrapingSource, id, targetURL string) *CrawlJob {
+	job := &CrawlJob{
+		ID:       id,
+		URL:      targetURL,
+		Domain:   source.Domain,
+		Depth:    0,
+		Source:   source.ID,
+		Metadata: map[string]string{
+			"source_id":   source.ID,
+			"source_name": source.Name,
+			"source_type": source.SourceType,
+		},
+	}
+	
+	// Add source-specific metadata
+	for k, v := range source.Metadata {
+		job.Metadata[k] = v
+	}
+	
+	return job
+}
+
 // healthCheckLoop performs periodic health checks
 func (ss *ScrapingService) healthCheckLoop(ctx context.Context) {
 	ticker := time.NewTicker(ss.config.HealthCheckInterval)
@@ -603,10 +702,14 @@ func (ss *ScrapingService) validateSource(source *ScrapingSource) error {
 		return fmt.Errorf("invalid base URL: %w", err)
 	}
 	
-	if len(source.StartURLs) == 0 {
+	if len(source.StartURLs) == 0 && !source.UseSitemaps {
 		return fmt.Errorf("at least one start URL is required")
 	}
 	
+	if _, err := NewSourceScope(source); err != nil {
+		return err
+	}
+	
 	return nil
 }
 
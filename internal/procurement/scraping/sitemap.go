@@ -0,0 +1,284 @@
+package scraping
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Limits from the sitemap protocol: https://www.sitemaps.org/protocol.html
+const (
+	maxSitemapBytes = 50 * 1024 * 1024 // uncompressed
+	maxSitemapURLs  = 50000
+)
+
+// SitemapEntry is a page listed in a sitemap
+type SitemapEntry struct {
+	Loc     string    `json:"loc"`
+	LastMod time.Time `json:"lastmod,omitempty"`
+}
+
+// SitemapFetcher reads sitemaps and sitemap indexes, plain or gzipped
+type SitemapFetcher struct {
+	client    *http.Client
+	UserAgent string
+	// Compliance, when set, skips sitemaps the site does not allow crawling
+	// and spaces requests as its robots.txt and policy ask
+	Compliance *ComplianceEngine
+	// RateLimiter, when set, paces sitemap requests per host together with
+	// the crawl's page requests
+	RateLimiter *AdaptiveRateLimiter
+	// MaxSitemaps caps the sitemap files read per Fetch, indexes included
+	MaxSitemaps int
+	// MaxURLs caps the entries returned per Fetch
+	MaxURLs int
+	// MaxIndexDepth caps how deeply sitemap indexes may nest
+	MaxIndexDepth int
+}
+
+// NewSitemapFetcher creates a sitemap fetcher. A nil client uses one with a
+// 30 second timeout.
+func NewSitemapFetcher(client *http.Client) *SitemapFetcher {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &SitemapFetcher{
+		client:        client,
+		UserAgent:     "CAIA-Library/1.0 (+https://caia.tech/bot)",
+		MaxSitemaps:   100,
+		MaxURLs:       maxSitemapURLs,
+		MaxIndexDepth: 3,
+	}
+}
+
+// Fetch returns the pages listed in the given sitemaps, following sitemap
+// indexes, newest first. With a non-zero since, pages and child sitemaps
+// whose lastmod is before it are left out; entries without a lastmod are
+// always kept. Sitemaps that cannot be read are logged and skipped; an
+// error is returned only if none could be read.
+func (f *SitemapFetcher) Fetch(ctx context.Context, sitemapURLs []string, since time.Time) ([]SitemapEntry, error) {
+	state := &sitemapFetch{
+		since:   since,
+		visited: make(map[string]bool),
+		seen:    make(map[string]bool),
+	}
+	for _, sitemapURL := range sitemapURLs {
+		f.fetch(ctx, state, sitemapURL, 0)
+	}
+	if state.read == 0 && state.lastErr != nil {
+		return nil, state.lastErr
+	}
+
+	sort.SliceStable(state.entries, func(i, j int) bool {
+		return state.entries[i].LastMod.After(state.entries[j].LastMod)
+	})
+	return state.entries, nil
+}
+
+// sitemapFetch is the state of one Fetch across nested sitemaps
+type sitemapFetch struct {
+	since   time.Time
+	visited map[string]bool
+	seen    map[string]bool
+	entries []SitemapEntry
+	read    int
+	lastErr error
+}
+
+func (f *SitemapFetcher) fetch(ctx context.Context, state *sitemapFetch, sitemapURL string, depth int) {
+	if ctx.Err() != nil || state.visited[sitemapURL] || len(state.visited) >= f.MaxSitemaps || len(state.entries) >= f.MaxURLs {
+		return
+	}
+	state.visited[sitemapURL] = true
+
+	doc, err := f.get(ctx, sitemapURL)
+	if err != nil {
+		log.Warn().Err(err).Str("sitemap", sitemapURL).Msg("Failed to read sitemap")
+		state.lastErr = err
+		return
+	}
+	state.read++
+
+	if len(doc.Sitemaps) > 0 {
+		if depth >= f.MaxIndexDepth {
+			log.Warn().Str("sitemap", sitemapURL).Int("depth", depth).Msg("Sitemap index nested too deeply")
+			return
+		}
+		for _, child := range doc.Sitemaps {
+			loc := strings.TrimSpace(child.Loc)
+			if loc == "" || !isModifiedSince(parseLastMod(child.LastMod), state.since) {
+				continue
+			}
+			f.fetch(ctx, state, loc, depth+1)
+		}
+	}
+
+	for _, page := range doc.URLs {
+		if len(state.entries) >= f.MaxURLs {
+			return
+		}
+		loc, err := CanonicalizeURL(page.Loc)
+		if err != nil || state.seen[loc] {
+			continue
+		}
+		lastMod := parseLastMod(page.LastMod)
+		if !isModifiedSince(lastMod, state.since) {
+			continue
+		}
+		state.seen[loc] = true
+		state.entries = append(state.entries, SitemapEntry{Loc: loc, LastMod: lastMod})
+	}
+}
+
+// get fetches and parses one sitemap file. Either element may be present:
+// <urlset> for a sitemap, <sitemapindex> for an index. Text sitemaps, one
+// URL per line, are accepted too.
+func (f *SitemapFetcher) get(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	if err := f.admit(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		f.record(req.URL.Host, 0, start)
+		return nil, fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+	f.record(req.URL.Host, resp.StatusCode, start)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s returned status %d", sitemapURL, resp.StatusCode)
+	}
+
+	// Sitemaps are often served as .gz files rather than with a gzip
+	// Content-Encoding, so detect compression from the content itself
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, maxSitemapBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap %s: %w", sitemapURL, err)
+	}
+	if len(content) > maxSitemapBytes {
+		return nil, fmt.Errorf("sitemap %s exceeds %d bytes", sitemapURL, maxSitemapBytes)
+	}
+
+	return parseSitemap(content)
+}
+
+// admit checks that a sitemap may be fetched and waits until its host's
+// rate limit allows it
+func (f *SitemapFetcher) admit(ctx context.Context, sitemapURL *url.URL) error {
+	var requiredDelay time.Duration
+	if f.Compliance != nil {
+		compliance, err := f.Compliance.CheckCompliance(ctx, sitemapURL.String())
+		if err != nil {
+			return fmt.Errorf("compliance check failed for sitemap %s: %w", sitemapURL, err)
+		}
+		if !compliance.Allowed {
+			return fmt.Errorf("sitemap %s not allowed: %s", sitemapURL, strings.Join(compliance.Restrictions, ", "))
+		}
+		requiredDelay = compliance.RequiredDelay
+		if f.RateLimiter != nil {
+			f.RateLimiter.SetRobotsDelay(sitemapURL.Host, compliance.CrawlDelay)
+		}
+	}
+
+	if f.RateLimiter != nil {
+		if err := f.RateLimiter.Wait(ctx, sitemapURL.Host, requiredDelay); err != nil {
+			return fmt.Errorf("rate limiting failed for sitemap %s: %w", sitemapURL, err)
+		}
+	}
+	return nil
+}
+
+// record reports how a sitemap request went to the rate limiter. A status
+// of 0 means no response was received.
+func (f *SitemapFetcher) record(host string, status int, start time.Time) {
+	if f.RateLimiter == nil {
+		return
+	}
+	f.RateLimiter.RecordRequest(host, RequestResult{
+		Timestamp:   time.Now(),
+		StatusCode:  status,
+		Duration:    time.Since(start),
+		Success:     status == http.StatusOK,
+		RateLimited: status == http.StatusTooManyRequests,
+	})
+}
+
+// parseSitemap parses an XML sitemap or sitemap index, or a text sitemap
+func parseSitemap(content []byte) (*sitemapDocument, error) {
+	trimmed := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(trimmed, []byte("<")) {
+		doc := &sitemapDocument{}
+		for _, line := range strings.Split(string(trimmed), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				doc.URLs = append(doc.URLs, sitemapLoc{Loc: line})
+			}
+		}
+		return doc, nil
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+	return &doc, nil
+}
+
+// parseLastMod parses a W3C datetime as used in sitemaps, returning the
+// zero time if the value is missing or malformed
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// isModifiedSince reports whether an entry should be crawled again. Entries
+// without a lastmod cannot be ruled out.
+func isModifiedSince(lastMod, since time.Time) bool {
+	return since.IsZero() || lastMod.IsZero() || !lastMod.Before(since)
+}
+
+// sitemapDocument holds either a sitemap's pages or a sitemap index's
+// child sitemaps. Element names are matched without their namespace.
+type sitemapDocument struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
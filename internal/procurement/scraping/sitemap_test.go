@@ -0,0 +1,215 @@
+package scraping
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSitemapServer serves a robots.txt that points at a gzipped sitemap
+// index listing an XML sitemap, a text sitemap and a missing one
+func newSitemapServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := server.URL
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n\nSitemap: /sitemap_index.xml.gz\nsitemap: " + base + "/sitemap_index.xml.gz\n"))
+		case "/sitemap_index.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + base + `/sitemap_docs.xml</loc><lastmod>2026-10-12T08:30:00Z</lastmod></sitemap>
+  <sitemap><loc>` + base + `/sitemap_old.xml</loc><lastmod>2026-01-01</lastmod></sitemap>
+  <sitemap><loc>` + base + `/missing.xml</loc></sitemap>
+</sitemapindex>`))
+			gz.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		case "/sitemap_docs.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + base + `/docs/new</loc><lastmod>2026-10-12T08:30:00+00:00</lastmod></url>
+  <url><loc>` + base + `/docs/older</loc><lastmod>2026-09-01</lastmod></url>
+  <url><loc>` + base + `/docs/undated</loc></url>
+  <url><loc>` + base + `/docs/new#dup</loc></url>
+  <url><loc>` + base + `/blog/post</loc><lastmod>2026-10-11</lastmod></url>
+</urlset>`))
+		case "/sitemap_old.xml":
+			w.Write([]byte(base + "/docs/archived\n" + base + "/private/notes\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestComplianceEngine creates a compliance engine whose policy lets
+// the test servers be crawled
+func newTestComplianceEngine(t *testing.T) *ComplianceEngine {
+	ce := NewComplianceEngine(nil)
+	registry, err := policy.NewRegistry(&policy.File{
+		Version: "test",
+		Domains: []policy.Policy{{Domain: "127.0.0.1", AutomationAllowed: true}},
+	})
+	require.NoError(t, err)
+	ce.SetPolicies(registry)
+	return ce
+}
+
+func TestParseRobotsTxtSitemaps(t *testing.T) {
+	server := newSitemapServer(t)
+	ce := NewComplianceEngine(nil)
+
+	sitemaps, err := ce.Sitemaps(context.Background(), server.URL+"/docs/new")
+	require.NoError(t, err)
+
+	// Relative and repeated Sitemap lines resolve to one absolute URL
+	assert.Equal(t, []string{server.URL + "/sitemap_index.xml.gz"}, sitemaps)
+}
+
+func TestSitemapFetcher(t *testing.T) {
+	server := newSitemapServer(t)
+	fetcher := NewSitemapFetcher(nil)
+	ctx := context.Background()
+
+	entries, err := fetcher.Fetch(ctx, []string{server.URL + "/sitemap_index.xml.gz"}, time.Time{})
+	require.NoError(t, err)
+
+	locs := make([]string, len(entries))
+	for i, entry := range entries {
+		locs[i] = strings.TrimPrefix(entry.Loc, server.URL)
+	}
+	// Newest first, undated last, duplicates dropped
+	assert.Equal(t, []string{"/docs/new", "/blog/post", "/docs/older", "/docs/undated", "/docs/archived", "/private/notes"}, locs)
+	assert.Equal(t, time.Date(2026, 10, 12, 8, 30, 0, 0, time.UTC), entries[0].LastMod.UTC())
+
+	// Incremental: only entries modified since are returned, and child
+	// sitemaps last modified before it are not read
+	entries, err = fetcher.Fetch(ctx, []string{server.URL + "/sitemap_index.xml.gz"}, time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	locs = locs[:0]
+	for _, entry := range entries {
+		locs = append(locs, strings.TrimPrefix(entry.Loc, server.URL))
+	}
+	assert.Equal(t, []string{"/docs/new", "/blog/post", "/docs/undated"}, locs)
+
+	_, err = fetcher.Fetch(ctx, []string{server.URL + "/missing.xml"}, time.Time{})
+	assert.Error(t, err)
+}
+
+func TestSitemapFetcherCompliance(t *testing.T) {
+	var mu sync.Mutex
+	agents := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.URL.Path] = r.UserAgent()
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		case "/sitemap.xml", "/private/sitemap.xml":
+			w.Write([]byte("https://example.com" + r.URL.Path + "/page\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	limiterConfig := DefaultRateLimiterConfig()
+	limiterConfig.DefaultDelay = 10 * time.Millisecond
+	limiterConfig.MinDelay = 10 * time.Millisecond
+	limiter := NewAdaptiveRateLimiter(limiterConfig)
+	extractorConfig := DefaultExtractorConfig()
+	extractorConfig.UserAgent = "TestBot/2.0 (+https://example.com/bot)"
+	ss := NewScrapingService(newTestComplianceEngine(t), limiter, NewContentExtractor(extractorConfig), nil, nil, nil)
+
+	entries, err := ss.sitemaps.Fetch(context.Background(), []string{server.URL + "/sitemap.xml", server.URL + "/private/sitemap.xml"}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://example.com/sitemap.xml/page", entries[0].Loc)
+
+	// Sitemaps are requested as the crawler, only where robots.txt allows,
+	// and counted against the host's rate limit
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, extractorConfig.UserAgent, agents["/sitemap.xml"])
+	assert.NotContains(t, agents, "/private/sitemap.xml")
+
+	stats := limiter.GetDomainStats(strings.TrimPrefix(server.URL, "http://"))
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(1), stats.SuccessCount)
+}
+
+func TestParseLastMod(t *testing.T) {
+	assert.Equal(t, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), parseLastMod("2026-03-04"))
+	assert.Equal(t, time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC), parseLastMod(" 2026-03-04T12:00+02:00 ").UTC())
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), parseLastMod("2026-03"))
+	assert.True(t, parseLastMod("yesterday").IsZero())
+}
+
+func TestGenerateSitemapCrawlJobs(t *testing.T) {
+	server := newSitemapServer(t)
+	ctx := context.Background()
+	ss := NewScrapingService(newTestComplianceEngine(t), nil, nil, nil, nil, nil)
+
+	source := &ScrapingSource{
+		ID:              "docs",
+		Name:            "Docs",
+		BaseURL:         server.URL,
+		UseSitemaps:     true,
+		URLPatterns:     []string{`/docs/`, `/private/`},
+		ExcludePatterns: []string{`/private/`},
+	}
+	require.NoError(t, ss.AddSource(source))
+
+	jobURLs := func(jobs []*CrawlJob) []string {
+		urls := make([]string, len(jobs))
+		for i, job := range jobs {
+			urls[i] = strings.TrimPrefix(job.URL, server.URL)
+		}
+		sort.Strings(urls)
+		return urls
+	}
+
+	// The first crawl takes every in-scope page, as new URLs
+	jobs := ss.generateCrawlJobs(ctx, source)
+	assert.Equal(t, []string{"/docs/archived", "/docs/new", "/docs/older", "/docs/undated"}, jobURLs(jobs))
+	for _, job := range jobs {
+		assert.False(t, job.Recrawl, job.URL)
+		assert.Equal(t, "sitemap", job.Metadata["discovered_from"])
+	}
+
+	// Later crawls recrawl pages modified since the last one; undated pages
+	// are submitted again but dropped by deduplication if already seen
+	source.LastCrawl = time.Date(2026, 10, 12, 18, 0, 0, 0, time.UTC)
+	jobs = ss.generateCrawlJobs(ctx, source)
+	assert.Equal(t, []string{"/docs/new", "/docs/undated"}, jobURLs(jobs))
+	for _, job := range jobs {
+		assert.Equal(t, strings.HasSuffix(job.URL, "/docs/new"), job.Recrawl, job.URL)
+	}
+
+	// Explicit sitemaps replace robots.txt discovery; start URLs still lead
+	source.Sitemaps = []string{server.URL + "/sitemap_old.xml"}
+	source.StartURLs = []string{server.URL + "/"}
+	source.LastCrawl = time.Time{}
+	jobs = ss.generateCrawlJobs(ctx, source)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, server.URL+"/", jobs[0].URL)
+	assert.True(t, jobs[0].Recrawl)
+	assert.Equal(t, server.URL+"/docs/archived", jobs[1].URL)
+}
@@ -38,6 +38,22 @@ func (ms *MockStorage) GetDocument(ctx context.Context, id string) (*document.Do
 	return nil, fmt.Errorf("document not found: %s", id)
 }
 
+func (ms *MockStorage) UpdateDocument(ctx context.Context, doc *document.Document) (string, error) {
+	if _, exists := ms.documents[doc.ID]; !exists {
+		return "", fmt.Errorf("document not found: %s", doc.ID)
+	}
+	ms.documents[doc.ID] = doc
+	return doc.ID, nil
+}
+
+func (ms *MockStorage) DeleteDocument(ctx context.Context, id, reason string) (string, error) {
+	if _, exists := ms.documents[id]; !exists {
+		return "", fmt.Errorf("document not found: %s", id)
+	}
+	delete(ms.documents, id)
+	return id, nil
+}
+
 func (ms *MockStorage) MergeBranch(ctx context.Context, branchName string) error {
 	return nil // Mock implementation
 }
@@ -98,6 +98,24 @@ type CodeValidation struct {
 	BestPractices bool   `json:"best_practices"`
 	SecuritySafe  bool   `json:"security_safe"`
 	Errors        []string `json:"errors"`
+	Diagnostics   []CodeDiagnostic `json:"diagnostics,omitempty"`
+}
+
+// Code diagnostic severities
+const (
+	DiagnosticError   = "error"
+	DiagnosticWarning = "warning"
+)
+
+// CodeDiagnostic is a problem found in a code snippet. Line and Column are
+// 1-based; columns count bytes.
+type CodeDiagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	// Source is the check that found the problem: syntax or types
+	Source  string `json:"source"`
+	Message string `json:"message"`
 }
 
 // MathValidation represents mathematical formula validation
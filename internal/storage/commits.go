@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// resolveCommitDepth bounds how far back from HEAD the govc backend looks
+// for a commit. Commits are resolved right after they are made, so recent
+// history is enough.
+const resolveCommitDepth = 1000
+
+// CommitResolver maps a commit made by a storage backend to the document
+// it stored
+type CommitResolver interface {
+	ResolveCommit(ctx context.Context, commitHash string) (string, error)
+}
+
+// documentCommitPrefixes start the messages of commits that store a document
+var documentCommitPrefixes = []string{"Add document ", "Update document "}
+
+// documentIDFromCommitMessage extracts the document ID from the message of a
+// commit that added or updated a document
+func documentIDFromCommitMessage(message string) (string, bool) {
+	line, _, _ := strings.Cut(message, "\n")
+	for _, prefix := range documentCommitPrefixes {
+		if id, ok := strings.CutPrefix(line, prefix); ok && id != "" {
+			return strings.TrimSpace(id), true
+		}
+	}
+	return "", false
+}
+
+// ResolveCommit returns the ID of the document stored by commitHash
+func (g *GitBackend) ResolveCommit(ctx context.Context, commitHash string) (string, error) {
+	commit, err := g.repo.CommitObject(plumbing.NewHash(commitHash))
+	if err != nil {
+		return "", fmt.Errorf("commit not found: %s", commitHash)
+	}
+
+	id, ok := documentIDFromCommitMessage(commit.Message)
+	if !ok {
+		return "", fmt.Errorf("commit %s does not store a document", commitHash)
+	}
+	return id, nil
+}
+
+// ResolveCommit returns the ID of the document stored by commitHash, which
+// must be among the most recent commits
+func (g *GovcBackend) ResolveCommit(ctx context.Context, commitHash string) (string, error) {
+	commits, err := g.repo.Log(resolveCommitDepth)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit log: %w", err)
+	}
+
+	for _, commit := range commits {
+		if commit.Hash() != commitHash {
+			continue
+		}
+		id, ok := documentIDFromCommitMessage(commit.Message)
+		if !ok {
+			return "", fmt.Errorf("commit %s does not store a document", commitHash)
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("commit not found: %s", commitHash)
+}
+
+// ResolveCommit looks the commit up in the primary backend, then in the
+// secondary one since a store may have fallen back to it
+func (h *HybridStorage) ResolveCommit(ctx context.Context, commitHash string) (string, error) {
+	var firstErr error
+	for _, backend := range []StorageBackend{h.getPrimaryBackend(), h.getSecondaryBackend()} {
+		resolver, ok := backend.(CommitResolver)
+		if !ok {
+			continue
+		}
+		id, err := resolver.ResolveCommit(ctx, commitHash)
+		if err == nil {
+			return id, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no backend can resolve commits")
+	}
+	return "", firstErr
+}
+
+// GetDocumentIndex returns the govc backend's document index, or nil when
+// the govc backend is not in use. The index covers only documents stored in
+// govc; use ListMetadata to list the documents storage serves.
+func (h *HybridStorage) GetDocumentIndex() *DocumentIndex {
+	if govcBackend, ok := h.govcBackend.(*GovcBackend); ok {
+		return govcBackend.GetDocumentIndex()
+	}
+	return nil
+}
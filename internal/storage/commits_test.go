@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolveCommit tests mapping storage commits back to their documents
+func TestResolveCommit(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+
+	config := &HybridStorageConfig{
+		PrimaryBackend:   "govc",
+		EnableFallback:   true,
+		OperationTimeout: 10 * time.Second,
+		EnableSync:       false,
+	}
+
+	hybridStorage, err := NewHybridStorage(repoPath, "resolve-test", config, NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer hybridStorage.Close()
+
+	ctx := context.Background()
+
+	govcCommit, err := hybridStorage.govcBackend.StoreDocument(ctx, newLifecycleDoc("in-govc"))
+	require.NoError(t, err)
+	updated := newLifecycleDoc("in-govc")
+	updated.Content.Text = "revised"
+	updateCommit, err := hybridStorage.govcBackend.UpdateDocument(ctx, updated)
+	require.NoError(t, err)
+
+	// A store that fell back to git is resolved from the secondary backend
+	gitCommit, err := hybridStorage.gitBackend.StoreDocument(ctx, newLifecycleDoc("in-git"))
+	require.NoError(t, err)
+
+	for commit, want := range map[string]string{
+		govcCommit:   "in-govc",
+		updateCommit: "in-govc",
+		gitCommit:    "in-git",
+	} {
+		id, err := hybridStorage.ResolveCommit(ctx, commit)
+		require.NoError(t, err, commit)
+		assert.Equal(t, want, id)
+	}
+
+	_, err = hybridStorage.ResolveCommit(ctx, "0123456789abcdef0123456789abcdef01234567")
+	assert.ErrorContains(t, err, "not found")
+
+	assert.NotNil(t, hybridStorage.GetDocumentIndex())
+}
+
+func TestDocumentIDFromCommitMessage(t *testing.T) {
+	id, ok := documentIDFromCommitMessage("Add document abc-123\n\nsource: web")
+	assert.True(t, ok)
+	assert.Equal(t, "abc-123", id)
+
+	id, ok = documentIDFromCommitMessage("Update document abc-123")
+	assert.True(t, ok)
+	assert.Equal(t, "abc-123", id)
+
+	_, ok = documentIDFromCommitMessage("Delete document abc-123")
+	assert.False(t, ok)
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/rs/zerolog/log"
+)
+
+// DedupIndex keeps a content dedup index in step with a storage backend, so
+// that a deleted document no longer counts as a duplicate of new content
+type DedupIndex struct {
+	*eventIndex
+	index *dedup.Index
+}
+
+// NewDedupIndex creates an empty dedup index over backend
+func NewDedupIndex(backend StorageBackend, config dedup.Config) *DedupIndex {
+	d := &DedupIndex{index: dedup.NewIndex(config)}
+	d.eventIndex = newEventIndex("Dedup", backend, d.apply)
+	return d
+}
+
+// Index returns the underlying dedup index
+func (d *DedupIndex) Index() *dedup.Index {
+	return d.index
+}
+
+// Load rebuilds the index from every stored document and returns the number
+// indexed. Unlike the other indexes it rebuilds in one pass, so documents
+// stored before deduplication existed are clustered in the order they were
+// created.
+func (d *DedupIndex) Load(ctx context.Context) (int, error) {
+	docs, err := d.backend.ListDocuments(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	d.mu.Lock()
+	for _, doc := range docs {
+		d.versions[doc.ID] = doc.UpdatedAt
+	}
+	d.index.Rebuild(docs)
+	d.mu.Unlock()
+
+	log.Info().
+		Int("documents", len(docs)).
+		Msgf("%s index loaded", d.name)
+
+	return len(docs), nil
+}
+
+// apply indexes or removes doc. Documents stored with a cluster keep it;
+// others join the cluster of their best match.
+func (d *DedupIndex) apply(doc *document.Document, deleted bool) bool {
+	if deleted {
+		d.index.Remove(doc.ID)
+		return false
+	}
+
+	fp := dedup.NewFingerprint(doc.Content.Text)
+	if cluster := doc.Content.Metadata[dedup.MetaCluster]; cluster != "" {
+		d.index.Add(doc.ID, fp, cluster)
+	} else {
+		d.index.Assign(doc.ID, fp)
+	}
+	return true
+}
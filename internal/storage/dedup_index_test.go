@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDedupIndex tests that deleted documents stop counting as duplicates
+func TestDedupIndex(t *testing.T) {
+	backend, err := NewGovcBackend("dedup-index-test", nil)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	text := "The committee met on Tuesday to review the annual budget and approved new funding for public libraries across the region."
+
+	original := newLifecycleDoc("original")
+	original.Content.Text = text
+	_, err = backend.StoreDocument(ctx, original)
+	require.NoError(t, err)
+
+	index := NewDedupIndex(backend, dedup.DefaultConfig())
+	indexed, err := index.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, indexed)
+
+	require.NoError(t, index.Subscribe(backend.GetEventBus()))
+	defer index.Close()
+
+	// Documents stored without a recorded cluster join their best match's
+	mirror := newLifecycleDoc("mirror")
+	mirror.Content.Text = text
+	_, err = backend.StoreDocument(ctx, mirror)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return index.Index().Size() == 2 }, 2*time.Second, 10*time.Millisecond)
+	cluster, ok := index.Index().ClusterOf("mirror")
+	require.True(t, ok)
+	assert.Equal(t, "original", cluster)
+
+	for _, id := range []string{"original", "mirror"} {
+		_, err = backend.DeleteDocument(ctx, id, "takedown")
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool { return index.Index().Size() == 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Nil(t, index.Index().Find(dedup.NewFingerprint(text)), "Re-ingesting deleted content should not be skipped")
+}
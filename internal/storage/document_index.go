@@ -12,16 +12,30 @@ type DocumentIndex struct {
 	mu       sync.RWMutex
 	index    map[string]string // docID -> path mapping
 	metadata map[string]*DocumentMetadata
+	
+	// journal persists changes when the index is opened from disk
+	journal *indexJournal
 }
 
 // DocumentMetadata caches frequently accessed document metadata
 type DocumentMetadata struct {
-	ID         string
-	Path       string
-	Type       string
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	LastAccess time.Time
+	ID         string    `json:"id"`
+	Path       string    `json:"path"`
+	Type       string    `json:"type,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	LastAccess time.Time `json:"-"`
+
+	// ContentHash is the ContentHash of the stored version
+	ContentHash string `json:"content_hash,omitempty"`
+
+	// Version is the content hash of the version that was indexed, Commit
+	// the commit that stored it and IndexedAt when it became queryable.
+	// Indexers records when each registered indexer processed that version.
+	Version   string               `json:"version,omitempty"`
+	Commit    string               `json:"commit,omitempty"`
+	IndexedAt time.Time            `json:"indexed_at,omitempty"`
+	Indexers  map[string]time.Time `json:"indexers,omitempty"`
 }
 
 // NewDocumentIndex creates a new document index
@@ -42,6 +56,7 @@ func (di *DocumentIndex) Add(docID, path string, meta *DocumentMetadata) {
 		meta.LastAccess = time.Now()
 		di.metadata[docID] = meta
 	}
+	di.journal.append(journalEntry{Op: journalPut, ID: docID, Path: path, Meta: meta})
 }
 
 // Get retrieves a document path from the index
@@ -75,8 +90,12 @@ func (di *DocumentIndex) Remove(docID string) {
 	di.mu.Lock()
 	defer di.mu.Unlock()
 	
+	if _, exists := di.index[docID]; !exists {
+		return
+	}
 	delete(di.index, docID)
 	delete(di.metadata, docID)
+	di.journal.append(journalEntry{Op: journalDelete, ID: docID})
 }
 
 // Size returns the number of documents in the index
@@ -94,6 +113,7 @@ func (di *DocumentIndex) Clear() {
 	
 	di.index = make(map[string]string)
 	di.metadata = make(map[string]*DocumentMetadata)
+	di.compactLocked()
 }
 
 // RebuildFromPaths rebuilds the index from a list of file paths
@@ -113,6 +133,7 @@ func (di *DocumentIndex) RebuildFromPaths(paths []string) {
 			di.index[docID] = path
 		}
 	}
+	di.compactLocked()
 }
 
 // GetAllDocuments returns all document metadata in the index
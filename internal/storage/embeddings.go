@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// EmbeddingsFile holds a document's embedding vector as little-endian float32s
+const EmbeddingsFile = "embeddings.bin"
+
+// encodeEmbeddings serializes an embedding vector for storage
+func encodeEmbeddings(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+// decodeEmbeddings parses a vector written by encodeEmbeddings
+func decodeEmbeddings(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid embeddings length: %d bytes", len(data))
+	}
+
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector, nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/rs/zerolog/log"
+)
+
+// eventIndex keeps an in-memory index in step with a storage backend: Load
+// indexes every stored document and Subscribe applies document events from
+// then on. Events may be delivered out of order, so a change older than the
+// last one applied to a document is dropped.
+type eventIndex struct {
+	name    string
+	backend StorageBackend
+
+	// apply indexes doc, or removes it when deleted, and reports whether
+	// it is now indexed. It is called with mu held.
+	apply func(doc *document.Document, deleted bool) bool
+
+	mu       sync.Mutex
+	versions map[string]time.Time
+
+	eventBus     *pipeline.EventBus
+	subscription *pipeline.Subscription
+}
+
+func newEventIndex(name string, backend StorageBackend, apply func(doc *document.Document, deleted bool) bool) *eventIndex {
+	return &eventIndex{
+		name:     name,
+		backend:  backend,
+		apply:    apply,
+		versions: make(map[string]time.Time),
+	}
+}
+
+// Load indexes every stored document and returns the number indexed
+func (e *eventIndex) Load(ctx context.Context) (int, error) {
+	docs, err := e.backend.ListDocuments(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	indexed := 0
+	for _, doc := range docs {
+		if e.update(doc, doc.UpdatedAt, false) {
+			indexed++
+		}
+	}
+
+	log.Info().
+		Int("documents", len(docs)).
+		Int("indexed", indexed).
+		Msgf("%s index loaded", e.name)
+
+	return indexed, nil
+}
+
+// Subscribe keeps the index current from document events on eventBus
+func (e *eventIndex) Subscribe(eventBus *pipeline.EventBus) error {
+	subscription, err := eventBus.Subscribe(
+		[]pipeline.EventType{
+			pipeline.EventDocumentAdded,
+			pipeline.EventDocumentUpdated,
+			pipeline.EventDocumentDeleted,
+		},
+		e.handleEvent,
+		100,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to document events: %w", err)
+	}
+
+	e.eventBus = eventBus
+	e.subscription = subscription
+	return nil
+}
+
+// Close stops listening for document events
+func (e *eventIndex) Close() {
+	if e.subscription != nil {
+		if err := e.eventBus.Unsubscribe(e.subscription.ID); err != nil {
+			log.Warn().Err(err).Msgf("Failed to unsubscribe %s index", e.name)
+		}
+		e.subscription = nil
+	}
+}
+
+func (e *eventIndex) handleEvent(ctx context.Context, event *pipeline.DocumentEvent) error {
+	if event.Document == nil {
+		return nil
+	}
+
+	switch event.Type {
+	case pipeline.EventDocumentAdded, pipeline.EventDocumentUpdated:
+		e.update(event.Document, event.Document.UpdatedAt, false)
+	case pipeline.EventDocumentDeleted:
+		e.update(event.Document, event.Timestamp, true)
+	}
+	return nil
+}
+
+// update applies a change to doc made at version unless a newer change was
+// already applied, and reports whether the document is now indexed
+func (e *eventIndex) update(doc *document.Document, version time.Time, deleted bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if last, ok := e.versions[doc.ID]; ok && version.Before(last) {
+		return false
+	}
+	e.versions[doc.ID] = version
+
+	return e.apply(doc, deleted)
+}
@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+type syncCopyKey struct{}
+
+// withSyncCopy marks ctx as copying a change from one backend to the other.
+// Backends do not publish events for sync copies: the backend the change was
+// made on has already published it.
+func withSyncCopy(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncCopyKey{}, true)
+}
+
+// isSyncCopy reports whether ctx was marked by withSyncCopy
+func isSyncCopy(ctx context.Context) bool {
+	marked, _ := ctx.Value(syncCopyKey{}).(bool)
+	return marked
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/textdiff"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/rs/zerolog/log"
@@ -20,6 +22,12 @@ type GitBackend struct {
 	repo            *git.Repository
 	repoPath        string
 	metricsCollector MetricsCollector
+	
+	// Bus document changes are published on; nil publishes nothing
+	eventBus *pipeline.EventBus
+	
+	// Content hashes of documents stored without one in their metadata
+	legacyHashes contentHashCache
 }
 
 // NewGitBackend creates a new Git-based storage backend
@@ -36,9 +44,17 @@ func NewGitBackend(repoPath string, metrics MetricsCollector) (*GitBackend, erro
 	}, nil
 }
 
+// SetEventBus publishes the backend's document changes on bus
+func (g *GitBackend) SetEventBus(bus *pipeline.EventBus) {
+	g.eventBus = bus
+}
+
 func (g *GitBackend) StoreDocument(ctx context.Context, doc *document.Document) (string, error) {
 	start := time.Now()
 	commitHash, err := g.storeDocumentInGit(ctx, doc)
+	if err == nil {
+		g.publish(ctx, pipeline.NewDocumentEvent(pipeline.EventDocumentAdded, doc), commitHash)
+	}
 	
 	g.recordMetric("store", start, err == nil, err)
 	return commitHash, err
@@ -54,6 +70,35 @@ func (g *GitBackend) GetDocument(ctx context.Context, id string) (*document.Docu
 	return doc, err
 }
 
+func (g *GitBackend) UpdateDocument(ctx context.Context, doc *document.Document) (string, error) {
+	start := time.Now()
+	commitHash, existing, err := g.updateDocumentInGit(ctx, doc)
+	if err == nil {
+		event := pipeline.NewDocumentEvent(pipeline.EventDocumentUpdated, doc)
+		// Describe what changed in the text since the previous version
+		diff := textdiff.Summarize(existing.Content.Text, doc.Content.Text)
+		event.Metadata["diff"] = diff
+		event.Metadata["diff_summary"] = diff.String()
+		g.publish(ctx, event, commitHash)
+	}
+	
+	g.recordMetric("update", start, err == nil, err)
+	return commitHash, err
+}
+
+func (g *GitBackend) DeleteDocument(ctx context.Context, id, reason string) (string, error) {
+	start := time.Now()
+	commitHash, existing, err := g.deleteDocumentInGit(ctx, id, reason)
+	if err == nil {
+		event := pipeline.NewDocumentEvent(pipeline.EventDocumentDeleted, existing)
+		event.Metadata["reason"] = reason
+		g.publish(ctx, event, commitHash)
+	}
+	
+	g.recordMetric("delete", start, err == nil, err)
+	return commitHash, err
+}
+
 func (g *GitBackend) MergeBranch(ctx context.Context, branchName string) error {
 	start := time.Now()
 	err := g.mergeBranchInGit(ctx, branchName)
@@ -65,12 +110,108 @@ func (g *GitBackend) MergeBranch(ctx context.Context, branchName string) error {
 func (g *GitBackend) ListDocuments(ctx context.Context, filters map[string]string) ([]*document.Document, error) {
 	start := time.Now()
 	
-	// For now, return empty slice - this would require walking the git tree
-	// TODO: Implement document listing from git repository
 	documents := []*document.Document{}
+	err := g.walkDocumentDirs(func(docPath string) error {
+		if _, err := os.Stat(filepath.Join(docPath, "metadata.json")); err != nil {
+			return nil
+		}
+		
+		doc, err := g.loadDocumentFromPath(docPath, filepath.Base(docPath))
+		if err != nil || doc == nil {
+			log.Warn().Err(err).Str("path", docPath).Msg("Failed to load document")
+			return nil
+		}
+		
+		if documentMatchesFilters(doc, filters) {
+			documents = append(documents, doc)
+		}
+		return nil
+	})
 	
-	g.recordMetric("list", start, true, nil)
-	return documents, nil
+	g.recordMetric("list", start, err == nil, err)
+	return documents, err
+}
+
+// ListMetadata returns the metadata of every live document, read from its
+// metadata.json. Documents stored before metadata carried a content hash
+// are loaded to compute it.
+func (g *GitBackend) ListMetadata(ctx context.Context) ([]*DocumentMetadata, error) {
+	root := filepath.Join(g.repoPath, "documents")
+	result := []*DocumentMetadata{}
+	err := g.walkDocumentDirs(func(docPath string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		metadataPath := filepath.Join(docPath, "metadata.json")
+		data, err := os.ReadFile(metadataPath)
+		if err != nil {
+			return nil // Deleted documents keep only their tombstone
+		}
+
+		var stored struct {
+			CreatedAt   time.Time `json:"created_at"`
+			UpdatedAt   time.Time `json:"updated_at"`
+			ContentHash string    `json:"content_hash"`
+		}
+		if err := json.Unmarshal(data, &stored); err != nil {
+			log.Warn().Err(err).Str("path", metadataPath).Msg("Failed to parse metadata")
+			return nil
+		}
+
+		id := filepath.Base(docPath)
+		rel, err := filepath.Rel(root, docPath)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(g.repoPath, metadataPath)
+		if err != nil {
+			return err
+		}
+		meta := &DocumentMetadata{
+			ID:          id,
+			Path:        filepath.ToSlash(relPath),
+			Type:        strings.Split(rel, string(filepath.Separator))[0],
+			CreatedAt:   stored.CreatedAt,
+			UpdatedAt:   stored.UpdatedAt,
+			ContentHash: stored.ContentHash,
+		}
+		if meta.ContentHash == "" {
+			hash, ok := g.legacyHashes.get(id, meta.UpdatedAt)
+			if !ok {
+				doc, err := g.loadDocumentFromPath(docPath, id)
+				if err != nil || doc == nil {
+					log.Warn().Err(err).Str("path", docPath).Msg("Failed to load document")
+					return nil
+				}
+				hash = ContentHash(doc)
+				g.legacyHashes.put(id, meta.UpdatedAt, hash)
+			}
+			meta.ContentHash = hash
+		}
+		result = append(result, meta)
+		return nil
+	})
+	return result, err
+}
+
+// ListTombstones returns the tombstones of all deleted documents
+func (g *GitBackend) ListTombstones(ctx context.Context) ([]*Tombstone, error) {
+	tombstones := []*Tombstone{}
+	err := g.walkDocumentDirs(func(docPath string) error {
+		data, err := os.ReadFile(filepath.Join(docPath, TombstoneFile))
+		if err != nil {
+			return nil
+		}
+		
+		var tombstone Tombstone
+		if err := json.Unmarshal(data, &tombstone); err != nil {
+			log.Warn().Err(err).Str("path", docPath).Msg("Failed to parse tombstone")
+			return nil
+		}
+		tombstones = append(tombstones, &tombstone)
+		return nil
+	})
+	return tombstones, err
 }
 
 func (g *GitBackend) Health(ctx context.Context) error {
@@ -85,6 +226,15 @@ func (g *GitBackend) Health(ctx context.Context) error {
 
 // findDocumentByID searches for a document by ID in the git repository
 func (g *GitBackend) findDocumentByID(ctx context.Context, id string) (*document.Document, error) {
+	docPath, err := g.findDocumentPath(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.loadDocumentFromPath(docPath, id)
+}
+
+// findDocumentPath returns the directory holding a live document's files
+func (g *GitBackend) findDocumentPath(id string) (string, error) {
 	// Search in documents directory using common patterns
 	searchPaths := []string{
 		fmt.Sprintf("documents/*/*/*/%s", id),
@@ -99,18 +249,45 @@ func (g *GitBackend) findDocumentByID(ctx context.Context, id string) (*document
 		}
 
 		for _, match := range matches {
-			doc, err := g.loadDocumentFromPath(match, id)
-			if err != nil {
-				log.Warn().Err(err).Str("path", match).Msg("Failed to load document")
-				continue
-			}
-			if doc != nil {
-				return doc, nil
+			// Deleted documents keep only their tombstone
+			if _, err := os.Stat(filepath.Join(match, "metadata.json")); err == nil {
+				return match, nil
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("document not found: %s", id)
+	return "", fmt.Errorf("document not found: %s", id)
+}
+
+// walkDocumentDirs calls fn for every document directory under documents/
+// Paths follow documents/{type}/{YYYY}/{MM}/{id}, so directories four levels
+// below documents/ are document directories
+func (g *GitBackend) walkDocumentDirs(fn func(docPath string) error) error {
+	root := filepath.Join(g.repoPath, "documents")
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if depth := len(strings.Split(rel, string(filepath.Separator))); depth == 4 {
+			if err := fn(path); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
 }
 
 // loadDocumentFromPath loads a document from a filesystem path
@@ -176,6 +353,14 @@ func (g *GitBackend) loadDocumentFromPath(docPath, id string) (*document.Documen
 		doc.Content.Raw = rawBytes
 	}
 
+	// Read embeddings
+	embeddingsPath := filepath.Join(docPath, EmbeddingsFile)
+	if embeddingBytes, err := os.ReadFile(embeddingsPath); err == nil {
+		if embeddings, err := decodeEmbeddings(embeddingBytes); err == nil {
+			doc.Content.Embeddings = embeddings
+		}
+	}
+
 	// Parse content metadata
 	if contentMetadata, ok := metadata["metadata"].(map[string]interface{}); ok {
 		doc.Content.Metadata = make(map[string]string)
@@ -207,47 +392,200 @@ func (g *GitBackend) storeDocumentInGit(ctx context.Context, doc *document.Docum
 		return "", fmt.Errorf("failed to create directory %s: %w", docPath, err)
 	}
 
-	// Write document files (similar to original implementation)
+	if err := g.writeDocumentFiles(docPath, doc); err != nil {
+		return "", err
+	}
+
+	// Add files to git
+	if _, err := w.Add(doc.GitPath()); err != nil {
+		return "", fmt.Errorf("failed to add files: %w", err)
+	}
+
+	// A document stored again under a deleted ID is live once more
+	if err := g.removeTombstones(w, doc.ID); err != nil {
+		return "", err
+	}
+
+	return g.commit(w, fmt.Sprintf("Add document %s", doc.ID))
+}
+
+// removeTombstones stages the removal of the tombstones left by deleting
+// the document with the given ID
+func (g *GitBackend) removeTombstones(w *git.Worktree, id string) error {
+	searchPaths := []string{
+		fmt.Sprintf("documents/*/*/*/%s/%s", id, TombstoneFile),
+		fmt.Sprintf("documents/*/*/%s/%s", id, TombstoneFile),
+		fmt.Sprintf("documents/*/%s/%s", id, TombstoneFile),
+	}
+
+	for _, pattern := range searchPaths {
+		matches, err := filepath.Glob(filepath.Join(g.repoPath, pattern))
+		if err != nil {
+			continue
+		}
+
+		for _, match := range matches {
+			relPath, err := filepath.Rel(g.repoPath, match)
+			if err != nil {
+				return fmt.Errorf("failed to resolve tombstone path: %w", err)
+			}
+			if _, err := w.Remove(relPath); err != nil {
+				return fmt.Errorf("failed to remove tombstone %s: %w", relPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// updateDocumentInGit rewrites an existing document's files in place and
+// returns the version it replaced
+func (g *GitBackend) updateDocumentInGit(ctx context.Context, doc *document.Document) (string, *document.Document, error) {
+	if err := doc.Validate(); err != nil {
+		return "", nil, fmt.Errorf("document validation failed: %w", err)
+	}
+
+	docPath, err := g.findDocumentPath(doc.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	existing, err := g.loadDocumentFromPath(docPath, doc.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	w, err := g.repo.Worktree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = existing.CreatedAt
+	}
+	doc.UpdatedAt = time.Now()
+
+	// Drop content files the new version no longer carries
+	if len(doc.Content.Raw) == 0 {
+		os.Remove(filepath.Join(docPath, "raw"))
+	}
+	if doc.Content.Text == "" {
+		os.Remove(filepath.Join(docPath, "text.txt"))
+	}
+	if len(doc.Content.Embeddings) == 0 {
+		os.Remove(filepath.Join(docPath, EmbeddingsFile))
+	}
+
+	if err := g.writeDocumentFiles(docPath, doc); err != nil {
+		return "", nil, err
+	}
+
+	relPath, err := filepath.Rel(g.repoPath, docPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve document path: %w", err)
+	}
+	if err := w.AddWithOptions(&git.AddOptions{Path: relPath, All: true}); err != nil {
+		return "", nil, fmt.Errorf("failed to add files: %w", err)
+	}
+
+	commitHash, err := g.commit(w, fmt.Sprintf("Update document %s", doc.ID))
+	return commitHash, existing, err
+}
+
+// deleteDocumentInGit removes a document's files and commits a tombstone in
+// their place so the history records what was removed and why. It returns
+// the removed document.
+func (g *GitBackend) deleteDocumentInGit(ctx context.Context, id, reason string) (string, *document.Document, error) {
+	docPath, err := g.findDocumentPath(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	existing, err := g.loadDocumentFromPath(docPath, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	w, err := g.repo.Worktree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	relPath, err := filepath.Rel(g.repoPath, docPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve document path: %w", err)
+	}
+
+	for _, name := range []string{"metadata.json", "text.txt", "raw", EmbeddingsFile} {
+		if _, err := os.Stat(filepath.Join(docPath, name)); err != nil {
+			continue
+		}
+		if _, err := w.Remove(filepath.Join(relPath, name)); err != nil {
+			return "", nil, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	tombstone, err := NewTombstone(existing, reason).Marshal()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal tombstone: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(docPath, TombstoneFile), tombstone, 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write tombstone: %w", err)
+	}
+	if _, err := w.Add(filepath.Join(relPath, TombstoneFile)); err != nil {
+		return "", nil, fmt.Errorf("failed to add tombstone: %w", err)
+	}
+
+	commitHash, err := g.commit(w, deleteCommitMessage(id, reason))
+	return commitHash, existing, err
+}
+
+// writeDocumentFiles writes a document's content and metadata files to docPath
+func (g *GitBackend) writeDocumentFiles(docPath string, doc *document.Document) error {
 	if len(doc.Content.Raw) > 0 {
 		rawPath := filepath.Join(docPath, "raw")
 		if err := os.WriteFile(rawPath, doc.Content.Raw, 0644); err != nil {
-			return "", fmt.Errorf("failed to write raw content: %w", err)
+			return fmt.Errorf("failed to write raw content: %w", err)
 		}
 	}
 
 	if doc.Content.Text != "" {
 		textPath := filepath.Join(docPath, "text.txt")
 		if err := os.WriteFile(textPath, []byte(doc.Content.Text), 0644); err != nil {
-			return "", fmt.Errorf("failed to write text content: %w", err)
+			return fmt.Errorf("failed to write text content: %w", err)
+		}
+	}
+
+	if len(doc.Content.Embeddings) > 0 {
+		embeddingsPath := filepath.Join(docPath, EmbeddingsFile)
+		if err := os.WriteFile(embeddingsPath, encodeEmbeddings(doc.Content.Embeddings), 0644); err != nil {
+			return fmt.Errorf("failed to write embeddings: %w", err)
 		}
 	}
 
-	// Write metadata
 	metadata := map[string]interface{}{
-		"id":         doc.ID,
-		"source":     doc.Source.URL,
-		"created_at": doc.CreatedAt,
-		"updated_at": doc.UpdatedAt,
-		"metadata":   doc.Content.Metadata,
+		"id":           doc.ID,
+		"source":       doc.Source.URL,
+		"created_at":   doc.CreatedAt,
+		"updated_at":   doc.UpdatedAt,
+		"metadata":     doc.Content.Metadata,
+		"content_hash": ContentHash(doc),
 	}
 
 	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
 	metadataPath := filepath.Join(docPath, "metadata.json")
 	if err := os.WriteFile(metadataPath, metadataBytes, 0644); err != nil {
-		return "", fmt.Errorf("failed to write metadata: %w", err)
-	}
-
-	// Add files to git
-	if _, err := w.Add(doc.GitPath()); err != nil {
-		return "", fmt.Errorf("failed to add files: %w", err)
+		return fmt.Errorf("failed to write metadata: %w", err)
 	}
+	return nil
+}
 
-	// Create commit
-	commit, err := w.Commit(fmt.Sprintf("Add document %s", doc.ID), &git.CommitOptions{
+// commit records the staged changes with the library's signature
+func (g *GitBackend) commit(w *git.Worktree, message string) (string, error) {
+	commit, err := w.Commit(message, &git.CommitOptions{
 		Author: &object.Signature{
 			Name:  "Caia Library",
 			Email: "library@caiatech.com",
@@ -268,6 +606,19 @@ func (g *GitBackend) mergeBranchInGit(ctx context.Context, branchName string) er
 	return nil
 }
 
+// publish emits a document change committed as commitHash, unless ctx is a
+// sync copy from the other backend
+func (g *GitBackend) publish(ctx context.Context, event *pipeline.DocumentEvent, commitHash string) {
+	if g.eventBus == nil || isSyncCopy(ctx) {
+		return
+	}
+	event.Metadata["commit_hash"] = commitHash
+	event.Metadata["backend"] = "git"
+	if err := g.eventBus.Publish(event); err != nil {
+		log.Warn().Err(err).Str("document_id", event.Document.ID).Msg("Failed to publish document event")
+	}
+}
+
 func (g *GitBackend) recordMetric(operation string, start time.Time, success bool, err error) {
 	if g.metricsCollector != nil {
 		g.metricsCollector.RecordMetric(StorageMetrics{
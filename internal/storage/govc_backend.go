@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Caia-Tech/caia-library/internal/pipeline"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/textdiff"
 	"github.com/caiatech/govc"
 	"github.com/rs/zerolog/log"
 )
@@ -26,13 +28,23 @@ type GovcBackend struct {
 	
 	// Event bus for real-time processing
 	eventBus         *pipeline.EventBus
+	
+	// writeMu serializes commits with their index updates so a checkpoint
+	// never claims a commit whose index entry has not been written yet
+	writeMu          sync.Mutex
+	indexWrites      int
+	
+	// Content hashes of documents indexed without one
+	legacyHashes     contentHashCache
 }
 
 // GovcConfig holds configuration for govc
 type GovcConfig struct {
-	MemoryMode bool          // Use pure memory mode
-	Path       string        // Repository path (":memory:" for in-memory)
-	Timeout    time.Duration // Operation timeout
+	MemoryMode   bool          // Use pure memory mode
+	Path         string        // Repository path (":memory:" for in-memory)
+	Timeout      time.Duration // Operation timeout
+	IndexPath    string        // Directory for the persisted document index (empty keeps it in memory)
+	EventLogPath string        // Directory for the durable event log (empty keeps events in memory)
 }
 
 // NewGovcBackend creates a new govc-based storage backend
@@ -95,12 +107,44 @@ func NewGovcBackendWithConfig(repoName string, config *GovcConfig, metrics Metri
 		}
 	}
 
+	return newGovcBackendForRepo(repo, repoName, repoPath, config, metrics)
+}
+
+// newGovcBackendForRepo wraps an opened repository and loads its document index
+func newGovcBackendForRepo(repo *govc.Repository, repoName, repoPath string, config *GovcConfig, metrics MetricsCollector) (*GovcBackend, error) {
+	var eventBus *pipeline.EventBus
+	if config.EventLogPath != "" {
+		// Persist events so subscribers pick up where they left off after a restart
+		durableBus, err := pipeline.NewDurableEventBus(pipeline.DefaultDurableConfig(filepath.Join(config.EventLogPath, repoName)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open event log: %w", err)
+		}
+		eventBus = durableBus
+	} else {
+		eventBus = pipeline.NewEventBus(1000, 4) // Large buffer, 4 workers
+	}
+	
 	backend := &GovcBackend{
 		repo:             repo,
 		repoPath:         repoPath,
 		metricsCollector: metrics,
 		docIndex:         NewDocumentIndex(),
-		eventBus:         pipeline.NewEventBus(1000, 4), // Large buffer, 4 workers
+		eventBus:         eventBus,
+	}
+	
+	if config.IndexPath != "" {
+		// Load the persisted index and replay only what changed since its checkpoint
+		index, checkpoint, err := OpenDocumentIndex(filepath.Join(config.IndexPath, repoName))
+		if err != nil {
+			eventBus.Close()
+			return nil, fmt.Errorf("failed to open document index: %w", err)
+		}
+		backend.docIndex = index
+		
+		if err := backend.loadIndex(checkpoint); err != nil {
+			log.Warn().Err(err).Msg("Failed to load persisted document index")
+		}
+		return backend, nil
 	}
 	
 	// Build initial index from existing documents
@@ -123,34 +167,32 @@ func (g *GovcBackend) StoreDocument(ctx context.Context, doc *document.Document)
 	// Prepare document paths
 	docPath := doc.GitPath()
 	
-	// Store document metadata as JSON
-	metadata, err := json.Marshal(map[string]interface{}{
-		"id":         doc.ID,
-		"source":     doc.Source,
-		"created_at": doc.CreatedAt,
-		"updated_at": doc.UpdatedAt,
-		"metadata":   doc.Content.Metadata,
-	})
+	files, err := g.documentFiles(docPath, doc)
 	if err != nil {
 		g.recordMetric("store", start, false, err)
-		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+		return "", err
 	}
 	
-	// Use govc's atomic multi-file update for efficiency
-	files := make(map[string][]byte)
-	files[fmt.Sprintf("%s/metadata.json", docPath)] = metadata
-	files[fmt.Sprintf("%s/content.txt", docPath)] = []byte(doc.Content.Text)
+	g.writeMu.Lock()
 	
-	if len(doc.Content.Raw) > 0 {
-		files[fmt.Sprintf("%s/raw", docPath)] = doc.Content.Raw
-	}
+	// A document stored again under a deleted ID is live once more
+	tombstonePath := fmt.Sprintf("%s/%s", docPath, TombstoneFile)
+	_, tombstoneErr := g.repo.ReadFile(tombstonePath)
 	
 	// Perform atomic commit with all files
-	commit, err := g.repo.AtomicMultiFileUpdate(
-		files,
-		fmt.Sprintf("Add document %s", doc.ID),
-	)
+	commit, err := g.repo.AtomicCommit(fmt.Sprintf("Add document %s", doc.ID), func(txn *govc.AtomicTransaction) error {
+		for path, content := range files {
+			if err := txn.AtomicFileUpdate(path, content); err != nil {
+				return err
+			}
+		}
+		if tombstoneErr == nil {
+			return txn.AtomicFileDelete(tombstonePath)
+		}
+		return nil
+	})
 	if err != nil {
+		g.writeMu.Unlock()
 		g.recordMetric("store", start, false, err)
 		return "", fmt.Errorf("failed to store document: %w", err)
 	}
@@ -158,14 +200,9 @@ func (g *GovcBackend) StoreDocument(ctx context.Context, doc *document.Document)
 	commitHash := commit.Hash()
 	
 	// Add to index for fast retrieval
-	metadataPath := fmt.Sprintf("%s/metadata.json", docPath)
-	g.docIndex.Add(doc.ID, metadataPath, &DocumentMetadata{
-		ID:        doc.ID,
-		Path:      metadataPath,
-		Type:      doc.Source.Type,
-		CreatedAt: doc.CreatedAt,
-		UpdatedAt: doc.UpdatedAt,
-	})
+	g.indexDocument(docPath, doc)
+	g.indexCommitted(commitHash)
+	g.writeMu.Unlock()
 	
 	log.Debug().
 		Str("document_id", doc.ID).
@@ -177,8 +214,10 @@ func (g *GovcBackend) StoreDocument(ctx context.Context, doc *document.Document)
 	event.Metadata["commit_hash"] = commitHash
 	event.Metadata["backend"] = "govc"
 	
-	if err := g.eventBus.Publish(event); err != nil {
-		log.Warn().Err(err).Str("document_id", doc.ID).Msg("Failed to publish document event")
+	if !isSyncCopy(ctx) {
+		if err := g.eventBus.Publish(event); err != nil {
+			log.Warn().Err(err).Str("document_id", doc.ID).Msg("Failed to publish document event")
+		}
 	}
 	
 	g.recordMetric("store", start, true, nil)
@@ -301,10 +340,173 @@ func (g *GovcBackend) GetDocument(ctx context.Context, id string) (*document.Doc
 		doc.Content.Raw = rawBytes
 	}
 	
+	// Read embeddings if exist
+	embeddingsPath := filepath.Join(docDir, EmbeddingsFile)
+	if embeddingBytes, err := g.repo.ReadFile(embeddingsPath); err == nil {
+		if embeddings, err := decodeEmbeddings(embeddingBytes); err == nil {
+			doc.Content.Embeddings = embeddings
+		}
+	}
+	
 	g.recordMetric("get", start, true, nil)
 	return doc, nil
 }
 
+// UpdateDocument replaces the content and metadata of an existing document
+// in place, keeping it at the path it was originally stored under
+func (g *GovcBackend) UpdateDocument(ctx context.Context, doc *document.Document) (string, error) {
+	start := time.Now()
+	
+	if err := doc.Validate(); err != nil {
+		g.recordMetric("update", start, false, err)
+		return "", fmt.Errorf("document validation failed: %w", err)
+	}
+	
+	existing, err := g.GetDocument(ctx, doc.ID)
+	if err != nil {
+		g.recordMetric("update", start, false, err)
+		return "", err
+	}
+	
+	metadataPath, _ := g.docIndex.Get(doc.ID)
+	docPath := filepath.Dir(metadataPath)
+	
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = existing.CreatedAt
+	}
+	doc.UpdatedAt = time.Now()
+	
+	files, err := g.documentFiles(docPath, doc)
+	if err != nil {
+		g.recordMetric("update", start, false, err)
+		return "", err
+	}
+	
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	
+	commit, err := g.repo.AtomicCommit(fmt.Sprintf("Update document %s", doc.ID), func(txn *govc.AtomicTransaction) error {
+		for path, content := range files {
+			if err := txn.AtomicFileUpdate(path, content); err != nil {
+				return err
+			}
+		}
+		// Drop optional files that the new version no longer carries
+		rawPath := fmt.Sprintf("%s/raw", docPath)
+		if _, ok := files[rawPath]; !ok && len(existing.Content.Raw) > 0 {
+			if err := txn.AtomicFileDelete(rawPath); err != nil {
+				return err
+			}
+		}
+		embeddingsPath := fmt.Sprintf("%s/%s", docPath, EmbeddingsFile)
+		if _, ok := files[embeddingsPath]; !ok && len(existing.Content.Embeddings) > 0 {
+			return txn.AtomicFileDelete(embeddingsPath)
+		}
+		return nil
+	})
+	if err != nil {
+		g.recordMetric("update", start, false, err)
+		return "", fmt.Errorf("failed to update document: %w", err)
+	}
+	
+	commitHash := commit.Hash()
+	g.indexDocument(docPath, doc)
+	g.indexCommitted(commitHash)
+	
+	log.Debug().
+		Str("document_id", doc.ID).
+		Str("commit", commitHash).
+		Msg("Document updated in govc repository")
+	
+	event := pipeline.NewDocumentEvent(pipeline.EventDocumentUpdated, doc)
+	event.Metadata["commit_hash"] = commitHash
+	event.Metadata["backend"] = "govc"
+	
+	// Describe what changed in the text since the previous version
+	diff := textdiff.Summarize(existing.Content.Text, doc.Content.Text)
+	event.Metadata["diff"] = diff
+	event.Metadata["diff_summary"] = diff.String()
+	
+	if !isSyncCopy(ctx) {
+		if err := g.eventBus.Publish(event); err != nil {
+			log.Warn().Err(err).Str("document_id", doc.ID).Msg("Failed to publish document event")
+		}
+	}
+	
+	g.recordMetric("update", start, true, nil)
+	return commitHash, nil
+}
+
+// DeleteDocument removes a document's files and leaves a tombstone in its
+// directory, so the repository history records what was removed and why
+func (g *GovcBackend) DeleteDocument(ctx context.Context, id, reason string) (string, error) {
+	start := time.Now()
+	
+	existing, err := g.GetDocument(ctx, id)
+	if err != nil {
+		g.recordMetric("delete", start, false, err)
+		return "", err
+	}
+	
+	metadataPath, _ := g.docIndex.Get(id)
+	docPath := filepath.Dir(metadataPath)
+	
+	tombstone, err := NewTombstone(existing, reason).Marshal()
+	if err != nil {
+		g.recordMetric("delete", start, false, err)
+		return "", fmt.Errorf("failed to marshal tombstone: %w", err)
+	}
+	
+	// Only files that are actually present can be deleted in the transaction
+	removed := make([]string, 0, 4)
+	for _, name := range []string{"metadata.json", "content.txt", "raw", EmbeddingsFile} {
+		path := fmt.Sprintf("%s/%s", docPath, name)
+		if _, err := g.repo.ReadFile(path); err == nil {
+			removed = append(removed, path)
+		}
+	}
+	
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	
+	commit, err := g.repo.AtomicCommit(deleteCommitMessage(id, reason), func(txn *govc.AtomicTransaction) error {
+		for _, path := range removed {
+			if err := txn.AtomicFileDelete(path); err != nil {
+				return err
+			}
+		}
+		return txn.AtomicFileUpdate(fmt.Sprintf("%s/%s", docPath, TombstoneFile), tombstone)
+	})
+	if err != nil {
+		g.recordMetric("delete", start, false, err)
+		return "", fmt.Errorf("failed to delete document: %w", err)
+	}
+	
+	commitHash := commit.Hash()
+	g.docIndex.Remove(id)
+	g.indexCommitted(commitHash)
+	
+	log.Debug().
+		Str("document_id", id).
+		Str("commit", commitHash).
+		Str("reason", reason).
+		Msg("Document deleted from govc repository")
+	
+	event := pipeline.NewDocumentEvent(pipeline.EventDocumentDeleted, existing)
+	event.Metadata["commit_hash"] = commitHash
+	event.Metadata["backend"] = "govc"
+	event.Metadata["reason"] = reason
+	
+	if !isSyncCopy(ctx) {
+		if err := g.eventBus.Publish(event); err != nil {
+			log.Warn().Err(err).Str("document_id", id).Msg("Failed to publish document event")
+		}
+	}
+	
+	g.recordMetric("delete", start, true, nil)
+	return commitHash, nil
+}
+
 func (g *GovcBackend) MergeBranch(ctx context.Context, branchName string) error {
 	start := time.Now()
 	
@@ -374,43 +576,73 @@ func (g *GovcBackend) ListDocuments(ctx context.Context, filters map[string]stri
 			continue
 		}
 		
-		// Apply filters
-		matches := true
-		for key, value := range filters {
-			switch key {
-			case "type":
-				if source, ok := metadata["source"].(map[string]interface{}); ok {
-					if docType, ok := source["type"].(string); ok {
-						if docType != value {
-							matches = false
-						}
-					}
-				}
-			case "source":
-				if source, ok := metadata["source"].(map[string]interface{}); ok {
-					if url, ok := source["url"].(string); ok {
-						if url != value {
-							matches = false
-						}
-					}
+		// Load full document and apply filters
+		doc, err := g.GetDocument(ctx, id)
+		if err == nil && doc != nil && documentMatchesFilters(doc, filters) {
+			documents = append(documents, doc)
+		}
+	}
+	
+	g.recordMetric("list", start, true, nil)
+	return documents, nil
+}
+
+// ListMetadata returns the index metadata of every stored document. Entries
+// indexed before stored metadata carried a content hash are completed from
+// the document.
+func (g *GovcBackend) ListMetadata(ctx context.Context) ([]*DocumentMetadata, error) {
+	entries := g.docIndex.GetAllDocuments()
+	result := make([]*DocumentMetadata, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		meta := *entry
+		if meta.ContentHash == "" || meta.CreatedAt.IsZero() {
+			if hash, ok := g.legacyHashes.get(meta.ID, meta.UpdatedAt); ok && !meta.CreatedAt.IsZero() {
+				meta.ContentHash = hash
+			} else {
+				doc, err := g.GetDocument(ctx, meta.ID)
+				if err != nil {
+					continue // Deleted since it was listed
 				}
+				meta.Type, meta.CreatedAt, meta.UpdatedAt = doc.Source.Type, doc.CreatedAt, doc.UpdatedAt
+				meta.ContentHash = ContentHash(doc)
+				g.legacyHashes.put(meta.ID, meta.UpdatedAt, meta.ContentHash)
 			}
-			if !matches {
-				break
-			}
+		}
+		result = append(result, &meta)
+	}
+	return result, nil
+}
+
+// ListTombstones returns the tombstones of all deleted documents
+func (g *GovcBackend) ListTombstones(ctx context.Context) ([]*Tombstone, error) {
+	allFiles, err := g.repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	
+	tombstones := []*Tombstone{}
+	for _, file := range allFiles {
+		if filepath.Base(file) != TombstoneFile || !strings.HasPrefix(file, "documents/") {
+			continue
 		}
 		
-		if matches {
-			// Load full document
-			doc, err := g.GetDocument(ctx, id)
-			if err == nil && doc != nil {
-				documents = append(documents, doc)
-			}
+		data, err := g.repo.ReadFile(file)
+		if err != nil {
+			continue
 		}
+		
+		var tombstone Tombstone
+		if err := json.Unmarshal(data, &tombstone); err != nil {
+			log.Warn().Err(err).Str("path", file).Msg("Failed to parse tombstone")
+			continue
+		}
+		tombstones = append(tombstones, &tombstone)
 	}
 	
-	g.recordMetric("list", start, true, nil)
-	return documents, nil
+	return tombstones, nil
 }
 
 func (g *GovcBackend) Health(ctx context.Context) error {
@@ -458,6 +690,47 @@ func (g *GovcBackend) recordMetric(operation string, start time.Time, success bo
 	}
 }
 
+// documentFiles builds the set of files that make up a stored document
+func (g *GovcBackend) documentFiles(docPath string, doc *document.Document) (map[string][]byte, error) {
+	// Store document metadata as JSON
+	metadata, err := json.Marshal(map[string]interface{}{
+		"id":           doc.ID,
+		"source":       doc.Source,
+		"created_at":   doc.CreatedAt,
+		"updated_at":   doc.UpdatedAt,
+		"metadata":     doc.Content.Metadata,
+		"content_hash": ContentHash(doc),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	
+	files := make(map[string][]byte)
+	files[fmt.Sprintf("%s/metadata.json", docPath)] = metadata
+	files[fmt.Sprintf("%s/content.txt", docPath)] = []byte(doc.Content.Text)
+	
+	if len(doc.Content.Raw) > 0 {
+		files[fmt.Sprintf("%s/raw", docPath)] = doc.Content.Raw
+	}
+	if len(doc.Content.Embeddings) > 0 {
+		files[fmt.Sprintf("%s/%s", docPath, EmbeddingsFile)] = encodeEmbeddings(doc.Content.Embeddings)
+	}
+	return files, nil
+}
+
+// indexDocument records a stored document in the document index
+func (g *GovcBackend) indexDocument(docPath string, doc *document.Document) {
+	metadataPath := fmt.Sprintf("%s/metadata.json", docPath)
+	g.docIndex.Add(doc.ID, metadataPath, &DocumentMetadata{
+		ID:          doc.ID,
+		Path:        metadataPath,
+		Type:        doc.Source.Type,
+		CreatedAt:   doc.CreatedAt,
+		UpdatedAt:   doc.UpdatedAt,
+		ContentHash: ContentHash(doc),
+	})
+}
+
 // buildIndex builds the document index from existing repository files
 func (g *GovcBackend) buildIndex() error {
 	// List all files in the repository
@@ -471,12 +744,15 @@ func (g *GovcBackend) buildIndex() error {
 	for _, file := range allFiles {
 		if filepath.Base(file) == "metadata.json" && strings.HasPrefix(file, "documents/") {
 			// Extract document ID from path
-			dir := filepath.Dir(file)
-			parts := strings.Split(dir, "/")
-			if len(parts) > 0 {
-				docID := parts[len(parts)-1]
-				g.docIndex.Add(docID, file, nil)
+			docID := extractDocIDFromPath(file)
+			if docID == "" {
+				continue
+			}
+			meta, err := g.readIndexMetadata(file)
+			if err != nil {
+				meta = nil
 			}
+			g.docIndex.Add(docID, file, meta)
 		}
 	}
 	
@@ -498,9 +774,15 @@ func (g *GovcBackend) GetDocumentIndex() *DocumentIndex {
 	return g.docIndex
 }
 
-// Close closes the backend and event bus
+// Close closes the backend and event bus, checkpointing a persisted index
 func (g *GovcBackend) Close() {
 	if g.eventBus != nil {
 		g.eventBus.Close()
 	}
+	if g.docIndex.IsPersistent() {
+		g.checkpointIndex()
+		if err := g.docIndex.Close(); err != nil {
+			log.Warn().Err(err).Msg("Failed to close document index")
+		}
+	}
 }
\ No newline at end of file
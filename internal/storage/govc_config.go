@@ -22,6 +22,14 @@ func GetGovcConfig() *GovcConfig {
 		}
 	}
 
+	if indexPath := os.Getenv("GOVC_INDEX_PATH"); indexPath != "" {
+		config.IndexPath = indexPath
+	}
+
+	if eventLogPath := os.Getenv("GOVC_EVENT_LOG_PATH"); eventLogPath != "" {
+		config.EventLogPath = eventLogPath
+	}
+
 	if timeout := os.Getenv("GOVC_TIMEOUT"); timeout != "" {
 		if d, err := time.ParseDuration(timeout); err == nil {
 			config.Timeout = d
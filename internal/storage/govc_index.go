@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// indexCheckpointInterval is the number of committed writes between
+// checkpoints of a persisted document index
+const indexCheckpointInterval = 500
+
+// IndexConsistencyReport describes differences between the document index
+// and the documents actually present in the repository
+type IndexConsistencyReport struct {
+	CheckedAt        time.Time `json:"checked_at"`
+	IndexedCount     int       `json:"indexed_count"`
+	RepositoryCount  int       `json:"repository_count"`
+	MissingFromIndex []string  `json:"missing_from_index"` // in the repository but not indexed
+	StaleInIndex     []string  `json:"stale_in_index"`     // indexed but no longer in the repository
+	PathMismatches   []string  `json:"path_mismatches"`    // indexed under a different path
+}
+
+// Consistent reports whether the index matches the repository exactly
+func (r *IndexConsistencyReport) Consistent() bool {
+	return len(r.MissingFromIndex) == 0 && len(r.StaleInIndex) == 0 && len(r.PathMismatches) == 0
+}
+
+// loadIndex brings a persisted index up to date with the repository. With a
+// checkpoint only the files changed in commits made since it are replayed;
+// without one, or when the checkpoint commit is unknown, the index is rebuilt.
+func (g *GovcBackend) loadIndex(checkpoint *IndexCheckpoint) error {
+	head, err := g.repo.CurrentCommit()
+	if err != nil {
+		// An empty repository has nothing to index, whatever the journal says
+		g.docIndex.Clear()
+		return nil
+	}
+	headHash := head.Hash()
+
+	if checkpoint != nil && checkpoint.Commit != "" {
+		if checkpoint.Commit == headHash {
+			log.Info().
+				Int("indexed_documents", g.docIndex.Size()).
+				Msg("Document index is current with repository")
+			return nil
+		}
+
+		replayed, err := g.replayIndexSince(checkpoint.Commit)
+		if err == nil {
+			log.Info().
+				Str("checkpoint", checkpoint.Commit).
+				Str("head", headHash).
+				Int("changed_paths", replayed).
+				Int("indexed_documents", g.docIndex.Size()).
+				Msg("Replayed commits since index checkpoint")
+			return g.docIndex.Checkpoint(headHash)
+		}
+		log.Warn().Err(err).Str("checkpoint", checkpoint.Commit).Msg("Cannot replay from index checkpoint, rebuilding")
+	}
+
+	g.docIndex.Clear()
+	if err := g.buildIndex(); err != nil {
+		return err
+	}
+	return g.docIndex.Checkpoint(headHash)
+}
+
+// replayIndexSince applies the document changes between commit and HEAD to
+// the index and returns the number of changed paths examined
+func (g *GovcBackend) replayIndexSince(commit string) (int, error) {
+	diff, err := g.repo.Diff(commit, "HEAD", "name-only")
+	if err != nil {
+		return 0, fmt.Errorf("failed to diff against checkpoint: %w", err)
+	}
+
+	changed := 0
+	for _, path := range strings.Split(diff, "\n") {
+		if !strings.HasPrefix(path, "documents/") {
+			continue
+		}
+		changed++
+
+		// Deletions show up as a metadata.json that can no longer be read
+		if filepath.Base(path) != "metadata.json" {
+			continue
+		}
+		docID := extractDocIDFromPath(path)
+		if docID == "" {
+			continue
+		}
+		meta, err := g.readIndexMetadata(path)
+		if err == nil {
+			g.docIndex.Add(docID, path, meta)
+		} else if indexed, ok := g.docIndex.Get(docID); ok && indexed == path {
+			g.docIndex.Remove(docID)
+		}
+	}
+	return changed, nil
+}
+
+// readIndexMetadata builds index metadata from a stored metadata.json file
+func (g *GovcBackend) readIndexMetadata(metadataPath string) (*DocumentMetadata, error) {
+	data, err := g.repo.ReadFile(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored struct {
+		ID     string `json:"id"`
+		Source struct {
+			Type string `json:"type"`
+		} `json:"source"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+		ContentHash string    `json:"content_hash"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	return &DocumentMetadata{
+		ID:          extractDocIDFromPath(metadataPath),
+		Path:        metadataPath,
+		Type:        stored.Source.Type,
+		CreatedAt:   stored.CreatedAt,
+		UpdatedAt:   stored.UpdatedAt,
+		ContentHash: stored.ContentHash,
+	}, nil
+}
+
+// indexCommitted counts a committed write and checkpoints a persisted index
+// every indexCheckpointInterval writes. Callers must hold writeMu.
+func (g *GovcBackend) indexCommitted(commitHash string) {
+	if !g.docIndex.IsPersistent() {
+		return
+	}
+
+	g.indexWrites++
+	if g.indexWrites%indexCheckpointInterval != 0 {
+		return
+	}
+	if err := g.docIndex.Checkpoint(commitHash); err != nil {
+		log.Warn().Err(err).Str("commit", commitHash).Msg("Failed to checkpoint document index")
+	}
+}
+
+// checkpointIndex records the current HEAD as the persisted index checkpoint
+func (g *GovcBackend) checkpointIndex() {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	head, err := g.repo.CurrentCommit()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve HEAD for index checkpoint")
+		return
+	}
+	if err := g.docIndex.Checkpoint(head.Hash()); err != nil {
+		log.Warn().Err(err).Msg("Failed to checkpoint document index")
+	}
+}
+
+// VerifyIndex compares the document index against the metadata files in the
+// repository without modifying either
+func (g *GovcBackend) VerifyIndex() (*IndexConsistencyReport, error) {
+	allFiles, err := g.repo.ListFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	inRepo := make(map[string]string)
+	for _, file := range allFiles {
+		if filepath.Base(file) == "metadata.json" && strings.HasPrefix(file, "documents/") {
+			if docID := extractDocIDFromPath(file); docID != "" {
+				inRepo[docID] = file
+			}
+		}
+	}
+
+	report := &IndexConsistencyReport{
+		CheckedAt:        time.Now(),
+		RepositoryCount:  len(inRepo),
+		MissingFromIndex: []string{},
+		StaleInIndex:     []string{},
+		PathMismatches:   []string{},
+	}
+
+	indexed := g.docIndex.GetAllDocumentIDs()
+	report.IndexedCount = len(indexed)
+
+	for _, docID := range indexed {
+		indexedPath, _ := g.docIndex.Get(docID)
+		repoPath, ok := inRepo[docID]
+		switch {
+		case !ok:
+			report.StaleInIndex = append(report.StaleInIndex, docID)
+		case repoPath != indexedPath:
+			report.PathMismatches = append(report.PathMismatches, docID)
+		}
+	}
+	for docID := range inRepo {
+		if _, ok := g.docIndex.Get(docID); !ok {
+			report.MissingFromIndex = append(report.MissingFromIndex, docID)
+		}
+	}
+
+	sort.Strings(report.MissingFromIndex)
+	sort.Strings(report.StaleInIndex)
+	sort.Strings(report.PathMismatches)
+	return report, nil
+}
+
+// RebuildIndex discards the document index and rebuilds it from the repository
+func (g *GovcBackend) RebuildIndex() error {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+
+	g.docIndex.Clear()
+	if err := g.buildIndex(); err != nil {
+		return err
+	}
+
+	if g.docIndex.IsPersistent() {
+		head, err := g.repo.CurrentCommit()
+		if err != nil {
+			return fmt.Errorf("failed to get current commit: %w", err)
+		}
+		return g.docIndex.Checkpoint(head.Hash())
+	}
+	return nil
+}
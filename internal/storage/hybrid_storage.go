@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
 	"github.com/Caia-Tech/caia-library/pkg/document"
 	"github.com/rs/zerolog/log"
 )
@@ -26,6 +27,12 @@ type HybridStorageConfig struct {
 	
 	// Sync interval for background synchronization
 	SyncInterval time.Duration `json:"sync_interval"`
+	
+	// Directory for the persisted govc document index (empty keeps it in memory)
+	IndexPath string `json:"index_path,omitempty"`
+	
+	// Directory for the durable govc event log (empty keeps events in memory)
+	EventLogPath string `json:"event_log_path,omitempty"`
 }
 
 // DefaultHybridConfig returns sensible defaults for hybrid storage
@@ -50,6 +57,11 @@ type HybridStorage struct {
 	syncTicker *time.Ticker
 	syncStop   chan bool
 	syncMutex  sync.RWMutex
+	
+	// Results of the most recent reconciliation run
+	syncStatsMu sync.RWMutex
+	lastSync    *SyncReport
+	syncRuns    int64
 }
 
 // NewHybridStorage creates a new hybrid storage system
@@ -63,10 +75,22 @@ func NewHybridStorage(gitRepoPath, govcRepoName string, config *HybridStorageCon
 		return nil, fmt.Errorf("failed to initialize git backend: %w", err)
 	}
 
-	govcBackend, err := NewGovcBackend(govcRepoName, metrics)
+	govcConfig := GetGovcConfig()
+	if config.IndexPath != "" {
+		govcConfig.IndexPath = config.IndexPath
+	}
+	if config.EventLogPath != "" {
+		govcConfig.EventLogPath = config.EventLogPath
+	}
+
+	govcBackend, err := NewGovcBackendWithConfig(govcRepoName, govcConfig, metrics)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize govc backend: %w", err)
 	}
+	
+	// Both backends publish on one bus, so changes are seen whichever
+	// backend is primary
+	gitBackend.SetEventBus(govcBackend.GetEventBus())
 
 	hs := &HybridStorage{
 		govcBackend:      govcBackend,
@@ -157,6 +181,80 @@ func (h *HybridStorage) GetDocument(ctx context.Context, id string) (*document.D
 	return doc, err
 }
 
+// UpdateDocument updates a document using the hybrid strategy
+func (h *HybridStorage) UpdateDocument(ctx context.Context, doc *document.Document) (string, error) {
+	start := time.Now()
+	
+	timeoutCtx, cancel := context.WithTimeout(ctx, h.config.OperationTimeout)
+	defer cancel()
+
+	primaryBackend := h.getPrimaryBackend()
+	secondaryBackend := h.getSecondaryBackend()
+
+	commitHash, err := primaryBackend.UpdateDocument(timeoutCtx, doc)
+	if err != nil && h.config.EnableFallback {
+		log.Warn().
+			Err(err).
+			Str("document_id", doc.ID).
+			Str("primary", h.config.PrimaryBackend).
+			Msg("Primary backend update failed, trying fallback")
+		
+		commitHash, err = secondaryBackend.UpdateDocument(timeoutCtx, doc)
+		if err == nil {
+			h.recordHybridMetric("update", start, true, "fallback_success")
+		} else {
+			h.recordHybridMetric("update", start, false, "both_failed")
+		}
+	} else if err == nil {
+		h.recordHybridMetric("update", start, true, "primary_success")
+	} else {
+		h.recordHybridMetric("update", start, false, "primary_failed_no_fallback")
+	}
+
+	if err == nil && h.config.EnableSync {
+		go h.backgroundUpdateSync(doc, secondaryBackend)
+	}
+
+	return commitHash, err
+}
+
+// DeleteDocument deletes a document using the hybrid strategy
+func (h *HybridStorage) DeleteDocument(ctx context.Context, id, reason string) (string, error) {
+	start := time.Now()
+	
+	timeoutCtx, cancel := context.WithTimeout(ctx, h.config.OperationTimeout)
+	defer cancel()
+
+	primaryBackend := h.getPrimaryBackend()
+	secondaryBackend := h.getSecondaryBackend()
+
+	commitHash, err := primaryBackend.DeleteDocument(timeoutCtx, id, reason)
+	if err != nil && h.config.EnableFallback {
+		log.Warn().
+			Err(err).
+			Str("document_id", id).
+			Str("primary", h.config.PrimaryBackend).
+			Msg("Primary backend delete failed, trying fallback")
+		
+		commitHash, err = secondaryBackend.DeleteDocument(timeoutCtx, id, reason)
+		if err == nil {
+			h.recordHybridMetric("delete", start, true, "fallback_success")
+		} else {
+			h.recordHybridMetric("delete", start, false, "both_failed")
+		}
+	} else if err == nil {
+		h.recordHybridMetric("delete", start, true, "primary_success")
+	} else {
+		h.recordHybridMetric("delete", start, false, "primary_failed_no_fallback")
+	}
+
+	if err == nil && h.config.EnableSync {
+		go h.backgroundDeleteSync(id, reason, secondaryBackend)
+	}
+
+	return commitHash, err
+}
+
 // MergeBranch merges a branch using the hybrid strategy
 func (h *HybridStorage) MergeBranch(ctx context.Context, branchName string) error {
 	start := time.Now()
@@ -209,6 +307,24 @@ func (h *HybridStorage) ListDocuments(ctx context.Context, filters map[string]st
 	return documents, err
 }
 
+// ListMetadata lists the index metadata of the documents ListDocuments
+// returns, without loading the documents themselves
+func (h *HybridStorage) ListMetadata(ctx context.Context) ([]*DocumentMetadata, error) {
+	start := time.Now()
+	
+	timeoutCtx, cancel := context.WithTimeout(ctx, h.config.OperationTimeout)
+	defer cancel()
+
+	entries, err := listMetadata(timeoutCtx, h.getPrimaryBackend())
+	if err == nil {
+		h.recordHybridMetric("list_metadata", start, true, "primary_success")
+	} else {
+		h.recordHybridMetric("list_metadata", start, false, "primary_failed")
+	}
+
+	return entries, err
+}
+
 // Health checks the health of both backends
 func (h *HybridStorage) Health(ctx context.Context) error {
 	start := time.Now()
@@ -245,9 +361,27 @@ func (h *HybridStorage) GetStats() map[string]interface{} {
 		stats["govc"] = govcBackend.GetMemoryStats()
 	}
 
+	h.syncStatsMu.RLock()
+	stats["sync"] = map[string]interface{}{
+		"enabled":  h.config.EnableSync,
+		"interval": h.config.SyncInterval.String(),
+		"runs":     h.syncRuns,
+		"last_run": h.lastSync,
+	}
+	h.syncStatsMu.RUnlock()
+
 	return stats
 }
 
+// GetEventBus returns the bus both backends publish document changes on,
+// or nil when the govc backend does not provide one
+func (h *HybridStorage) GetEventBus() *pipeline.EventBus {
+	if govcBackend, ok := h.govcBackend.(*GovcBackend); ok {
+		return govcBackend.GetEventBus()
+	}
+	return nil
+}
+
 // Close stops background sync and cleans up resources
 func (h *HybridStorage) Close() error {
 	if h.syncTicker != nil {
@@ -255,6 +389,11 @@ func (h *HybridStorage) Close() error {
 		h.syncStop <- true
 		close(h.syncStop)
 	}
+	
+	// Closing the govc backend checkpoints its persisted index
+	if govcBackend, ok := h.govcBackend.(*GovcBackend); ok {
+		govcBackend.Close()
+	}
 	return nil
 }
 
@@ -290,6 +429,10 @@ func (h *HybridStorage) startBackgroundSync() {
 	h.syncTicker = time.NewTicker(h.config.SyncInterval)
 	
 	go func() {
+		// Reconcile once at startup so a restarted govc backend catches up
+		// with git before the first tick
+		h.performBackgroundSync()
+		
 		for {
 			select {
 			case <-h.syncTicker.C:
@@ -302,16 +445,18 @@ func (h *HybridStorage) startBackgroundSync() {
 }
 
 func (h *HybridStorage) performBackgroundSync() {
-	h.syncMutex.Lock()
-	defer h.syncMutex.Unlock()
-	
 	log.Debug().Msg("Performing background sync between storage backends")
-	// TODO: Implement actual sync logic between govc and git
-	// This would involve comparing documents in both backends and syncing differences
+	
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.SyncInterval)
+	defer cancel()
+	
+	if _, err := h.Reconcile(ctx); err != nil {
+		log.Warn().Err(err).Msg("Background sync between storage backends failed")
+	}
 }
 
 func (h *HybridStorage) backgroundStoreSync(doc *document.Document, primary, secondary StorageBackend) {
-	ctx, cancel := context.WithTimeout(context.Background(), h.config.OperationTimeout)
+	ctx, cancel := context.WithTimeout(withSyncCopy(context.Background()), h.config.OperationTimeout)
 	defer cancel()
 	
 	// Try to store in secondary backend as well
@@ -326,4 +471,33 @@ func (h *HybridStorage) backgroundStoreSync(doc *document.Document, primary, sec
 			Str("document_id", doc.ID).
 			Msg("Successfully synced document to secondary backend")
 	}
-}
\ No newline at end of file
+}
+
+func (h *HybridStorage) backgroundUpdateSync(doc *document.Document, secondary StorageBackend) {
+	ctx, cancel := context.WithTimeout(withSyncCopy(context.Background()), h.config.OperationTimeout)
+	defer cancel()
+	
+	// Fall back to storing if the secondary never received the original
+	_, err := secondary.UpdateDocument(ctx, doc)
+	if err != nil {
+		_, err = secondary.StoreDocument(ctx, doc)
+	}
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("document_id", doc.ID).
+			Msg("Failed to sync document update to secondary backend")
+	}
+}
+
+func (h *HybridStorage) backgroundDeleteSync(id, reason string, secondary StorageBackend) {
+	ctx, cancel := context.WithTimeout(withSyncCopy(context.Background()), h.config.OperationTimeout)
+	defer cancel()
+	
+	if _, err := secondary.DeleteDocument(ctx, id, reason); err != nil {
+		log.Warn().
+			Err(err).
+			Str("document_id", id).
+			Msg("Failed to sync document deletion to secondary backend")
+	}
+}
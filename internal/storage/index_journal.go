@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	indexJournalFile    = "documents.jsonl"
+	indexCheckpointFile = "checkpoint.json"
+
+	journalPut    = "put"
+	journalDelete = "del"
+)
+
+// journalEntry is one line of the on-disk index journal
+type journalEntry struct {
+	Op   string            `json:"op"`
+	ID   string            `json:"id"`
+	Path string            `json:"path,omitempty"`
+	Meta *DocumentMetadata `json:"meta,omitempty"`
+}
+
+// IndexCheckpoint records the repository commit a persisted index reflects.
+// Everything committed after it must be replayed when the index is loaded.
+type IndexCheckpoint struct {
+	Commit    string    `json:"commit"`
+	Documents int       `json:"documents"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// indexJournal is an append-only log of index changes. Replaying it in order
+// reproduces the index; it is rewritten with only live entries on compaction.
+type indexJournal struct {
+	dir     string
+	file    *os.File
+	entries int
+}
+
+// OpenDocumentIndex loads a document index persisted in dir, creating the
+// directory if needed. The returned checkpoint is nil when the index has
+// never been checkpointed and must be rebuilt from the repository.
+func OpenDocumentIndex(dir string) (*DocumentIndex, *IndexCheckpoint, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create index directory %s: %w", dir, err)
+	}
+
+	di := NewDocumentIndex()
+	journal := &indexJournal{dir: dir}
+
+	entries, err := journal.replay(di)
+	if err != nil {
+		return nil, nil, err
+	}
+	journal.entries = entries
+
+	file, err := os.OpenFile(filepath.Join(dir, indexJournalFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open index journal: %w", err)
+	}
+	journal.file = file
+	di.journal = journal
+
+	checkpoint, err := readIndexCheckpoint(dir)
+	if err != nil {
+		log.Warn().Err(err).Str("dir", dir).Msg("Ignoring unreadable index checkpoint")
+		checkpoint = nil
+	}
+
+	log.Info().
+		Str("dir", dir).
+		Int("documents", len(di.index)).
+		Int("journal_entries", entries).
+		Msg("Loaded persisted document index")
+
+	return di, checkpoint, nil
+}
+
+// Checkpoint records that the index reflects the repository at commit.
+// The journal is compacted first when it has grown well beyond the number
+// of live documents.
+func (di *DocumentIndex) Checkpoint(commit string) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	if di.journal == nil {
+		return nil
+	}
+
+	if di.journal.entries > 2*len(di.index)+1000 {
+		di.compactLocked()
+	}
+	if err := di.journal.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync index journal: %w", err)
+	}
+
+	checkpoint := IndexCheckpoint{
+		Commit:    commit,
+		Documents: len(di.index),
+		CreatedAt: time.Now(),
+	}
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index checkpoint: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(di.journal.dir, indexCheckpointFile), data)
+}
+
+// Close flushes and closes the on-disk journal, if any
+func (di *DocumentIndex) Close() error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+
+	if di.journal == nil {
+		return nil
+	}
+	err := di.journal.file.Close()
+	di.journal = nil
+	return err
+}
+
+// IsPersistent reports whether the index is backed by an on-disk journal
+func (di *DocumentIndex) IsPersistent() bool {
+	di.mu.RLock()
+	defer di.mu.RUnlock()
+
+	return di.journal != nil
+}
+
+// compactLocked rewrites the journal with one entry per live document.
+// Callers must hold di.mu.
+func (di *DocumentIndex) compactLocked() {
+	if di.journal == nil {
+		return
+	}
+
+	path := filepath.Join(di.journal.dir, indexJournalFile)
+	tmpPath := path + ".tmp"
+
+	err := func() error {
+		tmp, err := os.Create(tmpPath)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(tmp)
+		enc := json.NewEncoder(w)
+		for docID, docPath := range di.index {
+			if err := enc.Encode(journalEntry{Op: journalPut, ID: docID, Path: docPath, Meta: di.metadata[docID]}); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+
+		di.journal.file.Close()
+		if err := os.Rename(tmpPath, path); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		di.journal.file = file
+		di.journal.entries = len(di.index)
+		return nil
+	}()
+	if err != nil {
+		log.Error().Err(err).Str("dir", di.journal.dir).Msg("Failed to compact index journal")
+	}
+}
+
+// append writes one entry to the journal. A nil journal is a no-op so the
+// in-memory index can call it unconditionally.
+func (j *indexJournal) append(entry journalEntry) {
+	if j == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Error().Err(err).Str("document_id", entry.ID).Msg("Failed to marshal index journal entry")
+		return
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Str("document_id", entry.ID).Msg("Failed to append to index journal")
+		return
+	}
+	j.entries++
+}
+
+// replay applies every journal entry to di and returns the number read.
+// A torn final line from a crash mid-write is skipped.
+func (j *indexJournal) replay(di *DocumentIndex) (int, error) {
+	file, err := os.Open(filepath.Join(j.dir, indexJournalFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open index journal: %w", err)
+	}
+	defer file.Close()
+
+	entries := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Warn().Err(err).Int("line", entries+1).Msg("Skipping corrupt index journal entry")
+			continue
+		}
+		entries++
+
+		switch entry.Op {
+		case journalPut:
+			di.index[entry.ID] = entry.Path
+			if entry.Meta != nil {
+				di.metadata[entry.ID] = entry.Meta
+			} else {
+				delete(di.metadata, entry.ID)
+			}
+		case journalDelete:
+			delete(di.index, entry.ID)
+			delete(di.metadata, entry.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read index journal: %w", err)
+	}
+	return entries, nil
+}
+
+func readIndexCheckpoint(dir string) (*IndexCheckpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, indexCheckpointFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint IndexCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// writeFileAtomic replaces path with data via a temporary file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
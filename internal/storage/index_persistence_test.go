@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPersistentIndexReplay tests loading a persisted index and replaying
+// only the commits made after its checkpoint
+func TestPersistentIndexReplay(t *testing.T) {
+	indexPath := t.TempDir()
+	ctx := context.Background()
+
+	// Share one repository across backend instances to simulate restarts
+	first, err := NewGovcBackend("persist-test", nil)
+	require.NoError(t, err)
+	repo := first.repo
+	first.Close()
+
+	persistent := &GovcConfig{MemoryMode: true, IndexPath: indexPath}
+	unindexed := &GovcConfig{MemoryMode: true}
+
+	// First run stores documents and checkpoints on close
+	backend, err := newGovcBackendForRepo(repo, "persist-test", ":memory:", persistent, nil)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := backend.StoreDocument(ctx, newLifecycleDoc(fmt.Sprintf("persist-%03d", i)))
+		require.NoError(t, err)
+	}
+	backend.Close()
+
+	checkpoint, err := readIndexCheckpoint(filepath.Join(indexPath, "persist-test"))
+	require.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 3, checkpoint.Documents)
+
+	// Commits made without the index simulate writes lost after a crash
+	backend, err = newGovcBackendForRepo(repo, "persist-test", ":memory:", unindexed, nil)
+	require.NoError(t, err)
+	_, err = backend.StoreDocument(ctx, newLifecycleDoc("persist-003"))
+	require.NoError(t, err)
+	_, err = backend.DeleteDocument(ctx, "persist-000", "removed while index was offline")
+	require.NoError(t, err)
+	backend.Close()
+
+	// Reopening replays the two commits since the checkpoint
+	backend, err = newGovcBackendForRepo(repo, "persist-test", ":memory:", persistent, nil)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	index := backend.GetDocumentIndex()
+	assert.Equal(t, 3, index.Size())
+	_, ok := index.Get("persist-000")
+	assert.False(t, ok, "Deleted document should be removed on replay")
+	meta, ok := index.GetMetadata("persist-003")
+	require.True(t, ok, "Document stored after checkpoint should be replayed")
+	assert.Equal(t, "text", meta.Type)
+
+	report, err := backend.VerifyIndex()
+	require.NoError(t, err)
+	assert.True(t, report.Consistent(), "%+v", report)
+
+	doc, err := backend.GetDocument(ctx, "persist-003")
+	require.NoError(t, err)
+	assert.Equal(t, "original text", doc.Content.Text)
+}
+
+// TestVerifyIndex tests the consistency check and repair of the document index
+func TestVerifyIndex(t *testing.T) {
+	backend, err := NewGovcBackend("verify-test", nil)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := backend.StoreDocument(ctx, newLifecycleDoc(fmt.Sprintf("verify-%03d", i)))
+		require.NoError(t, err)
+	}
+
+	backend.docIndex.Remove("verify-000")
+	backend.docIndex.Add("ghost", "documents/text/2020/01/ghost/metadata.json", nil)
+
+	report, err := backend.VerifyIndex()
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []string{"verify-000"}, report.MissingFromIndex)
+	assert.Equal(t, []string{"ghost"}, report.StaleInIndex)
+
+	require.NoError(t, backend.RebuildIndex())
+
+	report, err = backend.VerifyIndex()
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 2, report.IndexedCount)
+}
@@ -10,11 +10,19 @@ import (
 type StorageBackend interface {
 	StoreDocument(ctx context.Context, doc *document.Document) (string, error)
 	GetDocument(ctx context.Context, id string) (*document.Document, error)
+	UpdateDocument(ctx context.Context, doc *document.Document) (string, error)
+	DeleteDocument(ctx context.Context, id, reason string) (string, error)
 	MergeBranch(ctx context.Context, branchName string) error
 	ListDocuments(ctx context.Context, filters map[string]string) ([]*document.Document, error)
 	Health(ctx context.Context) error
 }
 
+// MetadataLister is implemented by backends that can list the metadata of
+// their live documents, content hash included, without loading them
+type MetadataLister interface {
+	ListMetadata(ctx context.Context) ([]*DocumentMetadata, error)
+}
+
 // StorageMetrics provides telemetry for storage operations
 type StorageMetrics struct {
 	OperationType string
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/textdiff"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLifecycleDoc(id string) *document.Document {
+	return &document.Document{
+		ID: id,
+		Source: document.Source{
+			Type: "text",
+			URL:  "https://example.com/" + id,
+		},
+		Content: document.Content{
+			Raw:      []byte("original raw"),
+			Text:     "original text",
+			Metadata: map[string]string{"title": "Original"},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// TestGovcUpdateAndDelete tests update and tombstoned deletion in the govc backend
+func TestGovcUpdateAndDelete(t *testing.T) {
+	backend, err := NewGovcBackend("lifecycle-test", NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	received := make(map[pipeline.EventType]*pipeline.DocumentEvent)
+	_, err = backend.GetEventBus().Subscribe(
+		[]pipeline.EventType{pipeline.EventDocumentUpdated, pipeline.EventDocumentDeleted},
+		func(ctx context.Context, event *pipeline.DocumentEvent) error {
+			mu.Lock()
+			received[event.Type] = event
+			mu.Unlock()
+			return nil
+		},
+		10,
+	)
+	require.NoError(t, err)
+
+	doc := newLifecycleDoc("lifecycle-001")
+	_, err = backend.StoreDocument(ctx, doc)
+	require.NoError(t, err)
+
+	updated := newLifecycleDoc("lifecycle-001")
+	updated.Content.Raw = nil
+	updated.Content.Text = "corrected text"
+	updated.Content.Metadata["title"] = "Corrected"
+
+	updateHash, err := backend.UpdateDocument(ctx, updated)
+	require.NoError(t, err)
+	assert.NotEmpty(t, updateHash)
+
+	retrieved, err := backend.GetDocument(ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "corrected text", retrieved.Content.Text)
+	assert.Equal(t, "Corrected", retrieved.Content.Metadata["title"])
+	assert.Empty(t, retrieved.Content.Raw)
+
+	deleteHash, err := backend.DeleteDocument(ctx, doc.ID, "takedown request")
+	require.NoError(t, err)
+	assert.NotEmpty(t, deleteHash)
+
+	_, err = backend.GetDocument(ctx, doc.ID)
+	assert.Error(t, err)
+
+	docs, err := backend.ListDocuments(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+
+	tombstoneBytes, err := backend.repo.ReadFile(doc.GitPath() + "/" + TombstoneFile)
+	require.NoError(t, err)
+	var tombstone Tombstone
+	require.NoError(t, json.Unmarshal(tombstoneBytes, &tombstone))
+	assert.Equal(t, doc.ID, tombstone.ID)
+	assert.Equal(t, "takedown request", tombstone.Reason)
+
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, received, pipeline.EventDocumentUpdated)
+	assert.Equal(t, updateHash, received[pipeline.EventDocumentUpdated].Metadata["commit_hash"])
+	diff, ok := received[pipeline.EventDocumentUpdated].Metadata["diff"].(textdiff.Summary)
+	require.True(t, ok)
+	assert.Equal(t, []string{"corrected text"}, diff.Added)
+	assert.Equal(t, diff.String(), received[pipeline.EventDocumentUpdated].Metadata["diff_summary"])
+	require.Contains(t, received, pipeline.EventDocumentDeleted)
+	assert.Equal(t, deleteHash, received[pipeline.EventDocumentDeleted].Metadata["commit_hash"])
+	assert.Equal(t, "takedown request", received[pipeline.EventDocumentDeleted].Metadata["reason"])
+
+	_, err = backend.DeleteDocument(ctx, doc.ID, "again")
+	assert.Error(t, err, "Deleting a deleted document should fail")
+
+	// Storing the ID again brings the document back without its tombstone
+	_, err = backend.StoreDocument(ctx, newLifecycleDoc("lifecycle-001"))
+	require.NoError(t, err)
+	_, err = backend.GetDocument(ctx, doc.ID)
+	assert.NoError(t, err)
+	tombstones, err := backend.ListTombstones(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+}
+
+// TestGitUpdateAndDelete tests that the git backend records updates and tombstones as commits
+func TestGitUpdateAndDelete(t *testing.T) {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+
+	backend, err := NewGitBackend(repoPath, NewSimpleMetricsCollector())
+	require.NoError(t, err)
+
+	bus := pipeline.NewEventBus(10, 1)
+	defer bus.Close()
+	backend.SetEventBus(bus)
+
+	var mu sync.Mutex
+	received := make(map[pipeline.EventType]*pipeline.DocumentEvent)
+	_, err = bus.Subscribe(
+		[]pipeline.EventType{pipeline.EventDocumentUpdated, pipeline.EventDocumentDeleted},
+		func(ctx context.Context, event *pipeline.DocumentEvent) error {
+			mu.Lock()
+			received[event.Type] = event
+			mu.Unlock()
+			return nil
+		},
+		10,
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	doc := newLifecycleDoc("git-lifecycle-001")
+	_, err = backend.StoreDocument(ctx, doc)
+	require.NoError(t, err)
+
+	updated := newLifecycleDoc("git-lifecycle-001")
+	updated.Content.Text = "corrected text"
+	updateHash, err := backend.UpdateDocument(ctx, updated)
+	require.NoError(t, err)
+
+	retrieved, err := backend.GetDocument(ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "corrected text", retrieved.Content.Text)
+
+	deleteHash, err := backend.DeleteDocument(ctx, doc.ID, "bad extraction")
+	require.NoError(t, err)
+
+	_, err = backend.GetDocument(ctx, doc.ID)
+	assert.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(repoPath, doc.GitPath(), "metadata.json"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(repoPath, doc.GitPath(), TombstoneFile))
+	assert.NoError(t, err)
+
+	head, err := repo.Head()
+	require.NoError(t, err)
+	assert.Equal(t, deleteHash, head.Hash().String())
+
+	commit, err := repo.CommitObject(head.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "Delete document git-lifecycle-001: bad extraction", commit.Message)
+
+	// The previous version must remain reachable in history
+	parent, err := commit.Parent(0)
+	require.NoError(t, err)
+	file, err := parent.File(doc.GitPath() + "/text.txt")
+	require.NoError(t, err)
+	contents, err := file.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "corrected text", contents)
+
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, received, pipeline.EventDocumentUpdated)
+	assert.Equal(t, updateHash, received[pipeline.EventDocumentUpdated].Metadata["commit_hash"])
+	assert.Equal(t, "git", received[pipeline.EventDocumentUpdated].Metadata["backend"])
+	diff, ok := received[pipeline.EventDocumentUpdated].Metadata["diff"].(textdiff.Summary)
+	require.True(t, ok)
+	assert.Equal(t, []string{"corrected text"}, diff.Added)
+	require.Contains(t, received, pipeline.EventDocumentDeleted)
+	assert.Equal(t, deleteHash, received[pipeline.EventDocumentDeleted].Metadata["commit_hash"])
+	assert.Equal(t, "bad extraction", received[pipeline.EventDocumentDeleted].Metadata["reason"])
+
+	// Storing the ID again brings the document back without its tombstone
+	storeHash, err := backend.StoreDocument(ctx, newLifecycleDoc("git-lifecycle-001"))
+	require.NoError(t, err)
+	_, err = backend.GetDocument(ctx, doc.ID)
+	assert.NoError(t, err)
+	tombstones, err := backend.ListTombstones(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+
+	commit, err = repo.CommitObject(plumbing.NewHash(storeHash))
+	require.NoError(t, err)
+	_, err = commit.File(doc.GitPath() + "/" + TombstoneFile)
+	assert.Error(t, err, "the tombstone is removed in the same commit")
+}
+
+// TestSyncCopiesDoNotPublish tests that copies between backends are not
+// published a second time
+func TestSyncCopiesDoNotPublish(t *testing.T) {
+	backend, err := NewGovcBackend("sync-copy-test", NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	var mu sync.Mutex
+	var events []*pipeline.DocumentEvent
+	_, err = backend.GetEventBus().Subscribe(
+		[]pipeline.EventType{pipeline.EventDocumentAdded, pipeline.EventDocumentUpdated, pipeline.EventDocumentDeleted},
+		func(ctx context.Context, event *pipeline.DocumentEvent) error {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+			return nil
+		},
+		10,
+	)
+	require.NoError(t, err)
+
+	ctx := withSyncCopy(context.Background())
+	doc := newLifecycleDoc("sync-copy-001")
+	_, err = backend.StoreDocument(ctx, doc)
+	require.NoError(t, err)
+	updated := newLifecycleDoc("sync-copy-001")
+	updated.Content.Text = "corrected text"
+	_, err = backend.UpdateDocument(ctx, updated)
+	require.NoError(t, err)
+	_, err = backend.DeleteDocument(ctx, doc.ID, "sync")
+	require.NoError(t, err)
+
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Empty(t, events)
+}
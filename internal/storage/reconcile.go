@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/rs/zerolog/log"
+)
+
+// maxReportedSyncErrors bounds the error list kept in a SyncReport
+const maxReportedSyncErrors = 50
+
+// tombstoneLister is implemented by backends that can report deleted documents
+type tombstoneLister interface {
+	ListTombstones(ctx context.Context) ([]*Tombstone, error)
+}
+
+// SyncConflict describes a document whose copies differ but carry the same
+// update time, so neither side can be shown to be newer
+type SyncConflict struct {
+	DocumentID string    `json:"document_id"`
+	GovcHash   string    `json:"govc_hash"`
+	GitHash    string    `json:"git_hash"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Resolution string    `json:"resolution"`
+}
+
+// SyncReport summarizes a reconciliation run between the govc and git backends
+type SyncReport struct {
+	StartedAt       time.Time      `json:"started_at"`
+	Duration        time.Duration  `json:"duration"`
+	GovcDocuments   int            `json:"govc_documents"`
+	GitDocuments    int            `json:"git_documents"`
+	InSync          int            `json:"in_sync"`
+	CopiedToGovc    int            `json:"copied_to_govc"`
+	CopiedToGit     int            `json:"copied_to_git"`
+	DeletedFromGovc int            `json:"deleted_from_govc"`
+	DeletedFromGit  int            `json:"deleted_from_git"`
+	Conflicts       []SyncConflict `json:"conflicts"`
+	Errors          []string       `json:"errors,omitempty"`
+}
+
+func (r *SyncReport) addError(format string, args ...interface{}) {
+	if len(r.Errors) < maxReportedSyncErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// ContentHash returns a stable hash over the parts of a document that both
+// backends round-trip: source URL, text, raw bytes and content metadata
+func ContentHash(doc *document.Document) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "url:%s\n", doc.Source.URL)
+	fmt.Fprintf(h, "text:%d:%s\n", len(doc.Content.Text), doc.Content.Text)
+	fmt.Fprintf(h, "raw:%d:", len(doc.Content.Raw))
+	h.Write(doc.Content.Raw)
+
+	keys := make([]string, 0, len(doc.Content.Metadata))
+	for k := range doc.Content.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\nmeta:%s=%s", k, doc.Content.Metadata[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentHashCache remembers the content hashes computed for documents
+// whose metadata records none, by document ID and update time, so they are
+// loaded once rather than on every listing
+type contentHashCache struct {
+	mu     sync.Mutex
+	hashes map[string]cachedContentHash
+}
+
+type cachedContentHash struct {
+	updatedAt time.Time
+	hash      string
+}
+
+func (c *contentHashCache) get(id string, updatedAt time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.hashes[id]
+	if !ok || !cached.updatedAt.Equal(updatedAt) {
+		return "", false
+	}
+	return cached.hash, true
+}
+
+func (c *contentHashCache) put(id string, updatedAt time.Time, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hashes == nil {
+		c.hashes = make(map[string]cachedContentHash)
+	}
+	c.hashes[id] = cachedContentHash{updatedAt: updatedAt, hash: hash}
+}
+
+// Reconcile diffs the govc and git backends by document ID and content hash
+// and copies missing or stale documents in whichever direction is needed.
+// The diff is made from metadata; only the documents to copy are loaded.
+// Deletions recorded as tombstones on one side are propagated to the other
+// unless the surviving copy was updated after the deletion. Copies are not
+// published as document events: the changes they carry already were, and
+// republishing them would reprocess and reindex the corpus.
+func (h *HybridStorage) Reconcile(ctx context.Context) (*SyncReport, error) {
+	h.syncMutex.Lock()
+	defer h.syncMutex.Unlock()
+
+	ctx = withSyncCopy(ctx)
+
+	start := time.Now()
+	report := &SyncReport{
+		StartedAt: start,
+		Conflicts: []SyncConflict{},
+	}
+
+	govcDocs, err := listMetadata(ctx, h.govcBackend)
+	if err != nil {
+		h.recordHybridMetric("sync", start, false, "govc_list_failed")
+		return nil, fmt.Errorf("failed to list govc documents: %w", err)
+	}
+	gitDocs, err := listMetadata(ctx, h.gitBackend)
+	if err != nil {
+		h.recordHybridMetric("sync", start, false, "git_list_failed")
+		return nil, fmt.Errorf("failed to list git documents: %w", err)
+	}
+
+	report.GovcDocuments = len(govcDocs)
+	report.GitDocuments = len(gitDocs)
+
+	govcByID := indexMetadataByID(govcDocs)
+	gitByID := indexMetadataByID(gitDocs)
+	govcTombstones := h.listTombstones(ctx, h.govcBackend, report)
+	gitTombstones := h.listTombstones(ctx, h.gitBackend, report)
+
+	for id, govcDoc := range govcByID {
+		if err := ctx.Err(); err != nil {
+			return h.finishSync(report, start), err
+		}
+
+		gitDoc, inGit := gitByID[id]
+		if !inGit {
+			if tombstone, deleted := gitTombstones[id]; deleted && !govcDoc.UpdatedAt.After(tombstone.DeletedAt) {
+				h.syncDelete(ctx, h.govcBackend, tombstone, report, &report.DeletedFromGovc)
+			} else {
+				h.syncCopy(ctx, h.govcBackend, h.gitBackend, id, false, report, &report.CopiedToGit)
+			}
+			continue
+		}
+
+		switch {
+		case govcDoc.ContentHash == gitDoc.ContentHash:
+			report.InSync++
+		case govcDoc.UpdatedAt.After(gitDoc.UpdatedAt):
+			h.syncCopy(ctx, h.govcBackend, h.gitBackend, id, true, report, &report.CopiedToGit)
+		case gitDoc.UpdatedAt.After(govcDoc.UpdatedAt):
+			h.syncCopy(ctx, h.gitBackend, h.govcBackend, id, true, report, &report.CopiedToGovc)
+		default:
+			// Same update time but different content: the primary wins
+			conflict := SyncConflict{
+				DocumentID: id,
+				GovcHash:   govcDoc.ContentHash,
+				GitHash:    gitDoc.ContentHash,
+				UpdatedAt:  govcDoc.UpdatedAt,
+				Resolution: fmt.Sprintf("kept_%s", h.config.PrimaryBackend),
+			}
+			report.Conflicts = append(report.Conflicts, conflict)
+			if h.config.PrimaryBackend == "govc" {
+				h.syncCopy(ctx, h.govcBackend, h.gitBackend, id, true, report, &report.CopiedToGit)
+			} else {
+				h.syncCopy(ctx, h.gitBackend, h.govcBackend, id, true, report, &report.CopiedToGovc)
+			}
+		}
+	}
+
+	for id, gitDoc := range gitByID {
+		if _, inGovc := govcByID[id]; inGovc {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return h.finishSync(report, start), err
+		}
+
+		if tombstone, deleted := govcTombstones[id]; deleted && !gitDoc.UpdatedAt.After(tombstone.DeletedAt) {
+			h.syncDelete(ctx, h.gitBackend, tombstone, report, &report.DeletedFromGit)
+		} else {
+			h.syncCopy(ctx, h.gitBackend, h.govcBackend, id, false, report, &report.CopiedToGovc)
+		}
+	}
+
+	return h.finishSync(report, start), nil
+}
+
+// LastSyncReport returns the report of the most recent reconciliation run
+func (h *HybridStorage) LastSyncReport() *SyncReport {
+	h.syncStatsMu.RLock()
+	defer h.syncStatsMu.RUnlock()
+
+	return h.lastSync
+}
+
+func (h *HybridStorage) finishSync(report *SyncReport, start time.Time) *SyncReport {
+	report.Duration = time.Since(start)
+
+	h.syncStatsMu.Lock()
+	h.lastSync = report
+	h.syncRuns++
+	h.syncStatsMu.Unlock()
+
+	h.recordHybridMetric("sync", start, len(report.Errors) == 0, "reconcile")
+
+	log.Info().
+		Int("govc_documents", report.GovcDocuments).
+		Int("git_documents", report.GitDocuments).
+		Int("copied_to_govc", report.CopiedToGovc).
+		Int("copied_to_git", report.CopiedToGit).
+		Int("deleted_from_govc", report.DeletedFromGovc).
+		Int("deleted_from_git", report.DeletedFromGit).
+		Int("conflicts", len(report.Conflicts)).
+		Int("errors", len(report.Errors)).
+		Dur("duration", report.Duration).
+		Msg("Storage backends reconciled")
+
+	return report
+}
+
+// syncCopy loads a document from source and stores it in target
+func (h *HybridStorage) syncCopy(ctx context.Context, source, target StorageBackend, id string, exists bool, report *SyncReport, counter *int) {
+	doc, err := source.GetDocument(ctx, id)
+	if err != nil {
+		report.addError("copy %s: %v", id, err)
+		return
+	}
+	if exists {
+		// UpdateDocument stamps its own update time, so copy the document
+		// rather than mutating the one read from the other backend
+		copied := *doc
+		_, err = target.UpdateDocument(ctx, &copied)
+	} else {
+		_, err = target.StoreDocument(ctx, doc)
+	}
+	if err != nil {
+		report.addError("copy %s: %v", doc.ID, err)
+		return
+	}
+	*counter++
+}
+
+func (h *HybridStorage) syncDelete(ctx context.Context, target StorageBackend, tombstone *Tombstone, report *SyncReport, counter *int) {
+	if _, err := target.DeleteDocument(ctx, tombstone.ID, tombstone.Reason); err != nil {
+		report.addError("delete %s: %v", tombstone.ID, err)
+		return
+	}
+	*counter++
+}
+
+func (h *HybridStorage) listTombstones(ctx context.Context, backend StorageBackend, report *SyncReport) map[string]*Tombstone {
+	result := make(map[string]*Tombstone)
+
+	lister, ok := backend.(tombstoneLister)
+	if !ok {
+		return result
+	}
+
+	tombstones, err := lister.ListTombstones(ctx)
+	if err != nil {
+		report.addError("list tombstones: %v", err)
+		return result
+	}
+	for _, tombstone := range tombstones {
+		result[tombstone.ID] = tombstone
+	}
+	return result
+}
+
+// listMetadata lists a backend's documents by metadata, loading them only
+// when the backend cannot list metadata on its own
+func listMetadata(ctx context.Context, backend StorageBackend) ([]*DocumentMetadata, error) {
+	if lister, ok := backend.(MetadataLister); ok {
+		return lister.ListMetadata(ctx)
+	}
+
+	docs, err := backend.ListDocuments(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*DocumentMetadata, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, &DocumentMetadata{
+			ID:          doc.ID,
+			Path:        doc.GitPath() + "/metadata.json",
+			Type:        doc.Source.Type,
+			CreatedAt:   doc.CreatedAt,
+			UpdatedAt:   doc.UpdatedAt,
+			ContentHash: ContentHash(doc),
+		})
+	}
+	return result, nil
+}
+
+func indexMetadataByID(entries []*DocumentMetadata) map[string]*DocumentMetadata {
+	result := make(map[string]*DocumentMetadata, len(entries))
+	for _, entry := range entries {
+		result[entry.ID] = entry
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/pipeline"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	git "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile tests bidirectional reconciliation between the govc and git backends
+func TestReconcile(t *testing.T) {
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+
+	config := &HybridStorageConfig{
+		PrimaryBackend:   "govc",
+		EnableFallback:   true,
+		OperationTimeout: 10 * time.Second,
+		EnableSync:       false,
+	}
+
+	hybridStorage, err := NewHybridStorage(repoPath, "reconcile-test", config, NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer hybridStorage.Close()
+
+	ctx := context.Background()
+	govcBackend := hybridStorage.govcBackend
+	gitBackend := hybridStorage.gitBackend
+
+	// Only in govc, e.g. the secondary write failed
+	_, err = govcBackend.StoreDocument(ctx, newLifecycleDoc("only-govc"))
+	require.NoError(t, err)
+
+	// Only in git, e.g. govc restarted in memory mode
+	_, err = gitBackend.StoreDocument(ctx, newLifecycleDoc("only-git"))
+	require.NoError(t, err)
+
+	// In both, but the git copy is stale
+	_, err = govcBackend.StoreDocument(ctx, newLifecycleDoc("stale-git"))
+	require.NoError(t, err)
+	_, err = gitBackend.StoreDocument(ctx, newLifecycleDoc("stale-git"))
+	require.NoError(t, err)
+	corrected := newLifecycleDoc("stale-git")
+	corrected.Content.Text = "corrected in govc"
+	_, err = govcBackend.UpdateDocument(ctx, corrected)
+	require.NoError(t, err)
+
+	// Deleted in govc, deletion never reached git
+	_, err = govcBackend.StoreDocument(ctx, newLifecycleDoc("deleted-in-govc"))
+	require.NoError(t, err)
+	_, err = gitBackend.StoreDocument(ctx, newLifecycleDoc("deleted-in-govc"))
+	require.NoError(t, err)
+	_, err = govcBackend.DeleteDocument(ctx, "deleted-in-govc", "takedown request")
+	require.NoError(t, err)
+
+	// Let the events of the writes above drain before watching for copies
+	time.Sleep(200 * time.Millisecond)
+	var mu sync.Mutex
+	var published []*pipeline.DocumentEvent
+	_, err = hybridStorage.GetEventBus().Subscribe(
+		[]pipeline.EventType{pipeline.EventDocumentAdded, pipeline.EventDocumentUpdated, pipeline.EventDocumentDeleted},
+		func(ctx context.Context, event *pipeline.DocumentEvent) error {
+			mu.Lock()
+			published = append(published, event)
+			mu.Unlock()
+			return nil
+		},
+		10,
+	)
+	require.NoError(t, err)
+
+	report, err := hybridStorage.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 1, report.CopiedToGovc)
+	assert.Equal(t, 2, report.CopiedToGit)
+	assert.Equal(t, 1, report.DeletedFromGit)
+
+	for _, id := range []string{"only-govc", "only-git", "stale-git"} {
+		govcDoc, err := govcBackend.GetDocument(ctx, id)
+		require.NoError(t, err, id)
+		gitDoc, err := gitBackend.GetDocument(ctx, id)
+		require.NoError(t, err, id)
+		assert.Equal(t, ContentHash(govcDoc), ContentHash(gitDoc), id)
+	}
+
+	gitDoc, err := gitBackend.GetDocument(ctx, "stale-git")
+	require.NoError(t, err)
+	assert.Equal(t, "corrected in govc", gitDoc.Content.Text)
+
+	_, err = gitBackend.GetDocument(ctx, "deleted-in-govc")
+	assert.Error(t, err, "Deletion should be propagated to git")
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, published, "Copies between backends should not be published")
+	mu.Unlock()
+
+	// Metadata written before content hashes were recorded has none
+	metadataPath := filepath.Join(repoPath, gitDoc.GitPath(), "metadata.json")
+	data, err := os.ReadFile(metadataPath)
+	require.NoError(t, err)
+	var stored map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &stored))
+	delete(stored, "content_hash")
+	data, err = json.Marshal(stored)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(metadataPath, data, 0644))
+
+	// A second run should find both backends identical without loading
+	// a single document
+	counting := &countingBackend{GitBackend: gitBackend.(*GitBackend)}
+	hybridStorage.gitBackend = counting
+	report, err = hybridStorage.Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.InSync)
+	assert.Zero(t, report.CopiedToGovc+report.CopiedToGit+report.DeletedFromGovc+report.DeletedFromGit)
+	assert.Zero(t, counting.loads)
+
+	syncStats, ok := hybridStorage.GetStats()["sync"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, int64(2), syncStats["runs"])
+	assert.Equal(t, report, syncStats["last_run"])
+}
+
+// countingBackend counts the documents loaded through a git backend
+type countingBackend struct {
+	*GitBackend
+	loads int
+}
+
+func (c *countingBackend) GetDocument(ctx context.Context, id string) (*document.Document, error) {
+	c.loads++
+	return c.GitBackend.GetDocument(ctx, id)
+}
+
+func (c *countingBackend) ListDocuments(ctx context.Context, filters map[string]string) ([]*document.Document, error) {
+	c.loads++
+	return c.GitBackend.ListDocuments(ctx, filters)
+}
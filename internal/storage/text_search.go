@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
+)
+
+// Fields of a document in the full-text index
+const (
+	TextFieldTitle  = "title"
+	TextFieldText   = "text"
+	TextFieldAuthor = "author"
+)
+
+// TextMatch is a full-text search result scored by BM25
+type TextMatch struct {
+	ID       string            `json:"id"`
+	Score    float64           `json:"score"`
+	Type     string            `json:"type"`
+	URL      string            `json:"url,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TextSearcher keeps a full-text index of document content in step with a
+// storage backend and answers keyword and phrase queries against it
+type TextSearcher struct {
+	*eventIndex
+	index *fulltext.Index
+
+	// entries holds the filterable fields of each indexed document
+	entriesMu sync.RWMutex
+	entries   map[string]*TextMatch
+}
+
+// NewTextSearcher creates a searcher over backend with the given BM25
+// parameters
+func NewTextSearcher(backend StorageBackend, config *fulltext.Config) *TextSearcher {
+	t := &TextSearcher{
+		index:   fulltext.NewIndex(config),
+		entries: make(map[string]*TextMatch),
+	}
+	t.eventIndex = newEventIndex("Text", backend, t.apply)
+	return t
+}
+
+// TextFields returns the text of each indexed field of doc
+func TextFields(doc *document.Document) map[string]string {
+	author := doc.Content.Metadata["author"]
+	if author == "" {
+		author = doc.Content.Metadata["authors"]
+	}
+
+	return map[string]string{
+		TextFieldTitle:  doc.Content.Metadata["title"],
+		TextFieldText:   doc.Content.Text,
+		TextFieldAuthor: author,
+	}
+}
+
+// Index returns the underlying full-text index
+func (t *TextSearcher) Index() *fulltext.Index {
+	return t.index
+}
+
+// Search runs query and returns the matching documents that satisfy all
+// filters, best first. Filters work as for vector search.
+func (t *TextSearcher) Search(query *fulltext.Query, filters map[string]string) []TextMatch {
+	t.entriesMu.RLock()
+	defer t.entriesMu.RUnlock()
+
+	scoped := *query
+	scoped.Filter = func(id string) bool {
+		entry, ok := t.entries[id]
+		if !ok || !matchesFilters(entry.Type, entry.URL, entry.Metadata, filters) {
+			return false
+		}
+		return query.Filter == nil || query.Filter(id)
+	}
+
+	hits := t.index.Search(&scoped)
+	matches := make([]TextMatch, len(hits))
+	for i, hit := range hits {
+		match := *t.entries[hit.ID]
+		match.Score = hit.Score
+		matches[i] = match
+	}
+	return matches
+}
+
+// Size returns the number of indexed documents
+func (t *TextSearcher) Size() int {
+	return t.index.Size()
+}
+
+// apply indexes or removes doc
+func (t *TextSearcher) apply(doc *document.Document, deleted bool) bool {
+	t.entriesMu.Lock()
+	defer t.entriesMu.Unlock()
+
+	if deleted {
+		t.index.Remove(doc.ID)
+		delete(t.entries, doc.ID)
+		return false
+	}
+
+	t.index.Add(doc.ID, TextFields(doc))
+	t.entries[doc.ID] = &TextMatch{
+		ID:       doc.ID,
+		Type:     doc.Source.Type,
+		URL:      doc.Source.URL,
+		Metadata: doc.Content.Metadata,
+	}
+	return true
+}
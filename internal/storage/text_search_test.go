@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTextSearcher tests that the full-text index follows document events from govc
+func TestTextSearcher(t *testing.T) {
+	backend, err := NewGovcBackend("text-search-test", nil)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	newDoc := func(id, title, text string) *document.Document {
+		doc := newLifecycleDoc(id)
+		doc.Content.Metadata["title"] = title
+		doc.Content.Text = text
+		return doc
+	}
+
+	_, err = backend.StoreDocument(ctx, newDoc("transformers", "Attention is all you need", "Transformers replace recurrence with self-attention layers."))
+	require.NoError(t, err)
+
+	searcher := NewTextSearcher(backend, nil)
+	indexed, err := searcher.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, indexed)
+
+	require.NoError(t, searcher.Subscribe(backend.GetEventBus()))
+	defer searcher.Close()
+
+	recurrent := newDoc("rnn", "Recurrent networks", "Recurrent neural networks process sequences one step at a time.")
+	recurrent.Source.Type = "arxiv"
+	_, err = backend.StoreDocument(ctx, recurrent)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return searcher.Size() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	matches := searcher.Search(&fulltext.Query{Text: "recurrence"}, nil)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "rnn", matches[0].ID, "Title matches should rank first")
+	assert.Equal(t, "arxiv", matches[0].Type)
+
+	matches = searcher.Search(&fulltext.Query{Text: `"self attention"`}, nil)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "transformers", matches[0].ID)
+
+	matches = searcher.Search(&fulltext.Query{Text: "recurrent"}, map[string]string{"type": "text"})
+	require.Len(t, matches, 1)
+	assert.Equal(t, "transformers", matches[0].ID)
+
+	_, err = backend.DeleteDocument(ctx, "rnn", "test")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return searcher.Size() == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Len(t, searcher.Search(&fulltext.Query{Text: "sequences"}, nil), 0)
+}
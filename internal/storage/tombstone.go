@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+)
+
+// TombstoneFile is the file left in a document directory after deletion
+const TombstoneFile = "tombstone.json"
+
+// Tombstone records what was removed from the repository and why
+type Tombstone struct {
+	ID        string          `json:"id"`
+	Reason    string          `json:"reason"`
+	Source    document.Source `json:"source"`
+	CreatedAt time.Time       `json:"created_at"`
+	DeletedAt time.Time       `json:"deleted_at"`
+}
+
+// NewTombstone creates a tombstone for a document that is about to be deleted
+func NewTombstone(doc *document.Document, reason string) *Tombstone {
+	return &Tombstone{
+		ID:        doc.ID,
+		Reason:    reason,
+		Source:    doc.Source,
+		CreatedAt: doc.CreatedAt,
+		DeletedAt: time.Now(),
+	}
+}
+
+// Marshal serializes the tombstone for storage
+func (t *Tombstone) Marshal() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// deleteCommitMessage builds the commit message recorded for a deletion
+func deleteCommitMessage(id, reason string) string {
+	if reason == "" {
+		return "Delete document " + id
+	}
+	return "Delete document " + id + ": " + reason
+}
+
+// documentMatchesFilters applies the ListDocuments filters shared by all backends
+func documentMatchesFilters(doc *document.Document, filters map[string]string) bool {
+	for key, value := range filters {
+		switch key {
+		case "type":
+			if doc.Source.Type != value {
+				return false
+			}
+		case "source":
+			if doc.Source.URL != value {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// hnswNode is one vector in the graph with its neighbour lists per layer
+type hnswNode struct {
+	entry     *VectorEntry
+	neighbors [][]int
+	deleted   bool
+}
+
+// HNSWVectorIndex is an approximate nearest-neighbour index based on a
+// hierarchical navigable small world graph (Malkov & Yashunin, 2016).
+// Removed entries stay in the graph as routing nodes until enough accumulate
+// to make a rebuild worthwhile.
+type HNSWVectorIndex struct {
+	mu         sync.RWMutex
+	config     VectorIndexConfig
+	nodes      []*hnswNode
+	ids        map[string]int
+	entryPoint int
+	maxLevel   int
+	dimension  int
+	deleted    int
+	levelMult  float64
+	rng        *rand.Rand
+}
+
+// NewHNSWVectorIndex creates an empty HNSW index
+func NewHNSWVectorIndex(config *VectorIndexConfig) *HNSWVectorIndex {
+	defaults := DefaultVectorIndexConfig()
+	cfg := *config
+	if cfg.M < 2 {
+		cfg.M = defaults.M
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = defaults.EfConstruction
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = defaults.EfSearch
+	}
+
+	return &HNSWVectorIndex{
+		config:     cfg,
+		ids:        make(map[string]int),
+		entryPoint: -1,
+		levelMult:  1 / math.Log(float64(cfg.M)),
+		rng:        rand.New(rand.NewSource(1)),
+	}
+}
+
+func (h *HNSWVectorIndex) Upsert(entry *VectorEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prepared, err := prepareEntry(entry, h.dimension)
+	if err != nil {
+		return err
+	}
+	h.dimension = len(prepared.Vector)
+
+	if old, ok := h.ids[prepared.ID]; ok {
+		h.markDeleted(old)
+	}
+	h.insert(prepared)
+	h.maybeRebuild()
+	return nil
+}
+
+func (h *HNSWVectorIndex) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if node, ok := h.ids[id]; ok {
+		h.markDeleted(node)
+		h.maybeRebuild()
+	}
+}
+
+func (h *HNSWVectorIndex) Get(id string) (*VectorEntry, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	node, ok := h.ids[id]
+	if !ok {
+		return nil, false
+	}
+	return h.nodes[node].entry, true
+}
+
+func (h *HNSWVectorIndex) Search(query []float32, k int, filters map[string]string) ([]VectorMatch, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if k <= 0 || len(h.ids) == 0 {
+		return []VectorMatch{}, nil
+	}
+	if len(query) != h.dimension {
+		return nil, fmt.Errorf("query dimension %d does not match index dimension %d", len(query), h.dimension)
+	}
+	q, err := normalizeVector(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := h.config.EfSearch
+	if k > ef {
+		ef = k
+	}
+
+	ep := h.entryPoint
+	for level := h.maxLevel; level > 0; level-- {
+		ep = h.greedyClosest(q, ep, level)
+	}
+
+	var results []VectorMatch
+	for _, c := range h.searchLayer(q, ep, ef, 0) {
+		node := h.nodes[c.node]
+		if !node.deleted && node.entry.matches(filters) {
+			results = append(results, node.entry.match(c.score))
+		}
+	}
+
+	// Restrictive filters or removed routing nodes can leave the graph
+	// search short; fall back to an exact scan rather than return too few
+	if len(results) < k && len(results) < len(h.ids) {
+		results = results[:0]
+		for _, node := range h.ids {
+			entry := h.nodes[node].entry
+			if entry.matches(filters) {
+				results = append(results, entry.match(dot(q, entry.Vector)))
+			}
+		}
+	}
+	return topMatches(results, k), nil
+}
+
+func (h *HNSWVectorIndex) Size() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.ids)
+}
+
+func (h *HNSWVectorIndex) insert(entry *VectorEntry) {
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	id := len(h.nodes)
+	node := &hnswNode{
+		entry:     entry,
+		neighbors: make([][]int, level+1),
+	}
+	h.nodes = append(h.nodes, node)
+	h.ids[entry.ID] = id
+
+	if h.entryPoint < 0 {
+		h.entryPoint = id
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entryPoint
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedyClosest(entry.Vector, ep, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(entry.Vector, ep, h.config.EfConstruction, l)
+		limit := h.maxNeighbors(l)
+
+		neighbors := make([]int, 0, limit)
+		for _, c := range candidates {
+			if len(neighbors) == limit {
+				break
+			}
+			neighbors = append(neighbors, c.node)
+		}
+		node.neighbors[l] = neighbors
+
+		for _, n := range neighbors {
+			other := h.nodes[n]
+			other.neighbors[l] = append(other.neighbors[l], id)
+			if len(other.neighbors[l]) > limit {
+				other.neighbors[l] = h.closestOf(other.entry.Vector, other.neighbors[l], limit)
+			}
+		}
+		ep = candidates[0].node
+	}
+
+	if level > h.maxLevel {
+		h.entryPoint = id
+		h.maxLevel = level
+	}
+}
+
+func (h *HNSWVectorIndex) markDeleted(node int) {
+	n := h.nodes[node]
+	if n.deleted {
+		return
+	}
+	n.deleted = true
+	delete(h.ids, n.entry.ID)
+	h.deleted++
+}
+
+// maybeRebuild reinserts the live entries once removed nodes outnumber them
+func (h *HNSWVectorIndex) maybeRebuild() {
+	if h.deleted < 64 || h.deleted < len(h.ids) {
+		return
+	}
+
+	live := make([]*VectorEntry, 0, len(h.ids))
+	for _, node := range h.nodes {
+		if !node.deleted {
+			live = append(live, node.entry)
+		}
+	}
+
+	h.nodes = nil
+	h.ids = make(map[string]int, len(live))
+	h.entryPoint = -1
+	h.maxLevel = 0
+	h.deleted = 0
+	for _, entry := range live {
+		h.insert(entry)
+	}
+}
+
+func (h *HNSWVectorIndex) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * h.config.M
+	}
+	return h.config.M
+}
+
+// greedyClosest walks from ep to the closest node it can reach on level
+func (h *HNSWVectorIndex) greedyClosest(q []float32, ep, level int) int {
+	best := ep
+	bestScore := dot(q, h.nodes[ep].entry.Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.nodes[best].neighbors[level] {
+			if score := dot(q, h.nodes[n].entry.Vector); score > bestScore {
+				best, bestScore = n, score
+				changed = true
+			}
+		}
+	}
+	return best
+}
+
+// searchLayer returns up to ef nodes on level closest to q, best first
+func (h *HNSWVectorIndex) searchLayer(q []float32, ep, ef, level int) []hnswCandidate {
+	visited := map[int]bool{ep: true}
+	start := hnswCandidate{node: ep, score: dot(q, h.nodes[ep].entry.Vector)}
+
+	candidates := &candidateMaxHeap{start}
+	results := &candidateMinHeap{start}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && current.score < (*results)[0].score {
+			break
+		}
+
+		node := h.nodes[current.node]
+		if level >= len(node.neighbors) {
+			continue
+		}
+		for _, n := range node.neighbors[level] {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			score := dot(q, h.nodes[n].entry.Vector)
+			if results.Len() < ef || score > (*results)[0].score {
+				heap.Push(candidates, hnswCandidate{node: n, score: score})
+				heap.Push(results, hnswCandidate{node: n, score: score})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	ordered := make([]hnswCandidate, results.Len())
+	for i := len(ordered) - 1; i >= 0; i-- {
+		ordered[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return ordered
+}
+
+// closestOf keeps the limit nodes closest to v
+func (h *HNSWVectorIndex) closestOf(v []float32, nodes []int, limit int) []int {
+	results := &candidateMinHeap{}
+	for _, n := range nodes {
+		heap.Push(results, hnswCandidate{node: n, score: dot(v, h.nodes[n].entry.Vector)})
+		if results.Len() > limit {
+			heap.Pop(results)
+		}
+	}
+
+	kept := make([]int, results.Len())
+	for i := len(kept) - 1; i >= 0; i-- {
+		kept[i] = heap.Pop(results).(hnswCandidate).node
+	}
+	return kept
+}
+
+type hnswCandidate struct {
+	node  int
+	score float32
+}
+
+// candidateMaxHeap pops the most similar candidate first
+type candidateMaxHeap []hnswCandidate
+
+func (c candidateMaxHeap) Len() int            { return len(c) }
+func (c candidateMaxHeap) Less(i, j int) bool  { return c[i].score > c[j].score }
+func (c candidateMaxHeap) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *candidateMaxHeap) Push(x interface{}) { *c = append(*c, x.(hnswCandidate)) }
+func (c *candidateMaxHeap) Pop() interface{} {
+	old := *c
+	item := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return item
+}
+
+// candidateMinHeap pops the least similar candidate first
+type candidateMinHeap []hnswCandidate
+
+func (c candidateMinHeap) Len() int            { return len(c) }
+func (c candidateMinHeap) Less(i, j int) bool  { return c[i].score < c[j].score }
+func (c candidateMinHeap) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *candidateMinHeap) Push(x interface{}) { *c = append(*c, x.(hnswCandidate)) }
+func (c *candidateMinHeap) Pop() interface{} {
+	old := *c
+	item := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return item
+}
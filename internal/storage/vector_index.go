@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Vector index types
+const (
+	VectorIndexFlat = "flat"
+	VectorIndexHNSW = "hnsw"
+)
+
+// VectorIndexConfig selects and tunes a vector index implementation
+type VectorIndexConfig struct {
+	// Type is "flat" for exact brute-force search or "hnsw" for an
+	// approximate hierarchical navigable small world graph
+	Type string `json:"type"`
+
+	// M is the number of neighbours each HNSW node keeps per layer
+	M int `json:"m"`
+
+	// EfConstruction is the candidate list size used while inserting
+	EfConstruction int `json:"ef_construction"`
+
+	// EfSearch is the candidate list size used while searching; it is
+	// raised to k when k is larger
+	EfSearch int `json:"ef_search"`
+}
+
+// DefaultVectorIndexConfig returns an exact index, which is fast enough for
+// tens of thousands of documents
+func DefaultVectorIndexConfig() *VectorIndexConfig {
+	return &VectorIndexConfig{
+		Type:           VectorIndexFlat,
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+	}
+}
+
+// VectorEntry is a document's embedding plus the fields search results can
+// be filtered on
+type VectorEntry struct {
+	ID       string            `json:"id"`
+	Vector   []float32         `json:"-"`
+	Type     string            `json:"type"`
+	URL      string            `json:"url,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// VectorMatch is a search result scored by cosine similarity
+type VectorMatch struct {
+	ID       string            `json:"id"`
+	Score    float32           `json:"score"`
+	Type     string            `json:"type"`
+	URL      string            `json:"url,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// VectorIndex stores document embeddings for nearest-neighbour search.
+// Implementations are safe for concurrent use.
+type VectorIndex interface {
+	// Upsert adds an entry or replaces the entry with the same ID
+	Upsert(entry *VectorEntry) error
+
+	// Remove drops an entry; unknown IDs are ignored
+	Remove(id string)
+
+	// Get returns the entry with the given ID
+	Get(id string) (*VectorEntry, bool)
+
+	// Search returns up to k entries most similar to query that match all
+	// filters, best first
+	Search(query []float32, k int, filters map[string]string) ([]VectorMatch, error)
+
+	// Size returns the number of live entries
+	Size() int
+}
+
+// NewVectorIndex creates the index described by config
+func NewVectorIndex(config *VectorIndexConfig) (VectorIndex, error) {
+	if config == nil {
+		config = DefaultVectorIndexConfig()
+	}
+
+	switch config.Type {
+	case "", VectorIndexFlat:
+		return NewFlatVectorIndex(), nil
+	case VectorIndexHNSW:
+		return NewHNSWVectorIndex(config), nil
+	default:
+		return nil, fmt.Errorf("unsupported vector index type: %s", config.Type)
+	}
+}
+
+// matches reports whether the entry satisfies every filter
+func (e *VectorEntry) matches(filters map[string]string) bool {
+	return matchesFilters(e.Type, e.URL, e.Metadata, filters)
+}
+
+// matchesFilters reports whether a document satisfies every filter. "type"
+// and "source" match the document's source type and URL; other keys match
+// content metadata.
+func matchesFilters(docType, url string, metadata map[string]string, filters map[string]string) bool {
+	for key, value := range filters {
+		switch key {
+		case "type":
+			if docType != value {
+				return false
+			}
+		case "source":
+			if url != value {
+				return false
+			}
+		default:
+			if metadata[key] != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *VectorEntry) match(score float32) VectorMatch {
+	return VectorMatch{
+		ID:       e.ID,
+		Score:    score,
+		Type:     e.Type,
+		URL:      e.URL,
+		Metadata: e.Metadata,
+	}
+}
+
+// normalizeVector returns a unit-length copy of v so cosine similarity
+// reduces to a dot product
+func normalizeVector(v []float32) ([]float32, error) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("cannot index a zero vector")
+	}
+
+	norm := float32(math.Sqrt(sum))
+	result := make([]float32, len(v))
+	for i, x := range v {
+		result[i] = x / norm
+	}
+	return result, nil
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// prepareEntry validates an entry against the index dimension and returns a
+// copy holding a normalized vector
+func prepareEntry(entry *VectorEntry, dimension int) (*VectorEntry, error) {
+	if entry.ID == "" {
+		return nil, fmt.Errorf("vector entry ID cannot be empty")
+	}
+	if dimension != 0 && len(entry.Vector) != dimension {
+		return nil, fmt.Errorf("vector dimension mismatch for %s: got %d, index has %d", entry.ID, len(entry.Vector), dimension)
+	}
+
+	vector, err := normalizeVector(entry.Vector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vector for %s: %w", entry.ID, err)
+	}
+
+	prepared := *entry
+	prepared.Vector = vector
+	return &prepared, nil
+}
+
+// FlatVectorIndex scores every entry on each search. Results are exact.
+type FlatVectorIndex struct {
+	mu        sync.RWMutex
+	entries   map[string]*VectorEntry
+	dimension int
+}
+
+// NewFlatVectorIndex creates an empty brute-force index
+func NewFlatVectorIndex() *FlatVectorIndex {
+	return &FlatVectorIndex{
+		entries: make(map[string]*VectorEntry),
+	}
+}
+
+func (f *FlatVectorIndex) Upsert(entry *VectorEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prepared, err := prepareEntry(entry, f.dimension)
+	if err != nil {
+		return err
+	}
+	f.dimension = len(prepared.Vector)
+	f.entries[prepared.ID] = prepared
+	return nil
+}
+
+func (f *FlatVectorIndex) Remove(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.entries, id)
+}
+
+func (f *FlatVectorIndex) Get(id string) (*VectorEntry, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entry, ok := f.entries[id]
+	return entry, ok
+}
+
+func (f *FlatVectorIndex) Search(query []float32, k int, filters map[string]string) ([]VectorMatch, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if k <= 0 || len(f.entries) == 0 {
+		return []VectorMatch{}, nil
+	}
+	if len(query) != f.dimension {
+		return nil, fmt.Errorf("query dimension %d does not match index dimension %d", len(query), f.dimension)
+	}
+	q, err := normalizeVector(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VectorMatch, 0, len(f.entries))
+	for _, entry := range f.entries {
+		if entry.matches(filters) {
+			results = append(results, entry.match(dot(q, entry.Vector)))
+		}
+	}
+	return topMatches(results, k), nil
+}
+
+func (f *FlatVectorIndex) Size() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.entries)
+}
+
+// topMatches sorts matches best first, breaking ties by ID, and keeps k
+func topMatches(matches []VectorMatch, k int) []VectorMatch {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
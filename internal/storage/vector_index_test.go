@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	return v
+}
+
+// TestVectorIndexes tests that the HNSW index agrees with exact search
+func TestVectorIndexes(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	const dim, count, k = 32, 500, 10
+
+	flat := NewFlatVectorIndex()
+	hnsw := NewHNSWVectorIndex(DefaultVectorIndexConfig())
+
+	for i := 0; i < count; i++ {
+		entry := &VectorEntry{
+			ID:       fmt.Sprintf("doc-%03d", i),
+			Vector:   randomVector(rng, dim),
+			Type:     []string{"text", "html"}[i%2],
+			Metadata: map[string]string{"bucket": fmt.Sprintf("%d", i%5)},
+		}
+		require.NoError(t, flat.Upsert(entry))
+		require.NoError(t, hnsw.Upsert(entry))
+	}
+
+	err := flat.Upsert(&VectorEntry{ID: "short", Vector: []float32{1, 2}})
+	assert.Error(t, err, "Dimension mismatch should be rejected")
+
+	// Recall of the approximate index against exact results
+	found, total := 0, 0
+	for q := 0; q < 20; q++ {
+		query := randomVector(rng, dim)
+		exact, err := flat.Search(query, k, nil)
+		require.NoError(t, err)
+		approx, err := hnsw.Search(query, k, nil)
+		require.NoError(t, err)
+		require.Len(t, approx, k)
+
+		want := make(map[string]bool)
+		for _, m := range exact {
+			want[m.ID] = true
+		}
+		for _, m := range approx {
+			if want[m.ID] {
+				found++
+			}
+		}
+		total += k
+	}
+	assert.GreaterOrEqual(t, float64(found)/float64(total), 0.9, "HNSW recall too low")
+
+	// Filters apply to both implementations
+	query := randomVector(rng, dim)
+	for name, index := range map[string]VectorIndex{"flat": flat, "hnsw": hnsw} {
+		results, err := index.Search(query, k, map[string]string{"type": "html", "bucket": "3"})
+		require.NoError(t, err, name)
+		require.Len(t, results, k, name)
+		for i, m := range results {
+			assert.Equal(t, "html", m.Type, name)
+			assert.Equal(t, "3", m.Metadata["bucket"], name)
+			if i > 0 {
+				assert.LessOrEqual(t, m.Score, results[i-1].Score, name)
+			}
+		}
+	}
+
+	// Removal and replacement
+	for _, index := range []VectorIndex{flat, hnsw} {
+		index.Remove("doc-000")
+		_, ok := index.Get("doc-000")
+		assert.False(t, ok)
+		assert.Equal(t, count-1, index.Size())
+
+		target := randomVector(rng, dim)
+		require.NoError(t, index.Upsert(&VectorEntry{ID: "doc-001", Vector: target}))
+		results, err := index.Search(target, 1, nil)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "doc-001", results[0].ID)
+		assert.InDelta(t, 1.0, results[0].Score, 1e-5)
+	}
+}
+
+// TestVectorSearcher tests that the searcher follows document events from govc
+func TestVectorSearcher(t *testing.T) {
+	backend, err := NewGovcBackend("vector-test", nil)
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	newDoc := func(id string, embeddings []float32) *document.Document {
+		doc := newLifecycleDoc(id)
+		doc.Content.Embeddings = embeddings
+		return doc
+	}
+
+	// Indexed at load time
+	_, err = backend.StoreDocument(ctx, newDoc("north", []float32{1, 0, 0}))
+	require.NoError(t, err)
+
+	stored, err := backend.GetDocument(ctx, "north")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 0, 0}, stored.Content.Embeddings, "Embeddings should round-trip through storage")
+
+	searcher, err := NewVectorSearcher(backend, &VectorIndexConfig{Type: VectorIndexHNSW})
+	require.NoError(t, err)
+	indexed, err := searcher.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, indexed)
+
+	require.NoError(t, searcher.Subscribe(backend.GetEventBus()))
+	defer searcher.Close()
+
+	// Indexed from events
+	_, err = backend.StoreDocument(ctx, newDoc("north-east", []float32{1, 1, 0}))
+	require.NoError(t, err)
+	_, err = backend.StoreDocument(ctx, newDoc("up", []float32{0, 0, 1}))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return searcher.Size() == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	results, err := searcher.SearchByDocument(ctx, "north", 2, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "north-east", results[0].ID)
+	assert.InDelta(t, 0.7071, results[0].Score, 1e-3)
+	assert.Equal(t, "Original", results[0].Metadata["title"])
+
+	filtered, err := searcher.SearchByDocument(ctx, "north", 2, map[string]string{"title": "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	_, err = backend.DeleteDocument(ctx, "north-east", "test")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return searcher.Size() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	results, err = searcher.SearchByVector([]float32{1, 0.9, 0}, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "north", results[0].ID)
+}
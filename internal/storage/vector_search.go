@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
+	"github.com/rs/zerolog/log"
+)
+
+// VectorSearcher keeps a vector index of document embeddings in step with a
+// storage backend and answers similarity queries against it
+type VectorSearcher struct {
+	*eventIndex
+	index VectorIndex
+
+	// model, when set, is the only embedding model whose vectors are
+	// indexed; mismatched counts documents skipped for another model
+	modelMu    sync.Mutex
+	model      string
+	mismatched int64
+}
+
+// NewVectorSearcher creates a searcher over backend using the configured index
+func NewVectorSearcher(backend StorageBackend, config *VectorIndexConfig) (*VectorSearcher, error) {
+	index, err := NewVectorIndex(config)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VectorSearcher{index: index}
+	v.eventIndex = newEventIndex("Vector", backend, v.apply)
+	return v, nil
+}
+
+// ExpectModel restricts the index to vectors from the named embedding model.
+// Documents whose recorded model differs are skipped and counted, since
+// their vectors are not comparable; documents with no recorded model are
+// accepted if their dimensions fit.
+func (v *VectorSearcher) ExpectModel(model string) {
+	v.modelMu.Lock()
+	defer v.modelMu.Unlock()
+
+	v.model = model
+}
+
+// Mismatched returns the number of documents skipped because their
+// embeddings came from a different model
+func (v *VectorSearcher) Mismatched() int64 {
+	v.modelMu.Lock()
+	defer v.modelMu.Unlock()
+
+	return v.mismatched
+}
+
+// SearchByVector returns the k documents most similar to query
+func (v *VectorSearcher) SearchByVector(query []float32, k int, filters map[string]string) ([]VectorMatch, error) {
+	return v.index.Search(query, k, filters)
+}
+
+// SearchByDocument returns the k documents most similar to the document
+// with the given ID, excluding the document itself
+func (v *VectorSearcher) SearchByDocument(ctx context.Context, id string, k int, filters map[string]string) ([]VectorMatch, error) {
+	var vector []float32
+	if entry, ok := v.index.Get(id); ok {
+		vector = entry.Vector
+	} else {
+		doc, err := v.backend.GetDocument(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content.Embeddings) == 0 {
+			return nil, fmt.Errorf("document has no embeddings: %s", id)
+		}
+		vector = doc.Content.Embeddings
+	}
+
+	matches, err := v.index.Search(vector, k+1, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VectorMatch, 0, k)
+	for _, match := range matches {
+		if match.ID != id && len(results) < k {
+			results = append(results, match)
+		}
+	}
+	return results, nil
+}
+
+// Size returns the number of indexed documents
+func (v *VectorSearcher) Size() int {
+	return v.index.Size()
+}
+
+// apply indexes or removes doc and reports whether it is now indexed.
+// Documents without embeddings are not indexed.
+func (v *VectorSearcher) apply(doc *document.Document, deleted bool) bool {
+	v.modelMu.Lock()
+	defer v.modelMu.Unlock()
+
+	if deleted || len(doc.Content.Embeddings) == 0 {
+		v.index.Remove(doc.ID)
+		return false
+	}
+
+	if model := doc.Content.Metadata[embedder.MetaModel]; v.model != "" && model != "" && model != v.model {
+		v.mismatched++
+		v.index.Remove(doc.ID)
+		log.Warn().
+			Str("document_id", doc.ID).
+			Str("model", model).
+			Str("expected_model", v.model).
+			Msg("Skipping embeddings from a different model")
+		return false
+	}
+
+	err := v.index.Upsert(&VectorEntry{
+		ID:       doc.ID,
+		Vector:   doc.Content.Embeddings,
+		Type:     doc.Source.Type,
+		URL:      doc.Source.URL,
+		Metadata: doc.Content.Metadata,
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("document_id", doc.ID).Msg("Failed to index document embeddings")
+		return false
+	}
+	return true
+}
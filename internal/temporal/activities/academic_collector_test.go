@@ -4,6 +4,11 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -185,10 +190,10 @@ func TestAcademicCollectorActivities_AllSourcesHaveAttribution(t *testing.T) {
 			}
 			
 			// We don't care if it fails, just that it tries to add attribution
-			docs, _ := collector.CollectAcademicSourcesActivity(ctx, input)
+			result, _ := collector.CollectAcademicSourcesActivity(ctx, input)
 			
 			// If we got any documents, verify they have attribution
-			for _, doc := range docs {
+			for _, doc := range result.Documents {
 				assert.NotEmpty(t, doc.Metadata["attribution"], "Document should have attribution")
 				assert.Contains(t, doc.Metadata["attribution"], "Caia Tech", "Attribution should mention Caia Tech")
 				assert.NotEmpty(t, doc.Metadata["source"], "Document should have source")
@@ -215,4 +220,275 @@ func BenchmarkAcademicCollectorActivities_CollectArXiv(b *testing.B) {
 		// Skip actual HTTP calls in benchmark
 		collector.formatAuthors([]ArXivAuthor{{Name: "Test Author"}})
 	}
-}
\ No newline at end of file
+}
+// academicFixtures serves recorded API responses from testdata/academic.
+// route names the fixture for a request; requests are recorded in order.
+type academicFixtures struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []*url.URL
+}
+
+func newAcademicFixtures(t *testing.T, route func(r *http.Request) string) *academicFixtures {
+	f := &academicFixtures{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("User-Agent"), "Academic-Research-Bot")
+
+		f.mu.Lock()
+		f.requests = append(f.requests, r.URL)
+		f.mu.Unlock()
+
+		name := route(r)
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "academic", name))
+		require.NoError(t, err)
+		w.Write(data)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func newFixtureCollector(f *academicFixtures) *AcademicCollectorActivities {
+	collector := NewAcademicCollectorActivities()
+	collector.pubmedURL = f.server.URL + "/eutils"
+	collector.doajURL = f.server.URL + "/doaj"
+	collector.plosURL = f.server.URL + "/plos"
+	collector.pageSize = 2
+	return collector
+}
+
+func docsByID(docs []workflows.CollectedDocument) map[string]workflows.CollectedDocument {
+	byID := make(map[string]workflows.CollectedDocument, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+	return byID
+}
+
+func TestAcademicCollectorActivities_CollectPubMed(t *testing.T) {
+	fixtures := newAcademicFixtures(t, func(r *http.Request) string {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/eutils/esearch.fcgi":
+			if q.Get("retstart") == "2" {
+				return "pmc_esearch_2.json"
+			}
+			return "pmc_esearch_1.json"
+		case "/eutils/efetch.fcgi":
+			if q.Get("id") == "10899001" {
+				return "pmc_efetch_2.xml"
+			}
+			return "pmc_efetch_1.xml"
+		}
+		return ""
+	})
+	collector := newFixtureCollector(fixtures)
+
+	input := workflows.ScheduledIngestionInput{
+		Name:     "pubmed",
+		Filters:  []string{"machine learning", "deep learning"},
+		Metadata: map[string]string{"collection": "health-ai"},
+	}
+	docs, oldest, exhausted, err := collector.collectPubMed(context.Background(), input, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, exhausted)
+	require.Len(t, docs, 3)
+	assert.Equal(t, time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), oldest, "A month-only date is taken as the end of its month")
+
+	// esearch and efetch for each of two pages
+	require.Len(t, fixtures.requests, 4)
+	search := fixtures.requests[0].Query()
+	assert.Equal(t, "pmc", search.Get("db"))
+	assert.Equal(t, "(machine learning) OR (deep learning) AND open access[filter]", search.Get("term"))
+	assert.Equal(t, "caia-library", search.Get("tool"))
+	assert.Equal(t, "library@caiatech.com", search.Get("email"))
+	assert.Empty(t, search.Get("mindate"))
+	assert.Equal(t, "10912345,10908765", fixtures.requests[1].Query().Get("id"))
+
+	byID := docsByID(docs)
+	retina := byID["pmc-PMC10912345"]
+	assert.Equal(t, "https://www.ncbi.nlm.nih.gov/pmc/articles/PMC10912345/", retina.URL)
+	assert.Equal(t, "html", retina.Type)
+	assert.Equal(t, "Deep learning for in vivo imaging of retinal cells", retina.Metadata["title"])
+	assert.Equal(t, "Adaeze Okafor, Maja Lindqvist", retina.Metadata["authors"])
+	assert.Equal(t, "We present a convolutional network that segments retinal cells & vessels in adaptive optics images.", retina.Metadata["abstract"])
+	assert.Equal(t, "2024-03-07", retina.Metadata["published"])
+	assert.Equal(t, "Scientific Reports", retina.Metadata["journal"])
+	assert.Equal(t, "10.1038/s41598-024-00001-1", retina.Metadata["doi"])
+	assert.Equal(t, "38512345", retina.Metadata["pmid"])
+	assert.Equal(t, "CC BY 4.0", retina.Metadata["license"])
+	assert.Equal(t, "https://creativecommons.org/licenses/by/4.0/", retina.Metadata["license_url"])
+	assert.Equal(t, "PubMed Central", retina.Metadata["source"])
+	assert.Contains(t, retina.Metadata["attribution"], "Caia Tech")
+	assert.Equal(t, "health-ai", retina.Metadata["collection"])
+
+	review := byID["pmc-PMC10908765"]
+	assert.Equal(t, "The CDS Review Group", review.Metadata["authors"])
+	assert.Equal(t, "2024-02-28", review.Metadata["published"])
+	assert.Equal(t, "CC BY-NC 4.0", review.Metadata["license"])
+
+	federated := byID["pmc-PMC10899001"]
+	assert.Equal(t, "2024-02", federated.Metadata["published"])
+	assert.Equal(t, "unknown", federated.Metadata["license"])
+}
+
+func TestAcademicCollectorActivities_CollectDOAJ(t *testing.T) {
+	fixtures := newAcademicFixtures(t, func(r *http.Request) string {
+		if !strings.HasPrefix(r.URL.Path, "/doaj/search/articles/") {
+			return ""
+		}
+		return "doaj_" + r.URL.Query().Get("page") + ".json"
+	})
+	collector := newFixtureCollector(fixtures)
+
+	since := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	docs, _, exhausted, err := collector.collectDOAJ(context.Background(), workflows.ScheduledIngestionInput{Name: "doaj"}, since, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, exhausted)
+
+	// The article without a full text link is skipped
+	require.Len(t, docs, 2)
+	require.Len(t, fixtures.requests, 2)
+	assert.Equal(t, "/doaj/search/articles/(artificial intelligence) AND created_date:[2024-03-01 TO *]", fixtures.requests[0].Path)
+	assert.Equal(t, "2", fixtures.requests[1].Query().Get("page"))
+
+	byID := docsByID(docs)
+	soil := byID["doaj-4f1a2b3c4d5e6f708192a3b4c5d6e7f8"]
+	assert.Equal(t, "https://www.mdpi.com/2072-4292/16/5/901", soil.URL)
+	assert.Equal(t, "html", soil.Type)
+	assert.Equal(t, "We compare gradient boosting and neural networks for estimating soil moisture from satellite data.", soil.Metadata["abstract"])
+	assert.Equal(t, "Lucía Fernández, Tomasz Nowak", soil.Metadata["authors"])
+	assert.Equal(t, "2024-03", soil.Metadata["published"])
+	assert.Equal(t, "10.3390/rs16050901", soil.Metadata["doi"])
+	assert.Equal(t, "CC BY", soil.Metadata["license"])
+	assert.Equal(t, "journal", soil.Metadata["license_source"])
+
+	grading := byID["doaj-9a8b7c6d5e4f30211203f4e5d6c7b8a9"]
+	assert.Equal(t, "pdf", grading.Type)
+	assert.Equal(t, "CC BY-SA 4.0", grading.Metadata["license"])
+	assert.Equal(t, "10.1234/jhe.2024.017", grading.Metadata["doi"])
+}
+
+func TestAcademicCollectorActivities_CollectPLOS(t *testing.T) {
+	fixtures := newAcademicFixtures(t, func(r *http.Request) string {
+		if r.URL.Path != "/plos/search" {
+			return ""
+		}
+		if r.URL.Query().Get("start") == "2" {
+			return "plos_2.json"
+		}
+		return "plos_1.json"
+	})
+	collector := newFixtureCollector(fixtures)
+
+	docs, oldest, exhausted, err := collector.collectPLOS(context.Background(), workflows.ScheduledIngestionInput{Name: "plos"}, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.True(t, exhausted)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), oldest)
+	require.Len(t, docs, 3)
+	require.Len(t, fixtures.requests, 2)
+
+	query := fixtures.requests[0].Query()
+	assert.Equal(t, `"artificial intelligence"`, query.Get("q"))
+	assert.Equal(t, []string{"doc_type:full"}, query["fq"])
+	assert.Equal(t, "publication_date desc", query.Get("sort"))
+
+	protein := docsByID(docs)["plos-10.1371/journal.pone.0299001"]
+	assert.Equal(t, "https://doi.org/10.1371/journal.pone.0299001", protein.URL)
+	assert.Equal(t, "Predicting protein stability with graph neural networks", protein.Metadata["title"])
+	assert.Equal(t, "Wei Zhang, Fatima Al-Sayed, Jonas Berg", protein.Metadata["authors"])
+	assert.Equal(t, "We train graph neural networks on mutational scans to predict stability changes.", protein.Metadata["abstract"])
+	assert.Equal(t, "2024-03-06", protein.Metadata["published"])
+	assert.Equal(t, "PLOS ONE", protein.Metadata["journal"])
+	assert.Equal(t, "CC BY 4.0", protein.Metadata["license"])
+	assert.Equal(t, "publisher", protein.Metadata["license_source"])
+}
+
+func TestAcademicCollectorActivities_IncrementalCursors(t *testing.T) {
+	failing := false
+	fixtures := newAcademicFixtures(t, func(r *http.Request) string {
+		if failing {
+			return ""
+		}
+		if r.URL.Query().Get("start") == "2" {
+			return "plos_2.json"
+		}
+		return "plos_1.json"
+	})
+	collector := newFixtureCollector(fixtures)
+
+	cursorPath := filepath.Join(t.TempDir(), "cursors.json")
+	cursors, err := LoadCollectionCursors(cursorPath)
+	require.NoError(t, err)
+	collector.UseCursors(cursors)
+
+	ctx := context.Background()
+	input := workflows.ScheduledIngestionInput{Name: "plos", Filters: []string{"genomics"}}
+	key := cursorKey("plos", input.Filters)
+	lastQuery := func() url.Values { return fixtures.requests[len(fixtures.requests)-1].Query() }
+
+	// The first run has no cursor and asks for the most recent articles.
+	// Its cursor is only saved once the workflow has ingested what it found.
+	result, err := collector.CollectAcademicSourcesActivity(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"doc_type:full"}, fixtures.requests[0].Query()["fq"])
+	require.NotNil(t, result.Cursor)
+	assert.True(t, cursors.Get(key).Since.IsZero())
+	require.NoError(t, collector.AdvanceCollectionCursorActivity(ctx, *result.Cursor))
+
+	// The next run only asks for articles since the first one
+	_, err = collector.CollectAcademicSourcesActivity(ctx, input)
+	require.NoError(t, err)
+	today := time.Now().UTC().Format("2006-01-02")
+	assert.Equal(t, []string{"doc_type:full", "publication_date:[" + today + "T00:00:00Z TO *]"}, lastQuery()["fq"])
+
+	// Cursors survive a restart, and a failed run returns none
+	reloaded, err := LoadCollectionCursors(cursorPath)
+	require.NoError(t, err)
+	since := reloaded.Get(key).Since
+	assert.False(t, since.IsZero())
+	assert.True(t, reloaded.Get(cursorKey("plos", nil)).Since.IsZero())
+
+	failing = true
+	_, err = collector.CollectAcademicSourcesActivity(ctx, input)
+	assert.ErrorContains(t, err, "status 404")
+	assert.True(t, cursors.Get(key).Since.Equal(since))
+
+	// A run that stops at maxResults keeps Since, and the next run asks
+	// for the older articles it did not reach
+	failing = false
+	collector.maxResults = 2
+	result, err = collector.CollectAcademicSourcesActivity(ctx, input)
+	require.NoError(t, err)
+	assert.Len(t, result.Documents, 2)
+	cut := *result.Cursor
+	assert.True(t, cut.Since.Equal(since))
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), cut.Until)
+	assert.False(t, cut.Next.Before(since))
+	require.NoError(t, collector.AdvanceCollectionCursorActivity(ctx, cut))
+
+	collector.maxResults = 200
+	result, err = collector.CollectAcademicSourcesActivity(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "publication_date:["+since.Format("2006-01-02")+"T00:00:00Z TO 2024-03-04T23:59:59Z]", lastQuery()["fq"][1])
+
+	// Once the older articles are collected, Since moves to the start of
+	// the run that was cut short
+	assert.True(t, result.Cursor.Since.Equal(cut.Next))
+	assert.True(t, result.Cursor.Until.IsZero())
+}
+
+func TestLoadCollectionCursors_Timestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cursors.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"plos|genomics": "2024-03-01T09:30:00Z"}`), 0644))
+
+	cursors, err := LoadCollectionCursors(path)
+	require.NoError(t, err)
+	cursor := cursors.Get("plos|genomics")
+	assert.Equal(t, "plos|genomics", cursor.Key)
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), cursor.Since)
+	assert.True(t, cursor.Until.IsZero())
+}
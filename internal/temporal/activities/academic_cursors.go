@@ -0,0 +1,101 @@
+package activities
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+)
+
+// CollectionCursors remembers how far each academic query has been
+// collected, so the next run only asks the source for articles it has not
+// seen. Cursors are kept in memory, and in a JSON file when opened with
+// LoadCollectionCursors.
+type CollectionCursors struct {
+	mu      sync.Mutex
+	path    string
+	cursors map[string]workflows.CollectionCursor
+}
+
+// NewCollectionCursors creates cursors that last as long as the process
+func NewCollectionCursors() *CollectionCursors {
+	return &CollectionCursors{cursors: make(map[string]workflows.CollectionCursor)}
+}
+
+// LoadCollectionCursors opens the cursors saved at path. A missing file
+// starts with no cursors. Files that saved only when each query last ran to
+// completion are read as cursors with just Since set.
+func LoadCollectionCursors(path string) (*CollectionCursors, error) {
+	c := NewCollectionCursors()
+	c.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection cursors: %w", err)
+	}
+
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse collection cursors: %w", err)
+	}
+	for key, raw := range saved {
+		cursor := workflows.CollectionCursor{Key: key}
+		if err := json.Unmarshal(raw, &cursor.Since); err != nil {
+			if err := json.Unmarshal(raw, &cursor); err != nil {
+				return nil, fmt.Errorf("failed to parse collection cursor %s: %w", key, err)
+			}
+		}
+		c.cursors[key] = cursor
+	}
+	return c, nil
+}
+
+// cursorKey identifies a query: the same source with different filters is
+// collected independently
+func cursorKey(source string, filters []string) string {
+	return source + "|" + strings.Join(filters, ",")
+}
+
+// Get returns the query's cursor; a query that has never run has a zero
+// one
+func (c *CollectionCursors) Get(key string) workflows.CollectionCursor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cursor, ok := c.cursors[key]
+	if !ok {
+		cursor.Key = key
+	}
+	return cursor
+}
+
+// Save records a query's cursor and saves the cursors
+func (c *CollectionCursors) Save(cursor workflows.CollectionCursor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cursors[cursor.Key] = cursor
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.cursors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode collection cursors: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cursor directory: %w", err)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write collection cursors: %w", err)
+	}
+	return os.Rename(tmpPath, c.path)
+}
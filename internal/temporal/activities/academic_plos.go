@@ -0,0 +1,125 @@
+package activities
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+)
+
+// PLOS publishes every article under CC BY 4.0, and its search API has no
+// per-article license field
+const (
+	plosLicense    = "CC BY 4.0"
+	plosLicenseURL = "https://creativecommons.org/licenses/by/4.0/"
+)
+
+// plosFields are the Solr fields requested for each article
+var plosFields = []string{"id", "title_display", "author_display", "abstract", "publication_date", "journal", "article_type"}
+
+// collectPLOS pages through the PLOS Solr search API, newest first,
+// returning full articles only
+//
+// PLOS API: https://api.plos.org/solr/faq/
+func (a *AcademicCollectorActivities) collectPLOS(ctx context.Context, input workflows.ScheduledIngestionInput, since, until time.Time) ([]workflows.CollectedDocument, time.Time, bool, error) {
+	query := academicQuery(input.Filters, `"artificial intelligence"`)
+
+	var documents []workflows.CollectedDocument
+	var oldest time.Time
+	exhausted := false
+	for start := 0; start < a.maxResults; start += a.pageSize {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Add("fq", "doc_type:full")
+		if !since.IsZero() || !until.IsZero() {
+			from, to := "*", "*"
+			if !since.IsZero() {
+				from = since.UTC().Format("2006-01-02") + "T00:00:00Z"
+			}
+			if !until.IsZero() {
+				to = until.UTC().Format("2006-01-02") + "T23:59:59Z"
+			}
+			params.Add("fq", "publication_date:["+from+" TO "+to+"]")
+		}
+		params.Set("fl", strings.Join(plosFields, ","))
+		params.Set("sort", "publication_date desc")
+		params.Set("start", strconv.Itoa(start))
+		params.Set("rows", strconv.Itoa(min(a.pageSize, a.maxResults-start)))
+		params.Set("wt", "json")
+
+		var result plosSearchResult
+		if err := a.getJSON(ctx, "plos", a.plosURL+"/search?"+params.Encode(), &result); err != nil {
+			return nil, time.Time{}, false, err
+		}
+
+		for _, article := range result.Response.Docs {
+			if published, err := time.Parse(time.RFC3339, article.PublicationDate); err == nil {
+				oldest = earliest(oldest, published)
+			}
+			if doc, ok := a.plosDocument(input, article); ok {
+				documents = append(documents, doc)
+			}
+		}
+
+		if len(result.Response.Docs) == 0 || start+len(result.Response.Docs) >= result.Response.NumFound {
+			exhausted = true
+			break
+		}
+	}
+	return documents, oldest, exhausted, nil
+}
+
+// plosDocument converts a PLOS search result to a collected document
+func (a *AcademicCollectorActivities) plosDocument(input workflows.ScheduledIngestionInput, article plosArticle) (workflows.CollectedDocument, bool) {
+	if article.ID == "" {
+		return workflows.CollectedDocument{}, false
+	}
+	articleURL := "https://doi.org/" + article.ID
+
+	published := article.PublicationDate
+	if t, err := time.Parse(time.RFC3339, published); err == nil {
+		published = t.Format("2006-01-02")
+	}
+
+	metadata := map[string]string{
+		"title":          cleanText(article.Title),
+		"authors":        strings.Join(article.Authors, ", "),
+		"abstract":       cleanText(strings.Join(article.Abstract, " ")),
+		"published":      published,
+		"journal":        article.Journal,
+		"article_type":   article.ArticleType,
+		"doi":            article.ID,
+		"source":         "PLOS",
+		"source_url":     articleURL,
+		"license":        plosLicense,
+		"license_url":    plosLicenseURL,
+		"license_source": "publisher",
+		"attribution":    "CC-BY content from PLOS, collected by Caia Tech (https://caiatech.com)",
+		"ethical_notice": "All PLOS content is Open Access under CC-BY",
+	}
+
+	return a.collectedDocument(input, "plos-"+article.ID, articleURL, "html", metadata), true
+}
+
+// PLOS response structures
+
+type plosSearchResult struct {
+	Response struct {
+		NumFound int           `json:"numFound"`
+		Start    int           `json:"start"`
+		Docs     []plosArticle `json:"docs"`
+	} `json:"response"`
+}
+
+type plosArticle struct {
+	ID              string   `json:"id"`
+	Title           string   `json:"title_display"`
+	Authors         []string `json:"author_display"`
+	Abstract        []string `json:"abstract"`
+	PublicationDate string   `json:"publication_date"`
+	Journal         string   `json:"journal"`
+	ArticleType     string   `json:"article_type"`
+}
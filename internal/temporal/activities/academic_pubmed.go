@@ -0,0 +1,304 @@
+package activities
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+)
+
+// collectPubMed searches PubMed Central's open access subset with the
+// E-utilities esearch endpoint and fetches each page of results as JATS XML
+// with efetch.
+//
+// E-utilities: https://www.ncbi.nlm.nih.gov/books/NBK25501/
+// Rate limit: 3 requests per second without an API key
+func (a *AcademicCollectorActivities) collectPubMed(ctx context.Context, input workflows.ScheduledIngestionInput, since, until time.Time) ([]workflows.CollectedDocument, time.Time, bool, error) {
+	term := academicQuery(input.Filters, "artificial intelligence") + " AND open access[filter]"
+
+	var documents []workflows.CollectedDocument
+	var oldest time.Time
+	exhausted := false
+	for start := 0; start < a.maxResults; start += a.pageSize {
+		ids, total, err := a.searchPubMed(ctx, term, since, until, start)
+		if err != nil {
+			return nil, time.Time{}, false, err
+		}
+		if len(ids) == 0 {
+			exhausted = true
+			break
+		}
+
+		articles, err := a.fetchPubMed(ctx, ids)
+		if err != nil {
+			return nil, time.Time{}, false, err
+		}
+		for _, article := range articles {
+			if published, ok := article.Meta.lastPublishedDay(); ok {
+				oldest = earliest(oldest, published)
+			}
+			if doc, ok := a.pubMedDocument(input, article); ok {
+				documents = append(documents, doc)
+			}
+		}
+
+		if start+len(ids) >= total {
+			exhausted = true
+			break
+		}
+	}
+	return documents, oldest, exhausted, nil
+}
+
+// searchPubMed returns a page of PMC IDs matching term published from
+// since through until, newest first, and the total number of matches
+func (a *AcademicCollectorActivities) searchPubMed(ctx context.Context, term string, since, until time.Time, start int) ([]string, int, error) {
+	params := a.pubMedParams()
+	params.Set("term", term)
+	params.Set("retmode", "json")
+	params.Set("sort", "pub_date")
+	params.Set("retstart", strconv.Itoa(start))
+	params.Set("retmax", strconv.Itoa(min(a.pageSize, a.maxResults-start)))
+	if !since.IsZero() || !until.IsZero() {
+		// E-utilities needs both ends of a date range
+		params.Set("datetype", "pdat")
+		params.Set("mindate", "1800")
+		params.Set("maxdate", "3000")
+		if !since.IsZero() {
+			params.Set("mindate", since.UTC().Format("2006/01/02"))
+		}
+		if !until.IsZero() {
+			params.Set("maxdate", until.UTC().Format("2006/01/02"))
+		}
+	}
+
+	var result pubMedSearchResult
+	if err := a.getJSON(ctx, "pubmed", a.pubmedURL+"/esearch.fcgi?"+params.Encode(), &result); err != nil {
+		return nil, 0, err
+	}
+
+	total, _ := strconv.Atoi(result.Result.Count)
+	return result.Result.IDs, total, nil
+}
+
+// fetchPubMed fetches the JATS records of PMC articles
+func (a *AcademicCollectorActivities) fetchPubMed(ctx context.Context, ids []string) ([]pmcArticle, error) {
+	params := a.pubMedParams()
+	params.Set("id", strings.Join(ids, ","))
+	params.Set("retmode", "xml")
+
+	body, err := a.get(ctx, "pubmed", a.pubmedURL+"/efetch.fcgi?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var set pmcArticleSet
+	if err := xml.Unmarshal(body, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse PubMed Central articles: %w", err)
+	}
+	return set.Articles, nil
+}
+
+// pubMedParams returns the parameters common to every E-utilities request,
+// identifying the tool and a contact address as NCBI asks
+func (a *AcademicCollectorActivities) pubMedParams() url.Values {
+	params := url.Values{}
+	params.Set("db", "pmc")
+	params.Set("tool", "caia-library")
+	params.Set("email", "library@caiatech.com")
+	return params
+}
+
+// pubMedDocument converts a JATS record to a collected document. Records
+// without a PMC ID cannot be linked to and are skipped.
+func (a *AcademicCollectorActivities) pubMedDocument(input workflows.ScheduledIngestionInput, article pmcArticle) (workflows.CollectedDocument, bool) {
+	meta := article.Meta
+	pmcid := meta.articleID("pmc", "pmcid", "pmcaid")
+	if pmcid == "" {
+		return workflows.CollectedDocument{}, false
+	}
+	if !strings.HasPrefix(pmcid, "PMC") {
+		pmcid = "PMC" + pmcid
+	}
+	articleURL := fmt.Sprintf("https://www.ncbi.nlm.nih.gov/pmc/articles/%s/", pmcid)
+
+	license, licenseURL := meta.License.describe()
+	metadata := map[string]string{
+		"title":          cleanText(meta.Title.Inner),
+		"authors":        meta.authors(),
+		"abstract":       cleanText(meta.Abstract.Inner),
+		"published":      meta.published(),
+		"journal":        cleanText(article.Journal),
+		"pmcid":          pmcid,
+		"source":         "PubMed Central",
+		"source_url":     articleURL,
+		"license":        license,
+		"license_url":    licenseURL,
+		"license_source": "article",
+		"attribution":    "Content from PubMed Central, collected by Caia Tech (https://caiatech.com)",
+		"ethical_notice": "Collected in compliance with NCBI Terms and Conditions",
+	}
+	if doi := meta.articleID("doi"); doi != "" {
+		metadata["doi"] = doi
+	}
+	if pmid := meta.articleID("pmid"); pmid != "" {
+		metadata["pmid"] = pmid
+	}
+
+	return a.collectedDocument(input, "pmc-"+pmcid, articleURL, "html", metadata), true
+}
+
+// PubMed Central response structures
+
+type pubMedSearchResult struct {
+	Result struct {
+		Count string   `json:"count"`
+		IDs   []string `json:"idlist"`
+	} `json:"esearchresult"`
+}
+
+type pmcArticleSet struct {
+	Articles []pmcArticle `xml:"article"`
+}
+
+type pmcArticle struct {
+	Journal string         `xml:"front>journal-meta>journal-title-group>journal-title"`
+	Meta    pmcArticleMeta `xml:"front>article-meta"`
+}
+
+type pmcArticleMeta struct {
+	IDs      []pmcArticleID `xml:"article-id"`
+	Title    pmcMarkup      `xml:"title-group>article-title"`
+	Contribs []pmcContrib   `xml:"contrib-group>contrib"`
+	Abstract pmcMarkup      `xml:"abstract"`
+	PubDates []pmcDate      `xml:"pub-date"`
+	License  pmcLicense     `xml:"permissions>license"`
+}
+
+type pmcArticleID struct {
+	Type  string `xml:"pub-id-type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// pmcMarkup holds an element whose text may contain JATS markup
+type pmcMarkup struct {
+	Inner string `xml:",innerxml"`
+}
+
+type pmcContrib struct {
+	Type    string `xml:"contrib-type,attr"`
+	Surname string `xml:"name>surname"`
+	Given   string `xml:"name>given-names"`
+	Collab  string `xml:"collab"`
+}
+
+type pmcDate struct {
+	PubType  string `xml:"pub-type,attr"`
+	DateType string `xml:"date-type,attr"`
+	Year     string `xml:"year"`
+	Month    string `xml:"month"`
+	Day      string `xml:"day"`
+}
+
+type pmcLicense struct {
+	Type string    `xml:"license-type,attr"`
+	Href string    `xml:"href,attr"`
+	Ref  string    `xml:"license_ref"`
+	Text pmcMarkup `xml:"license-p"`
+}
+
+// articleID returns the first article ID of any of the given types
+func (m pmcArticleMeta) articleID(types ...string) string {
+	for _, t := range types {
+		for _, id := range m.IDs {
+			if id.Type == t && strings.TrimSpace(id.Value) != "" {
+				return strings.TrimSpace(id.Value)
+			}
+		}
+	}
+	return ""
+}
+
+func (m pmcArticleMeta) authors() string {
+	var names []string
+	for _, c := range m.Contribs {
+		if c.Type != "" && c.Type != "author" {
+			continue
+		}
+		switch {
+		case c.Surname != "" && c.Given != "":
+			names = append(names, strings.TrimSpace(c.Given)+" "+strings.TrimSpace(c.Surname))
+		case c.Surname != "":
+			names = append(names, strings.TrimSpace(c.Surname))
+		case c.Collab != "":
+			names = append(names, cleanText(c.Collab))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// published returns the electronic publication date, falling back to any
+// other, as YYYY, YYYY-MM or YYYY-MM-DD
+func (m pmcArticleMeta) published() string {
+	if len(m.PubDates) == 0 {
+		return ""
+	}
+	date := m.PubDates[0]
+	for _, d := range m.PubDates {
+		if d.PubType == "epub" || d.DateType == "pub" {
+			date = d
+			break
+		}
+	}
+
+	parts := []string{strings.TrimSpace(date.Year)}
+	for _, part := range []string{date.Month, date.Day} {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%02d", n))
+	}
+	return strings.Join(parts, "-")
+}
+
+// lastPublishedDay returns the last day the publication date covers: the
+// date itself, or the end of its month or year when only those are given
+func (m pmcArticleMeta) lastPublishedDay() (time.Time, bool) {
+	published := m.published()
+	if t, err := time.Parse("2006-01-02", published); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01", published); err == nil {
+		return t.AddDate(0, 1, -1), true
+	}
+	if t, err := time.Parse("2006", published); err == nil {
+		return t.AddDate(1, 0, -1), true
+	}
+	return time.Time{}, false
+}
+
+// describe names the license and returns its URL. Records without a
+// license element are reported as "unknown".
+func (l pmcLicense) describe() (string, string) {
+	licenseURL := strings.TrimSpace(l.Ref)
+	if licenseURL == "" {
+		licenseURL = strings.TrimSpace(l.Href)
+	}
+
+	if name := licenseName(licenseURL); name != "" {
+		return name, licenseURL
+	}
+	if l.Type != "" {
+		return l.Type, licenseURL
+	}
+	if text := cleanText(l.Text.Inner); text != "" {
+		return text, licenseURL
+	}
+	return "unknown", licenseURL
+}
@@ -195,7 +195,8 @@ func TestGenerateEmbeddingsActivity(t *testing.T) {
 	ctx := context.Background()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			embeddings, err := GenerateEmbeddingsActivity(ctx, tt.content)
+			result, err := GenerateEmbeddingsActivity(ctx, tt.content)
+			embeddings := result.Vector
 			
 			if tt.expectError {
 				assert.Error(t, err)
@@ -203,6 +204,8 @@ func TestGenerateEmbeddingsActivity(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.Len(t, embeddings, 384) // Expected embedding dimension
+				assert.NotEmpty(t, result.Model)
+				assert.Equal(t, len(embeddings), result.Dimensions)
 				
 				// Check that embeddings are not all zeros (basic sanity check)
 				hasNonZero := false
@@ -272,39 +275,6 @@ func TestStoreDocumentActivity(t *testing.T) {
 	// and ingest branches are created for document storage
 }
 
-func TestIndexDocumentActivity(t *testing.T) {
-	tests := []struct {
-		name        string
-		commitHash  string
-		expectError bool
-	}{
-		{
-			name:        "valid commit hash",
-			commitHash:  "abc123def456",
-			expectError: false,
-		},
-		{
-			name:        "empty commit hash",
-			commitHash:  "",
-			expectError: true,
-		},
-	}
-
-	ctx := context.Background()
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := IndexDocumentActivity(ctx, tt.commitHash)
-			
-			if tt.expectError {
-				assert.Error(t, err)
-			} else {
-				// For now, this activity is a placeholder, so it should always succeed
-				assert.NoError(t, err)
-			}
-		})
-	}
-}
-
 func TestMergeBranchActivity(t *testing.T) {
 	// Create temporary repository
 	tempDir, err := os.MkdirTemp("", "caia-merge-test-*")
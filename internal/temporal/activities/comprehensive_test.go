@@ -225,7 +225,8 @@ func TestEmbeddingsActivityPerformance(t *testing.T) {
 			}
 
 			start := time.Now()
-			embeddings, err := GenerateEmbeddingsActivity(ctx, content)
+			result, err := GenerateEmbeddingsActivity(ctx, content)
+			embeddings := result.Vector
 			duration := time.Since(start)
 
 			assert.NoError(t, err)
@@ -0,0 +1,48 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
+	"go.temporal.io/sdk/activity"
+)
+
+// Global dedup index - should be injected via dependency injection in production
+var globalDedupIndex *dedup.Index
+
+// SetGlobalDedupIndex sets the content dedup index used by activities.
+// Without one, content duplicates are neither detected nor recorded.
+func SetGlobalDedupIndex(index *dedup.Index) {
+	globalDedupIndex = index
+}
+
+// CheckContentDuplicateActivity looks up extracted text in the dedup index.
+// The result's Kind is empty when no duplicate is found.
+func CheckContentDuplicateActivity(ctx context.Context, input workflows.DuplicateCheckInput) (workflows.DuplicateCheckResult, error) {
+	logger := activity.GetLogger(ctx)
+
+	if globalDedupIndex == nil {
+		return workflows.DuplicateCheckResult{}, nil
+	}
+
+	fp := dedup.NewFingerprint(input.Text)
+	match := globalDedupIndex.FindOther(fp, input.PreviousDocumentID)
+	if match == nil {
+		return workflows.DuplicateCheckResult{ContentHash: fp.ContentHash}, nil
+	}
+
+	logger.Info("Found duplicate content",
+		"url", input.URL,
+		"kind", match.Kind,
+		"duplicateOf", match.DocumentID,
+		"similarity", match.Similarity)
+
+	return workflows.DuplicateCheckResult{
+		Kind:        string(match.Kind),
+		DocumentID:  match.DocumentID,
+		ClusterID:   match.ClusterID,
+		Similarity:  match.Similarity,
+		ContentHash: fp.ContentHash,
+	}, nil
+}
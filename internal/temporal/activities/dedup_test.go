@@ -0,0 +1,47 @@
+package activities
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// TestCheckContentDuplicateActivity tests content lookups against the dedup index
+func TestCheckContentDuplicateActivity(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(CheckContentDuplicateActivity)
+
+	text := "The committee met on Tuesday to review the annual budget and approved new funding for public libraries across the region."
+
+	index := dedup.NewIndex(dedup.DefaultConfig())
+	index.Add("stored-doc", dedup.NewFingerprint(text), "")
+	SetGlobalDedupIndex(index)
+	defer SetGlobalDedupIndex(nil)
+
+	check := func(input workflows.DuplicateCheckInput) workflows.DuplicateCheckResult {
+		val, err := env.ExecuteActivity(CheckContentDuplicateActivity, input)
+		require.NoError(t, err)
+		var result workflows.DuplicateCheckResult
+		require.NoError(t, val.Get(&result))
+		return result
+	}
+
+	mirror := check(workflows.DuplicateCheckInput{URL: "https://mirror.example.com/a", Text: strings.ToUpper(text)})
+	assert.Equal(t, "exact", mirror.Kind)
+	assert.Equal(t, "stored-doc", mirror.DocumentID)
+	assert.Equal(t, "stored-doc", mirror.ClusterID)
+
+	// A new version of the stored document is not its own duplicate
+	revision := check(workflows.DuplicateCheckInput{URL: "https://example.com/a", Text: text, PreviousDocumentID: "stored-doc"})
+	assert.Empty(t, revision.Kind)
+
+	fresh := check(workflows.DuplicateCheckInput{URL: "https://example.com/b", Text: "A short note about something else entirely, written for this test only."})
+	assert.Empty(t, fresh.Kind)
+	assert.NotEmpty(t, fresh.ContentHash)
+}
@@ -4,24 +4,47 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
 	"github.com/Caia-Tech/caia-library/pkg/embedder"
 	"go.temporal.io/sdk/activity"
 )
 
-func GenerateEmbeddingsActivity(ctx context.Context, content []byte) ([]float32, error) {
+// Global embedding engine - should be injected via dependency injection in production
+var globalEmbedder *embedder.Engine
+
+// SetGlobalEmbedder sets the embedding engine used by activities. Without
+// one, activities fall back to the default hash embedder.
+func SetGlobalEmbedder(engine *embedder.Engine) {
+	globalEmbedder = engine
+}
+
+func embeddingEngine() (*embedder.Engine, error) {
+	if globalEmbedder != nil {
+		return globalEmbedder, nil
+	}
+	return embedder.NewEngine()
+}
+
+// GenerateEmbeddingsActivity embeds content and reports the model that
+// produced the vector
+func GenerateEmbeddingsActivity(ctx context.Context, content []byte) (workflows.EmbeddingResult, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Generating embeddings", "contentSize", len(content))
 
-	engine, err := embedder.NewEngine()
+	engine, err := embeddingEngine()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create embedder: %w", err)
+		return workflows.EmbeddingResult{}, fmt.Errorf("failed to create embedder: %w", err)
 	}
 
 	embeddings, err := engine.Generate(ctx, string(content))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		return workflows.EmbeddingResult{}, fmt.Errorf("failed to generate embeddings: %w", err)
 	}
 
-	logger.Info("Embeddings generated successfully", "dimensions", len(embeddings))
-	return embeddings, nil
-}
\ No newline at end of file
+	logger.Info("Embeddings generated successfully", "model", engine.Model(), "dimensions", len(embeddings))
+	return workflows.EmbeddingResult{
+		Vector:     embeddings,
+		Model:      engine.Model(),
+		Dimensions: len(embeddings),
+	}, nil
+}
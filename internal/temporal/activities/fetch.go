@@ -5,12 +5,58 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/procurement/policy"
 	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
 	"go.temporal.io/sdk/activity"
 )
 
+// Global fetch validators - should be injected via dependency injection in production
+var globalFetchValidators *conditional.Store
+
+// SetGlobalFetchValidators sets the store of validators recorded for each
+// fetched URL. Without one, every fetch downloads the full document.
+func SetGlobalFetchValidators(store *conditional.Store) {
+	globalFetchValidators = store
+}
+
+// Global WARC writer - should be injected via dependency injection in production
+var globalWARCWriter *warc.FileWriter
+
+// SetGlobalWARCWriter sets the writer every fetch is captured to as WARC
+// request and response records. Without one, fetches are not archived.
+func SetGlobalWARCWriter(writer *warc.FileWriter) {
+	globalWARCWriter = writer
+}
+
+// Global domain policies - should be injected via dependency injection in production
+var globalPolicies *policy.Registry
+
+// SetGlobalPolicies sets the domain policies whose version fetched
+// documents record. Without them, documents record no policy.
+func SetGlobalPolicies(registry *policy.Registry) {
+	globalPolicies = registry
+}
+
+// policyMetadata returns the metadata recording the policy a document from
+// the URL is collected under
+func policyMetadata(rawURL string) map[string]string {
+	if globalPolicies == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	metadata := make(map[string]string)
+	globalPolicies.Lookup(u.Host).Stamp(metadata)
+	return metadata
+}
+
 func FetchDocumentActivity(ctx context.Context, url string) (workflows.FetchResult, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Fetching document", "url", url)
@@ -26,12 +72,27 @@ func FetchDocumentActivity(ctx context.Context, url string) (workflows.FetchResu
 
 	req.Header.Set("User-Agent", "CAIA-Library/1.0")
 
+	// Re-fetches are conditional on the document having changed
+	var prev conditional.Validators
+	var fetchedBefore bool
+	if globalFetchValidators != nil {
+		prev, fetchedBefore = globalFetchValidators.Get(url)
+		if fetchedBefore {
+			prev.Apply(req)
+		}
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return workflows.FetchResult{}, fmt.Errorf("failed to fetch document: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && fetchedBefore {
+		logger.Info("Document not modified", "url", url, "documentID", prev.DocumentID)
+		return notModified(ctx, prev), nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return workflows.FetchResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -41,12 +102,43 @@ func FetchDocumentActivity(ctx context.Context, url string) (workflows.FetchResu
 		return workflows.FetchResult{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if globalWARCWriter != nil {
+		if _, err := globalWARCWriter.WriteExchange(resp.Request, resp, content); err != nil {
+			logger.Warn("Failed to capture fetch to WARC", "url", url, "error", err)
+		}
+	}
+
+	// Servers that ignore conditional requests may still send the same bytes
+	if fetchedBefore && prev.Unchanged(content) {
+		logger.Info("Document content unchanged", "url", url, "documentID", prev.DocumentID)
+		return notModified(ctx, prev), nil
+	}
+
 	// Get content type from response header
 	contentType := resp.Header.Get("Content-Type")
-	
+
 	logger.Info("Document fetched successfully", "url", url, "size", len(content), "contentType", contentType)
 	return workflows.FetchResult{
-		Content:     content,
-		ContentType: contentType,
+		Content:            content,
+		ContentType:        contentType,
+		ETag:               resp.Header.Get("ETag"),
+		LastModified:       resp.Header.Get("Last-Modified"),
+		PreviousDocumentID: prev.DocumentID,
+		Metadata:           policyMetadata(url),
 	}, nil
-}
\ No newline at end of file
+}
+
+// notModified records that an unchanged document was checked and reports it
+func notModified(ctx context.Context, prev conditional.Validators) workflows.FetchResult {
+	prev.FetchedAt = time.Now().UTC()
+	if err := globalFetchValidators.Put(prev); err != nil {
+		activity.GetLogger(ctx).Warn("Failed to save fetch validators", "url", prev.URL, "error", err)
+	}
+
+	return workflows.FetchResult{
+		NotModified:        true,
+		ETag:               prev.ETag,
+		LastModified:       prev.LastModified,
+		PreviousDocumentID: prev.DocumentID,
+	}
+}
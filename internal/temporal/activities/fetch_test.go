@@ -0,0 +1,84 @@
+package activities
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// TestFetchDocumentActivityConditional tests that re-fetches of a stored URL
+// are conditional and report unchanged documents
+func TestFetchDocumentActivityConditional(t *testing.T) {
+	var mu sync.Mutex
+	body, etag, honorConditional := "first version", `"v1"`, true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if honorConditional && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(FetchDocumentActivity)
+
+	store := conditional.NewStore()
+	SetGlobalFetchValidators(store)
+	defer SetGlobalFetchValidators(nil)
+
+	fetch := func() workflows.FetchResult {
+		val, err := env.ExecuteActivity(FetchDocumentActivity, server.URL)
+		require.NoError(t, err)
+		var result workflows.FetchResult
+		require.NoError(t, val.Get(&result))
+		return result
+	}
+
+	first := fetch()
+	assert.False(t, first.NotModified)
+	assert.Equal(t, "first version", string(first.Content))
+	assert.Equal(t, `"v1"`, first.ETag)
+	assert.Empty(t, first.PreviousDocumentID)
+
+	// What StoreDocumentActivity records once the document is stored
+	require.NoError(t, store.Put(conditional.Validators{
+		URL:         server.URL,
+		ETag:        first.ETag,
+		ContentHash: conditional.HashContent(first.Content),
+		DocumentID:  "doc-1",
+	}))
+
+	unchanged := fetch()
+	assert.True(t, unchanged.NotModified)
+	assert.Empty(t, unchanged.Content)
+	assert.Equal(t, "doc-1", unchanged.PreviousDocumentID)
+
+	// Without 304 support the content hash still catches an unchanged body
+	mu.Lock()
+	honorConditional = false
+	mu.Unlock()
+	assert.True(t, fetch().NotModified)
+
+	mu.Lock()
+	body, etag = "second version", `"v2"`
+	mu.Unlock()
+	changed := fetch()
+	assert.False(t, changed.NotModified)
+	assert.Equal(t, "second version", string(changed.Content))
+	assert.Equal(t, `"v2"`, changed.ETag)
+	assert.Equal(t, "doc-1", changed.PreviousDocumentID)
+}
@@ -2,18 +2,230 @@ package activities
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"time"
 
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/document"
 	"go.temporal.io/sdk/activity"
 )
 
-func IndexDocumentActivity(ctx context.Context, commitHash string) error {
+// DocumentIndexerName names the built-in stage that records a document in
+// the storage document index, which makes it queryable
+const DocumentIndexerName = "document_index"
+
+// Indexer keeps a derived store, such as a search index, up to date with
+// stored documents
+type Indexer interface {
+	// Name identifies the indexer in outcomes and in the record of which
+	// indexers have processed a document
+	Name() string
+
+	// Index adds doc to the derived store, replacing any earlier version
+	Index(ctx context.Context, doc *document.Document) error
+}
+
+var (
+	indexersMu     sync.RWMutex
+	globalIndexers []Indexer
+
+	// indexMu serializes indexing so concurrent attempts on the same
+	// document cannot both run an indexer
+	indexMu sync.Mutex
+
+	// fallbackIndex records indexing when the storage has no document
+	// index of its own
+	fallbackIndex     *storage.DocumentIndex
+	fallbackIndexOnce sync.Once
+)
+
+// RegisterIndexer adds an indexer that IndexDocumentActivity runs after the
+// document index is updated. Indexers run in registration order.
+func RegisterIndexer(indexer Indexer) {
+	indexersMu.Lock()
+	defer indexersMu.Unlock()
+	globalIndexers = append(globalIndexers, indexer)
+}
+
+// ResetIndexers removes all registered indexers
+func ResetIndexers() {
+	indexersMu.Lock()
+	defer indexersMu.Unlock()
+	globalIndexers = nil
+}
+
+func registeredIndexers() []Indexer {
+	indexersMu.RLock()
+	defer indexersMu.RUnlock()
+	return append([]Indexer(nil), globalIndexers...)
+}
+
+// IndexDocumentActivity records a stored document in the document index and
+// runs the registered indexers on it. ref is the commit returned by
+// StoreDocumentActivity; the document ID returned by StoreFileActivity is
+// accepted too.
+//
+// Indexing is idempotent: an indexer that has already processed this version
+// of the document is skipped, so a retried activity only reruns the indexers
+// that failed. A failure to update the document index fails the activity;
+// failures of other indexers are reported in the result.
+func IndexDocumentActivity(ctx context.Context, ref string) (workflows.IndexResult, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Indexing document", "commitHash", commitHash)
+	logger.Info("Indexing document", "ref", ref)
+
+	if ref == "" {
+		return workflows.IndexResult{}, fmt.Errorf("commit hash is required")
+	}
+	if globalHybridStorage == nil {
+		return workflows.IndexResult{}, fmt.Errorf("hybrid storage not initialized")
+	}
+
+	docID, commitHash, err := resolveIndexRef(ctx, ref)
+	if err != nil {
+		return workflows.IndexResult{}, err
+	}
+
+	doc, err := globalHybridStorage.GetDocument(ctx, docID)
+	if err != nil {
+		return workflows.IndexResult{}, fmt.Errorf("failed to load document %s: %w", docID, err)
+	}
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	index := documentIndex()
+	meta := indexedMetadata(index, doc, commitHash)
+	result := workflows.IndexResult{
+		DocumentID: doc.ID,
+		CommitHash: commitHash,
+	}
+
+	// The document index comes first: once it holds the document, the
+	// document is queryable
+	if meta.IndexedAt.IsZero() {
+		meta.IndexedAt = time.Now()
+		index.Add(doc.ID, meta.Path, meta)
+		result.Indexers = append(result.Indexers, workflows.IndexerOutcome{
+			Name:   DocumentIndexerName,
+			Status: workflows.IndexStatusIndexed,
+		})
+	} else {
+		result.Indexers = append(result.Indexers, workflows.IndexerOutcome{
+			Name:   DocumentIndexerName,
+			Status: workflows.IndexStatusSkipped,
+		})
+	}
+
+	indexed := false
+	for _, indexer := range registeredIndexers() {
+		name := indexer.Name()
+		if _, done := meta.Indexers[name]; done {
+			result.Indexers = append(result.Indexers, workflows.IndexerOutcome{
+				Name:   name,
+				Status: workflows.IndexStatusSkipped,
+			})
+			continue
+		}
+
+		start := time.Now()
+		err := indexer.Index(ctx, doc)
+		outcome := workflows.IndexerOutcome{
+			Name:     name,
+			Status:   workflows.IndexStatusIndexed,
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			outcome.Status = workflows.IndexStatusFailed
+			outcome.Error = err.Error()
+			logger.Warn("Indexer failed", "documentID", doc.ID, "indexer", name, "error", err)
+		} else {
+			meta.Indexers[name] = time.Now()
+			indexed = true
+		}
+		result.Indexers = append(result.Indexers, outcome)
+	}
+	if indexed {
+		index.Add(doc.ID, meta.Path, meta)
+	}
+
+	result.QueryableAt = meta.IndexedAt
+	result.AlreadyIndexed = true
+	for _, outcome := range result.Indexers {
+		if outcome.Status != workflows.IndexStatusSkipped {
+			result.AlreadyIndexed = false
+		}
+	}
+
+	logger.Info("Document indexed successfully",
+		"documentID", doc.ID,
+		"commitHash", commitHash,
+		"queryableAt", result.QueryableAt,
+		"alreadyIndexed", result.AlreadyIndexed)
+	return result, nil
+}
+
+// resolveIndexRef returns the document a ref points at and, when the ref is
+// a commit, the commit hash
+func resolveIndexRef(ctx context.Context, ref string) (string, string, error) {
+	docID, err := globalHybridStorage.ResolveCommit(ctx, ref)
+	if err == nil {
+		return docID, ref, nil
+	}
+
+	// Not a commit; file uploads pass the document ID instead
+	if _, getErr := globalHybridStorage.GetDocument(ctx, ref); getErr == nil {
+		return ref, "", nil
+	}
+	return "", "", fmt.Errorf("failed to resolve commit %s: %w", ref, err)
+}
+
+// documentIndex returns the index that records indexed documents: the
+// storage's own when it has one
+func documentIndex() *storage.DocumentIndex {
+	if index := globalHybridStorage.GetDocumentIndex(); index != nil {
+		return index
+	}
+	fallbackIndexOnce.Do(func() {
+		fallbackIndex = storage.NewDocumentIndex()
+	})
+	return fallbackIndex
+}
+
+// indexedMetadata returns a copy of the document's index entry to record
+// indexing in. The indexing record is kept only if it is for the same
+// version of the document; a new version starts over.
+func indexedMetadata(index *storage.DocumentIndex, doc *document.Document, commitHash string) *storage.DocumentMetadata {
+	meta := &storage.DocumentMetadata{
+		ID:   doc.ID,
+		Path: doc.GitPath() + "/metadata.json",
+	}
+	if existing, ok := index.GetMetadata(doc.ID); ok && existing != nil {
+		copied := *existing
+		meta = &copied
+	} else if path, ok := index.Get(doc.ID); ok {
+		meta.Path = path
+	}
+
+	version := storage.ContentHash(doc)
+	if meta.Version != version {
+		meta.Version = version
+		meta.IndexedAt = time.Time{}
+		meta.Indexers = nil
+	}
 
-	// TODO: Implement actual indexing logic
-	// This could update a search index, database, or other metadata store
-	// For now, we'll just log success
+	indexers := make(map[string]time.Time, len(meta.Indexers))
+	for name, at := range meta.Indexers {
+		indexers[name] = at
+	}
+	meta.Indexers = indexers
 
-	logger.Info("Document indexed successfully", "commitHash", commitHash)
-	return nil
-}
\ No newline at end of file
+	meta.Type = doc.Source.Type
+	meta.CreatedAt = doc.CreatedAt
+	meta.UpdatedAt = doc.UpdatedAt
+	if commitHash != "" {
+		meta.Commit = commitHash
+	}
+	return meta
+}
@@ -0,0 +1,136 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	git "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// countingIndexer counts the documents it is asked to index, failing while
+// err is set
+type countingIndexer struct {
+	name  string
+	calls int
+	err   error
+}
+
+func (c *countingIndexer) Name() string { return c.name }
+
+func (c *countingIndexer) Index(ctx context.Context, doc *document.Document) error {
+	c.calls++
+	return c.err
+}
+
+// TestIndexDocumentActivity tests indexing a stored document and that
+// retries only rerun the indexers that failed
+func TestIndexDocumentActivity(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(IndexDocumentActivity)
+
+	repoPath := t.TempDir()
+	_, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+
+	config := storage.DefaultHybridConfig()
+	config.PrimaryBackend = "govc"
+	config.EnableSync = false
+
+	metrics := storage.NewSimpleMetricsCollector()
+	hybridStorage, err := storage.NewHybridStorage(repoPath, "index-test", config, metrics)
+	require.NoError(t, err)
+	defer hybridStorage.Close()
+
+	SetGlobalStorage(hybridStorage, metrics)
+	defer SetGlobalStorage(nil, nil)
+
+	search := &countingIndexer{name: "search"}
+	flaky := &countingIndexer{name: "flaky", err: errors.New("unavailable")}
+	RegisterIndexer(search)
+	RegisterIndexer(flaky)
+	defer ResetIndexers()
+
+	doc := &document.Document{
+		ID:        "indexed-doc",
+		Source:    document.Source{Type: "text", URL: "https://example.com/indexed"},
+		Content:   document.Content{Text: "A document to index"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	commitHash, err := hybridStorage.StoreDocument(context.Background(), doc)
+	require.NoError(t, err)
+
+	index := func(ref string) workflows.IndexResult {
+		val, err := env.ExecuteActivity(IndexDocumentActivity, ref)
+		require.NoError(t, err)
+		var result workflows.IndexResult
+		require.NoError(t, val.Get(&result))
+		return result
+	}
+	statuses := func(result workflows.IndexResult) map[string]string {
+		byName := make(map[string]string)
+		for _, outcome := range result.Indexers {
+			byName[outcome.Name] = outcome.Status
+		}
+		return byName
+	}
+
+	first := index(commitHash)
+	assert.Equal(t, "indexed-doc", first.DocumentID)
+	assert.Equal(t, commitHash, first.CommitHash)
+	assert.False(t, first.QueryableAt.IsZero())
+	assert.Equal(t, map[string]string{
+		DocumentIndexerName: workflows.IndexStatusIndexed,
+		"search":            workflows.IndexStatusIndexed,
+		"flaky":             workflows.IndexStatusFailed,
+	}, statuses(first))
+	require.Len(t, first.Failed(), 1)
+	assert.Equal(t, "unavailable", first.Failed()[0].Error)
+
+	meta, ok := hybridStorage.GetDocumentIndex().GetMetadata("indexed-doc")
+	require.True(t, ok)
+	assert.Equal(t, commitHash, meta.Commit)
+	assert.Contains(t, meta.Indexers, "search")
+	assert.NotContains(t, meta.Indexers, "flaky")
+
+	// A retry reruns only the failed indexer
+	flaky.err = nil
+	retry := index(commitHash)
+	assert.True(t, retry.QueryableAt.Equal(first.QueryableAt))
+	assert.False(t, retry.AlreadyIndexed)
+	assert.Equal(t, map[string]string{
+		DocumentIndexerName: workflows.IndexStatusSkipped,
+		"search":            workflows.IndexStatusSkipped,
+		"flaky":             workflows.IndexStatusIndexed,
+	}, statuses(retry))
+	assert.Equal(t, 1, search.calls)
+	assert.Equal(t, 2, flaky.calls)
+
+	// Indexing by document ID finds the work already done
+	again := index("indexed-doc")
+	assert.True(t, again.AlreadyIndexed)
+	assert.Equal(t, 1, search.calls)
+	assert.Equal(t, 2, flaky.calls)
+
+	// A new version of the document is indexed afresh
+	doc.Content.Text = "A revised document to index"
+	updateCommit, err := hybridStorage.UpdateDocument(context.Background(), doc)
+	require.NoError(t, err)
+	revised := index(updateCommit)
+	assert.False(t, revised.AlreadyIndexed)
+	assert.Equal(t, 2, search.calls)
+
+	_, err = env.ExecuteActivity(IndexDocumentActivity, "")
+	assert.Error(t, err)
+	_, err = env.ExecuteActivity(IndexDocumentActivity, "0123456789abcdef0123456789abcdef01234567")
+	assert.Error(t, err)
+}
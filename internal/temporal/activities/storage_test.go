@@ -1,6 +1,7 @@
 package activities
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
@@ -85,7 +87,9 @@ func TestStoreDocumentActivityWithHybridStorage(t *testing.T) {
 			"title":  "Test Document",
 			"source": "unit test",
 		},
-		Embeddings: []float32{0.1, 0.2, 0.3, 0.4},
+		Embeddings:          []float32{0.1, 0.2, 0.3, 0.4},
+		EmbeddingModel:      "test-model-4",
+		EmbeddingDimensions: 4,
 	}
 
 	// Execute activity
@@ -98,6 +102,13 @@ func TestStoreDocumentActivityWithHybridStorage(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, commitHash)
 
+	// The embeddings are labelled with the model that produced them
+	docs, err := hybridStorage.ListDocuments(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "test-model-4", docs[0].Content.Metadata[embedder.MetaModel])
+	assert.Equal(t, "4", docs[0].Content.Metadata[embedder.MetaDimensions])
+
 	// Verify metrics were recorded
 	summary := metrics.GetMetricsSummary()
 	assert.NotNil(t, summary)
@@ -7,7 +7,10 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/conditional"
+	"github.com/Caia-Tech/caia-library/pkg/dedup"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
 	"github.com/google/uuid"
 	"go.temporal.io/sdk/activity"
 )
@@ -22,6 +25,9 @@ func SetGlobalStorage(hybridStorage *storage.HybridStorage, metrics *storage.Sim
 	globalMetrics = metrics
 }
 
+// StoreDocumentActivity stores a fetched document. When the URL's previous
+// document is given and still exists, the document is stored as a new
+// version of it, keeping its ID.
 func StoreDocumentActivity(ctx context.Context, input workflows.StoreInput) (string, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Storing document", "url", input.URL, "type", input.Type)
@@ -30,6 +36,11 @@ func StoreDocumentActivity(ctx context.Context, input workflows.StoreInput) (str
 		return "", fmt.Errorf("hybrid storage not initialized")
 	}
 
+	metadata := make(map[string]string, len(input.Metadata))
+	for k, v := range input.Metadata {
+		metadata[k] = v
+	}
+
 	// Create document
 	doc := &document.Document{
 		ID: uuid.New().String(),
@@ -40,18 +51,71 @@ func StoreDocumentActivity(ctx context.Context, input workflows.StoreInput) (str
 		Content: document.Content{
 			Raw:        input.Content,
 			Text:       input.Text,
-			Metadata:   input.Metadata,
+			Metadata:   metadata,
 			Embeddings: input.Embeddings,
 		},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	commitHash, err := globalHybridStorage.StoreDocument(ctx, doc)
+	var previous *document.Document
+	if input.PreviousDocumentID != "" {
+		existing, err := globalHybridStorage.GetDocument(ctx, input.PreviousDocumentID)
+		if err != nil {
+			logger.Warn("Previous version not found, storing as new document", "documentID", input.PreviousDocumentID, "error", err)
+		} else {
+			previous = existing
+			doc.ID = existing.ID
+			doc.CreatedAt = existing.CreatedAt
+		}
+	}
+
+	// Record the model that produced the embeddings so vectors from
+	// different models are not mixed
+	if len(input.Embeddings) > 0 && metadata[embedder.MetaModel] == "" {
+		if input.EmbeddingModel != "" {
+			embedder.RecordModel(metadata, input.EmbeddingModel, input.EmbeddingDimensions)
+		} else {
+			logger.Warn("Embedding model not reported, storing embeddings unlabelled", "url", input.URL)
+		}
+	}
+
+	// Record which duplicate cluster the document joins
+	var fp *dedup.Fingerprint
+	if globalDedupIndex != nil {
+		fp = dedup.NewFingerprint(input.Text)
+		globalDedupIndex.FindOther(fp, doc.ID).Annotate(metadata, doc.ID, fp)
+	}
+
+	var commitHash string
+	var err error
+	if previous != nil {
+		commitHash, err = globalHybridStorage.UpdateDocument(ctx, doc)
+	} else {
+		commitHash, err = globalHybridStorage.StoreDocument(ctx, doc)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to store document: %w", err)
 	}
 
-	logger.Info("Document stored successfully", "documentID", doc.ID, "commitHash", commitHash)
+	if fp != nil {
+		globalDedupIndex.Add(doc.ID, fp, metadata[dedup.MetaCluster])
+	}
+
+	// Keep the URL's validators so the next fetch can be conditional
+	if globalFetchValidators != nil && input.URL != "" {
+		if err := globalFetchValidators.Put(conditional.Validators{
+			URL:          input.URL,
+			ETag:         input.ETag,
+			LastModified: input.LastModified,
+			ContentHash:  conditional.HashContent(input.Content),
+			DocumentID:   doc.ID,
+			FetchedAt:    time.Now().UTC(),
+		}); err != nil {
+			logger.Warn("Failed to save fetch validators", "url", input.URL, "error", err)
+		}
+	}
+
+	logger.Info("Document stored successfully", "documentID", doc.ID, "commitHash", commitHash, "newVersion", previous != nil)
 	return commitHash, nil
 }
\ No newline at end of file
@@ -0,0 +1,326 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
+	"go.temporal.io/sdk/activity"
+)
+
+// maxArchivedContent caps the content read from an archived record, as
+// FetchDocumentActivity caps live fetches
+const maxArchivedContent = 100 * 1024 * 1024
+
+// FetchWARCRecordActivity reads a document from an archived WARC record in
+// place of fetching it live. The record's location and identity are
+// returned as metadata to be stored with the document.
+func FetchWARCRecordActivity(ctx context.Context, input workflows.ArchivedRecord) (workflows.FetchResult, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Reading archived document", "warc", input.WARC, "offset", input.Offset)
+
+	var record *warc.Record
+	var err error
+	if isRemoteWARC(input.WARC) {
+		record, err = warc.FetchRecord(ctx, nil, input.WARC, input.Offset, input.Length)
+	} else {
+		var file *os.File
+		file, err = os.Open(input.WARC)
+		if err != nil {
+			return workflows.FetchResult{}, fmt.Errorf("failed to open WARC file: %w", err)
+		}
+		defer file.Close()
+		record, err = warc.ReadRecordAt(file, input.Offset, input.Length)
+	}
+	if err != nil {
+		return workflows.FetchResult{}, fmt.Errorf("failed to read WARC record: %w", err)
+	}
+
+	payload, err := warc.ReadPayload(record, maxArchivedContent)
+	if err != nil {
+		return workflows.FetchResult{}, fmt.Errorf("failed to read archived content: %w", err)
+	}
+	if payload.StatusCode != http.StatusOK {
+		return workflows.FetchResult{}, fmt.Errorf("archived response has status code %d", payload.StatusCode)
+	}
+
+	metadata := map[string]string{
+		"warc_file":       input.WARC,
+		"warc_offset":     strconv.FormatInt(input.Offset, 10),
+		"warc_record_id":  record.Header.RecordID(),
+		"warc_date":       record.Header.Get(warc.FieldDate),
+		"warc_target_uri": record.Header.TargetURI(),
+	}
+	for k, v := range policyMetadata(record.Header.TargetURI()) {
+		metadata[k] = v
+	}
+
+	logger.Info("Archived document read successfully", "url", record.Header.TargetURI(), "size", len(payload.Body), "contentType", payload.ContentType)
+	return workflows.FetchResult{
+		Content:      payload.Body,
+		ContentType:  payload.ContentType,
+		ETag:         payload.Header.Get("ETag"),
+		LastModified: payload.Header.Get("Last-Modified"),
+		Metadata:     metadata,
+	}, nil
+}
+
+// ListWARCRecordsActivity finds the archived captures a WARC ingestion
+// selects, returning one document input per capture to be read from its
+// record. With a CDX index only the latest capture of each URL is taken.
+func ListWARCRecordsActivity(ctx context.Context, input workflows.WARCIngestionInput) ([]workflows.DocumentInput, error) {
+	logger := activity.GetLogger(ctx)
+
+	var documents []workflows.DocumentInput
+	var err error
+	switch {
+	case input.WARC != "" && input.CDX != "":
+		return nil, fmt.Errorf("either a WARC file or a CDX index is required, not both")
+	case input.WARC != "":
+		documents, err = listWARCFile(ctx, input)
+	case input.CDX != "":
+		if len(input.URLs) == 0 {
+			return nil, fmt.Errorf("URLs to look up in the CDX index are required")
+		}
+		documents, err = listCDXCaptures(ctx, input)
+	default:
+		return nil, fmt.Errorf("a WARC file or a CDX index is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Listed archived documents", "warc", input.WARC, "cdx", input.CDX, "count", len(documents))
+	return documents, nil
+}
+
+// listWARCFile reads a whole WARC file for the response and resource
+// records to ingest
+func listWARCFile(ctx context.Context, input workflows.WARCIngestionInput) ([]workflows.DocumentInput, error) {
+	var src io.ReadCloser
+	if isRemoteWARC(input.WARC) {
+		req, err := http.NewRequestWithContext(ctx, "GET", input.WARC, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("User-Agent", "CAIA-Library/1.0")
+		resp, err := (&http.Client{Timeout: 30 * time.Minute}).Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch WARC file: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		src = resp.Body
+	} else {
+		file, err := os.Open(input.WARC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open WARC file: %w", err)
+		}
+		src = file
+	}
+	defer src.Close()
+
+	type candidate struct {
+		record *warc.Record
+		mime   string
+	}
+	var candidates []candidate
+	shared := 0
+
+	reader := warc.NewReader(src)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC file: %w", err)
+		}
+		// Next has measured the previous record, so the limit is checked here
+		if input.Limit > 0 && len(candidates) >= input.Limit {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		switch record.Header.Type() {
+		case warc.TypeResponse, warc.TypeResource:
+		default:
+			continue
+		}
+		payload, err := warc.ReadPayloadHeader(record)
+		if err != nil || payload.StatusCode != http.StatusOK {
+			continue
+		}
+		mediaType := mediaTypeOf(payload.ContentType)
+		if !wantMIME(input.MIMETypes, mediaType) {
+			continue
+		}
+		candidates = append(candidates, candidate{record: record, mime: mediaType})
+	}
+
+	var documents []workflows.DocumentInput
+	for _, c := range candidates {
+		// Records sharing a gzip member cannot be read on their own
+		if c.record.Length == 0 {
+			shared++
+			continue
+		}
+		documents = append(documents, archivedDocument(input, c.record.Header.TargetURI(), c.mime, workflows.ArchivedRecord{
+			WARC:   input.WARC,
+			Offset: c.record.Offset,
+			Length: c.record.Length,
+		}))
+	}
+	if shared > 0 {
+		activity.GetLogger(ctx).Warn("Skipped records that share a gzip member", "warc", input.WARC, "count", shared)
+	}
+	return documents, nil
+}
+
+// listCDXCaptures looks up each URL in a CDX server or local index
+func listCDXCaptures(ctx context.Context, input workflows.WARCIngestionInput) ([]workflows.DocumentInput, error) {
+	base := input.BaseURL
+	var lookup func(string) ([]warc.CDXEntry, error)
+	if isRemoteWARC(input.CDX) {
+		if base == "" {
+			base = warc.CommonCrawlDataURL
+		}
+		client := warc.NewCDXClient(input.CDX, nil)
+		lookup = func(u string) ([]warc.CDXEntry, error) {
+			return client.Lookup(ctx, u, 0)
+		}
+	} else {
+		if base == "" {
+			base = filepath.Dir(input.CDX)
+		}
+		index, err := warc.LoadCDXIndex(input.CDX)
+		if err != nil {
+			return nil, err
+		}
+		lookup = func(u string) ([]warc.CDXEntry, error) {
+			return index.Lookup(u), nil
+		}
+	}
+
+	var documents []workflows.DocumentInput
+	for _, u := range input.URLs {
+		entries, err := lookup(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", u, err)
+		}
+
+		// Keep the latest usable capture of each URL
+		latest := make(map[string]warc.CDXEntry)
+		var order []string
+		for _, entry := range entries {
+			mediaType := mediaTypeOf(entry.MIME)
+			if (entry.Status != "" && entry.Status != "200") || entry.Filename == "" || entry.Length <= 0 {
+				continue
+			}
+			if mediaType != "" && !wantMIME(input.MIMETypes, mediaType) {
+				continue
+			}
+			key := entry.URLKey
+			if key == "" {
+				key = entry.URL
+			}
+			prev, seen := latest[key]
+			if !seen {
+				order = append(order, key)
+			}
+			if !seen || entry.Timestamp > prev.Timestamp {
+				latest[key] = entry
+			}
+		}
+
+		for _, key := range order {
+			if input.Limit > 0 && len(documents) >= input.Limit {
+				return documents, nil
+			}
+			entry := latest[key]
+			location := entry.Location(base)
+			// A CDX server only points at archives on other servers
+			if isRemoteWARC(input.CDX) && !isRemoteWARC(location) {
+				continue
+			}
+			documents = append(documents, archivedDocument(input, entry.URL, mediaTypeOf(entry.MIME), workflows.ArchivedRecord{
+				WARC:   location,
+				Offset: entry.Offset,
+				Length: entry.Length,
+			}))
+		}
+	}
+	return documents, nil
+}
+
+// archivedDocument builds the ingestion input of one capture
+func archivedDocument(input workflows.WARCIngestionInput, targetURL, mediaType string, record workflows.ArchivedRecord) workflows.DocumentInput {
+	docType := input.Type
+	if docType == "" {
+		docType = documentTypeOf(mediaType)
+	}
+	metadata := make(map[string]string, len(input.Metadata))
+	for k, v := range input.Metadata {
+		metadata[k] = v
+	}
+	return workflows.DocumentInput{
+		URL:             targetURL,
+		Type:            docType,
+		Metadata:        metadata,
+		DuplicatePolicy: input.DuplicatePolicy,
+		Archive:         &record,
+	}
+}
+
+func isRemoteWARC(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// wantMIME reports whether a capture of the media type is ingested;
+// without a selection only HTML is
+func wantMIME(selected []string, mediaType string) bool {
+	if len(selected) == 0 {
+		return mediaType == "text/html"
+	}
+	for _, m := range selected {
+		if strings.EqualFold(strings.TrimSpace(m), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// documentTypeOf maps a media type to the document types extraction knows
+func documentTypeOf(mediaType string) string {
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return "html"
+	case mediaType == "application/pdf":
+		return "pdf"
+	case mediaType == "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return "docx"
+	default:
+		return "text"
+	}
+}
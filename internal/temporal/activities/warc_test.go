@@ -0,0 +1,94 @@
+package activities
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Caia-Tech/caia-library/internal/temporal/workflows"
+	"github.com/Caia-Tech/caia-library/pkg/warc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// TestWARCCaptureAndIngestion tests that fetches are captured to WARC and
+// that captures can be listed and read back for ingestion
+func TestWARCCaptureAndIngestion(t *testing.T) {
+	pages := map[string]string{
+		"/article": "<html><body><p>An archived article.</p></body></html>",
+		"/data":    `{"not": "html"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/data" {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write([]byte(pages[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	capture, err := warc.OpenFileWriter(dir, "fetch", 0, nil)
+	require.NoError(t, err)
+	SetGlobalWARCWriter(capture)
+	defer SetGlobalWARCWriter(nil)
+
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestActivityEnvironment()
+	env.RegisterActivity(FetchDocumentActivity)
+	env.RegisterActivity(ListWARCRecordsActivity)
+	env.RegisterActivity(FetchWARCRecordActivity)
+
+	for path := range pages {
+		_, err := env.ExecuteActivity(FetchDocumentActivity, server.URL+path)
+		require.NoError(t, err)
+	}
+	require.NoError(t, capture.Close())
+
+	warcs, err := filepath.Glob(filepath.Join(dir, "fetch-*.warc.gz"))
+	require.NoError(t, err)
+	require.Len(t, warcs, 1)
+	cdxs, err := filepath.Glob(filepath.Join(dir, "fetch-*.cdxj"))
+	require.NoError(t, err)
+	require.Len(t, cdxs, 1)
+
+	list := func(input workflows.WARCIngestionInput) []workflows.DocumentInput {
+		val, err := env.ExecuteActivity(ListWARCRecordsActivity, input)
+		require.NoError(t, err)
+		var documents []workflows.DocumentInput
+		require.NoError(t, val.Get(&documents))
+		return documents
+	}
+
+	// Reading the whole file selects HTML responses by default
+	fromFile := list(workflows.WARCIngestionInput{WARC: warcs[0], Metadata: map[string]string{"source": "archive"}})
+	require.Len(t, fromFile, 1)
+	assert.Equal(t, server.URL+"/article", fromFile[0].URL)
+	assert.Equal(t, "html", fromFile[0].Type)
+	assert.Equal(t, "archive", fromFile[0].Metadata["source"])
+	require.NotNil(t, fromFile[0].Archive)
+
+	// The CDXJ sidecar locates the same record
+	fromIndex := list(workflows.WARCIngestionInput{CDX: cdxs[0], URLs: []string{server.URL + "/article", server.URL + "/missing"}})
+	require.Len(t, fromIndex, 1)
+	assert.Equal(t, *fromFile[0].Archive, *fromIndex[0].Archive)
+
+	assert.Len(t, list(workflows.WARCIngestionInput{WARC: warcs[0], MIMETypes: []string{"text/html", "application/json"}}), 2)
+	assert.Len(t, list(workflows.WARCIngestionInput{WARC: warcs[0], MIMETypes: []string{"text/html", "application/json"}, Limit: 1}), 1)
+
+	_, err = env.ExecuteActivity(ListWARCRecordsActivity, workflows.WARCIngestionInput{CDX: cdxs[0]})
+	assert.Error(t, err, "CDX lookups need URLs")
+
+	val, err := env.ExecuteActivity(FetchWARCRecordActivity, *fromIndex[0].Archive)
+	require.NoError(t, err)
+	var result workflows.FetchResult
+	require.NoError(t, val.Get(&result))
+	assert.Equal(t, pages["/article"], string(result.Content))
+	assert.Equal(t, "text/html; charset=utf-8", result.ContentType)
+	assert.Equal(t, warcs[0], result.Metadata["warc_file"])
+	assert.Equal(t, server.URL+"/article", result.Metadata["warc_target_uri"])
+	assert.NotEmpty(t, result.Metadata["warc_record_id"])
+}
@@ -1,10 +1,13 @@
 package workflows
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -13,8 +16,25 @@ type DocumentInput struct {
 	URL      string
 	Type     string
 	Metadata map[string]string
+
+	// DuplicatePolicy decides what happens to content already in the library.
+	// Empty skips exact duplicates and stores near duplicates linked to their
+	// cluster.
+	DuplicatePolicy string
+
+	// Archive, when set, reads the document from a WARC record captured
+	// from URL instead of fetching it
+	Archive *ArchivedRecord
 }
 
+// Duplicate policies for DocumentInput
+const (
+	// DuplicatePolicySkip skips both exact and near duplicates
+	DuplicatePolicySkip = "skip"
+	// DuplicatePolicyLink stores every duplicate, linked to its cluster
+	DuplicatePolicyLink = "link"
+)
+
 // FileProcessingInput represents the input for file processing workflow
 type FileProcessingInput struct {
 	Filename    string            `json:"filename"`
@@ -23,6 +43,16 @@ type FileProcessingInput struct {
 	Metadata    map[string]string `json:"metadata"`
 }
 
+// Change IDs passed to workflow.GetVersion for steps changed in the
+// ingestion workflows after executions of them were already running.
+// Executions recorded without a change replay the steps as they were.
+const (
+	contentDedupChange     = "content-dedup"
+	indexResultChange      = "index-result"
+	conditionalFetchChange = "conditional-fetch"
+	archivedFetchChange    = "archived-fetch"
+)
+
 func DocumentIngestionWorkflow(ctx workflow.Context, input DocumentInput) error {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Starting document ingestion", "url", input.URL)
@@ -39,11 +69,27 @@ func DocumentIngestionWorkflow(ctx workflow.Context, input DocumentInput) error
 	}
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
-	// Fetch document
+	// Fetch document, or read it from its archived capture without
+	// requesting the live URL. Executions started before archived captures
+	// always fetch.
 	var fetchResult FetchResult
-	if err := workflow.ExecuteActivity(ctx, FetchDocumentActivityName, input.URL).Get(ctx, &fetchResult); err != nil {
+	var fetch workflow.Future
+	archived := workflow.GetVersion(ctx, archivedFetchChange, workflow.DefaultVersion, 1) >= 1
+	if archived && input.Archive != nil {
+		fetch = workflow.ExecuteActivity(ctx, FetchWARCRecordActivityName, *input.Archive)
+	} else {
+		fetch = workflow.ExecuteActivity(ctx, FetchDocumentActivityName, input.URL)
+	}
+	if err := fetch.Get(ctx, &fetchResult); err != nil {
 		return err
 	}
+	// Executions started before conditional fetches go on to store what
+	// was fetched
+	conditional := workflow.GetVersion(ctx, conditionalFetchChange, workflow.DefaultVersion, 1) >= 1
+	if conditional && fetchResult.NotModified {
+		logger.Info("Document unchanged since last fetch", "url", input.URL, "documentID", fetchResult.PreviousDocumentID)
+		return nil
+	}
 
 	// Validate content type matches expected type
 	if err := validateContentType(fetchResult.ContentType, input.Type); err != nil {
@@ -71,19 +117,51 @@ func DocumentIngestionWorkflow(ctx workflow.Context, input DocumentInput) error
 		return err
 	}
 
-	var embeddings []float32
+	var embeddings EmbeddingResult
 	if err := embedFuture.Get(ctx, &embeddings); err != nil {
 		return err
 	}
 
-	// Store in Git
+	// Skip content the library already holds under another URL. Executions
+	// started before the check existed store without it.
+	if workflow.GetVersion(ctx, contentDedupChange, workflow.DefaultVersion, 1) >= 1 {
+		var duplicate DuplicateCheckResult
+		if err := workflow.ExecuteActivity(ctx, CheckContentDuplicateActivityName, DuplicateCheckInput{
+			URL:                input.URL,
+			Text:               extractResult.Text,
+			PreviousDocumentID: fetchResult.PreviousDocumentID,
+		}).Get(ctx, &duplicate); err != nil {
+			logger.Warn("Content duplicate check failed, storing anyway", "error", err)
+		} else if skipDuplicate(input.DuplicatePolicy, duplicate.Kind) {
+			logger.Info("Skipping duplicate content",
+				"url", input.URL,
+				"kind", duplicate.Kind,
+				"duplicateOf", duplicate.DocumentID,
+				"cluster", duplicate.ClusterID)
+			return nil
+		}
+	}
+
+	// Store in Git, as a new version if the URL was stored before
+	metadata := make(map[string]string, len(extractResult.Metadata)+len(fetchResult.Metadata))
+	for k, v := range extractResult.Metadata {
+		metadata[k] = v
+	}
+	for k, v := range fetchResult.Metadata {
+		metadata[k] = v
+	}
 	storeInput := StoreInput{
-		URL:        input.URL,
-		Type:       input.Type,
-		Content:    fetchResult.Content,
-		Text:       extractResult.Text,
-		Metadata:   extractResult.Metadata,
-		Embeddings: embeddings,
+		URL:                 input.URL,
+		Type:                input.Type,
+		Content:             fetchResult.Content,
+		Text:                extractResult.Text,
+		Metadata:            metadata,
+		Embeddings:          embeddings.Vector,
+		EmbeddingModel:      embeddings.Model,
+		EmbeddingDimensions: embeddings.Dimensions,
+		PreviousDocumentID:  fetchResult.PreviousDocumentID,
+		ETag:                fetchResult.ETag,
+		LastModified:        fetchResult.LastModified,
 	}
 
 	var commitHash string
@@ -92,7 +170,7 @@ func DocumentIngestionWorkflow(ctx workflow.Context, input DocumentInput) error
 	}
 
 	// Index document
-	if err := workflow.ExecuteActivity(ctx, IndexDocumentActivityName, commitHash).Get(ctx, nil); err != nil {
+	if err := indexDocument(ctx, logger, commitHash); err != nil {
 		return err
 	}
 
@@ -147,7 +225,7 @@ func FileProcessingWorkflow(ctx workflow.Context, input FileProcessingInput) err
 		return err
 	}
 
-	var embeddings []float32
+	var embeddings EmbeddingResult
 	if err := embedFuture.Get(ctx, &embeddings); err != nil {
 		return err
 	}
@@ -163,12 +241,14 @@ func FileProcessingWorkflow(ctx workflow.Context, input FileProcessingInput) err
 
 	// Store in CAIA Library storage
 	storeInput := FileStoreInput{
-		Filename:   input.Filename,
-		Type:       input.ContentType,
-		Content:    input.Content,
-		Text:       extractResult.Text,
-		Metadata:   combinedMetadata,
-		Embeddings: embeddings,
+		Filename:            input.Filename,
+		Type:                input.ContentType,
+		Content:             input.Content,
+		Text:                extractResult.Text,
+		Metadata:            combinedMetadata,
+		Embeddings:          embeddings.Vector,
+		EmbeddingModel:      embeddings.Model,
+		EmbeddingDimensions: embeddings.Dimensions,
 	}
 
 	var documentID string
@@ -177,7 +257,7 @@ func FileProcessingWorkflow(ctx workflow.Context, input FileProcessingInput) err
 	}
 
 	// Index the document
-	if err := workflow.ExecuteActivity(ctx, IndexDocumentActivityName, documentID).Get(ctx, nil); err != nil {
+	if err := indexDocument(ctx, logger, documentID); err != nil {
 		return err
 	}
 
@@ -185,6 +265,50 @@ func FileProcessingWorkflow(ctx workflow.Context, input FileProcessingInput) err
 	return nil
 }
 
+// indexDocument indexes a stored document and logs how it was indexed.
+// Executions started before IndexDocumentActivity returned a result only
+// wait for it to finish.
+func indexDocument(ctx workflow.Context, logger log.Logger, ref string) error {
+	if workflow.GetVersion(ctx, indexResultChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return workflow.ExecuteActivity(ctx, IndexDocumentActivityName, ref).Get(ctx, nil)
+	}
+
+	var indexResult IndexResult
+	if err := workflow.ExecuteActivity(ctx, IndexDocumentActivityName, ref).Get(ctx, &indexResult); err != nil {
+		return err
+	}
+	logIndexResult(logger, indexResult)
+	return nil
+}
+
+// logIndexResult records when a document became queryable and which
+// indexers failed on it. Failed indexers do not fail the workflow; they are
+// retried the next time the document is indexed.
+func logIndexResult(logger log.Logger, result IndexResult) {
+	logger.Info("Document queryable",
+		"documentID", result.DocumentID,
+		"queryableAt", result.QueryableAt,
+		"alreadyIndexed", result.AlreadyIndexed)
+	for _, outcome := range result.Failed() {
+		logger.Warn("Indexer failed", "documentID", result.DocumentID, "indexer", outcome.Name, "error", outcome.Error)
+	}
+}
+
+// skipDuplicate reports whether a duplicate of the given kind should be
+// dropped under policy
+func skipDuplicate(policy, kind string) bool {
+	switch {
+	case kind == "":
+		return false
+	case policy == DuplicatePolicyLink:
+		return false
+	case policy == DuplicatePolicySkip:
+		return true
+	default:
+		return kind == "exact"
+	}
+}
+
 // validateContentType checks if the fetched content type matches the expected document type
 func validateContentType(contentType, expectedType string) error {
 	contentType = strings.ToLower(contentType)
@@ -211,9 +335,21 @@ func validateContentType(contentType, expectedType string) error {
 }
 
 // Activity types
+
+// FetchResult is a fetched document. A URL fetched before is requested
+// conditionally; NotModified is set, with no content, when it is unchanged.
+// PreviousDocumentID is the document stored from the last fetch. Metadata
+// describes where the content came from, e.g. the WARC record it was read
+// from, and is stored with the document.
 type FetchResult struct {
 	Content     []byte
 	ContentType string
+	Metadata    map[string]string
+
+	NotModified        bool
+	ETag               string
+	LastModified       string
+	PreviousDocumentID string
 }
 
 type ExtractInput struct {
@@ -226,6 +362,34 @@ type ExtractResult struct {
 	Metadata map[string]string
 }
 
+// EmbeddingResult is an embedding vector and the model that produced it, so
+// the vector is labelled with that model when stored
+type EmbeddingResult struct {
+	Vector     []float32 `json:"vector"`
+	Model      string    `json:"model"`
+	Dimensions int       `json:"dimensions"`
+}
+
+// UnmarshalJSON also accepts a bare vector, which GenerateEmbeddingsActivity
+// returned before it reported its model and which is still recorded in the
+// history of executions started then. Such a vector has no model.
+func (r *EmbeddingResult) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		*r = EmbeddingResult{}
+		if err := json.Unmarshal(data, &r.Vector); err != nil {
+			return err
+		}
+		r.Dimensions = len(r.Vector)
+		return nil
+	}
+	type result EmbeddingResult
+	return json.Unmarshal(data, (*result)(r))
+}
+
+// StoreInput is a document to store. With a PreviousDocumentID it is stored
+// as a new version of that document; ETag and LastModified are kept for the
+// next conditional fetch of the URL. EmbeddingModel and EmbeddingDimensions
+// describe the model that produced Embeddings.
 type StoreInput struct {
 	URL        string
 	Type       string
@@ -233,6 +397,69 @@ type StoreInput struct {
 	Text       string
 	Metadata   map[string]string
 	Embeddings []float32
+
+	EmbeddingModel      string
+	EmbeddingDimensions int
+
+	PreviousDocumentID string
+	ETag               string
+	LastModified       string
+}
+
+// DuplicateCheckInput is the extracted content checked against the dedup
+// index. The previous version of the document, if any, is not a duplicate.
+type DuplicateCheckInput struct {
+	URL                string `json:"url"`
+	Text               string `json:"text"`
+	PreviousDocumentID string `json:"previous_document_id,omitempty"`
+}
+
+// DuplicateCheckResult describes the stored document a new one duplicates.
+// Kind is "exact", "near", or empty when there is no duplicate.
+type DuplicateCheckResult struct {
+	Kind        string  `json:"kind"`
+	DocumentID  string  `json:"document_id"`
+	ClusterID   string  `json:"cluster_id"`
+	Similarity  float64 `json:"similarity"`
+	ContentHash string  `json:"content_hash"`
+}
+
+// IndexResult reports how a stored document was indexed. QueryableAt is
+// when the document index first picked up this version of the document.
+type IndexResult struct {
+	DocumentID     string           `json:"document_id"`
+	CommitHash     string           `json:"commit_hash,omitempty"`
+	QueryableAt    time.Time        `json:"queryable_at"`
+	AlreadyIndexed bool             `json:"already_indexed"`
+	Indexers       []IndexerOutcome `json:"indexers"`
+}
+
+// IndexerOutcome is what one indexer did with a document
+type IndexerOutcome struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Indexer outcome statuses
+const (
+	IndexStatusIndexed = "indexed"
+	// IndexStatusSkipped means the indexer had already processed this
+	// version, e.g. on an activity retry
+	IndexStatusSkipped = "skipped"
+	IndexStatusFailed  = "failed"
+)
+
+// Failed returns the outcomes of indexers that failed
+func (r IndexResult) Failed() []IndexerOutcome {
+	var failed []IndexerOutcome
+	for _, outcome := range r.Indexers {
+		if outcome.Status == IndexStatusFailed {
+			failed = append(failed, outcome)
+		}
+	}
+	return failed
 }
 
 // FileStoreInput represents input for storing uploaded files
@@ -243,15 +470,21 @@ type FileStoreInput struct {
 	Text       string            `json:"text"`
 	Metadata   map[string]string `json:"metadata"`
 	Embeddings []float32         `json:"embeddings"`
+
+	EmbeddingModel      string `json:"embedding_model,omitempty"`
+	EmbeddingDimensions int    `json:"embedding_dimensions,omitempty"`
 }
 
 // Activity names for registration
 const (
-	FetchDocumentActivityName      = "FetchDocumentActivity"
-	ExtractTextActivityName        = "ExtractTextActivity"
-	GenerateEmbeddingsActivityName = "GenerateEmbeddingsActivity"
-	StoreDocumentActivityName      = "StoreDocumentActivity"
-	StoreFileActivityName          = "StoreFileActivity"
-	IndexDocumentActivityName      = "IndexDocumentActivity"
-	MergeBranchActivityName        = "MergeBranchActivity"
-)
\ No newline at end of file
+	FetchDocumentActivityName         = "FetchDocumentActivity"
+	ExtractTextActivityName           = "ExtractTextActivity"
+	GenerateEmbeddingsActivityName    = "GenerateEmbeddingsActivity"
+	StoreDocumentActivityName         = "StoreDocumentActivity"
+	StoreFileActivityName             = "StoreFileActivity"
+	IndexDocumentActivityName         = "IndexDocumentActivity"
+	CheckContentDuplicateActivityName = "CheckContentDuplicateActivity"
+	MergeBranchActivityName           = "MergeBranchActivity"
+	FetchWARCRecordActivityName       = "FetchWARCRecordActivity"
+	ListWARCRecordsActivityName       = "ListWARCRecordsActivity"
+)
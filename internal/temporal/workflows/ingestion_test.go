@@ -0,0 +1,98 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// registerIngestionActivities registers stand-ins for the document
+// ingestion activities so they can be mocked by name
+func registerIngestionActivities(env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterActivityWithOptions(func(ctx context.Context, url string) (FetchResult, error) {
+		return FetchResult{}, nil
+	}, activity.RegisterOptions{Name: FetchDocumentActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, record ArchivedRecord) (FetchResult, error) {
+		return FetchResult{}, nil
+	}, activity.RegisterOptions{Name: FetchWARCRecordActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, input ExtractInput) (ExtractResult, error) {
+		return ExtractResult{}, nil
+	}, activity.RegisterOptions{Name: ExtractTextActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, content []byte) (EmbeddingResult, error) {
+		return EmbeddingResult{}, nil
+	}, activity.RegisterOptions{Name: GenerateEmbeddingsActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, input DuplicateCheckInput) (DuplicateCheckResult, error) {
+		return DuplicateCheckResult{}, nil
+	}, activity.RegisterOptions{Name: CheckContentDuplicateActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, input StoreInput) (string, error) {
+		return "", nil
+	}, activity.RegisterOptions{Name: StoreDocumentActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, ref string) (IndexResult, error) {
+		return IndexResult{}, nil
+	}, activity.RegisterOptions{Name: IndexDocumentActivityName})
+	env.RegisterActivityWithOptions(func(ctx context.Context, branch string) error {
+		return nil
+	}, activity.RegisterOptions{Name: MergeBranchActivityName})
+}
+
+func TestDocumentIngestionWorkflow_Archived(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	env := testSuite.NewTestWorkflowEnvironment()
+	registerIngestionActivities(env)
+
+	record := ArchivedRecord{WARC: "crawl.warc.gz", Offset: 1024, Length: 512}
+	var mu sync.Mutex
+	var started []string
+	env.SetOnActivityStartedListener(func(info *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		mu.Lock()
+		started = append(started, info.ActivityType.Name)
+		mu.Unlock()
+	})
+	env.OnActivity(FetchWARCRecordActivityName, mock.Anything, record).Return(FetchResult{
+		Content:     []byte("<html>archived</html>"),
+		ContentType: "text/html",
+		Metadata:    map[string]string{"warc_file": "crawl.warc.gz"},
+	}, nil).Once()
+	env.OnActivity(ExtractTextActivityName, mock.Anything, mock.Anything).Return(ExtractResult{
+		Text:     "archived",
+		Metadata: map[string]string{"document_id": "archived-001"},
+	}, nil)
+	env.OnActivity(GenerateEmbeddingsActivityName, mock.Anything, mock.Anything).Return(EmbeddingResult{Vector: []float32{0.1}, Model: "test-1", Dimensions: 1}, nil)
+	env.OnActivity(CheckContentDuplicateActivityName, mock.Anything, mock.Anything).Return(DuplicateCheckResult{}, nil)
+	env.OnActivity(StoreDocumentActivityName, mock.Anything, mock.Anything).Return("0123456789abcdef", nil)
+	env.OnActivity(IndexDocumentActivityName, mock.Anything, mock.Anything).Return(IndexResult{DocumentID: "archived-001"}, nil)
+	env.OnActivity(MergeBranchActivityName, mock.Anything, "ingest/archived-001").Return(nil)
+
+	env.ExecuteWorkflow(DocumentIngestionWorkflow, DocumentInput{
+		URL:     "https://example.com/page",
+		Type:    "html",
+		Archive: &record,
+	})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+	// The live URL must not be requested for an archived capture
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotContains(t, started, FetchDocumentActivityName)
+}
+
+func TestEmbeddingResult_UnmarshalJSON(t *testing.T) {
+	var result EmbeddingResult
+	require.NoError(t, json.Unmarshal([]byte(`{"vector":[0.5,0.25],"model":"test-2","dimensions":2}`), &result))
+	assert.Equal(t, EmbeddingResult{Vector: []float32{0.5, 0.25}, Model: "test-2", Dimensions: 2}, result)
+
+	// Recorded before the activity reported its model
+	var legacy EmbeddingResult
+	require.NoError(t, json.Unmarshal([]byte(`[0.5,0.25]`), &legacy))
+	assert.Equal(t, EmbeddingResult{Vector: []float32{0.5, 0.25}, Dimensions: 2}, legacy)
+}
@@ -16,6 +16,9 @@ type ScheduledIngestionInput struct {
 	Schedule string            `json:"schedule"` // Cron expression
 	Filters  []string          `json:"filters"`
 	Metadata map[string]string `json:"metadata"`
+	
+	// DuplicatePolicy is passed to each document's ingestion; see DocumentInput
+	DuplicatePolicy string `json:"duplicate_policy,omitempty"`
 }
 
 // ScheduledIngestionWorkflow runs document collection on a schedule
@@ -38,20 +41,21 @@ func ScheduledIngestionWorkflow(ctx workflow.Context, input ScheduledIngestionIn
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	// Collect documents from source
-	var documents []CollectedDocument
+	var collected CollectionResult
+	var err error
 	
 	// Use appropriate collector based on source type
-	activityName := "CollectFromSourceActivity"
 	if isAcademicSource(input.Name) {
-		activityName = "CollectAcademicSourcesActivity"
 		logger.Info("Using academic collector with ethical rate limiting", "source", input.Name)
+		err = workflow.ExecuteActivity(ctx, "CollectAcademicSourcesActivity", input).Get(ctx, &collected)
+	} else {
+		err = workflow.ExecuteActivity(ctx, "CollectFromSourceActivity", input).Get(ctx, &collected.Documents)
 	}
-	
-	err := workflow.ExecuteActivity(ctx, activityName, input).Get(ctx, &documents)
 	if err != nil {
 		logger.Error("Failed to collect documents", "error", err)
 		return err
 	}
+	documents := collected.Documents
 
 	logger.Info("Collected documents", "count", len(documents))
 
@@ -71,26 +75,60 @@ func ScheduledIngestionWorkflow(ctx workflow.Context, input ScheduledIngestionIn
 			WorkflowID: "ingest-" + doc.ID,
 		})
 
+		// Content duplicates under other URLs are caught after extraction
 		future := workflow.ExecuteChildWorkflow(childCtx, DocumentIngestionWorkflow, DocumentInput{
-			URL:      doc.URL,
-			Type:     doc.Type,
-			Metadata: doc.Metadata,
+			URL:             doc.URL,
+			Type:            doc.Type,
+			Metadata:        doc.Metadata,
+			DuplicatePolicy: input.DuplicatePolicy,
 		})
 		futures = append(futures, future)
 	}
 
 	// Wait for all ingestions to complete
+	failed := 0
 	for _, future := range futures {
 		if err := future.Get(ctx, nil); err != nil {
 			logger.Error("Document ingestion failed", "error", err)
+			failed++
 			// Continue with other documents
 		}
 	}
 
+	// Move the query's cursor past these documents only once all of them
+	// are stored; otherwise the next run collects them again
+	if collected.Cursor != nil {
+		if failed > 0 {
+			logger.Warn("Not advancing collection cursor", "failed", failed)
+		} else if err := workflow.ExecuteActivity(ctx, "AdvanceCollectionCursorActivity", *collected.Cursor).Get(ctx, nil); err != nil {
+			logger.Error("Failed to advance collection cursor", "error", err)
+			return err
+		}
+	}
+
 	logger.Info("Scheduled ingestion completed", "processed", len(futures))
 	return nil
 }
 
+// CollectionResult is what an incremental collector found, with the cursor
+// its query resumes from once every document has been ingested
+type CollectionResult struct {
+	Documents []CollectedDocument `json:"documents"`
+	Cursor    *CollectionCursor   `json:"cursor,omitempty"`
+}
+
+// CollectionCursor is where an incremental query resumes. Articles up to
+// Since have been collected. When a run stops short of the oldest match,
+// Until marks the oldest day it reached and Next when it started: later
+// runs collect between Since and Until, and once they reach Since, Since
+// moves to Next.
+type CollectionCursor struct {
+	Key   string    `json:"key"`
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	Next  time.Time `json:"next"`
+}
+
 // CollectedDocument represents a document found by a collector
 type CollectedDocument struct {
 	ID       string            `json:"id"`
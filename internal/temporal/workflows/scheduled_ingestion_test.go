@@ -1,22 +1,42 @@
 package workflows
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/testsuite"
 )
 
+// registerCollectorActivities registers stand-ins for the collector
+// activities so they can be mocked by name
+func registerCollectorActivities(env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterActivityWithOptions(func(ctx context.Context, input ScheduledIngestionInput) (CollectionResult, error) {
+		return CollectionResult{}, nil
+	}, activity.RegisterOptions{Name: "CollectAcademicSourcesActivity"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, input ScheduledIngestionInput) ([]CollectedDocument, error) {
+		return nil, nil
+	}, activity.RegisterOptions{Name: "CollectFromSourceActivity"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, cursor CollectionCursor) error {
+		return nil
+	}, activity.RegisterOptions{Name: "AdvanceCollectionCursorActivity"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, documentID string) (bool, error) {
+		return false, nil
+	}, activity.RegisterOptions{Name: "CheckDuplicateActivity"})
+}
+
 func TestScheduledIngestionWorkflow(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestWorkflowEnvironment()
+	registerCollectorActivities(env)
 
 	// Mock activities
 	env.OnActivity("CollectAcademicSourcesActivity", mock.Anything, mock.Anything).Return(
-		[]CollectedDocument{
+		CollectionResult{Documents: []CollectedDocument{
 			{
 				ID:   "arxiv-2301.00001",
 				URL:  "https://arxiv.org/pdf/2301.00001.pdf",
@@ -35,7 +55,7 @@ func TestScheduledIngestionWorkflow(t *testing.T) {
 					"attribution": "Content from arXiv.org, collected by Caia Tech",
 				},
 			},
-		}, nil)
+		}}, nil)
 
 	env.OnActivity("CheckDuplicateActivity", mock.Anything, "arxiv-2301.00001").Return(false, nil)
 	env.OnActivity("CheckDuplicateActivity", mock.Anything, "arxiv-2301.00002").Return(true, nil) // Second doc is duplicate
@@ -64,19 +84,54 @@ func TestScheduledIngestionWorkflow(t *testing.T) {
 	env.AssertExpectations(t)
 
 	// Verify only non-duplicate document was processed
-	calls := env.GetWorkflowHistory().Events
-	childWorkflowCount := 0
-	for _, event := range calls {
-		if event.GetEventType().String() == "EVENT_TYPE_START_CHILD_WORKFLOW_EXECUTION_INITIATED" {
-			childWorkflowCount++
-		}
+	env.AssertNumberOfCalls(t, "DocumentIngestionWorkflow", 1)
+}
+
+func TestScheduledIngestionWorkflow_CollectionCursor(t *testing.T) {
+	testSuite := &testsuite.WorkflowTestSuite{}
+	cursor := CollectionCursor{Key: "plos|genomics", Since: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)}
+	collected := CollectionResult{
+		Documents: []CollectedDocument{
+			{ID: "plos-1", URL: "https://doi.org/10.1371/1", Type: "html"},
+			{ID: "plos-2", URL: "https://doi.org/10.1371/2", Type: "html"},
+		},
+		Cursor: &cursor,
 	}
-	assert.Equal(t, 1, childWorkflowCount, "Only one child workflow should be started (duplicate skipped)")
+	input := ScheduledIngestionInput{Name: "plos", Type: "plos", Filters: []string{"genomics"}}
+
+	// The cursor advances once every document is ingested
+	env := testSuite.NewTestWorkflowEnvironment()
+	registerCollectorActivities(env)
+	env.OnActivity("CollectAcademicSourcesActivity", mock.Anything, mock.Anything).Return(collected, nil)
+	env.OnWorkflow(DocumentIngestionWorkflow, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity("AdvanceCollectionCursorActivity", mock.Anything, cursor).Return(nil).Once()
+
+	env.ExecuteWorkflow(ScheduledIngestionWorkflow, input)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+
+	// but not when an ingestion fails, so the next run collects the
+	// documents again
+	env = testSuite.NewTestWorkflowEnvironment()
+	registerCollectorActivities(env)
+	env.OnActivity("CollectAcademicSourcesActivity", mock.Anything, mock.Anything).Return(collected, nil)
+	env.OnWorkflow(DocumentIngestionWorkflow, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnWorkflow(DocumentIngestionWorkflow, mock.Anything, mock.Anything).Return(assert.AnError).Once()
+	advanced := false
+	env.OnActivity("AdvanceCollectionCursorActivity", mock.Anything, mock.Anything).Return(func(ctx context.Context, cursor CollectionCursor) error {
+		advanced = true
+		return nil
+	})
+
+	env.ExecuteWorkflow(ScheduledIngestionWorkflow, input)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	assert.False(t, advanced)
 }
 
 func TestScheduledIngestionWorkflow_AcademicSource(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
-	env := testSuite.NewTestWorkflowEnvironment()
 
 	// Test that academic sources use the correct activity
 	academicSources := []string{"arxiv", "pubmed", "doaj", "plos"}
@@ -84,10 +139,11 @@ func TestScheduledIngestionWorkflow_AcademicSource(t *testing.T) {
 	for _, source := range academicSources {
 		t.Run(source, func(t *testing.T) {
 			env := testSuite.NewTestWorkflowEnvironment()
+			registerCollectorActivities(env)
 
 			// Should use academic collector for these sources
 			env.OnActivity("CollectAcademicSourcesActivity", mock.Anything, mock.Anything).Return(
-				[]CollectedDocument{}, nil).Once()
+				CollectionResult{}, nil).Once()
 
 			input := ScheduledIngestionInput{
 				Name: source,
@@ -104,6 +160,7 @@ func TestScheduledIngestionWorkflow_AcademicSource(t *testing.T) {
 func TestScheduledIngestionWorkflow_NonAcademicSource(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestWorkflowEnvironment()
+	registerCollectorActivities(env)
 
 	// Should use regular collector for non-academic sources
 	env.OnActivity("CollectFromSourceActivity", mock.Anything, mock.Anything).Return(
@@ -190,12 +247,13 @@ func TestIsAcademicSource(t *testing.T) {
 func TestScheduledIngestionWorkflow_CronSchedule(t *testing.T) {
 	testSuite := &testsuite.WorkflowTestSuite{}
 	env := testSuite.NewTestWorkflowEnvironment()
+	registerCollectorActivities(env)
 
 	// Set up cron schedule
 	env.SetStartTime(time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC))
 
 	env.OnActivity("CollectAcademicSourcesActivity", mock.Anything, mock.Anything).Return(
-		[]CollectedDocument{}, nil)
+		CollectionResult{}, nil)
 
 	input := ScheduledIngestionInput{
 		Name:     "arxiv",
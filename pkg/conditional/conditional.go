@@ -0,0 +1,225 @@
+// Package conditional makes re-fetching a URL cheap. It remembers the
+// validators a server sent with each page (ETag, Last-Modified) and a hash
+// of the content, so the next request can be conditional and a page that
+// comes back unchanged can be skipped.
+package conditional
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Validators describe the last version of a URL that was stored
+type Validators struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentHash  string    `json:"content_hash,omitempty"`
+	DocumentID   string    `json:"document_id,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// FromResponse records the validators of a fetched page
+func FromResponse(rawURL string, resp *http.Response, body []byte) Validators {
+	return Validators{
+		URL:          rawURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentHash:  HashContent(body),
+		FetchedAt:    time.Now().UTC(),
+	}
+}
+
+// HashContent returns the hex SHA-256 of a response body
+func HashContent(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Apply makes req conditional on the page having changed since these
+// validators were recorded
+func (v Validators) Apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// Unchanged reports whether body is the content these validators were
+// recorded for, for servers that ignore conditional requests
+func (v Validators) Unchanged(body []byte) bool {
+	return v.ContentHash != "" && v.ContentHash == HashContent(body)
+}
+
+// Store keeps the validators of every URL fetched. Opened with a path, it
+// appends each change to a JSON lines file that is replayed on open and
+// compacted when it has grown well beyond the number of URLs.
+type Store struct {
+	mu      sync.RWMutex
+	entries map[string]Validators
+	path    string
+	file    *os.File
+	lines   int
+}
+
+// NewStore creates a store that lasts as long as the process
+func NewStore() *Store {
+	return &Store{entries: make(map[string]Validators)}
+}
+
+// OpenStore opens the store saved at path, creating it if needed. A torn
+// or corrupt line, e.g. from a crash mid-write, is skipped.
+func OpenStore(path string) (*Store, error) {
+	s := NewStore()
+	s.path = path
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create validator store directory: %w", err)
+	}
+
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			s.lines++
+			var v Validators
+			if err := json.Unmarshal(scanner.Bytes(), &v); err != nil || v.URL == "" {
+				continue
+			}
+			s.entries[v.URL] = v
+		}
+		err := scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read validator store: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open validator store: %w", err)
+	}
+
+	// Rewriting on open also drops a torn final line
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the validators recorded for a URL
+func (s *Store) Get(rawURL string) (Validators, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.entries[rawURL]
+	return v, ok
+}
+
+// Put records the validators of a URL, replacing earlier ones
+func (s *Store) Put(v Validators) error {
+	if v.URL == "" {
+		return fmt.Errorf("validators have no URL")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[v.URL] = v
+	if s.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode validators: %w", err)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write validators: %w", err)
+	}
+	s.lines++
+
+	if s.lines > 2*len(s.entries)+1000 {
+		return s.compactLocked()
+	}
+	return nil
+}
+
+// Len returns the number of URLs with validators
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Close syncs and closes the store's file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Sync()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
+
+func (s *Store) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked()
+}
+
+// compactLocked rewrites the file with one line per URL and reopens it
+// for appending. Callers must hold s.mu.
+func (s *Store) compactLocked() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to compact validator store: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, v := range s.entries {
+		if err := enc.Encode(v); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact validator store: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact validator store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact validator store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact validator store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to compact validator store: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open validator store: %w", err)
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = file
+	s.lines = len(s.entries)
+	return nil
+}
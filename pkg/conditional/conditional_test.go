@@ -0,0 +1,99 @@
+package conditional
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidators(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("ETag", `"v1"`)
+	resp.Header.Set("Last-Modified", "Wed, 14 Oct 2026 08:00:00 GMT")
+
+	v := FromResponse("https://example.com/a", resp, []byte("body"))
+	assert.Equal(t, `"v1"`, v.ETag)
+	assert.True(t, v.Unchanged([]byte("body")))
+	assert.False(t, v.Unchanged([]byte("other body")))
+	assert.False(t, Validators{}.Unchanged(nil), "no hash recorded")
+
+	req, err := http.NewRequest("GET", v.URL, nil)
+	require.NoError(t, err)
+	v.Apply(req)
+	assert.Equal(t, `"v1"`, req.Header.Get("If-None-Match"))
+	assert.Equal(t, "Wed, 14 Oct 2026 08:00:00 GMT", req.Header.Get("If-Modified-Since"))
+
+	req, _ = http.NewRequest("GET", v.URL, nil)
+	Validators{}.Apply(req)
+	assert.Empty(t, req.Header)
+}
+
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validators", "fetch.jsonl")
+
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(Validators{URL: "https://example.com/a", ETag: `"1"`}))
+	require.NoError(t, store.Put(Validators{URL: "https://example.com/b", ContentHash: "abc"}))
+	require.NoError(t, store.Put(Validators{URL: "https://example.com/a", ETag: `"2"`, DocumentID: "doc-a"}))
+	assert.Error(t, store.Put(Validators{}))
+	require.NoError(t, store.Close())
+
+	// Simulate a crash mid-write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"url":"https://example.com/c","et`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store, err = OpenStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.Equal(t, 2, store.Len())
+	v, ok := store.Get("https://example.com/a")
+	require.True(t, ok)
+	assert.Equal(t, `"2"`, v.ETag)
+	assert.Equal(t, "doc-a", v.DocumentID)
+	_, ok = store.Get("https://example.com/c")
+	assert.False(t, ok)
+
+	// Writes after reopening land on their own line
+	require.NoError(t, store.Put(Validators{URL: "https://example.com/d"}))
+	require.NoError(t, store.Close())
+	store, err = OpenStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.Len())
+	require.NoError(t, store.Close())
+}
+
+func TestStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fetch.jsonl")
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for i := 0; i < 1500; i++ {
+		require.NoError(t, store.Put(Validators{URL: fmt.Sprintf("https://example.com/%d", i%10), ETag: fmt.Sprint(i)}))
+	}
+	assert.LessOrEqual(t, store.lines, 2*store.Len()+1000)
+
+	reopened, err := OpenStore(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	v, ok := reopened.Get("https://example.com/9")
+	require.True(t, ok)
+	assert.Equal(t, "1499", v.ETag)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.Put(Validators{URL: "https://example.com/"}))
+	assert.Equal(t, 1, store.Len())
+	assert.NoError(t, store.Close())
+}
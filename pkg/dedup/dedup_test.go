@@ -0,0 +1,117 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const article = `Researchers at the institute have published a new study on the
+long-term effects of urban heat islands. The study tracked surface temperatures
+across forty cities over a decade and found that neighbourhoods with little tree
+cover were consistently warmer at night. The authors recommend expanding green
+space and reflective roofing in the most affected districts, and they call for
+city planners to publish temperature data so residents can see the changes.`
+
+func TestNormalizeText(t *testing.T) {
+	assert.Equal(t, "hello world 2024", NormalizeText("  Hello,\n\tWORLD!  (2024) "))
+	assert.Equal(t, "", NormalizeText(" ... "))
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := NewFingerprint(article)
+	reformatted := NewFingerprint(strings.ToUpper(strings.ReplaceAll(article, "\n", "  ")))
+
+	assert.Equal(t, fp.ContentHash, reformatted.ContentHash, "Formatting should not change the exact hash")
+	assert.Equal(t, 1.0, fp.Similarity(reformatted))
+	assert.Zero(t, fp.SimHashDistance(reformatted))
+
+	parsed, err := ParseSimHash(fp.SimHashHex())
+	require.NoError(t, err)
+	assert.Equal(t, fp.SimHash, parsed)
+
+	edited := NewFingerprint(strings.Replace(article, "forty cities", "forty-two cities", 1))
+	assert.NotEqual(t, fp.ContentHash, edited.ContentHash)
+	assert.Greater(t, fp.Similarity(edited), 0.8)
+	assert.Less(t, fp.SimHashDistance(edited), 16)
+
+	unrelated := NewFingerprint("A recipe for sourdough bread needs flour, water, salt and a lively starter kept warm overnight.")
+	assert.Less(t, fp.Similarity(unrelated), 0.2)
+}
+
+func TestIndexAssign(t *testing.T) {
+	idx := NewIndex(DefaultConfig())
+
+	assert.Nil(t, idx.Assign("original", NewFingerprint(article)))
+
+	exact := idx.Assign("mirror", NewFingerprint(strings.ToLower(article)))
+	require.NotNil(t, exact)
+	assert.Equal(t, MatchExact, exact.Kind)
+	assert.Equal(t, "original", exact.DocumentID)
+	assert.Equal(t, "original", exact.ClusterID)
+
+	near := idx.Assign("edited", NewFingerprint(strings.Replace(article, "a decade", "ten years", 1)))
+	require.NotNil(t, near)
+	assert.Equal(t, MatchNear, near.Kind)
+	assert.Equal(t, "original", near.ClusterID)
+
+	assert.Nil(t, idx.Assign("other", NewFingerprint("An entirely different text about compilers, parsers and the grammar of programming languages.")))
+	assert.Nil(t, idx.Assign("empty", NewFingerprint("")))
+	assert.Nil(t, idx.Find(NewFingerprint("")), "Empty documents should not match each other")
+
+	assert.Equal(t, []string{"edited", "mirror", "original"}, idx.Members("original"))
+	assert.Equal(t, []string{"mirror", "original"}, idx.Lookup(NewFingerprint(article).ContentHash))
+	cluster, ok := idx.ClusterOf("mirror")
+	assert.True(t, ok)
+	assert.Equal(t, "original", cluster)
+
+	stats := idx.Stats()
+	assert.Equal(t, 5, stats["documents"])
+	assert.Equal(t, 1, stats["duplicate_clusters"])
+	assert.Equal(t, 2, stats["duplicates"])
+
+	// A new version of a document does not duplicate itself
+	assert.Nil(t, idx.FindOther(NewFingerprint("An entirely different text about compilers, parsers and the grammar of programming languages."), "other"))
+	assert.Equal(t, "original", idx.FindOther(NewFingerprint(article), "mirror").DocumentID)
+
+	idx.Remove("mirror")
+	assert.Equal(t, []string{"edited", "original"}, idx.Members("original"))
+	assert.Equal(t, []string{"original"}, idx.Lookup(NewFingerprint(article).ContentHash))
+}
+
+func TestMatchAnnotate(t *testing.T) {
+	fp := NewFingerprint(article)
+
+	metadata := map[string]string{}
+	var none *Match
+	none.Annotate(metadata, "doc-1", fp)
+	assert.Equal(t, "doc-1", metadata[MetaCluster])
+	assert.Equal(t, fp.ContentHash, metadata[MetaContentHash])
+	assert.NotContains(t, metadata, MetaDuplicateOf)
+
+	metadata = map[string]string{}
+	(&Match{Kind: MatchNear, DocumentID: "doc-1", ClusterID: "doc-1", Similarity: 0.875}).Annotate(metadata, "doc-2", fp)
+	assert.Equal(t, "doc-1", metadata[MetaCluster])
+	assert.Equal(t, "doc-1", metadata[MetaDuplicateOf])
+	assert.Equal(t, "near", metadata[MetaKind])
+	assert.Equal(t, "0.875", metadata[MetaSimilarity])
+}
+
+func TestIndexRebuild(t *testing.T) {
+	now := time.Now()
+	docs := []*document.Document{
+		{ID: "b", Content: document.Content{Text: article, Metadata: map[string]string{MetaCluster: "a"}}, CreatedAt: now},
+		{ID: "legacy", Content: document.Content{Text: article}, CreatedAt: now.Add(time.Hour)},
+		{ID: "a", Content: document.Content{Text: article, Metadata: map[string]string{MetaCluster: "a"}}, CreatedAt: now},
+	}
+
+	idx := NewIndex(DefaultConfig())
+	idx.Rebuild(docs)
+
+	assert.Equal(t, 3, idx.Size())
+	assert.Equal(t, []string{"a", "b", "legacy"}, idx.Members("a"))
+}
@@ -0,0 +1,196 @@
+// Package dedup detects exact and near-duplicate documents by content.
+//
+// Exact duplicates share a SHA-256 hash over normalized text. Near duplicates
+// are found with MinHash signatures over word shingles, bucketed by
+// locality-sensitive hashing; a SimHash is kept alongside for cheap
+// similarity checks between two known documents.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ShingleSize is the number of words in each shingle
+	ShingleSize = 3
+
+	// SignatureSize is the number of MinHash permutations per signature
+	SignatureSize = 64
+)
+
+// Fingerprint holds the content hashes computed for one document
+type Fingerprint struct {
+	ContentHash string                `json:"content_hash"`
+	SimHash     uint64                `json:"simhash"`
+	MinHash     [SignatureSize]uint64 `json:"-"`
+	Shingles    int                   `json:"shingles"`
+}
+
+// NewFingerprint computes the exact hash, SimHash and MinHash signature of text
+func NewFingerprint(text string) *Fingerprint {
+	normalized := NormalizeText(text)
+	sum := sha256.Sum256([]byte(normalized))
+
+	shingles := shingleHashes(normalized)
+	return &Fingerprint{
+		ContentHash: hex.EncodeToString(sum[:]),
+		SimHash:     simHash(shingles),
+		MinHash:     minHash(shingles),
+		Shingles:    len(shingles),
+	}
+}
+
+// NormalizeText lowercases text and collapses every run of characters that
+// are not letters or digits into a single space, so formatting, punctuation
+// and markup whitespace do not affect the hash
+func NormalizeText(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	space := false
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Similarity estimates the Jaccard similarity of the two documents' shingle
+// sets from their MinHash signatures
+func (f *Fingerprint) Similarity(other *Fingerprint) float64 {
+	if f.Shingles == 0 || other.Shingles == 0 {
+		if f.ContentHash == other.ContentHash {
+			return 1
+		}
+		return 0
+	}
+
+	matches := 0
+	for i := range f.MinHash {
+		if f.MinHash[i] == other.MinHash[i] {
+			matches++
+		}
+	}
+	return float64(matches) / SignatureSize
+}
+
+// SimHashDistance returns the Hamming distance between the two SimHashes
+func (f *Fingerprint) SimHashDistance(other *Fingerprint) int {
+	return bits.OnesCount64(f.SimHash ^ other.SimHash)
+}
+
+// SimHashHex formats the SimHash as a fixed-width hex string for metadata
+func (f *Fingerprint) SimHashHex() string {
+	return fmt.Sprintf("%016x", f.SimHash)
+}
+
+// ParseSimHash parses a SimHash written by SimHashHex
+func ParseSimHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// shingleHashes returns the distinct hashes of every ShingleSize-word window.
+// Texts shorter than one window produce a single shingle of all their words.
+func shingleHashes(normalized string) []uint64 {
+	words := strings.Fields(normalized)
+	if len(words) == 0 {
+		return nil
+	}
+
+	seen := make(map[uint64]struct{})
+	var result []uint64
+	add := func(shingle []string) {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(shingle, " ")))
+		sum := h.Sum64()
+		if _, ok := seen[sum]; !ok {
+			seen[sum] = struct{}{}
+			result = append(result, sum)
+		}
+	}
+
+	if len(words) < ShingleSize {
+		add(words)
+		return result
+	}
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		add(words[i : i+ShingleSize])
+	}
+	return result
+}
+
+// simHash combines shingle hashes into a 64-bit Charikar SimHash
+func simHash(shingles []uint64) uint64 {
+	var weights [64]int
+	for _, h := range shingles {
+		for bit := 0; bit < 64; bit++ {
+			if h&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var result uint64
+	for bit, w := range weights {
+		if w > 0 {
+			result |= 1 << uint(bit)
+		}
+	}
+	return result
+}
+
+// minHash computes a MinHash signature, deriving each permutation by mixing
+// the shingle hash with a per-permutation seed
+func minHash(shingles []uint64) [SignatureSize]uint64 {
+	var sig [SignatureSize]uint64
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	for _, h := range shingles {
+		for i := range sig {
+			if v := mix64(h ^ permutationSeeds[i]); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// permutationSeeds are fixed so signatures stay comparable across restarts
+var permutationSeeds = func() [SignatureSize]uint64 {
+	var seeds [SignatureSize]uint64
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		state += 0x9e3779b97f4a7c15
+		seeds[i] = mix64(state)
+	}
+	return seeds
+}()
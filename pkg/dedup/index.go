@@ -0,0 +1,370 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/Caia-Tech/caia-library/pkg/document"
+)
+
+// Metadata keys recorded on stored documents
+const (
+	MetaContentHash = "dedup_content_hash"
+	MetaSimHash     = "dedup_simhash"
+	MetaCluster     = "dedup_cluster"
+	MetaDuplicateOf = "dedup_duplicate_of"
+	MetaKind        = "dedup_kind"
+	MetaSimilarity  = "dedup_similarity"
+)
+
+// MatchKind classifies how a document relates to ones already indexed
+type MatchKind string
+
+const (
+	MatchNone  MatchKind = ""
+	MatchExact MatchKind = "exact"
+	MatchNear  MatchKind = "near"
+)
+
+// Config controls near-duplicate detection
+type Config struct {
+	// Bands is the number of LSH bands the MinHash signature is split into.
+	// More bands find less similar candidates at the cost of more comparisons.
+	Bands int
+
+	// SimilarityThreshold is the estimated Jaccard similarity at or above
+	// which two documents are near duplicates
+	SimilarityThreshold float64
+
+	// MinShingles is the minimum number of shingles a document needs before
+	// it is considered for near-duplicate matching; shorter texts only
+	// match exactly
+	MinShingles int
+}
+
+// DefaultConfig returns settings that flag documents sharing roughly 80% of
+// their shingles
+func DefaultConfig() Config {
+	return Config{
+		Bands:               16,
+		SimilarityThreshold: 0.8,
+		MinShingles:         5,
+	}
+}
+
+// Match describes the indexed document a new document duplicates
+type Match struct {
+	Kind       MatchKind `json:"kind"`
+	DocumentID string    `json:"document_id"`
+	ClusterID  string    `json:"cluster_id"`
+	Similarity float64   `json:"similarity"`
+}
+
+// Annotate records the fingerprint and cluster membership in metadata.
+// A nil match makes the document the head of its own cluster.
+func (m *Match) Annotate(metadata map[string]string, docID string, fp *Fingerprint) {
+	metadata[MetaContentHash] = fp.ContentHash
+	metadata[MetaSimHash] = fp.SimHashHex()
+
+	if m == nil || m.Kind == MatchNone {
+		metadata[MetaCluster] = docID
+		return
+	}
+	metadata[MetaCluster] = m.ClusterID
+	metadata[MetaDuplicateOf] = m.DocumentID
+	metadata[MetaKind] = string(m.Kind)
+	metadata[MetaSimilarity] = strconv.FormatFloat(m.Similarity, 'f', 3, 64)
+}
+
+type indexEntry struct {
+	fp      *Fingerprint
+	cluster string
+	bands   []string
+}
+
+// Index keeps document fingerprints and duplicate clusters in memory.
+// It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	config   Config
+	entries  map[string]*indexEntry
+	byHash   map[string][]string
+	buckets  map[string][]string
+	clusters map[string][]string
+}
+
+// NewIndex creates an empty index
+func NewIndex(config Config) *Index {
+	if config.Bands <= 0 || SignatureSize%config.Bands != 0 {
+		config.Bands = DefaultConfig().Bands
+	}
+	return &Index{
+		config:   config,
+		entries:  make(map[string]*indexEntry),
+		byHash:   make(map[string][]string),
+		buckets:  make(map[string][]string),
+		clusters: make(map[string][]string),
+	}
+}
+
+// Find returns the best match for fp among indexed documents, or nil
+func (idx *Index) Find(fp *Fingerprint) *Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.findLocked(fp, "")
+}
+
+// FindOther is Find for a new version of an indexed document: the document
+// itself is not a match
+func (idx *Index) FindOther(fp *Fingerprint, docID string) *Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.findLocked(fp, docID)
+}
+
+// Assign finds the best match for fp and adds the document to that match's
+// cluster, or to a new cluster of its own when there is none
+func (idx *Index) Assign(docID string, fp *Fingerprint) *Match {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	match := idx.findLocked(fp, docID)
+	cluster := docID
+	if match != nil {
+		cluster = match.ClusterID
+	}
+	idx.addLocked(docID, fp, cluster)
+	return match
+}
+
+// Add indexes a document under the given cluster. An empty cluster makes
+// the document the head of its own cluster. Re-adding a document replaces
+// its previous fingerprint.
+func (idx *Index) Add(docID string, fp *Fingerprint, cluster string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if cluster == "" {
+		cluster = docID
+	}
+	idx.addLocked(docID, fp, cluster)
+}
+
+// Rebuild replaces the index contents with docs. Documents keep the cluster
+// recorded in their metadata; those stored before deduplication existed are
+// assigned to clusters as if they were being stored now.
+func (idx *Index) Rebuild(docs []*document.Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.entries = make(map[string]*indexEntry)
+	idx.byHash = make(map[string][]string)
+	idx.buckets = make(map[string][]string)
+	idx.clusters = make(map[string][]string)
+
+	var unclustered []*document.Document
+	for _, doc := range docs {
+		if cluster := doc.Content.Metadata[MetaCluster]; cluster != "" {
+			idx.addLocked(doc.ID, NewFingerprint(doc.Content.Text), cluster)
+		} else {
+			unclustered = append(unclustered, doc)
+		}
+	}
+
+	sort.Slice(unclustered, func(i, j int) bool {
+		return unclustered[i].CreatedAt.Before(unclustered[j].CreatedAt)
+	})
+	for _, doc := range unclustered {
+		fp := NewFingerprint(doc.Content.Text)
+		cluster := doc.ID
+		if match := idx.findLocked(fp, doc.ID); match != nil {
+			cluster = match.ClusterID
+		}
+		idx.addLocked(doc.ID, fp, cluster)
+	}
+}
+
+// Remove drops a document from the index
+func (idx *Index) Remove(docID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(docID)
+}
+
+// Lookup returns the documents whose normalized text has the given hash,
+// sorted by ID
+func (idx *Index) Lookup(contentHash string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	ids := append([]string(nil), idx.byHash[contentHash]...)
+	sort.Strings(ids)
+	return ids
+}
+
+// ClusterOf returns the cluster a document belongs to
+func (idx *Index) ClusterOf(docID string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	entry, ok := idx.entries[docID]
+	if !ok {
+		return "", false
+	}
+	return entry.cluster, true
+}
+
+// Members returns the documents in a cluster, sorted by ID
+func (idx *Index) Members(cluster string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	members := append([]string(nil), idx.clusters[cluster]...)
+	sort.Strings(members)
+	return members
+}
+
+// Size returns the number of indexed documents
+func (idx *Index) Size() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.entries)
+}
+
+// Stats returns index statistics
+func (idx *Index) Stats() map[string]interface{} {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	duplicateClusters := 0
+	for _, members := range idx.clusters {
+		if len(members) > 1 {
+			duplicateClusters++
+		}
+	}
+	return map[string]interface{}{
+		"documents":          len(idx.entries),
+		"clusters":           len(idx.clusters),
+		"duplicate_clusters": duplicateClusters,
+		"duplicates":         len(idx.entries) - len(idx.clusters),
+	}
+}
+
+func (idx *Index) findLocked(fp *Fingerprint, self string) *Match {
+	// Documents without text say nothing about each other
+	if fp.Shingles == 0 {
+		return nil
+	}
+
+	for _, docID := range idx.byHash[fp.ContentHash] {
+		if docID != self {
+			return &Match{
+				Kind:       MatchExact,
+				DocumentID: docID,
+				ClusterID:  idx.entries[docID].cluster,
+				Similarity: 1,
+			}
+		}
+	}
+
+	if fp.Shingles < idx.config.MinShingles {
+		return nil
+	}
+
+	var best *Match
+	checked := make(map[string]bool)
+	for _, key := range idx.bandKeys(fp) {
+		for _, docID := range idx.buckets[key] {
+			if docID == self || checked[docID] {
+				continue
+			}
+			checked[docID] = true
+
+			entry := idx.entries[docID]
+			similarity := fp.Similarity(entry.fp)
+			if similarity < idx.config.SimilarityThreshold {
+				continue
+			}
+			if best == nil || similarity > best.Similarity || (similarity == best.Similarity && docID < best.DocumentID) {
+				best = &Match{
+					Kind:       MatchNear,
+					DocumentID: docID,
+					ClusterID:  entry.cluster,
+					Similarity: similarity,
+				}
+			}
+		}
+	}
+	return best
+}
+
+func (idx *Index) addLocked(docID string, fp *Fingerprint, cluster string) {
+	idx.removeLocked(docID)
+
+	entry := &indexEntry{fp: fp, cluster: cluster}
+	idx.entries[docID] = entry
+	idx.byHash[fp.ContentHash] = append(idx.byHash[fp.ContentHash], docID)
+	idx.clusters[cluster] = append(idx.clusters[cluster], docID)
+
+	if fp.Shingles >= idx.config.MinShingles {
+		entry.bands = idx.bandKeys(fp)
+		for _, key := range entry.bands {
+			idx.buckets[key] = append(idx.buckets[key], docID)
+		}
+	}
+}
+
+func (idx *Index) removeLocked(docID string) {
+	entry, ok := idx.entries[docID]
+	if !ok {
+		return
+	}
+	delete(idx.entries, docID)
+
+	removeID(idx.byHash, entry.fp.ContentHash, docID)
+	removeID(idx.clusters, entry.cluster, docID)
+	for _, key := range entry.bands {
+		removeID(idx.buckets, key, docID)
+	}
+}
+
+// bandKeys splits the MinHash signature into bands and hashes each one;
+// documents sharing any band key are candidate near duplicates
+func (idx *Index) bandKeys(fp *Fingerprint) []string {
+	rows := SignatureSize / idx.config.Bands
+	keys := make([]string, idx.config.Bands)
+
+	buf := make([]byte, 8)
+	for band := 0; band < idx.config.Bands; band++ {
+		h := fnv.New64a()
+		for _, v := range fp.MinHash[band*rows : (band+1)*rows] {
+			binary.LittleEndian.PutUint64(buf, v)
+			h.Write(buf)
+		}
+		keys[band] = fmt.Sprintf("%d:%016x", band, h.Sum64())
+	}
+	return keys
+}
+
+func removeID(m map[string][]string, key, docID string) {
+	ids := m[key]
+	for i, id := range ids {
+		if id == docID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(m, key)
+	} else {
+		m[key] = ids
+	}
+}
@@ -196,17 +196,22 @@ func normalize(embeddings []float32) []float32 {
 	return result
 }
 
-// CosineSimilarity calculates similarity between two embeddings
+// CosineSimilarity calculates similarity between two embeddings. Vectors
+// need not be normalized; a zero vector is similar to nothing.
 func CosineSimilarity(a, b []float32) float32 {
 	if len(a) != len(b) {
 		return 0
 	}
 	
-	var dotProduct float32
+	var dotProduct, normA, normB float64
 	for i := range a {
-		dotProduct += a[i] * b[i]
+		dotProduct += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
 	}
 	
-	// Assuming both vectors are normalized
-	return dotProduct
+	return float32(dotProduct / (math.Sqrt(normA) * math.Sqrt(normB)))
 }
\ No newline at end of file
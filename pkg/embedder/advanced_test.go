@@ -178,4 +178,10 @@ func BenchmarkAdvancedEmbedder_LongText(b *testing.B) {
 			b.Fatal(err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, CosineSimilarity([]float32{3, 4}, []float32{0.6, 0.8}), 1e-6, "Scale should not matter")
+	assert.InDelta(t, 0.0, CosineSimilarity([]float32{2, 0}, []float32{0, 5}), 1e-6)
+	assert.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+	assert.Zero(t, CosineSimilarity([]float32{1}, []float32{1, 0}))
+}
@@ -0,0 +1,142 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Metadata keys recording which model produced a document's embeddings
+const (
+	MetaModel      = "embedding_model"
+	MetaDimensions = "embedding_dimensions"
+)
+
+// Embedding providers selectable through Config
+const (
+	ProviderHash  = "hash"
+	ProviderTFIDF = "tfidf"
+	ProviderHTTP  = "http"
+)
+
+// Embedder turns text into fixed-length vectors
+type Embedder interface {
+	// Model identifies the model; vectors from different models are not
+	// comparable
+	Model() string
+
+	// Dimensions is the length of every vector the model produces. It may
+	// be zero for remote models until the first vector has been generated.
+	Dimensions() int
+
+	// Generate embeds each text, returning one vector per input in order
+	Generate(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Config selects and configures an embedding provider
+type Config struct {
+	// Provider is "hash", "tfidf" or "http"
+	Provider string `json:"provider"`
+
+	// Dimensions of the produced vectors. Required for hash and tfidf;
+	// for http it is checked against the server's responses when set.
+	Dimensions int `json:"dimensions"`
+
+	// ModelPath is where a trained tfidf model is saved and loaded
+	ModelPath string `json:"model_path,omitempty"`
+
+	// Endpoint is the base URL of the http embedding server
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Model is the model name sent to the http embedding server
+	Model string `json:"model,omitempty"`
+
+	// API is the http request format: "openai" or "ollama"
+	API string `json:"api,omitempty"`
+
+	// APIKey is sent as a bearer token to the http embedding server
+	APIKey string `json:"-"`
+
+	// BatchSize is the number of texts sent per http request
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// Timeout for each http request
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultConfig returns the hash embedder at the library's standard 384
+// dimensions
+func DefaultConfig() *Config {
+	return &Config{
+		Provider:   ProviderHash,
+		Dimensions: 384,
+		API:        APIOpenAI,
+		BatchSize:  32,
+		Timeout:    30 * time.Second,
+	}
+}
+
+// New creates the embedder described by config. A tfidf provider loads its
+// model from ModelPath; use TrainTFIDFEmbedder to create one.
+func New(config *Config) (Embedder, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	switch config.Provider {
+	case "", ProviderHash:
+		dimensions := config.Dimensions
+		if dimensions <= 0 {
+			dimensions = DefaultConfig().Dimensions
+		}
+		return NewHashEmbedder(dimensions), nil
+	case ProviderTFIDF:
+		if config.ModelPath == "" {
+			return nil, fmt.Errorf("tfidf embedder requires a model path")
+		}
+		return LoadTFIDFEmbedder(config.ModelPath)
+	case ProviderHTTP:
+		return NewHTTPEmbedder(config)
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Provider)
+	}
+}
+
+// RecordModel stores the model name and vector dimensions in document
+// metadata
+func RecordModel(metadata map[string]string, model string, dimensions int) {
+	metadata[MetaModel] = model
+	metadata[MetaDimensions] = strconv.Itoa(dimensions)
+}
+
+// HashEmbedder adapts AdvancedEmbedder to the Embedder interface. Its
+// vectors capture surface features of the text, not meaning.
+type HashEmbedder struct {
+	embedder *AdvancedEmbedder
+}
+
+// NewHashEmbedder creates a hash embedder producing vectors of the given size
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{embedder: NewAdvancedEmbedder(dimensions)}
+}
+
+func (h *HashEmbedder) Model() string {
+	return fmt.Sprintf("caia-hash-%d", h.embedder.Dimensions)
+}
+
+func (h *HashEmbedder) Dimensions() int {
+	return h.embedder.Dimensions
+}
+
+func (h *HashEmbedder) Generate(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := h.embedder.Generate(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
@@ -0,0 +1,167 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func TestNew_Providers(t *testing.T) {
+	e, err := New(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "caia-hash-384", e.Model())
+	assert.Equal(t, 384, e.Dimensions())
+
+	_, err = New(&Config{Provider: ProviderTFIDF})
+	assert.Error(t, err, "tfidf needs a model path")
+
+	_, err = New(&Config{Provider: ProviderHTTP, Model: "m"})
+	assert.Error(t, err, "http needs an endpoint")
+
+	_, err = New(&Config{Provider: "word2vec"})
+	assert.Error(t, err)
+
+	metadata := map[string]string{}
+	RecordModel(metadata, e.Model(), e.Dimensions())
+	assert.Equal(t, "caia-hash-384", metadata[MetaModel])
+	assert.Equal(t, "384", metadata[MetaDimensions])
+}
+
+func TestTFIDFEmbedder(t *testing.T) {
+	corpus := []string{
+		"The neural network learns weights through gradient descent and backpropagation.",
+		"Training a deep neural network requires gradient descent over many epochs.",
+		"Backpropagation computes the gradient for every layer of the network.",
+		"The recipe calls for flour, butter and sugar baked in a hot oven.",
+		"Bake the bread in the oven until the crust turns golden; flour the board first.",
+		"Butter and sugar are creamed together before the flour is folded in.",
+		"Stock markets fell as interest rates rose and investors sold shares.",
+		"Investors bought shares when interest rates were cut by the central bank.",
+	}
+
+	model, err := TrainTFIDFEmbedder(corpus, 16)
+	require.NoError(t, err)
+	assert.Equal(t, 16, model.Dimensions())
+	assert.Greater(t, model.Vocabulary(), 0)
+
+	ctx := context.Background()
+	vectors, err := model.Generate(ctx, []string{
+		"gradient descent trains the neural network",
+		"backpropagation of the gradient through a network",
+		"butter, sugar and flour for the oven",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 3)
+	for _, v := range vectors {
+		assert.Len(t, v, 16)
+	}
+	assert.Greater(t, cosine(vectors[0], vectors[1]), cosine(vectors[0], vectors[2]),
+		"Texts on the same topic should be closer")
+
+	// Save and load reproduce the same model
+	path := filepath.Join(t.TempDir(), "tfidf.json")
+	require.NoError(t, model.Save(path))
+
+	loaded, err := New(&Config{Provider: ProviderTFIDF, ModelPath: path})
+	require.NoError(t, err)
+	assert.Equal(t, model.Model(), loaded.Model())
+
+	reloaded, err := loaded.Generate(ctx, []string{"gradient descent trains the neural network"})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, vectors[0], reloaded[0], 1e-6)
+
+	_, err = TrainTFIDFEmbedder([]string{"", "   "}, 16)
+	assert.ErrorIs(t, err, ErrEmptyCorpus)
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "mini", body.Model)
+
+		vector := func(i int) []float32 { return []float32{float32(len(body.Input[i])), 1, 0} }
+
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			type item struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}
+			// Returned out of order to check the index is honoured
+			data := make([]item, 0, len(body.Input))
+			for i := len(body.Input) - 1; i >= 0; i-- {
+				data = append(data, item{Index: i, Embedding: vector(i)})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		case "/api/embed":
+			embeddings := make([][]float32, len(body.Input))
+			for i := range body.Input {
+				embeddings[i] = vector(i)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+
+	openai, err := New(&Config{Provider: ProviderHTTP, Endpoint: server.URL + "/", Model: "mini", API: APIOpenAI, APIKey: "secret", BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 0, openai.Dimensions(), "Dimensions are learned from the server")
+
+	vectors, err := openai.Generate(ctx, texts)
+	require.NoError(t, err)
+	require.Len(t, vectors, 5)
+	for i, v := range vectors {
+		assert.Equal(t, float32(i+1), v[0])
+	}
+	assert.Equal(t, 3, openai.Dimensions())
+	assert.Equal(t, "mini", openai.Model())
+	assert.Equal(t, 3, requests, "Texts should be sent in batches")
+
+	ollama, err := New(&Config{Provider: ProviderHTTP, Endpoint: server.URL, Model: "mini", API: APIOllama, Dimensions: 3})
+	require.NoError(t, err)
+	vectors, err = ollama.Generate(ctx, texts[:2])
+	require.NoError(t, err)
+	assert.Equal(t, []float32{2, 1, 0}, vectors[1])
+
+	mismatched, err := New(&Config{Provider: ProviderHTTP, Endpoint: server.URL, Model: "mini", API: APIOllama, Dimensions: 8})
+	require.NoError(t, err)
+	_, err = mismatched.Generate(ctx, texts[:1])
+	assert.Error(t, err, "Vectors of the wrong size should be rejected")
+
+	engine := NewEngineWithEmbedder(ollama)
+	single, err := engine.Generate(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 1, 0}, single)
+}
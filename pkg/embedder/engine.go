@@ -2,19 +2,62 @@ package embedder
 
 import (
 	"context"
+	"fmt"
 )
 
+// Engine generates embeddings with a configured Embedder
 type Engine struct {
-	embedder *AdvancedEmbedder
+	embedder Embedder
 }
 
 func NewEngine() (*Engine, error) {
-	// Use advanced embedder with 384 dimensions (same as all-MiniLM-L6-v2)
+	// Use hash embedder with 384 dimensions (same as all-MiniLM-L6-v2)
 	return &Engine{
-		embedder: NewAdvancedEmbedder(384),
+		embedder: NewHashEmbedder(384),
 	}, nil
 }
 
+// NewEngineWithConfig creates an engine using the provider in config
+func NewEngineWithConfig(config *Config) (*Engine, error) {
+	e, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewEngineWithEmbedder(e), nil
+}
+
+// NewEngineWithEmbedder creates an engine around an existing embedder
+func NewEngineWithEmbedder(e Embedder) *Engine {
+	return &Engine{embedder: e}
+}
+
 func (e *Engine) Generate(ctx context.Context, text string) ([]float32, error) {
-	return e.embedder.Generate(ctx, text)
-}
\ No newline at end of file
+	vectors, err := e.embedder.Generate(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// GenerateBatch embeds several texts in one call to the embedder
+func (e *Engine) GenerateBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.embedder.Generate(ctx, texts)
+}
+
+// Model returns the name of the model the engine uses
+func (e *Engine) Model() string {
+	return e.embedder.Model()
+}
+
+// Dimensions returns the length of the engine's vectors
+func (e *Engine) Dimensions() int {
+	return e.embedder.Dimensions()
+}
+
+// Embedder returns the underlying embedder
+func (e *Engine) Embedder() Embedder {
+	return e.embedder
+}
@@ -0,0 +1,208 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request formats understood by HTTPEmbedder
+const (
+	// APIOpenAI posts to /v1/embeddings, as served by OpenAI-compatible
+	// servers such as llama.cpp, vLLM and LocalAI
+	APIOpenAI = "openai"
+
+	// APIOllama posts to Ollama's /api/embed
+	APIOllama = "ollama"
+)
+
+// HTTPEmbedder calls a local embedding server
+type HTTPEmbedder struct {
+	client    *http.Client
+	endpoint  string
+	model     string
+	api       string
+	apiKey    string
+	batchSize int
+
+	mu         sync.RWMutex
+	dimensions int
+}
+
+// NewHTTPEmbedder creates a client for the server described by config
+func NewHTTPEmbedder(config *Config) (*HTTPEmbedder, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("http embedder requires an endpoint")
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("http embedder requires a model name")
+	}
+
+	api := config.API
+	if api == "" {
+		api = APIOpenAI
+	}
+	if api != APIOpenAI && api != APIOllama {
+		return nil, fmt.Errorf("unsupported embedding API: %s", api)
+	}
+
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultConfig().BatchSize
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultConfig().Timeout
+	}
+
+	return &HTTPEmbedder{
+		client:     &http.Client{Timeout: timeout},
+		endpoint:   strings.TrimRight(config.Endpoint, "/"),
+		model:      config.Model,
+		api:        api,
+		apiKey:     config.APIKey,
+		batchSize:  batchSize,
+		dimensions: config.Dimensions,
+	}, nil
+}
+
+func (h *HTTPEmbedder) Model() string {
+	return h.model
+}
+
+// Dimensions returns the configured size, or the size of the vectors the
+// server has returned so far when none was configured
+func (h *HTTPEmbedder) Dimensions() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.dimensions
+}
+
+func (h *HTTPEmbedder) Generate(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += h.batchSize {
+		end := start + h.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := h.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if err := h.checkDimensions(batch); err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (h *HTTPEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var (
+		url  string
+		body interface{}
+	)
+	switch h.api {
+	case APIOllama:
+		url = h.endpoint + "/api/embed"
+		body = map[string]interface{}{"model": h.model, "input": texts}
+	default:
+		url = h.endpoint + "/v1/embeddings"
+		body = map[string]interface{}{"model": h.model, "input": texts}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding server returned %d after %v: %s", resp.StatusCode, time.Since(start), truncate(string(data), 200))
+	}
+
+	var vectors [][]float32
+	switch h.api {
+	case APIOllama:
+		var parsed struct {
+			Embeddings [][]float32 `json:"embeddings"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+		}
+		vectors = parsed.Embeddings
+	default:
+		var parsed struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+		}
+		vectors = make([][]float32, len(parsed.Data))
+		for _, item := range parsed.Data {
+			if item.Index < 0 || item.Index >= len(vectors) {
+				return nil, fmt.Errorf("embedding response has out of range index %d", item.Index)
+			}
+			vectors[item.Index] = item.Embedding
+		}
+	}
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding server returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// checkDimensions verifies every vector has the model's size, learning the
+// size from the first response when it was not configured
+func (h *HTTPEmbedder) checkDimensions(vectors [][]float32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, v := range vectors {
+		if h.dimensions == 0 {
+			h.dimensions = len(v)
+		}
+		if len(v) != h.dimensions {
+			return fmt.Errorf("embedding server returned %d dimensions, expected %d", len(v), h.dimensions)
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
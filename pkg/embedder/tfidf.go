@@ -0,0 +1,500 @@
+package embedder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+const (
+	// maxVocabulary bounds the number of terms a TF-IDF model keeps
+	maxVocabulary = 10000
+
+	// svdOversample is the number of extra directions sampled by the
+	// randomized SVD to improve accuracy of the leading components
+	svdOversample = 10
+
+	// svdPowerIterations sharpens the randomized SVD for slowly decaying
+	// singular values, which is typical of text
+	svdPowerIterations = 2
+)
+
+// TFIDFEmbedder projects TF-IDF term vectors onto the leading singular
+// vectors of a training corpus (latent semantic analysis). Documents that
+// use related vocabulary end up close together even without shared terms.
+type TFIDFEmbedder struct {
+	terms      []string
+	vocabulary map[string]int
+	idf        []float64
+	// components holds the projection, one row of dimensions values per term
+	components [][]float32
+	dimensions int
+	model      string
+}
+
+// ErrEmptyCorpus is returned when a TF-IDF model is trained on a corpus with
+// no indexable terms, such as an empty library
+var ErrEmptyCorpus = errors.New("training corpus has no usable text")
+
+// tfidfModelFile is the on-disk form of a trained model
+type tfidfModelFile struct {
+	Dimensions int         `json:"dimensions"`
+	Terms      []string    `json:"terms"`
+	IDF        []float64   `json:"idf"`
+	Components [][]float32 `json:"components"`
+}
+
+// TrainTFIDFEmbedder fits a TF-IDF vocabulary and an LSA projection of the
+// given size on a corpus. If the corpus has fewer independent directions
+// than dimensions, the remaining components are zero.
+func TrainTFIDFEmbedder(corpus []string, dimensions int) (*TFIDFEmbedder, error) {
+	if dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive")
+	}
+
+	docs := make([][]string, 0, len(corpus))
+	for _, text := range corpus {
+		if terms := tfidfTerms(text); len(terms) > 0 {
+			docs = append(docs, terms)
+		}
+	}
+	if len(docs) == 0 {
+		return nil, ErrEmptyCorpus
+	}
+
+	terms := selectVocabulary(docs)
+	e := &TFIDFEmbedder{
+		terms:      terms,
+		vocabulary: make(map[string]int, len(terms)),
+		idf:        make([]float64, len(terms)),
+		dimensions: dimensions,
+	}
+	for i, term := range terms {
+		e.vocabulary[term] = i
+	}
+
+	df := make([]int, len(terms))
+	for _, doc := range docs {
+		seen := make(map[int]bool)
+		for _, term := range doc {
+			if col, ok := e.vocabulary[term]; ok && !seen[col] {
+				seen[col] = true
+				df[col]++
+			}
+		}
+	}
+	n := float64(len(docs))
+	for i := range terms {
+		e.idf[i] = math.Log((1+n)/(1+float64(df[i]))) + 1
+	}
+
+	rows := make([][]sparseEntry, len(docs))
+	for i, doc := range docs {
+		rows[i] = e.weigh(doc)
+	}
+
+	e.components = lsaComponents(rows, len(terms), dimensions)
+	e.model = e.fingerprint()
+	return e, nil
+}
+
+// LoadTFIDFEmbedder reads a model written by Save
+func LoadTFIDFEmbedder(path string) (*TFIDFEmbedder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tfidf model: %w", err)
+	}
+
+	var file tfidfModelFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tfidf model: %w", err)
+	}
+	if len(file.IDF) != len(file.Terms) || len(file.Components) != len(file.Terms) {
+		return nil, fmt.Errorf("tfidf model is inconsistent: %d terms, %d idf weights, %d components",
+			len(file.Terms), len(file.IDF), len(file.Components))
+	}
+
+	e := &TFIDFEmbedder{
+		terms:      file.Terms,
+		vocabulary: make(map[string]int, len(file.Terms)),
+		idf:        file.IDF,
+		components: file.Components,
+		dimensions: file.Dimensions,
+	}
+	for i, term := range file.Terms {
+		e.vocabulary[term] = i
+	}
+	e.model = e.fingerprint()
+	return e, nil
+}
+
+// Save writes the model to path
+func (e *TFIDFEmbedder) Save(path string) error {
+	data, err := json.Marshal(tfidfModelFile{
+		Dimensions: e.dimensions,
+		Terms:      e.terms,
+		IDF:        e.idf,
+		Components: e.components,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal tfidf model: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tfidf model: %w", err)
+	}
+	return nil
+}
+
+// Model names the model by its size and a hash of its contents, so vectors
+// from models trained on different corpora are told apart
+func (e *TFIDFEmbedder) Model() string {
+	return e.model
+}
+
+func (e *TFIDFEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Generate projects each text into the LSA space. Texts sharing no terms
+// with the training vocabulary produce a zero vector.
+func (e *TFIDFEmbedder) Generate(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("text cannot be empty")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vector := make([]float32, e.dimensions)
+		for _, entry := range e.weigh(tfidfTerms(text)) {
+			row := e.components[entry.col]
+			for d := range vector {
+				vector[d] += float32(entry.value) * row[d]
+			}
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+// Vocabulary returns the number of terms in the model
+func (e *TFIDFEmbedder) Vocabulary() int {
+	return len(e.terms)
+}
+
+type sparseEntry struct {
+	col   int
+	value float64
+}
+
+// weigh converts terms to a unit-length sublinear TF-IDF vector
+func (e *TFIDFEmbedder) weigh(terms []string) []sparseEntry {
+	counts := make(map[int]int)
+	for _, term := range terms {
+		if col, ok := e.vocabulary[term]; ok {
+			counts[col]++
+		}
+	}
+
+	entries := make([]sparseEntry, 0, len(counts))
+	var norm float64
+	for col, count := range counts {
+		value := (1 + math.Log(float64(count))) * e.idf[col]
+		entries = append(entries, sparseEntry{col: col, value: value})
+		norm += value * value
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range entries {
+			entries[i].value /= norm
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].col < entries[j].col })
+	return entries
+}
+
+func (e *TFIDFEmbedder) fingerprint() string {
+	h := sha256.New()
+	for i, term := range e.terms {
+		fmt.Fprintf(h, "%s:%g:", term, e.idf[i])
+		if len(e.components[i]) > 0 {
+			fmt.Fprintf(h, "%g\n", e.components[i][0])
+		}
+	}
+	return fmt.Sprintf("tfidf-lsa-%d-%s", e.dimensions, hex.EncodeToString(h.Sum(nil))[:12])
+}
+
+// tfidfTerms tokenizes text, dropping single characters and stop words
+func tfidfTerms(text string) []string {
+	words := tokenize(normalizeText(text))
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) > 1 && !stopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// selectVocabulary keeps terms that occur in more than one document, or all
+// terms for a single-document corpus, up to maxVocabulary by frequency
+func selectVocabulary(docs [][]string) []string {
+	df := make(map[string]int)
+	for _, doc := range docs {
+		seen := make(map[string]bool)
+		for _, term := range doc {
+			if !seen[term] {
+				seen[term] = true
+				df[term]++
+			}
+		}
+	}
+
+	minDF := 2
+	if len(docs) < 2 {
+		minDF = 1
+	}
+
+	terms := make([]string, 0, len(df))
+	for term, count := range df {
+		if count >= minDF {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if df[terms[i]] != df[terms[j]] {
+			return df[terms[i]] > df[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > maxVocabulary {
+		terms = terms[:maxVocabulary]
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// lsaComponents computes the leading right singular vectors of the sparse
+// document-term matrix with a randomized SVD (Halko, Martinsson & Tropp),
+// returning them as one row of dimensions values per term
+func lsaComponents(rows [][]sparseEntry, numTerms, dimensions int) [][]float32 {
+	sample := dimensions + svdOversample
+	if sample > numTerms {
+		sample = numTerms
+	}
+	if sample > len(rows) {
+		sample = len(rows)
+	}
+
+	// Random projection of the term space
+	rng := rand.New(rand.NewSource(7))
+	omega := newMatrix(numTerms, sample)
+	for i := range omega {
+		for j := range omega[i] {
+			omega[i][j] = rng.NormFloat64()
+		}
+	}
+
+	q := orthonormalize(multiplySparse(rows, omega, sample))
+	for i := 0; i < svdPowerIterations; i++ {
+		z := orthonormalize(multiplySparseT(rows, q, numTerms, sample))
+		q = orthonormalize(multiplySparse(rows, z, sample))
+	}
+
+	// Bᵀ = Xᵀ Q is terms × sample; the eigenvectors of B Bᵀ = Zᵀ Z give
+	// the right singular vectors as V = Z u / σ
+	z := multiplySparseT(rows, q, numTerms, sample)
+	gram := newMatrix(sample, sample)
+	for _, row := range z {
+		for a := 0; a < sample; a++ {
+			for b := a; b < sample; b++ {
+				gram[a][b] += row[a] * row[b]
+			}
+		}
+	}
+	for a := 0; a < sample; a++ {
+		for b := 0; b < a; b++ {
+			gram[a][b] = gram[b][a]
+		}
+	}
+	values, vectors := symmetricEigen(gram)
+
+	components := make([][]float32, numTerms)
+	for t := range components {
+		components[t] = make([]float32, dimensions)
+	}
+	for d := 0; d < dimensions && d < sample; d++ {
+		if values[d] <= 1e-10 {
+			break
+		}
+		sigma := math.Sqrt(values[d])
+		for t, row := range z {
+			var v float64
+			for a := 0; a < sample; a++ {
+				v += row[a] * vectors[a][d]
+			}
+			components[t][d] = float32(v / sigma)
+		}
+	}
+	return components
+}
+
+func newMatrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+// multiplySparse returns X M for sparse X (docs × terms) and dense M (terms × cols)
+func multiplySparse(rows [][]sparseEntry, m [][]float64, cols int) [][]float64 {
+	result := newMatrix(len(rows), cols)
+	for i, row := range rows {
+		for _, entry := range row {
+			for j, v := range m[entry.col] {
+				result[i][j] += entry.value * v
+			}
+		}
+	}
+	return result
+}
+
+// multiplySparseT returns Xᵀ M for sparse X (docs × terms) and dense M (docs × cols)
+func multiplySparseT(rows [][]sparseEntry, m [][]float64, numTerms, cols int) [][]float64 {
+	result := newMatrix(numTerms, cols)
+	for i, row := range rows {
+		for _, entry := range row {
+			for j, v := range m[i] {
+				result[entry.col][j] += entry.value * v
+			}
+		}
+	}
+	return result
+}
+
+// orthonormalize makes the columns of m orthonormal in place using modified
+// Gram-Schmidt; columns that become numerically zero are left zero
+func orthonormalize(m [][]float64) [][]float64 {
+	if len(m) == 0 {
+		return m
+	}
+	cols := len(m[0])
+	for j := 0; j < cols; j++ {
+		for k := 0; k < j; k++ {
+			var d float64
+			for i := range m {
+				d += m[i][j] * m[i][k]
+			}
+			for i := range m {
+				m[i][j] -= d * m[i][k]
+			}
+		}
+		var norm float64
+		for i := range m {
+			norm += m[i][j] * m[i][j]
+		}
+		norm = math.Sqrt(norm)
+		for i := range m {
+			if norm > 1e-12 {
+				m[i][j] /= norm
+			} else {
+				m[i][j] = 0
+			}
+		}
+	}
+	return m
+}
+
+// symmetricEigen diagonalizes a symmetric matrix with cyclic Jacobi
+// rotations, returning eigenvalues in descending order and the matching
+// eigenvectors as columns
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	v := newMatrix(n, n)
+	for i := range v {
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < 100; sweep++ {
+		var off float64
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(a[p][q]) < 1e-300 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return a[order[i]][order[i]] > a[order[j]][order[j]] })
+
+	values := make([]float64, n)
+	vectors := newMatrix(n, n)
+	for j, idx := range order {
+		values[j] = a[idx][idx]
+		for i := 0; i < n; i++ {
+			vectors[i][j] = v[i][idx]
+		}
+	}
+	return values, vectors
+}
+
+// stopWords are common English words that carry little topical meaning
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "been": true, "but": true, "by": true, "can": true, "do": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true, "he": true,
+	"her": true, "his": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "may": true, "more": true, "no": true, "not": true,
+	"of": true, "on": true, "or": true, "our": true, "she": true, "so": true,
+	"such": true, "than": true, "that": true, "the": true, "their": true, "them": true,
+	"then": true, "there": true, "these": true, "they": true, "this": true, "to": true,
+	"was": true, "we": true, "were": true, "which": true, "while": true, "who": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+}
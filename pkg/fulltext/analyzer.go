@@ -0,0 +1,74 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxTokenLength bounds the terms kept by the analyzer; longer runs of
+// letters are almost always encoded data rather than words
+const maxTokenLength = 64
+
+// Token is an analyzed term and its position in the source text. Positions
+// count every word, including dropped stopwords, so phrase queries keep the
+// original spacing between terms.
+type Token struct {
+	Term     string
+	Position int
+}
+
+// Analyze splits text into lowercase words, drops stopwords and reduces the
+// remaining words to their stems
+func Analyze(text string) []Token {
+	var tokens []Token
+	position := 0
+	for _, word := range splitWords(text) {
+		if len(word) <= maxTokenLength && !isStopword(word) {
+			tokens = append(tokens, Token{Term: Stem(word), Position: position})
+		}
+		position++
+	}
+	return tokens
+}
+
+// Terms returns the distinct analyzed terms of text in order of first use
+func Terms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range Analyze(text) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// splitWords lowercases text and splits it on anything that is not a letter
+// or digit
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isStopword(word string) bool {
+	_, ok := stopwords[word]
+	return ok
+}
+
+// stopwords are common English words that carry little meaning for ranking
+var stopwords = map[string]struct{}{
+	"a": {}, "about": {}, "after": {}, "all": {}, "also": {}, "an": {}, "and": {},
+	"any": {}, "are": {}, "as": {}, "at": {}, "be": {}, "been": {}, "but": {},
+	"by": {}, "can": {}, "could": {}, "did": {}, "do": {}, "does": {}, "for": {},
+	"from": {}, "had": {}, "has": {}, "have": {}, "he": {}, "her": {}, "his": {},
+	"how": {}, "i": {}, "if": {}, "in": {}, "into": {}, "is": {}, "it": {},
+	"its": {}, "may": {}, "more": {}, "most": {}, "no": {}, "not": {}, "of": {},
+	"on": {}, "or": {}, "other": {}, "our": {}, "she": {}, "should": {}, "so": {},
+	"some": {}, "such": {}, "than": {}, "that": {}, "the": {}, "their": {},
+	"them": {}, "then": {}, "there": {}, "these": {}, "they": {}, "this": {},
+	"those": {}, "to": {}, "was": {}, "we": {}, "were": {}, "what": {}, "when": {},
+	"which": {}, "while": {}, "who": {}, "will": {}, "with": {}, "would": {},
+	"you": {}, "your": {},
+}
@@ -0,0 +1,105 @@
+package fulltext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"cats":           "cat",
+		"agreed":         "agre",
+		"running":        "run",
+		"hopping":        "hop",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"generalization": "gener",
+		"networks":       "network",
+		"learning":       "learn",
+		"adjustment":     "adjust",
+		"controll":       "control",
+		"go":             "go",
+		"naïve":          "naïve",
+	}
+	for word, want := range cases {
+		assert.Equal(t, want, Stem(word), word)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tokens := Analyze("The State of the Art in Neural Networks!")
+	require.Len(t, tokens, 4)
+	assert.Equal(t, Token{Term: "state", Position: 1}, tokens[0])
+	assert.Equal(t, Token{Term: "art", Position: 4}, tokens[1])
+	assert.Equal(t, Token{Term: "neural", Position: 6}, tokens[2])
+	assert.Equal(t, Token{Term: "network", Position: 7}, tokens[3])
+
+	assert.Equal(t, []string{"learn", "machin"}, Terms("learning machines, machine learning"))
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex(nil)
+	ix.Add("nn", map[string]string{
+		"title": "Neural networks",
+		"text":  "Deep neural networks learn representations. Networks of neurons are trained with gradient descent.",
+	})
+	ix.Add("bake", map[string]string{
+		"title": "Baking bread",
+		"text":  "Knead the dough and bake the bread in a hot oven.",
+	})
+	ix.Add("net", map[string]string{
+		"title": "Fishing",
+		"text":  "A fishing net is not a neural network, though both have networks of knots.",
+	})
+	assert.Equal(t, 3, ix.Size())
+
+	// Ranked keyword search, with the title boost favouring "nn"
+	hits := ix.Search(&Query{Text: "neural networks"})
+	require.Len(t, hits, 2)
+	assert.Equal(t, "nn", hits[0].ID)
+	assert.Equal(t, "net", hits[1].ID)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+
+	// Phrases must appear in order, allowing for dropped stopwords
+	hits = ix.Search(&Query{Text: `"networks of neurons"`})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "nn", hits[0].ID)
+	assert.Empty(t, ix.Search(&Query{Text: `"neurons networks"`}))
+
+	// MatchAll requires every clause
+	assert.Len(t, ix.Search(&Query{Text: "neural oven"}), 3)
+	assert.Empty(t, ix.Search(&Query{Text: "neural oven", MatchAll: true}))
+
+	// Field restriction and boosts
+	hits = ix.Search(&Query{Text: "fishing", Fields: map[string]float64{"text": 1}})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "net", hits[0].ID)
+	assert.Empty(t, ix.Search(&Query{Text: "bread", Fields: map[string]float64{"author": 1}}))
+
+	// Filters and limits
+	hits = ix.Search(&Query{Text: "networks", Filter: func(id string) bool { return id != "nn" }})
+	require.Len(t, hits, 1)
+	assert.Equal(t, "net", hits[0].ID)
+	assert.Len(t, ix.Search(&Query{Text: "networks", Limit: 1}), 1)
+
+	// Stopword-only queries match nothing
+	assert.Empty(t, ix.Search(&Query{Text: "the of and"}))
+
+	// Replacing and removing documents updates postings
+	ix.Add("bake", map[string]string{"title": "Neural bread"})
+	hits = ix.Search(&Query{Text: "bread"})
+	require.Len(t, hits, 1)
+	assert.Empty(t, ix.Search(&Query{Text: "oven"}))
+
+	ix.Remove("nn")
+	ix.Remove("missing")
+	assert.False(t, ix.Contains("nn"))
+	hits = ix.Search(&Query{Text: "neural"})
+	require.Len(t, hits, 2)
+	assert.Equal(t, "bake", hits[0].ID, "Title match should outrank body match")
+}
@@ -0,0 +1,343 @@
+package fulltext
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Config tunes BM25 scoring
+type Config struct {
+	// K1 controls how quickly repeated terms stop adding to the score
+	K1 float64 `json:"k1"`
+
+	// B controls how strongly scores are normalized by field length
+	B float64 `json:"b"`
+
+	// FieldBoosts weights each field's contribution to the score when a
+	// query does not give its own boosts. Fields not listed weigh 1.
+	FieldBoosts map[string]float64 `json:"field_boosts,omitempty"`
+}
+
+// DefaultConfig returns the standard BM25 parameters with titles weighted
+// above body text
+func DefaultConfig() *Config {
+	return &Config{
+		K1:          1.2,
+		B:           0.75,
+		FieldBoosts: map[string]float64{"title": 2.0},
+	}
+}
+
+// Query is a full-text search request
+type Query struct {
+	// Text holds the search terms. Words in double quotes form a phrase
+	// that must appear in order.
+	Text string
+
+	// Fields restricts the search to the listed fields with the given
+	// boosts. When empty every field is searched with the configured boosts.
+	Fields map[string]float64
+
+	// MatchAll requires every term and phrase to match; otherwise a
+	// document matching any of them is returned
+	MatchAll bool
+
+	// Limit caps the number of hits; zero returns all of them
+	Limit int
+
+	// Filter, when set, drops hits for which it returns false
+	Filter func(id string) bool
+}
+
+// Hit is a document matching a query, scored by BM25
+type Hit struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Index is an in-memory positional inverted index over named text fields.
+// It is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	config Config
+	fields map[string]*fieldIndex
+
+	// docs records the distinct terms of each document per field so the
+	// document can be removed without scanning every posting list
+	docs map[string]map[string][]string
+}
+
+// fieldIndex holds the postings of one field
+type fieldIndex struct {
+	// postings maps term to document ID to the term's positions
+	postings    map[string]map[string][]int
+	lengths     map[string]int
+	totalLength int
+}
+
+// clause is one term or phrase of a parsed query. A term is a phrase of
+// one token.
+type clause struct {
+	tokens []Token
+}
+
+// NewIndex creates an empty index
+func NewIndex(config *Config) *Index {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	return &Index{
+		config: *config,
+		fields: make(map[string]*fieldIndex),
+		docs:   make(map[string]map[string][]string),
+	}
+}
+
+// Add indexes a document's fields, replacing any earlier version of it
+func (ix *Index) Add(id string, fields map[string]string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	terms := make(map[string][]string)
+	for name, text := range fields {
+		tokens := Analyze(text)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		field := ix.fields[name]
+		if field == nil {
+			field = &fieldIndex{
+				postings: make(map[string]map[string][]int),
+				lengths:  make(map[string]int),
+			}
+			ix.fields[name] = field
+		}
+
+		for _, token := range tokens {
+			postings := field.postings[token.Term]
+			if postings == nil {
+				postings = make(map[string][]int)
+				field.postings[token.Term] = postings
+			}
+			if _, seen := postings[id]; !seen {
+				terms[name] = append(terms[name], token.Term)
+			}
+			postings[id] = append(postings[id], token.Position)
+		}
+		field.lengths[id] = len(tokens)
+		field.totalLength += len(tokens)
+	}
+
+	ix.docs[id] = terms
+}
+
+// Remove drops a document; unknown IDs are ignored
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	terms, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+
+	for name, fieldTerms := range terms {
+		field := ix.fields[name]
+		for _, term := range fieldTerms {
+			delete(field.postings[term], id)
+			if len(field.postings[term]) == 0 {
+				delete(field.postings, term)
+			}
+		}
+		field.totalLength -= field.lengths[id]
+		delete(field.lengths, id)
+	}
+	delete(ix.docs, id)
+}
+
+// Contains reports whether a document is indexed
+func (ix *Index) Contains(id string) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	_, ok := ix.docs[id]
+	return ok
+}
+
+// Size returns the number of indexed documents
+func (ix *Index) Size() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.docs)
+}
+
+// Search returns the documents matching query, best first. Ties are broken
+// by document ID so results are stable.
+func (ix *Index) Search(query *Query) []Hit {
+	clauses := parseClauses(query.Text)
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	boosts := ix.boosts(query.Fields)
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+
+	for _, c := range clauses {
+		hit := make(map[string]bool)
+		for name, boost := range boosts {
+			field := ix.fields[name]
+			if field == nil || boost == 0 {
+				continue
+			}
+
+			frequencies := field.match(c)
+			if len(frequencies) == 0 {
+				continue
+			}
+
+			idf := ix.idf(len(frequencies))
+			avgLength := float64(field.totalLength) / float64(len(field.lengths))
+			for id, tf := range frequencies {
+				norm := 1 - ix.config.B + ix.config.B*float64(field.lengths[id])/avgLength
+				scores[id] += boost * idf * tf * (ix.config.K1 + 1) / (tf + ix.config.K1*norm)
+				hit[id] = true
+			}
+		}
+		for id := range hit {
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if query.MatchAll && matched[id] < len(clauses) {
+			continue
+		}
+		if query.Filter != nil && !query.Filter(id) {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits
+}
+
+// boosts returns the fields to search with their weights
+func (ix *Index) boosts(requested map[string]float64) map[string]float64 {
+	if len(requested) > 0 {
+		return requested
+	}
+
+	boosts := make(map[string]float64, len(ix.fields))
+	for name := range ix.fields {
+		boost, ok := ix.config.FieldBoosts[name]
+		if !ok {
+			boost = 1
+		}
+		boosts[name] = boost
+	}
+	return boosts
+}
+
+// idf is the BM25 inverse document frequency for a clause matching df
+// documents
+func (ix *Index) idf(df int) float64 {
+	n := float64(len(ix.docs))
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// match returns how often the clause occurs in each document of the field
+func (f *fieldIndex) match(c clause) map[string]float64 {
+	first := f.postings[c.tokens[0].Term]
+	if len(c.tokens) == 1 {
+		frequencies := make(map[string]float64, len(first))
+		for id, positions := range first {
+			frequencies[id] = float64(len(positions))
+		}
+		return frequencies
+	}
+
+	frequencies := make(map[string]float64)
+	for id, positions := range first {
+		count := 0
+		for _, start := range positions {
+			if f.phraseAt(c, id, start) {
+				count++
+			}
+		}
+		if count > 0 {
+			frequencies[id] = float64(count)
+		}
+	}
+	return frequencies
+}
+
+// phraseAt reports whether the clause's tokens follow one another from
+// position start in the document
+func (f *fieldIndex) phraseAt(c clause, id string, start int) bool {
+	for _, token := range c.tokens[1:] {
+		positions := f.postings[token.Term][id]
+		want := start + token.Position - c.tokens[0].Position
+		i := sort.SearchInts(positions, want)
+		if i == len(positions) || positions[i] != want {
+			return false
+		}
+	}
+	return true
+}
+
+// parseClauses splits query text into phrases, given in double quotes, and
+// single terms. Phrases made entirely of stopwords are dropped.
+func parseClauses(text string) []clause {
+	var clauses []clause
+	seen := make(map[string]bool)
+	add := func(tokens []Token) {
+		if len(tokens) == 0 {
+			return
+		}
+		key := ""
+		for _, token := range tokens {
+			key += token.Term + " "
+		}
+		if !seen[key] {
+			seen[key] = true
+			clauses = append(clauses, clause{tokens: tokens})
+		}
+	}
+
+	parts := strings.Split(text, `"`)
+	for i, part := range parts {
+		if i%2 == 1 {
+			add(Analyze(part))
+			continue
+		}
+		for _, token := range Analyze(part) {
+			add([]Token{token})
+		}
+	}
+	return clauses
+}
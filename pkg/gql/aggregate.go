@@ -0,0 +1,326 @@
+package gql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AggregateFunc names an aggregate function in a SELECT list
+type AggregateFunc string
+
+const (
+	AggCount AggregateFunc = "count"
+)
+
+// Aggregate is an aggregate in a SELECT list. An empty Field stands for *.
+type Aggregate struct {
+	Func  AggregateFunc
+	Field string
+}
+
+// Name returns the column the aggregate is reported under: "count" for
+// COUNT(*), and e.g. "count(doi)" for COUNT(doi)
+func (a Aggregate) Name() string {
+	if a.Field == "" {
+		return string(a.Func)
+	}
+	return string(a.Func) + "(" + a.Field + ")"
+}
+
+// String renders the aggregate as GQL
+func (a Aggregate) String() string {
+	field := a.Field
+	if field == "" {
+		field = "*"
+	}
+	return strings.ToUpper(string(a.Func)) + "(" + field + ")"
+}
+
+// TimeBucket is the calendar period a time field is grouped by
+type TimeBucket string
+
+const (
+	BucketDay   TimeBucket = "day"
+	BucketWeek  TimeBucket = "week"
+	BucketMonth TimeBucket = "month"
+	BucketYear  TimeBucket = "year"
+)
+
+// timeBuckets maps the bucketing functions accepted in GROUP BY
+var timeBuckets = map[string]TimeBucket{
+	"DAY":   BucketDay,
+	"WEEK":  BucketWeek,
+	"MONTH": BucketMonth,
+	"YEAR":  BucketYear,
+}
+
+// start returns the beginning of the bucket holding t, in UTC. Weeks start
+// on Monday.
+func (b TimeBucket) start(t time.Time) time.Time {
+	t = t.UTC()
+	switch b {
+	case BucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// next returns the start of the bucket after the one starting at start
+func (b TimeBucket) next(start time.Time) time.Time {
+	switch b {
+	case BucketDay:
+		return start.AddDate(0, 0, 1)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(1, 0, 0)
+	}
+}
+
+// label formats the bucket starting at start. Labels sort in time order.
+func (b TimeBucket) label(start time.Time) string {
+	switch b {
+	case BucketDay:
+		return start.Format("2006-01-02")
+	case BucketWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case BucketMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format("2006")
+	}
+}
+
+// GroupKey is a GROUP BY term: a field, or a time field bucketed by period
+type GroupKey struct {
+	Field  string
+	Bucket TimeBucket
+}
+
+// Name returns the column the key is reported under: the field, or e.g.
+// "month(created_at)" for a bucketed field
+func (k GroupKey) Name() string {
+	if k.Bucket == "" {
+		return k.Field
+	}
+	return string(k.Bucket) + "(" + k.Field + ")"
+}
+
+// String renders the key as GQL
+func (k GroupKey) String() string {
+	if k.Bucket == "" {
+		return k.Field
+	}
+	return strings.ToUpper(string(k.Bucket)) + "(" + k.Field + ")"
+}
+
+// maxHistogramBuckets bounds the buckets a histogram may span, so one stray
+// timestamp cannot make a query fill hundreds of thousands of empty rows
+const maxHistogramBuckets = 10000
+
+// aggregator counts documents into the groups of an aggregate query
+type aggregator struct {
+	query  *Query
+	groups map[string]*group
+}
+
+type group struct {
+	keys   []interface{}
+	counts []int
+
+	// bucket is the start of the time bucket of a histogram's single key
+	bucket time.Time
+}
+
+func newAggregator(q *Query) *aggregator {
+	return &aggregator{
+		query:  q,
+		groups: make(map[string]*group),
+	}
+}
+
+// add counts a document, reading its fields through lookup. Documents
+// lacking a GROUP BY field are counted under a nil key; those whose
+// bucketed field is not a time are skipped.
+func (a *aggregator) add(lookup func(field string) (interface{}, bool)) {
+	keys := make([]interface{}, len(a.query.GroupBy))
+	var bucket time.Time
+	for i, key := range a.query.GroupBy {
+		value, exists := lookup(key.Field)
+		if !exists {
+			continue
+		}
+		if key.Bucket == "" {
+			keys[i] = value
+			continue
+		}
+
+		t, ok := filterTime(value)
+		if !ok {
+			return
+		}
+		bucket = key.Bucket.start(t)
+		keys[i] = key.Bucket.label(bucket)
+	}
+
+	id := fmt.Sprintf("%#v", keys)
+	g, ok := a.groups[id]
+	if !ok {
+		g = &group{keys: keys, counts: make([]int, len(a.query.Aggregates)), bucket: bucket}
+		a.groups[id] = g
+	}
+
+	for i, agg := range a.query.Aggregates {
+		if agg.Field != "" {
+			if value, exists := lookup(agg.Field); !exists || value == "" {
+				continue
+			}
+		}
+		g.counts[i]++
+	}
+}
+
+// results returns one row per group, keyed by column name, ordered and
+// limited as the query asks. A histogram over a single time bucket also
+// gets zero rows for the empty buckets between its first and last, and is
+// rejected when those span more than maxHistogramBuckets.
+func (a *aggregator) results() ([]interface{}, error) {
+	q := a.query
+	if a.isHistogram() {
+		if err := a.fillBuckets(); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]map[string]interface{}, 0, len(a.groups))
+	for _, g := range a.groups {
+		row := make(map[string]interface{}, len(g.keys)+len(g.counts))
+		for i, key := range q.GroupBy {
+			row[key.Name()] = g.keys[i]
+		}
+		for i, agg := range q.Aggregates {
+			row[agg.Name()] = g.counts[i]
+		}
+		rows = append(rows, row)
+	}
+
+	// Histograms read in time order, other groupings largest first
+	orderBy, descending := q.OrderBy, q.Descending
+	if orderBy == "" {
+		if len(q.GroupBy) > 0 && q.GroupBy[0].Bucket != "" {
+			orderBy = q.GroupBy[0].Name()
+		} else {
+			orderBy, descending = q.Aggregates[0].Name(), true
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if c := compareValues(rows[i][orderBy], rows[j][orderBy]); c != 0 {
+			return (c < 0) != descending
+		}
+		return compareKeys(rows[i], rows[j], q.GroupBy) < 0
+	})
+
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+	}
+
+	results := make([]interface{}, len(rows))
+	for i, row := range rows {
+		results[i] = row
+	}
+	return results, nil
+}
+
+func (a *aggregator) isHistogram() bool {
+	return len(a.query.GroupBy) == 1 && a.query.GroupBy[0].Bucket != ""
+}
+
+// fillBuckets adds empty groups for the buckets missing from a histogram.
+// It fails without adding any when the histogram spans too many buckets.
+func (a *aggregator) fillBuckets() error {
+	bucket := a.query.GroupBy[0].Bucket
+
+	var first, last time.Time
+	for _, g := range a.groups {
+		if g.keys[0] == nil {
+			continue
+		}
+		if first.IsZero() || g.bucket.Before(first) {
+			first = g.bucket
+		}
+		if g.bucket.After(last) {
+			last = g.bucket
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+
+	span := 0
+	for start := first; !start.After(last); start = bucket.next(start) {
+		if span++; span > maxHistogramBuckets {
+			return fmt.Errorf("histogram spans more than %d %s buckets from %s to %s; narrow the time range or group by a longer period",
+				maxHistogramBuckets, bucket, bucket.label(first), bucket.label(last))
+		}
+	}
+
+	for start := first; !start.After(last); start = bucket.next(start) {
+		keys := []interface{}{bucket.label(start)}
+		id := fmt.Sprintf("%#v", keys)
+		if _, ok := a.groups[id]; !ok {
+			a.groups[id] = &group{keys: keys, counts: make([]int, len(a.query.Aggregates)), bucket: start}
+		}
+	}
+	return nil
+}
+
+// compareKeys orders rows by their group keys in GROUP BY order
+func compareKeys(a, b map[string]interface{}, keys []GroupKey) int {
+	for _, key := range keys {
+		if c := compareValues(a[key.Name()], b[key.Name()]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders two column values, with missing values first
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if n1, ok1 := filterNumber(a); ok1 {
+		if n2, ok2 := filterNumber(b); ok2 {
+			switch {
+			case n1 < n2:
+				return -1
+			case n1 > n2:
+				return 1
+			}
+			return 0
+		}
+	}
+	if t1, ok1 := a.(time.Time); ok1 {
+		if t2, ok2 := b.(time.Time); ok2 {
+			return t1.Compare(t2)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
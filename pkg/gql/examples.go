@@ -32,6 +32,11 @@ const (
 	ExampleTopAuthors = `SELECT FROM authors ORDER BY count DESC LIMIT 20`
 	
 	ExampleProlificAuthors = `SELECT FROM authors WHERE count > 5`
+
+	// Aggregate queries
+	ExampleCorpusComposition = `SELECT COUNT(*) FROM documents GROUP BY source_type, metadata.category`
+	
+	ExampleMonthlyHistogram = `SELECT COUNT(*) FROM documents WHERE created_at > "2024-01-01" GROUP BY MONTH(created_at)`
 )
 
 // QueryExamples provides example queries with descriptions
@@ -75,6 +80,16 @@ var QueryExamples = []struct {
 		Query:       ExampleTopAuthors,
 		Description: "Find the most published authors in the library",
 	},
+	{
+		Name:        "Corpus Composition",
+		Query:       ExampleCorpusComposition,
+		Description: "Count documents per source type and category",
+	},
+	{
+		Name:        "Monthly Histogram",
+		Query:       ExampleMonthlyHistogram,
+		Description: "Count documents collected per month since January 2024",
+	},
 }
 
 // QueryBuilder helps construct GQL queries programmatically
@@ -128,21 +143,7 @@ func (qb *QueryBuilder) Build() string {
 			if i > 0 {
 				query += " AND "
 			}
-			query += filter.Field + " " + string(filter.Operator) + " "
-			
-			// Format value
-			switch v := filter.Value.(type) {
-			case string:
-				query += `"` + v + `"`
-			case bool:
-				if v {
-					query += "true"
-				} else {
-					query += "false"
-				}
-			default:
-				query += fmt.Sprintf("%v", v)
-			}
+			query += filter.String()
 		}
 	}
 
@@ -67,6 +67,7 @@ type DocumentResult struct {
 	UpdatedAt  time.Time         `json:"updated_at"`
 	Metadata   map[string]string `json:"metadata"`
 	CommitHash string            `json:"commit_hash"`
+	Score      float64           `json:"score,omitempty"`
 }
 
 // AttributionResult represents attribution tracking
@@ -79,37 +80,34 @@ type AttributionResult struct {
 	AttributionText  string    `json:"attribution_text"`
 }
 
-// executeDocumentQuery executes queries on documents
-func (e *Executor) executeDocumentQuery(ctx context.Context, q *Query) (*Result, error) {
-	start := time.Now()
-
+// walkDocuments calls fn with the HEAD commit and the path and parsed
+// metadata.json of every document in it. Returning object.ErrCanceled from
+// fn stops the walk without an error.
+func (e *Executor) walkDocuments(fn func(commit *object.Commit, path string, metadata map[string]interface{}) error) error {
 	// Open repository
 	repo, err := git.PlainOpen(e.repoPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return fmt.Errorf("failed to open repository: %w", err)
 	}
 
 	// Get HEAD reference
 	ref, err := repo.Head()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		return fmt.Errorf("failed to get HEAD: %w", err)
 	}
 
 	// Get commit
 	commit, err := repo.CommitObject(ref.Hash())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get commit: %w", err)
+		return fmt.Errorf("failed to get commit: %w", err)
 	}
 
 	// Get tree
 	tree, err := commit.Tree()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tree: %w", err)
+		return fmt.Errorf("failed to get tree: %w", err)
 	}
 
-	var results []interface{}
-	count := 0
-
 	// Walk through documents directory
 	docsPath := "documents"
 	err = tree.Files().ForEach(func(f *object.File) error {
@@ -133,14 +131,35 @@ func (e *Executor) executeDocumentQuery(ctx context.Context, q *Query) (*Result,
 			return nil // Skip invalid JSON
 		}
 
+		return fn(commit, f.Name, metadata)
+	})
+
+	if err != nil && err != object.ErrCanceled {
+		return fmt.Errorf("failed to walk tree: %w", err)
+	}
+	return nil
+}
+
+// executeDocumentQuery executes queries on documents
+func (e *Executor) executeDocumentQuery(ctx context.Context, q *Query) (*Result, error) {
+	start := time.Now()
+
+	if q.IsAggregate() {
+		return e.executeAggregateQuery(ctx, q)
+	}
+
+	var results []interface{}
+	count := 0
+
+	err := e.walkDocuments(func(commit *object.Commit, path string, metadata map[string]interface{}) error {
 		// Apply filters
-		if !e.matchesFilters(metadata, q.Filters) {
+		if !e.matchesFilters(metadata, q.Where) {
 			return nil
 		}
 
 		// Extract document info
 		docResult := DocumentResult{
-			ID:         e.extractDocIDFromPath(f.Name),
+			ID:         e.extractDocIDFromPath(path),
 			CommitHash: commit.Hash.String(),
 			CreatedAt:  commit.Author.When,
 			UpdatedAt:  commit.Author.When,
@@ -178,9 +197,8 @@ func (e *Executor) executeDocumentQuery(ctx context.Context, q *Query) (*Result,
 
 		return nil
 	})
-
-	if err != nil && err != object.ErrCanceled {
-		return nil, fmt.Errorf("failed to walk tree: %w", err)
+	if err != nil {
+		return nil, err
 	}
 
 	// Sort results if needed
@@ -196,6 +214,42 @@ func (e *Executor) executeDocumentQuery(ctx context.Context, q *Query) (*Result,
 	}, nil
 }
 
+// executeAggregateQuery counts the matching documents per group. Documents
+// without their own timestamps are dated by the HEAD commit.
+func (e *Executor) executeAggregateQuery(ctx context.Context, q *Query) (*Result, error) {
+	start := time.Now()
+
+	agg := newAggregator(q)
+	err := e.walkDocuments(func(commit *object.Commit, path string, metadata map[string]interface{}) error {
+		if !e.matchesFilters(metadata, q.Where) {
+			return nil
+		}
+
+		agg.add(func(field string) (interface{}, bool) {
+			value, exists := metadataField(metadata, field)
+			if !exists && (field == "created_at" || field == "updated_at") {
+				return commit.Author.When, true
+			}
+			return value, exists
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := agg.results()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Type:    QueryDocuments,
+		Count:   len(results),
+		Items:   results,
+		Elapsed: time.Since(start),
+	}, nil
+}
+
 // executeAttributionQuery tracks attribution compliance
 func (e *Executor) executeAttributionQuery(ctx context.Context, q *Query) (*Result, error) {
 	start := time.Now()
@@ -262,7 +316,7 @@ func (e *Executor) executeAttributionQuery(ctx context.Context, q *Query) (*Resu
 			"document_count": stats.DocumentCount,
 			"caia_attribution": stats.CAIAAttribution,
 		}
-		if !e.matchesFilters(metadata, q.Filters) {
+		if !e.matchesFilters(metadata, q.Where) {
 			continue
 		}
 
@@ -370,41 +424,68 @@ func (e *Executor) executeAuthorsQuery(ctx context.Context, q *Query) (*Result,
 
 // Helper methods
 
-func (e *Executor) matchesFilters(metadata map[string]interface{}, filters []Filter) bool {
-	for _, filter := range filters {
-		value, exists := metadata[filter.Field]
-		
-		switch filter.Operator {
-		case OpEquals:
-			if !exists || value != filter.Value {
-				return false
-			}
-		case OpNotEquals:
-			if exists && value == filter.Value {
-				return false
-			}
-		case OpContains:
-			if !exists {
-				return false
-			}
-			str, ok1 := value.(string)
-			filterStr, ok2 := filter.Value.(string)
-			if !ok1 || !ok2 || !strings.Contains(str, filterStr) {
-				return false
-			}
-		case OpExists:
-			if !exists {
-				return false
-			}
-		case OpNotExists:
-			if exists {
-				return false
-			}
+func (e *Executor) matchesFilters(metadata map[string]interface{}, where *Expr) bool {
+	return where.Eval(func(leaf *Expr) bool {
+		return e.matchesFilter(metadata, *leaf.Filter)
+	})
+}
+
+func (e *Executor) matchesFilter(metadata map[string]interface{}, filter Filter) bool {
+	value, exists := metadataField(metadata, filter.Field)
+	
+	switch filter.Operator {
+	case OpEquals:
+		if !exists || value != filter.Value {
+			return false
+		}
+	case OpNotEquals:
+		if exists && value == filter.Value {
+			return false
+		}
+	case OpContains:
+		if !exists {
+			return false
+		}
+		str, ok1 := value.(string)
+		filterStr, ok2 := filter.Value.(string)
+		if !ok1 || !ok2 || !strings.Contains(str, filterStr) {
+			return false
+		}
+	case OpGreater, OpLess:
+		return exists && compareOrdered(value, filter)
+	case OpExists:
+		if !exists {
+			return false
+		}
+	case OpNotExists:
+		if exists {
+			return false
 		}
 	}
 	return true
 }
 
+// metadataField looks a GQL field up in a document's metadata.json.
+// "source_type" is the source, and "metadata." fields read the nested
+// content metadata before the top level.
+func metadataField(metadata map[string]interface{}, field string) (interface{}, bool) {
+	if field == "source_type" {
+		field = "source"
+	}
+
+	if name, ok := strings.CutPrefix(field, "metadata."); ok {
+		if nested, ok := metadata["metadata"].(map[string]interface{}); ok {
+			if value, exists := nested[name]; exists {
+				return value, true
+			}
+		}
+		field = name
+	}
+
+	value, exists := metadata[field]
+	return value, exists
+}
+
 func (e *Executor) extractDocIDFromPath(path string) string {
 	// Path format: documents/xx/yy/doc-id/metadata.json
 	dir := filepath.Dir(path)
@@ -0,0 +1,100 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExecutorRepo commits a metadata.json per document in the layout the
+// git executor reads
+func newExecutorRepo(t *testing.T, docs map[string]map[string]interface{}) string {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	for id, metadata := range docs {
+		rel := filepath.Join("documents", id[:2], id[2:4], id, "metadata.json")
+		require.NoError(t, os.MkdirAll(filepath.Join(repoPath, filepath.Dir(rel)), 0755))
+		data, err := json.Marshal(metadata)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, rel), data, 0644))
+		_, err = worktree.Add(rel)
+		require.NoError(t, err)
+	}
+
+	_, err = worktree.Commit("Add documents", &git.CommitOptions{
+		Author: &object.Signature{Name: "GQL Test", Email: "test@caiatech.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return repoPath
+}
+
+func TestExecutor_BooleanExpressions(t *testing.T) {
+	repoPath := newExecutorRepo(t, map[string]map[string]interface{}{
+		"doc-ai":  {"source": "arXiv", "title": "Advances in artificial intelligence", "author": "John Doe", "created_at": "2024-04-01"},
+		"doc-ml":  {"source": "arXiv", "title": "Practical machine learning", "authors": "Jane Roe, John Doe", "created_at": "2024-02-01"},
+		"doc-bio": {"source": "PubMed", "title": "Cell biology", "author": "Ann Lee", "created_at": "2024-05-01"},
+	})
+	executor := NewExecutor(repoPath)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{ExampleAIDocuments, []string{"doc-ai", "doc-ml"}},
+		{ExampleDocumentsByAuthor, []string{"doc-ai", "doc-ml"}},
+		{ExampleRecentDocuments, []string{"doc-ai", "doc-bio"}},
+		{ExampleArxivDocuments, []string{"doc-ai", "doc-ml"}},
+		{`SELECT FROM documents WHERE NOT (source = "arXiv" AND author exists)`, []string{"doc-bio", "doc-ml"}},
+		{`SELECT FROM documents WHERE source = "PubMed" OR (title ~ "machine" AND NOT author = "Ann Lee")`, []string{"doc-bio", "doc-ml"}},
+	}
+	for _, tt := range tests {
+		result, err := executor.Execute(ctx, tt.query)
+		require.NoError(t, err, tt.query)
+
+		var ids []string
+		for _, item := range result.Items {
+			ids = append(ids, item.(DocumentResult).ID)
+		}
+		sort.Strings(ids)
+		assert.Equal(t, tt.want, ids, tt.query)
+	}
+}
+
+func TestExecutor_Aggregates(t *testing.T) {
+	repoPath := newExecutorRepo(t, map[string]map[string]interface{}{
+		"doc-ai":  {"source": "arXiv", "metadata": map[string]interface{}{"category": "cs"}, "created_at": "2024-04-01"},
+		"doc-ml":  {"source": "arXiv", "metadata": map[string]interface{}{"category": "cs"}, "created_at": "2024-02-01"},
+		"doc-bio": {"source": "PubMed", "category": "bio", "created_at": "2024-04-20T10:00:00Z"},
+	})
+	executor := NewExecutor(repoPath)
+	ctx := context.Background()
+
+	result, err := executor.Execute(ctx, ExampleCorpusComposition)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"source_type": "arXiv", "metadata.category": "cs", "count": 2},
+		map[string]interface{}{"source_type": "PubMed", "metadata.category": "bio", "count": 1},
+	}, result.Items)
+
+	result, err = executor.Execute(ctx, ExampleMonthlyHistogram)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"month(created_at)": "2024-02", "count": 1},
+		map[string]interface{}{"month(created_at)": "2024-03", "count": 0},
+		map[string]interface{}{"month(created_at)": "2024-04", "count": 2},
+	}, result.Items)
+}
@@ -0,0 +1,181 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExprKind identifies the node type of a WHERE expression
+type ExprKind string
+
+const (
+	ExprFilter ExprKind = "filter"
+	ExprAnd    ExprKind = "and"
+	ExprOr     ExprKind = "or"
+	ExprNot    ExprKind = "not"
+)
+
+// Expr is a node of a WHERE clause's boolean expression tree. A filter node
+// holds a single comparison; AND and OR nodes combine two or more children
+// and a NOT node negates its single child.
+type Expr struct {
+	Kind     ExprKind
+	Filter   *Filter
+	Children []*Expr
+}
+
+// FilterExpr wraps a filter as an expression leaf
+func FilterExpr(filter Filter) *Expr {
+	return &Expr{Kind: ExprFilter, Filter: &filter}
+}
+
+// And combines expressions that must all hold, flattening nested ANDs. It
+// returns nil for no expressions and the expression itself for one.
+func And(exprs ...*Expr) *Expr {
+	return combine(ExprAnd, exprs)
+}
+
+// Or combines expressions of which at least one must hold, flattening nested
+// ORs
+func Or(exprs ...*Expr) *Expr {
+	return combine(ExprOr, exprs)
+}
+
+// Not negates an expression
+func Not(expr *Expr) *Expr {
+	return &Expr{Kind: ExprNot, Children: []*Expr{expr}}
+}
+
+func combine(kind ExprKind, exprs []*Expr) *Expr {
+	var children []*Expr
+	for _, expr := range exprs {
+		switch {
+		case expr == nil:
+		case expr.Kind == kind:
+			children = append(children, expr.Children...)
+		default:
+			children = append(children, expr)
+		}
+	}
+
+	switch len(children) {
+	case 0:
+		return nil
+	case 1:
+		return children[0]
+	default:
+		return &Expr{Kind: kind, Children: children}
+	}
+}
+
+// Eval evaluates the expression, calling match for each filter it needs.
+// AND and OR short-circuit. A nil expression matches everything.
+func (e *Expr) Eval(match func(filter *Expr) bool) bool {
+	if e == nil {
+		return true
+	}
+
+	switch e.Kind {
+	case ExprFilter:
+		return match(e)
+	case ExprAnd:
+		for _, child := range e.Children {
+			if !child.Eval(match) {
+				return false
+			}
+		}
+		return true
+	case ExprOr:
+		for _, child := range e.Children {
+			if child.Eval(match) {
+				return true
+			}
+		}
+		return false
+	case ExprNot:
+		return !e.Children[0].Eval(match)
+	default:
+		return false
+	}
+}
+
+// Conjuncts returns the expressions that must all hold for e to hold: the
+// children of a top-level AND, or e itself
+func (e *Expr) Conjuncts() []*Expr {
+	if e == nil {
+		return nil
+	}
+	if e.Kind == ExprAnd {
+		return e.Children
+	}
+	return []*Expr{e}
+}
+
+// Filters returns every filter in the expression in source order
+func (e *Expr) Filters() []Filter {
+	if e == nil {
+		return nil
+	}
+	if e.Kind == ExprFilter {
+		return []Filter{*e.Filter}
+	}
+
+	var filters []Filter
+	for _, child := range e.Children {
+		filters = append(filters, child.Filters()...)
+	}
+	return filters
+}
+
+// String renders the expression as GQL, parenthesizing every group
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+
+	switch e.Kind {
+	case ExprFilter:
+		return e.Filter.String()
+	case ExprNot:
+		return "NOT " + e.Children[0].groupString()
+	default:
+		parts := make([]string, len(e.Children))
+		for i, child := range e.Children {
+			parts[i] = child.groupString()
+		}
+		return strings.Join(parts, " "+strings.ToUpper(string(e.Kind))+" ")
+	}
+}
+
+func (e *Expr) groupString() string {
+	if e.Kind == ExprAnd || e.Kind == ExprOr {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// String renders the filter as GQL
+func (f Filter) String() string {
+	switch f.Operator {
+	case OpExists:
+		return f.Field + " EXISTS"
+	case OpNotExists:
+		return f.Field + " NOT EXISTS"
+	}
+	return f.Field + " " + string(f.Operator) + " " + formatValue(f.Value)
+}
+
+// formatValue renders a filter value as a GQL literal
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return `"` + v + `"`
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
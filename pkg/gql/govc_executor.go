@@ -9,12 +9,14 @@ import (
 
 	"github.com/Caia-Tech/caia-library/internal/storage"
 	"github.com/Caia-Tech/caia-library/pkg/document"
+	"github.com/Caia-Tech/caia-library/pkg/fulltext"
 )
 
-// GovcExecutor executes GQL queries against the govc backend
-// This is optimized to use the document index for O(1) lookups
+// GovcExecutor executes GQL queries against a storage backend
+// This is optimized to use the govc document index for O(1) lookups
 type GovcExecutor struct {
 	backend storage.StorageBackend
+	text    *storage.TextSearcher
 }
 
 // NewGovcExecutor creates a new query executor using govc backend
@@ -24,6 +26,13 @@ func NewGovcExecutor(backend storage.StorageBackend) *GovcExecutor {
 	}
 }
 
+// SetTextSearcher makes ~ filters on title, text and author use the
+// full-text index: they then match documents containing the filter's words
+// as a phrase, and results without ORDER BY are ranked by BM25 score
+func (e *GovcExecutor) SetTextSearcher(searcher *storage.TextSearcher) {
+	e.text = searcher
+}
+
 // Execute runs a GQL query using the govc backend for optimized performance
 func (e *GovcExecutor) Execute(ctx context.Context, query string) (*Result, error) {
 	// Parse the query
@@ -52,29 +61,31 @@ func (e *GovcExecutor) Execute(ctx context.Context, query string) (*Result, erro
 func (e *GovcExecutor) executeDocumentQuery(ctx context.Context, q *Query) (*Result, error) {
 	start := time.Now()
 
-	// Get govc backend to access document index
-	govcBackend, ok := e.backend.(*storage.GovcBackend)
-	if !ok {
-		// Fallback to slower method if not using govc
-		return e.executeDocumentQuerySlow(ctx, q)
+	// Resolve full-text filters through the text index when there is one
+	docIDs, textMatches := e.textCandidates(q.Where)
+	var loaded map[string]*document.Document
+	if docIDs == nil {
+		var err error
+		if docIDs, loaded, err = e.listDocuments(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list documents: %w", err)
+		}
 	}
 
-	// Get all document IDs from the document index (O(1) operation)
-	docIndex := govcBackend.GetDocumentIndex()
-	allDocIDs := docIndex.GetAllDocumentIDs()
+	if q.IsAggregate() {
+		return e.executeAggregateQuery(ctx, q, docIDs, loaded, textMatches, start)
+	}
 
 	var results []interface{}
 	processedCount := 0
 
 	// Process documents in batches for better memory usage
-	for _, docID := range allDocIDs {
+	for _, docID := range docIDs {
 		// Apply limit early to avoid processing unnecessary documents
 		if len(results) >= q.Limit {
 			break
 		}
 
-		// Retrieve document using fast O(1) index lookup
-		doc, err := e.backend.GetDocument(ctx, docID)
+		doc, err := e.getDocument(ctx, docID, loaded)
 		if err != nil {
 			continue // Skip documents we can't retrieve
 		}
@@ -82,7 +93,7 @@ func (e *GovcExecutor) executeDocumentQuery(ctx context.Context, q *Query) (*Res
 		processedCount++
 
 		// Apply filters
-		if !e.matchesDocumentFilters(doc, q.Filters) {
+		if !e.matchesDocumentFilters(doc, q.Where, textMatches) {
 			continue
 		}
 
@@ -94,6 +105,7 @@ func (e *GovcExecutor) executeDocumentQuery(ctx context.Context, q *Query) (*Res
 			CreatedAt: doc.CreatedAt,
 			UpdatedAt: doc.UpdatedAt,
 			Metadata:  doc.Content.Metadata,
+			Score:     textMatches.score(doc.ID),
 		}
 
 		// Extract title from metadata or content
@@ -137,6 +149,35 @@ func (e *GovcExecutor) executeDocumentQuery(ctx context.Context, q *Query) (*Res
 	}, nil
 }
 
+// executeAggregateQuery counts the matching documents among docIDs per
+// group
+func (e *GovcExecutor) executeAggregateQuery(ctx context.Context, q *Query, docIDs []string, loaded map[string]*document.Document, text textMatches, start time.Time) (*Result, error) {
+	agg := newAggregator(q)
+	for _, docID := range docIDs {
+		doc, err := e.getDocument(ctx, docID, loaded)
+		if err != nil {
+			continue // Skip documents we can't retrieve
+		}
+		if !e.matchesDocumentFilters(doc, q.Where, text) {
+			continue
+		}
+		agg.add(func(field string) (interface{}, bool) {
+			return documentField(doc, field)
+		})
+	}
+
+	results, err := agg.results()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Type:    QueryDocuments,
+		Count:   len(results),
+		Items:   results,
+		Elapsed: time.Since(start),
+	}, nil
+}
+
 // executeAttributionQuery tracks attribution compliance
 func (e *GovcExecutor) executeAttributionQuery(ctx context.Context, q *Query) (*Result, error) {
 	start := time.Now()
@@ -201,7 +242,7 @@ func (e *GovcExecutor) executeAttributionQuery(ctx context.Context, q *Query) (*
 			"caia_attribution": stats.CAIAAttribution,
 		}
 
-		if !e.matchesFilters(metadata, q.Filters) {
+		if !e.matchesFilters(metadata, q.Where) {
 			continue
 		}
 
@@ -254,7 +295,7 @@ func (e *GovcExecutor) executeSourcesQuery(ctx context.Context, q *Query) (*Resu
 			"count":  count,
 		}
 
-		if !e.matchesFilters(sourceResult, q.Filters) {
+		if !e.matchesFilters(sourceResult, q.Where) {
 			continue
 		}
 
@@ -325,7 +366,7 @@ func (e *GovcExecutor) executeAuthorsQuery(ctx context.Context, q *Query) (*Resu
 			"count":  count,
 		}
 
-		if !e.matchesFilters(authorResult, q.Filters) {
+		if !e.matchesFilters(authorResult, q.Where) {
 			continue
 		}
 
@@ -352,68 +393,198 @@ func (e *GovcExecutor) executeAuthorsQuery(ctx context.Context, q *Query) (*Resu
 	}, nil
 }
 
-// executeDocumentQuerySlow - fallback for non-govc backends
-func (e *GovcExecutor) executeDocumentQuerySlow(ctx context.Context, q *Query) (*Result, error) {
-	// This would implement the slower approach for non-govc backends
-	// For now, return error encouraging govc usage
-	return nil, fmt.Errorf("optimized queries require govc backend - consider using NewGovcBackend")
+// listDocuments returns the IDs of every stored document. The govc index
+// and backends that list metadata give IDs without loading documents; other
+// backends are listed in full and the loaded documents returned by ID.
+func (e *GovcExecutor) listDocuments(ctx context.Context) ([]string, map[string]*document.Document, error) {
+	switch backend := e.backend.(type) {
+	case *storage.GovcBackend:
+		// Get all document IDs from the document index (O(1) operation)
+		return backend.GetDocumentIndex().GetAllDocumentIDs(), nil, nil
+	case storage.MetadataLister:
+		entries, err := backend.ListMetadata(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			ids[i] = entry.ID
+		}
+		return ids, nil, nil
+	}
+
+	docs, err := e.backend.ListDocuments(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, len(docs))
+	loaded := make(map[string]*document.Document, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID
+		loaded[doc.ID] = doc
+	}
+	return ids, loaded, nil
+}
+
+// getDocument returns a document loaded while listing, or reads it from
+// the backend
+func (e *GovcExecutor) getDocument(ctx context.Context, id string, loaded map[string]*document.Document) (*document.Document, error) {
+	if doc, ok := loaded[id]; ok {
+		return doc, nil
+	}
+	return e.backend.GetDocument(ctx, id)
 }
 
 // Helper methods
 
-func (e *GovcExecutor) matchesDocumentFilters(doc *document.Document, filters []Filter) bool {
-	for _, filter := range filters {
-		if !e.matchesDocumentFilter(doc, filter) {
-			return false
+// textMatches holds the documents matching each full-text filter leaf of
+// a WHERE expression, with their BM25 scores
+type textMatches map[*Expr]map[string]float64
+
+// score sums the document's scores over the full-text filters
+func (m textMatches) score(id string) float64 {
+	total := 0.0
+	for _, matches := range m {
+		total += matches[id]
+	}
+	return total
+}
+
+// textCandidates answers the ~ filters on indexed fields from the text
+// index. It returns the IDs of documents that can satisfy every full-text
+// filter required at the top level, best first, or nil when no such filter
+// narrows the search, along with the matches for every full-text filter.
+func (e *GovcExecutor) textCandidates(where *Expr) ([]string, textMatches) {
+	if e.text == nil || where == nil {
+		return nil, nil
+	}
+
+	matches := make(textMatches)
+	var visit func(expr *Expr)
+	visit = func(expr *Expr) {
+		if expr.Kind != ExprFilter {
+			for _, child := range expr.Children {
+				visit(child)
+			}
+			return
 		}
+
+		filter := expr.Filter
+		field := textField(filter.Field)
+		value, ok := filter.Value.(string)
+		if filter.Operator != OpContains || field == "" || !ok || len(fulltext.Terms(value)) == 0 {
+			return
+		}
+
+		results := e.text.Search(&fulltext.Query{
+			Text:   `"` + strings.ReplaceAll(value, `"`, " ") + `"`,
+			Fields: map[string]float64{field: 1},
+		}, nil)
+		scores := make(map[string]float64, len(results))
+		for _, result := range results {
+			scores[result.ID] = result.Score
+		}
+		matches[expr] = scores
+	}
+	visit(where)
+
+	// Only filters every result must pass can narrow the candidates
+	var candidates map[string]bool
+	for _, conjunct := range where.Conjuncts() {
+		scores, ok := matches[conjunct]
+		if !ok {
+			continue
+		}
+		next := make(map[string]bool, len(scores))
+		for id := range scores {
+			if candidates == nil || candidates[id] {
+				next[id] = true
+			}
+		}
+		candidates = next
+	}
+	if candidates == nil {
+		return nil, matches
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		si, sj := matches.score(ids[i]), matches.score(ids[j])
+		if si != sj {
+			return si > sj
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, matches
+}
+
+// textField maps a GQL field to the text index field holding it
+func textField(field string) string {
+	switch field {
+	case "title":
+		return storage.TextFieldTitle
+	case "text", "content":
+		return storage.TextFieldText
+	case "author", "authors":
+		return storage.TextFieldAuthor
+	default:
+		return ""
 	}
-	return true
+}
+
+func (e *GovcExecutor) matchesDocumentFilters(doc *document.Document, where *Expr, text textMatches) bool {
+	return where.Eval(func(leaf *Expr) bool {
+		// Full-text filters were answered by the text index
+		if matches, ok := text[leaf]; ok {
+			_, found := matches[doc.ID]
+			return found
+		}
+		return e.matchesDocumentFilter(doc, *leaf.Filter)
+	})
 }
 
 func (e *GovcExecutor) matchesDocumentFilter(doc *document.Document, filter Filter) bool {
-	var value interface{}
-	var exists bool
+	value, exists := documentField(doc, filter.Field)
+	return e.matchesFilterValue(value, exists, filter)
+}
 
-	// Map filter field to document field
-	switch filter.Field {
+// documentField maps a GQL field to the document's value for it. Fields
+// prefixed with "metadata." always read the metadata, and other unknown
+// fields fall back to it.
+func documentField(doc *document.Document, field string) (interface{}, bool) {
+	switch field {
 	case "id":
-		value = doc.ID
-		exists = true
-	case "source":
-		value = doc.Source.Type
-		exists = doc.Source.Type != ""
+		return doc.ID, true
+	case "source", "source_type":
+		return doc.Source.Type, doc.Source.Type != ""
 	case "url":
-		value = doc.Source.URL
-		exists = doc.Source.URL != ""
+		return doc.Source.URL, doc.Source.URL != ""
 	case "created_at":
-		value = doc.CreatedAt
-		exists = true
+		return doc.CreatedAt, true
 	case "updated_at":
-		value = doc.UpdatedAt
-		exists = true
-	case "title":
-		value, exists = doc.Content.Metadata["title"]
+		return doc.UpdatedAt, true
+	case "text", "content":
+		return doc.Content.Text, doc.Content.Text != ""
 	case "author":
-		value, exists = doc.Content.Metadata["author"]
-		if !exists {
-			value, exists = doc.Content.Metadata["authors"]
+		if value, exists := doc.Content.Metadata["author"]; exists {
+			return value, true
 		}
-	default:
-		// Check in metadata
-		value, exists = doc.Content.Metadata[filter.Field]
+		value, exists := doc.Content.Metadata["authors"]
+		return value, exists
 	}
 
-	return e.matchesFilterValue(value, exists, filter)
+	value, exists := doc.Content.Metadata[strings.TrimPrefix(field, "metadata.")]
+	return value, exists
 }
 
-func (e *GovcExecutor) matchesFilters(metadata map[string]interface{}, filters []Filter) bool {
-	for _, filter := range filters {
-		value, exists := metadata[filter.Field]
-		if !e.matchesFilterValue(value, exists, filter) {
-			return false
-		}
-	}
-	return true
+func (e *GovcExecutor) matchesFilters(metadata map[string]interface{}, where *Expr) bool {
+	return where.Eval(func(leaf *Expr) bool {
+		value, exists := metadata[leaf.Filter.Field]
+		return e.matchesFilterValue(value, exists, *leaf.Filter)
+	})
 }
 
 func (e *GovcExecutor) matchesFilterValue(value interface{}, exists bool, filter Filter) bool {
@@ -435,50 +606,70 @@ func (e *GovcExecutor) matchesFilterValue(value interface{}, exists bool, filter
 		if !ok1 || !ok2 || !strings.Contains(strings.ToLower(str), strings.ToLower(filterStr)) {
 			return false
 		}
-	case OpGreater:
+	case OpGreater, OpLess:
+		return exists && compareOrdered(value, filter)
+	case OpExists:
 		if !exists {
 			return false
 		}
-		// Handle time comparison
-		if t1, ok1 := value.(time.Time); ok1 {
-			if t2, ok2 := filter.Value.(time.Time); ok2 {
+	case OpNotExists:
+		if exists {
+			return false
+		}
+	}
+	return true
+}
+
+// compareOrdered applies a > or < filter to a time or numeric value
+func compareOrdered(value interface{}, filter Filter) bool {
+	// Handle time comparison
+	if t1, ok1 := filterTime(value); ok1 {
+		if t2, ok2 := filterTime(filter.Value); ok2 {
+			if filter.Operator == OpGreater {
 				return t1.After(t2)
 			}
+			return t1.Before(t2)
 		}
-		// Handle numeric comparison
-		if n1, ok1 := value.(float64); ok1 {
-			if n2, ok2 := filter.Value.(float64); ok2 {
+	}
+	// Handle numeric comparison
+	if n1, ok1 := filterNumber(value); ok1 {
+		if n2, ok2 := filterNumber(filter.Value); ok2 {
+			if filter.Operator == OpGreater {
 				return n1 > n2
 			}
+			return n1 < n2
 		}
-		return false
-	case OpLess:
-		if !exists {
-			return false
-		}
-		// Handle time comparison
-		if t1, ok1 := value.(time.Time); ok1 {
-			if t2, ok2 := filter.Value.(time.Time); ok2 {
-				return t1.Before(t2)
-			}
-		}
-		// Handle numeric comparison
-		if n1, ok1 := value.(float64); ok1 {
-			if n2, ok2 := filter.Value.(float64); ok2 {
-				return n1 < n2
+	}
+	return false
+}
+
+// filterTime reads a filter value as a time, accepting dates written as
+// strings such as "2024-03-01"
+func filterTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range []string{"2006-01-02", time.RFC3339} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
 			}
 		}
-		return false
-	case OpExists:
-		if !exists {
-			return false
-		}
-	case OpNotExists:
-		if exists {
-			return false
-		}
 	}
-	return true
+	return time.Time{}, false
+}
+
+// filterNumber reads a filter or field value as a number
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
 }
 
 func (e *GovcExecutor) hasCAIAAttribution(doc DocumentResult) bool {
@@ -3,6 +3,7 @@ package gql
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -475,4 +476,206 @@ func TestGovcExecutor_InvalidQuery(t *testing.T) {
 			assert.Nil(t, result)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGovcExecutor_TextIndex(t *testing.T) {
+	backend, err := storage.NewGovcBackend("test-gql-text", storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	texts := map[string]string{
+		"text-001": "Convolutional networks dominate image classification benchmarks.",
+		"text-002": "Image classification with neural networks, and image classification at scale.",
+		"text-003": "A survey of network protocols for classification of traffic.",
+	}
+	for id, text := range texts {
+		_, err := backend.StoreDocument(ctx, &document.Document{
+			ID:        id,
+			Source:    document.Source{Type: "arXiv", URL: "https://arxiv.org/abs/" + id},
+			Content:   document.Content{Text: text, Metadata: map[string]string{"title": "Paper " + id}},
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	searcher := storage.NewTextSearcher(backend, nil)
+	_, err = searcher.Load(ctx)
+	require.NoError(t, err)
+
+	executor := NewGovcExecutor(backend)
+	executor.SetTextSearcher(searcher)
+
+	// Stemmed phrase match, ranked by BM25
+	result, err := executor.Execute(ctx, `SELECT FROM documents WHERE text ~ "image classification" AND source = "arXiv"`)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Count)
+	first := result.Items[0].(DocumentResult)
+	second := result.Items[1].(DocumentResult)
+	assert.Equal(t, "text-002", first.ID)
+	assert.Equal(t, "text-001", second.ID)
+	assert.Greater(t, first.Score, second.Score)
+
+	// Several full-text filters intersect
+	result, err = executor.Execute(ctx, `SELECT FROM documents WHERE content ~ "networks" AND text ~ "convolutional"`)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Count)
+	assert.Equal(t, "text-001", result.Items[0].(DocumentResult).ID)
+
+	// Without the index, ~ on text falls back to substring matching
+	result, err = NewGovcExecutor(backend).Execute(ctx, `SELECT FROM documents WHERE text ~ "network protocols"`)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Count)
+	assert.Equal(t, "text-003", result.Items[0].(DocumentResult).ID)
+}
+
+func TestGovcExecutor_BooleanExpressions(t *testing.T) {
+	backend, err := storage.NewGovcBackend("test-gql-bool", storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	docs := []struct {
+		id, source, title, authorKey, author string
+		created                               time.Time
+	}{
+		{"bool-ai", "arXiv", "Artificial Intelligence Today", "author", "John Doe", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"bool-ml", "arXiv", "Machine Learning at Scale", "authors", "Jane Roe, John Doe", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"bool-bio", "PubMed", "Cell Biology", "author", "Ann Lee", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, d := range docs {
+		_, err := backend.StoreDocument(ctx, &document.Document{
+			ID:     d.id,
+			Source: document.Source{Type: d.source, URL: "https://example.com/" + d.id},
+			Content: document.Content{
+				Text:     d.title + " in depth.",
+				Metadata: map[string]string{"title": d.title, d.authorKey: d.author},
+			},
+			CreatedAt: d.created,
+			UpdatedAt: d.created,
+		})
+		require.NoError(t, err)
+	}
+
+	ids := func(result *Result) []string {
+		var ids []string
+		for _, item := range result.Items {
+			ids = append(ids, item.(DocumentResult).ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	searcher := storage.NewTextSearcher(backend, nil)
+	_, err = searcher.Load(ctx)
+	require.NoError(t, err)
+	indexed := NewGovcExecutor(backend)
+	indexed.SetTextSearcher(searcher)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{ExampleAIDocuments, []string{"bool-ai", "bool-ml"}},
+		{ExampleDocumentsByAuthor, []string{"bool-ai", "bool-ml"}},
+		{ExampleRecentDocuments, []string{"bool-ai", "bool-bio"}},
+		{`SELECT FROM documents WHERE NOT source = "arXiv"`, []string{"bool-bio"}},
+		{`SELECT FROM documents WHERE (source = "PubMed" OR title ~ "machine") AND NOT author = "Ann Lee"`, []string{"bool-ml"}},
+		{`SELECT FROM documents WHERE authors EXISTS OR NOT (created_at > "2024-03-01")`, []string{"bool-ml"}},
+	}
+	for _, executor := range map[string]*GovcExecutor{"scan": NewGovcExecutor(backend), "indexed": indexed} {
+		for _, tt := range tests {
+			result, err := executor.Execute(ctx, tt.query)
+			require.NoError(t, err, tt.query)
+			assert.Equal(t, tt.want, ids(result), tt.query)
+		}
+	}
+
+	result, err := NewGovcExecutor(backend).Execute(ctx, `SELECT FROM sources WHERE count > 1`)
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "arXiv", result.Items[0].(map[string]interface{})["source"])
+}
+
+func TestGovcExecutor_Aggregates(t *testing.T) {
+	backend, err := storage.NewGovcBackend("test-gql-agg", storage.NewSimpleMetricsCollector())
+	require.NoError(t, err)
+	defer backend.Close()
+
+	ctx := context.Background()
+	docs := []struct {
+		id, source, category string
+		created              time.Time
+	}{
+		{"agg-1", "arxiv", "cs", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"agg-2", "arxiv", "cs", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"agg-3", "arxiv", "bio", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"agg-4", "pubmed", "bio", time.Date(2024, 4, 9, 0, 0, 0, 0, time.UTC)},
+		{"agg-5", "web", "", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, d := range docs {
+		metadata := map[string]string{"title": d.id}
+		if d.category != "" {
+			metadata["category"] = d.category
+		}
+		_, err := backend.StoreDocument(ctx, &document.Document{
+			ID:        d.id,
+			Source:    document.Source{Type: d.source, URL: "https://example.com/" + d.id},
+			Content:   document.Content{Text: "Aggregate test document.", Metadata: metadata},
+			CreatedAt: d.created,
+			UpdatedAt: d.created,
+		})
+		require.NoError(t, err)
+	}
+
+	executor := NewGovcExecutor(backend)
+
+	result, err := executor.Execute(ctx, ExampleCorpusComposition)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"source_type": "arxiv", "metadata.category": "cs", "count": 2},
+		map[string]interface{}{"source_type": "arxiv", "metadata.category": "bio", "count": 1},
+		map[string]interface{}{"source_type": "pubmed", "metadata.category": "bio", "count": 1},
+		map[string]interface{}{"source_type": "web", "metadata.category": nil, "count": 1},
+	}, result.Items)
+
+	// Histograms fill empty buckets and read in time order
+	result, err = executor.Execute(ctx, ExampleMonthlyHistogram)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"month(created_at)": "2024-01", "count": 2},
+		map[string]interface{}{"month(created_at)": "2024-02", "count": 0},
+		map[string]interface{}{"month(created_at)": "2024-03", "count": 1},
+		map[string]interface{}{"month(created_at)": "2024-04", "count": 1},
+	}, result.Items)
+
+	result, err = executor.Execute(ctx, `SELECT COUNT(*), COUNT(category) FROM documents WHERE NOT source_type = "pubmed"`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"count": 4, "count(category)": 3},
+	}, result.Items)
+
+	result, err = executor.Execute(ctx, `SELECT COUNT(*) FROM documents WHERE metadata.category = "bio" GROUP BY YEAR(created_at), source ORDER BY source DESC LIMIT 1`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"year(created_at)": "2024", "source": "pubmed", "count": 1},
+	}, result.Items)
+
+	// One stray timestamp must not fill decades of empty days
+	_, err = backend.StoreDocument(ctx, &document.Document{
+		ID:        "agg-epoch",
+		Source:    document.Source{Type: "web", URL: "https://example.com/agg-epoch"},
+		Content:   document.Content{Text: "Aggregate test document."},
+		CreatedAt: time.Unix(0, 0).UTC(),
+		UpdatedAt: time.Unix(0, 0).UTC(),
+	})
+	require.NoError(t, err)
+
+	_, err = executor.Execute(ctx, `SELECT COUNT(*) FROM documents GROUP BY DAY(created_at)`)
+	assert.ErrorContains(t, err, "histogram spans more than")
+
+	result, err = executor.Execute(ctx, `SELECT COUNT(*) FROM documents GROUP BY YEAR(created_at)`)
+	require.NoError(t, err)
+	assert.Len(t, result.Items, 2024-1970+1)
+}
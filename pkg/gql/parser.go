@@ -9,11 +9,21 @@ import (
 // Query represents a parsed GQL query
 type Query struct {
 	Type       QueryType
-	Filters    []Filter
+	Where      *Expr
 	Timeframe  *TimeRange
 	Limit      int
 	OrderBy    string
 	Descending bool
+
+	// Aggregates and GroupBy make a documents query report counts per
+	// group instead of documents
+	Aggregates []Aggregate
+	GroupBy    []GroupKey
+}
+
+// IsAggregate reports whether the query returns groups rather than records
+func (q *Query) IsAggregate() bool {
+	return len(q.Aggregates) > 0
 }
 
 // QueryType defines the type of query
@@ -78,6 +88,7 @@ const (
 	tokenLeftParen
 	tokenRightParen
 	tokenComma
+	tokenStar
 )
 
 // NewParser creates a new GQL parser
@@ -110,6 +121,7 @@ func (p *Parser) tokenize(query string) ([]token, error) {
 		"ORDER": true, "BY": true, "DESC": true, "ASC": true, "LIMIT": true,
 		"BETWEEN": true, "IN": true, "NOT": true, "EXISTS": true,
 		"DOCUMENTS": true, "AUTHORS": true, "SOURCES": true, "ATTRIBUTION": true,
+		"GROUP": true,
 	}
 
 	i := 0
@@ -188,6 +200,8 @@ func (p *Parser) tokenize(query string) ([]token, error) {
 			tokens = append(tokens, token{typ: tokenRightParen, value: ")"})
 		case ',':
 			tokens = append(tokens, token{typ: tokenComma, value: ","})
+		case '*':
+			tokens = append(tokens, token{typ: tokenStar, value: "*"})
 		default:
 			return nil, fmt.Errorf("unexpected character '%c' at position %d", query[i], i)
 		}
@@ -209,6 +223,27 @@ func (p *Parser) parseQuery() (*Query, error) {
 		return nil, fmt.Errorf("expected SELECT keyword")
 	}
 
+	// Optional select list of aggregates and grouped fields
+	var selected []GroupKey
+	if p.current().typ != tokenKeyword {
+		for {
+			aggregate, key, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			if aggregate != nil {
+				q.Aggregates = append(q.Aggregates, *aggregate)
+			} else {
+				selected = append(selected, key)
+			}
+
+			if p.current().typ != tokenComma {
+				break
+			}
+			p.advance()
+		}
+	}
+
 	// Parse query type
 	if err := p.parseQueryType(q); err != nil {
 		return nil, err
@@ -216,11 +251,33 @@ func (p *Parser) parseQuery() (*Query, error) {
 
 	// Optional WHERE clause
 	if p.matchKeyword("WHERE") {
-		filters, err := p.parseFilters()
+		where, err := p.parseOr()
 		if err != nil {
 			return nil, err
 		}
-		q.Filters = filters
+		q.Where = where
+	}
+
+	// Optional GROUP BY
+	if p.matchKeyword("GROUP") {
+		if !p.expectKeyword("BY") {
+			return nil, fmt.Errorf("expected BY after GROUP")
+		}
+		for {
+			aggregate, key, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			if aggregate != nil {
+				return nil, fmt.Errorf("cannot group by aggregate %s", aggregate)
+			}
+			q.GroupBy = append(q.GroupBy, key)
+
+			if p.current().typ != tokenComma {
+				break
+			}
+			p.advance()
+		}
 	}
 
 	// Optional ORDER BY
@@ -228,11 +285,15 @@ func (p *Parser) parseQuery() (*Query, error) {
 		if !p.expectKeyword("BY") {
 			return nil, fmt.Errorf("expected BY after ORDER")
 		}
-		orderBy, err := p.parseIdentifier()
+		aggregate, key, err := p.parseTerm()
 		if err != nil {
 			return nil, err
 		}
-		q.OrderBy = orderBy
+		if aggregate != nil {
+			q.OrderBy = aggregate.Name()
+		} else {
+			q.OrderBy = key.Name()
+		}
 
 		if p.matchKeyword("DESC") {
 			q.Descending = true
@@ -250,9 +311,54 @@ func (p *Parser) parseQuery() (*Query, error) {
 		q.Limit = int(limit)
 	}
 
+	if p.current().typ != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q after query", p.current().value)
+	}
+
+	if err := validateAggregation(q, selected); err != nil {
+		return nil, err
+	}
+
 	return q, nil
 }
 
+// validateAggregation checks the select list, GROUP BY and ORDER BY of an
+// aggregate query fit together. Grouping without an aggregate counts the
+// documents in each group.
+func validateAggregation(q *Query, selected []GroupKey) error {
+	if len(q.GroupBy) > 0 && len(q.Aggregates) == 0 {
+		q.Aggregates = []Aggregate{{Func: AggCount}}
+	}
+
+	if !q.IsAggregate() {
+		if len(selected) > 0 {
+			return fmt.Errorf("selecting %s requires GROUP BY", selected[0])
+		}
+		return nil
+	}
+
+	if q.Type != QueryDocuments {
+		return fmt.Errorf("aggregates are only supported on documents, not %s", q.Type)
+	}
+
+	columns := make(map[string]bool)
+	for _, key := range q.GroupBy {
+		columns[key.Name()] = true
+	}
+	for _, key := range selected {
+		if !columns[key.Name()] {
+			return fmt.Errorf("selected field %s must appear in GROUP BY", key)
+		}
+	}
+	for _, aggregate := range q.Aggregates {
+		columns[aggregate.Name()] = true
+	}
+	if q.OrderBy != "" && !columns[q.OrderBy] {
+		return fmt.Errorf("cannot order aggregate results by %s", q.OrderBy)
+	}
+	return nil
+}
+
 // parseQueryType parses the FROM clause to determine query type
 func (p *Parser) parseQueryType(q *Query) error {
 	if !p.expectKeyword("FROM") {
@@ -281,34 +387,89 @@ func (p *Parser) parseQueryType(q *Query) error {
 	return nil
 }
 
-// parseFilters parses WHERE clause filters
-func (p *Parser) parseFilters() ([]Filter, error) {
-	var filters []Filter
+// parseOr parses a WHERE expression. OR binds looser than AND, which binds
+// looser than NOT.
+func (p *Parser) parseOr() (*Expr, error) {
+	exprs := []*Expr{}
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if !p.matchKeyword("OR") {
+			break
+		}
+	}
+	return Or(exprs...), nil
+}
 
+// parseAnd parses terms joined by AND
+func (p *Parser) parseAnd() (*Expr, error) {
+	exprs := []*Expr{}
 	for {
-		filter, err := p.parseFilter()
+		expr, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
-		filters = append(filters, filter)
+		exprs = append(exprs, expr)
 
-		// Check for AND (OR not supported yet for simplicity)
 		if !p.matchKeyword("AND") {
 			break
 		}
 	}
+	return And(exprs...), nil
+}
+
+// parseUnary parses a negation, a parenthesized group or a single filter
+func (p *Parser) parseUnary() (*Expr, error) {
+	if p.matchKeyword("NOT") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(expr), nil
+	}
+
+	if p.current().typ == tokenLeftParen {
+		p.advance()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.current().typ != tokenRightParen {
+			return nil, fmt.Errorf("expected ) to close group, got %q", p.current().value)
+		}
+		p.advance()
+		return expr, nil
+	}
 
-	return filters, nil
+	filter, err := p.parseFilter()
+	if err != nil {
+		return nil, err
+	}
+	return FilterExpr(filter), nil
 }
 
 // parseFilter parses a single filter condition
 func (p *Parser) parseFilter() (Filter, error) {
 	// Get field name
-	field, err := p.parseIdentifier()
+	field, err := p.parseField()
 	if err != nil {
 		return Filter{}, fmt.Errorf("expected field name: %w", err)
 	}
 
+	// Existence checks take no value
+	if p.matchKeyword("EXISTS") {
+		return Filter{Field: field, Operator: OpExists}, nil
+	}
+	if p.current().typ == tokenKeyword && p.current().value == "NOT" &&
+		p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].typ == tokenKeyword && p.tokens[p.pos+1].value == "EXISTS" {
+		p.pos += 2
+		return Filter{Field: field, Operator: OpNotExists}, nil
+	}
+
 	// Get operator
 	op := p.current()
 	if op.typ != tokenOperator {
@@ -345,6 +506,48 @@ func (p *Parser) parseFilter() (Filter, error) {
 	}, nil
 }
 
+// parseTerm parses a select list, GROUP BY or ORDER BY term: an aggregate
+// such as COUNT(*), a bucketed time field such as MONTH(created_at), or a
+// plain field. Exactly one of the aggregate and key is set.
+func (p *Parser) parseTerm() (*Aggregate, GroupKey, error) {
+	tok := p.current()
+	if tok.typ != tokenIdentifier || p.peek().typ != tokenLeftParen {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, GroupKey{}, fmt.Errorf("expected field name: %w", err)
+		}
+		return nil, GroupKey{Field: field}, nil
+	}
+
+	name := strings.ToUpper(tok.value)
+	bucket, isBucket := timeBuckets[name]
+	if name != "COUNT" && !isBucket {
+		return nil, GroupKey{}, fmt.Errorf("unknown function: %s", tok.value)
+	}
+	p.pos += 2
+
+	// COUNT(*) counts every document, COUNT(field) those with the field
+	var field string
+	if name == "COUNT" && p.current().typ == tokenStar {
+		p.advance()
+	} else {
+		var err error
+		if field, err = p.parseField(); err != nil {
+			return nil, GroupKey{}, fmt.Errorf("expected field in %s(): %w", name, err)
+		}
+	}
+
+	if p.current().typ != tokenRightParen {
+		return nil, GroupKey{}, fmt.Errorf("expected ) to close %s(, got %q", name, p.current().value)
+	}
+	p.advance()
+
+	if isBucket {
+		return nil, GroupKey{Field: field, Bucket: bucket}, nil
+	}
+	return &Aggregate{Func: AggCount, Field: field}, GroupKey{}, nil
+}
+
 // Helper methods
 
 func (p *Parser) current() token {
@@ -354,6 +557,13 @@ func (p *Parser) current() token {
 	return p.tokens[p.pos]
 }
 
+func (p *Parser) peek() token {
+	if p.pos+1 >= len(p.tokens) {
+		return token{typ: tokenEOF}
+	}
+	return p.tokens[p.pos+1]
+}
+
 func (p *Parser) advance() {
 	if p.pos < len(p.tokens) {
 		p.pos++
@@ -387,6 +597,20 @@ func (p *Parser) parseIdentifier() (string, error) {
 	return tok.value, nil
 }
 
+// parseField parses a field name. Query type keywords are accepted too,
+// since fields such as "authors" share their names.
+func (p *Parser) parseField() (string, error) {
+	tok := p.current()
+	if tok.typ == tokenKeyword {
+		switch tok.value {
+		case "DOCUMENTS", "AUTHORS", "SOURCES", "ATTRIBUTION":
+			p.advance()
+			return strings.ToLower(tok.value), nil
+		}
+	}
+	return p.parseIdentifier()
+}
+
 func (p *Parser) parseNumber() (float64, error) {
 	tok := p.current()
 	if tok.typ != tokenNumber {
@@ -30,9 +30,7 @@ func TestParser_Parse(t *testing.T) {
 			query: `SELECT FROM documents WHERE source = "arXiv"`,
 			want: &Query{
 				Type: QueryDocuments,
-				Filters: []Filter{
-					{Field: "source", Operator: OpEquals, Value: "arXiv"},
-				},
+				Where: FilterExpr(Filter{Field: "source", Operator: OpEquals, Value: "arXiv"}),
 				Limit: 100,
 			},
 			wantErr: false,
@@ -42,10 +40,10 @@ func TestParser_Parse(t *testing.T) {
 			query: `SELECT FROM documents WHERE source = "arXiv" AND title ~ "neural"`,
 			want: &Query{
 				Type: QueryDocuments,
-				Filters: []Filter{
-					{Field: "source", Operator: OpEquals, Value: "arXiv"},
-					{Field: "title", Operator: OpContains, Value: "neural"},
-				},
+				Where: And(
+					FilterExpr(Filter{Field: "source", Operator: OpEquals, Value: "arXiv"}),
+					FilterExpr(Filter{Field: "title", Operator: OpContains, Value: "neural"}),
+				),
 				Limit: 100,
 			},
 			wantErr: false,
@@ -66,9 +64,7 @@ func TestParser_Parse(t *testing.T) {
 			query: `SELECT FROM attribution WHERE caia_attribution = true`,
 			want: &Query{
 				Type: QueryAttribution,
-				Filters: []Filter{
-					{Field: "caia_attribution", Operator: OpEquals, Value: true},
-				},
+				Where: FilterExpr(Filter{Field: "caia_attribution", Operator: OpEquals, Value: true}),
 				Limit: 100,
 			},
 			wantErr: false,
@@ -93,6 +89,52 @@ func TestParser_Parse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:  "or binds looser than and",
+			query: `SELECT FROM documents WHERE source = "arXiv" AND title ~ "neural" OR author exists`,
+			want: &Query{
+				Type: QueryDocuments,
+				Where: Or(
+					And(
+						FilterExpr(Filter{Field: "source", Operator: OpEquals, Value: "arXiv"}),
+						FilterExpr(Filter{Field: "title", Operator: OpContains, Value: "neural"}),
+					),
+					FilterExpr(Filter{Field: "author", Operator: OpExists}),
+				),
+				Limit: 100,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "parentheses and not",
+			query: `SELECT FROM documents WHERE NOT (source = "arXiv" OR source = "PubMed") AND (title ~ "a" OR NOT title ~ "b") AND url NOT EXISTS`,
+			want: &Query{
+				Type: QueryDocuments,
+				Where: And(
+					Not(Or(
+						FilterExpr(Filter{Field: "source", Operator: OpEquals, Value: "arXiv"}),
+						FilterExpr(Filter{Field: "source", Operator: OpEquals, Value: "PubMed"}),
+					)),
+					Or(
+						FilterExpr(Filter{Field: "title", Operator: OpContains, Value: "a"}),
+						Not(FilterExpr(Filter{Field: "title", Operator: OpContains, Value: "b"})),
+					),
+					FilterExpr(Filter{Field: "url", Operator: OpNotExists}),
+				),
+				Limit: 100,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unclosed group",
+			query:   `SELECT FROM documents WHERE (source = "arXiv" OR source = "PubMed"`,
+			wantErr: true,
+		},
+		{
+			name:    "dangling or",
+			query:   `SELECT FROM documents WHERE source = "arXiv" OR`,
+			wantErr: true,
+		},
 		{
 			name:    "missing SELECT",
 			query:   `FROM documents`,
@@ -127,13 +169,8 @@ func TestParser_Parse(t *testing.T) {
 			assert.Equal(t, tt.want.Limit, got.Limit)
 			assert.Equal(t, tt.want.OrderBy, got.OrderBy)
 			assert.Equal(t, tt.want.Descending, got.Descending)
-			assert.Equal(t, len(tt.want.Filters), len(got.Filters))
-			
-			for i, filter := range tt.want.Filters {
-				assert.Equal(t, filter.Field, got.Filters[i].Field)
-				assert.Equal(t, filter.Operator, got.Filters[i].Operator)
-				assert.Equal(t, filter.Value, got.Filters[i].Value)
-			}
+			assert.Equal(t, tt.want.Where.Filters(), got.Where.Filters())
+			assert.Equal(t, tt.want.Where.String(), got.Where.String())
 		})
 	}
 }
@@ -341,4 +378,103 @@ func TestQueryTypes(t *testing.T) {
 	for i, typ := range types {
 		assert.Equal(t, expected[i], string(typ))
 	}
-}
\ No newline at end of file
+}
+
+func TestParser_Examples(t *testing.T) {
+	parser := NewParser()
+
+	examples := map[string]string{
+		"ExampleAllDocuments":          ExampleAllDocuments,
+		"ExampleArxivDocuments":        ExampleArxivDocuments,
+		"ExampleRecentDocuments":       ExampleRecentDocuments,
+		"ExampleAIDocuments":           ExampleAIDocuments,
+		"ExampleDocumentsByAuthor":     ExampleDocumentsByAuthor,
+		"ExampleAttributionCompliance": ExampleAttributionCompliance,
+		"ExampleAttributionBySource":   ExampleAttributionBySource,
+		"ExampleMissingAttribution":    ExampleMissingAttribution,
+		"ExampleAllSources":            ExampleAllSources,
+		"ExampleActiveSource":          ExampleActiveSource,
+		"ExampleTopAuthors":            ExampleTopAuthors,
+		"ExampleProlificAuthors":       ExampleProlificAuthors,
+		"ExampleCorpusComposition":     ExampleCorpusComposition,
+		"ExampleMonthlyHistogram":      ExampleMonthlyHistogram,
+	}
+	for name, query := range examples {
+		_, err := parser.Parse(query)
+		assert.NoError(t, err, name)
+	}
+
+	q, err := parser.Parse(ExampleAIDocuments)
+	require.NoError(t, err)
+	assert.Equal(t, `title ~ "artificial intelligence" OR title ~ "machine learning"`, q.Where.String())
+
+	q, err = parser.Parse(ExampleDocumentsByAuthor)
+	require.NoError(t, err)
+	assert.Equal(t, ExprOr, q.Where.Kind)
+	assert.Len(t, q.Where.Filters(), 2)
+}
+
+func TestExpr_Eval(t *testing.T) {
+	q, err := NewParser().Parse(`SELECT FROM documents WHERE a = "1" AND NOT (b = "1" OR c = "1")`)
+	require.NoError(t, err)
+
+	eval := func(values map[string]string) bool {
+		return q.Where.Eval(func(leaf *Expr) bool {
+			return values[leaf.Filter.Field] == leaf.Filter.Value
+		})
+	}
+	assert.True(t, eval(map[string]string{"a": "1"}))
+	assert.False(t, eval(map[string]string{"a": "1", "c": "1"}))
+	assert.False(t, eval(map[string]string{"b": "1"}))
+
+	var empty *Expr
+	assert.True(t, empty.Eval(func(*Expr) bool { return false }), "A missing WHERE matches everything")
+	assert.Len(t, q.Where.Conjuncts(), 2)
+}
+
+func TestParser_Aggregates(t *testing.T) {
+	parser := NewParser()
+
+	q, err := parser.Parse(ExampleCorpusComposition)
+	require.NoError(t, err)
+	assert.True(t, q.IsAggregate())
+	assert.Equal(t, []Aggregate{{Func: AggCount}}, q.Aggregates)
+	assert.Equal(t, []GroupKey{{Field: "source_type"}, {Field: "metadata.category"}}, q.GroupBy)
+
+	q, err = parser.Parse(`SELECT month(created_at), count(*), COUNT(doi) FROM documents WHERE source = "arXiv" GROUP BY MONTH(created_at) ORDER BY COUNT(doi) DESC LIMIT 12`)
+	require.NoError(t, err)
+	assert.Equal(t, []GroupKey{{Field: "created_at", Bucket: BucketMonth}}, q.GroupBy)
+	require.Len(t, q.Aggregates, 2)
+	assert.Equal(t, "count", q.Aggregates[0].Name())
+	assert.Equal(t, "COUNT(doi)", q.Aggregates[1].String())
+	assert.Equal(t, "count(doi)", q.OrderBy)
+	assert.True(t, q.Descending)
+	assert.Equal(t, 12, q.Limit)
+	assert.Equal(t, "month(created_at)", q.GroupBy[0].Name())
+
+	// GROUP BY alone counts documents
+	q, err = parser.Parse(`SELECT FROM documents GROUP BY YEAR(created_at)`)
+	require.NoError(t, err)
+	assert.Equal(t, []Aggregate{{Func: AggCount}}, q.Aggregates)
+
+	// Plain queries are unaffected
+	q, err = parser.Parse(ExampleTopAuthors)
+	require.NoError(t, err)
+	assert.False(t, q.IsAggregate())
+	assert.Equal(t, "count", q.OrderBy)
+
+	invalid := []string{
+		`SELECT source FROM documents`,
+		`SELECT source, COUNT(*) FROM documents GROUP BY title`,
+		`SELECT COUNT(*) FROM documents GROUP BY COUNT(*)`,
+		`SELECT COUNT(*) FROM authors`,
+		`SELECT COUNT(*) FROM documents GROUP BY source ORDER BY title`,
+		`SELECT SUM(size) FROM documents`,
+		`SELECT COUNT(* FROM documents`,
+		`SELECT COUNT(*) FROM documents GROUP source`,
+	}
+	for _, query := range invalid {
+		_, err := parser.Parse(query)
+		assert.Error(t, err, query)
+	}
+}
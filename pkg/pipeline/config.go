@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/Caia-Tech/caia-library/internal/storage"
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
 )
 
@@ -18,6 +19,9 @@ type PipelineConfig struct {
 	// Processing configuration
 	Processing *ProcessingConfig `json:"processing"`
 	
+	// Embedding provider configuration
+	Embedding *embedder.Config `json:"embedding"`
+	
 	// Server configuration
 	Server *ServerConfig `json:"server"`
 	
@@ -64,6 +68,7 @@ type DataPathsConfig struct {
 	GitRepo     string `json:"git_repo"`
 	GovcData    string `json:"govc_data"`
 	IndexPath   string `json:"index_path"`
+	EventLogPath string `json:"event_log_path"` // durable event log; empty keeps events in memory
 	
 	// Log paths
 	LogDir      string `json:"log_dir"`
@@ -108,6 +113,15 @@ func DefaultPipelineConfig() *PipelineConfig {
 			BatchSize:         10,
 		},
 		
+		Embedding: &embedder.Config{
+			Provider:   embedder.ProviderHash,
+			Dimensions: 384,
+			ModelPath:  "./data/indexes/tfidf-model.json",
+			API:        embedder.APIOpenAI,
+			BatchSize:  32,
+			Timeout:    30 * time.Second,
+		},
+		
 		Server: &ServerConfig{
 			Host:           "0.0.0.0",
 			Port:           8080,
@@ -1,11 +1,13 @@
 package pipeline
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 
+	"github.com/Caia-Tech/caia-library/pkg/embedder"
 	"github.com/Caia-Tech/caia-library/pkg/logging"
 )
 
@@ -18,6 +20,7 @@ func SetupDirectories(config *PipelineConfig) error {
 		config.DataPaths.GitRepo,
 		config.DataPaths.GovcData,
 		config.DataPaths.IndexPath,
+		config.DataPaths.EventLogPath,
 		config.DataPaths.LogDir,
 		config.DataPaths.TempDir,
 		config.DataPaths.UploadDir,
@@ -27,6 +30,9 @@ func SetupDirectories(config *PipelineConfig) error {
 	logger.Info().Msg("Setting up pipeline directories")
 	
 	for _, dir := range directories {
+		if dir == "" {
+			continue
+		}
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			logger.Error().Err(err).Str("directory", dir).Msg("Failed to create directory")
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
@@ -129,6 +135,68 @@ Generated by CAIA Library Pipeline
 	return nil
 }
 
+// InitializeEmbedder creates the embedding engine selected in the config.
+// A tfidf model that has not been trained yet is trained on the texts
+// returned by corpus and saved to its model path. While the corpus has no
+// text to train on, the hash embedder is used instead and training is tried
+// again on the next start.
+func InitializeEmbedder(config *PipelineConfig, corpus func() ([]string, error)) (*embedder.Engine, error) {
+	logger := logging.GetLogger("embedder-setup")
+	
+	embeddingConfig := config.Embedding
+	if embeddingConfig == nil {
+		embeddingConfig = embedder.DefaultConfig()
+	}
+	
+	if embeddingConfig.Provider == embedder.ProviderTFIDF {
+		if _, err := os.Stat(embeddingConfig.ModelPath); os.IsNotExist(err) {
+			texts, err := corpus()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load training corpus: %w", err)
+			}
+			
+			model, err := embedder.TrainTFIDFEmbedder(texts, embeddingConfig.Dimensions)
+			switch {
+			case errors.Is(err, embedder.ErrEmptyCorpus):
+				logger.Warn().
+					Int("documents", len(texts)).
+					Msg("No text to train the tfidf embedding model on, using the hash embedder until there is")
+				fallback := *embeddingConfig
+				fallback.Provider = embedder.ProviderHash
+				embeddingConfig = &fallback
+			case err != nil:
+				return nil, fmt.Errorf("failed to train tfidf embedder: %w", err)
+			default:
+				if err := os.MkdirAll(filepath.Dir(embeddingConfig.ModelPath), 0755); err != nil {
+					return nil, fmt.Errorf("failed to create model directory: %w", err)
+				}
+				if err := model.Save(embeddingConfig.ModelPath); err != nil {
+					return nil, err
+				}
+				
+				logger.Info().
+					Str("model", model.Model()).
+					Int("documents", len(texts)).
+					Int("vocabulary", model.Vocabulary()).
+					Msg("Trained tfidf embedding model")
+			}
+		}
+	}
+	
+	engine, err := embedder.NewEngineWithConfig(embeddingConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	logger.Info().
+		Str("provider", embeddingConfig.Provider).
+		Str("model", engine.Model()).
+		Int("dimensions", engine.Dimensions()).
+		Msg("Embedding engine initialized")
+	
+	return engine, nil
+}
+
 // ValidateConfiguration checks if the pipeline configuration is valid
 func ValidateConfiguration(config *PipelineConfig) error {
 	logger := logging.GetLogger("config-validator")
@@ -152,6 +220,23 @@ func ValidateConfiguration(config *PipelineConfig) error {
 		return fmt.Errorf("server port must be between 1 and 65535")
 	}
 	
+	// Validate embedding provider
+	if config.Embedding != nil {
+		switch config.Embedding.Provider {
+		case embedder.ProviderHash, embedder.ProviderTFIDF:
+			if config.Embedding.Dimensions <= 0 {
+				return fmt.Errorf("embedding dimensions must be greater than 0")
+			}
+		case embedder.ProviderHTTP:
+			if config.Embedding.Endpoint == "" || config.Embedding.Model == "" {
+				return fmt.Errorf("http embedding provider requires endpoint and model")
+			}
+		default:
+			return fmt.Errorf("invalid embedding provider: %s. Valid options: %v",
+				config.Embedding.Provider, []string{embedder.ProviderHash, embedder.ProviderTFIDF, embedder.ProviderHTTP})
+		}
+	}
+	
 	// Validate storage backend
 	validBackends := []string{"govc", "git"}
 	found := false
@@ -185,6 +270,7 @@ func GetPipelineStatus(config *PipelineConfig) map[string]interface{} {
 		"git_repo":    config.DataPaths.GitRepo,
 		"govc_data":   config.DataPaths.GovcData,
 		"index_path":  config.DataPaths.IndexPath,
+		"event_log_path": config.DataPaths.EventLogPath,
 		"log_dir":     config.DataPaths.LogDir,
 		"temp_dir":    config.DataPaths.TempDir,
 		"upload_dir":  config.DataPaths.UploadDir,
@@ -219,6 +305,9 @@ func GetPipelineStatus(config *PipelineConfig) map[string]interface{} {
 		"enable_ocr":        config.Processing.EnableOCR,
 		"max_workers":       config.Processing.MaxWorkers,
 	}
+	if config.Embedding != nil {
+		status["config"].(map[string]interface{})["embedding_provider"] = config.Embedding.Provider
+	}
 	
 	return status
 }
\ No newline at end of file
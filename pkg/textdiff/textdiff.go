@@ -0,0 +1,162 @@
+// Package textdiff summarizes how a document's text changed between two
+// versions, line by line.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxSampleLines caps the added and removed lines kept in a summary
+	maxSampleLines = 5
+	// maxSampleLength caps the length of each sampled line
+	maxSampleLength = 200
+	// maxLCSCells bounds the line LCS table; larger changes are compared
+	// as multisets of lines instead
+	maxLCSCells = 4_000_000
+)
+
+// Summary describes a line diff between two texts
+type Summary struct {
+	LinesAdded     int      `json:"lines_added"`
+	LinesRemoved   int      `json:"lines_removed"`
+	LinesUnchanged int      `json:"lines_unchanged"`
+	ChangeRatio    float64  `json:"change_ratio"`
+	Added          []string `json:"added,omitempty"`
+	Removed        []string `json:"removed,omitempty"`
+}
+
+// Changed reports whether any line was added or removed
+func (s Summary) Changed() bool {
+	return s.LinesAdded > 0 || s.LinesRemoved > 0
+}
+
+// String renders the summary as e.g. "+3 -1 lines (12% changed)"
+func (s Summary) String() string {
+	return fmt.Sprintf("+%d -%d lines (%.0f%% changed)", s.LinesAdded, s.LinesRemoved, s.ChangeRatio*100)
+}
+
+// Summarize diffs oldText against newText by line. Blank lines and
+// surrounding whitespace are ignored. ChangeRatio is the share of lines,
+// out of the longer version, that were added or removed.
+func Summarize(oldText, newText string) Summary {
+	a, b := lines(oldText), lines(newText)
+
+	// Common prefix and suffix are unchanged and need no LCS
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var removed, added []string
+	if len(midA)*len(midB) <= maxLCSCells {
+		removed, added = lcsDiff(midA, midB)
+	} else {
+		removed, added = multisetDiff(midA, midB)
+	}
+
+	s := Summary{
+		LinesAdded:     len(added),
+		LinesRemoved:   len(removed),
+		LinesUnchanged: len(b) - len(added),
+		Added:          sample(added),
+		Removed:        sample(removed),
+	}
+	if total := max(len(a), len(b)); total > 0 {
+		s.ChangeRatio = float64(max(len(added), len(removed))) / float64(total)
+	}
+	return s
+}
+
+// lines splits text into trimmed, non-blank lines
+func lines(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// lcsDiff returns the lines of a and b outside their longest common
+// subsequence, in order
+func lcsDiff(a, b []string) (removed, added []string) {
+	n, m := len(a), len(b)
+	// table[i][j] is the LCS length of a[i:] and b[j:]
+	table := make([][]int32, n+1)
+	for i := range table {
+		table[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	removed = append(removed, a[i:]...)
+	added = append(added, b[j:]...)
+	return removed, added
+}
+
+// multisetDiff ignores line order: a line is unchanged if the other text
+// has an unmatched copy of it
+func multisetDiff(a, b []string) (removed, added []string) {
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			added = append(added, line)
+		}
+	}
+	for _, line := range a {
+		if counts[line] > 0 {
+			counts[line]--
+			removed = append(removed, line)
+		}
+	}
+	return removed, added
+}
+
+func sample(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	n := min(len(lines), maxSampleLines)
+	out := make([]string, n)
+	for i, line := range lines[:n] {
+		if len(line) > maxSampleLength {
+			line = strings.ToValidUTF8(line[:maxSampleLength], "") + "…"
+		}
+		out[i] = line
+	}
+	return out
+}
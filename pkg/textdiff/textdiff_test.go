@@ -0,0 +1,49 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	old := "Title\n\nFirst paragraph.\nSecond paragraph.\nThird paragraph.\n"
+	updated := "Title\nFirst paragraph.\n  Second paragraph, revised.\nThird paragraph.\nA new closing line.\n"
+
+	s := Summarize(old, updated)
+	assert.True(t, s.Changed())
+	assert.Equal(t, 2, s.LinesAdded)
+	assert.Equal(t, 1, s.LinesRemoved)
+	assert.Equal(t, 3, s.LinesUnchanged)
+	assert.Equal(t, []string{"Second paragraph, revised.", "A new closing line."}, s.Added)
+	assert.Equal(t, []string{"Second paragraph."}, s.Removed)
+	assert.InDelta(t, 0.4, s.ChangeRatio, 1e-9)
+	assert.Equal(t, "+2 -1 lines (40% changed)", s.String())
+
+	// Blank lines and indentation alone are not changes
+	s = Summarize(old, "  Title\nFirst paragraph.\n\n\nSecond paragraph.\nThird paragraph.")
+	assert.False(t, s.Changed())
+	assert.Equal(t, 0.0, s.ChangeRatio)
+
+	assert.False(t, Summarize("", "").Changed())
+	assert.Equal(t, 1.0, Summarize("", "only line").ChangeRatio)
+}
+
+func TestSummarizeSamples(t *testing.T) {
+	var added []string
+	for i := 0; i < 20; i++ {
+		added = append(added, strings.Repeat("x", 300)+string(rune('a'+i)))
+	}
+
+	s := Summarize("", strings.Join(added, "\n"))
+	assert.Equal(t, 20, s.LinesAdded)
+	assert.Len(t, s.Added, maxSampleLines)
+	assert.Equal(t, maxSampleLength+len("…"), len(s.Added[0]))
+}
+
+func TestMultisetDiff(t *testing.T) {
+	removed, added := multisetDiff([]string{"a", "b", "b", "c"}, []string{"b", "d", "a"})
+	assert.Equal(t, []string{"b", "c"}, removed)
+	assert.Equal(t, []string{"d"}, added)
+}
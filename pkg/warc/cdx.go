@@ -0,0 +1,366 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CDXEntry locates one capture of a URL in a WARC file, as listed by a CDX
+// or CDXJ index
+type CDXEntry struct {
+	URLKey    string `json:"urlkey,omitempty"`
+	Timestamp string `json:"timestamp"`
+	URL       string `json:"url"`
+	MIME      string `json:"mime,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Length    int64  `json:"length,omitempty"`
+	Offset    int64  `json:"offset"`
+	Filename  string `json:"filename,omitempty"`
+}
+
+// Time returns the capture time
+func (e CDXEntry) Time() time.Time {
+	t, _ := time.Parse(cdxTimeLayout, e.Timestamp)
+	return t
+}
+
+// Location resolves the entry's filename against base, such as
+// CommonCrawlDataURL or a local directory
+func (e CDXEntry) Location(base string) string {
+	if base == "" || strings.Contains(e.Filename, "://") || strings.HasPrefix(e.Filename, "/") {
+		return e.Filename
+	}
+	return strings.TrimSuffix(base, "/") + "/" + e.Filename
+}
+
+// CommonCrawlDataURL is the base Common Crawl CDX filenames resolve against
+const CommonCrawlDataURL = "https://data.commoncrawl.org/"
+
+const cdxTimeLayout = "20060102150405"
+
+// FormatTimestamp formats a time as a 14 digit CDX timestamp
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(cdxTimeLayout)
+}
+
+// SURT returns the sort-friendly form of a URL that CDX indexes are keyed
+// and sorted by, e.g. "com,example)/a?b=1" for http://www.example.com/a?b=1
+func SURT(rawURL string) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return "", fmt.Errorf("invalid URL %q: no host", rawURL)
+	}
+	if rest, ok := strings.CutPrefix(host, "www"); ok {
+		// www., www1., www2. ...
+		digits := strings.TrimLeft(rest, "0123456789")
+		if strings.HasPrefix(digits, ".") && strings.Count(digits, ".") > 1 {
+			host = digits[1:]
+		}
+	}
+
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	key := strings.Join(labels, ",")
+	if port := u.Port(); port != "" && !(port == "80" && u.Scheme == "http") && !(port == "443" && u.Scheme == "https") {
+		key += ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + path
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		sort.Strings(params)
+		key += "?" + strings.Join(params, "&")
+	}
+	return strings.ToLower(key), nil
+}
+
+// cdxFields is the classic 11 field CDX layout, "N b a m s k r M S V g"
+const cdxFields = "NbamskrMSVg"
+
+// ParseCDXLine parses one index line: a CDXJ line ("urlkey timestamp
+// {json}"), a JSON object as CDX servers return, or a classic space
+// separated CDX line in the 11 field layout
+func ParseCDXLine(line string) (CDXEntry, error) {
+	return parseCDXLine(line, cdxFields)
+}
+
+func parseCDXLine(line, fields string) (CDXEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return CDXEntry{}, fmt.Errorf("empty CDX line")
+	}
+
+	if strings.HasPrefix(line, "{") {
+		return parseCDXJSON(line, CDXEntry{})
+	}
+	if i := strings.Index(line, " {"); i >= 0 {
+		key, timestamp, ok := strings.Cut(line[:i], " ")
+		if !ok {
+			return CDXEntry{}, fmt.Errorf("invalid CDXJ line %q", truncate(line, 60))
+		}
+		return parseCDXJSON(line[i+1:], CDXEntry{URLKey: key, Timestamp: strings.TrimSpace(timestamp)})
+	}
+
+	values := strings.Fields(line)
+	if len(values) == 9 && fields == cdxFields {
+		fields = "NbamskrVg" // the older 9 field layout
+	}
+	if len(values) != len(fields) {
+		return CDXEntry{}, fmt.Errorf("CDX line has %d fields, expected %d", len(values), len(fields))
+	}
+
+	var entry CDXEntry
+	for i, value := range values {
+		if value == "-" {
+			continue
+		}
+		var err error
+		switch fields[i] {
+		case 'N':
+			entry.URLKey = value
+		case 'b':
+			entry.Timestamp = value
+		case 'a':
+			entry.URL = value
+		case 'm':
+			entry.MIME = value
+		case 's':
+			entry.Status = value
+		case 'k':
+			entry.Digest = value
+		case 'S':
+			entry.Length, err = strconv.ParseInt(value, 10, 64)
+		case 'V':
+			entry.Offset, err = strconv.ParseInt(value, 10, 64)
+		case 'g':
+			entry.Filename = value
+		}
+		if err != nil {
+			return CDXEntry{}, fmt.Errorf("invalid CDX field %c %q", fields[i], value)
+		}
+	}
+	return entry, nil
+}
+
+// parseCDXJSON reads the JSON part of a CDXJ line. Servers differ in field
+// names and in writing numbers as strings, so both are accepted.
+func parseCDXJSON(data string, entry CDXEntry) (CDXEntry, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return CDXEntry{}, fmt.Errorf("invalid CDX JSON: %w", err)
+	}
+
+	str := func(names ...string) string {
+		for _, name := range names {
+			switch v := fields[name].(type) {
+			case string:
+				return v
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		return ""
+	}
+	num := func(name string) (int64, error) {
+		value := str(name)
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(value, 10, 64)
+	}
+
+	if v := str("urlkey"); v != "" {
+		entry.URLKey = v
+	}
+	if v := str("timestamp"); v != "" {
+		entry.Timestamp = v
+	}
+	entry.URL = str("url", "original")
+	entry.MIME = str("mime", "mimetype")
+	entry.Status = str("status", "statuscode")
+	entry.Digest = str("digest")
+	entry.Filename = str("filename")
+
+	var err error
+	if entry.Length, err = num("length"); err != nil {
+		return CDXEntry{}, fmt.Errorf("invalid CDX length %q", str("length"))
+	}
+	if entry.Offset, err = num("offset"); err != nil {
+		return CDXEntry{}, fmt.Errorf("invalid CDX offset %q", str("offset"))
+	}
+	return entry, nil
+}
+
+// FormatCDXJ formats an entry as a CDXJ line, numbers written as strings
+// as Common Crawl and pywb do
+func FormatCDXJ(entry CDXEntry) string {
+	fields := struct {
+		URL      string `json:"url"`
+		MIME     string `json:"mime,omitempty"`
+		Status   string `json:"status,omitempty"`
+		Digest   string `json:"digest,omitempty"`
+		Length   string `json:"length,omitempty"`
+		Offset   string `json:"offset"`
+		Filename string `json:"filename,omitempty"`
+	}{
+		URL:      entry.URL,
+		MIME:     entry.MIME,
+		Status:   entry.Status,
+		Digest:   entry.Digest,
+		Offset:   strconv.FormatInt(entry.Offset, 10),
+		Filename: entry.Filename,
+	}
+	if entry.Length > 0 {
+		fields.Length = strconv.FormatInt(entry.Length, 10)
+	}
+	data, _ := json.Marshal(fields)
+	return entry.URLKey + " " + entry.Timestamp + " " + string(data)
+}
+
+// ReadCDX reads a CDX or CDXJ index. A classic " CDX ..." header line sets
+// the field layout of the lines after it; comment lines starting with "!"
+// or "#" are skipped.
+func ReadCDX(r io.Reader) ([]CDXEntry, error) {
+	var entries []CDXEntry
+	fields := cdxFields
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '#' {
+			continue
+		}
+		if layout, ok := strings.CutPrefix(line, "CDX "); ok {
+			fields = strings.Join(strings.Fields(layout), "")
+			continue
+		}
+		entry, err := parseCDXLine(line, fields)
+		if err != nil {
+			return nil, fmt.Errorf("CDX line %d: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CDX index: %w", err)
+	}
+	return entries, nil
+}
+
+// CDXIndex answers URL lookups from CDX entries held in memory
+type CDXIndex struct {
+	entries []CDXEntry
+}
+
+// NewCDXIndex indexes entries by SURT key, filling in keys that are missing
+func NewCDXIndex(entries []CDXEntry) *CDXIndex {
+	sorted := make([]CDXEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.URLKey == "" {
+			key, err := SURT(entry.URL)
+			if err != nil {
+				continue
+			}
+			entry.URLKey = key
+		}
+		sorted = append(sorted, entry)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].URLKey != sorted[j].URLKey {
+			return sorted[i].URLKey < sorted[j].URLKey
+		}
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+	return &CDXIndex{entries: sorted}
+}
+
+// LoadCDXIndex reads a CDX or CDXJ file, plain or gzipped
+func LoadCDXIndex(path string) (*CDXIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CDX index: %w", err)
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress CDX index: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	entries, err := ReadCDX(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewCDXIndex(entries), nil
+}
+
+// Len returns the number of entries
+func (idx *CDXIndex) Len() int {
+	return len(idx.entries)
+}
+
+// Entries returns every entry in key order
+func (idx *CDXIndex) Entries() []CDXEntry {
+	return append([]CDXEntry(nil), idx.entries...)
+}
+
+// Lookup returns the captures of a URL, oldest first
+func (idx *CDXIndex) Lookup(rawURL string) []CDXEntry {
+	key, err := SURT(rawURL)
+	if err != nil {
+		return nil
+	}
+	i := sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].URLKey >= key })
+	var out []CDXEntry
+	for ; i < len(idx.entries) && idx.entries[i].URLKey == key; i++ {
+		out = append(out, idx.entries[i])
+	}
+	return out
+}
+
+// Closest returns the capture of a URL nearest to t
+func (idx *CDXIndex) Closest(rawURL string, t time.Time) (CDXEntry, bool) {
+	var best CDXEntry
+	var bestDiff time.Duration = -1
+	for _, entry := range idx.Lookup(rawURL) {
+		diff := entry.Time().Sub(t)
+		if diff < 0 {
+			diff = -diff
+		}
+		if bestDiff < 0 || diff < bestDiff {
+			best, bestDiff = entry, diff
+		}
+	}
+	return best, bestDiff >= 0
+}
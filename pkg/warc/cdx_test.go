@@ -0,0 +1,177 @@
+package warc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSURT(t *testing.T) {
+	cases := map[string]string{
+		"http://www.example.com/a?b=1":       "com,example)/a?b=1",
+		"https://Example.com":                "com,example)/",
+		"http://www2.sub.example.com:80/x":   "com,example,sub)/x",
+		"https://example.com:8443/x?z=1&a=2": "com,example:8443)/x?a=2&z=1",
+		"example.com/Path":                   "com,example)/path",
+		"http://www.com/":                    "com,www)/",
+	}
+	for input, want := range cases {
+		got, err := SURT(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := SURT("http:///nohost")
+	assert.Error(t, err)
+}
+
+func TestParseCDXLine(t *testing.T) {
+	// Common Crawl's CDXJ
+	entry, err := ParseCDXLine(`com,example)/ 20240301120000 {"url": "https://example.com/", "mime": "text/html", "status": "200", "digest": "ABC", "length": "1234", "offset": "5678", "filename": "crawl-data/CC-MAIN-2024-10/a.warc.gz"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "com,example)/", entry.URLKey)
+	assert.Equal(t, int64(1234), entry.Length)
+	assert.Equal(t, int64(5678), entry.Offset)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), entry.Time())
+	assert.Equal(t, CommonCrawlDataURL+"crawl-data/CC-MAIN-2024-10/a.warc.gz", entry.Location(CommonCrawlDataURL))
+
+	// A CDX server's JSON lines
+	entry, err = ParseCDXLine(`{"urlkey": "com,example)/", "timestamp": "20240301120000", "original": "https://example.com/", "mimetype": "text/html", "statuscode": 200, "length": 10, "offset": 20, "filename": "a.warc.gz"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", entry.URL)
+	assert.Equal(t, "200", entry.Status)
+	assert.Equal(t, int64(20), entry.Offset)
+
+	// Classic 11 and 9 field lines
+	entry, err = ParseCDXLine("com,example)/ 20240301120000 https://example.com/ text/html 200 ABC - - 1234 5678 a.warc.gz")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), entry.Length)
+	assert.Equal(t, int64(5678), entry.Offset)
+	assert.Equal(t, "a.warc.gz", entry.Filename)
+
+	entry, err = ParseCDXLine("com,example)/ 20240301120000 https://example.com/ text/html 200 ABC - 5678 a.warc.gz")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5678), entry.Offset)
+
+	_, err = ParseCDXLine("too few fields")
+	assert.Error(t, err)
+	_, err = ParseCDXLine(`com,example)/ 2024 {"offset": "abc"}`)
+	assert.Error(t, err)
+
+	// Formatting round trips
+	entry = CDXEntry{URLKey: "com,example)/", Timestamp: "20240301120000", URL: "https://example.com/", Status: "200", Length: 7, Offset: 9, Filename: "a.warc.gz"}
+	parsed, err := ParseCDXLine(FormatCDXJ(entry))
+	require.NoError(t, err)
+	assert.Equal(t, entry, parsed)
+}
+
+func TestReadCDXHeader(t *testing.T) {
+	input := strings.Join([]string{
+		"# comment",
+		" CDX a b V g",
+		"https://example.com/ 20240301120000 100 a.warc.gz",
+		"",
+	}, "\n")
+	entries, err := ReadCDX(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://example.com/", entries[0].URL)
+	assert.Equal(t, int64(100), entries[0].Offset)
+}
+
+func TestCDXIndexLookup(t *testing.T) {
+	index := NewCDXIndex([]CDXEntry{
+		{URL: "https://example.com/b", Timestamp: "20240301000000", Offset: 1},
+		{URL: "https://example.com/a", Timestamp: "20240301000000", Offset: 2},
+		{URL: "http://www.example.com/a", Timestamp: "20230301000000", Offset: 3},
+		{URL: "https://example.com/a", Timestamp: "20250301000000", Offset: 4},
+	})
+	assert.Equal(t, 4, index.Len())
+
+	found := index.Lookup("https://example.com/a")
+	require.Len(t, found, 3)
+	assert.Equal(t, int64(3), found[0].Offset, "oldest first")
+	assert.Empty(t, index.Lookup("https://example.com/c"))
+
+	closest, ok := index.Closest("example.com/a", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, int64(2), closest.Offset)
+	_, ok = index.Closest("https://example.com/c", time.Now())
+	assert.False(t, ok)
+}
+
+func TestCDXClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "json", r.URL.Query().Get("output"))
+		switch r.URL.Path {
+		case "/cc":
+			if r.URL.Query().Get("url") == "https://missing.example/" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, `{"urlkey": "com,example)/", "timestamp": "20240301120000", "url": "https://example.com/", "length": "10", "offset": "20", "filename": "a.warc.gz"}`)
+			fmt.Fprintln(w, `{"urlkey": "com,example)/", "timestamp": "20240401120000", "url": "https://example.com/", "length": "11", "offset": "40", "filename": "b.warc.gz"}`)
+		case "/ia":
+			fmt.Fprint(w, `[["urlkey","timestamp","original","mimetype","statuscode","digest","length"],`+
+				`["com,example)/","20240301120000","https://example.com/","text/html","200","ABC","512"]]`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	entries, err := NewCDXClient(server.URL+"/cc", nil).Lookup(ctx, "https://example.com/", 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b.warc.gz", entries[1].Filename)
+
+	entries, err = NewCDXClient(server.URL+"/cc", nil).Lookup(ctx, "https://missing.example/", 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entries, err = NewCDXClient(server.URL+"/ia", server.Client()).Lookup(ctx, "https://example.com/", 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://example.com/", entries[0].URL)
+	assert.Equal(t, int64(512), entries[0].Length)
+}
+
+func TestFetchRecord(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true)
+	_, err := w.WriteWarcinfo("remote.warc.gz", nil)
+	require.NoError(t, err)
+	req, resp := testExchange(t, "https://example.com/remote")
+	info, err := w.WriteExchange(req, resp, []byte("remote body"))
+	require.NoError(t, err)
+	data := buf.Bytes()
+
+	ranged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "remote.warc.gz", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ranged.Close()
+	full := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer full.Close()
+
+	for _, server := range []*httptest.Server{ranged, full} {
+		record, err := FetchRecord(context.Background(), server.Client(), server.URL, info.Offset, info.Length)
+		require.NoError(t, err)
+		assert.Equal(t, info.Offset, record.Offset)
+		assert.Equal(t, "https://example.com/remote", record.Header.TargetURI())
+		payload, err := ReadPayload(record, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "remote body", string(payload.Body))
+	}
+
+	_, err = FetchRecord(context.Background(), nil, ranged.URL, 0, 0)
+	assert.Error(t, err, "length required")
+}
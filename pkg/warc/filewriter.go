@@ -0,0 +1,178 @@
+package warc
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxFileSize is the size at which FileWriter starts a new file
+const DefaultMaxFileSize = 1 << 30
+
+// FileWriter captures HTTP fetches into a directory of gzipped WARC files.
+// Each file starts with a warcinfo record and is closed once it reaches the
+// maximum size, the next capture starting a new one. Beside each file a
+// CDXJ index, in capture order, lists its response records. FileWriter is
+// safe for concurrent use.
+type FileWriter struct {
+	mu      sync.Mutex
+	dir     string
+	prefix  string
+	maxSize int64
+	info    Header
+	file    *os.File
+	cdx     *os.File
+	name    string
+	writer  *Writer
+	closed  bool
+}
+
+// OpenFileWriter creates a writer for files named
+// <prefix>-<timestamp>-<sequence>.warc.gz in dir. A maxSize of 0 uses
+// DefaultMaxFileSize. The info fields, e.g. software and operator, go in
+// each file's warcinfo record.
+func OpenFileWriter(dir, prefix string, maxSize int64, info Header) (*FileWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WARC directory: %w", err)
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if prefix == "" {
+		prefix = "capture"
+	}
+	return &FileWriter{dir: dir, prefix: prefix, maxSize: maxSize, info: info}, nil
+}
+
+// WriteExchange captures an HTTP fetch as response and request records and
+// returns the response's index entry
+func (fw *FileWriter) WriteExchange(req *http.Request, resp *http.Response, body []byte) (CDXEntry, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if err := fw.ensureFile(); err != nil {
+		return CDXEntry{}, err
+	}
+
+	now := time.Now()
+	info, err := fw.writer.WriteExchange(req, resp, body)
+	if err != nil {
+		return CDXEntry{}, err
+	}
+
+	target := req.URL.String()
+	entry := CDXEntry{
+		Timestamp: FormatTimestamp(now),
+		URL:       target,
+		Status:    fmt.Sprint(resp.StatusCode),
+		Digest:    strings.TrimPrefix(Digest(body), "sha1:"),
+		Length:    info.Length,
+		Offset:    info.Offset,
+		Filename:  fw.name,
+	}
+	entry.URLKey, _ = SURT(target)
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		entry.MIME = mediaType
+	}
+	if _, err := fw.cdx.WriteString(FormatCDXJ(entry) + "\n"); err != nil {
+		return CDXEntry{}, fmt.Errorf("failed to write CDXJ entry: %w", err)
+	}
+	return entry, nil
+}
+
+// WriteRecord writes any record to the current file
+func (fw *FileWriter) WriteRecord(header Header, block []byte) (RecordInfo, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if err := fw.ensureFile(); err != nil {
+		return RecordInfo{}, err
+	}
+	return fw.writer.WriteRecord(header, block)
+}
+
+// Dir returns the directory files are written to
+func (fw *FileWriter) Dir() string {
+	return fw.dir
+}
+
+// Close closes the current file. Further writes fail.
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.closed = true
+	return fw.closeFile()
+}
+
+// ensureFile opens a file if none is open or the current one is full.
+// Callers must hold fw.mu.
+func (fw *FileWriter) ensureFile() error {
+	if fw.closed {
+		return errors.New("WARC writer is closed")
+	}
+	if fw.writer != nil && fw.writer.Offset() < fw.maxSize {
+		return nil
+	}
+	if err := fw.closeFile(); err != nil {
+		return err
+	}
+
+	stamp := FormatTimestamp(time.Now())
+	for seq := 0; ; seq++ {
+		name := fmt.Sprintf("%s-%s-%05d.warc.gz", fw.prefix, stamp, seq)
+		file, err := os.OpenFile(filepath.Join(fw.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create WARC file: %w", err)
+		}
+
+		cdxName := strings.TrimSuffix(name, ".warc.gz") + ".cdxj"
+		cdx, err := os.OpenFile(filepath.Join(fw.dir, cdxName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to create CDXJ index: %w", err)
+		}
+
+		fw.file, fw.cdx, fw.name = file, cdx, name
+		fw.writer = NewWriter(file, true)
+		break
+	}
+
+	info := Header{{Name: "format", Value: "WARC File Format 1.1"}}
+	info = append(info, fw.info...)
+	if _, err := fw.writer.WriteWarcinfo(fw.name, info); err != nil {
+		return fmt.Errorf("failed to write warcinfo record: %w", err)
+	}
+	return nil
+}
+
+// closeFile syncs and closes the current file and its index. Callers must
+// hold fw.mu.
+func (fw *FileWriter) closeFile() error {
+	if fw.file == nil {
+		return nil
+	}
+	var errs []error
+	for _, f := range []*os.File{fw.file, fw.cdx} {
+		if err := f.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fw.file, fw.cdx, fw.writer, fw.name = nil, nil, nil, ""
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to close WARC file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestBlock serializes an HTTP request as the block of a request record
+func RequestBlock(req *http.Request) []byte {
+	var buf bytes.Buffer
+	proto := req.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	fmt.Fprintf(&buf, "%s %s %s\r\n", req.Method, req.URL.RequestURI(), proto)
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	req.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// ResponseBlock serializes an HTTP response and the body read from it as the
+// block of a response record. Go's client has already undone any chunked
+// transfer encoding, so the header gets the body's actual Content-Length.
+func ResponseBlock(resp *http.Response, body []byte) []byte {
+	var buf bytes.Buffer
+	proto := resp.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	fmt.Fprintf(&buf, "%s %s\r\n", proto, status)
+
+	header := resp.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// WriteExchange writes an HTTP fetch as a response record followed by the
+// request record, linked by WARC-Concurrent-To, and returns the location of
+// the response record
+func (w *Writer) WriteExchange(req *http.Request, resp *http.Response, body []byte) (RecordInfo, error) {
+	date := FormatDate(time.Now())
+	target := req.URL.String()
+
+	response, err := w.WriteRecord(Header{
+		{Name: FieldType, Value: TypeResponse},
+		{Name: FieldDate, Value: date},
+		{Name: FieldTargetURI, Value: target},
+		{Name: FieldContentType, Value: ContentTypeHTTPResponse},
+		{Name: FieldPayloadDigest, Value: Digest(body)},
+	}, ResponseBlock(resp, body))
+	if err != nil {
+		return RecordInfo{}, err
+	}
+
+	_, err = w.WriteRecord(Header{
+		{Name: FieldType, Value: TypeRequest},
+		{Name: FieldDate, Value: date},
+		{Name: FieldTargetURI, Value: target},
+		{Name: FieldConcurrentTo, Value: response.ID},
+		{Name: FieldContentType, Value: ContentTypeHTTPRequest},
+	}, RequestBlock(req))
+	if err != nil {
+		return RecordInfo{}, err
+	}
+	return response, nil
+}
+
+// Payload is the content a response or resource record archived
+type Payload struct {
+	StatusCode  int
+	Header      http.Header
+	ContentType string
+	Body        []byte
+}
+
+// ReadPayload reads the archived content of a record. A response record's
+// HTTP message is parsed and its body decoded from chunked transfer and
+// gzip or deflate content encoding; a resource record's block is the
+// content itself. At most limit bytes of content are read.
+func ReadPayload(record *Record, limit int64) (*Payload, error) {
+	switch record.Header.Type() {
+	case TypeResource:
+		body, err := readLimited(record.Body, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &Payload{
+			StatusCode:  http.StatusOK,
+			Header:      http.Header{},
+			ContentType: record.Header.Get(FieldContentType),
+			Body:        body,
+		}, nil
+	case TypeResponse:
+	default:
+		return nil, fmt.Errorf("%s records have no payload", record.Header.Type())
+	}
+
+	if ct := record.Header.Get(FieldContentType); ct != "" && !strings.HasPrefix(strings.ToLower(ct), "application/http") {
+		return nil, fmt.Errorf("response record holds %s, not an HTTP response", ct)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(record.Body), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archived HTTP response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archived response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	case "deflate":
+		fr := flate.NewReader(resp.Body)
+		defer fr.Close()
+		body = fr
+	}
+
+	content, err := readLimited(body, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Payload{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        content,
+	}, nil
+}
+
+// ReadPayloadHeader reads the status and HTTP header of a record's payload
+// without its body, e.g. to select records before reading them in full
+func ReadPayloadHeader(record *Record) (*Payload, error) {
+	switch record.Header.Type() {
+	case TypeResource:
+		return &Payload{
+			StatusCode:  http.StatusOK,
+			Header:      http.Header{},
+			ContentType: record.Header.Get(FieldContentType),
+		}, nil
+	case TypeResponse:
+	default:
+		return nil, fmt.Errorf("%s records have no payload", record.Header.Type())
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(record.Body), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archived HTTP response: %w", err)
+	}
+	resp.Body.Close()
+	return &Payload{
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archived content: %w", err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("archived content exceeds %d bytes", limit)
+	}
+	return content, nil
+}
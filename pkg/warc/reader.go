@@ -0,0 +1,305 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxHeaderBytes caps the size of a record header
+const maxHeaderBytes = 1 << 20
+
+// Record is a WARC record read from a file
+type Record struct {
+	Version string
+	Header  Header
+	// Body is the record block. It is only valid until the next call to
+	// Reader.Next.
+	Body io.Reader
+
+	// Offset is where the record starts in the file: the start of its gzip
+	// member in a compressed file
+	Offset int64
+	// Length is the number of bytes the record takes in the file, gzip
+	// member included, as a CDX index records it. It is known once the
+	// reader has moved past the record, and stays 0 for records that share
+	// a gzip member with others.
+	Length int64
+}
+
+// Reader reads the records of a WARC file in order. Gzipped files are
+// detected from their content; each gzip member may hold one record, as
+// is usual, or several.
+type Reader struct {
+	src        *countingReader
+	base       int64
+	compressed bool
+
+	gz          *gzip.Reader
+	member      *bufio.Reader
+	memberStart int64
+	inMember    []*Record
+
+	current *Record
+	body    *io.LimitedReader
+	start   int64
+}
+
+// NewReader creates a reader for a plain or gzipped WARC stream
+func NewReader(r io.Reader) *Reader {
+	src := &countingReader{r: bufio.NewReaderSize(r, 64*1024)}
+	magic, _ := src.r.Peek(2)
+	return &Reader{
+		src:        src,
+		compressed: len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b,
+	}
+}
+
+// ReadRecordAt reads the single record at offset, as located by a CDX
+// index. A length of 0 reads to the end of r if needed.
+func ReadRecordAt(r io.ReaderAt, offset, length int64) (*Record, error) {
+	if length <= 0 {
+		length = 1<<63 - 1 - offset
+	}
+	reader := NewReader(io.NewSectionReader(r, offset, length))
+	reader.base = offset
+	record, err := reader.Next()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: no record at offset %d", ErrMalformed, offset)
+	}
+	return record, err
+}
+
+// Next returns the next record, skipping whatever is left of the previous
+// record's body. It returns io.EOF after the last record.
+func (r *Reader) Next() (*Record, error) {
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+
+	var in lineReader = r.src
+	if r.compressed {
+		for {
+			if r.member == nil {
+				start := r.src.n
+				var err error
+				if r.gz == nil {
+					r.gz, err = gzip.NewReader(r.src)
+				} else {
+					err = r.gz.Reset(r.src)
+				}
+				if err == io.EOF {
+					return nil, io.EOF
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to read gzip member at offset %d: %w", r.base+start, err)
+				}
+				r.gz.Multistream(false)
+				r.member = bufio.NewReaderSize(r.gz, 64*1024)
+				r.memberStart = start
+				r.inMember = r.inMember[:0]
+			}
+			more, err := skipBlankLines(r.member)
+			if err != nil {
+				return nil, err
+			}
+			if more {
+				break
+			}
+			r.endMember()
+		}
+		in = r.member
+		r.start = r.memberStart
+	} else {
+		more, err := skipBlankLines(r.src)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return nil, io.EOF
+		}
+		r.start = r.src.n
+	}
+
+	version, header, err := readHeader(in)
+	if err != nil {
+		return nil, fmt.Errorf("record at offset %d: %w", r.base+r.start, err)
+	}
+	length, err := header.ContentLength()
+	if err != nil {
+		return nil, fmt.Errorf("record at offset %d: %w", r.base+r.start, err)
+	}
+
+	r.body = &io.LimitedReader{R: in, N: length}
+	r.current = &Record{
+		Version: version,
+		Header:  header,
+		Body:    r.body,
+		Offset:  r.base + r.start,
+	}
+	if r.compressed {
+		r.inMember = append(r.inMember, r.current)
+	}
+	return r.current, nil
+}
+
+// finish skips the rest of the current record and works out its length
+func (r *Reader) finish() error {
+	if r.current == nil {
+		return nil
+	}
+	defer func() { r.current = nil }()
+
+	if _, err := io.Copy(io.Discard, r.body); err != nil {
+		return fmt.Errorf("failed to read record at offset %d: %w", r.current.Offset, err)
+	}
+	if r.body.N > 0 {
+		return fmt.Errorf("%w: record at offset %d is truncated", io.ErrUnexpectedEOF, r.current.Offset)
+	}
+
+	if !r.compressed {
+		// Each record ends with two CRLFs
+		if trailer, _ := r.src.Peek(4); string(trailer) == "\r\n\r\n" {
+			r.src.Discard(4)
+		}
+		r.current.Length = r.src.n - r.start
+		return nil
+	}
+
+	more, err := skipBlankLines(r.member)
+	if err != nil {
+		return err
+	}
+	if !more {
+		r.endMember()
+	}
+	return nil
+}
+
+// endMember closes the current gzip member, recording the length of its
+// record if it held only one
+func (r *Reader) endMember() {
+	if len(r.inMember) == 1 {
+		r.inMember[0].Length = r.src.n - r.memberStart
+	}
+	r.member = nil
+	r.inMember = r.inMember[:0]
+}
+
+// lineReader is the reading a record needs from its stream
+type lineReader interface {
+	io.Reader
+	ReadString(delim byte) (string, error)
+	Peek(n int) ([]byte, error)
+	Discard(n int) (int, error)
+}
+
+// skipBlankLines skips the line breaks between records and reports whether
+// anything follows them
+func skipBlankLines(r lineReader) (bool, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b[0] != '\r' && b[0] != '\n' {
+			return true, nil
+		}
+		r.Discard(1)
+	}
+}
+
+// readHeader reads a version line and the named fields up to the blank line
+// that ends the header. Folded continuation lines are joined.
+func readHeader(r lineReader) (string, Header, error) {
+	read := 0
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		read += len(line)
+		if read > maxHeaderBytes {
+			return "", fmt.Errorf("%w: header exceeds %d bytes", ErrMalformed, maxHeaderBytes)
+		}
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: header ends early", ErrMalformed)
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	version, err := readLine()
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return "", nil, fmt.Errorf("%w: expected a WARC version line, got %q", ErrMalformed, truncate(version, 40))
+	}
+
+	var header Header
+	for {
+		line, err := readLine()
+		if err != nil {
+			return "", nil, err
+		}
+		if line == "" {
+			return version, header, nil
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(header) > 0 {
+			header[len(header)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", nil, fmt.Errorf("%w: invalid header line %q", ErrMalformed, truncate(line, 40))
+		}
+		header = append(header, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
+
+// countingReader counts the bytes consumed from a buffered reader, so
+// offsets stay exact however much the buffer has read ahead. It is an
+// io.ByteReader, which keeps gzip from buffering past a member's end.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+func (c *countingReader) ReadString(delim byte) (string, error) {
+	s, err := c.r.ReadString(delim)
+	c.n += int64(len(s))
+	return s, err
+}
+
+func (c *countingReader) Peek(n int) ([]byte, error) {
+	return c.r.Peek(n)
+}
+
+func (c *countingReader) Discard(n int) (int, error) {
+	d, err := c.r.Discard(n)
+	c.n += int64(d)
+	return d, err
+}
@@ -0,0 +1,163 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRemoteRecordBytes caps a record fetched by range request
+const maxRemoteRecordBytes = 200 * 1024 * 1024
+
+// CDXClient queries a CDX server, such as a Common Crawl index
+// (https://index.commoncrawl.org/CC-MAIN-2024-10-index) or the Internet
+// Archive's (https://web.archive.org/cdx/search/cdx)
+type CDXClient struct {
+	Endpoint  string
+	UserAgent string
+	client    *http.Client
+}
+
+// NewCDXClient creates a client for a CDX server endpoint. A nil client
+// uses one with a 60 second timeout.
+func NewCDXClient(endpoint string, client *http.Client) *CDXClient {
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &CDXClient{
+		Endpoint:  endpoint,
+		UserAgent: "CAIA-Library/1.0 (+https://caia.tech/bot)",
+		client:    client,
+	}
+}
+
+// Lookup returns the captures of a URL. A URL ending in "*" matches by
+// prefix, as CDX servers support. A limit of 0 leaves the server's default.
+func (c *CDXClient) Lookup(ctx context.Context, rawURL string, limit int) ([]CDXEntry, error) {
+	query := url.Values{}
+	query.Set("url", rawURL)
+	query.Set("output", "json")
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	endpoint := c.Endpoint
+	if strings.Contains(endpoint, "?") {
+		endpoint += "&" + query.Encode()
+	} else {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CDX server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Common Crawl answers 404 when a URL has no captures
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("CDX server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	br := bufio.NewReader(resp.Body)
+	if first, err := br.Peek(1); err == nil && first[0] == '[' {
+		return parseCDXTable(br)
+	}
+	return ReadCDX(br)
+}
+
+// parseCDXTable reads the JSON output of the Internet Archive's CDX server:
+// an array of rows, the first naming the fields
+func parseCDXTable(r io.Reader) ([]CDXEntry, error) {
+	var rows [][]string
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("invalid CDX response: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, nil
+	}
+
+	names := rows[0]
+	entries := make([]CDXEntry, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		fields := make(map[string]string, len(row))
+		for i, value := range row {
+			if i < len(names) {
+				fields[names[i]] = value
+			}
+		}
+		data, _ := json.Marshal(fields)
+		entry, err := parseCDXJSON(string(data), CDXEntry{})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// FetchRecord reads the record at offset in a remote WARC file with an HTTP
+// range request, as Common Crawl's files are read
+func FetchRecord(ctx context.Context, client *http.Client, warcURL string, offset, length int64) (*Record, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	if length <= 0 || length > maxRemoteRecordBytes {
+		return nil, fmt.Errorf("record length %d out of range", length)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", warcURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch WARC record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		body = resp.Body
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			return nil, fmt.Errorf("failed to read WARC record: %w", err)
+		}
+		body = resp.Body
+	default:
+		return nil, fmt.Errorf("WARC server returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WARC record: %w", err)
+	}
+
+	record, err := ReadRecordAt(bytes.NewReader(data), 0, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	record.Offset = offset
+	return record, nil
+}
@@ -0,0 +1,168 @@
+// Package warc reads and writes WARC 1.1 files, the ISO 28500 web archive
+// format used by Common Crawl and the Internet Archive.
+//
+// Reader handles plain and gzipped files, where each record is normally its
+// own gzip member so a record can be read from its offset alone. Writer
+// produces such files, and FileWriter rotates them and keeps a CDXJ index
+// beside each so captured responses can be found again by URL.
+package warc
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version is the WARC version written
+const Version = "WARC/1.1"
+
+// Record types
+const (
+	TypeWarcinfo     = "warcinfo"
+	TypeResponse     = "response"
+	TypeResource     = "resource"
+	TypeRequest      = "request"
+	TypeMetadata     = "metadata"
+	TypeRevisit      = "revisit"
+	TypeConversion   = "conversion"
+	TypeContinuation = "continuation"
+)
+
+// Header field names
+const (
+	FieldType           = "WARC-Type"
+	FieldRecordID       = "WARC-Record-ID"
+	FieldDate           = "WARC-Date"
+	FieldTargetURI      = "WARC-Target-URI"
+	FieldContentLength  = "Content-Length"
+	FieldContentType    = "Content-Type"
+	FieldBlockDigest    = "WARC-Block-Digest"
+	FieldPayloadDigest  = "WARC-Payload-Digest"
+	FieldConcurrentTo   = "WARC-Concurrent-To"
+	FieldWarcinfoID     = "WARC-Warcinfo-ID"
+	FieldFilename       = "WARC-Filename"
+	FieldIPAddress      = "WARC-IP-Address"
+	FieldRefersTo       = "WARC-Refers-To"
+	FieldTruncated      = "WARC-Truncated"
+	FieldIdentifiedType = "WARC-Identified-Payload-Type"
+)
+
+// Content types of HTTP request and response blocks
+const (
+	ContentTypeHTTPRequest  = "application/http; msgtype=request"
+	ContentTypeHTTPResponse = "application/http; msgtype=response"
+	ContentTypeWarcFields   = "application/warc-fields"
+)
+
+// ErrMalformed is returned for input that is not a valid WARC record
+var ErrMalformed = errors.New("malformed WARC record")
+
+// Field is a named header field
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header holds a record's named fields in order. Names are matched case
+// insensitively.
+type Header []Field
+
+// Get returns the first value of the named field, or ""
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the named field, keeping its position, or appends it
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			h.delFrom(name, i+1)
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Add appends a field, keeping earlier ones of the same name
+func (h *Header) Add(name, value string) {
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Del removes every field of the given name
+func (h *Header) Del(name string) {
+	h.delFrom(name, 0)
+}
+
+func (h *Header) delFrom(name string, start int) {
+	out := (*h)[:start]
+	for _, f := range (*h)[start:] {
+		if !strings.EqualFold(f.Name, name) {
+			out = append(out, f)
+		}
+	}
+	*h = out
+}
+
+// Type returns the record's WARC-Type
+func (h Header) Type() string { return h.Get(FieldType) }
+
+// RecordID returns the record's WARC-Record-ID
+func (h Header) RecordID() string { return h.Get(FieldRecordID) }
+
+// TargetURI returns the record's WARC-Target-URI without the angle
+// brackets some writers add
+func (h Header) TargetURI() string {
+	return strings.Trim(h.Get(FieldTargetURI), "<>")
+}
+
+// Date returns the record's WARC-Date, or the zero time if it is missing
+// or malformed
+func (h Header) Date() time.Time {
+	t, err := time.Parse(time.RFC3339Nano, h.Get(FieldDate))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// ContentLength returns the length of the record block
+func (h Header) ContentLength() (int64, error) {
+	value := h.Get(FieldContentLength)
+	if value == "" {
+		return 0, fmt.Errorf("%w: missing Content-Length", ErrMalformed)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: invalid Content-Length %q", ErrMalformed, value)
+	}
+	return n, nil
+}
+
+// NewRecordID returns a new globally unique record ID
+func NewRecordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+// FormatDate formats a time as a WARC-Date
+func FormatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// Digest returns the SHA-1 digest of data in the form WARC files and CDX
+// indexes use, e.g. "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ"
+func Digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,249 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testExchange(t *testing.T, rawURL string) (*http.Request, *http.Response) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "test-agent")
+
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{},
+	}
+	resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+	resp.Header.Set("Transfer-Encoding", "chunked")
+	return req, resp
+}
+
+func TestWriteAndRead(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, compress)
+
+		infoID, err := w.WriteWarcinfo("test.warc", Header{{Name: "software", Value: "caia"}})
+		require.NoError(t, err)
+
+		req, resp := testExchange(t, "https://example.com/a?b=1")
+		first, err := w.WriteExchange(req, resp, []byte("<p>first</p>"))
+		require.NoError(t, err)
+		req, resp = testExchange(t, "https://example.com/b")
+		second, err := w.WriteExchange(req, resp, []byte("<p>second</p>"))
+		require.NoError(t, err)
+
+		r := NewReader(bytes.NewReader(buf.Bytes()))
+		var records []*Record
+		var payloads []string
+		for {
+			record, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			records = append(records, record)
+			if record.Header.Type() == TypeResponse {
+				payload, err := ReadPayload(record, 0)
+				require.NoError(t, err)
+				assert.Equal(t, "text/html; charset=utf-8", payload.ContentType)
+				payloads = append(payloads, string(payload.Body))
+			}
+		}
+
+		require.Len(t, records, 5, "compress=%v", compress)
+		assert.Equal(t, TypeWarcinfo, records[0].Header.Type())
+		assert.Equal(t, infoID, records[0].Header.RecordID())
+		assert.Equal(t, TypeResponse, records[1].Header.Type())
+		assert.Equal(t, TypeRequest, records[2].Header.Type())
+		assert.Equal(t, records[1].Header.RecordID(), records[2].Header.Get(FieldConcurrentTo))
+		assert.Equal(t, infoID, records[1].Header.Get(FieldWarcinfoID))
+		assert.Equal(t, "https://example.com/a?b=1", records[1].Header.TargetURI())
+		assert.False(t, records[1].Header.Date().IsZero())
+		assert.Equal(t, []string{"<p>first</p>", "<p>second</p>"}, payloads)
+
+		assert.Equal(t, first.Offset, records[1].Offset)
+		assert.Equal(t, first.Length, records[1].Length)
+		assert.Equal(t, second.Offset, records[3].Offset)
+		assert.Equal(t, second.Length, records[3].Length)
+		assert.Equal(t, int64(buf.Len()), records[4].Offset+records[4].Length)
+
+		// A record can be read on its own from where an index puts it
+		record, err := ReadRecordAt(bytes.NewReader(buf.Bytes()), second.Offset, second.Length)
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, record.Header.RecordID())
+		payload, err := ReadPayload(record, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "<p>second</p>", string(payload.Body))
+	}
+}
+
+func TestReadMultiRecordMember(t *testing.T) {
+	// Some tools compress a whole file as one gzip member
+	var plain bytes.Buffer
+	w := NewWriter(&plain, false)
+	for _, target := range []string{"https://example.com/1", "https://example.com/2"} {
+		_, err := w.WriteRecord(Header{
+			{Name: FieldType, Value: TypeResource},
+			{Name: FieldTargetURI, Value: target},
+			{Name: FieldContentType, Value: "text/plain"},
+		}, []byte("content of "+target))
+		require.NoError(t, err)
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(plain.Bytes())
+	require.NoError(t, gz.Close())
+
+	r := NewReader(&compressed)
+	var targets []string
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		payload, err := ReadPayload(record, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "content of "+record.Header.TargetURI(), string(payload.Body))
+		targets = append(targets, record.Header.TargetURI())
+	}
+	assert.Equal(t, []string{"https://example.com/1", "https://example.com/2"}, targets)
+}
+
+func TestReadMalformed(t *testing.T) {
+	_, err := NewReader(strings.NewReader("not a warc file\r\n\r\n")).Next()
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	_, err = NewReader(strings.NewReader("WARC/1.1\r\nWARC-Type: resource\r\n\r\n")).Next()
+	assert.ErrorIs(t, err, ErrMalformed, "missing Content-Length")
+
+	r := NewReader(strings.NewReader("WARC/1.1\r\nWARC-Type: resource\r\nContent-Length: 100\r\n\r\nshort"))
+	_, err = r.Next()
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = NewWriter(io.Discard, false).WriteRecord(Header{
+		{Name: FieldType, Value: TypeResource},
+		{Name: FieldTargetURI, Value: "https://example.com/\r\nX: y"},
+	}, nil)
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestReadPayloadEncodings(t *testing.T) {
+	var gzBody bytes.Buffer
+	gz := gzip.NewWriter(&gzBody)
+	gz.Write([]byte("decoded body"))
+	require.NoError(t, gz.Close())
+
+	block := "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Encoding: gzip\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"10\r\n" + gzBody.String()[:16] + "\r\n" +
+		strconv.FormatInt(int64(gzBody.Len()-16), 16) + "\r\n" + gzBody.String()[16:] + "\r\n0\r\n\r\n"
+
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, true).WriteRecord(Header{
+		{Name: FieldType, Value: TypeResponse},
+		{Name: FieldTargetURI, Value: "https://example.com/"},
+		{Name: FieldContentType, Value: ContentTypeHTTPResponse},
+	}, []byte(block))
+	require.NoError(t, err)
+
+	record, err := NewReader(bytes.NewReader(buf.Bytes())).Next()
+	require.NoError(t, err)
+	payload, err := ReadPayload(record, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "decoded body", string(payload.Body))
+
+	record, err = NewReader(bytes.NewReader(buf.Bytes())).Next()
+	require.NoError(t, err)
+	_, err = ReadPayload(record, 4)
+	assert.Error(t, err, "over the limit")
+}
+
+func TestFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := OpenFileWriter(dir, "test", 2048, Header{{Name: "software", Value: "caia"}})
+	require.NoError(t, err)
+
+	var entries []CDXEntry
+	for i := 0; i < 6; i++ {
+		target := "https://www.example.com/page" + string(rune('a'+i))
+		req, resp := testExchange(t, target)
+		entry, err := fw.WriteExchange(req, resp, bytes.Repeat([]byte("x"), 500))
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+	require.NoError(t, fw.Close())
+
+	req, resp := testExchange(t, "https://example.com/")
+	_, err = fw.WriteExchange(req, resp, nil)
+	assert.Error(t, err, "closed")
+
+	warcs, err := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	require.NoError(t, err)
+	assert.Greater(t, len(warcs), 1, "files rotate at the size limit")
+	cdxs, err := filepath.Glob(filepath.Join(dir, "test-*.cdxj"))
+	require.NoError(t, err)
+	assert.Len(t, cdxs, len(warcs))
+
+	// Every capture can be found through the sidecar indexes
+	var indexed []CDXEntry
+	for _, path := range cdxs {
+		idx, err := LoadCDXIndex(path)
+		require.NoError(t, err)
+		indexed = append(indexed, idx.Entries()...)
+	}
+	index := NewCDXIndex(indexed)
+	assert.Equal(t, len(entries), index.Len())
+
+	for _, entry := range entries {
+		found := index.Lookup(entry.URL)
+		require.Len(t, found, 1)
+		assert.Equal(t, "text/html", found[0].MIME)
+		assert.Equal(t, "200", found[0].Status)
+
+		file, err := os.Open(found[0].Location(dir))
+		require.NoError(t, err)
+		record, err := ReadRecordAt(file, found[0].Offset, found[0].Length)
+		require.NoError(t, err)
+		assert.Equal(t, entry.URL, record.Header.TargetURI())
+		payload, err := ReadPayload(record, 0)
+		require.NoError(t, err)
+		assert.Equal(t, found[0].Digest, strings.TrimPrefix(Digest(payload.Body), "sha1:"))
+		file.Close()
+	}
+
+	// Each file opens with its own warcinfo record
+	file, err := os.Open(warcs[len(warcs)-1])
+	require.NoError(t, err)
+	defer file.Close()
+	record, err := NewReader(file).Next()
+	require.NoError(t, err)
+	assert.Equal(t, TypeWarcinfo, record.Header.Type())
+	assert.Equal(t, filepath.Base(warcs[len(warcs)-1]), record.Header.Get(FieldFilename))
+}
+
+func TestRequestBlock(t *testing.T) {
+	req := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Scheme: "https", Host: "example.com", Path: "/a", RawQuery: "b=1"},
+		Header: http.Header{"Accept": {"text/html"}},
+	}
+	assert.Equal(t, "GET /a?b=1 HTTP/1.1\r\nHost: example.com\r\nAccept: text/html\r\n\r\n", string(RequestBlock(req)))
+}
@@ -0,0 +1,142 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordInfo locates a written record in its file
+type RecordInfo struct {
+	ID     string
+	Offset int64
+	Length int64
+}
+
+// Writer writes WARC records to a stream. When compressing, each record is
+// written as its own gzip member so it can be read back from its offset.
+// A Writer is not safe for concurrent use; see FileWriter.
+type Writer struct {
+	w          *countingWriter
+	compress   bool
+	warcinfoID string
+}
+
+// NewWriter creates a writer that appends records to w
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: &countingWriter{w: w}, compress: compress}
+}
+
+// Offset returns the number of bytes written so far
+func (w *Writer) Offset() int64 {
+	return w.w.n
+}
+
+// WriteRecord writes a record with the given header and block. WARC-Type
+// is required. WARC-Record-ID, WARC-Date and WARC-Block-Digest are filled
+// in when missing, as is WARC-Warcinfo-ID once a warcinfo record has been
+// written. Content-Length is always set from the block.
+func (w *Writer) WriteRecord(header Header, block []byte) (RecordInfo, error) {
+	recordType := header.Type()
+	if recordType == "" {
+		return RecordInfo{}, fmt.Errorf("%w: missing WARC-Type", ErrMalformed)
+	}
+	id := header.RecordID()
+	if id == "" {
+		id = NewRecordID()
+	}
+	date := header.Get(FieldDate)
+	if date == "" {
+		date = FormatDate(time.Now())
+	}
+
+	// Mandatory fields first, Content-Length last
+	fields := Header{
+		{Name: FieldType, Value: recordType},
+		{Name: FieldRecordID, Value: id},
+		{Name: FieldDate, Value: date},
+	}
+	for _, f := range header {
+		switch strings.ToLower(f.Name) {
+		case "warc-type", "warc-record-id", "warc-date", "content-length":
+			continue
+		}
+		if strings.ContainsAny(f.Name+f.Value, "\r\n") {
+			return RecordInfo{}, fmt.Errorf("%w: line break in field %s", ErrMalformed, f.Name)
+		}
+		fields = append(fields, f)
+	}
+	if w.warcinfoID != "" && recordType != TypeWarcinfo && fields.Get(FieldWarcinfoID) == "" {
+		fields = append(fields, Field{Name: FieldWarcinfoID, Value: w.warcinfoID})
+	}
+	if fields.Get(FieldBlockDigest) == "" {
+		fields = append(fields, Field{Name: FieldBlockDigest, Value: Digest(block)})
+	}
+	fields = append(fields, Field{Name: FieldContentLength, Value: strconv.Itoa(len(block))})
+
+	offset := w.w.n
+	var out io.Writer = w.w
+	var gz *gzip.Writer
+	if w.compress {
+		gz = gzip.NewWriter(w.w)
+		out = gz
+	}
+
+	bw := bufio.NewWriter(out)
+	bw.WriteString(Version + "\r\n")
+	for _, f := range fields {
+		bw.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	bw.WriteString("\r\n")
+	bw.Write(block)
+	bw.WriteString("\r\n\r\n")
+	if err := bw.Flush(); err != nil {
+		return RecordInfo{}, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return RecordInfo{}, fmt.Errorf("failed to write WARC record: %w", err)
+		}
+	}
+
+	return RecordInfo{ID: id, Offset: offset, Length: w.w.n - offset}, nil
+}
+
+// WriteWarcinfo writes a warcinfo record describing the file and the
+// software writing it. Records written after it refer to it through
+// WARC-Warcinfo-ID.
+func (w *Writer) WriteWarcinfo(filename string, fields Header) (string, error) {
+	var block strings.Builder
+	for _, f := range fields {
+		block.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+
+	header := Header{
+		{Name: FieldType, Value: TypeWarcinfo},
+		{Name: FieldContentType, Value: ContentTypeWarcFields},
+	}
+	if filename != "" {
+		header.Set(FieldFilename, filename)
+	}
+	info, err := w.WriteRecord(header, []byte(block.String()))
+	if err != nil {
+		return "", err
+	}
+	w.warcinfoID = info.ID
+	return info.ID, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
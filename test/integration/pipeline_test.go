@@ -36,8 +36,8 @@ func mockStoreDocument(ctx context.Context, input workflows.StoreInput) (string,
 	return "abc123", nil
 }
 
-func mockIndexDocument(ctx context.Context, commitHash string) error {
-	return nil
+func mockIndexDocument(ctx context.Context, commitHash string) (workflows.IndexResult, error) {
+	return workflows.IndexResult{CommitHash: commitHash, QueryableAt: time.Now()}, nil
 }
 
 func mockMergeBranch(ctx context.Context, commitHash string) error {